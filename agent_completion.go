@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// defaultAgentMaxSteps React Agent 默认的最大推理步数，可通过 AGENT_MAX_STEPS 调整
+const defaultAgentMaxSteps = 10
+
+// completionToolResultChars 收尾步骤中每个工具结果保留的最大字符数，控制上下文长度
+const completionToolResultChars = 4000
+
+// completionPrompt 收尾步骤的用户提示词，%d 为步数上限，%s 为已收集的数据
+const completionPrompt = `分析已达到最大推理步数（%d），不能再调用任何工具。请只根据下面已收集的数据，按照系统提示词的输出要求撰写完整的投资分析报告并给出投资评级；缺少的数据请在报告中说明，不要编造数据。
+
+## 已收集的数据
+
+%s`
+
+// completionFilePrompt 数据以附件文件提供时的收尾提示词，%d 为步数上限，%s 为附件文件名
+const completionFilePrompt = `分析已达到最大推理步数（%d），不能再调用任何工具。已收集的全部工具结果和中间分析见附件 %s（markdown，未截断）。请只根据附件中的数据，按照系统提示词的输出要求撰写完整的投资分析报告并给出投资评级；缺少的数据请在报告中说明，不要编造数据。`
+
+// gatheredDataFileName 收尾步骤上传的数据附件名
+const gatheredDataFileName = "gathered_data.md"
+
+// completeReportFromGatheredData Agent 达到最大推理步数仍未给出最终回复时，汇总已收集的数据，
+// 单独调用一次模型（不绑定工具）撰写报告，代替返回截断或空的结果
+// files 不为空且数据超出内联长度时，将未截断的数据上传为附件，上传失败时退回内联截断后的数据
+func completeReportFromGatheredData(ctx context.Context, chatModel model.BaseChatModel, files modelFileUploader, systemPrompt, userPrompt string, maxSteps int, progress *analysisProgress, events *progressEmitter, printer *terminalPrinter) error {
+	data := progress.gatheredData(completionToolResultChars)
+	request := schema.UserMessage(fmt.Sprintf(completionPrompt, maxSteps, data))
+	if part := uploadGatheredData(ctx, files, progress, data); part != nil {
+		request = fileMessage(fmt.Sprintf(completionFilePrompt, maxSteps, gatheredDataFileName), *part)
+	}
+	messages := []*schema.Message{
+		schema.SystemMessage(systemPrompt),
+		schema.UserMessage(userPrompt),
+		request,
+	}
+	stream, err := chatModel.Stream(ctx, messages)
+	if err != nil {
+		return fmt.Errorf("根据已收集数据撰写报告失败: %w", err)
+	}
+	msg, err := readMessageStream(stream, events, printer)
+	if err != nil {
+		return fmt.Errorf("根据已收集数据撰写报告失败: %w", err)
+	}
+	progress.addMessage(msg)
+	progress.setStepLimitedFinal(msg.Content, maxSteps)
+	return nil
+}
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"investment/provider"
+	"investment/tools"
+)
+
+// Alpha Vantage 接口地址；免费密钥每分钟 5 次、每天 25 次请求
+const (
+	alphaVantageHost             = "www.alphavantage.co"
+	alphaVantageBaseURL          = "https://" + alphaVantageHost
+	defaultAlphaVantageRateLimit = 5
+	// alphaVantageCompactDays TIME_SERIES_DAILY 的 compact 输出覆盖最近 100 个交易日，开始日期在此天数内时不请求完整历史
+	alphaVantageCompactDays = 140
+)
+
+// alphaVantage Alpha Vantage 的 provider.DataProvider 实现，需要 ALPHA_VANTAGE_API_KEY
+// 提供日线价格（TIME_SERIES_DAILY）、公司概况和 TTM 指标（OVERVIEW）、历年和历季每股收益（EARNINGS）；不提供新闻和内部人交易，这些数据返回 tools.ErrNoData
+// ALPHA_VANTAGE_RATE_LIMIT: 每分钟最多请求数，默认按免费密钥的 5 次，付费密钥可调大
+type alphaVantage struct{}
+
+// Name 实现 provider.DataProvider
+func (alphaVantage) Name() string {
+	return providerAlphaVantage
+}
+
+// alphaVantageRateLimit 每分钟最多请求 Alpha Vantage 的次数，无效值在配置检查中报告，这里按默认值处理
+func alphaVantageRateLimit() int {
+	perMinute, err := positiveIntEnv("ALPHA_VANTAGE_RATE_LIMIT", defaultAlphaVantageRateLimit)
+	if err != nil {
+		return defaultAlphaVantageRateLimit
+	}
+	return perMinute
+}
+
+// alphaVantageMessage Alpha Vantage 在 200 响应中返回的提示：Note / Information 为限流或付费接口说明，Error Message 为参数错误（如代码不存在）
+type alphaVantageMessage struct {
+	Note         string `json:"Note"`
+	Information  string `json:"Information"`
+	ErrorMessage string `json:"Error Message"`
+}
+
+// parseAlphaVantageMessage 解析响应中的提示，正常数据返回零值
+func parseAlphaVantageMessage(body []byte) alphaVantageMessage {
+	var message alphaVantageMessage
+	_ = json.Unmarshal(body, &message)
+	return message
+}
+
+// dailyLimit 是否为每日请求额度用完的提示，当天内重试或等待都无法恢复
+func (m alphaVantageMessage) dailyLimit() bool {
+	return strings.Contains(strings.ToLower(m.Note+" "+m.Information), "requests per day")
+}
+
+// throttled 是否为每分钟请求数超限的提示（Note），等待一分钟后可以重试；每日额度用完见 dailyLimit
+func (m alphaVantageMessage) throttled() bool {
+	return m.Note != "" && !m.dailyLimit()
+}
+
+// alphaVantageTransport 只作用于 Alpha Vantage 的请求：按 ALPHA_VANTAGE_RATE_LIMIT 限速，并把 200 响应中每分钟的限流提示改写为 429，
+// 使限流同样走密钥轮换和退避重试，且不会被响应缓存保存；每日额度用完时返回包装 tools.ErrRateLimited 的错误；回放快照时不限速
+type alphaVantageTransport struct {
+	next  http.RoundTripper
+	pacer *requestPacer
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t alphaVantageTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != alphaVantageHost {
+		return t.next.RoundTrip(req)
+	}
+	if rt := activeSnapshot.Load(); rt == nil || isSnapshotRecorder(*rt) {
+		if err := t.pacer.wait(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	message := parseAlphaVantageMessage(body)
+	if message.dailyLimit() {
+		// 直接返回错误：不重试、不轮换密钥，也不进入响应缓存
+		return nil, fmt.Errorf("Alpha Vantage 今日请求额度已用完: %w: %s", tools.ErrRateLimited, strings.TrimSpace(message.Note+" "+message.Information))
+	}
+	if message.throttled() {
+		resp.StatusCode = http.StatusTooManyRequests
+		resp.Status = http.StatusText(http.StatusTooManyRequests)
+		if resp.Header == nil {
+			resp.Header = make(http.Header)
+		}
+		resp.Header.Set("Retry-After", "60")
+	}
+	return resp, nil
+}
+
+// alphaVantageSymbol 把代码转换为 Alpha Vantage 的格式：上交所 .SHH、深交所 .SHZ
+func alphaVantageSymbol(ticker string) string {
+	for suffix, replacement := range map[string]string{".SS": ".SHH", ".SH": ".SHH", ".SZ": ".SHZ"} {
+		if strings.HasSuffix(ticker, suffix) {
+			return strings.TrimSuffix(ticker, suffix) + replacement
+		}
+	}
+	return ticker
+}
+
+// fetchAlphaVantage 请求 Alpha Vantage 的 /query 接口（经过响应缓存），返回正常数据的响应体
+func fetchAlphaVantage(ctx context.Context, ticker, function string, params url.Values) ([]byte, error) {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("function", function)
+	params.Set("symbol", alphaVantageSymbol(ticker))
+	resp, err := dataAPIClient().do(ctx, providerAlphaVantage, "GET", "/query?"+params.Encode(), nil, true)
+	if err != nil {
+		return nil, fmt.Errorf("API 请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应体失败: %w", err)
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("获取数据错误: %s: %w", ticker, tools.StatusError(resp.StatusCode, body))
+	}
+	message := parseAlphaVantageMessage(body)
+	switch {
+	case message.ErrorMessage != "":
+		return nil, fmt.Errorf("获取数据错误: %s: %w: %s", ticker, tools.ErrNotFound, message.ErrorMessage)
+	case message.Information != "":
+		// 付费接口或参数不受当前密钥支持
+		return nil, fmt.Errorf("%s %s: %w: %s", ticker, function, tools.ErrNoData, message.Information)
+	}
+	return body, nil
+}
+
+// Prices 获取日线价格，开始日期在最近约 100 个交易日内时只请求 compact 输出（完整历史对免费密钥可能需要付费订阅）
+func (alphaVantage) Prices(ctx context.Context, ticker, startDate, endDate string) ([]provider.Price, error) {
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		return nil, fmt.Errorf("无效的开始日期: %s", startDate)
+	}
+	if _, err := time.Parse("2006-01-02", endDate); err != nil {
+		return nil, fmt.Errorf("无效的结束日期: %s", endDate)
+	}
+	outputSize := "compact"
+	if time.Since(start) > alphaVantageCompactDays*24*time.Hour {
+		outputSize = "full"
+	}
+	body, err := fetchAlphaVantage(ctx, ticker, "TIME_SERIES_DAILY", url.Values{"outputsize": {outputSize}})
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Series map[string]struct {
+			Open   string `json:"1. open"`
+			High   string `json:"2. high"`
+			Low    string `json:"3. low"`
+			Close  string `json:"4. close"`
+			Volume string `json:"5. volume"`
+		} `json:"Time Series (Daily)"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("解析价格响应失败: %w", err)
+	}
+
+	var prices []provider.Price
+	for date, bar := range response.Series {
+		if date < startDate || date > endDate {
+			continue
+		}
+		price := provider.Price{Time: date}
+		price.Open, _ = strconv.ParseFloat(bar.Open, 64)
+		price.High, _ = strconv.ParseFloat(bar.High, 64)
+		price.Low, _ = strconv.ParseFloat(bar.Low, 64)
+		price.Close, _ = strconv.ParseFloat(bar.Close, 64)
+		price.Volume, _ = strconv.ParseInt(bar.Volume, 10, 64)
+		prices = append(prices, price)
+	}
+	slices.SortFunc(prices, func(a, b provider.Price) int { return strings.Compare(a.Time, b.Time) })
+	return prices, nil
+}
+
+// alphaVantageOverview OVERVIEW 接口的响应，数值均为字符串，没有数据时为 "None" 或 "-"
+type alphaVantageOverview struct {
+	Symbol                     string `json:"Symbol"`
+	AssetType                  string `json:"AssetType"`
+	Name                       string `json:"Name"`
+	CIK                        string `json:"CIK"`
+	Exchange                   string `json:"Exchange"`
+	Currency                   string `json:"Currency"`
+	Sector                     string `json:"Sector"`
+	Industry                   string `json:"Industry"`
+	Address                    string `json:"Address"`
+	OfficialSite               string `json:"OfficialSite"`
+	LatestQuarter              string `json:"LatestQuarter"`
+	MarketCapitalization       string `json:"MarketCapitalization"`
+	PERatio                    string `json:"PERatio"`
+	PEGRatio                   string `json:"PEGRatio"`
+	BookValue                  string `json:"BookValue"`
+	DividendPerShare           string `json:"DividendPerShare"`
+	EPS                        string `json:"EPS"`
+	ProfitMargin               string `json:"ProfitMargin"`
+	OperatingMarginTTM         string `json:"OperatingMarginTTM"`
+	ReturnOnAssetsTTM          string `json:"ReturnOnAssetsTTM"`
+	ReturnOnEquityTTM          string `json:"ReturnOnEquityTTM"`
+	RevenueTTM                 string `json:"RevenueTTM"`
+	GrossProfitTTM             string `json:"GrossProfitTTM"`
+	QuarterlyEarningsGrowthYOY string `json:"QuarterlyEarningsGrowthYOY"`
+	QuarterlyRevenueGrowthYOY  string `json:"QuarterlyRevenueGrowthYOY"`
+	PriceToSalesRatioTTM       string `json:"PriceToSalesRatioTTM"`
+	PriceToBookRatio           string `json:"PriceToBookRatio"`
+	EVToRevenue                string `json:"EVToRevenue"`
+	EVToEBITDA                 string `json:"EVToEBITDA"`
+	SharesOutstanding          string `json:"SharesOutstanding"`
+}
+
+// alphaVantageNumber 解析数值字符串，"None"、"-" 或空值返回 false
+func alphaVantageNumber(value string) (float64, bool) {
+	n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// alphaVantageValue 数值，没有数据时为 0
+func alphaVantageValue(value string) float64 {
+	n, _ := alphaVantageNumber(value)
+	return n
+}
+
+// overview 获取公司概况，代码不存在时 Alpha Vantage 返回空对象
+func (alphaVantage) overview(ctx context.Context, ticker string) (*alphaVantageOverview, error) {
+	body, err := fetchAlphaVantage(ctx, ticker, "OVERVIEW", nil)
+	if err != nil {
+		return nil, err
+	}
+	var overview alphaVantageOverview
+	if err := json.Unmarshal(body, &overview); err != nil {
+		return nil, fmt.Errorf("解析公司概况响应失败: %w", err)
+	}
+	if overview.Symbol == "" {
+		return nil, fmt.Errorf("%s 公司概况: %w", ticker, tools.ErrNoData)
+	}
+	return &overview, nil
+}
+
+// Metrics ttm 取自公司概况（只有最新一期，报告期为最近一个季度末）；annual、quarterly 取自每股收益历史，只有每股收益和同比增长
+func (p alphaVantage) Metrics(ctx context.Context, ticker, endDate, period string, limit int) ([]tools.FinancialMetrics, error) {
+	var metrics []tools.FinancialMetrics
+	var err error
+	if period == "ttm" {
+		metrics, err = p.overviewMetrics(ctx, ticker, endDate)
+	} else {
+		metrics, err = p.earningsMetrics(ctx, ticker, endDate, period, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	units := dataAPIClient().providers[providerAlphaVantage].MetricUnits
+	for _, note := range tools.NormalizeFinancialMetrics(metrics, units) {
+		log.Printf("[Metrics] %s %s", ticker, note)
+	}
+	return metrics, nil
+}
+
+// overviewMetrics 按公司概况生成最新一期的 TTM 指标，endDate 早于最近一个季度末时返回 tools.ErrNoData
+func (p alphaVantage) overviewMetrics(ctx context.Context, ticker, endDate string) ([]tools.FinancialMetrics, error) {
+	overview, err := p.overview(ctx, ticker)
+	if err != nil {
+		return nil, err
+	}
+	if overview.LatestQuarter > endDate {
+		return nil, fmt.Errorf("%s 截至 %s 的财务指标（Alpha Vantage 没有历史 TTM 指标）: %w", ticker, endDate, tools.ErrNoData)
+	}
+
+	ptr := func(value string) *float64 {
+		if n, ok := alphaVantageNumber(value); ok {
+			return &n
+		}
+		return nil
+	}
+	metrics := tools.FinancialMetrics{
+		Ticker:                        ticker,
+		ReportPeriod:                  overview.LatestQuarter,
+		Period:                        "ttm",
+		Currency:                      overview.Currency,
+		MarketCap:                     alphaVantageValue(overview.MarketCapitalization),
+		EnterpriseValue:               alphaVantageValue(overview.EVToRevenue) * alphaVantageValue(overview.RevenueTTM),
+		PriceToEarningsRatio:          alphaVantageValue(overview.PERatio),
+		PriceToBookRatio:              alphaVantageValue(overview.PriceToBookRatio),
+		PriceToSalesRatio:             alphaVantageValue(overview.PriceToSalesRatioTTM),
+		EnterpriseValueToEbitdaRatio:  alphaVantageValue(overview.EVToEBITDA),
+		EnterpriseValueToRevenueRatio: alphaVantageValue(overview.EVToRevenue),
+		PegRatio:                      alphaVantageValue(overview.PEGRatio),
+		OperatingMargin:               ptr(overview.OperatingMarginTTM),
+		NetMargin:                     ptr(overview.ProfitMargin),
+		ReturnOnEquity:                ptr(overview.ReturnOnEquityTTM),
+		ReturnOnAssets:                ptr(overview.ReturnOnAssetsTTM),
+		RevenueGrowth:                 alphaVantageValue(overview.QuarterlyRevenueGrowthYOY),
+		EarningsGrowth:                alphaVantageValue(overview.QuarterlyEarningsGrowthYOY),
+		EarningsPerShare:              alphaVantageValue(overview.EPS),
+		BookValuePerShare:             alphaVantageValue(overview.BookValue),
+	}
+	if revenue := alphaVantageValue(overview.RevenueTTM); revenue > 0 {
+		metrics.GrossMargin = alphaVantageValue(overview.GrossProfitTTM) / revenue
+	}
+	if metrics.EarningsPerShare > 0 {
+		metrics.PayoutRatio = alphaVantageValue(overview.DividendPerShare) / metrics.EarningsPerShare
+	}
+	return []tools.FinancialMetrics{metrics}, nil
+}
+
+// earningsMetrics 按每股收益历史生成截至 endDate 的最近 limit 期指标，从新到旧排列，同比增长与上一年同期比较
+func (alphaVantage) earningsMetrics(ctx context.Context, ticker, endDate, period string, limit int) ([]tools.FinancialMetrics, error) {
+	type earnings struct {
+		FiscalDateEnding string `json:"fiscalDateEnding"`
+		ReportedEPS      string `json:"reportedEPS"`
+	}
+	body, err := fetchAlphaVantage(ctx, ticker, "EARNINGS", nil)
+	if err != nil {
+		return nil, err
+	}
+	var response struct {
+		Annual    []earnings `json:"annualEarnings"`
+		Quarterly []earnings `json:"quarterlyEarnings"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("解析每股收益响应失败: %w", err)
+	}
+
+	// 接口按报告期从新到旧返回，季度数据与四个季度前比较
+	history, lag := response.Annual, 1
+	if period == "quarterly" {
+		history, lag = response.Quarterly, 4
+	}
+	var metrics []tools.FinancialMetrics
+	for i, entry := range history {
+		if entry.FiscalDateEnding > endDate {
+			continue
+		}
+		eps, ok := alphaVantageNumber(entry.ReportedEPS)
+		if !ok {
+			continue
+		}
+		m := tools.FinancialMetrics{
+			Ticker:           ticker,
+			ReportPeriod:     entry.FiscalDateEnding,
+			Period:           period,
+			EarningsPerShare: eps,
+		}
+		if i+lag < len(history) {
+			if prior, ok := alphaVantageNumber(history[i+lag].ReportedEPS); ok && prior != 0 {
+				m.EarningsPerShareGrowth = (eps - prior) / math.Abs(prior)
+			}
+		}
+		metrics = append(metrics, m)
+		if len(metrics) == limit {
+			break
+		}
+	}
+	if len(metrics) == 0 {
+		return nil, fmt.Errorf("%s %s 财务指标: %w", ticker, period, tools.ErrNoData)
+	}
+	return metrics, nil
+}
+
+// News Alpha Vantage 数据源不提供公司新闻
+func (alphaVantage) News(ctx context.Context, ticker, endDate string, startDate *string, limit int, handle func(page []tools.CompanyNews) error) error {
+	return fmt.Errorf("%s 新闻（Alpha Vantage 数据源不提供新闻）: %w", ticker, tools.ErrNoData)
+}
+
+// InsiderTrades Alpha Vantage 数据源不提供内部人交易
+func (alphaVantage) InsiderTrades(ctx context.Context, ticker, endDate string, startDate *string, limit int, handle func(page []tools.InsiderTrade) error) (string, error) {
+	return "", fmt.Errorf("%s 内部人交易（Alpha Vantage 数据源不提供内部人交易）: %w", ticker, tools.ErrNoData)
+}
+
+// MarketCap 当天的市值取自公司概况；历史日期按该日之前最近的收盘价乘以当前总股本估算，期间有增发或回购时存在偏差
+func (p alphaVantage) MarketCap(ctx context.Context, ticker, endDate string) (float64, error) {
+	overview, err := p.overview(ctx, ticker)
+	if err != nil {
+		return 0, err
+	}
+	if endDate == time.Now().Format("2006-01-02") {
+		if marketCap := alphaVantageValue(overview.MarketCapitalization); marketCap > 0 {
+			return marketCap, nil
+		}
+		return 0, fmt.Errorf("%s 公司概况中的市值: %w", ticker, tools.ErrNoData)
+	}
+
+	shares := alphaVantageValue(overview.SharesOutstanding)
+	if shares <= 0 {
+		return 0, fmt.Errorf("%s 总股本: %w", ticker, tools.ErrNoData)
+	}
+	end, err := time.Parse("2006-01-02", endDate)
+	if err != nil {
+		return 0, fmt.Errorf("无效的结束日期: %s", endDate)
+	}
+	prices, err := p.Prices(ctx, ticker, end.AddDate(0, 0, -10).Format("2006-01-02"), endDate)
+	if err != nil {
+		return 0, err
+	}
+	if len(prices) == 0 {
+		return 0, fmt.Errorf("%s 截至 %s 的市值: %w", ticker, endDate, tools.ErrNoData)
+	}
+	return prices[len(prices)-1].Close * shares, nil
+}
+
+// Facts 按公司概况生成公司基本信息，股本为当前总股本
+func (p alphaVantage) Facts(ctx context.Context, ticker string) (*provider.CompanyFacts, error) {
+	overview, err := p.overview(ctx, ticker)
+	if err != nil {
+		return nil, err
+	}
+	return &provider.CompanyFacts{
+		Ticker:                ticker,
+		Name:                  overview.Name,
+		CIK:                   overview.CIK,
+		Industry:              overview.Industry,
+		Sector:                overview.Sector,
+		Category:              overview.AssetType,
+		Exchange:              overview.Exchange,
+		IsActive:              true,
+		Location:              overview.Address,
+		MarketCap:             alphaVantageValue(overview.MarketCapitalization),
+		WebsiteURL:            overview.OfficialSite,
+		WeightedAverageShares: int(alphaVantageValue(overview.SharesOutstanding)),
+	}, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"investment/tools"
+)
+
+func TestAlphaVantageTransportThrottle(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantRetry  string
+		wantErr    error
+	}{
+		{name: "正常数据", body: `{"Symbol":"IBM"}`, wantStatus: http.StatusOK},
+		{name: "每分钟限流", body: `{"Note":"Thank you for using Alpha Vantage! Our standard API call frequency is 5 calls per minute and 500 calls per day."}`,
+			wantStatus: http.StatusTooManyRequests, wantRetry: "60"},
+		{name: "每日额度用完", body: `{"Information":"Thank you for using Alpha Vantage! Our standard API rate limit is 25 requests per day."}`,
+			wantErr: tools.ErrRateLimited},
+		{name: "付费接口说明", body: `{"Information":"This is a premium endpoint."}`, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := alphaVantageTransport{
+				next: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+					return &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: io.NopCloser(strings.NewReader(tt.body))}, nil
+				}),
+				pacer: newRequestPacer(60), // 第一个请求不等待
+			}
+			req, _ := http.NewRequest("GET", alphaVantageBaseURL+"/query?function=OVERVIEW&symbol=IBM", nil)
+			resp, err := transport.RoundTrip(req)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("得到错误 %v，期望 %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus || resp.Header.Get("Retry-After") != tt.wantRetry {
+				t.Errorf("得到状态码 %d、Retry-After %q，期望 %d、%q", resp.StatusCode, resp.Header.Get("Retry-After"), tt.wantStatus, tt.wantRetry)
+			}
+		})
+	}
+}
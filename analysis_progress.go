@@ -0,0 +1,353 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"investment/tools"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// analysisOptions 单次分析的运行选项
+type analysisOptions struct {
+	ToolTimeout   time.Duration // 单次工具调用的执行期限
+	Transcript    string        // 报告附录中保存的分析过程
+	Progress      ProgressFunc  // 可选，接收进度事件
+	Stream        string        // 终端流式输出模式，为空时不输出到终端
+	ModelType     string        // 与 MODEL_TYPE 一致，用于选择结构化抽取的 JSON 模式
+	Prompts       PromptSet     // 系统和用户提示词
+	Market        string        // 股票所属市场（us/cn/hk），为空时按代码后缀识别
+	Depth         string        // 分析深度（quick/standard/full），为空时使用 ANALYSIS_DEPTH
+	PriorFindings string        // 可选，该股票以往分析的要点，追加到系统提示词
+	Note          string        // 可选，用户在关注列表中为该股票写的备注，追加到用户提示词
+
+	PortfolioSymbols []string              // 组合模式下组合的现有持仓，用于评估分散化
+	Checkpoint       *tools.ToolCheckpoint // 可选，服务模式任务的工具调用检查点，重启后恢复时不重复请求数据源
+	ToolQuota        *tools.ToolQuota      // 可选，服务模式任务的工具调用次数和结果大小配额
+}
+
+// analysisProgress 记录 Agent 分析过程中已产生的内容，分析超时时用于生成部分报告
+type analysisProgress struct {
+	mu          sync.Mutex
+	start       time.Time
+	contents    []string // 模型中间输出
+	toolsCalled []string // 已返回结果的工具
+	final       string   // 最终回复，正常结束时才有
+	symbol      string   // 分析的股票代码
+	plan        *AnalysisPlan
+	valuation   *tools.MonteCarloValuationOutput
+	metrics     *tools.FinancialMetricsOutput // 分析股票期数最多的一次财务指标结果，用于在报告中附加数据表
+	score       *int                          // analyze_fundamentals 的基本面评分，用于摘要卡片
+	competition *int                          // analyze_competition 的竞争地位评分，用于摘要卡片
+	management  *int                          // assess_management 的管理层质量评分，用于摘要卡片
+	priceTarget *tools.PriceTargetCheckOutput // 分析股票最近一次目标价合理性检查，附加到报告末尾
+	liquidity   *tools.LiquidityOutput        // 分析股票最近一次流动性评估，附加到报告末尾
+	quote       *tools.QuoteOutput            // 分析股票最近一次实时行情，盘前/盘后显著异动时在报告开头提示
+	returns     *tools.ShareholderReturns     // 程序计算的股东回报历史，附加到每份报告
+	returnsErr  error                         // 股东回报计算失败的原因
+	provenance  []tools.ProvenanceRecord      // 工具调用所使用数据的来源
+	facts       []tools.NumericFact           // 工具结果中的数值，用于核对报告中引用的数据
+	verify      bool                          // 保存前是否核对报告中的数值
+	transcript  string                        // 报告附录中保存的分析过程详细程度
+	messages    []*schema.Message             // Agent 的全部中间消息
+	format      tools.NumberFormat            // 程序渲染章节使用的数字格式
+	stepLimit   int                           // Agent 达到最大推理步数后由收尾步骤撰写报告时的步数上限，否则为 0
+
+	creditRiskDE float64  // 债务股权比阈值，超过时必须调用 assess_credit_risk
+	leverage     *float64 // 分析股票最新一期超过阈值的债务股权比，未超过时为空
+}
+
+// addMessage 记录 Agent 的中间消息，用于生成分析过程附录
+func (p *analysisProgress) addMessage(msg *schema.Message) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, msg)
+}
+
+// addContent 记录模型的中间输出
+func (p *analysisProgress) addContent(content string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.contents = append(p.contents, content)
+}
+
+// addToolResult 记录工具调用结果和数据来源，蒙特卡洛估值结果用于在报告中渲染估值区间
+func (p *analysisProgress) addToolResult(msg *schema.Message) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.toolsCalled = append(p.toolsCalled, msg.ToolName)
+	p.provenance = append(p.provenance, attributeImportedStatements(tools.ProvenanceFromToolResult(msg.ToolName, msg.Content, time.Now()))...)
+	p.facts = append(p.facts, tools.NumericFactsFromToolResult(msg.ToolName, msg.Content)...)
+	if msg.ToolName == "monte_carlo_valuation" {
+		var output tools.MonteCarloValuationOutput
+		if err := json.Unmarshal([]byte(msg.Content), &output); err == nil && output.Error == "" {
+			p.valuation = &output
+		}
+	}
+	if msg.ToolName == "get_financial_metrics" {
+		var output tools.FinancialMetricsOutput
+		if err := json.Unmarshal([]byte(msg.Content), &output); err == nil && output.Error == "" && len(output.Metrics) > 0 &&
+			strings.EqualFold(output.Symbol, p.symbol) {
+			if p.metrics == nil || len(output.Metrics) >= len(p.metrics.Metrics) {
+				p.metrics = &output
+			}
+			// 债务股权比超过阈值时记录，报告中检查是否做了信用风险评估
+			if output.CreditRiskRequired {
+				p.leverage = output.Metrics[0].DebtToEquity
+			}
+		}
+	}
+	if msg.ToolName == "analyze_fundamentals" {
+		var output tools.FundamentalAnalysisResponse
+		if err := json.Unmarshal([]byte(msg.Content), &output); err == nil && output.Error == "" {
+			p.score = &output.Score
+		}
+	}
+	if msg.ToolName == "analyze_competition" {
+		var output tools.CompetitiveAnalysisOutput
+		if err := json.Unmarshal([]byte(msg.Content), &output); err == nil && output.Error == "" && output.Assessment != nil {
+			p.competition = &output.Assessment.Score
+		}
+	}
+	if msg.ToolName == "check_price_target" {
+		var output tools.PriceTargetCheckOutput
+		if err := json.Unmarshal([]byte(msg.Content), &output); err == nil && output.Error == "" && strings.EqualFold(output.Symbol, p.symbol) {
+			p.priceTarget = &output
+		}
+	}
+	if msg.ToolName == "get_quote" {
+		var output tools.QuoteOutput
+		if err := json.Unmarshal([]byte(msg.Content), &output); err == nil && output.Error == "" && strings.EqualFold(output.Symbol, p.symbol) {
+			p.quote = &output
+		}
+	}
+	if msg.ToolName == "assess_liquidity" {
+		var output tools.LiquidityOutput
+		if err := json.Unmarshal([]byte(msg.Content), &output); err == nil && output.Error == "" && strings.EqualFold(output.Symbol, p.symbol) {
+			p.liquidity = &output
+		}
+	}
+	if msg.ToolName == "assess_management" {
+		var output tools.ManagementQualityOutput
+		if err := json.Unmarshal([]byte(msg.Content), &output); err == nil && output.Error == "" && strings.EqualFold(output.Symbol, p.symbol) {
+			p.management = &output.Score
+		}
+	}
+}
+
+// analysisResult 一次分析的报告，以及生成摘要卡片所需的工具结果
+type analysisResult struct {
+	Report    string
+	Score     *int                             // 基本面评分（满分 tools.FundamentalMaxScore），未调用基本面分析时为空
+	Compete   *int                             // 竞争地位评分（满分 tools.CompetitiveMaxScore），未调用竞争格局分析时为空
+	Manage    *int                             // 管理层质量评分（满分 tools.ManagementMaxScore），未调用管理层评估时为空
+	Valuation *tools.MonteCarloValuationOutput // 蒙特卡洛估值结果，未调用估值工具时为空
+	Format    tools.NumberFormat               // 报告使用的数字格式
+
+	StepLimited bool          // Agent 达到最大推理步数，报告由收尾步骤根据已收集的数据撰写
+	Plan        *AnalysisPlan // 分析前确定的工具和调用顺序
+}
+
+// result 将报告和记录的工具结果组合为分析结果
+func (p *analysisProgress) result(report string) *analysisResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return &analysisResult{Report: report, Score: p.score, Compete: p.competition, Manage: p.management, Valuation: p.valuation, Format: p.format, StepLimited: p.stepLimit > 0, Plan: p.plan}
+}
+
+// setShareholderReturns 记录后台计算的股东回报
+func (p *analysisProgress) setShareholderReturns(returns *tools.ShareholderReturns, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.returns, p.returnsErr = returns, err
+}
+
+// shareholderReturnsSection 股东回报章节；计算失败时说明原因，尚未完成时返回空字符串
+func (p *analysisProgress) shareholderReturnsSection() string {
+	if p.returns != nil {
+		return tools.RenderShareholderReturns(p.returns, p.format)
+	}
+	if p.returnsErr != nil {
+		return fmt.Sprintf("## 📈 股东回报历史\n\n> 说明: 无法计算股东回报: %v\n", p.returnsErr)
+	}
+	return ""
+}
+
+// valuationTriangulation 内置和自定义估值模型的对比章节，数据不足以让任何模型估值时返回空字符串
+func (p *analysisProgress) valuationTriangulation() string {
+	data := &tools.ValuationData{Symbol: p.symbol, Currency: tools.DefaultCurrency(), MonteCarlo: p.valuation}
+	// 季度指标的 EPS 和自由现金流只是一个季度的数值，不用于估值
+	if p.metrics != nil && p.metrics.Period != "quarterly" {
+		data.Metrics = p.metrics.Metrics
+		if currency := p.metrics.Metrics[0].Currency; currency != "" {
+			data.Currency = currency
+		}
+	}
+	switch {
+	case p.quote != nil && p.quote.Price.Valid():
+		data.Price = float64(p.quote.Price)
+	case p.priceTarget != nil && p.priceTarget.CurrentPrice.Valid():
+		data.Price = float64(p.priceTarget.CurrentPrice)
+	case p.valuation != nil && p.valuation.CurrentPrice.Valid():
+		data.Price = float64(p.valuation.CurrentPrice)
+	}
+	return tools.RenderValuationTriangulation(tools.TriangulateValuation(data), data, p.format)
+}
+
+// setFinal 记录最终回复
+func (p *analysisProgress) setFinal(content string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.final = content
+}
+
+// draft Agent 的最终回复，分章节撰写报告时作为草稿
+func (p *analysisProgress) draft() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.final
+}
+
+// setStepLimitedFinal 记录收尾步骤根据已收集数据撰写的最终回复
+func (p *analysisProgress) setStepLimitedFinal(content string, maxSteps int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.final = content
+	p.stepLimit = maxSteps
+}
+
+// gatheredData 将 Agent 已获取的工具结果和中间分析整理为 markdown，供收尾步骤撰写报告
+// 每个工具结果最多保留 maxChars 个字符，避免超出模型上下文；maxChars <= 0 时不截断
+func (p *analysisProgress) gatheredData(maxChars int) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var sb strings.Builder
+	for _, msg := range p.messages {
+		switch {
+		case msg.Role == schema.Tool:
+			content := []rune(msg.Content)
+			if maxChars > 0 && len(content) > maxChars {
+				content = append(content[:maxChars], []rune("…（已截断）")...)
+			}
+			sb.WriteString(fmt.Sprintf("### 工具结果: %s\n\n```json\n%s\n```\n\n", msg.ToolName, string(content)))
+		case msg.Role == schema.Assistant && strings.TrimSpace(msg.Content) != "":
+			sb.WriteString(fmt.Sprintf("### 中间分析\n\n%s\n\n", strings.TrimSpace(msg.Content)))
+		}
+	}
+	if sb.Len() == 0 {
+		return "（没有获取到任何数据）"
+	}
+	return sb.String()
+}
+
+// report 生成完整报告，开启数值核对时先将模型撰写的内容与工具数据核对，并附加核对附录
+func (p *analysisProgress) report() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	report := p.final
+	var claims []tools.NumericClaim
+	if p.verify {
+		report, claims = tools.VerifyReportNumbers(report, p.facts, p.format)
+	}
+	if alert := tools.RenderExtendedMoveAlert(p.quote, p.format); alert != "" {
+		report = alert + "\n" + report
+	}
+	if p.stepLimit > 0 {
+		report += fmt.Sprintf("\n\n> 说明: Agent 达到最大推理步数（%d）仍未完成分析，本报告根据已收集的数据撰写，部分分析步骤可能缺失。可调大 AGENT_MAX_STEPS 后重新分析。", p.stepLimit)
+	}
+	if p.leverage != nil && !slices.Contains(p.toolsCalled, "assess_credit_risk") {
+		report += fmt.Sprintf("\n\n> 说明: 最新一期债务股权比为 %.2f，超过 %.2f，但本次分析没有完成破产与信用风险评估（assess_credit_risk），报告中的风险结论可能低估了偿债风险。", *p.leverage, p.creditRiskDE)
+	}
+	if p.valuation != nil {
+		report += "\n\n" + tools.RenderValuationRange(p.valuation, p.format)
+	}
+	if section := p.valuationTriangulation(); section != "" {
+		report += "\n\n" + section
+	}
+	if p.priceTarget != nil {
+		report += "\n\n" + tools.RenderPriceTargetCheck(p.priceTarget, p.format)
+	}
+	if p.liquidity != nil {
+		report += "\n\n" + tools.RenderLiquidityAssessment(p.liquidity, p.format)
+	}
+	if section := p.shareholderReturnsSection(); section != "" {
+		report += "\n\n" + section
+	}
+	if p.metrics != nil {
+		report += "\n\n" + tools.RenderMetricsTable(p.metrics, p.format)
+	}
+	report += "\n\n" + tools.RenderProvenanceAppendix(p.provenance)
+	if attributions := dataLicenses().renderAttributions(p.provenance); attributions != "" {
+		report += "\n" + attributions
+	}
+	if p.verify {
+		report += "\n\n" + tools.RenderVerificationAppendix(claims, p.format)
+	}
+	if transcript := renderTranscript(p.messages, p.transcript, p.final); transcript != "" {
+		report += "\n\n" + transcript
+	}
+	// 模型可能逐字引用新闻，按数据授权条款在整份报告中省略受限的新闻原文
+	return redactNewsText(report, dataLicenses().restrictedNewsTexts(p.messages))
+}
+
+// truncatedReport 生成部分报告：已产生的分析内容加上明确的"分析已截断"说明
+func (p *analysisProgress) truncatedReport(cause error) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var sb strings.Builder
+	if alert := tools.RenderExtendedMoveAlert(p.quote, p.format); alert != "" {
+		sb.WriteString(alert + "\n")
+	}
+	if len(p.contents) > 0 {
+		sb.WriteString(strings.Join(p.contents, "\n\n"))
+		sb.WriteString("\n\n")
+	}
+	if p.valuation != nil {
+		sb.WriteString(tools.RenderValuationRange(p.valuation, p.format))
+		sb.WriteString("\n\n")
+	}
+	if section := p.shareholderReturnsSection(); section != "" {
+		sb.WriteString(section)
+		sb.WriteString("\n")
+	}
+	if p.metrics != nil {
+		sb.WriteString(tools.RenderMetricsTable(p.metrics, p.format))
+		sb.WriteString("\n")
+	}
+
+	reason := "分析被取消"
+	var quotaErr *jobQuotaError
+	if errors.Is(cause, context.DeadlineExceeded) {
+		reason = "分析超过设定的时间上限（--timeout），数据源或模型未在期限内返回"
+	} else if errors.As(cause, &quotaErr) {
+		reason = quotaErr.Error()
+	}
+	sb.WriteString("## ⚠️ 分析已截断\n\n")
+	sb.WriteString(fmt.Sprintf("- 原因: %s\n", reason))
+	sb.WriteString(fmt.Sprintf("- 已运行时间: %s\n", time.Since(p.start).Round(time.Second)))
+	if len(p.toolsCalled) > 0 {
+		sb.WriteString(fmt.Sprintf("- 已完成的工具调用: %s\n", strings.Join(p.toolsCalled, ", ")))
+	} else {
+		sb.WriteString("- 已完成的工具调用: 无\n")
+	}
+	sb.WriteString("\n以上内容仅基于截断前获取的数据，未形成完整的投资评级，请勿直接作为投资依据。可调大 --timeout / --tool-timeout 后重新分析。\n\n")
+	sb.WriteString(tools.RenderProvenanceAppendix(p.provenance))
+	if attributions := dataLicenses().renderAttributions(p.provenance); attributions != "" {
+		sb.WriteString("\n")
+		sb.WriteString(attributions)
+	}
+	if transcript := renderTranscript(p.messages, p.transcript, ""); transcript != "" {
+		sb.WriteString("\n")
+		sb.WriteString(transcript)
+	}
+	return redactNewsText(sb.String(), dataLicenses().restrictedNewsTexts(p.messages))
+}
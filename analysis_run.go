@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"investment/tools"
+
+	"github.com/cloudwego/eino/components/model"
+)
+
+// analysisRequest 一次完整分析的参数，分析完成后保存报告和运行记录
+type analysisRequest struct {
+	Symbol    string
+	Portfolio string
+	Tags      []string
+	Timeout   time.Duration // 整个分析的最长时间，0 表示不限制
+	Options   analysisOptions
+	Snapshot  *snapshotRecorder // 可选，分析期间录制的请求，完成后保存为快照包
+	Bus       *eventBus         // 可选，发布分析结果和工具遥测的消息总线
+	WarmStart bool              // 把该股票以往分析的要点和交易日志注入系统提示词，完成后把本次结论写入知识库（KNOWLEDGE_BASE=false 时关闭知识库）
+}
+
+// createChatModel 根据 MODEL_TYPE 创建聊天模型，返回模型和模型类型
+func createChatModel(ctx context.Context) (model.ToolCallingChatModel, string) {
+	modelType := os.Getenv("MODEL_TYPE")
+	return createChatModelOfType(ctx, modelType), modelType
+}
+
+// createChatModelOfType 创建指定类型的聊天模型，未知类型按 deepseek 处理
+func createChatModelOfType(ctx context.Context, modelType string) model.ToolCallingChatModel {
+	var chatModel model.ToolCallingChatModel
+	switch modelType {
+	case "gemini":
+		chatModel = createGeminiChatModel(ctx)
+	case "openai":
+		chatModel = createOpenAIChatModel(ctx)
+	case "deepseek":
+		chatModel = createDeepseekChatModel(ctx)
+	default:
+		chatModel = createDeepseekChatModel(ctx)
+	}
+	log.Printf("Using model: %s", modelType)
+	return chatModel
+}
+
+// runAnalysis 执行一次分析，保存 markdown 报告（及可选的结构化结论）和运行记录
+// 超时时保存带截断说明的部分报告，运行记录标记为 truncated
+func runAnalysis(ctx context.Context, chatModel model.ToolCallingChatModel, req analysisRequest) (*RunRecord, error) {
+	// 整个分析的截止时间，数据源或模型卡住时不会无限等待
+	analysisCtx := ctx
+	if req.Timeout > 0 {
+		var cancel context.CancelFunc
+		analysisCtx, cancel = context.WithTimeout(ctx, req.Timeout)
+		defer cancel()
+	}
+
+	// 记录本次运行的元数据，用于按组合和标签查询历史分析，并追溯使用的提示词和配置版本
+	run := &RunRecord{
+		ID:            fmt.Sprintf("%s_%s", req.Symbol, time.Now().Format("2006-01-02_15-04-05")),
+		Symbol:        req.Symbol,
+		Portfolio:     req.Portfolio,
+		Tags:          req.Tags,
+		Note:          req.Options.Note,
+		Model:         req.Options.ModelType,
+		PromptVersion: req.Options.Prompts.Version,
+		ConfigVersion: appEnv.version(),
+		StartedAt:     time.Now(),
+	}
+	// 本次运行的日志带上运行 ID 和股票代码，随 context 传给 Agent、工具和数据获取函数
+	logger := tools.NewRunLogger(run.ID, req.Symbol)
+	analysisCtx = tools.WithLogger(analysisCtx, logger)
+	cacheStart := sharedAPICache().Stats()
+	budget := retryBudgetFromEnv()
+	analysisCtx = withRetryBudget(analysisCtx, budget)
+
+	// 组合模式下把现有持仓告诉 Agent，评估加入该股票后的分散化
+	if req.Portfolio != "" {
+		portfolio, err := LoadPortfolio(req.Portfolio)
+		if err != nil {
+			logger.Printf("读取组合 %s 失败: %v", req.Portfolio, err)
+		} else {
+			req.Options.PortfolioSymbols = portfolio.Symbols()
+		}
+	}
+
+	// 已有该股票的历史分析时，让 Agent 在以往的结论和待解决问题基础上继续分析
+	warmStart := req.WarmStart && knowledgeBaseEnabled()
+	if warmStart {
+		knowledge, err := loadCompanyKnowledge(req.Symbol)
+		if err != nil {
+			logger.Printf("[Knowledge] %v", err)
+		} else if knowledge != nil {
+			req.Options.PriorFindings = knowledge.promptContext()
+			run.PriorRuns = min(len(knowledge.Entries), knowledgePromptRuns)
+			logger.Printf("[Knowledge] 注入最近 %d 次分析的要点", run.PriorRuns)
+		}
+	}
+
+	// 用户在交易日志中记录过该股票的决策时，让 Agent 检验以前的投资逻辑是否仍然成立，报告末尾附上原文
+	var journal *TradeJournal
+	if req.WarmStart {
+		var err error
+		journal, err = loadTradeJournal(req.Symbol)
+		if err != nil {
+			logger.Printf("[Journal] %v", err)
+		} else if journal != nil {
+			req.Options.PriorFindings = strings.TrimSpace(req.Options.PriorFindings + "\n\n" + journal.promptContext())
+			run.JournalEntries = min(len(journal.Entries), journalPromptEntries)
+			logger.Printf("[Journal] 注入最近 %d 条投资决策", run.JournalEntries)
+		}
+	}
+
+	// 配置了消息总线时，工具调用事件同时发布为遥测
+	req.Options.Progress = req.Bus.withToolTelemetry(run.ID, req.Symbol, req.Options.Progress)
+
+	// 使用 React Agent 进行分析
+	result, err := analyzeWithReactAgent(analysisCtx, chatModel, req.Symbol, req.Options)
+	if err != nil {
+		return nil, err
+	}
+	run.StepLimited = result.StepLimited
+	run.Plan = result.Plan
+	var quotaErr *jobQuotaError
+	if errors.Is(analysisCtx.Err(), context.DeadlineExceeded) {
+		run.Truncated = true
+		fmt.Printf("⚠️ 分析超过 %s 未完成，已生成部分报告\n", req.Timeout)
+	} else if errors.As(context.Cause(analysisCtx), &quotaErr) {
+		run.Truncated = true
+		fmt.Printf("⚠️ %v，已生成部分报告\n", quotaErr)
+	}
+
+	if skipped := budget.Skipped(); len(skipped) > 0 {
+		run.SkippedData = skipped
+		result.Report += budget.reportNote()
+		logger.Printf("[RetryBudget] 限流重试等待 %s 已达上限，跳过 %d 份数据: %s", budget.Waited().Round(time.Second), len(skipped), strings.Join(skipped, ", "))
+	}
+
+	if journal != nil {
+		result.Report += journal.renderPreviousThesis()
+	}
+
+	// 输出分析结果
+	fmt.Print(strings.Repeat("=", 50) + "\n")
+	fmt.Printf("✅ 分析完成\n")
+
+	// 保存分析结果为 markdown 文件
+	reportPath, err := saveReportAsMarkdown(req.Symbol, req.Portfolio, result.Report)
+	if err != nil {
+		return nil, fmt.Errorf("保存报告失败: %v", err)
+	}
+	fmt.Printf("📄 报告已保存: %s\n", reportPath)
+
+	// 设置 SUMMARY_CARD=true 时生成一页摘要卡片，最新收盘价在保存快照前获取，回放快照时同样可用
+	summaryCard := os.Getenv("SUMMARY_CARD") == "true"
+	var price *float64
+	if summaryCard {
+		if last, err := latestClose(ctx, req.Symbol); err != nil {
+			logger.Printf("获取 %s 最新价格失败，摘要卡片不显示价格: %v", req.Symbol, err)
+		} else {
+			price = &last
+		}
+	}
+
+	run.FinishedAt = time.Now()
+	run.ReportPath = reportPath
+	if cache := sharedAPICache(); cache != nil {
+		stats := cache.Stats().Sub(cacheStart)
+		run.Cache = &stats
+		logger.Printf("[APICache] %s", stats)
+	}
+	if req.Snapshot != nil {
+		snapshotPath := filepath.Join(snapshotsDir, run.ID+".zip")
+		err := req.Snapshot.save(snapshotPath, SnapshotManifest{
+			RunID:     run.ID,
+			Symbol:    run.Symbol,
+			Model:     run.Model,
+			CreatedAt: run.FinishedAt,
+		})
+		if err != nil {
+			logger.Printf("保存数据快照失败: %v", err)
+		} else {
+			run.SnapshotPath = snapshotPath
+		}
+	}
+	run.Rating = extractRating(result.Report)
+	// 设置 STRUCTURED_REPORT=true 时，使用 JSON 模式从报告中抽取结构化结论，评级以结构化结果为准；
+	// 摘要卡片的优势和风险、知识库的要点同样来自结构化结论，只开启 SUMMARY_CARD 或知识库时抽取但不单独保存
+	var structured *StructuredReport
+	saveStructured := os.Getenv("STRUCTURED_REPORT") == "true"
+	if (saveStructured || summaryCard || warmStart) && !run.Truncated {
+		structured, err = extractStructuredReport(ctx, newStructuredGenerator(chatModel, req.Options.ModelType), req.Symbol, result.Report)
+		if err != nil {
+			logger.Printf("生成结构化报告失败: %v", err)
+		} else {
+			run.Rating = structured.Rating
+			if saveStructured {
+				if path, err := saveStructuredReport(reportName(req.Symbol, req.Portfolio), structured); err != nil {
+					logger.Printf("保存结构化报告失败: %v", err)
+				} else {
+					fmt.Printf("🧾 结构化结论已保存: %s\n", path)
+				}
+			}
+		}
+	}
+	if warmStart && structured != nil {
+		if err := recordKnowledge(run, structured); err != nil {
+			logger.Printf("[Knowledge] 记录分析要点失败: %v", err)
+		}
+	}
+	var card *SummaryCard
+	if summaryCard {
+		card = buildSummaryCard(run, result, structured, price)
+		if path, err := saveSummaryCard(reportName(req.Symbol, req.Portfolio), card, result.Format); err != nil {
+			logger.Printf("保存摘要卡片失败: %v", err)
+		} else {
+			fmt.Printf("🪪 摘要卡片已保存: %s\n", path)
+			run.CardPath = path
+		}
+	}
+	if err := saveRunRecord(run); err != nil {
+		logger.Printf("保存运行记录失败: %v", err)
+	}
+	req.Bus.publishRun(run, structured, card)
+	return run, nil
+}
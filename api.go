@@ -2,15 +2,19 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
-	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"investment/provider"
 	"investment/tools"
 )
 
@@ -67,26 +71,9 @@ type LineItemResponse struct {
 	SearchResults []LineItem `json:"search_results"`
 }
 
-// InsiderTrade 结构体
-type InsiderTrade struct {
-	Ticker                       string   `json:"ticker"`
-	Issuer                       *string  `json:"issuer"`
-	Name                         *string  `json:"name"`
-	Title                        *string  `json:"title"`
-	IsBoardDirector              *bool    `json:"is_board_director"`
-	TransactionDate              *string  `json:"transaction_date"`
-	TransactionShares            *float64 `json:"transaction_shares"`
-	TransactionPricePerShare     *float64 `json:"transaction_price_per_share"`
-	TransactionValue             *float64 `json:"transaction_value"`
-	SharesOwnedBeforeTransaction *float64 `json:"shares_owned_before_transaction"`
-	SharesOwnedAfterTransaction  *float64 `json:"shares_owned_after_transaction"`
-	SecurityTitle                *string  `json:"security_title"`
-	FilingDate                   string   `json:"filing_date"`
-}
-
 // InsiderTradeResponse 结构体
 type InsiderTradeResponse struct {
-	InsiderTrades []InsiderTrade `json:"insider_trades"`
+	InsiderTrades []tools.InsiderTrade `json:"insider_trades"`
 }
 
 // CompanyNews 结构体
@@ -100,41 +87,37 @@ type CompanyNews struct {
 	Sentiment *string `json:"sentiment"`
 }
 
-// CompanyFacts 结构体
-type CompanyFacts struct {
-	Ticker                string  `json:"ticker"`
-	Name                  string  `json:"name"`
-	CIK                   string  `json:"cik"`
-	Industry              string  `json:"industry"`
-	Sector                string  `json:"sector"`
-	Category              string  `json:"category"`
-	Exchange              string  `json:"exchange"`
-	IsActive              bool    `json:"is_active"`
-	ListingDate           string  `json:"listing_date"`
-	Location              string  `json:"location"`
-	MarketCap             float64 `json:"market_cap"`
-	NumberOfEmployees     int     `json:"number_of_employees"`
-	SecFilingsURL         string  `json:"sec_filings_url"`
-	SicCode               string  `json:"sic_code"`
-	SicIndustry           string  `json:"sic_industry"`
-	SicSector             string  `json:"sic_sector"`
-	WebsiteURL            string  `json:"website_url"`
-	WeightedAverageShares int     `json:"weighted_average_shares"`
-}
-
 // CompanyFactsResponse 结构体
 type CompanyFactsResponse struct {
-	CompanyFacts CompanyFacts `json:"company_facts"`
+	CompanyFacts provider.CompanyFacts `json:"company_facts"`
+}
+
+// FilingItem 结构体（SEC 文件中的章节）
+type FilingItem struct {
+	Number string `json:"number"`
+	Name   string `json:"name"`
+	Text   string `json:"text"`
 }
 
+// FilingItemsResponse 结构体
+type FilingItemsResponse struct {
+	Ticker     string       `json:"ticker"`
+	FilingType string       `json:"filing_type"`
+	Year       int          `json:"year"`
+	URL        string       `json:"url"`
+	Items      []FilingItem `json:"items"`
+}
+
+// cli 不属于数据提供方的请求（如券商持仓导入）使用的客户端，数据请求使用 dataAPIClient
 var cli *http.Client
 
 func init() {
-	cli = &http.Client{Timeout: 30 * time.Second}
+	cli = newHTTPClient(30 * time.Second)
 }
 
-// makeAPIRequest 执行 API 请求，带有重试和限流处理
-func makeAPIRequest(url string, headers map[string]string, method string, jsonData map[string]any, maxRetries int) (*http.Response, error) {
+// makeAPIRequest 执行 API 请求，带有重试和限流处理；ctx 取消时请求和重试前的等待都立即结束
+// 上一次响应的 X-RateLimit-Remaining 为 0 且尚未到重置时间时，先等到重置再请求，等待和 429 退避一样计入重试预算
+func makeAPIRequest(ctx context.Context, client *http.Client, url string, headers map[string]string, method string, jsonData map[string]any, maxRetries int) (*http.Response, error) {
 
 	for attempt := 0; attempt <= maxRetries; attempt++ {
 		var req *http.Request
@@ -145,13 +128,13 @@ func makeAPIRequest(url string, headers map[string]string, method string, jsonDa
 			if err != nil {
 				return nil, fmt.Errorf("序列化 JSON 数据失败: %w", err)
 			}
-			req, err = http.NewRequest("POST", url, bytes.NewBuffer(body))
+			req, err = http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
 			if err != nil {
 				return nil, fmt.Errorf("创建 POST 请求失败: %w", err)
 			}
 			req.Header.Set("Content-Type", "application/json")
 		} else {
-			req, err = http.NewRequest("GET", url, nil)
+			req, err = http.NewRequestWithContext(ctx, "GET", url, nil)
 			if err != nil {
 				return nil, fmt.Errorf("创建 GET 请求失败: %w", err)
 			}
@@ -162,45 +145,68 @@ func makeAPIRequest(url string, headers map[string]string, method string, jsonDa
 			req.Header.Set(key, value)
 		}
 
-		resp, err := cli.Do(req)
+		if delay := quotaWait(req.URL.Host, time.Now()); delay > 0 {
+			if !retryBudgetFrom(ctx).reserve(url, delay) {
+				return nil, errRetryBudgetExhausted(url)
+			}
+			fmt.Printf("%s 的限流配额已用完，等待 %s 配额重置后请求...\n", req.URL.Host, delay.Round(time.Second))
+			if err := sleepContext(ctx, delay); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := client.Do(req)
 		if err != nil {
 			return nil, fmt.Errorf("执行 HTTP 请求失败: %w", err)
 		}
 
+		recordRateLimitQuota(resp, time.Now())
+
 		if resp.StatusCode == 429 && attempt < maxRetries {
-			// 线性退避：60s, 90s, 120s, 150s...
-			delay := 60 + (30 * attempt)
-			fmt.Printf("接收到限流响应 (429)。尝试 %d/%d。等待 %ds 后重试...\n", attempt+1, maxRetries+1, delay)
+			// 按 Retry-After / X-RateLimit-Reset 指示的时间等待，没有时线性退避
+			delay := retryDelay(resp, attempt, time.Now())
+			// 整个分析共用重试预算，用完后不再等待，跳过这份数据
+			if !retryBudgetFrom(ctx).reserve(url, delay) {
+				resp.Body.Close()
+				return nil, errRetryBudgetExhausted(url)
+			}
+			fmt.Printf("接收到限流响应 (429)。尝试 %d/%d。等待 %s 后重试...\n", attempt+1, maxRetries+1, delay.Round(time.Second))
 			resp.Body.Close()
-			time.Sleep(time.Duration(delay) * time.Second)
+			if err := sleepContext(ctx, delay); err != nil {
+				return nil, err
+			}
 			continue
 		}
 
 		return resp, nil
 	}
 
-	return nil, fmt.Errorf("在 %d 次重试后仍然失败", maxRetries)
+	return nil, fmt.Errorf("在 %d 次重试后仍然失败: %w", maxRetries, tools.ErrRateLimited)
 }
 
-// GetPrices 获取价格数据
-func GetPrices(ticker, startDate, endDate string, apiKey ...string) ([]Price, error) {
-	// 准备 API 请求
-	headers := make(map[string]string)
-	financialAPIKey := ""
-	if len(apiKey) > 0 && apiKey[0] != "" {
-		financialAPIKey = apiKey[0]
-	} else {
-		financialAPIKey = os.Getenv("FINANCIAL_DATASETS_API_KEY")
+// GetPrices 通过当前的数据提供方获取价格数据，检查数据缺口
+func GetPrices(ctx context.Context, ticker, startDate, endDate string) ([]provider.Price, error) {
+	prices, err := dataSource().Prices(ctx, ticker, startDate, endDate)
+	if err != nil {
+		return nil, err
 	}
-
-	if financialAPIKey != "" {
-		headers["X-API-KEY"] = financialAPIKey
+	if len(prices) == 0 {
+		return nil, fmt.Errorf("%s 在 %s ~ %s 的价格: %w", ticker, startDate, endDate, tools.ErrNoData)
 	}
+	storeData("价格", func(s *dataStore) error { return s.savePrices(ticker, prices) })
+	if gaps := detectPriceGaps(prices, maxPriceGapDays); len(gaps) > 0 {
+		for _, gap := range gaps {
+			log.Printf("[Prices] %s 价格数据存在缺口: %s ~ %s (%d 天)", ticker, gap.From.Format("2006-01-02"), gap.To.Format("2006-01-02"), gap.Days)
+		}
+	}
+	return prices, nil
+}
 
-	url := fmt.Sprintf("https://api.financialdatasets.ai/prices/?ticker=%s&interval=day&interval_multiplier=1&start_date=%s&end_date=%s",
-		ticker, startDate, endDate)
-
-	resp, err := makeAPIRequest(url, headers, "GET", nil, 3)
+// GetPriceSnapshot 获取实时行情快照
+// 实时数据不经过响应缓存，每次调用都会请求数据源
+func GetPriceSnapshot(ctx context.Context, ticker string) (*PriceSnapshot, error) {
+	endpoint := fmt.Sprintf("/prices/snapshot/?ticker=%s", ticker)
+	resp, err := dataAPIClient().do(ctx, providerFinancialDatasets, "GET", endpoint, nil, false)
 	if err != nil {
 		return nil, fmt.Errorf("API 请求失败: %w", err)
 	}
@@ -208,7 +214,7 @@ func GetPrices(ticker, startDate, endDate string, apiKey ...string) ([]Price, er
 
 	if resp.StatusCode != 200 {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("获取数据错误: %s - %d - %s", ticker, resp.StatusCode, string(body))
+		return nil, fmt.Errorf("获取实时行情错误: %s: %w", ticker, tools.StatusError(resp.StatusCode, body))
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -216,72 +222,76 @@ func GetPrices(ticker, startDate, endDate string, apiKey ...string) ([]Price, er
 		return nil, fmt.Errorf("读取响应体失败: %w", err)
 	}
 
-	var priceResponse PriceResponse
-	if err := json.Unmarshal(body, &priceResponse); err != nil {
-		return nil, fmt.Errorf("解析价格响应失败: %w", err)
+	var snapshotResponse PriceSnapshotResponse
+	if err := json.Unmarshal(body, &snapshotResponse); err != nil {
+		return nil, fmt.Errorf("解析实时行情响应失败: %w", err)
 	}
-
-	if len(priceResponse.Prices) == 0 {
-		return []Price{}, nil
+	if snapshotResponse.Snapshot.Price <= 0 {
+		return nil, fmt.Errorf("%s 的实时行情: %w", ticker, tools.ErrNoData)
 	}
-	return priceResponse.Prices, nil
+
+	return &snapshotResponse.Snapshot, nil
 }
 
-// GetFinancialMetrics 获取财务指标数据
-func GetFinancialMetrics(ticker, endDate string, period string, limit int, apiKey ...string) ([]tools.FinancialMetrics, error) {
-	if period == "" {
-		period = "ttm"
-	}
-	if limit == 0 {
-		limit = 10
-	}
+// maxPriceGapDays 相邻交易日间隔超过该天数视为数据缺口（长假期最多约 4-5 天不交易）
+const maxPriceGapDays = 7
 
-	// 准备 API 请求
-	headers := make(map[string]string)
-	financialAPIKey := ""
-	if len(apiKey) > 0 && apiKey[0] != "" {
-		financialAPIKey = apiKey[0]
-	} else {
-		financialAPIKey = os.Getenv("FINANCIAL_DATASETS_API_KEY")
-	}
+// PriceGap 价格数据中的缺口
+type PriceGap struct {
+	From time.Time
+	To   time.Time
+	Days int
+}
 
-	if financialAPIKey != "" {
-		headers["X-API-KEY"] = financialAPIKey
+// detectPriceGaps 检查相邻交易日间隔超过 maxDays 天的缺口
+func detectPriceGaps(prices []provider.Price, maxDays int) []PriceGap {
+	dates := make([]time.Time, 0, len(prices))
+	for _, price := range prices {
+		date, err := time.Parse(time.RFC3339, price.Time)
+		if err != nil {
+			if date, err = time.Parse("2006-01-02", price.Time); err != nil {
+				continue
+			}
+		}
+		dates = append(dates, date)
 	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
 
-	url := fmt.Sprintf("https://api.financialdatasets.ai/financial-metrics/?ticker=%s&report_period_lte=%s&limit=%d&period=%s",
-		ticker, endDate, limit, period)
-
-	resp, err := makeAPIRequest(url, headers, "GET", nil, 3)
-	if err != nil {
-		return nil, fmt.Errorf("API 请求失败: %w", err)
+	var gaps []PriceGap
+	for i := 1; i < len(dates); i++ {
+		days := int(dates[i].Sub(dates[i-1]).Hours() / 24)
+		if days > maxDays {
+			gaps = append(gaps, PriceGap{From: dates[i-1], To: dates[i], Days: days})
+		}
 	}
-	defer resp.Body.Close()
+	return gaps
+}
 
-	if resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("获取数据错误: %s - %d - %s", ticker, resp.StatusCode, string(body))
+// GetFinancialMetrics 获取财务指标数据，用户导入了报表时由报表计算，否则请求当前的数据提供方
+func GetFinancialMetrics(ctx context.Context, ticker, endDate string, period string, limit int) ([]tools.FinancialMetrics, error) {
+	if period == "" {
+		period = "ttm"
 	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("读取响应体失败: %w", err)
+	if limit == 0 {
+		limit = 10
 	}
 
-	var metricsResponse FinancialMetricsResponse
-	if err := json.Unmarshal(body, &metricsResponse); err != nil {
-		return nil, fmt.Errorf("解析财务指标响应失败: %w", err)
+	if statements, err := loadCompanyStatements(ticker); err != nil {
+		return nil, err
+	} else if statements != nil {
+		return statements.financialMetrics(endDate, period, limit)
 	}
 
-	if len(metricsResponse.FinancialMetrics) == 0 {
-		return []tools.FinancialMetrics{}, nil
+	metrics, err := dataSource().Metrics(ctx, ticker, endDate, period, limit)
+	if err != nil {
+		return nil, err
 	}
-
-	return metricsResponse.FinancialMetrics, nil
+	storeData("财务指标", func(s *dataStore) error { return s.saveMetrics(ticker, metrics) })
+	return metrics, nil
 }
 
 // SearchLineItems 搜索行项目数据
-func SearchLineItems(ticker string, lineItems []string, endDate, period string, limit int, apiKey ...string) ([]LineItem, error) {
+func SearchLineItems(ctx context.Context, ticker string, lineItems []string, endDate, period string, limit int) ([]LineItem, error) {
 	if period == "" {
 		period = "ttm"
 	}
@@ -289,20 +299,13 @@ func SearchLineItems(ticker string, lineItems []string, endDate, period string,
 		limit = 10
 	}
 
-	// 准备 API 请求
-	headers := make(map[string]string)
-	financialAPIKey := ""
-	if len(apiKey) > 0 && apiKey[0] != "" {
-		financialAPIKey = apiKey[0]
-	} else {
-		financialAPIKey = os.Getenv("FINANCIAL_DATASETS_API_KEY")
-	}
-
-	if financialAPIKey != "" {
-		headers["X-API-KEY"] = financialAPIKey
+	if statements, err := loadCompanyStatements(ticker); err != nil {
+		return nil, err
+	} else if statements != nil {
+		return statements.lineItems(lineItems, endDate, period, limit), nil
 	}
 
-	url := "https://api.financialdatasets.ai/financials/search/line-items"
+	endpoint := "/financials/search/line-items"
 
 	body := map[string]any{
 		"tickers":    []string{ticker},
@@ -312,7 +315,7 @@ func SearchLineItems(ticker string, lineItems []string, endDate, period string,
 		"limit":      limit,
 	}
 
-	resp, err := makeAPIRequest(url, headers, "POST", body, 3)
+	resp, err := fetchFinancialDatasets(ctx, "POST", endpoint, body)
 	if err != nil {
 		return nil, fmt.Errorf("API 请求失败: %w", err)
 	}
@@ -320,7 +323,7 @@ func SearchLineItems(ticker string, lineItems []string, endDate, period string,
 
 	if resp.StatusCode != 200 {
 		responseBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("获取数据错误: %s - %d - %s", ticker, resp.StatusCode, string(responseBody))
+		return nil, fmt.Errorf("获取数据错误: %s: %w", ticker, tools.StatusError(resp.StatusCode, responseBody))
 	}
 
 	responseBody, err := io.ReadAll(resp.Body)
@@ -345,185 +348,87 @@ func SearchLineItems(ticker string, lineItems []string, endDate, period string,
 	return lineItemResponse.SearchResults, nil
 }
 
-// GetInsiderTrades 获取内部交易数据
-func GetInsiderTrades(ticker, endDate string, startDate *string, limit int, apiKey ...string) ([]InsiderTrade, error) {
-	if limit == 0 {
-		limit = 1000
-	}
-
-	// 创建缓存键
-	startDateStr := "none"
-	if startDate == nil {
-		startDateStr = time.Now().AddDate(0, 0, -30).Format("2006-01-02")
-	}
-
-	// 准备 API 请求
-	headers := make(map[string]string)
-	financialAPIKey := ""
-	if len(apiKey) > 0 && apiKey[0] != "" {
-		financialAPIKey = apiKey[0]
-	} else {
-		financialAPIKey = os.Getenv("FINANCIAL_DATASETS_API_KEY")
-	}
-
-	if financialAPIKey != "" {
-		headers["X-API-KEY"] = financialAPIKey
-	}
-
-	var allTrades []InsiderTrade
-	currentEndDate := endDate
-
-	for {
-		url := fmt.Sprintf("https://api.financialdatasets.ai/insider-trades/?ticker=%s&filing_date_lte=%s", ticker, currentEndDate)
-		if startDate != nil {
-			url += fmt.Sprintf("&filing_date_gte=%s", startDateStr)
-		}
-		url += fmt.Sprintf("&limit=%d", limit)
-
-		resp, err := makeAPIRequest(url, headers, "GET", nil, 3)
-		if err != nil {
-			return nil, fmt.Errorf("API 请求失败: %w", err)
-		}
-
-		if resp.StatusCode != 200 {
-			body, _ := io.ReadAll(resp.Body)
-			resp.Body.Close()
-			return nil, fmt.Errorf("获取数据错误: %s - %d - %s", ticker, resp.StatusCode, string(body))
-		}
-
-		body, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			return nil, fmt.Errorf("读取响应体失败: %w", err)
-		}
-
-		var tradeResponse InsiderTradeResponse
-		if err := json.Unmarshal(body, &tradeResponse); err != nil {
-			return nil, fmt.Errorf("解析内部交易响应失败: %w", err)
-		}
-
-		if len(tradeResponse.InsiderTrades) == 0 {
-			break
-		}
-
-		allTrades = append(allTrades, tradeResponse.InsiderTrades...)
-
-		// 只有在设置了开始日期且获得了完整页面时才继续分页
-		if startDate == nil || len(tradeResponse.InsiderTrades) < limit {
-			break
-		}
-
-		// 更新下一次迭代的结束日期
-		minDate := tradeResponse.InsiderTrades[0].FilingDate
-		for _, trade := range tradeResponse.InsiderTrades {
-			if trade.FilingDate < minDate {
-				minDate = trade.FilingDate
-			}
-		}
-
-		// 提取日期部分（去除时间）
-		if strings.Contains(minDate, "T") {
-			minDate = strings.Split(minDate, "T")[0]
-		}
-		currentEndDate = minDate
+// 内部交易分页的默认保护条件，可以用 INSIDER_TRADES_MAX_RECORDS / INSIDER_TRADES_MAX_WINDOW_DAYS 调整
+const (
+	defaultInsiderTradesMaxRecords    = 2000
+	defaultInsiderTradesMaxWindowDays = 5 * 365
+)
 
-		// 如果已达到或超过开始日期，停止
-		if startDate != nil && currentEndDate <= *startDate {
-			break
-		}
+// GetInsiderTrades 获取内部交易数据，返回的 truncated 不为空时说明数据因条数上限或时间窗口被截断
+// startDate 为 nil 时只获取截至 endDate 的一页数据
+func GetInsiderTrades(ctx context.Context, ticker, endDate string, startDate *string, limit int) ([]tools.InsiderTrade, string, error) {
+	var allTrades []tools.InsiderTrade
+	truncated, err := ForEachInsiderTradesPage(ctx, ticker, endDate, startDate, limit, func(page []tools.InsiderTrade) error {
+		allTrades = append(allTrades, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
 	}
 
 	if len(allTrades) == 0 {
-		return []InsiderTrade{}, nil
+		return []tools.InsiderTrade{}, truncated, nil
 	}
-	return allTrades, nil
+	return allTrades, truncated, nil
 }
 
-// GetCompanyNews 获取公司新闻数据
-func GetCompanyNews(ticker, endDate string, startDate *string, limit int, apiKey ...string) ([]tools.CompanyNews, error) {
+// ForEachInsiderTradesPage 分页获取内部交易数据，每获取一页调用一次 handle，不在内存中累积全部数据
+// startDate 为 nil 时只获取截至 endDate 的一页数据；handle 返回错误时停止分页；数据被截断时返回说明（见 provider.DataProvider.InsiderTrades）
+func ForEachInsiderTradesPage(ctx context.Context, ticker, endDate string, startDate *string, limit int, handle func(page []tools.InsiderTrade) error) (string, error) {
 	if limit == 0 {
 		limit = 1000
 	}
+	return dataSource().InsiderTrades(ctx, ticker, endDate, startDate, limit, func(page []tools.InsiderTrade) error {
+		storeData("内部人交易", func(s *dataStore) error { return s.saveInsiderTrades(ticker, page) })
+		return handle(page)
+	})
+}
 
-	startDateStr := "none"
-	if startDate == nil {
-		startDateStr = time.Now().AddDate(0, 0, 30).Format("2006-01-02")
+// insiderTradeKey 用于分页去重的交易标识
+func insiderTradeKey(trade tools.InsiderTrade) string {
+	str := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
 	}
-
-	// 准备 API 请求
-	headers := make(map[string]string)
-	financialAPIKey := ""
-	if len(apiKey) > 0 && apiKey[0] != "" {
-		financialAPIKey = apiKey[0]
-	} else {
-		financialAPIKey = os.Getenv("FINANCIAL_DATASETS_API_KEY")
+	num := func(f *float64) string {
+		if f == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*f, 'f', -1, 64)
 	}
+	return strings.Join([]string{trade.FilingDate, str(trade.Name), str(trade.TransactionDate), num(trade.TransactionShares), num(trade.TransactionPricePerShare), str(trade.SecurityTitle)}, "|")
+}
 
-	if financialAPIKey != "" {
-		headers["X-API-KEY"] = financialAPIKey
+// newsKey 用于分页去重的新闻标识，没有 ID 时使用链接，再退化为标题和发布时间
+func newsKey(news tools.CompanyNews) string {
+	if news.ID != "" {
+		return news.ID
 	}
+	if news.URL != "" {
+		return news.URL
+	}
+	return news.Title + "|" + news.DateTime
+}
 
-	var allNews []tools.CompanyNews
-	currentEndDate := endDate
-
-	for {
-		url := fmt.Sprintf("https://api.financialdatasets.ai/news/?ticker=%s&end_date=%s", ticker, currentEndDate)
-		if startDate != nil {
-			url += fmt.Sprintf("&start_date=%s", startDateStr)
-		}
-		url += fmt.Sprintf("&limit=%d", limit)
-
-		resp, err := makeAPIRequest(url, headers, "GET", nil, 3)
-		if err != nil {
-			return nil, fmt.Errorf("API 请求失败: %w", err)
-		}
-
-		if resp.StatusCode != 200 {
-			body, _ := io.ReadAll(resp.Body)
-			resp.Body.Close()
-			return nil, fmt.Errorf("获取数据错误: %s - %d - %s", ticker, resp.StatusCode, string(body))
-		}
-
-		body, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			return nil, fmt.Errorf("读取响应体失败: %w", err)
-		}
-
-		var newsResponse CompanyNewsResponse
-		if err := json.Unmarshal(body, &newsResponse); err != nil {
-			return nil, fmt.Errorf("解析公司新闻响应失败: %w", err)
-		}
-
-		if len(newsResponse.News) == 0 {
-			break
-		}
-
-		allNews = append(allNews, newsResponse.News...)
-
-		// 只有在设置了开始日期且获得了完整页面时才继续分页
-		if startDate == nil || len(newsResponse.News) < limit {
-			break
-		}
-
-		// 更新下一次迭代的结束日期
-		minDate := newsResponse.News[0].DateTime
-		for _, news := range newsResponse.News {
-			if news.DateTime < minDate {
-				minDate = news.DateTime
-			}
-		}
-
-		// 提取日期部分（去除时间）
-		if strings.Contains(minDate, "T") {
-			minDate = strings.Split(minDate, "T")[0]
-		}
-		currentEndDate = minDate
+// errNewsLimitReached 已获取 limit 条新闻，用于提前结束分页
+var errNewsLimitReached = errors.New("已获取足够的新闻")
 
-		// 如果已达到或超过开始日期，停止
-		if startDate != nil && currentEndDate <= *startDate {
-			break
+// GetCompanyNews 获取公司新闻数据，最多返回 limit 条
+// startDate 为 nil 时只获取截至 endDate 的一页数据；按开始日期分页时获取到 limit 条即停止翻页
+func GetCompanyNews(ctx context.Context, ticker, endDate string, startDate *string, limit int) ([]tools.CompanyNews, error) {
+	var allNews []tools.CompanyNews
+	err := ForEachCompanyNewsPage(ctx, ticker, endDate, startDate, limit, func(page []tools.CompanyNews) error {
+		allNews = append(allNews, page...)
+		if limit > 0 && len(allNews) >= limit {
+			allNews = allNews[:limit]
+			return errNewsLimitReached
 		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errNewsLimitReached) {
+		return nil, err
 	}
 
 	if len(allNews) == 0 {
@@ -532,79 +437,87 @@ func GetCompanyNews(ticker, endDate string, startDate *string, limit int, apiKey
 	return allNews, nil
 }
 
-// GetMarketCap 获取市值数据
-func GetMarketCap(ticker, endDate string, apiKey ...string) (float64, error) {
-	// 检查是否是今天
-	today := time.Now().Format("2006-01-02")
-	if endDate == today {
-		// 从公司事实 API 获取市值
-		headers := make(map[string]string)
-		financialAPIKey := ""
-		if len(apiKey) > 0 && apiKey[0] != "" {
-			financialAPIKey = apiKey[0]
-		} else {
-			financialAPIKey = os.Getenv("FINANCIAL_DATASETS_API_KEY")
-		}
-
-		if financialAPIKey != "" {
-			headers["X-API-KEY"] = financialAPIKey
-		}
-
-		url := fmt.Sprintf("https://api.financialdatasets.ai/company/facts/?ticker=%s", ticker)
-		resp, err := makeAPIRequest(url, headers, "GET", nil, 3)
-		if err != nil {
-			return 0, fmt.Errorf("API 请求失败: %w", err)
-		}
-		defer resp.Body.Close()
+// ForEachCompanyNewsPage 分页获取公司新闻数据，每获取一页调用一次 handle，不在内存中累积全部数据
+// startDate 为 nil 时只获取截至 endDate 的一页数据；handle 返回错误时停止分页
+func ForEachCompanyNewsPage(ctx context.Context, ticker, endDate string, startDate *string, limit int, handle func(page []tools.CompanyNews) error) error {
+	if limit == 0 {
+		limit = 1000
+	}
+	return dataSource().News(ctx, ticker, endDate, startDate, limit, func(page []tools.CompanyNews) error {
+		// handle 可能为新闻补充主题和情绪评分，处理后再保存
+		err := handle(page)
+		storeData("新闻", func(s *dataStore) error { return s.saveNews(ticker, page) })
+		return err
+	})
+}
 
-		if resp.StatusCode != 200 {
-			fmt.Printf("获取公司事实错误: %s - %d\n", ticker, resp.StatusCode)
-			return 0, nil
-		}
+// GetCompanyFacts 获取公司基本信息（行业、板块、上市日期等）
+func GetCompanyFacts(ctx context.Context, ticker string) (*provider.CompanyFacts, error) {
+	return dataSource().Facts(ctx, ticker)
+}
 
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return 0, fmt.Errorf("读取响应体失败: %w", err)
-		}
+// GetFilingItems 获取 SEC 文件中指定章节的文本，如 10-K 的 Item-3（法律诉讼）
+func GetFilingItems(ctx context.Context, ticker, filingType string, year int, items []string) (*FilingItemsResponse, error) {
+	endpoint := fmt.Sprintf("/filings/items/?ticker=%s&filing_type=%s&year=%d", ticker, filingType, year)
+	for _, item := range items {
+		endpoint += fmt.Sprintf("&item=%s", item)
+	}
 
-		var factsResponse CompanyFactsResponse
-		if err := json.Unmarshal(body, &factsResponse); err != nil {
-			return 0, fmt.Errorf("解析公司事实响应失败: %w", err)
-		}
+	resp, err := fetchFinancialDatasets(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("API 请求失败: %w", err)
+	}
+	defer resp.Body.Close()
 
-		return factsResponse.CompanyFacts.MarketCap, nil
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("获取数据错误: %s: %w", ticker, tools.StatusError(resp.StatusCode, body))
 	}
 
-	// 从财务指标获取市值
-	financialMetrics, err := GetFinancialMetrics(ticker, endDate, "ttm", 10, apiKey...)
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return 0, err
+		return nil, fmt.Errorf("读取响应体失败: %w", err)
 	}
 
-	if len(financialMetrics) == 0 {
-		return 0, nil
+	var itemsResponse FilingItemsResponse
+	if err := json.Unmarshal(body, &itemsResponse); err != nil {
+		return nil, fmt.Errorf("解析文件章节响应失败: %w", err)
+	}
+	if len(itemsResponse.Items) == 0 {
+		return nil, fmt.Errorf("%s %d 年 %s 章节: %w", ticker, year, filingType, tools.ErrNoData)
 	}
 
-	return financialMetrics[0].MarketCap, nil
+	return &itemsResponse, nil
 }
 
-// PriceDataFrame 表示价格数据框架
-type PriceDataFrame struct {
-	Dates  []time.Time
-	Open   []float64
-	Close  []float64
-	High   []float64
-	Low    []float64
-	Volume []int64
+// GetMarketCap 获取市值数据
+// 请求失败返回包装了错误类型的错误，没有市值数据时返回 ErrNoData，不会返回 (0, nil)
+func GetMarketCap(ctx context.Context, ticker, endDate string) (float64, error) {
+	// 用户导入了报表时，历史市值由报表计算
+	if endDate != time.Now().Format("2006-01-02") {
+		if statements, err := loadCompanyStatements(ticker); err != nil {
+			return 0, err
+		} else if statements != nil {
+			metrics, err := statements.financialMetrics(endDate, "ttm", 1)
+			if err != nil {
+				return 0, err
+			}
+			if metrics[0].MarketCap <= 0 {
+				return 0, fmt.Errorf("%s 截至 %s 的市值: %w", ticker, endDate, tools.ErrNoData)
+			}
+			return metrics[0].MarketCap, nil
+		}
+	}
+	return dataSource().MarketCap(ctx, ticker, endDate)
 }
 
 // PricesToDataFrame 将价格转换为数据框架
-func PricesToDataFrame(prices []Price) (*PriceDataFrame, error) {
+func PricesToDataFrame(prices []provider.Price) (*tools.PriceDataFrame, error) {
 	if len(prices) == 0 {
-		return &PriceDataFrame{}, nil
+		return &tools.PriceDataFrame{}, nil
 	}
 
-	df := &PriceDataFrame{
+	df := &tools.PriceDataFrame{
 		Dates:  make([]time.Time, len(prices)),
 		Open:   make([]float64, len(prices)),
 		Close:  make([]float64, len(prices)),
@@ -670,9 +583,58 @@ func PricesToDataFrame(prices []Price) (*PriceDataFrame, error) {
 	return df, nil
 }
 
+// GetPriceHistoryStats 获取最近 years 年（最多 20 年）的价格历史并计算长周期统计
+func GetPriceHistoryStats(ctx context.Context, ticker string, years int) (*tools.PriceHistoryStats, error) {
+	if years <= 0 || years > tools.MaxPriceHistoryYears {
+		return nil, fmt.Errorf("回溯年数需在 1-%d 之间: %d", tools.MaxPriceHistoryYears, years)
+	}
+	endDate := time.Now().Format("2006-01-02")
+	startDate := time.Now().AddDate(-years, 0, 0).Format("2006-01-02")
+
+	prices, err := GetPrices(ctx, ticker, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	df, err := PricesToDataFrame(prices)
+	if err != nil {
+		return nil, err
+	}
+	if df.Len() < 2 {
+		return nil, fmt.Errorf("%s 在 %s ~ %s 没有足够的价格数据", ticker, startDate, endDate)
+	}
+
+	last := df.Len() - 1
+	return &tools.PriceHistoryStats{
+		Symbol:           ticker,
+		StartDate:        df.Dates[0].Format("2006-01-02"),
+		EndDate:          df.Dates[last].Format("2006-01-02"),
+		TradingDays:      df.Len(),
+		StartPrice:       df.Close[0],
+		EndPrice:         df.Close[last],
+		CAGR:             df.CAGR(),
+		MaxDrawdown:      tools.Sanitize(df.MaxDrawdown()),
+		AnnualVolatility: df.AnnualizedVolatility(),
+		WorstMonth:       df.WorstPeriodReturn("M"),
+		MA50:             df.LastRollingMean(50),
+		MA200:            df.LastRollingMean(200),
+		Gaps:             len(detectPriceGaps(prices, maxPriceGapDays)),
+	}, nil
+}
+
+// GetPriceSeries 获取最近 years 年的日收盘价序列，用于组合相关性分析
+func GetPriceSeries(ctx context.Context, ticker string, years int) (*tools.PriceSeries, error) {
+	endDate := time.Now().Format("2006-01-02")
+	startDate := time.Now().AddDate(-years, 0, 0).Format("2006-01-02")
+	df, err := GetPriceData(ctx, ticker, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	return &tools.PriceSeries{Symbol: ticker, PriceDataFrame: *df}, nil
+}
+
 // GetPriceData 获取价格数据并转换为数据框架
-func GetPriceData(ticker, startDate, endDate string, apiKey ...string) (*PriceDataFrame, error) {
-	prices, err := GetPrices(ticker, startDate, endDate, apiKey...)
+func GetPriceData(ctx context.Context, ticker, startDate, endDate string) (*tools.PriceDataFrame, error) {
+	prices, err := GetPrices(ctx, ticker, startDate, endDate)
 	if err != nil {
 		return nil, err
 	}
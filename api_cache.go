@@ -0,0 +1,430 @@
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// apiCacheDir 数据源响应的磁盘缓存目录
+var apiCacheDir = filepath.Join("output", "cache", "api")
+
+// 缓存默认配置
+const (
+	defaultAPICacheTTL           = 6 * time.Hour
+	defaultAPICacheMemoryEntries = 256
+)
+
+// defaultAPICacheEndpointTTLs 各接口默认的缓存有效期，键为接口路径（不含首尾的 /），未列出的接口使用 API_CACHE_TTL
+// 财务数据按季度更新，公司资料和年报很少变化，新闻和内部人交易更新较快
+var defaultAPICacheEndpointTTLs = map[string]time.Duration{
+	"news":              time.Hour,
+	"insider-trades":    12 * time.Hour,
+	"financial-metrics": 24 * time.Hour,
+	"financials":        24 * time.Hour,
+	"company/facts":     7 * 24 * time.Hour,
+	"filings":           7 * 24 * time.Hour,
+}
+
+// apiCacheEntry 一条缓存的响应，只缓存状态码为 200 的响应
+type apiCacheEntry struct {
+	Method      string            `json:"method"`
+	URL         string            `json:"url"` // 已移除密钥参数
+	RequestBody string            `json:"request_body,omitempty"`
+	Header      map[string]string `json:"header,omitempty"`
+	Body        []byte            `json:"body"`
+	StoredAt    time.Time         `json:"stored_at"`
+
+	// 响应的校验信息，过期后带上 If-None-Match / If-Modified-Since 重新请求，数据未变化时数据源返回 304，不再传输响应体
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// APICacheStats 各层缓存的命中次数
+type APICacheStats struct {
+	MemoryHits  int64 `json:"memory_hits"`
+	DiskHits    int64 `json:"disk_hits"`
+	Misses      int64 `json:"misses"`
+	Revalidated int64 `json:"revalidated,omitempty"` // 未命中中数据源以 304 确认数据未变化、沿用缓存响应的次数
+}
+
+// Sub 两次统计之间的增量，用于计算单次分析的命中情况
+func (s APICacheStats) Sub(prev APICacheStats) APICacheStats {
+	return APICacheStats{
+		MemoryHits:  s.MemoryHits - prev.MemoryHits,
+		DiskHits:    s.DiskHits - prev.DiskHits,
+		Misses:      s.Misses - prev.Misses,
+		Revalidated: s.Revalidated - prev.Revalidated,
+	}
+}
+
+// String 日志中的命中统计
+func (s APICacheStats) String() string {
+	total := s.MemoryHits + s.DiskHits + s.Misses
+	if total == 0 {
+		return "无数据源请求"
+	}
+	revalidated := ""
+	if s.Revalidated > 0 {
+		revalidated = fmt.Sprintf("（其中 %d 次数据源确认未变化）", s.Revalidated)
+	}
+	return fmt.Sprintf("内存命中 %d，磁盘命中 %d，未命中 %d%s，命中率 %.0f%%",
+		s.MemoryHits, s.DiskHits, s.Misses, revalidated, float64(s.MemoryHits+s.DiskHits)/float64(total)*100)
+}
+
+// apiCache 数据源响应的两级缓存：进程内 LRU 在前，磁盘缓存在后
+// Agent 在一次分析中经常重复请求相同的指标，内存层直接返回；磁盘层在多次运行之间复用
+type apiCache struct {
+	ttl        time.Duration            // 未单独设置的接口的有效期
+	endpoints  map[string]time.Duration // 各接口的有效期，为 0 时该接口不缓存
+	maxEntries int
+	dir        string
+
+	mu      sync.Mutex
+	order   *list.List // 最近使用的在前
+	entries map[string]*list.Element
+
+	memoryHits  atomic.Int64
+	diskHits    atomic.Int64
+	misses      atomic.Int64
+	revalidated atomic.Int64
+}
+
+// lruItem LRU 链表中的元素
+type lruItem struct {
+	key   string
+	entry *apiCacheEntry
+}
+
+// dataCache 全局数据源缓存，配置从环境变量读取（见 newAPICacheFromEnv），首次使用时创建
+var (
+	dataCache     *apiCache
+	dataCacheOnce sync.Once
+)
+
+// sharedAPICache 返回全局数据源缓存，API_CACHE_TTL=0 时为 nil（不缓存）
+func sharedAPICache() *apiCache {
+	dataCacheOnce.Do(func() {
+		dataCache = newAPICacheFromEnv()
+	})
+	return dataCache
+}
+
+// newAPICacheFromEnv API_CACHE_TTL: 缓存有效期（如 6h、30m），默认 6h，为 0 时关闭缓存；
+// API_CACHE_ENDPOINT_TTL: 各接口的有效期，见 apiCacheEndpointTTLs；
+// API_CACHE_MEMORY_ENTRIES: 内存层最多保存的响应数，默认 256
+func newAPICacheFromEnv() *apiCache {
+	ttl := defaultAPICacheTTL
+	if value := os.Getenv("API_CACHE_TTL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			log.Printf("无效的 API_CACHE_TTL: %s，使用默认值 %s", value, defaultAPICacheTTL)
+		} else {
+			ttl = parsed
+		}
+	}
+	if ttl <= 0 {
+		return nil
+	}
+	maxEntries, err := positiveIntEnv("API_CACHE_MEMORY_ENTRIES", defaultAPICacheMemoryEntries)
+	if err != nil {
+		log.Printf("%v，使用默认值 %d", err, defaultAPICacheMemoryEntries)
+		maxEntries = defaultAPICacheMemoryEntries
+	}
+	endpoints, err := apiCacheEndpointTTLs()
+	if err != nil {
+		log.Printf("%v，使用各接口的默认有效期", err)
+		endpoints = defaultAPICacheEndpointTTLs
+	}
+	return newAPICache(ttl, endpoints, maxEntries, apiCacheDir)
+}
+
+// apiCacheEndpointTTLs 各接口的缓存有效期：内置默认值（defaultAPICacheEndpointTTLs）加上 API_CACHE_ENDPOINT_TTL 的设置，
+// 格式为 接口路径=有效期，逗号分隔，如 news=30m,prices=1h,company/facts=0；为 0 时该接口不缓存
+func apiCacheEndpointTTLs() (map[string]time.Duration, error) {
+	ttls := make(map[string]time.Duration, len(defaultAPICacheEndpointTTLs))
+	for endpoint, ttl := range defaultAPICacheEndpointTTLs {
+		ttls[endpoint] = ttl
+	}
+	value := os.Getenv("API_CACHE_ENDPOINT_TTL")
+	if strings.TrimSpace(value) == "" {
+		return ttls, nil
+	}
+	for _, item := range strings.Split(value, ",") {
+		endpoint, raw, ok := strings.Cut(strings.TrimSpace(item), "=")
+		endpoint = strings.Trim(strings.TrimSpace(endpoint), "/")
+		if !ok || endpoint == "" {
+			return nil, fmt.Errorf("无效的 API_CACHE_ENDPOINT_TTL %q，格式应为 接口路径=有效期", item)
+		}
+		ttl, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil || ttl < 0 {
+			return nil, fmt.Errorf("无效的 API_CACHE_ENDPOINT_TTL %q，有效期应为 6h、30m 形式的非负时长", item)
+		}
+		ttls[endpoint] = ttl
+	}
+	return ttls, nil
+}
+
+// newAPICache 创建两级缓存
+func newAPICache(ttl time.Duration, endpoints map[string]time.Duration, maxEntries int, dir string) *apiCache {
+	return &apiCache{
+		ttl:        ttl,
+		endpoints:  endpoints,
+		maxEntries: maxEntries,
+		dir:        dir,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Stats 当前累计的命中统计，为 nil 时返回零值
+func (c *apiCache) Stats() APICacheStats {
+	if c == nil {
+		return APICacheStats{}
+	}
+	return APICacheStats{
+		MemoryHits:  c.memoryHits.Load(),
+		DiskHits:    c.diskHits.Load(),
+		Misses:      c.misses.Load(),
+		Revalidated: c.revalidated.Load(),
+	}
+}
+
+// apiCacheKey 请求的缓存键，由方法、去掉密钥参数的 URL 和请求体计算，请求头中的 API Key 不参与
+func apiCacheKey(method, redactedURL, body string) string {
+	sum := sha256.Sum256([]byte(method + "\n" + redactedURL + "\n" + body))
+	return hex.EncodeToString(sum[:])
+}
+
+// ttlFor 请求路径对应的有效期：路径中包含的最长的已设置接口（如 /v1/company/facts/ 匹配 company/facts），都不匹配时使用默认有效期
+func (c *apiCache) ttlFor(path string) time.Duration {
+	path = "/" + strings.Trim(path, "/") + "/"
+	ttl, matched := c.ttl, ""
+	for endpoint, endpointTTL := range c.endpoints {
+		if len(endpoint) > len(matched) && strings.Contains(path, "/"+endpoint+"/") {
+			ttl, matched = endpointTTL, endpoint
+		}
+	}
+	return ttl
+}
+
+// get 依次查找内存层和磁盘层中存入不超过 ttl 的响应，磁盘命中时提升到内存层
+func (c *apiCache) get(key string, ttl time.Duration) (*apiCacheEntry, bool) {
+	now := time.Now()
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		item := elem.Value.(*lruItem)
+		if now.Sub(item.entry.StoredAt) < ttl {
+			c.order.MoveToFront(elem)
+			c.mu.Unlock()
+			c.memoryHits.Add(1)
+			return item.entry, true
+		}
+		c.order.Remove(elem)
+		delete(c.entries, key)
+	}
+	c.mu.Unlock()
+
+	if entry, ok := c.readDisk(key, now, ttl); ok {
+		c.putMemory(key, entry)
+		c.diskHits.Add(1)
+		return entry, true
+	}
+	c.misses.Add(1)
+	return nil, false
+}
+
+// stale 读取已过期但带有校验信息（ETag 或 Last-Modified）的磁盘缓存，用于发送条件请求；内存层的响应都已写入磁盘层
+func (c *apiCache) stale(key string) (*apiCacheEntry, bool) {
+	entry, ok := c.readDisk(key, time.Now(), math.MaxInt64)
+	if !ok || (entry.ETag == "" && entry.LastModified == "") {
+		return nil, false
+	}
+	return entry, true
+}
+
+// put 同时写入内存层和磁盘层，磁盘写入失败只记录日志
+func (c *apiCache) put(key string, entry *apiCacheEntry) {
+	c.putMemory(key, entry)
+	if err := c.writeDisk(key, entry); err != nil {
+		log.Printf("[APICache] 写入磁盘缓存失败: %v", err)
+	}
+}
+
+// putMemory 写入内存层，超过容量时淘汰最久未使用的响应
+func (c *apiCache) putMemory(key string, entry *apiCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*lruItem).entry = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruItem{key: key, entry: entry})
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruItem).key)
+	}
+}
+
+// readDisk 读取未过期的磁盘缓存，文件不存在或损坏时视为未命中
+func (c *apiCache) readDisk(key string, now time.Time, ttl time.Duration) (*apiCacheEntry, bool) {
+	data, err := os.ReadFile(filepath.Join(c.dir, key+".json"))
+	if err != nil {
+		return nil, false
+	}
+	var entry apiCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	if now.Sub(entry.StoredAt) >= ttl {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// writeDisk 先写临时文件再重命名，并发写同一个键时不会留下半截文件
+func (c *apiCache) writeDisk(key string, entry *apiCacheEntry) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(c.dir, key+".json"))
+}
+
+// response 把缓存的响应还原为 http.Response，调用方按正常响应读取和关闭
+func (e *apiCacheEntry) response() *http.Response {
+	header := make(http.Header, len(e.Header))
+	for key, value := range e.Header {
+		header.Set(key, value)
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+	}
+}
+
+// makeCachedAPIRequest 带两级缓存的 makeAPIRequest，用于获取市场和财务数据（券商持仓等实时数据不要使用），有效期按接口路径确定
+// 缓存过期且响应带有 ETag/Last-Modified 时发送条件请求，数据源返回 304 时沿用缓存的响应并重新计算有效期
+// 回放快照时不使用缓存，保证回放结果只来自快照；录制快照时缓存命中的响应同样写入快照，且不发送条件请求，避免快照中只有 304 响应
+func makeCachedAPIRequest(ctx context.Context, client *http.Client, rawURL string, headers map[string]string, method string, jsonData map[string]any, maxRetries int) (*http.Response, error) {
+	cache := sharedAPICache()
+	rt := activeSnapshot.Load()
+	if cache == nil || (rt != nil && !isSnapshotRecorder(*rt)) {
+		return makeAPIRequest(ctx, client, rawURL, headers, method, jsonData, maxRetries)
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("无效的请求地址: %w", err)
+	}
+	ttl := cache.ttlFor(parsed.Path)
+	if ttl <= 0 {
+		return makeAPIRequest(ctx, client, rawURL, headers, method, jsonData, maxRetries)
+	}
+	var requestBody string
+	if method == "POST" && jsonData != nil {
+		data, err := json.Marshal(jsonData)
+		if err != nil {
+			return nil, fmt.Errorf("序列化 JSON 数据失败: %w", err)
+		}
+		requestBody = string(data)
+	}
+	redacted := redactURL(parsed)
+	key := apiCacheKey(method, redacted, requestBody)
+
+	if entry, ok := cache.get(key, ttl); ok {
+		if rt != nil {
+			(*rt).(*snapshotRecorder).add(entry.Method, entry.URL, entry.RequestBody, http.StatusOK, entry.Header, entry.Body)
+		}
+		return entry.response(), nil
+	}
+
+	stale, revalidate := cache.stale(key)
+	if revalidate && rt == nil {
+		headers = maps.Clone(headers)
+		if stale.ETag != "" {
+			headers["If-None-Match"] = stale.ETag
+		}
+		if stale.LastModified != "" {
+			headers["If-Modified-Since"] = stale.LastModified
+		}
+	} else {
+		revalidate = false
+	}
+
+	resp, err := makeAPIRequest(ctx, client, rawURL, headers, method, jsonData, maxRetries)
+	if err == nil && revalidate && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		refreshed := *stale
+		refreshed.StoredAt = time.Now()
+		cache.put(key, &refreshed)
+		cache.revalidated.Add(1)
+		return refreshed.response(), nil
+	}
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("读取响应体失败: %w", err)
+	}
+	cache.put(key, &apiCacheEntry{
+		Method:       method,
+		URL:          redacted,
+		RequestBody:  requestBody,
+		Header:       snapshotHeader(resp.Header),
+		Body:         body,
+		StoredAt:     time.Now(),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	})
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// isSnapshotRecorder 当前生效的快照是否为录制模式
+func isSnapshotRecorder(rt http.RoundTripper) bool {
+	_, ok := rt.(*snapshotRecorder)
+	return ok
+}
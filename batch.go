@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"investment/tools"
+)
+
+// batchResult 批量分析中一只股票的结果
+type batchResult struct {
+	Symbol   string
+	Note     string     // 关注列表中的备注
+	Run      *RunRecord // 分析完成时的运行记录
+	Err      error
+	Duration time.Duration
+}
+
+// runBatch 用 opts.Workers 个并发的 Agent 分析多只股票，每只股票单独保存报告和运行记录，最后打印汇总表
+// 股票来自命令行或关注列表（--watchlist），关注列表中的备注附加到该股票的用户提示词；所有分析共用一个模型客户端和数据源客户端，数据请求按 DATA_API_RATE_LIMIT 统一限速；
+// 与服务模式一样不录制数据快照，并发时不向终端流式输出报告
+func runBatch(opts analyzeFlags, entries []watchlistEntry) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	prompts, err := loadPromptSet(promptsDir())
+	if err != nil {
+		return fmt.Errorf("加载提示词失败: %v", err)
+	}
+
+	var symbols []string
+	markets := make(map[string]string)
+	notes := make(map[string]string)
+	for _, entry := range entries {
+		symbol := canonicalSymbol(entry.Symbol)
+		if symbol != entry.Symbol {
+			fmt.Printf("ℹ️ %s 已更名为 %s，按新代码分析\n", entry.Symbol, symbol)
+		}
+		// 同一只股票出现多次时只分析一次，备注合并
+		if _, seen := markets[symbol]; seen {
+			if entry.Note != "" {
+				notes[symbol] = strings.TrimPrefix(notes[symbol]+"；"+entry.Note, "；")
+			}
+			continue
+		}
+		profile, err := resolveMarket(symbol, opts.Market)
+		if err != nil {
+			return err
+		}
+		symbols = append(symbols, symbol)
+		markets[symbol] = profile.Name
+		notes[symbol] = entry.Note
+	}
+	// 全部股票属于同一市场时与单只分析一样使用该市场的默认模型和币种；跨市场时与服务模式一样只按代码使用报告单位和基准的默认值
+	if profile, ok := batchMarket(symbols, markets); ok {
+		profile.apply()
+	}
+	if err := validateAnalysisConfig(); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	chatModel, modelType := createChatModel(ctx)
+	var llmCache *cachedChatModel
+	if llmCacheEnabled() && !opts.NoLLMCache {
+		llmCache = withLLMCache(chatModel, modelType)
+		chatModel = llmCache
+	}
+	bus, err := newEventBusFromEnv()
+	if err != nil {
+		log.Printf("消息总线不可用，本次不发布分析结果: %v", err)
+	}
+	defer bus.Close()
+
+	workers := min(opts.Workers, len(symbols))
+	stream := opts.Stream
+	if workers > 1 {
+		stream = ""
+	}
+	fmt.Printf("=== 智能投资助手 - 批量分析 %d 只股票，并发 %d ===\n", len(symbols), workers)
+
+	results := make([]batchResult, len(symbols))
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				symbol := symbols[i]
+				fmt.Printf("▶️ [%d/%d] 开始分析 %s\n", i+1, len(symbols), symbol)
+				start := time.Now()
+				run, err := runAnalysis(ctx, chatModel, analysisRequest{
+					Symbol:    symbol,
+					Portfolio: opts.Portfolio,
+					Tags:      parseTags(opts.Tag),
+					Timeout:   opts.Timeout,
+					WarmStart: true,
+					Options: analysisOptions{
+						ToolTimeout: opts.ToolTimeout,
+						Transcript:  opts.Transcript,
+						Stream:      stream,
+						ModelType:   modelType,
+						Prompts:     prompts,
+						Market:      markets[symbol],
+						Depth:       opts.Depth,
+						Note:        notes[symbol],
+					},
+					Bus: bus,
+				})
+				results[i] = batchResult{Symbol: symbol, Note: notes[symbol], Run: run, Err: err, Duration: time.Since(start)}
+				if err != nil {
+					log.Printf("%s 分析失败: %v", symbol, err)
+				}
+			}
+		}()
+	}
+	for i := range symbols {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+
+	if llmCache != nil {
+		stats := llmCache.cache.Stats()
+		log.Printf("模型响应缓存: 命中 %d，未命中 %d", stats.Hits, stats.Misses)
+	}
+	failed := printBatchSummary(results)
+	if failed > 0 {
+		return fmt.Errorf("%d/%d 只股票分析失败", failed, len(results))
+	}
+	return nil
+}
+
+// batchMarket 全部股票属于同一市场时返回该市场配置
+func batchMarket(symbols []string, markets map[string]string) (marketProfile, bool) {
+	for _, symbol := range symbols[1:] {
+		if markets[symbol] != markets[symbols[0]] {
+			return marketProfile{}, false
+		}
+	}
+	profile, ok := marketProfiles[markets[symbols[0]]]
+	return profile, ok
+}
+
+// printBatchSummary 打印每只股票的评级、用时和报告位置，返回失败的数量
+func printBatchSummary(results []batchResult) int {
+	failed := 0
+	fmt.Printf("\n%s\n%-10s %-6s %-8s %8s  %s\n", strings.Repeat("=", 50), "股票", "状态", "评级", "用时", "报告")
+	for _, r := range results {
+		duration := r.Duration.Round(time.Second).String()
+		switch {
+		case r.Err != nil:
+			failed++
+			reason := r.Err.Error()
+			if errors.Is(r.Err, tools.ErrUnauthorized) {
+				reason = "数据源 API 密钥无效或无权限"
+			}
+			fmt.Printf("%-10s %-6s %-8s %8s  %s\n", r.Symbol, "❌", "-", duration, reason)
+		case r.Run.Truncated:
+			fmt.Printf("%-10s %-6s %-8s %8s  %s（超时，部分报告）\n", r.Symbol, "⚠️", orDash(r.Run.Rating), duration, r.Run.ReportPath)
+		default:
+			fmt.Printf("%-10s %-6s %-8s %8s  %s\n", r.Symbol, "✅", orDash(r.Run.Rating), duration, r.Run.ReportPath)
+		}
+		if r.Note != "" {
+			fmt.Printf("%-10s 备注: %s\n", "", r.Note)
+		}
+	}
+	return failed
+}
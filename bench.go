@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"investment/tools"
+)
+
+// BenchEntry 一个模型在对比中的分析结果、用量和耗时
+type BenchEntry struct {
+	ModelType   string          `json:"model_type"`
+	ModelName   string          `json:"model_name"`
+	Duration    time.Duration   `json:"duration"` // 整个分析的耗时（包括工具调用）
+	Usage       ModelUsage      `json:"usage"`
+	Cost        tools.SafeFloat `json:"cost_usd"` // 按 <MODEL_TYPE>_PRICE_INPUT/OUTPUT 计算，未配置价格时为 n/a
+	Rating      string          `json:"rating,omitempty"`
+	Score       *int            `json:"fundamental_score,omitempty"`
+	Compete     *int            `json:"competitive_score,omitempty"`
+	Manage      *int            `json:"management_score,omitempty"`
+	FairValue   tools.SafeFloat `json:"fair_value_p50"` // 蒙特卡洛估值的中位数，未调用估值工具时为 n/a
+	ReportChars int             `json:"report_chars"`
+	ReportPath  string          `json:"report_path,omitempty"`
+	Truncated   bool            `json:"truncated"`
+	StepLimited bool            `json:"step_limited,omitempty"`
+	Error       string          `json:"error,omitempty"`
+}
+
+// BenchReport 同一股票在多个模型上的对比结果
+type BenchReport struct {
+	ID          string       `json:"id"`
+	Symbol      string       `json:"symbol"`
+	StartedAt   time.Time    `json:"started_at"`
+	Entries     []BenchEntry `json:"entries"`
+	SummaryPath string       `json:"summary_path,omitempty"`
+}
+
+// runBench 处理 bench 子命令：用每个模型依次分析同一只股票，报告并排保存在 bench/<id>/ 下，
+// 并生成包含耗时、token 用量、成本和结论差异的对比摘要
+func runBench(args []string) error {
+	usage := "用法: bench <stock_symbol> --models deepseek,gemini,openai [--timeout 10m] [--tool-timeout 2m]"
+	if len(args) < 1 || strings.HasPrefix(args[0], "-") {
+		return errors.New(usage)
+	}
+	symbol := strings.ToUpper(args[0])
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	modelsFlag := fs.String("models", "", "参与对比的模型类型，用逗号分隔，如 deepseek,gemini,openai")
+	timeout := fs.Duration("timeout", 10*time.Minute, "每个模型分析的最长时间（0 表示不限制）")
+	toolTimeout := fs.Duration("tool-timeout", 2*time.Minute, "单次工具调用的最长时间（0 表示不限制）")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	models := parseBenchModels(*modelsFlag)
+	if len(models) == 0 {
+		return errors.New(usage)
+	}
+	if err := validateBenchConfig(models); err != nil {
+		return err
+	}
+	prompts, err := loadPromptSet(promptsDir())
+	if err != nil {
+		return fmt.Errorf("加载提示词失败: %v", err)
+	}
+
+	report := &BenchReport{
+		ID:        fmt.Sprintf("%s_%s", symbol, time.Now().Format("2006-01-02_15-04-05")),
+		Symbol:    symbol,
+		StartedAt: time.Now(),
+	}
+	fmt.Printf("=== 模型对比：%s（%s）===\n", symbol, strings.Join(models, "、"))
+	ctx := context.Background()
+	for i, modelType := range models {
+		fmt.Printf("[%d/%d] 使用 %s 分析 %s...\n", i+1, len(models), modelType, symbol)
+		entry := runBenchModel(ctx, report.ID, symbol, modelType, *timeout, analysisOptions{
+			ToolTimeout: *toolTimeout,
+			Transcript:  TranscriptNone,
+			ModelType:   modelType,
+			Prompts:     prompts,
+		})
+		if entry.Error != "" {
+			fmt.Printf("❌ %s 分析失败: %s\n", modelType, entry.Error)
+		} else {
+			fmt.Printf("✅ %s 完成，用时 %s，评级 %s\n", modelType, entry.Duration.Round(time.Second), orNA(entry.Rating))
+		}
+		report.Entries = append(report.Entries, entry)
+	}
+
+	summary := renderBenchSummary(report)
+	location, err := tools.Output().WriteReport(path.Join("bench", report.ID, "summary.md"), []byte(summary))
+	if err != nil {
+		return fmt.Errorf("保存对比摘要失败: %v", err)
+	}
+	report.SummaryPath = location
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化对比结果失败: %v", err)
+	}
+	if _, err := tools.Output().WriteArtifact(path.Join("bench", report.ID, "bench.json"), data); err != nil {
+		log.Printf("[Bench] 保存对比结果失败: %v", err)
+	}
+
+	fmt.Print(strings.Repeat("=", 50) + "\n")
+	fmt.Print(summary)
+	fmt.Printf("📊 对比摘要已保存: %s\n", location)
+	return nil
+}
+
+// parseBenchModels 解析 --models，去掉空项和重复项，统一为小写
+func parseBenchModels(raw string) []string {
+	var models []string
+	for _, m := range strings.Split(raw, ",") {
+		m = strings.ToLower(strings.TrimSpace(m))
+		if m != "" && !slices.Contains(models, m) {
+			models = append(models, m)
+		}
+	}
+	return models
+}
+
+// runBenchModel 使用一个模型完成分析并保存报告；不使用模型响应缓存，保证耗时和用量是真实调用的结果
+// 数据源请求经过共享的 API 缓存，后面的模型与第一个模型看到相同的数据
+func runBenchModel(ctx context.Context, benchID, symbol, modelType string, timeout time.Duration, options analysisOptions) BenchEntry {
+	entry := BenchEntry{
+		ModelType: modelType,
+		ModelName: os.Getenv(strings.ToUpper(modelType) + "_MODEL_NAME"),
+		Cost:      tools.NaN(),
+		FairValue: tools.NaN(),
+	}
+	chatModel, recorder := withUsageTracking(createChatModelOfType(ctx, modelType))
+
+	analysisCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		analysisCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	// 每个模型单独的重试预算，前面的模型用完预算不影响后面的模型
+	analysisCtx = withRetryBudget(analysisCtx, retryBudgetFromEnv())
+	start := time.Now()
+	result, err := analyzeWithReactAgent(analysisCtx, chatModel, symbol, options)
+	entry.Duration = time.Since(start)
+	entry.Usage = recorder.Usage()
+	if pricing, err := modelPricingFromEnv(modelType); err == nil {
+		entry.Cost = pricing.Cost(entry.Usage)
+	}
+	if err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+
+	entry.Truncated = errors.Is(analysisCtx.Err(), context.DeadlineExceeded)
+	entry.StepLimited = result.StepLimited
+	entry.Rating = extractRating(result.Report)
+	entry.Score, entry.Compete, entry.Manage = result.Score, result.Compete, result.Manage
+	if result.Valuation != nil {
+		entry.FairValue = result.Valuation.P50
+	}
+	entry.ReportChars = len([]rune(result.Report))
+
+	content := fmt.Sprintf("# %s 投资分析报告（%s %s）\n\n分析时间: %s\n\n%s",
+		symbol, modelType, entry.ModelName, start.Format("2006-01-02 15:04:05"), result.Report)
+	location, err := tools.Output().WriteReport(path.Join("bench", benchID, modelType+".md"), []byte(content))
+	if err != nil {
+		log.Printf("[Bench] 保存 %s 的报告失败: %v", modelType, err)
+	} else {
+		entry.ReportPath = location
+	}
+	return entry
+}
+
+// renderBenchSummary 生成对比摘要：用量和耗时表、结论对比表，以及评级是否一致、最快和最便宜的模型
+func renderBenchSummary(report *BenchReport) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# %s 模型对比\n\n对比时间: %s\n\n", report.Symbol, report.StartedAt.Format("2006-01-02 15:04:05")))
+
+	sb.WriteString("## 耗时与成本\n\n")
+	sb.WriteString("| 模型 | 模型名称 | 总耗时 | 模型耗时 | 调用次数 | 输入 tokens | 输出 tokens | 成本（USD） | 状态 |\n")
+	sb.WriteString("|---|---|---|---|---|---|---|---|---|\n")
+	for _, e := range report.Entries {
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %d | %d | %d | %s | %s |\n",
+			e.ModelType, orNA(e.ModelName), e.Duration.Round(time.Second), e.Usage.ModelTime.Round(time.Second),
+			e.Usage.Calls, e.Usage.PromptTokens, e.Usage.CompletionTokens, e.Cost.Sprintf("%.4f"), benchStatus(e)))
+	}
+
+	sb.WriteString("\n## 结论\n\n")
+	sb.WriteString("| 模型 | 评级 | 基本面评分 | 竞争地位评分 | 管理层评分 | 估值 P50 | 报告字数 | 报告 |\n")
+	sb.WriteString("|---|---|---|---|---|---|---|---|\n")
+	for _, e := range report.Entries {
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s | %s | %d | %s |\n",
+			e.ModelType, orNA(e.Rating), intOrNA(e.Score), intOrNA(e.Compete), intOrNA(e.Manage),
+			e.FairValue.Sprintf("%.2f"), e.ReportChars, orNA(e.ReportPath)))
+	}
+
+	sb.WriteString("\n## 摘要\n\n")
+	ratings := make(map[string][]string)
+	var fastest, cheapest *BenchEntry
+	for i := range report.Entries {
+		e := &report.Entries[i]
+		if e.Error != "" {
+			continue
+		}
+		if e.Rating != "" {
+			ratings[e.Rating] = append(ratings[e.Rating], e.ModelType)
+		}
+		if fastest == nil || e.Duration < fastest.Duration {
+			fastest = e
+		}
+		if e.Cost.Valid() && (cheapest == nil || e.Cost < cheapest.Cost) {
+			cheapest = e
+		}
+	}
+	switch len(ratings) {
+	case 0:
+		sb.WriteString("- 没有模型给出可识别的评级\n")
+	case 1:
+		for rating, models := range ratings {
+			sb.WriteString(fmt.Sprintf("- 评级一致：%s 均为「%s」\n", strings.Join(models, "、"), rating))
+		}
+	default:
+		var parts []string
+		for _, rating := range ratingOrder {
+			if models, ok := ratings[rating]; ok {
+				parts = append(parts, fmt.Sprintf("「%s」%s", rating, strings.Join(models, "、")))
+			}
+		}
+		sb.WriteString(fmt.Sprintf("- 评级存在分歧：%s\n", strings.Join(parts, "；")))
+	}
+	if fastest != nil {
+		sb.WriteString(fmt.Sprintf("- 最快：%s（%s）\n", fastest.ModelType, fastest.Duration.Round(time.Second)))
+	}
+	if cheapest != nil {
+		sb.WriteString(fmt.Sprintf("- 最便宜：%s（%s USD）\n", cheapest.ModelType, cheapest.Cost.Sprintf("%.4f")))
+	}
+	return sb.String()
+}
+
+// benchStatus 分析的完成状态
+func benchStatus(e BenchEntry) string {
+	switch {
+	case e.Error != "":
+		return "失败: " + strings.NewReplacer("|", "/", "\n", " ").Replace(e.Error)
+	case e.Truncated:
+		return "超时截断"
+	case e.StepLimited:
+		return "达到步数上限"
+	}
+	return "完成"
+}
+
+// orNA 空字符串显示为 n/a
+func orNA(s string) string {
+	if s == "" {
+		return "n/a"
+	}
+	return s
+}
+
+// intOrNA 空评分显示为 n/a
+func intOrNA(v *int) string {
+	if v == nil {
+		return "n/a"
+	}
+	return fmt.Sprintf("%d", *v)
+}
@@ -0,0 +1,555 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"investment/tools"
+)
+
+// BrokerImporter 从券商 API 拉取当前持仓
+type BrokerImporter interface {
+	Name() string
+	FetchPositions(ctx context.Context) ([]Position, error)
+}
+
+// brokerImporters 已支持的券商及其构造函数
+var brokerImporters = map[string]func() (BrokerImporter, error){
+	"ibkr":   newIBKRFlexImporterFromEnv,
+	"alpaca": newAlpacaImporterFromEnv,
+	"futu":   newFutuImporterFromEnv,
+}
+
+// NewBrokerImporter 根据券商名称创建持仓导入器
+func NewBrokerImporter(broker string) (BrokerImporter, error) {
+	newImporter, ok := brokerImporters[strings.ToLower(broker)]
+	if !ok {
+		return nil, fmt.Errorf("不支持的券商: %s，可选值为 %s", broker, strings.Join(brokerNames(), ", "))
+	}
+	return newImporter()
+}
+
+// brokerNames 返回已支持的券商名称
+func brokerNames() []string {
+	names := make([]string, 0, len(brokerImporters))
+	for name := range brokerImporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ibkrFlexImporter 通过 IBKR Flex Web Service 拉取持仓，需要在账户管理中创建包含 Open Positions 的 Flex Query
+type ibkrFlexImporter struct {
+	token   string
+	queryID string
+	baseURL string
+}
+
+// newIBKRFlexImporterFromEnv IBKR_FLEX_TOKEN: Flex Web Service 令牌；IBKR_FLEX_QUERY_ID: Flex Query ID
+func newIBKRFlexImporterFromEnv() (BrokerImporter, error) {
+	token := os.Getenv("IBKR_FLEX_TOKEN")
+	queryID := os.Getenv("IBKR_FLEX_QUERY_ID")
+	if token == "" || queryID == "" {
+		return nil, fmt.Errorf("请设置 IBKR_FLEX_TOKEN 和 IBKR_FLEX_QUERY_ID")
+	}
+	return &ibkrFlexImporter{
+		token:   token,
+		queryID: queryID,
+		baseURL: "https://ndcdyn.interactivebrokers.com/AccountManagement/FlexWebService",
+	}, nil
+}
+
+func (i *ibkrFlexImporter) Name() string { return "ibkr" }
+
+// flexStatementResponse SendRequest 的响应
+type flexStatementResponse struct {
+	Status        string `xml:"Status"`
+	ReferenceCode string `xml:"ReferenceCode"`
+	URL           string `xml:"Url"`
+	ErrorCode     string `xml:"ErrorCode"`
+	ErrorMessage  string `xml:"ErrorMessage"`
+}
+
+// flexQueryResponse GetStatement 返回的报表，只解析持仓部分
+type flexQueryResponse struct {
+	XMLName    xml.Name `xml:"FlexQueryResponse"`
+	Statements []struct {
+		OpenPositions []struct {
+			Symbol         string `xml:"symbol,attr"`
+			Position       string `xml:"position,attr"`
+			CostBasisPrice string `xml:"costBasisPrice,attr"`
+			Currency       string `xml:"currency,attr"`
+			AssetCategory  string `xml:"assetCategory,attr"`
+		} `xml:"OpenPositions>OpenPosition"`
+	} `xml:"FlexStatements>FlexStatement"`
+}
+
+// FetchPositions 先提交报表请求获取 ReferenceCode，再轮询下载报表；报表生成中（错误码 1019）时等待后重试
+func (i *ibkrFlexImporter) FetchPositions(ctx context.Context) ([]Position, error) {
+	sendURL := fmt.Sprintf("%s/SendRequest?t=%s&q=%s&v=3", i.baseURL, url.QueryEscape(i.token), url.QueryEscape(i.queryID))
+	body, err := fetchBrokerResponse(ctx, sendURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	var sendResp flexStatementResponse
+	if err := xml.Unmarshal(body, &sendResp); err != nil {
+		return nil, fmt.Errorf("解析 Flex 请求响应失败: %w", err)
+	}
+	if sendResp.Status != "Success" {
+		return nil, fmt.Errorf("Flex 请求失败: %s %s", sendResp.ErrorCode, sendResp.ErrorMessage)
+	}
+
+	statementURL := fmt.Sprintf("%s?t=%s&q=%s&v=3", sendResp.URL, url.QueryEscape(i.token), url.QueryEscape(sendResp.ReferenceCode))
+	for attempt := 0; attempt < 10; attempt++ {
+		body, err := fetchBrokerResponse(ctx, statementURL, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		// 报表尚未生成时返回 FlexStatementResponse 而不是 FlexQueryResponse
+		var pending flexStatementResponse
+		if xml.Unmarshal(body, &pending) == nil && pending.ErrorCode != "" {
+			if pending.ErrorCode == "1019" {
+				select {
+				case <-ctx.Done():
+					return nil, context.Cause(ctx)
+				case <-time.After(5 * time.Second):
+				}
+				continue
+			}
+			return nil, fmt.Errorf("下载 Flex 报表失败: %s %s", pending.ErrorCode, pending.ErrorMessage)
+		}
+
+		var statement flexQueryResponse
+		if err := xml.Unmarshal(body, &statement); err != nil {
+			return nil, fmt.Errorf("解析 Flex 报表失败: %w", err)
+		}
+		var positions []Position
+		for _, s := range statement.Statements {
+			for _, p := range s.OpenPositions {
+				if p.AssetCategory != "" && p.AssetCategory != "STK" {
+					continue
+				}
+				quantity, err := strconv.ParseFloat(p.Position, 64)
+				if err != nil {
+					return nil, fmt.Errorf("无效的持仓数量 %s: %q", p.Symbol, p.Position)
+				}
+				costBasis, _ := strconv.ParseFloat(p.CostBasisPrice, 64)
+				positions = append(positions, Position{
+					Symbol:    strings.ToUpper(p.Symbol),
+					Quantity:  quantity,
+					CostBasis: costBasis,
+					Currency:  p.Currency,
+					Source:    i.Name(),
+				})
+			}
+		}
+		return positions, nil
+	}
+	return nil, fmt.Errorf("Flex 报表生成超时，请稍后重试")
+}
+
+// alpacaImporter 通过 Alpaca Trading API 拉取持仓
+type alpacaImporter struct {
+	keyID     string
+	secretKey string
+	baseURL   string
+}
+
+// newAlpacaImporterFromEnv ALPACA_API_KEY_ID / ALPACA_API_SECRET_KEY: API 密钥；
+// ALPACA_BASE_URL: 默认 https://api.alpaca.markets，模拟盘使用 https://paper-api.alpaca.markets
+func newAlpacaImporterFromEnv() (BrokerImporter, error) {
+	keyID := os.Getenv("ALPACA_API_KEY_ID")
+	secretKey := os.Getenv("ALPACA_API_SECRET_KEY")
+	if keyID == "" || secretKey == "" {
+		return nil, fmt.Errorf("请设置 ALPACA_API_KEY_ID 和 ALPACA_API_SECRET_KEY")
+	}
+	baseURL := os.Getenv("ALPACA_BASE_URL")
+	if baseURL == "" {
+		baseURL = "https://api.alpaca.markets"
+	}
+	return &alpacaImporter{keyID: keyID, secretKey: secretKey, baseURL: strings.TrimRight(baseURL, "/")}, nil
+}
+
+func (a *alpacaImporter) Name() string { return "alpaca" }
+
+// alpacaPosition Alpaca 持仓，数值字段以字符串返回
+type alpacaPosition struct {
+	Symbol        string `json:"symbol"`
+	Qty           string `json:"qty"`
+	AvgEntryPrice string `json:"avg_entry_price"`
+	AssetClass    string `json:"asset_class"`
+}
+
+// FetchPositions 获取全部持仓，只保留股票类资产
+func (a *alpacaImporter) FetchPositions(ctx context.Context) ([]Position, error) {
+	body, err := fetchBrokerResponse(ctx, a.baseURL+"/v2/positions", map[string]string{
+		"APCA-API-KEY-ID":     a.keyID,
+		"APCA-API-SECRET-KEY": a.secretKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var raw []alpacaPosition
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("解析 Alpaca 持仓失败: %w", err)
+	}
+
+	var positions []Position
+	for _, p := range raw {
+		if p.AssetClass != "" && p.AssetClass != "us_equity" {
+			continue
+		}
+		quantity, err := strconv.ParseFloat(p.Qty, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无效的持仓数量 %s: %q", p.Symbol, p.Qty)
+		}
+		costBasis, _ := strconv.ParseFloat(p.AvgEntryPrice, 64)
+		positions = append(positions, Position{
+			Symbol:    strings.ToUpper(p.Symbol),
+			Quantity:  quantity,
+			CostBasis: costBasis,
+			Currency:  "USD",
+			Source:    a.Name(),
+		})
+	}
+	return positions, nil
+}
+
+// 富途 OpenD 协议号和包头
+const (
+	futuProtoInitConnect     = 1001
+	futuProtoGetAccList      = 2001
+	futuProtoGetPositionList = 2102
+
+	futuHeaderLen = 44 // "FT"、协议号、包体格式、协议版本、序列号、包体长度、包体 SHA1、保留字段
+	futuFmtJSON   = 1  // 包体格式：0 为 Protobuf，1 为 JSON
+)
+
+// 富途交易接口的枚举值
+const (
+	futuTrdEnvSimulate = 0
+	futuTrdEnvReal     = 1
+
+	futuTrdMarketHK   = 1
+	futuTrdMarketUS   = 2
+	futuTrdMarketCN   = 3
+	futuTrdMarketHKCC = 4 // A 股通
+
+	futuSecMarketHK   = 1
+	futuSecMarketUS   = 2
+	futuSecMarketCNSH = 31
+	futuSecMarketCNSZ = 32
+	futuSecMarketSG   = 41
+	futuSecMarketJP   = 51
+)
+
+// futuCurrencies 富途 Currency 枚举对应的币种代码
+var futuCurrencies = map[int]string{1: "HKD", 2: "USD", 3: "CNH", 4: "JPY", 5: "SGD", 6: "AUD", 7: "CAD", 8: "MYR"}
+
+// futuImporter 通过本地 FutuOpenD 网关拉取富途/moomoo 证券账户的持仓
+// OpenD 只提供 TCP 协议，包体可以选择 JSON 格式，因此不需要 Protobuf 依赖；OpenD 配置了 RSA 私钥（加密通信）时无法连接
+type futuImporter struct {
+	addr   string // OpenD 地址
+	trdEnv int    // 交易环境：futuTrdEnvReal 或 futuTrdEnvSimulate
+	accID  uint64 // 只导入该账户，0 表示全部证券账户
+}
+
+// newFutuImporterFromEnv FUTU_OPEND_ADDR: OpenD 地址，默认 127.0.0.1:11111；FUTU_TRD_ENV: real（默认）或 simulate；
+// FUTU_ACC_ID: 可选，只导入该账户，默认导入交易环境下的全部证券账户
+func newFutuImporterFromEnv() (BrokerImporter, error) {
+	importer := &futuImporter{addr: os.Getenv("FUTU_OPEND_ADDR"), trdEnv: futuTrdEnvReal}
+	if importer.addr == "" {
+		importer.addr = "127.0.0.1:11111"
+	}
+	switch env := os.Getenv("FUTU_TRD_ENV"); env {
+	case "", "real":
+	case "simulate":
+		importer.trdEnv = futuTrdEnvSimulate
+	default:
+		return nil, fmt.Errorf("无效的 FUTU_TRD_ENV: %s，可选值为 real、simulate", env)
+	}
+	if raw := os.Getenv("FUTU_ACC_ID"); raw != "" {
+		accID, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无效的 FUTU_ACC_ID: %s", raw)
+		}
+		importer.accID = accID
+	}
+	return importer, nil
+}
+
+func (f *futuImporter) Name() string { return "futu" }
+
+// futuUint64 OpenD 的 JSON 包体按 Protobuf 的 JSON 映射把 uint64 编码为字符串，解析时也兼容数字
+type futuUint64 uint64
+
+func (v *futuUint64) UnmarshalJSON(data []byte) error {
+	n, err := strconv.ParseUint(strings.Trim(string(data), `"`), 10, 64)
+	if err != nil {
+		return fmt.Errorf("无效的 uint64: %s", data)
+	}
+	*v = futuUint64(n)
+	return nil
+}
+
+// futuTrdHeader 交易协议的公共请求头
+type futuTrdHeader struct {
+	TrdEnv    int    `json:"trdEnv"`
+	AccID     uint64 `json:"accID,string"`
+	TrdMarket int    `json:"trdMarket"`
+}
+
+// futuAccount Trd_GetAccList 返回的交易账户
+type futuAccount struct {
+	TrdEnv            int        `json:"trdEnv"`
+	AccID             futuUint64 `json:"accID"`
+	TrdMarketAuthList []int      `json:"trdMarketAuthList"`
+	AccStatus         int        `json:"accStatus"` // 0 可用，1 已停用
+}
+
+// futuPosition Trd_GetPositionList 返回的持仓
+type futuPosition struct {
+	Code             string  `json:"code"`
+	Name             string  `json:"name"`
+	Qty              float64 `json:"qty"`
+	CostPrice        float64 `json:"costPrice"`
+	AverageCostPrice float64 `json:"averageCostPrice"` // 较新版本的 OpenD 才返回，优先于 costPrice
+	SecMarket        int     `json:"secMarket"`
+	Currency         int     `json:"currency"`
+}
+
+// FetchPositions 连接 OpenD，列出交易环境下的证券账户，逐个账户、逐个已开通的市场查询持仓
+// 查询持仓不需要解锁交易
+func (f *futuImporter) FetchPositions(ctx context.Context) ([]Position, error) {
+	conn, err := dialFutu(ctx, f.addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var initResp struct {
+		LoginUserID futuUint64 `json:"loginUserID"`
+	}
+	initReq := map[string]any{"clientVer": 100, "clientID": "investment", "recvNotify": false, "packetEncAlgo": -1, "pushProtoFmt": futuFmtJSON, "programmingLanguage": "Go"}
+	if err := conn.call(futuProtoInitConnect, initReq, &initResp); err != nil {
+		return nil, err
+	}
+	var accResp struct {
+		AccList []futuAccount `json:"accList"`
+	}
+	if err := conn.call(futuProtoGetAccList, map[string]any{"userID": strconv.FormatUint(uint64(initResp.LoginUserID), 10), "trdCategory": 1, "needGeneralSecAccount": true}, &accResp); err != nil {
+		return nil, err
+	}
+
+	var positions []Position
+	seen := make(map[string]bool)
+	found := false
+	for _, acc := range accResp.AccList {
+		if acc.TrdEnv != f.trdEnv || acc.AccStatus != 0 || (f.accID != 0 && uint64(acc.AccID) != f.accID) {
+			continue
+		}
+		found = true
+		for _, market := range acc.TrdMarketAuthList {
+			var posResp struct {
+				PositionList []futuPosition `json:"positionList"`
+			}
+			req := map[string]any{"header": futuTrdHeader{TrdEnv: f.trdEnv, AccID: uint64(acc.AccID), TrdMarket: market}, "refreshCache": true}
+			if err := conn.call(futuProtoGetPositionList, req, &posResp); err != nil {
+				return nil, err
+			}
+			for _, p := range posResp.PositionList {
+				symbol, currency, ok := futuSymbol(p, market)
+				if !ok {
+					tools.Logger(ctx).Printf("跳过无法识别市场的富途持仓: %s %s", p.Code, p.Name)
+					continue
+				}
+				// 综合账户的不同市场权限可能返回同一笔持仓
+				key := fmt.Sprintf("%d/%s", acc.AccID, symbol)
+				if p.Qty == 0 || seen[key] {
+					continue
+				}
+				seen[key] = true
+				costBasis := p.AverageCostPrice
+				if costBasis == 0 {
+					costBasis = p.CostPrice
+				}
+				positions = append(positions, Position{
+					Symbol:    symbol,
+					Quantity:  p.Qty,
+					CostBasis: costBasis,
+					Currency:  currency,
+					Source:    f.Name(),
+				})
+			}
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("OpenD 中没有可用的证券账户（FUTU_TRD_ENV、FUTU_ACC_ID）")
+	}
+	return positions, nil
+}
+
+// futuSymbol 把富途的代码和市场转换为本项目的股票代码（0700.HK、AAPL、600519.SS），同时返回持仓币种；
+// 较旧的 OpenD 不返回 secMarket，按查询的交易市场推断
+func futuSymbol(p futuPosition, trdMarket int) (string, string, bool) {
+	market := p.SecMarket
+	if market == 0 {
+		switch trdMarket {
+		case futuTrdMarketHK:
+			market = futuSecMarketHK
+		case futuTrdMarketUS:
+			market = futuSecMarketUS
+		case futuTrdMarketCN, futuTrdMarketHKCC:
+			market = futuSecMarketCNSZ
+			if strings.HasPrefix(p.Code, "6") {
+				market = futuSecMarketCNSH
+			}
+		}
+	}
+	var symbol, currency string
+	switch market {
+	case futuSecMarketHK:
+		code := strings.TrimLeft(p.Code, "0")
+		if len(code) < 4 {
+			code = strings.Repeat("0", 4-len(code)) + code
+		}
+		symbol, currency = code+".HK", "HKD"
+	case futuSecMarketUS:
+		symbol, currency = strings.ToUpper(p.Code), "USD"
+	case futuSecMarketCNSH:
+		symbol, currency = p.Code+".SS", "CNY"
+	case futuSecMarketCNSZ:
+		symbol, currency = p.Code+".SZ", "CNY"
+	case futuSecMarketSG:
+		symbol, currency = p.Code+".SI", "SGD"
+	case futuSecMarketJP:
+		symbol, currency = p.Code+".T", "JPY"
+	default:
+		return "", "", false
+	}
+	if c, ok := futuCurrencies[p.Currency]; ok {
+		currency = c
+	}
+	return symbol, currency, true
+}
+
+// futuConn 与 OpenD 的一条连接，按顺序发送请求并等待对应协议号的响应
+type futuConn struct {
+	conn   net.Conn
+	serial uint32
+	stop   func() bool // 取消 ctx 时关闭连接的回调
+}
+
+// dialFutu 连接 OpenD；ctx 取消时关闭连接，正在进行的读写随之返回
+func dialFutu(ctx context.Context, addr string) (*futuConn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("连接 FutuOpenD（%s）失败，请确认 OpenD 已启动并登录: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(time.Minute))
+	}
+	return &futuConn{conn: conn, stop: context.AfterFunc(ctx, func() { conn.Close() })}, nil
+}
+
+func (c *futuConn) Close() error {
+	c.stop()
+	return c.conn.Close()
+}
+
+// call 发送 {"c2s": c2s} 并把响应中的 s2c 解析到 s2c；跳过 OpenD 推送的其他协议，retType 不为 0 时返回错误
+func (c *futuConn) call(protoID uint32, c2s, s2c any) error {
+	body, err := json.Marshal(map[string]any{"c2s": c2s})
+	if err != nil {
+		return fmt.Errorf("序列化富途请求失败: %w", err)
+	}
+	c.serial++
+	header := make([]byte, futuHeaderLen)
+	copy(header, "FT")
+	binary.LittleEndian.PutUint32(header[2:], protoID)
+	header[6] = futuFmtJSON
+	binary.LittleEndian.PutUint32(header[8:], c.serial)
+	binary.LittleEndian.PutUint32(header[12:], uint32(len(body)))
+	sum := sha1.Sum(body)
+	copy(header[16:36], sum[:])
+	if _, err := c.conn.Write(append(header, body...)); err != nil {
+		return fmt.Errorf("发送富途请求（协议 %d）失败: %w", protoID, err)
+	}
+
+	for {
+		if _, err := io.ReadFull(c.conn, header); err != nil {
+			return fmt.Errorf("读取富途响应（协议 %d）失败: %w", protoID, err)
+		}
+		if string(header[:2]) != "FT" {
+			return fmt.Errorf("无效的富途响应包头")
+		}
+		resp := make([]byte, binary.LittleEndian.Uint32(header[12:]))
+		if _, err := io.ReadFull(c.conn, resp); err != nil {
+			return fmt.Errorf("读取富途响应（协议 %d）失败: %w", protoID, err)
+		}
+		if binary.LittleEndian.Uint32(header[2:]) != protoID {
+			continue
+		}
+		if sum := sha1.Sum(resp); !bytes.Equal(sum[:], header[16:36]) {
+			return fmt.Errorf("富途响应（协议 %d）校验失败", protoID)
+		}
+		if header[6] != futuFmtJSON {
+			return fmt.Errorf("富途响应（协议 %d）不是 JSON 格式，请确认 OpenD 没有启用加密通信", protoID)
+		}
+		var envelope struct {
+			RetType int             `json:"retType"`
+			RetMsg  string          `json:"retMsg"`
+			ErrCode int             `json:"errCode"`
+			S2C     json.RawMessage `json:"s2c"`
+		}
+		if err := json.Unmarshal(resp, &envelope); err != nil {
+			return fmt.Errorf("解析富途响应（协议 %d）失败: %w", protoID, err)
+		}
+		if envelope.RetType != 0 {
+			return fmt.Errorf("富途 OpenD 返回错误（协议 %d）: %s", protoID, envelope.RetMsg)
+		}
+		if len(envelope.S2C) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(envelope.S2C, s2c); err != nil {
+			return fmt.Errorf("解析富途响应（协议 %d）失败: %w", protoID, err)
+		}
+		return nil
+	}
+}
+
+// fetchBrokerResponse 发送 GET 请求并返回响应体，非 200 状态码返回错误
+func fetchBrokerResponse(ctx context.Context, rawURL string, headers map[string]string) ([]byte, error) {
+	resp, err := makeAPIRequest(ctx, cli, rawURL, headers, "GET", nil, 3)
+	if err != nil {
+		return nil, fmt.Errorf("API 请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应体失败: %w", err)
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("券商 API 错误: %w", tools.StatusError(resp.StatusCode, body))
+	}
+	return body, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+)
+
+// writeFutuPacket 按 OpenD 的包格式写出 JSON 包体
+func writeFutuPacket(t *testing.T, conn net.Conn, protoID uint32, body string) {
+	t.Helper()
+	header := make([]byte, futuHeaderLen)
+	copy(header, "FT")
+	binary.LittleEndian.PutUint32(header[2:], protoID)
+	header[6] = futuFmtJSON
+	binary.LittleEndian.PutUint32(header[12:], uint32(len(body)))
+	sum := sha1.Sum([]byte(body))
+	copy(header[16:36], sum[:])
+	if _, err := conn.Write(append(header, body...)); err != nil {
+		t.Error(err)
+	}
+}
+
+// fakeOpenD 模拟 OpenD：一个真实账户开通港股和美股，一个模拟账户；综合账户在两个市场都返回同一笔美股持仓
+func fakeOpenD(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		header := make([]byte, futuHeaderLen)
+		for {
+			if _, err := io.ReadFull(conn, header); err != nil {
+				return
+			}
+			body := make([]byte, binary.LittleEndian.Uint32(header[12:]))
+			if _, err := io.ReadFull(conn, body); err != nil {
+				return
+			}
+			var req struct {
+				C2S struct {
+					Header struct {
+						AccID     string `json:"accID"`
+						TrdMarket int    `json:"trdMarket"`
+					} `json:"header"`
+				} `json:"c2s"`
+			}
+			json.Unmarshal(body, &req)
+			switch protoID := binary.LittleEndian.Uint32(header[2:]); protoID {
+			case futuProtoInitConnect:
+				writeFutuPacket(t, conn, protoID, `{"retType":0,"s2c":{"serverVer":900,"loginUserID":"12345","connID":"1"}}`)
+			case futuProtoGetAccList:
+				writeFutuPacket(t, conn, protoID, `{"retType":0,"s2c":{"accList":[{"trdEnv":1,"accID":"281756","trdMarketAuthList":[1,2]},{"trdEnv":0,"accID":"999","trdMarketAuthList":[2]}]}}`)
+			case futuProtoGetPositionList:
+				if req.C2S.Header.AccID != "281756" {
+					writeFutuPacket(t, conn, protoID, `{"retType":-1,"retMsg":"unexpected account"}`)
+					continue
+				}
+				// 其他协议的推送应被跳过
+				writeFutuPacket(t, conn, 1004, `{"retType":0}`)
+				positions := `{"code":"AAPL","qty":10,"costPrice":150.5,"secMarket":2,"currency":2}`
+				if req.C2S.Header.TrdMarket == futuTrdMarketHK {
+					positions += `,{"code":"00700","qty":200,"costPrice":310,"averageCostPrice":300,"secMarket":1,"currency":1},{"code":"09988","qty":0,"secMarket":1}`
+				}
+				writeFutuPacket(t, conn, protoID, `{"retType":0,"s2c":{"positionList":[`+positions+`]}}`)
+			}
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestFutuImporterFetchPositions(t *testing.T) {
+	importer := &futuImporter{addr: fakeOpenD(t), trdEnv: futuTrdEnvReal}
+	positions, err := importer.FetchPositions(context.Background())
+	if err != nil {
+		t.Fatalf("FetchPositions: %v", err)
+	}
+	want := []Position{
+		{Symbol: "AAPL", Quantity: 10, CostBasis: 150.5, Currency: "USD", Source: "futu"},
+		{Symbol: "0700.HK", Quantity: 200, CostBasis: 300, Currency: "HKD", Source: "futu"},
+	}
+	if len(positions) != len(want) {
+		t.Fatalf("positions = %+v, want %+v", positions, want)
+	}
+	for i := range want {
+		if positions[i].Symbol != want[i].Symbol || positions[i].Quantity != want[i].Quantity || positions[i].CostBasis != want[i].CostBasis || positions[i].Currency != want[i].Currency || positions[i].Source != want[i].Source {
+			t.Errorf("positions[%d] = %+v, want %+v", i, positions[i], want[i])
+		}
+	}
+}
+
+func TestFutuSymbol(t *testing.T) {
+	tests := []struct {
+		position  futuPosition
+		trdMarket int
+		want      string
+	}{
+		{futuPosition{Code: "00005", SecMarket: futuSecMarketHK}, futuTrdMarketHK, "0005.HK"},
+		{futuPosition{Code: "600519", SecMarket: futuSecMarketCNSH}, futuTrdMarketHKCC, "600519.SS"},
+		{futuPosition{Code: "000001"}, futuTrdMarketCN, "000001.SZ"},
+		{futuPosition{Code: "tsla"}, futuTrdMarketUS, "TSLA"},
+	}
+	for _, tt := range tests {
+		if got, _, ok := futuSymbol(tt.position, tt.trdMarket); !ok || got != tt.want {
+			t.Errorf("futuSymbol(%+v) = %s, want %s", tt.position, got, tt.want)
+		}
+	}
+}
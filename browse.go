@@ -0,0 +1,377 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	browseMaxDiffLines  = 2000 // 报告超过该行数时只比较前面的部分
+	browseMaxDiffOutput = 200  // 差异最多输出的行数
+)
+
+// reportBrowser 只读的终端报告浏览器：按股票和日期浏览历史分析，查看摘要卡片、比较两次运行、在分页器中打开报告
+// 只读取 output/runs/ 下的运行记录和本地报告文件，不修改任何输出
+type reportBrowser struct {
+	in      *bufio.Scanner
+	out     io.Writer
+	records []RunRecord // 按开始时间倒序
+}
+
+// runBrowse 处理 browse 子命令：browse [--symbol AAPL] [--portfolio name] [--tag a]
+func runBrowse(args []string) error {
+	fs := flag.NewFlagSet("browse", flag.ExitOnError)
+	var filter runFilter
+	fs.StringVar(&filter.Symbol, "symbol", "", "直接进入该股票的分析列表")
+	fs.StringVar(&filter.Portfolio, "portfolio", "", "只浏览该组合的分析")
+	fs.StringVar(&filter.Tag, "tag", "", "只浏览带该标签的分析")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	records, err := listRunRecords(filter)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		fmt.Println("没有符合条件的分析记录")
+		return nil
+	}
+	b := &reportBrowser{in: bufio.NewScanner(os.Stdin), out: os.Stdout, records: records}
+	if filter.Symbol != "" {
+		if !b.browseSymbol(canonicalSymbol(filter.Symbol)) {
+			return nil
+		}
+	}
+	b.browseSymbols()
+	return nil
+}
+
+// prompt 显示提示符并读取一行输入，输入结束时返回 false
+func (b *reportBrowser) prompt(text string) (string, bool) {
+	fmt.Fprintf(b.out, "\n%s> ", text)
+	if !b.in.Scan() {
+		fmt.Fprintln(b.out)
+		return "", false
+	}
+	return strings.TrimSpace(b.in.Text()), true
+}
+
+// symbols 有分析记录的股票（当前代码），按最近一次分析时间倒序
+func (b *reportBrowser) symbols() []string {
+	var symbols []string
+	seen := make(map[string]bool)
+	for _, r := range b.records {
+		symbol := canonicalSymbol(r.Symbol)
+		if !seen[symbol] {
+			seen[symbol] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+	return symbols
+}
+
+// runsOf 股票的分析记录，包括代码变更前的记录，按开始时间倒序
+func (b *reportBrowser) runsOf(symbol string) []RunRecord {
+	symbol = canonicalSymbol(symbol)
+	var runs []RunRecord
+	for _, r := range b.records {
+		if canonicalSymbol(r.Symbol) == symbol {
+			runs = append(runs, r)
+		}
+	}
+	return runs
+}
+
+// browseSymbols 股票列表：输入序号或代码进入该股票，q 退出
+func (b *reportBrowser) browseSymbols() {
+	for {
+		symbols := b.symbols()
+		fmt.Fprintf(b.out, "\n=== 历史分析（%d 只股票，%d 次分析）===\n", len(symbols), len(b.records))
+		for i, symbol := range symbols {
+			runs := b.runsOf(symbol)
+			fmt.Fprintf(b.out, "%3d. %-10s %d 次  最近 %s  评级=%s\n",
+				i+1, symbol, len(runs), runs[0].StartedAt.Format("2006-01-02 15:04"), orDash(runs[0].Rating))
+		}
+		input, ok := b.prompt("序号或股票代码进入，q 退出")
+		if !ok || input == "q" {
+			return
+		}
+		symbol := canonicalSymbol(input)
+		if n, err := strconv.Atoi(input); err == nil && n >= 1 && n <= len(symbols) {
+			symbol = symbols[n-1]
+		}
+		if len(b.runsOf(symbol)) == 0 {
+			fmt.Fprintf(b.out, "没有 %s 的分析记录\n", symbol)
+			continue
+		}
+		if !b.browseSymbol(symbol) {
+			return
+		}
+	}
+}
+
+// browseSymbol 一只股票的分析列表，返回 false 表示用户要求退出
+func (b *reportBrowser) browseSymbol(symbol string) bool {
+	runs := b.runsOf(symbol)
+	if len(runs) == 0 {
+		fmt.Fprintf(b.out, "没有 %s 的分析记录\n", symbol)
+		return true
+	}
+	for {
+		fmt.Fprintf(b.out, "\n=== %s 的分析 ===\n", symbol)
+		for i, r := range runs {
+			fmt.Fprintf(b.out, "%3d. %s  评级=%s  模型=%s  组合=%s  %s\n",
+				i+1, r.StartedAt.Format("2006-01-02 15:04"), orDash(r.Rating), r.Model, orDash(r.Portfolio), runStatus(r))
+		}
+		input, ok := b.prompt("<n> 摘要卡片，o <n> 打开报告，d <n> <m> 比较两次分析，b 返回，q 退出")
+		if !ok || input == "q" {
+			return false
+		}
+		fields := strings.Fields(input)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "b":
+			return true
+		case "o":
+			if r, ok := b.pick(runs, fields[1:], 1); ok {
+				b.openReport(r[0])
+			}
+		case "d":
+			if r, ok := b.pick(runs, fields[1:], 2); ok {
+				b.diff(r[1], r[0])
+			}
+		default:
+			if r, ok := b.pick(runs, fields, 1); ok {
+				b.showRun(r[0])
+			}
+		}
+	}
+}
+
+// pick 按输入的序号选出 n 次分析，序号无效时提示并返回 false
+func (b *reportBrowser) pick(runs []RunRecord, args []string, n int) ([]RunRecord, bool) {
+	if len(args) != n {
+		fmt.Fprintf(b.out, "需要 %d 个序号\n", n)
+		return nil, false
+	}
+	picked := make([]RunRecord, 0, n)
+	for _, arg := range args {
+		i, err := strconv.Atoi(arg)
+		if err != nil || i < 1 || i > len(runs) {
+			fmt.Fprintf(b.out, "无效的序号: %s\n", arg)
+			return nil, false
+		}
+		picked = append(picked, runs[i-1])
+	}
+	// 按时间倒序，第一个为较新的分析
+	sort.SliceStable(picked, func(i, j int) bool {
+		return picked[i].StartedAt.After(picked[j].StartedAt)
+	})
+	return picked, true
+}
+
+// showRun 显示运行记录的要点和摘要卡片（SUMMARY_CARD=true 时生成）
+func (b *reportBrowser) showRun(r RunRecord) {
+	fmt.Fprintf(b.out, "\n运行 %s\n", r.ID)
+	fmt.Fprintf(b.out, "时间: %s - %s\n", r.StartedAt.Format("2006-01-02 15:04:05"), r.FinishedAt.Format("15:04:05"))
+	fmt.Fprintf(b.out, "评级: %s  模型: %s  状态: %s\n", orDash(r.Rating), r.Model, runStatus(r))
+	fmt.Fprintf(b.out, "标签: %s  提示词: %s  配置: %s\n", orDash(strings.Join(r.Tags, ",")), orDash(r.PromptVersion), orDash(r.ConfigVersion))
+	fmt.Fprintf(b.out, "报告: %s\n", r.ReportPath)
+	if len(r.SkippedData) > 0 {
+		fmt.Fprintf(b.out, "跳过的数据: %s\n", strings.Join(r.SkippedData, ", "))
+	}
+	if r.CardPath == "" {
+		fmt.Fprintln(b.out, "\n（本次分析没有生成摘要卡片，设置 SUMMARY_CARD=true 后生成）")
+		return
+	}
+	card, err := os.ReadFile(r.CardPath)
+	if err != nil {
+		fmt.Fprintf(b.out, "\n读取摘要卡片失败: %v\n", err)
+		return
+	}
+	fmt.Fprintf(b.out, "\n%s\n", card)
+}
+
+// openReport 在 $PAGER（默认 less）中打开完整的 markdown 报告，分页器不可用时直接输出
+func (b *reportBrowser) openReport(r RunRecord) {
+	if _, err := os.Stat(r.ReportPath); err != nil {
+		fmt.Fprintf(b.out, "报告不在本地磁盘: %s\n", r.ReportPath)
+		return
+	}
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = "less"
+	}
+	args := strings.Fields(pager)
+	cmd := exec.Command(args[0], append(args[1:], r.ReportPath)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		data, readErr := os.ReadFile(r.ReportPath)
+		if readErr != nil {
+			fmt.Fprintf(b.out, "读取报告失败: %v\n", readErr)
+			return
+		}
+		fmt.Fprintf(b.out, "%s\n", data)
+	}
+}
+
+// diff 比较两次分析：运行记录的差异、知识库中记录的结论变化，以及两份报告的逐行差异
+// 同一股票和组合的报告保存在同一路径，较早的报告已被覆盖时只比较运行记录和知识库
+func (b *reportBrowser) diff(older, newer RunRecord) {
+	fmt.Fprintf(b.out, "\n=== %s → %s ===\n", older.ID, newer.ID)
+	changed := false
+	for _, f := range []struct{ name, a, b string }{
+		{"评级", older.Rating, newer.Rating},
+		{"模型", older.Model, newer.Model},
+		{"状态", runStatus(older), runStatus(newer)},
+		{"组合", older.Portfolio, newer.Portfolio},
+		{"提示词版本", older.PromptVersion, newer.PromptVersion},
+		{"配置版本", older.ConfigVersion, newer.ConfigVersion},
+	} {
+		if f.a != f.b {
+			fmt.Fprintf(b.out, "%s: %s → %s\n", f.name, orDash(f.a), orDash(f.b))
+			changed = true
+		}
+	}
+	if !changed {
+		fmt.Fprintln(b.out, "评级、模型、提示词和配置版本均相同")
+	}
+
+	knowledge, err := loadCompanyKnowledge(newer.Symbol)
+	if err != nil {
+		fmt.Fprintf(b.out, "%v\n", err)
+	} else if knowledge != nil {
+		oldEntry, newEntry := knowledge.entry(older.ID), knowledge.entry(newer.ID)
+		if oldEntry != nil && newEntry != nil {
+			printListDiff(b.out, "优势", oldEntry.Strengths, newEntry.Strengths)
+			printListDiff(b.out, "风险", oldEntry.Risks, newEntry.Risks)
+			printListDiff(b.out, "待解决的问题", oldEntry.OpenQuestions, newEntry.OpenQuestions)
+		}
+	}
+
+	if older.ReportPath == newer.ReportPath {
+		fmt.Fprintln(b.out, "\n两次分析的报告保存在同一路径，较早的报告已被覆盖，无法逐行比较")
+		return
+	}
+	oldReport, err1 := os.ReadFile(older.ReportPath)
+	newReport, err2 := os.ReadFile(newer.ReportPath)
+	if err1 != nil || err2 != nil {
+		fmt.Fprintln(b.out, "\n报告不在本地磁盘，无法逐行比较")
+		return
+	}
+	fmt.Fprintln(b.out, "\n报告差异（- 较早，+ 较新）：")
+	lines := diffLines(strings.Split(string(oldReport), "\n"), strings.Split(string(newReport), "\n"))
+	if len(lines) == 0 {
+		fmt.Fprintln(b.out, "（报告内容相同）")
+	}
+	for i, line := range lines {
+		if i == browseMaxDiffOutput {
+			fmt.Fprintf(b.out, "…… 另有 %d 行差异，用 o 打开完整报告\n", len(lines)-i)
+			break
+		}
+		fmt.Fprintln(b.out, line)
+	}
+}
+
+// entry 知识库中指定运行的要点，没有记录时返回 nil
+func (k *CompanyKnowledge) entry(runID string) *KnowledgeEntry {
+	for i := range k.Entries {
+		if k.Entries[i].RunID == runID {
+			return &k.Entries[i]
+		}
+	}
+	return nil
+}
+
+// printListDiff 输出两次分析要点列表中新增和不再提及的条目
+func printListDiff(w io.Writer, title string, older, newer []string) {
+	oldSet := make(map[string]bool, len(older))
+	for _, item := range older {
+		oldSet[item] = true
+	}
+	newSet := make(map[string]bool, len(newer))
+	for _, item := range newer {
+		newSet[item] = true
+	}
+	for _, item := range newer {
+		if !oldSet[item] {
+			fmt.Fprintf(w, "%s 新增: %s\n", title, item)
+		}
+	}
+	for _, item := range older {
+		if !newSet[item] {
+			fmt.Fprintf(w, "%s 不再提及: %s\n", title, item)
+		}
+	}
+}
+
+// diffLines 基于最长公共子序列的逐行差异，只返回删除（- ）和新增（+ ）的行
+func diffLines(a, b []string) []string {
+	a = a[:min(len(a), browseMaxDiffLines)]
+	b = b[:min(len(b), browseMaxDiffLines)]
+	// lcs[i][j] 为 a[i:] 和 b[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var out []string
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, "- "+a[i])
+			i++
+		default:
+			out = append(out, "+ "+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out = append(out, "- "+a[i])
+	}
+	for ; j < len(b); j++ {
+		out = append(out, "+ "+b[j])
+	}
+	return out
+}
+
+// runStatus 运行的完成状态，与 runs 子命令一致
+func runStatus(r RunRecord) string {
+	switch {
+	case r.Truncated:
+		return "截断"
+	case r.StepLimited:
+		return "步数上限"
+	}
+	return "完成"
+}
+
+// orDash 空字符串显示为 -
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
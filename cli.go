@@ -0,0 +1,508 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cliCommand 一个子命令：用法行、帮助说明、失败时日志的前缀和入口，入口收到子命令名之后的参数
+type cliCommand struct {
+	Name    string
+	Summary string
+	Usage   []string // 用法行，不含程序名；shell 补全从中提取二级子命令和参数名
+	Help    string   // help <command> 显示的详细说明，可以为空
+	Failure string   // 失败时的日志前缀
+	Run     func(args []string) error
+}
+
+// 子命令处理函数报告用法错误时也使用的用法行
+const (
+	analyzeUsage = "analyze <stock_symbol>... [--watchlist watchlist.txt] [--workers 3] [--timeout 10m] [--tool-timeout 2m] [--transcript none|reasoning|full] [--stream formatted|raw] [--no-llm-cache] [--market auto|us|cn|hk] [--depth quick|standard|full] [--portfolio name] [--tag a,b]"
+	screenUsage  = "screen [--index sp500|nasdaq100|csi300 | --tickers a,b] [--min-roe 0.15] [--max-pe 25] [--max-de 1] [--min-revenue-growth 0] [--top 20] [--sort roe|qvm] [--weights quality=0.4,value=0.3,momentum=0.3]"
+	compareUsage = "compare <stock_symbol> <stock_symbol>... [--years 3]"
+	rankUsage    = "rank (--watchlist watchlist.txt | --tickers a,b | --index sp500) [--weights quality=0.4,value=0.3,momentum=0.3] [--top 50]"
+)
+
+// cliCommands 全部子命令，按帮助中的顺序排列；第一个参数不是子命令名时按股票代码分析（等同于 analyze）
+var cliCommands = []cliCommand{
+	{
+		Name:    "analyze",
+		Summary: "分析一只或多只股票并保存报告",
+		Usage:   []string{analyzeUsage},
+		Help:    "第一个参数不是子命令名时按股票代码分析，与 analyze 相同。分析参数可以写在股票代码之前或之后，没有在命令行设置的参数使用 ANALYSIS_TIMEOUT、TOOL_TIMEOUT、TRANSCRIPT、STREAM_MODE、MARKET、ANALYSIS_DEPTH、ANALYSIS_WORKERS 环境变量的值。传入多只股票或 --watchlist 关注列表时按 --workers 并发分析，每只股票单独保存报告，最后打印评级、用时和报告位置的汇总表；关注列表每行一只股票，代码之后的内容为备注，分析时附加到提示词，请 Agent 在报告中回应；并发时不向终端流式输出报告，也不录制数据快照。",
+		Failure: "分析失败",
+		Run:     runAnalyzeCommand,
+	},
+	{
+		Name:    "screen",
+		Summary: "按财务指标筛选指数成分股或给定的股票",
+		Usage:   []string{screenUsage},
+		Help:    "股票池为 --index 指定指数的成分股（先运行 universe refresh 下载）或 --tickers 给定的股票。指标取最新的 TTM 财务指标，比例类条件为小数形式；条件对应的指标缺失的股票不列出。",
+		Failure: "筛选失败",
+		Run:     runScreen,
+	},
+	{
+		Name:    "compare",
+		Summary: "并排比较几只股票的关键指标和偏好排序（不调用模型）",
+		Usage:   []string{compareUsage},
+		Help:    "按行业报告的方法计算偏好排序、关键指标对比、中位数和近几个财年的趋势，只在终端输出数据表，不调用模型也不保存报告。需要叙述性结论时使用 industry。",
+		Failure: "比较失败",
+		Run:     runCompare,
+	},
+	{
+		Name:    "rank",
+		Summary: "按质量-估值-动量综合评分给关注列表或一组股票排序（不调用模型）",
+		Usage:   []string{rankUsage},
+		Help:    "质量（ROE、毛利率、营业利润率、负债权益比）、估值（市盈率、市净率、EV/EBITDA、自由现金流收益率）和动量（12-1 个月、6 个月涨跌幅）三项子得分为各指标在这组股票之间百分位的平均值，再按 --weights 或 QVM_WEIGHTS 的权重合成 0-100 的综合得分。得分是相对排名，股票池不同时不可比较；数据不足的股票排在最后。screen --sort qvm 使用同样的评分。",
+		Failure: "排序失败",
+		Run:     runRank,
+	},
+	{
+		Name:    "portfolio",
+		Summary: "管理组合持仓，支持从券商 API 导入",
+		Usage: []string{
+			"portfolio import <ibkr|alpaca|futu> [--name default]",
+			"portfolio show [--name default]",
+			"portfolio report [--name default] [--years 1]",
+		},
+		Failure: "组合操作失败",
+		Run:     runPortfolio,
+	},
+	{
+		Name:    "serve",
+		Summary: "以 HTTP 服务方式运行，提示词和 .env 配置修改后无需重启",
+		Usage:   []string{"serve [--addr :8080] [--timeout 10m] [--reload-interval 2s]"},
+		Failure: "服务运行失败",
+		Run:     runServe,
+	},
+	{
+		Name:    "runs",
+		Summary: "列出历史分析记录",
+		Usage:   []string{"runs [--symbol AAPL] [--portfolio name] [--tag a]"},
+		Failure: "查询分析记录失败",
+		Run:     runListRuns,
+	},
+	{
+		Name:    "history",
+		Summary: "按时间列出一只股票的历次分析评级和交易日志决策",
+		Usage:   []string{"history <stock_symbol> [--limit 20]"},
+		Help:    "分析记录来自 output/runs/，决策来自 output/journal/，代码变更前旧代码下的记录一并列出。评级较上一次分析上调或下调时标注 ↑/↓。",
+		Failure: "查询历史失败",
+		Run:     runHistory,
+	},
+	{
+		Name:    "watch",
+		Summary: "定时刷新几只股票的实时行情，价格变动超过阈值时提示",
+		Usage:   []string{"watch <stock_symbol>... [--interval 5m] [--move 0.03] [--count 0]"},
+		Help: "每隔 --interval 获取一次实时行情，显示价格、当日涨跌和最近一次分析的评级。" +
+			"价格相对上一次提示（首次为开始时）的变动超过 --move 时打印提示；--count 为刷新次数，0 表示一直运行到 Ctrl-C。",
+		Failure: "行情监控失败",
+		Run:     runWatch,
+	},
+	{
+		Name:    "performance",
+		Summary: "统计历史评级之后的实际收益和准确率",
+		Usage:   []string{"performance [--symbol AAPL] [--portfolio name] [--tag a]"},
+		Failure: "统计评级表现失败",
+		Run:     runPerformance,
+	},
+	{
+		Name:    "browse",
+		Summary: "在终端中浏览历史报告、摘要卡片和两次分析的差异（只读）",
+		Usage:   []string{"browse [--symbol AAPL] [--portfolio name] [--tag a]"},
+		Failure: "浏览报告失败",
+		Run:     runBrowse,
+	},
+	{
+		Name:    "journal",
+		Summary: "记录投资决策和理由，重新分析时报告对照以前的投资逻辑",
+		Usage: []string{
+			"journal add <stock_symbol> --decision buy|add|hold|trim|sell|watch|pass --note \"thesis\" [--run id] [--price 0] [--tag a,b]",
+			"journal list [--symbol AAPL] [--decision buy]",
+			"journal show <stock_symbol>",
+		},
+		Failure: "交易日志操作失败",
+		Run:     runJournal,
+	},
+	{
+		Name:    "statements",
+		Summary: "导入未上市或数据源未覆盖公司的财务报表，分析时由报表计算财务指标",
+		Usage: []string{
+			"statements import <stock_symbol> <file.csv|file.json> [--currency EUR]",
+			"statements show <stock_symbol> [--period annual|quarterly|ttm]",
+			"statements list | statements remove <stock_symbol>",
+		},
+		Failure: "财务报表操作失败",
+		Run:     runStatements,
+	},
+	{
+		Name:    "export",
+		Summary: "导出价格和财务指标历史为 Parquet 文件",
+		Usage:   []string{"export <stock_symbol> [years]"},
+		Failure: "导出失败",
+		Run:     runExportCommand,
+	},
+	{
+		Name:    "universe",
+		Summary: "下载和查看指数成分股（筛选和回测使用的股票池）",
+		Usage:   []string{"universe list | universe show <index> | universe refresh <index|all> [--file constituents.csv]"},
+		Failure: "成分股操作失败",
+		Run:     runUniverse,
+	},
+	{
+		Name:    "snapshot",
+		Summary: "数据快照的导出和离线重跑，回放时不要求本机配置 API 密钥",
+		Usage: []string{
+			"snapshot export <stock_symbol> [--run id] [--out file]",
+			"snapshot import <file> [--timeout 10m]",
+		},
+		Failure: "快照操作失败",
+		Run:     runSnapshot,
+	},
+	{
+		Name:    "openapi",
+		Summary: "根据服务接口定义生成 OpenAPI 文档和 Go 客户端",
+		Usage:   []string{"openapi [--out api/openapi.json] [--client client/client.go]"},
+		Failure: "生成 OpenAPI 文档失败",
+		Run:     runOpenAPI,
+	},
+	{
+		Name:    "bench",
+		Summary: "用多个模型分析同一只股票，对比报告、耗时和成本",
+		Usage:   []string{"bench <stock_symbol> --models deepseek,gemini,openai [--timeout 10m]"},
+		Failure: "模型对比失败",
+		Run:     runBench,
+	},
+	{
+		Name:    "doctor",
+		Summary: "检查配置、提示词、输出目录和数据源连接，一次列出所有问题",
+		Usage:   []string{"doctor [--online] [--symbol AAPL]"},
+		Help:    "依次检查配置文件、分析所需的配置（与分析开始前的检查相同）、提示词文件和输出目录是否可写；--online 时再用 --symbol 请求一次实时行情，检查数据源密钥和网络。有检查未通过时以非零状态退出。",
+		Failure: "环境检查未通过",
+		Run:     runDoctor,
+	},
+	{
+		Name:    "store",
+		Summary: "查看和查询 SQLite 数据库中保存的价格、财务指标、新闻、内部人交易和分析记录",
+		Usage:   []string{"store status | store query \"SELECT ...\" [--limit 100]"},
+		Help:    "设置 DATA_STORE=sqlite 后，分析时获取的数据和运行记录同时写入 DATA_STORE_PATH（默认 output/investment.db），打开数据库时自动执行结构迁移。query 在只读连接上执行，data 列为数据源返回的完整 JSON，可以用 json_extract 查询。需要用 go build -tags sqlite 构建（先 go get modernc.org/sqlite）。",
+		Failure: "数据库操作失败",
+		Run:     runStore,
+	},
+	{
+		Name:    "validate",
+		Summary: "校验输出目录中保存的 JSON 结果文件，列出损坏或不完整的文件",
+		Usage:   []string{"validate [--dir output] [--kind metrics,news,analysis,structured_report] [--verbose]"},
+		Help:    "逐个检查工具中间结果、结构化结论、摘要卡片、行业报告、模型对比、评级表现和运行记录：先检查能否解析（空文件、写入中断造成的截断），再按写入时的 Go 类型生成的 JSON Schema 检查字段类型和必需字段，最后检查内容（文件名与股票代码一致、结构化结论的评级和估值区间、运行记录引用的报告是否存在）。前一阶段未通过时不再检查后面的阶段；有文件未通过时以非零状态退出。适合在程序崩溃或手工编辑结果文件之后运行。",
+		Failure: "校验失败",
+		Run:     runValidate,
+	},
+	{
+		Name:    "industry",
+		Summary: "多只股票的行业概览报告，给出行业趋势和偏好排序",
+		Usage:   []string{"industry <name> --tickers NVDA,AMD,INTC,TSM [--years 3] [--timeout 5m]"},
+		Failure: "生成行业报告失败",
+		Run:     runIndustry,
+	},
+}
+
+// help 和 completion 的入口要遍历 cliCommands，在 init 中加入以避免初始化循环
+func init() {
+	cliCommands = append(cliCommands,
+		cliCommand{
+			Name:    "help",
+			Summary: "显示全部子命令或一个子命令的用法和说明",
+			Usage:   []string{"help [command]"},
+			Failure: "显示帮助失败",
+			Run:     runHelp,
+		},
+		cliCommand{
+			Name:    "completion",
+			Summary: "输出 shell 补全脚本",
+			Usage:   []string{"completion bash|zsh"},
+			Help:    "bash: source <(./investment completion bash)；zsh: ./investment completion zsh > \"${fpath[1]}/_investment\"。补全子命令名、二级子命令和各子命令用法行中的参数名。",
+			Failure: "生成补全脚本失败",
+			Run:     runCompletion,
+		},
+	)
+}
+
+// globalFlagNames 所有子命令共用的全局参数，可以写在子命令之前或之后
+var globalFlagNames = []string{"env-file", "config"}
+
+// extractGlobalFlags 从子命令参数中取出全局参数并设置到 fs，返回其余参数；遇到 "--" 后不再处理
+func extractGlobalFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return append(rest, args[i:]...), nil
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || !slices.Contains(globalFlagNames, name) {
+			rest = append(rest, arg)
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("参数 -%s 缺少取值", name)
+			}
+			i++
+			value = args[i]
+		}
+		if err := fs.Set(name, value); err != nil {
+			return nil, fmt.Errorf("无效的 -%s: %v", name, err)
+		}
+	}
+	return rest, nil
+}
+
+// parseSymbolList 解析命令行给出的股票代码：每项可以是逗号分隔的多个代码，统一为大写，去掉空项和重复项，保持原有顺序
+func parseSymbolList(items ...string) []string {
+	var symbols []string
+	for _, item := range items {
+		for _, symbol := range strings.Split(item, ",") {
+			symbol = strings.ToUpper(strings.TrimSpace(symbol))
+			if symbol != "" && !slices.Contains(symbols, symbol) {
+				symbols = append(symbols, symbol)
+			}
+		}
+	}
+	return symbols
+}
+
+// findCommand 按名称查找子命令，不是子命令时返回 nil
+func findCommand(name string) *cliCommand {
+	for i := range cliCommands {
+		if cliCommands[i].Name == name {
+			return &cliCommands[i]
+		}
+	}
+	return nil
+}
+
+// printUsage 列出全部子命令的用法、示例、全局参数和分析参数
+func printUsage() {
+	fmt.Println("Usage: investment_assistant [global flags] [analyze flags] <stock_symbol>...")
+	fmt.Println("       investment_assistant [global flags] <command> [flags]")
+	fmt.Println()
+	fmt.Println("Commands:")
+	for _, command := range cliCommands {
+		fmt.Printf("  %-12s %s\n", command.Name, command.Summary)
+		for _, usage := range command.Usage {
+			fmt.Printf("               investment_assistant %s\n", usage)
+		}
+	}
+	fmt.Println()
+	fmt.Println("Example: investment_assistant AAPL")
+	fmt.Println("Example: investment_assistant analyze TSLA --timeout 5m")
+	fmt.Println("Example: investment_assistant AAPL MSFT NVDA GOOG --workers 2")
+	fmt.Println("Example: investment_assistant --watchlist watchlist.txt")
+	fmt.Println("Example: investment_assistant --transcript full MSFT")
+	fmt.Println("Example: investment_assistant 0700.HK")
+	fmt.Println("Example: investment_assistant --portfolio dividend --tag core,q3-review KO")
+	fmt.Println("Example: investment_assistant screen --index sp500 --min-roe 0.2 --max-pe 30")
+	fmt.Println("Example: investment_assistant compare AAPL MSFT GOOGL")
+	fmt.Println("Example: investment_assistant watch AAPL NVDA --interval 1m")
+	fmt.Println("Example: investment_assistant runs --portfolio dividend")
+	fmt.Println("Example: investment_assistant export AAPL 5")
+	fmt.Println()
+	fmt.Println("Global flags（可以写在子命令之前或之后）:")
+	printFlags(flag.CommandLine, func(name string) bool { return slices.Contains(globalFlagNames, name) })
+	fmt.Println()
+	fmt.Println("Analyze flags（写在股票代码或子命令之前；也可以写在 analyze 子命令之后）:")
+	printFlags(flag.CommandLine, func(name string) bool { return !slices.Contains(globalFlagNames, name) })
+	fmt.Println()
+	fmt.Println("运行 investment_assistant help <command> 查看子命令的详细说明")
+}
+
+// printFlags 按 flag.PrintDefaults 的格式输出 fs 中满足 include 的参数
+func printFlags(fs *flag.FlagSet, include func(name string) bool) {
+	subset := flag.NewFlagSet(fs.Name(), flag.ContinueOnError)
+	subset.SetOutput(os.Stdout)
+	fs.VisitAll(func(f *flag.Flag) {
+		if include(f.Name) {
+			subset.Var(f.Value, f.Name, f.Usage)
+		}
+	})
+	subset.PrintDefaults()
+}
+
+// runHelp 处理 help 子命令：没有参数时列出全部子命令，否则显示一个子命令的用法和说明
+func runHelp(args []string) error {
+	if len(args) == 0 {
+		printUsage()
+		return nil
+	}
+	command := findCommand(args[0])
+	if command == nil {
+		return fmt.Errorf("未知的子命令: %s（可用: %s）", args[0], strings.Join(commandNames(), ", "))
+	}
+	fmt.Printf("%s - %s\n\n", command.Name, command.Summary)
+	fmt.Println("Usage:")
+	for _, usage := range command.Usage {
+		fmt.Printf("  investment_assistant %s\n", usage)
+	}
+	if command.Help != "" {
+		fmt.Printf("\n%s\n", command.Help)
+	}
+	if command.Name == "analyze" {
+		fmt.Println("\nFlags:")
+		printFlags(flag.CommandLine, func(name string) bool { return !slices.Contains(globalFlagNames, name) })
+	} else if commandFlags(*command) != nil {
+		fmt.Printf("\n各参数的说明见 investment_assistant %s -h\n", command.Name)
+	}
+	return nil
+}
+
+// commandNames 全部子命令名
+func commandNames() []string {
+	names := make([]string, len(cliCommands))
+	for i, command := range cliCommands {
+		names[i] = command.Name
+	}
+	return names
+}
+
+// analyzeFlags 分析一只股票的参数
+type analyzeFlags struct {
+	Timeout     time.Duration
+	ToolTimeout time.Duration
+	Tag         string
+	Portfolio   string
+	Transcript  string
+	Stream      string
+	Market      string
+	Depth       string
+	Workers     int    // 同时分析多只股票时的并发数
+	Watchlist   string // 关注列表文件，其中的股票与命令行传入的股票一起批量分析
+	NoLLMCache  bool
+}
+
+// analyzeDefaults 写在子命令之前的分析参数（已合并环境变量），analyze 子命令以此为默认值
+var analyzeDefaults analyzeFlags
+
+// analyzeFlagEnv 可以用环境变量设置默认值的分析参数
+var analyzeFlagEnv = map[string]string{
+	"timeout":      "ANALYSIS_TIMEOUT",
+	"tool-timeout": "TOOL_TIMEOUT",
+	"transcript":   "TRANSCRIPT",
+	"stream":       "STREAM_MODE",
+	"market":       "MARKET",
+	"depth":        "ANALYSIS_DEPTH",
+	"workers":      "ANALYSIS_WORKERS",
+}
+
+// defaultAnalyzeFlags 分析参数的默认值
+func defaultAnalyzeFlags() analyzeFlags {
+	return analyzeFlags{
+		Timeout:     10 * time.Minute,
+		ToolTimeout: 2 * time.Minute,
+		Transcript:  TranscriptNone,
+		Stream:      StreamFormatted,
+		Market:      MarketAuto,
+		Depth:       DepthFull,
+		Workers:     3,
+	}
+}
+
+// register 在 fs 上定义分析参数，以 o 的当前值为默认值
+func (o *analyzeFlags) register(fs *flag.FlagSet) {
+	fs.DurationVar(&o.Timeout, "timeout", o.Timeout, "整个分析的最长时间，超时后输出带截断说明的部分报告（0 表示不限制）")
+	fs.DurationVar(&o.ToolTimeout, "tool-timeout", o.ToolTimeout, "单次工具调用的最长时间（0 表示不限制）")
+	fs.StringVar(&o.Tag, "tag", o.Tag, "为本次分析添加标签，多个标签用逗号分隔，如 growth,tech")
+	fs.StringVar(&o.Portfolio, "portfolio", o.Portfolio, "本次分析所属的组合，报告保存到 output/report/<portfolio>/ 下")
+	fs.StringVar(&o.Transcript, "transcript", o.Transcript, "报告附录中保存的分析过程：none（只保留结论）、reasoning（附加中间推理）、full（附加推理、工具调用和工具结果）")
+	fs.StringVar(&o.Stream, "stream", o.Stream, "终端流式输出模式：formatted（标注每个章节的用时）、raw（原样输出模型内容）")
+	fs.BoolVar(&o.NoLLMCache, "no-llm-cache", o.NoLLMCache, "本次分析不读取也不写入模型响应缓存（LLM_CACHE=true 时有效）")
+	fs.StringVar(&o.Depth, "depth", o.Depth, "分析深度：quick（概况、核心财务、新闻和估值）、standard（增加信用、营运资本、内部人、管理层、同行和交易层面）、full（全部工具）；没有数据的工具（如非美股的内部人交易和年报章节）总是跳过")
+	fs.StringVar(&o.Watchlist, "watchlist", o.Watchlist, "关注列表文件：每行一只股票，代码之后的内容为备注（分析时请 Agent 在报告中回应），# 开头的行为注释；与命令行传入的股票一起批量分析")
+	fs.IntVar(&o.Workers, "workers", o.Workers, "传入多只股票时同时分析的数量，所有分析共用数据源限速（DATA_API_RATE_LIMIT）")
+	fs.StringVar(&o.Market, "market", o.Market, "股票所属市场：auto（按代码后缀识别，.SS/.SZ 为 A 股，.HK 为港股，其他为美股）、us、cn、hk，决定模型、报告单位、币种和基准的默认值")
+}
+
+// validate 检查参数取值
+func (o *analyzeFlags) validate() error {
+	if err := validateTranscriptLevel(o.Transcript); err != nil {
+		return err
+	}
+	if err := validateStreamMode(o.Stream); err != nil {
+		return err
+	}
+	if err := validMarket(o.Market); err != nil {
+		return err
+	}
+	if err := validateDepth(o.Depth); err != nil {
+		return err
+	}
+	if o.Workers < 1 {
+		return fmt.Errorf("--workers 必须大于 0: %d", o.Workers)
+	}
+	if o.Portfolio != "" && !validPortfolioName(o.Portfolio) {
+		return fmt.Errorf("无效的组合名称: %s", o.Portfolio)
+	}
+	return nil
+}
+
+// runAnalyzeCommand 处理 analyze 子命令，参数可以写在股票代码之前或之后；传入多只股票或关注列表时批量分析
+func runAnalyzeCommand(args []string) error {
+	usage := fmt.Errorf("用法: %s", analyzeUsage)
+	opts := analyzeDefaults
+	fs := flag.NewFlagSet("analyze", flag.ExitOnError)
+	opts.register(fs)
+
+	var symbols []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		symbols, args = append(symbols, args[0]), args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	symbols = append(symbols, fs.Args()...)
+	if opts.Watchlist == "" {
+		switch len(symbols) {
+		case 0:
+			return usage
+		case 1:
+			return runAnalyze(opts, symbols[0])
+		}
+	}
+	var entries []watchlistEntry
+	for _, symbol := range parseSymbolList(symbols...) {
+		entries = append(entries, watchlistEntry{Symbol: symbol})
+	}
+	if opts.Watchlist != "" {
+		watchlist, err := readWatchlist(opts.Watchlist)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, watchlist...)
+	}
+	return runBatch(opts, entries)
+}
+
+// runExportCommand 处理 export 子命令：export <stock_symbol> [years]
+func runExportCommand(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("用法: export <stock_symbol> [years]")
+	}
+	years := 5
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			return fmt.Errorf("无效的年数: %s", args[1])
+		}
+		years = n
+	}
+	if err := runExport(context.Background(), strings.ToUpper(args[0]), years); err != nil {
+		return err
+	}
+	fmt.Printf("📦 数据已导出到 output/export 目录\n")
+	return nil
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseSymbolList(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []string
+		want  []string
+	}{
+		{name: "空输入", want: nil},
+		{name: "位置参数", items: []string{"aapl", "MSFT"}, want: []string{"AAPL", "MSFT"}},
+		{name: "逗号分隔", items: []string{" aapl, msft ,,googl"}, want: []string{"AAPL", "MSFT", "GOOGL"}},
+		{name: "混合并去重", items: []string{"AAPL,msft", "aapl", "0700.hk"}, want: []string{"AAPL", "MSFT", "0700.HK"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseSymbolList(tt.items...); !slices.Equal(got, tt.want) {
+				t.Errorf("得到 %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
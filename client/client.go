@@ -0,0 +1,317 @@
+// Code generated by "investment openapi" from serverRoutes in openapi.go; DO NOT EDIT.
+
+// Package client 是分析服务（investment serve）的 Go 客户端，接口与 api/openapi.json 一致
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type APICacheStats struct {
+	MemoryHits  int64 `json:"memory_hits"`
+	DiskHits    int64 `json:"disk_hits"`
+	Misses      int64 `json:"misses"`
+	Revalidated int64 `json:"revalidated,omitempty"`
+}
+
+type AnalysisJob struct {
+	ID            string          `json:"id"`              // 任务 ID
+	Symbol        string          `json:"symbol"`          // 股票代码
+	Status        string          `json:"status"`          // 任务状态
+	Error         string          `json:"error,omitempty"` // 任务失败的原因
+	PromptVersion string          `json:"prompt_version"`  // 任务使用的提示词版本
+	CreatedAt     time.Time       `json:"created_at"`      // 任务创建时间
+	Progress      JobProgress     `json:"progress"`        // 任务进度，每完成一次工具调用或一个章节时保存检查点
+	Quota         *JobQuotaStatus `json:"quota,omitempty"` // 任务的配额和当前用量，配置了 JOB_MAX_* 或任务时间上限时返回
+	Run           *RunRecord      `json:"run,omitempty"`
+}
+
+type AnalysisPlan struct {
+	Depth   string            `json:"depth"`
+	Market  string            `json:"market"`
+	Stages  []PlannedStage    `json:"stages"`
+	Skipped []PlanSkippedTool `json:"skipped,omitempty"`
+}
+
+type AnalyzeRequestBody struct {
+	Symbol     string   `json:"symbol"`               // 股票代码，如 AAPL、600519.SS
+	Portfolio  string   `json:"portfolio,omitempty"`  // 组合名称
+	Tags       []string `json:"tags,omitempty"`       // 运行记录的标签
+	Transcript string   `json:"transcript,omitempty"` // 推理过程记录级别，默认 none
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"` // 错误信息
+}
+
+type JobProgress struct {
+	ToolCalls     int        `json:"tool_calls"`               // 已成功完成的工具调用次数
+	Sections      []string   `json:"sections,omitempty"`       // 报告中已输出完成的章节
+	Resumed       int        `json:"resumed,omitempty"`        // 服务重启后恢复任务的次数
+	ResumedAt     *time.Time `json:"resumed_at,omitempty"`     // 最近一次恢复的时间
+	ReplayedCalls int        `json:"replayed_calls,omitempty"` // 最近一次恢复后直接从检查点返回的工具调用次数
+}
+
+type JobQuotaStatus struct {
+	MaxToolCalls   int      `json:"max_tool_calls,omitempty"`   // 工具调用次数上限，未设置时不限制
+	MaxToolBytes   int      `json:"max_tool_bytes,omitempty"`   // 工具从数据源获取的响应累计字节数上限
+	MaxResultBytes int      `json:"max_result_bytes,omitempty"` // 单次工具结果的字节数上限
+	MaxTokens      int      `json:"max_tokens,omitempty"`       // 模型 token 用量上限
+	MaxWallTime    string   `json:"max_wall_time,omitempty"`    // 任务的最长运行时间
+	ToolCalls      int      `json:"tool_calls"`                 // 已执行的工具调用次数（不含检查点回放）
+	ToolBytes      int64    `json:"tool_bytes"`                 // 工具从数据源获取的响应累计字节数（包括响应缓存命中）
+	Tokens         int      `json:"tokens"`                     // 模型 token 用量（输入加输出）
+	Exceeded       []string `json:"exceeded,omitempty"`         // 达到上限的配额：tool_calls、tool_bytes、result_bytes、tokens、wall_time；工具配额用完后 Agent 以已获取的数据完成报告，tokens 和 wall_time 达到上限时输出部分报告
+}
+
+type PlanSkippedTool struct {
+	Tool   string `json:"tool"`
+	Reason string `json:"reason"`
+}
+
+type PlannedStage struct {
+	Name  string   `json:"name"`
+	Tools []string `json:"tools"`
+}
+
+type PromptsResponse struct {
+	Version string `json:"version"` // 提示词内容哈希
+	System  string `json:"system"`  // 系统提示词
+	User    string `json:"user"`    // 用户提示词模板，{symbol} 为股票代码
+}
+
+type ReportResponse struct {
+	RunID      string `json:"run_id"`           // 运行记录 ID
+	Symbol     string `json:"symbol"`           // 股票代码
+	Rating     string `json:"rating,omitempty"` // 报告中的投资评级，未识别时为空
+	ReportPath string `json:"report_path"`      // 报告文件位置
+	Content    string `json:"content"`          // markdown 报告全文
+}
+
+type RunRecord struct {
+	ID             string         `json:"id"`
+	Symbol         string         `json:"symbol"`
+	Portfolio      string         `json:"portfolio,omitempty"`
+	Tags           []string       `json:"tags,omitempty"`
+	Note           string         `json:"note,omitempty"`
+	Model          string         `json:"model"`
+	StartedAt      time.Time      `json:"started_at"`
+	FinishedAt     time.Time      `json:"finished_at"`
+	Truncated      bool           `json:"truncated"`
+	StepLimited    bool           `json:"step_limited,omitempty"`
+	Rating         string         `json:"rating,omitempty"`
+	ReportPath     string         `json:"report_path"`
+	PromptVersion  string         `json:"prompt_version,omitempty"`
+	ConfigVersion  string         `json:"config_version,omitempty"`
+	SnapshotPath   string         `json:"snapshot_path,omitempty"`
+	CardPath       string         `json:"card_path,omitempty"`
+	PriorRuns      int            `json:"prior_runs,omitempty"`
+	JournalEntries int            `json:"journal_entries,omitempty"`
+	SkippedData    []string       `json:"skipped_data,omitempty"`
+	Plan           *AnalysisPlan  `json:"plan,omitempty"`
+	Cache          *APICacheStats `json:"cache,omitempty"`
+}
+
+type Watchlist struct {
+	Name    string           `json:"name"`    // 关注列表名称
+	Path    string           `json:"path"`    // 关注列表文件，可直接用于 --watchlist
+	Entries []WatchlistEntry `json:"entries"` // 关注的股票，按文件中的顺序排列
+}
+
+type WatchlistEntry struct {
+	Symbol string `json:"symbol"`         // 股票代码
+	Note   string `json:"note,omitempty"` // 备注，分析时附加到用户提示词，请 Agent 在报告中回应
+}
+
+type WatchlistRequestBody struct {
+	Entries []WatchlistEntry `json:"entries"` // 关注的股票，整体替换原有列表，重复的股票只保留第一条
+}
+
+// Client 分析服务的客户端
+type Client struct {
+	BaseURL    string       // 服务地址，如 http://localhost:8080
+	HTTPClient *http.Client // 为空时使用 http.DefaultClient
+}
+
+// New 创建客户端
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// APIError 服务返回的错误响应
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("分析服务返回 %d: %s", e.StatusCode, e.Message)
+}
+
+// do 发送请求并解析响应，状态码与 status 不一致时返回 *APIError
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body any, status int, out any) error {
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("序列化请求失败: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != status {
+		var e ErrorResponse
+		if json.NewDecoder(resp.Body).Decode(&e) != nil || e.Error == "" {
+			e.Error = http.StatusText(resp.StatusCode)
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: e.Error}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+	return nil
+}
+
+// Analyze 创建分析任务，立即返回任务信息，分析在后台执行
+// POST /analyze
+func (c *Client) Analyze(ctx context.Context, body AnalyzeRequestBody) (*AnalysisJob, error) {
+	var out AnalysisJob
+	if err := c.do(ctx, "POST", "/analyze", nil, body, 202, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetJob 查询任务状态，任务成功后包含运行记录
+// GET /jobs/{id}
+func (c *Client) GetJob(ctx context.Context, id string) (*AnalysisJob, error) {
+	var out AnalysisJob
+	if err := c.do(ctx, "GET", strings.NewReplacer("{id}", url.PathEscape(id)).Replace("/jobs/{id}"), nil, nil, 200, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListRunsParams ListRuns 的查询参数，空字符串表示不筛选
+type ListRunsParams struct {
+	Symbol    string // 股票代码
+	Portfolio string // 组合名称
+	Tag       string // 标签
+}
+
+// ListRuns 按股票代码、组合和标签筛选历史运行记录
+// GET /runs
+func (c *Client) ListRuns(ctx context.Context, params ListRunsParams) ([]RunRecord, error) {
+	query := url.Values{}
+	if params.Symbol != "" {
+		query.Set("symbol", params.Symbol)
+	}
+	if params.Portfolio != "" {
+		query.Set("portfolio", params.Portfolio)
+	}
+	if params.Tag != "" {
+		query.Set("tag", params.Tag)
+	}
+	var out []RunRecord
+	if err := c.do(ctx, "GET", "/runs", query, nil, 200, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetRunReport 返回运行记录对应的报告全文，只能读取使用本地输出保存的报告
+// GET /runs/{id}/report
+func (c *Client) GetRunReport(ctx context.Context, id string) (*ReportResponse, error) {
+	var out ReportResponse
+	if err := c.do(ctx, "GET", strings.NewReplacer("{id}", url.PathEscape(id)).Replace("/runs/{id}/report"), nil, nil, 200, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListWatchlists 列出全部关注列表
+// GET /watchlists
+func (c *Client) ListWatchlists(ctx context.Context) ([]Watchlist, error) {
+	var out []Watchlist
+	if err := c.do(ctx, "GET", "/watchlists", nil, nil, 200, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetWatchlist 查询关注列表
+// GET /watchlists/{name}
+func (c *Client) GetWatchlist(ctx context.Context, name string) (*Watchlist, error) {
+	var out Watchlist
+	if err := c.do(ctx, "GET", strings.NewReplacer("{name}", url.PathEscape(name)).Replace("/watchlists/{name}"), nil, nil, 200, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PutWatchlist 创建关注列表或整体替换已有列表
+// PUT /watchlists/{name}
+func (c *Client) PutWatchlist(ctx context.Context, name string, body WatchlistRequestBody) (*Watchlist, error) {
+	var out Watchlist
+	if err := c.do(ctx, "PUT", strings.NewReplacer("{name}", url.PathEscape(name)).Replace("/watchlists/{name}"), nil, body, 200, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteWatchlist 删除关注列表，返回删除前的内容
+// DELETE /watchlists/{name}
+func (c *Client) DeleteWatchlist(ctx context.Context, name string) (*Watchlist, error) {
+	var out Watchlist
+	if err := c.do(ctx, "DELETE", strings.NewReplacer("{name}", url.PathEscape(name)).Replace("/watchlists/{name}"), nil, nil, 200, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetPrompts 返回当前生效的提示词版本和内容
+// GET /prompts
+func (c *Client) GetPrompts(ctx context.Context) (*PromptsResponse, error) {
+	var out PromptsResponse
+	if err := c.do(ctx, "GET", "/prompts", nil, nil, 200, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetOpenAPISpec 返回本服务的 OpenAPI 3 文档
+// GET /openapi.json
+func (c *Client) GetOpenAPISpec(ctx context.Context) (map[string]any, error) {
+	var out map[string]any
+	if err := c.do(ctx, "GET", "/openapi.json", nil, nil, 200, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
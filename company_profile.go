@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"investment/tools"
+
+	"golang.org/x/net/html"
+)
+
+// 公司简介配置
+const (
+	profileDescriptionRunes = 3000             // 年报业务章节截取的最大字符数
+	profileWebsiteTimeout   = 15 * time.Second // 抓取官网页面的最长时间
+	profileWebsiteMaxBytes  = 2 << 20          // 官网页面最多读取的字节数
+)
+
+// getCompanyOverview 获取公司基本信息和业务描述：优先使用最近一份 10-K 的业务章节，
+// 没有年报时（如外国发行人、新上市公司）抓取官网首页的 description / og:description
+func getCompanyOverview(ctx context.Context, symbol string) (*tools.CompanyOverview, error) {
+	facts, err := GetCompanyFacts(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	overview := &tools.CompanyOverview{
+		Name:        facts.Name,
+		Sector:      facts.Sector,
+		Industry:    facts.Industry,
+		Exchange:    facts.Exchange,
+		Location:    facts.Location,
+		ListingDate: facts.ListingDate,
+		Employees:   facts.NumberOfEmployees,
+		Website:     facts.WebsiteURL,
+	}
+
+	if text, year, url := latestBusinessSection(ctx, symbol, profileDescriptionRunes); text != "" {
+		overview.Description = text
+		overview.DescriptionSource = tools.DescriptionSourceFiling
+		overview.DescriptionYear = year
+		overview.SourceURL = url
+		return overview, nil
+	}
+	if facts.WebsiteURL != "" {
+		description, err := websiteDescription(ctx, facts.WebsiteURL)
+		if err == nil && description != "" {
+			overview.Description = description
+			overview.DescriptionSource = tools.DescriptionSourceWebsite
+			overview.SourceURL = facts.WebsiteURL
+		}
+	}
+	return overview, nil
+}
+
+// websiteDescription 抓取公司官网页面，返回 meta description（优先）或 og:description
+func websiteDescription(ctx context.Context, rawURL string) (string, error) {
+	if !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
+		rawURL = "https://" + rawURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("无效的官网地址: %w", err)
+	}
+	req.Header.Set("User-Agent", "investment-buddy/1.0")
+	resp, err := newHTTPClient(profileWebsiteTimeout).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("请求官网失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("请求官网失败: 状态码 %d", resp.StatusCode)
+	}
+	doc, err := html.Parse(io.LimitReader(resp.Body, profileWebsiteMaxBytes))
+	if err != nil {
+		return "", fmt.Errorf("解析官网页面失败: %w", err)
+	}
+
+	var description, ogDescription string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "meta" {
+			var name, content string
+			for _, attr := range n.Attr {
+				switch strings.ToLower(attr.Key) {
+				case "name", "property":
+					name = strings.ToLower(attr.Val)
+				case "content":
+					content = strings.Join(strings.Fields(attr.Val), " ")
+				}
+			}
+			switch {
+			case name == "description" && description == "":
+				description = content
+			case name == "og:description" && ogDescription == "":
+				ogDescription = content
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	if description != "" {
+		return description, nil
+	}
+	return ogDescription, nil
+}
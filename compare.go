@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"investment/tools"
+)
+
+// runCompare 处理 compare 子命令：收集几只股票的数据，按行业报告的方法计算偏好排序、指标对比和趋势，
+// 只在终端输出数据表，不调用模型也不保存报告
+func runCompare(args []string) error {
+	usage := fmt.Errorf("用法: %s", compareUsage)
+	var symbols []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		symbols, args = append(symbols, args[0]), args[1:]
+	}
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	years := fs.Int("years", 3, "趋势回溯的财年数（1-10）")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	tickers := parseSymbolList(append(symbols, fs.Args()...)...)
+	if len(tickers) < 2 {
+		return usage
+	}
+	if *years < 1 || *years > 10 {
+		return fmt.Errorf("无效的 --years: %d（1-10）", *years)
+	}
+	if err := configError(sharedConfigProblems()); err != nil {
+		return err
+	}
+	format, err := tools.NewNumberFormat(os.Getenv("REPORT_LOCALE"))
+	if err != nil {
+		return err
+	}
+
+	report := &IndustryReport{Industry: "比较", GeneratedAt: time.Now()}
+	for i, symbol := range tickers {
+		symbol = canonicalSymbol(symbol)
+		fmt.Printf("[%d/%d] 收集 %s 的数据...\n", i+1, len(tickers), symbol)
+		company := collectIndustryCompany(context.Background(), symbol, *years)
+		if company.Error != "" {
+			fmt.Printf("⚠️ %s 数据不完整: %s\n", symbol, company.Error)
+		}
+		report.Companies = append(report.Companies, company)
+	}
+	if err := rankIndustryCompanies(report.Companies); err != nil {
+		return err
+	}
+	report.Medians = industryMedians(report.Companies)
+	report.Trends = industryTrends(report.Companies, *years)
+
+	fmt.Println()
+	fmt.Println(renderIndustryDataTables(report, format))
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"investment/tools"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// newLLMCompetitiveAssessor 创建基于大模型的竞争格局评估器：作为子分析步骤，只根据传入的公司简介、
+// 指标和组内排名比较市场地位和定价权，不调用工具
+func newLLMCompetitiveAssessor(generator *structuredGenerator) tools.CompetitiveAssessor {
+	return func(ctx context.Context, symbol string, companies []tools.CompetitorSnapshot, rankings []tools.PeerMetricComparison) (*tools.CompetitiveAssessment, error) {
+		companiesJSON, err := json.Marshal(companies)
+		if err != nil {
+			return nil, fmt.Errorf("序列化公司数据失败: %w", err)
+		}
+		rankingsJSON, err := json.Marshal(rankings)
+		if err != nil {
+			return nil, fmt.Errorf("序列化指标排名失败: %w", err)
+		}
+
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("你是竞争战略分析师。以下是 %s 及其主要竞争对手的业务描述和最新 TTM 指标（第一家为 %s），以及 %s 各项指标在组内的排名（rank 1 为最好）。\n", symbol, symbol, symbol))
+		sb.WriteString("请评估：\n")
+		sb.WriteString(fmt.Sprintf("1. %s 的市场地位（%s）\n", symbol, strings.Join(tools.MarketPositions, "/")))
+		sb.WriteString("2. 定价权（强/中/弱），主要依据毛利率、营运利润率相对竞争对手的水平和业务描述中的差异化因素\n")
+		sb.WriteString("3. 相对竞争对手的优势、劣势和主要竞争威胁\n")
+		sb.WriteString(fmt.Sprintf("4. 竞争地位评分（1 到 %d，%d 表示具有明显且可持续的竞争优势），并用一段话说明理由\n", tools.CompetitiveMaxScore, tools.CompetitiveMaxScore))
+		sb.WriteString("只根据给出的数据判断，数据缺失的公司不要推测其指标。\n")
+		sb.WriteString(`只输出 JSON，格式为 {"market_position":"","pricing_power":"","score":0,"advantages":[""],"disadvantages":[""],"threats":[""],"rationale":""}`)
+		sb.WriteString("\n\n## 公司数据\n\n")
+		sb.Write(companiesJSON)
+		sb.WriteString("\n\n## 组内排名\n\n")
+		sb.Write(rankingsJSON)
+
+		var assessment tools.CompetitiveAssessment
+		err = generator.generate(ctx, []*schema.Message{
+			schema.UserMessage(sb.String()),
+		}, &assessment, assessment.Validate)
+		if err != nil {
+			return nil, fmt.Errorf("模型评估失败: %w", err)
+		}
+		return &assessment, nil
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// completionFlagPattern 用法行中的参数名
+var completionFlagPattern = regexp.MustCompile(`--[a-z][a-z0-9-]*`)
+
+// completionWordPattern 用法行中的二级子命令，如 portfolio import、completion bash|zsh
+var completionWordPattern = regexp.MustCompile(`^[a-z]+(\|[a-z]+)*$`)
+
+// commandFlags 子命令用法行中出现的参数名（带 --），按出现顺序去重
+func commandFlags(command cliCommand) []string {
+	var flags []string
+	for _, usage := range command.Usage {
+		for _, name := range completionFlagPattern.FindAllString(usage, -1) {
+			if !slices.Contains(flags, name) {
+				flags = append(flags, name)
+			}
+		}
+	}
+	return flags
+}
+
+// commandSubcommands 子命令用法行中紧跟子命令名的二级子命令或固定取值，如 portfolio 的 import、show、report
+func commandSubcommands(command cliCommand) []string {
+	var words []string
+	for _, usage := range command.Usage {
+		for _, alternative := range strings.Split(usage, " | ") {
+			fields := strings.Fields(alternative)
+			if len(fields) < 2 || fields[0] != command.Name || !completionWordPattern.MatchString(fields[1]) {
+				continue
+			}
+			for _, word := range strings.Split(fields[1], "|") {
+				if !slices.Contains(words, word) {
+					words = append(words, word)
+				}
+			}
+		}
+	}
+	return words
+}
+
+// globalFlagWords 全局参数的补全词
+func globalFlagWords() []string {
+	words := make([]string, len(globalFlagNames))
+	for i, name := range globalFlagNames {
+		words[i] = "--" + name
+	}
+	return words
+}
+
+// runCompletion 处理 completion 子命令：根据 cliCommands 生成 bash 或 zsh 补全脚本并输出到标准输出
+func runCompletion(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("用法: completion bash|zsh")
+	}
+	switch args[0] {
+	case "bash":
+		fmt.Print(bashCompletion())
+	case "zsh":
+		fmt.Print(zshCompletion())
+	default:
+		return fmt.Errorf("不支持的 shell: %s（可用: bash, zsh）", args[0])
+	}
+	return nil
+}
+
+// bashCompletion bash 补全脚本：第一个词补全子命令名，第二个词补全二级子命令，其余补全参数名
+func bashCompletion() string {
+	var sb strings.Builder
+	sb.WriteString("# investment bash completion，由 ./investment completion bash 生成\n")
+	sb.WriteString("_investment() {\n")
+	sb.WriteString("    local cur=\"${COMP_WORDS[COMP_CWORD]}\" subcommands=\"\" flags=\"\"\n")
+	fmt.Fprintf(&sb, "    if [[ $COMP_CWORD -eq 1 ]]; then\n        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n        return\n    fi\n",
+		strings.Join(append(commandNames(), globalFlagWords()...), " "))
+	sb.WriteString("    case \"${COMP_WORDS[1]}\" in\n")
+	for _, command := range cliCommands {
+		fmt.Fprintf(&sb, "        %s) subcommands=%q; flags=%q ;;\n", command.Name,
+			strings.Join(commandSubcommands(command), " "), strings.Join(append(commandFlags(command), globalFlagWords()...), " "))
+	}
+	sb.WriteString("    esac\n")
+	sb.WriteString("    if [[ $COMP_CWORD -eq 2 && \"$cur\" != -* && -n \"$subcommands\" ]]; then\n")
+	sb.WriteString("        COMPREPLY=($(compgen -W \"$subcommands\" -- \"$cur\"))\n")
+	sb.WriteString("    elif [[ \"$cur\" == -* ]]; then\n")
+	sb.WriteString("        COMPREPLY=($(compgen -W \"$flags\" -- \"$cur\"))\n")
+	sb.WriteString("    fi\n")
+	sb.WriteString("}\n")
+	sb.WriteString("complete -o default -F _investment investment investment_assistant\n")
+	return sb.String()
+}
+
+// zshCompletion zsh 补全脚本，既可以放入 $fpath 自动加载，也可以直接 source
+func zshCompletion() string {
+	var sb strings.Builder
+	sb.WriteString("#compdef investment investment_assistant\n")
+	sb.WriteString("# investment zsh completion，由 ./investment completion zsh 生成\n\n")
+	sb.WriteString("_investment() {\n")
+	sb.WriteString("    if (( CURRENT == 2 )); then\n")
+	sb.WriteString("        local -a commands\n        commands=(\n")
+	for _, command := range cliCommands {
+		fmt.Fprintf(&sb, "            '%s:%s'\n", command.Name, zshQuote(command.Summary))
+	}
+	sb.WriteString("        )\n")
+	sb.WriteString("        _describe 'command' commands\n")
+	fmt.Fprintf(&sb, "        compadd -- %s\n        return\n    fi\n", strings.Join(globalFlagWords(), " "))
+	sb.WriteString("    local -a subcommands flags\n")
+	sb.WriteString("    case ${words[2]} in\n")
+	for _, command := range cliCommands {
+		fmt.Fprintf(&sb, "        %s) subcommands=(%s); flags=(%s) ;;\n", command.Name,
+			strings.Join(commandSubcommands(command), " "), strings.Join(append(commandFlags(command), globalFlagWords()...), " "))
+	}
+	sb.WriteString("    esac\n")
+	sb.WriteString("    if (( CURRENT == 3 )) && [[ ${words[CURRENT]} != -* ]] && (( ${#subcommands} )); then\n")
+	sb.WriteString("        compadd -- $subcommands\n")
+	sb.WriteString("    elif [[ ${words[CURRENT]} == -* ]]; then\n")
+	sb.WriteString("        compadd -- $flags\n")
+	sb.WriteString("    else\n")
+	sb.WriteString("        _files\n")
+	sb.WriteString("    fi\n")
+	sb.WriteString("}\n\n")
+	sb.WriteString("if [[ $zsh_eval_context[-1] == loadautofunc ]]; then\n")
+	sb.WriteString("    _investment \"$@\"\n")
+	sb.WriteString("else\n")
+	sb.WriteString("    compdef _investment investment investment_assistant\n")
+	sb.WriteString("fi\n")
+	return sb.String()
+}
+
+// zshQuote 转义放在单引号中的 _describe 说明文本
+func zshQuote(s string) string {
+	return strings.NewReplacer("'", `'\''`, ":", `\:`).Replace(s)
+}
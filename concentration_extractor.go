@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"investment/tools"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// newLLMConcentrationExtractor 创建基于大模型的客户/供应商集中度抽取器
+func newLLMConcentrationExtractor(generator *structuredGenerator) tools.ConcentrationExtractor {
+	return func(ctx context.Context, symbol string, excerpts []string) (*tools.ConcentrationDisclosure, error) {
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("以下是 %s 年报中与客户、供应商相关的段落。请提取：\n", symbol))
+		sb.WriteString("1. 主要客户（名称、收入占比或描述）\n2. 主要供应商（名称、依赖程度或描述，如单一来源）\n3. 集中度相关披露（如前N大客户占比、单一供应商依赖等）\n\n")
+		sb.WriteString("只提取原文中明确提到的信息，不要推测。若原文未点名，可使用原文中的描述（如\"一家大型分销商\"）作为名称。\n")
+		sb.WriteString(`只输出 JSON，格式为 {"customers":[{"name":"","relationship":"customer","share":"","notes":""}],"suppliers":[{"name":"","relationship":"supplier","share":"","notes":""}],"concentration_notes":[""]}`)
+		sb.WriteString("\n\n")
+		for i, excerpt := range excerpts {
+			sb.WriteString(fmt.Sprintf("[%d] %s\n\n", i+1, excerpt))
+		}
+
+		var disclosure tools.ConcentrationDisclosure
+		validate := func() error {
+			for _, c := range disclosure.Customers {
+				if c.Name == "" || c.Relationship != "customer" {
+					return fmt.Errorf("客户条目缺少名称或 relationship 不为 customer: %+v", c)
+				}
+			}
+			for _, s := range disclosure.Suppliers {
+				if s.Name == "" || s.Relationship != "supplier" {
+					return fmt.Errorf("供应商条目缺少名称或 relationship 不为 supplier: %+v", s)
+				}
+			}
+			return nil
+		}
+		err := generator.generate(ctx, []*schema.Message{
+			schema.UserMessage(sb.String()),
+		}, &disclosure, validate)
+		if err != nil {
+			return nil, fmt.Errorf("模型提取失败: %w", err)
+		}
+		return &disclosure, nil
+	}
+}
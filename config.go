@@ -0,0 +1,484 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"investment/tools"
+
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultEnvFiles 默认的配置文件，前面的优先：.env.local 用于本机覆盖（不提交到仓库），.env 为共享配置
+var defaultEnvFiles = []string{".env.local", ".env"}
+
+// defaultStructuredConfigFiles 默认的分节配置文件，使用第一个存在的；优先级低于 .env 文件
+var defaultStructuredConfigFiles = []string{"investment.yaml", "investment.yml", "investment.toml"}
+
+// envConfig 配置加载器，优先级从高到低：命令行参数 > 环境变量 > 配置文件（.env.local > .env > investment.yaml） > 默认值
+// 启动时已存在的环境变量不会被配置文件覆盖，服务模式重新加载配置文件时同样保持这一顺序
+type envConfig struct {
+	mu      sync.Mutex
+	files   []string        // 按优先级从高到低
+	process map[string]bool // 启动时进程环境中已有的变量
+	applied map[string]bool // 上次从配置文件写入环境的变量，重新加载时删除文件中已不存在的
+}
+
+// appEnv 当前进程使用的配置，由 loadEnvConfig 初始化
+var appEnv = &envConfig{files: defaultEnvFiles}
+
+// loadEnvConfig 记录进程环境并加载配置文件
+// envFile 为 --env-file 指定的文件，必须存在，替代默认的 .env 文件；未指定时加载存在的默认文件，都不存在时只使用环境变量
+// configFile 为 --config 指定的分节配置文件（YAML 或 TOML），未指定时使用 CONFIG_FILE，再退回 investment.yaml 等默认文件
+func loadEnvConfig(envFile, configFile string) error {
+	files := defaultEnvFiles
+	if envFile != "" {
+		if _, err := os.Stat(envFile); err != nil {
+			return fmt.Errorf("读取配置文件 %s 失败: %w", envFile, err)
+		}
+		files = []string{envFile}
+	}
+	structured, err := structuredConfigFile(configFile)
+	if err != nil {
+		return err
+	}
+	if structured != "" {
+		files = append(slices.Clone(files), structured)
+	}
+
+	process := make(map[string]bool)
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		process[key] = true
+	}
+
+	appEnv.mu.Lock()
+	appEnv.files = files
+	appEnv.process = process
+	appEnv.mu.Unlock()
+
+	loaded, err := appEnv.apply()
+	if err != nil {
+		return err
+	}
+	if len(loaded) == 0 {
+		log.Printf("未找到配置文件（%s），只使用环境变量", strings.Join(files, "、"))
+	} else {
+		log.Printf("已加载配置文件: %s", strings.Join(loaded, "、"))
+	}
+	return nil
+}
+
+// apply 读取存在的配置文件并写入环境变量，启动时已存在的变量保持不变，返回实际加载的文件
+// 上次加载时写入、但已从配置文件中删除的变量会被删除，恢复为未设置（使用默认值）
+func (c *envConfig) apply() ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	values := make(map[string]string)
+	var loaded []string
+	// 从低优先级到高优先级读取，高优先级文件中的同名变量覆盖低优先级的
+	for i := len(c.files) - 1; i >= 0; i-- {
+		path := c.files[i]
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		fileValues, err := readConfigFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("解析配置文件 %s 失败: %w", path, err)
+		}
+		for key, value := range fileValues {
+			values[key] = value
+		}
+		loaded = append([]string{path}, loaded...)
+	}
+	applied := make(map[string]bool, len(values))
+	for key, value := range values {
+		if !c.process[key] {
+			os.Setenv(key, value)
+			applied[key] = true
+		}
+	}
+	for key := range c.applied {
+		if !applied[key] {
+			os.Unsetenv(key)
+		}
+	}
+	c.applied = applied
+	return loaded, nil
+}
+
+// version 配置文件内容的版本号，记录在运行记录中
+func (c *envConfig) version() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return configVersion(c.files...)
+}
+
+// applyEnvDefaults 命令行中没有显式设置的参数使用对应环境变量的值（含配置文件中的值），
+// 保证命令行参数优先于环境变量；names 为参数名到环境变量名的映射
+func applyEnvDefaults(fs *flag.FlagSet, names map[string]string) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	for name, envName := range names {
+		value := os.Getenv(envName)
+		if explicit[name] || value == "" {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("无效的 %s: %s（%v）", envName, value, err)
+		}
+	}
+	return nil
+}
+
+// structuredConfigFile 本次使用的分节配置文件：显式指定的文件必须存在，默认文件都不存在时返回空字符串
+func structuredConfigFile(configFile string) (string, error) {
+	if configFile == "" {
+		configFile = os.Getenv("CONFIG_FILE")
+	}
+	if configFile != "" {
+		if _, err := os.Stat(configFile); err != nil {
+			return "", fmt.Errorf("读取配置文件 %s 失败: %w", configFile, err)
+		}
+		if !isStructuredConfig(configFile) {
+			return "", fmt.Errorf("不支持的配置文件格式: %s（支持 .yaml、.yml、.toml）", configFile)
+		}
+		return configFile, nil
+	}
+	for _, path := range defaultStructuredConfigFiles {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", nil
+}
+
+// isStructuredConfig 按扩展名判断是否为分节配置文件
+func isStructuredConfig(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".toml":
+		return true
+	}
+	return false
+}
+
+// readConfigFile 读取一个配置文件中的变量：.env 格式直接读取，YAML/TOML 按 structuredConfigKeys 换算为环境变量名
+func readConfigFile(path string) (map[string]string, error) {
+	if !isStructuredConfig(path) {
+		return godotenv.Read(path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		err = toml.Unmarshal(data, &doc)
+	} else {
+		err = yaml.Unmarshal(data, &doc)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	var unknown []string
+	flattenConfig("", doc, func(key string, value any) {
+		name, ok := structuredConfigKeys[key]
+		if !ok {
+			unknown = append(unknown, key)
+			return
+		}
+		values[name] = configValue(value)
+	})
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("未知的配置项: %s（可用的配置项见 investment.example.yaml）", strings.Join(unknown, ", "))
+	}
+	return values, nil
+}
+
+// flattenConfig 把嵌套的配置展开为以 . 连接的键，如 model.deepseek.api_key；取值为空的项跳过
+func flattenConfig(prefix string, node map[string]any, emit func(key string, value any)) {
+	for key, value := range node {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case nil:
+			// 空的节或没有取值的项视为未设置
+		case map[string]any:
+			flattenConfig(key, v, emit)
+		default:
+			emit(key, value)
+		}
+	}
+}
+
+// configValue 把配置值转为环境变量的字符串形式，列表（如多个 API 密钥）以逗号连接
+func configValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = configValue(item)
+		}
+		return strings.Join(parts, ",")
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// structuredConfigKeys YAML/TOML 配置项到环境变量的映射；按模型、数据源、输出、报告、Agent、工具等分节，
+// 每项都与一个环境变量对应，环境变量和 .env 文件中的值优先
+var structuredConfigKeys = map[string]string{
+	"model.type":                  "MODEL_TYPE",
+	"model.proxy":                 "LLM_PROXY",
+	"model.file_inputs":           "MODEL_FILE_INPUTS",
+	"model.cache":                 "LLM_CACHE",
+	"model.deepseek.api_key":      "DEEPSEEK_API_KEY",
+	"model.deepseek.model_name":   "DEEPSEEK_MODEL_NAME",
+	"model.deepseek.base_url":     "DEEPSEEK_BASE_URL",
+	"model.deepseek.proxy":        "DEEPSEEK_PROXY",
+	"model.deepseek.price_input":  "DEEPSEEK_PRICE_INPUT",
+	"model.deepseek.price_output": "DEEPSEEK_PRICE_OUTPUT",
+	"model.openai.api_key":        "OPENAI_API_KEY",
+	"model.openai.model_name":     "OPENAI_MODEL_NAME",
+	"model.openai.base_url":       "OPENAI_BASE_URL",
+	"model.openai.proxy":          "OPENAI_PROXY",
+	"model.openai.price_input":    "OPENAI_PRICE_INPUT",
+	"model.openai.price_output":   "OPENAI_PRICE_OUTPUT",
+	"model.gemini.api_key":        "GEMINI_API_KEY",
+	"model.gemini.model_name":     "GEMINI_MODEL_NAME",
+	"model.gemini.proxy":          "GEMINI_PROXY",
+	"model.gemini.price_input":    "GEMINI_PRICE_INPUT",
+	"model.gemini.price_output":   "GEMINI_PRICE_OUTPUT",
+
+	"embedding.provider":   "EMBEDDING_PROVIDER",
+	"embedding.base_url":   "EMBEDDING_BASE_URL",
+	"embedding.model_name": "EMBEDDING_MODEL_NAME",
+
+	"data.provider":                    "DATA_PROVIDER",
+	"data.financial_datasets.api_key":  "FINANCIAL_DATASETS_API_KEY",
+	"data.financial_datasets.base_url": "FINANCIAL_DATASETS_BASE_URL",
+	"data.financial_datasets.proxy":    "FINANCIAL_DATASETS_PROXY",
+	"data.alpha_vantage.api_key":       "ALPHA_VANTAGE_API_KEY",
+	"data.alpha_vantage.rate_limit":    "ALPHA_VANTAGE_RATE_LIMIT",
+	"data.proxy":                       "DATA_API_PROXY",
+	"data.ca_bundle":                   "CA_BUNDLE",
+	"data.max_retries":                 "DATA_API_MAX_RETRIES",
+	"data.rate_limit":                  "DATA_API_RATE_LIMIT",
+	"data.retry_budget":                "RETRY_BUDGET",
+	"data.cache_ttl":                   "API_CACHE_TTL",
+	"data.cache_endpoint_ttl":          "API_CACHE_ENDPOINT_TTL",
+	"data.store":                       "DATA_STORE",
+	"data.store_path":                  "DATA_STORE_PATH",
+	"data.cache_memory_entries":        "API_CACHE_MEMORY_ENTRIES",
+	"data.snapshot_record":             "SNAPSHOT_RECORD",
+	"data.license_file":                "DATA_LICENSE_FILE",
+	"data.symbol_history_file":         "SYMBOL_HISTORY_FILE",
+	"data.peer_sets_file":              "PEER_SETS_FILE",
+
+	"brokers.ibkr.flex_token":       "IBKR_FLEX_TOKEN",
+	"brokers.ibkr.flex_query_id":    "IBKR_FLEX_QUERY_ID",
+	"brokers.alpaca.api_key_id":     "ALPACA_API_KEY_ID",
+	"brokers.alpaca.api_secret_key": "ALPACA_API_SECRET_KEY",
+	"brokers.alpaca.base_url":       "ALPACA_BASE_URL",
+	"brokers.futu.opend_addr":       "FUTU_OPEND_ADDR",
+	"brokers.futu.trd_env":          "FUTU_TRD_ENV",
+	"brokers.futu.acc_id":           "FUTU_ACC_ID",
+
+	"output.dir":         "OUTPUT_DIR",
+	"output.sink":        "OUTPUT_SINK",
+	"output.s3.bucket":   "OUTPUT_S3_BUCKET",
+	"output.s3.prefix":   "OUTPUT_S3_PREFIX",
+	"output.s3.endpoint": "OUTPUT_S3_ENDPOINT",
+	"output.prompts_dir": "PROMPTS_DIR",
+
+	"report.locale":                       "REPORT_LOCALE",
+	"report.sectioned":                    "SECTIONED_REPORT",
+	"report.structured":                   "STRUCTURED_REPORT",
+	"report.summary_card":                 "SUMMARY_CARD",
+	"report.verify_numbers":               "VERIFY_NUMBERS",
+	"report.transcript":                   "TRANSCRIPT",
+	"report.stream_mode":                  "STREAM_MODE",
+	"report.knowledge_base":               "KNOWLEDGE_BASE",
+	"report.shareholder_return_benchmark": "SHAREHOLDER_RETURN_BENCHMARK",
+
+	"agent.market":               "MARKET",
+	"agent.depth":                "ANALYSIS_DEPTH",
+	"agent.workers":              "ANALYSIS_WORKERS",
+	"agent.max_steps":            "AGENT_MAX_STEPS",
+	"agent.tool_max_parallelism": "TOOL_MAX_PARALLELISM",
+	"agent.timeout":              "ANALYSIS_TIMEOUT",
+	"agent.tool_timeout":         "TOOL_TIMEOUT",
+
+	"tools.schema_locale":                  "TOOL_SCHEMA_LOCALE",
+	"tools.news.llm_categorize":            "NEWS_LLM_CATEGORIZE",
+	"tools.news.sentiment":                 "NEWS_SENTIMENT",
+	"tools.news.sentiment_batch_size":      "NEWS_SENTIMENT_BATCH_SIZE",
+	"tools.news.sentiment_concurrency":     "NEWS_SENTIMENT_CONCURRENCY",
+	"tools.insider_trades.max_records":     "INSIDER_TRADES_MAX_RECORDS",
+	"tools.insider_trades.max_window_days": "INSIDER_TRADES_MAX_WINDOW_DAYS",
+	"tools.credit_risk.de_threshold":       "CREDIT_RISK_DE_THRESHOLD",
+	"tools.industry_benchmark.universe":    "INDUSTRY_BENCHMARK_UNIVERSE",
+	"tools.industry_benchmark.ttl_hours":   "INDUSTRY_BENCHMARK_TTL_HOURS",
+	"tools.similarity.universe":            "SIMILARITY_UNIVERSE",
+
+	"ranking.qvm_weights": "QVM_WEIGHTS",
+
+	"server.max_running":          "JOB_MAX_RUNNING",
+	"server.job.max_tool_calls":   "JOB_MAX_TOOL_CALLS",
+	"server.job.max_tool_bytes":   "JOB_MAX_TOOL_BYTES",
+	"server.job.max_result_bytes": "JOB_MAX_RESULT_BYTES",
+	"server.job.max_tokens":       "JOB_MAX_TOKENS",
+
+	"event_bus.type":         "EVENT_BUS",
+	"event_bus.url":          "EVENT_BUS_URL",
+	"event_bus.topic_prefix": "EVENT_BUS_TOPIC_PREFIX",
+}
+
+// modelCredentials 各模型类型必需的环境变量
+var modelCredentials = map[string][]string{
+	"deepseek": {"DEEPSEEK_API_KEY", "DEEPSEEK_MODEL_NAME"},
+	"openai":   {"OPENAI_API_KEY", "OPENAI_MODEL_NAME"},
+	"gemini":   {"GEMINI_API_KEY", "GEMINI_MODEL_NAME"},
+}
+
+// ConfigError 配置检查发现的全部问题
+type ConfigError struct {
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("配置有误（共 %d 项），请修改环境变量或配置文件后重试：", len(e.Problems)))
+	for _, problem := range e.Problems {
+		sb.WriteString("\n  - ")
+		sb.WriteString(problem)
+	}
+	return sb.String()
+}
+
+// validateAnalysisConfig 在创建模型和 Agent 之前检查分析所需的配置，一次列出所有问题，
+// 而不是在分析中途第一次调用模型或数据源时才失败；没有问题时返回 nil
+func validateAnalysisConfig() error {
+	modelType := os.Getenv("MODEL_TYPE")
+	effective := modelType
+	if effective == "" {
+		effective = "deepseek"
+	}
+	problems := modelConfigProblems(modelType)
+	if os.Getenv("MODEL_FILE_INPUTS") == "true" && effective != "gemini" && effective != "openai" {
+		problems = append(problems, fmt.Sprintf("MODEL_FILE_INPUTS=true 只支持 MODEL_TYPE=gemini 或 openai，当前为 %s", effective))
+	}
+	if _, err := analysisDepth(""); err != nil {
+		problems = append(problems, fmt.Sprintf("ANALYSIS_DEPTH %v", err))
+	}
+	if err := tools.ValidateSchemaLocale(toolSchemaLocale()); err != nil {
+		problems = append(problems, fmt.Sprintf("TOOL_SCHEMA_LOCALE %v", err))
+	}
+	return configError(append(problems, sharedConfigProblems()...))
+}
+
+// toolSchemaLocale 向模型描述工具使用的语言（TOOL_SCHEMA_LOCALE），未设置时为中文
+func toolSchemaLocale() string {
+	locale := strings.ToLower(strings.TrimSpace(os.Getenv("TOOL_SCHEMA_LOCALE")))
+	if locale == "" {
+		return tools.SchemaLocaleZh
+	}
+	return locale
+}
+
+// validateBenchConfig 检查 bench 对比的每个模型的密钥、价格配置和共用的数据源配置
+func validateBenchConfig(modelTypes []string) error {
+	var problems []string
+	for _, modelType := range modelTypes {
+		problems = append(problems, modelConfigProblems(modelType)...)
+		if _, err := modelPricingFromEnv(modelType); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	return configError(append(problems, sharedConfigProblems()...))
+}
+
+// modelConfigProblems 检查模型类型是否受支持以及必需的密钥和模型名称，空类型按 deepseek 处理
+func modelConfigProblems(modelType string) []string {
+	effective := modelType
+	if effective == "" {
+		effective = "deepseek"
+	}
+	required, ok := modelCredentials[effective]
+	if !ok {
+		return []string{fmt.Sprintf("MODEL_TYPE=%s 不受支持（可选 deepseek、openai、gemini）", modelType)}
+	}
+	var problems []string
+	for _, name := range required {
+		if os.Getenv(name) == "" {
+			problems = append(problems, fmt.Sprintf("MODEL_TYPE=%s 需要设置 %s", effective, name))
+		}
+	}
+	return problems
+}
+
+// sharedConfigProblems 检查与模型类型无关的数据源和运行参数配置
+func sharedConfigProblems() []string {
+	var problems []string
+
+	problems = append(problems, dataProviderProblems()...)
+	problems = append(problems, networkConfigProblems()...)
+	if strings.ToLower(os.Getenv("EMBEDDING_PROVIDER")) == EmbeddingOpenAI && os.Getenv("OPENAI_API_KEY") == "" {
+		problems = append(problems, "EMBEDDING_PROVIDER=openai 需要设置 OPENAI_API_KEY")
+	}
+
+	for _, name := range []string{"AGENT_MAX_STEPS", "TOOL_MAX_PARALLELISM", "DATA_API_MAX_RETRIES", "DATA_API_RATE_LIMIT", "NEWS_SENTIMENT_BATCH_SIZE", "NEWS_SENTIMENT_CONCURRENCY", "INSIDER_TRADES_MAX_RECORDS", "INSIDER_TRADES_MAX_WINDOW_DAYS", "ALPHA_VANTAGE_RATE_LIMIT"} {
+		if _, err := positiveIntEnv(name, 1); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if _, err := retryBudgetLimit(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if _, err := apiCacheEndpointTTLs(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if _, err := creditRiskDebtToEquity(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if _, err := qvmWeightsFromEnv(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if _, err := tools.NewNumberFormat(os.Getenv("REPORT_LOCALE")); err != nil {
+		problems = append(problems, fmt.Sprintf("REPORT_LOCALE %v", err))
+	}
+	problems = append(problems, dataStoreProblems()...)
+	problems = append(problems, jobQuotaProblems()...)
+	problems = append(problems, symbolHistoryProblems()...)
+	problems = append(problems, dataLicenseProblems()...)
+	return problems
+}
+
+// configError 没有问题时返回 nil，否则返回列出全部问题的 ConfigError
+func configError(problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ConfigError{Problems: problems}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnvConfigReloadUnsetsRemovedKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, key := range []string{"RELOAD_TEST_KEPT", "RELOAD_TEST_REMOVED", "RELOAD_TEST_PROCESS"} {
+		t.Cleanup(func() { os.Unsetenv(key) })
+	}
+	t.Setenv("RELOAD_TEST_PROCESS", "from-process")
+	config := &envConfig{files: []string{path}, process: map[string]bool{"RELOAD_TEST_PROCESS": true}}
+
+	write("RELOAD_TEST_KEPT=1\nRELOAD_TEST_REMOVED=2\nRELOAD_TEST_PROCESS=from-file\n")
+	if _, err := config.apply(); err != nil {
+		t.Fatal(err)
+	}
+	if got := os.Getenv("RELOAD_TEST_REMOVED"); got != "2" {
+		t.Fatalf("首次加载后 RELOAD_TEST_REMOVED=%q，期望 2", got)
+	}
+
+	write("RELOAD_TEST_KEPT=3\n")
+	if _, err := config.apply(); err != nil {
+		t.Fatal(err)
+	}
+	if got := os.Getenv("RELOAD_TEST_KEPT"); got != "3" {
+		t.Errorf("重新加载后 RELOAD_TEST_KEPT=%q，期望 3", got)
+	}
+	if got, ok := os.LookupEnv("RELOAD_TEST_REMOVED"); ok {
+		t.Errorf("从配置文件删除的 RELOAD_TEST_REMOVED 仍为 %q，期望未设置", got)
+	}
+	if got := os.Getenv("RELOAD_TEST_PROCESS"); got != "from-process" {
+		t.Errorf("启动时已有的 RELOAD_TEST_PROCESS=%q，期望保持 from-process", got)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"investment/tools"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// corporateActionsResponse 模型返回的交易列表
+type corporateActionsResponse struct {
+	Actions []tools.CorporateAction `json:"actions"`
+}
+
+// newLLMCorporateActionExtractor 创建基于大模型的并购与公司行动抽取器
+func newLLMCorporateActionExtractor(generator *structuredGenerator) tools.CorporateActionExtractor {
+	return func(ctx context.Context, symbol string, sources []tools.CorporateActionSource) ([]tools.CorporateAction, error) {
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("以下是 %s 相关的新闻和年报段落。请整理该公司作为收购方、出售方、被分拆方或合并方参与的交易：\n", symbol))
+		sb.WriteString("1. type：acquisition（收购）、divestiture（出售业务或资产）、spin_off（分拆）、merger（合并）\n")
+		sb.WriteString("2. counterparty：交易对手或标的名称；date：宣布或完成日期（YYYY-MM-DD，只知道年份时写 YYYY）\n")
+		sb.WriteString("3. deal_value：以美元计的交易金额（如 1.2e9），原文未披露时省略；deal_value_text：原文中的金额描述\n")
+		sb.WriteString("4. status：announced（已宣布未完成）、completed（已完成）、terminated（终止或被否决）\n")
+		sb.WriteString("5. integration：原文提到的整合结果，如收入贡献、协同效应、商誉减值、裁员或后续再出售，没有提到时省略\n")
+		sb.WriteString("6. sources：支持该交易的段落编号\n\n")
+		sb.WriteString("同一笔交易在多条来源中出现时合并为一条。只提取原文中明确提到的信息，不要推测；传闻、分析师猜测以及公司作为财务投资者的少量持股不算交易。\n")
+		sb.WriteString(`只输出 JSON，格式为 {"actions":[{"date":"","type":"acquisition","counterparty":"","description":"","deal_value":0,"deal_value_text":"","status":"completed","integration":"","sources":[1]}]}`)
+		sb.WriteString("\n\n")
+		for _, source := range sources {
+			sb.WriteString(fmt.Sprintf("[%d] (%s %s) %s\n%s\n\n", source.ID, source.Source, source.Date, source.Title, source.Text))
+		}
+
+		var response corporateActionsResponse
+		validate := func() error {
+			for i := range response.Actions {
+				action := &response.Actions[i]
+				if err := action.Validate(); err != nil {
+					return fmt.Errorf("第 %d 笔交易: %w", i+1, err)
+				}
+				for _, id := range action.Sources {
+					if id < 1 || id > len(sources) {
+						return fmt.Errorf("第 %d 笔交易的来源编号 %d 超出范围 1-%d", i+1, id, len(sources))
+					}
+				}
+			}
+			return nil
+		}
+		err := generator.generate(ctx, []*schema.Message{
+			schema.UserMessage(sb.String()),
+		}, &response, validate)
+		if err != nil {
+			return nil, fmt.Errorf("模型提取失败: %w", err)
+		}
+		return response.Actions, nil
+	}
+}
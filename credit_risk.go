@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"investment/tools"
+)
+
+// creditRiskLineItems 信用风险评估使用的财务报表项目
+var creditRiskLineItems = []string{
+	"current_assets",
+	"current_liabilities",
+	"retained_earnings",
+	"ebit",
+	"operating_income",
+	"interest_expense",
+	"total_assets",
+	"total_liabilities",
+	"shareholders_equity",
+	"ebitda",
+	"depreciation_and_amortization",
+	"total_debt",
+	"operating_lease_liabilities",
+	"pension_and_postretirement_benefit_obligations",
+}
+
+// getCreditRiskData 获取最近 years 个年度计算 Z″-score 和利息保障倍数所需的报表项目，最新的在前
+// 没有 EBIT 时使用营业利润代替，没有 EBITDA 时使用 EBIT 加折旧摊销；利息费用统一转换为正数
+// 养老金项目为计划资产抵减后的净负债，净资产（超额拨备）不计入调整后债务
+func getCreditRiskData(ctx context.Context, symbol string, years int) ([]tools.CreditRiskPeriod, error) {
+	items, err := SearchLineItems(ctx, symbol, creditRiskLineItems, time.Now().Format("2006-01-02"), "annual", years)
+	if err != nil {
+		return nil, err
+	}
+	periods := make([]tools.CreditRiskPeriod, 0, len(items))
+	for _, item := range items {
+		ebit := lineItemValue(item, "ebit")
+		if ebit == nil {
+			ebit = lineItemValue(item, "operating_income")
+		}
+		interest := lineItemValue(item, "interest_expense")
+		if interest != nil {
+			v := math.Abs(*interest)
+			interest = &v
+		}
+		ebitda := lineItemValue(item, "ebitda")
+		if da := lineItemValue(item, "depreciation_and_amortization"); ebitda == nil && ebit != nil && da != nil {
+			v := *ebit + math.Abs(*da)
+			ebitda = &v
+		}
+		periods = append(periods, tools.CreditRiskPeriod{
+			ReportPeriod:       item.ReportPeriod,
+			CurrentAssets:      lineItemValue(item, "current_assets"),
+			CurrentLiabilities: lineItemValue(item, "current_liabilities"),
+			RetainedEarnings:   lineItemValue(item, "retained_earnings"),
+			EBIT:               ebit,
+			InterestExpense:    interest,
+			TotalAssets:        lineItemValue(item, "total_assets"),
+			TotalLiabilities:   lineItemValue(item, "total_liabilities"),
+			ShareholdersEquity: lineItemValue(item, "shareholders_equity"),
+			EBITDA:             ebitda,
+			TotalDebt:          lineItemValue(item, "total_debt"),
+			OperatingLeases:    lineItemValue(item, "operating_lease_liabilities"),
+			PensionDeficit:     lineItemValue(item, "pension_and_postretirement_benefit_obligations"),
+		})
+	}
+	return periods, nil
+}
+
+// creditRiskDebtToEquity 读取 CREDIT_RISK_DE_THRESHOLD，债务股权比超过该值时必须做信用风险评估
+func creditRiskDebtToEquity() (float64, error) {
+	raw := os.Getenv("CREDIT_RISK_DE_THRESHOLD")
+	if raw == "" {
+		return tools.DefaultCreditRiskDebtToEquity, nil
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil || v <= 0 || !tools.IsFinite(v) {
+		return 0, fmt.Errorf("无效的 CREDIT_RISK_DE_THRESHOLD: %s", raw)
+	}
+	return v, nil
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"investment/provider"
+	"investment/tools"
+)
+
+// 数据提供方名称
+const (
+	providerFinancialDatasets = "financialdatasets" // 财务数据、价格和新闻，需要 API 密钥
+	providerYahooFinance      = "yahoo"             // 价格、市值和基础指标，免费，不提供新闻和内部人交易
+	providerAlphaVantage      = "alphavantage"      // 价格、公司概况和每股收益，需要 API 密钥，免费密钥限流严格
+)
+
+// dataProviders 可选的数据提供方（DATA_PROVIDER）；新的数据源实现 provider.DataProvider 后在这里登记
+var dataProviders = map[string]provider.DataProvider{
+	providerFinancialDatasets: financialDatasets{},
+	providerYahooFinance:      yahooFinance{},
+	providerAlphaVantage:      alphaVantage{},
+}
+
+// dataProviderName 当前配置的数据提供方名称；未设置时按 FINANCIAL_DATASETS_API_KEY、ALPHA_VANTAGE_API_KEY 的顺序选择有密钥的提供方，都没有时退回免费的 yahoo
+func dataProviderName() string {
+	if name := strings.ToLower(strings.TrimSpace(os.Getenv("DATA_PROVIDER"))); name != "" {
+		return name
+	}
+	switch {
+	case len(parseAPIKeys(os.Getenv("FINANCIAL_DATASETS_API_KEY"))) > 0:
+		return providerFinancialDatasets
+	case len(parseAPIKeys(os.Getenv("ALPHA_VANTAGE_API_KEY"))) > 0:
+		return providerAlphaVantage
+	default:
+		return providerYahooFinance
+	}
+}
+
+// dataSource 当前配置的数据提供方，价格、财务指标、新闻、内部人交易、市值和公司信息都经由它获取；
+// 无效的 DATA_PROVIDER 在配置检查中报告，这里按默认处理
+func dataSource() provider.DataProvider {
+	if p, ok := dataProviders[dataProviderName()]; ok {
+		return p
+	}
+	return dataProviders[providerFinancialDatasets]
+}
+
+// dataProviderProblems 检查 DATA_PROVIDER 和所选提供方需要的密钥
+func dataProviderProblems() []string {
+	name := dataProviderName()
+	if _, ok := dataProviders[name]; !ok {
+		names := slices.Sorted(maps.Keys(dataProviders))
+		return []string{fmt.Sprintf("DATA_PROVIDER=%s 不受支持（可选 %s）", name, strings.Join(names, "、"))}
+	}
+	if name == providerFinancialDatasets && len(parseAPIKeys(os.Getenv("FINANCIAL_DATASETS_API_KEY"))) == 0 {
+		return []string{"未设置 FINANCIAL_DATASETS_API_KEY，无法获取市值、财务指标、新闻等数据（不设置 DATA_PROVIDER 时自动使用免费的 yahoo）"}
+	}
+	if name == providerAlphaVantage && len(parseAPIKeys(os.Getenv("ALPHA_VANTAGE_API_KEY"))) == 0 {
+		return []string{"DATA_PROVIDER=alphavantage 需要设置 ALPHA_VANTAGE_API_KEY"}
+	}
+	return nil
+}
+
+// dataProvider 一个数据提供方的地址和凭证；配置了多个密钥时，遇到限流轮换到下一个
+type dataProvider struct {
+	BaseURL string
+	Header  string            // 携带 API 密钥的请求头
+	Param   string            // 携带 API 密钥的查询参数，数据源只支持在 URL 中传密钥时使用，设置后不使用 Header
+	Headers map[string]string // 每个请求固定携带的请求头
+	// MetricUnits 财务指标中比例字段的单位（json 字段名），未声明的字段由 tools.NormalizeFinancialMetrics 按取值判断
+	MetricUnits map[string]tools.MetricUnit
+
+	mu   sync.Mutex
+	keys []string
+	next int
+}
+
+// request 按当前密钥生成接口的完整地址和请求头，密钥放在 Param 查询参数或 Header 请求头中，没有密钥时不携带
+func (p *dataProvider) request(endpoint string) (string, map[string]string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	rawURL := p.BaseURL + endpoint
+	headers := maps.Clone(p.Headers)
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	if len(p.keys) == 0 {
+		return rawURL, headers
+	}
+	if p.Param != "" {
+		separator := "?"
+		if strings.Contains(rawURL, "?") {
+			separator = "&"
+		}
+		return rawURL + separator + p.Param + "=" + url.QueryEscape(p.keys[p.next]), headers
+	}
+	headers[p.Header] = p.keys[p.next]
+	return rawURL, headers
+}
+
+// rotate 切换到下一个密钥，之后的请求都使用新密钥；记录的限流配额属于旧密钥，一并清除
+func (p *dataProvider) rotate() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.keys) > 1 {
+		p.next = (p.next + 1) % len(p.keys)
+		if u, err := url.Parse(p.BaseURL); err == nil {
+			forgetRateLimitQuota(u.Host)
+		}
+	}
+}
+
+// quotaExhausted 当前密钥最近一次响应显示配额已用完且尚未重置
+func (p *dataProvider) quotaExhausted() bool {
+	u, err := url.Parse(p.BaseURL)
+	return err == nil && quotaWait(u.Host, time.Now()) > 0
+}
+
+// dataClient 数据源客户端：集中管理各数据提供方的地址、凭证和代理，按配置创建一次，所有数据请求共用
+type dataClient struct {
+	http      *http.Client
+	providers map[string]*dataProvider
+}
+
+// newDataClientFromEnv 按配置创建数据源客户端
+// FINANCIAL_DATASETS_API_KEY: 一个或多个（逗号分隔）API 密钥；FINANCIAL_DATASETS_BASE_URL: 接口地址，默认官方地址；
+// FINANCIAL_DATASETS_PROXY / DATA_API_PROXY: 数据请求使用的代理（http、https、socks5），未设置时按 HTTPS_PROXY / HTTP_PROXY 环境变量
+// DATA_API_RATE_LIMIT: 每分钟最多发出的数据请求数，所有并发的分析共用，未设置时不限速；ALPHA_VANTAGE_API_KEY / ALPHA_VANTAGE_RATE_LIMIT 见 alphaVantage
+// 代理地址无效时返回错误，同时返回不使用该代理的客户端
+func newDataClientFromEnv() (*dataClient, error) {
+	base, proxyErr := transportFor(dataProxyEnv...)
+	var transport http.RoundTripper = snapshotTransport{base: base}
+	// 无效值在配置检查中报告，这里按不限速处理
+	if perMinute, err := positiveIntEnv("DATA_API_RATE_LIMIT", 0); err == nil && perMinute > 0 {
+		transport = pacedTransport{next: transport, pacer: newRequestPacer(perMinute)}
+	}
+	transport = alphaVantageTransport{next: transport, pacer: newRequestPacer(alphaVantageRateLimit())}
+
+	baseURL := strings.TrimRight(os.Getenv("FINANCIAL_DATASETS_BASE_URL"), "/")
+	if baseURL == "" {
+		baseURL = "https://api.financialdatasets.ai"
+	}
+	// Yahoo Finance 的接口按会话 cookie 校验 crumb，cookie 随客户端保存
+	jar, _ := cookiejar.New(nil)
+	return &dataClient{
+		http: &http.Client{
+			Transport: transport,
+			Jar:       jar,
+			Timeout:   30 * time.Second,
+		},
+		providers: map[string]*dataProvider{
+			providerFinancialDatasets: {
+				BaseURL: baseURL,
+				Header:  "X-API-KEY",
+				keys:    parseAPIKeys(os.Getenv("FINANCIAL_DATASETS_API_KEY")),
+				// 利润率、回报率和增长率均以小数返回
+				MetricUnits: fractionUnits("gross_margin", "operating_margin", "net_margin", "return_on_equity", "return_on_assets",
+					"return_on_invested_capital", "free_cash_flow_yield", "debt_to_assets", "payout_ratio", "revenue_growth",
+					"earnings_growth", "book_value_growth", "earnings_per_share_growth", "free_cash_flow_growth",
+					"operating_income_growth", "ebitda_growth"),
+			},
+			providerYahooFinance: {
+				BaseURL: "https://query2.finance.yahoo.com",
+				// 不带浏览器 User-Agent 的请求会被直接限流
+				Headers: map[string]string{"User-Agent": yahooUserAgent},
+				// 利润率、回报率和增长率均以小数返回
+				MetricUnits: fractionUnits("gross_margin", "operating_margin", "net_margin", "return_on_equity", "return_on_assets",
+					"free_cash_flow_yield", "payout_ratio", "revenue_growth", "earnings_growth"),
+			},
+			providerAlphaVantage: {
+				BaseURL: alphaVantageBaseURL,
+				Param:   "apikey",
+				keys:    parseAPIKeys(os.Getenv("ALPHA_VANTAGE_API_KEY")),
+				// 公司概况中的利润率、回报率和同比增长均以小数返回
+				MetricUnits: fractionUnits("gross_margin", "operating_margin", "net_margin", "return_on_equity", "return_on_assets",
+					"payout_ratio", "revenue_growth", "earnings_growth", "earnings_per_share_growth"),
+			},
+		},
+	}, proxyErr
+}
+
+// fractionUnits 把列出的字段声明为小数形式的比例
+func fractionUnits(keys ...string) map[string]tools.MetricUnit {
+	units := make(map[string]tools.MetricUnit, len(keys))
+	for _, key := range keys {
+		units[key] = tools.UnitFraction
+	}
+	return units
+}
+
+// parseAPIKeys 解析逗号分隔的 API 密钥列表
+func parseAPIKeys(value string) []string {
+	var keys []string
+	for _, key := range strings.Split(value, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// do 请求数据提供方的接口，endpoint 为包含查询参数的路径；cached 为 false 时不经过响应缓存（实时数据）
+// 返回 429 时依次换用其他密钥立即重试，所有密钥都被限流后按 makeAPIRequest 的退避策略等待；
+// 上一次响应显示配额已用完时，有其他密钥则先换用，不等待配额重置
+// ctx 带有工具配额时，读取响应体的字节数计入配额
+func (c *dataClient) do(ctx context.Context, provider, method, endpoint string, body map[string]any, cached bool) (*http.Response, error) {
+	p, ok := c.providers[provider]
+	if !ok {
+		return nil, fmt.Errorf("未知的数据提供方: %s", provider)
+	}
+	// DATA_API_MAX_RETRIES 最后一个密钥被限流时的最多重试次数，无效值在配置检查中报告
+	maxRetries, err := positiveIntEnv("DATA_API_MAX_RETRIES", 3)
+	if err != nil {
+		maxRetries = 3
+	}
+	if len(p.keys) > 1 && p.quotaExhausted() {
+		p.rotate()
+		log.Printf("[DataClient] %s 的 API 密钥配额已用完，切换到下一个密钥", provider)
+	}
+	for remaining := len(p.keys) - 1; ; remaining-- {
+		retries := maxRetries
+		if remaining > 0 {
+			retries = 0
+		}
+		var resp *http.Response
+		var err error
+		rawURL, headers := p.request(endpoint)
+		if cached {
+			resp, err = makeCachedAPIRequest(ctx, c.http, rawURL, headers, method, body, retries)
+		} else {
+			resp, err = makeAPIRequest(ctx, c.http, rawURL, headers, method, body, retries)
+		}
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || remaining <= 0 {
+			if quota := tools.ToolQuotaFrom(ctx); err == nil && quota != nil {
+				resp.Body = &quotaBody{ReadCloser: resp.Body, quota: quota}
+			}
+			return resp, err
+		}
+		resp.Body.Close()
+		p.rotate()
+		log.Printf("[DataClient] %s 的 API 密钥被限流，切换到下一个密钥", provider)
+	}
+}
+
+// quotaBody 把读取的响应字节数计入工具配额（服务模式任务的 JOB_MAX_TOOL_BYTES）
+type quotaBody struct {
+	io.ReadCloser
+	quota *tools.ToolQuota
+}
+
+func (b *quotaBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.quota.AddFetched(n)
+	return n, err
+}
+
+var (
+	dataAPIMu sync.Mutex
+	dataAPI   *dataClient
+)
+
+// dataAPIClient 返回数据源客户端，第一次调用时按当前配置创建；代理配置无效时记录日志并使用默认代理设置
+func dataAPIClient() *dataClient {
+	dataAPIMu.Lock()
+	defer dataAPIMu.Unlock()
+	if dataAPI == nil {
+		client, err := newDataClientFromEnv()
+		if err != nil {
+			log.Printf("[DataClient] %v，使用默认代理设置", err)
+		}
+		dataAPI = client
+	}
+	return dataAPI
+}
+
+// resetDataAPIClient 丢弃当前的数据源客户端，服务模式重新加载配置后调用，之后的请求使用新的密钥和代理
+func resetDataAPIClient() {
+	dataAPIMu.Lock()
+	defer dataAPIMu.Unlock()
+	dataAPI = nil
+}
+
+// fetchFinancialDatasets 请求 FinancialDatasets.ai 接口（经过响应缓存），endpoint 如 /prices/?ticker=AAPL
+// 财务报表科目、SEC 文件等只有 FinancialDatasets.ai 提供；使用其他数据提供方且没有密钥时返回 tools.ErrNoData，不按密钥无效中止分析
+func fetchFinancialDatasets(ctx context.Context, method, endpoint string, body map[string]any) (*http.Response, error) {
+	client := dataAPIClient()
+	if len(client.providers[providerFinancialDatasets].keys) == 0 && dataProviderName() != providerFinancialDatasets {
+		return nil, fmt.Errorf("未设置 FINANCIAL_DATASETS_API_KEY，%s 数据源不提供该数据: %w", dataProviderName(), tools.ErrNoData)
+	}
+	return client.do(ctx, providerFinancialDatasets, method, endpoint, body, true)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"investment/tools"
+)
+
+func TestDataClientCountsFetchedBytes(t *testing.T) {
+	// 工具配额按数据层读取的响应字节数计数，而不是工具返回给 Agent 的结果大小
+	body := newsJSON("A@2025-01-10", "B@2025-01-09")
+	stubFinancialDatasets(t, func(r *http.Request) (int, string) {
+		return http.StatusOK, body
+	})
+	quota := &tools.ToolQuota{MaxBytes: 1 << 20}
+	ctx := tools.WithToolQuota(context.Background(), quota)
+	if _, err := GetCompanyNews(ctx, "AAPL", "2025-01-10", nil, 10); err != nil {
+		t.Fatalf("GetCompanyNews: %v", err)
+	}
+	if got := quota.Usage().Bytes; got != int64(len(body)) {
+		t.Errorf("Bytes = %d, want %d", got, len(body))
+	}
+
+	// 不带配额的请求不计数
+	if _, err := GetCompanyNews(context.Background(), "AAPL", "2025-01-10", nil, 10); err != nil {
+		t.Fatalf("GetCompanyNews: %v", err)
+	}
+	if got := quota.Usage().Bytes; got != int64(len(body)) {
+		t.Errorf("Bytes = %d after unquoted request, want %d", got, len(body))
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"investment/tools"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// redactedNewsText 报告中按数据授权条款省略的新闻原文
+const redactedNewsText = "〔新闻原文已按数据授权条款省略〕"
+
+// minRedactedNewsRunes 新闻标题或摘要达到该字数才在报告中查找并省略，避免误伤报告中的普通短语
+const minRedactedNewsRunes = 12
+
+// DataLicense 一个数据提供方的再分发限制，按数据供应商的使用条款配置
+type DataLicense struct {
+	NoRawNewsText bool   `json:"no_raw_news_text"` // 导出的报告中不得包含新闻原文（标题、摘要）
+	NoRawData     bool   `json:"no_raw_data"`      // 不得再分发原始数据：过程记录附录不附带工具返回结果，export 子命令拒绝导出
+	Attribution   string `json:"attribution"`      // 使用该提供方数据的报告必须附带的来源声明
+}
+
+// dataLicensePolicy 按提供方名称（与报告数据来源附录中的提供方一致，不区分大小写）配置的再分发限制
+type dataLicensePolicy map[string]DataLicense
+
+var (
+	dataLicenseOnce sync.Once
+	dataLicenseData dataLicensePolicy
+)
+
+// dataLicenses 进程内共享的再分发限制，第一次使用时加载；配置文件有误时记录日志并不施加限制（配置校验会报告该问题）
+func dataLicenses() dataLicensePolicy {
+	dataLicenseOnce.Do(func() {
+		var err error
+		dataLicenseData, err = loadDataLicenses()
+		if err != nil {
+			log.Printf("[DataLicense] %v，不施加数据再分发限制", err)
+			dataLicenseData = dataLicensePolicy{}
+		} else if len(dataLicenseData) > 0 {
+			log.Printf("[DataLicense] 已加载 %d 个数据提供方的再分发限制", len(dataLicenseData))
+		}
+	})
+	return dataLicenseData
+}
+
+// loadDataLicenses 读取 DATA_LICENSE_FILE（默认 data_license.json，不存在时没有限制），
+// 文件为提供方名称到 DataLicense 的映射，如 {"FinancialDatasets.ai": {"no_raw_news_text": true}}
+func loadDataLicenses() (dataLicensePolicy, error) {
+	path := os.Getenv("DATA_LICENSE_FILE")
+	explicit := path != ""
+	if !explicit {
+		path = "data_license.json"
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && os.IsNotExist(err) {
+			return dataLicensePolicy{}, nil
+		}
+		return nil, fmt.Errorf("读取数据授权配置 %s 失败: %w", path, err)
+	}
+	var raw map[string]DataLicense
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("解析数据授权配置 %s 失败: %w", path, err)
+	}
+	policy := make(dataLicensePolicy, len(raw))
+	for provider, license := range raw {
+		provider = strings.ToLower(strings.TrimSpace(provider))
+		if provider == "" {
+			return nil, fmt.Errorf("数据授权配置 %s 中有空的提供方名称", path)
+		}
+		policy[provider] = license
+	}
+	return policy, nil
+}
+
+// forProvider 提供方的再分发限制，没有配置时为零值（不限制）
+func (p dataLicensePolicy) forProvider(provider string) DataLicense {
+	return p[strings.ToLower(strings.TrimSpace(provider))]
+}
+
+// withholdToolResult 工具返回结果不能原样写入报告时返回限制它的提供方：结果来自 no_raw_data 的提供方，
+// 或包含 no_raw_news_text 的提供方的新闻
+func (p dataLicensePolicy) withholdToolResult(toolName, content string) string {
+	if len(p) == 0 {
+		return ""
+	}
+	for _, record := range tools.ProvenanceFromToolResult(toolName, content, time.Time{}) {
+		license := p.forProvider(record.Provider)
+		if license.NoRawData || (license.NoRawNewsText && strings.Contains(record.Dataset, "新闻")) {
+			return record.Provider
+		}
+	}
+	return ""
+}
+
+// restrictedNewsTexts 工具结果中来自 no_raw_news_text 提供方的新闻标题和摘要，按长度从长到短排列
+func (p dataLicensePolicy) restrictedNewsTexts(messages []*schema.Message) []string {
+	if len(p) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var texts []string
+	for _, msg := range messages {
+		if msg.Role != schema.Tool {
+			continue
+		}
+		restricted := false
+		for _, record := range tools.ProvenanceFromToolResult(msg.ToolName, msg.Content, time.Time{}) {
+			if strings.Contains(record.Dataset, "新闻") && p.forProvider(record.Provider).NoRawNewsText {
+				restricted = true
+				break
+			}
+		}
+		if !restricted {
+			continue
+		}
+		for _, text := range rawNewsTexts(msg.ToolName, msg.Content) {
+			text = strings.TrimSpace(text)
+			if utf8.RuneCountInString(text) >= minRedactedNewsRunes && !seen[text] {
+				seen[text] = true
+				texts = append(texts, text)
+			}
+		}
+	}
+	// 先替换较长的文本，避免标题是摘要的一部分时摘要只被替换一半
+	sort.SliceStable(texts, func(i, j int) bool { return len(texts[i]) > len(texts[j]) })
+	return texts
+}
+
+// rawNewsTexts 工具结果中的新闻原文：公司新闻的标题和摘要、行情异动的相关新闻标题、新闻数据集摘要的样例
+func rawNewsTexts(toolName, content string) []string {
+	var texts []string
+	switch toolName {
+	case "get_company_news":
+		var output tools.CompanyNewsOutput
+		if json.Unmarshal([]byte(content), &output) != nil {
+			return nil
+		}
+		for _, news := range append(output.News, output.RiskNews...) {
+			texts = append(texts, news.Title, news.Summary)
+		}
+	case "get_quote":
+		var output tools.QuoteOutput
+		if json.Unmarshal([]byte(content), &output) != nil {
+			return nil
+		}
+		for _, headline := range output.Headlines {
+			texts = append(texts, headline.Title)
+		}
+	case "summarize_dataset":
+		var output tools.DatasetSummary
+		if json.Unmarshal([]byte(content), &output) != nil || output.Dataset != tools.DatasetNews {
+			return nil
+		}
+		texts = append(texts, output.Samples...)
+	}
+	return texts
+}
+
+// redactNewsText 把报告中逐字引用的新闻原文替换为省略说明
+func redactNewsText(report string, texts []string) string {
+	for _, text := range texts {
+		report = strings.ReplaceAll(report, text, redactedNewsText)
+	}
+	return report
+}
+
+// renderAttributions 本次分析用到的提供方要求附带的来源声明，没有时返回空字符串
+func (p dataLicensePolicy) renderAttributions(records []tools.ProvenanceRecord) string {
+	if len(p) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	seen := make(map[string]bool)
+	for _, record := range records {
+		key := strings.ToLower(record.Provider)
+		attribution := strings.TrimSpace(p.forProvider(record.Provider).Attribution)
+		if seen[key] || attribution == "" {
+			continue
+		}
+		seen[key] = true
+		fmt.Fprintf(&sb, "- %s: %s\n", record.Provider, attribution)
+	}
+	if sb.Len() == 0 {
+		return ""
+	}
+	return "### 数据授权声明\n\n" + sb.String()
+}
+
+// dataLicenseProblems 配置校验：DATA_LICENSE_FILE 无法读取或格式有误
+func dataLicenseProblems() []string {
+	if _, err := loadDataLicenses(); err != nil {
+		return []string{err.Error()}
+	}
+	return nil
+}
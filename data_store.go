@@ -0,0 +1,446 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"investment/provider"
+	"investment/tools"
+)
+
+// DATA_STORE 的取值
+const DataStoreSQLite = "sqlite"
+
+// dataStorePath SQLite 数据库文件的默认位置，DATA_STORE_PATH 可以修改
+var dataStorePath = filepath.Join("output", "investment.db")
+
+// dataStoreMigrations 数据库结构的迁移，按顺序执行，第 i 项把结构升级到版本 i+1；已发布的迁移不要修改，只在末尾追加
+// 各表保留数据源返回的完整 JSON（data 列），常用字段单独成列便于筛选，其他字段用 json_extract 查询
+var dataStoreMigrations = []string{
+	`CREATE TABLE prices (
+		symbol     TEXT NOT NULL,
+		time       TEXT NOT NULL,
+		open       REAL,
+		high       REAL,
+		low        REAL,
+		close      REAL,
+		volume     INTEGER,
+		fetched_at TEXT NOT NULL,
+		PRIMARY KEY (symbol, time)
+	);
+	CREATE TABLE financial_metrics (
+		symbol         TEXT NOT NULL,
+		period         TEXT NOT NULL,
+		report_period  TEXT NOT NULL,
+		currency       TEXT,
+		market_cap     REAL,
+		pe_ratio       REAL,
+		gross_margin   REAL,
+		net_margin     REAL,
+		roe            REAL,
+		debt_to_equity REAL,
+		data           TEXT NOT NULL,
+		fetched_at     TEXT NOT NULL,
+		PRIMARY KEY (symbol, period, report_period)
+	);
+	CREATE TABLE news (
+		symbol     TEXT NOT NULL,
+		url        TEXT NOT NULL,
+		datetime   TEXT,
+		title      TEXT,
+		source     TEXT,
+		data       TEXT NOT NULL,
+		fetched_at TEXT NOT NULL,
+		PRIMARY KEY (symbol, url)
+	);
+	CREATE INDEX news_symbol_datetime ON news (symbol, datetime);
+	CREATE TABLE insider_trades (
+		symbol            TEXT NOT NULL,
+		trade_key         TEXT NOT NULL,
+		filing_date       TEXT,
+		name              TEXT,
+		transaction_date  TEXT,
+		transaction_value REAL,
+		data              TEXT NOT NULL,
+		fetched_at        TEXT NOT NULL,
+		PRIMARY KEY (symbol, trade_key)
+	);
+	CREATE INDEX insider_trades_symbol_filing ON insider_trades (symbol, filing_date);
+	CREATE TABLE analysis_runs (
+		id          TEXT PRIMARY KEY,
+		symbol      TEXT NOT NULL,
+		portfolio   TEXT,
+		model       TEXT,
+		rating      TEXT,
+		truncated   INTEGER NOT NULL,
+		started_at  TEXT NOT NULL,
+		finished_at TEXT,
+		report_path TEXT,
+		data        TEXT NOT NULL
+	);
+	CREATE INDEX analysis_runs_symbol ON analysis_runs (symbol, started_at);`,
+}
+
+// dataStoreTables 数据表，store status 按此顺序列出行数
+var dataStoreTables = []string{"prices", "financial_metrics", "news", "insider_trades", "analysis_runs"}
+
+// dataStore 把获取的价格、财务指标、新闻、内部人交易和分析结果保存到 SQLite 数据库（DATA_STORE=sqlite），
+// 与 output/ 下的 JSON 文件并存，便于跨运行查询历史数据；同一条数据再次获取时覆盖为最新内容
+type dataStore struct {
+	db   *sql.DB
+	path string
+}
+
+var (
+	sharedStore     *dataStore
+	sharedStoreOnce sync.Once
+)
+
+// sharedDataStore 返回全局数据库，DATA_STORE 未设置或打开失败时为 nil（打开失败只记录一次日志，不影响分析）
+func sharedDataStore() *dataStore {
+	sharedStoreOnce.Do(func() {
+		if dataStoreKind() == "" {
+			return
+		}
+		store, err := openDataStore(dataStoreFile())
+		if err != nil {
+			log.Printf("[DataStore] %v，本次不保存到数据库", err)
+			return
+		}
+		sharedStore = store
+	})
+	return sharedStore
+}
+
+// dataStoreKind DATA_STORE 的值，为空时不使用数据库
+func dataStoreKind() string {
+	return strings.ToLower(strings.TrimSpace(os.Getenv("DATA_STORE")))
+}
+
+// dataStoreFile DATA_STORE_PATH，默认 output/investment.db
+func dataStoreFile() string {
+	if path := os.Getenv("DATA_STORE_PATH"); path != "" {
+		return path
+	}
+	return dataStorePath
+}
+
+// dataStoreProblems DATA_STORE 的配置问题
+func dataStoreProblems() []string {
+	switch dataStoreKind() {
+	case "", DataStoreSQLite:
+		return nil
+	default:
+		return []string{fmt.Sprintf("无效的 DATA_STORE: %s（可选 %s，为空时不使用数据库）", os.Getenv("DATA_STORE"), DataStoreSQLite)}
+	}
+}
+
+// openDataStore 打开（不存在时创建）数据库并执行未应用的迁移
+func openDataStore(path string) (*dataStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("创建数据库目录失败: %w", err)
+	}
+	// 并发的分析同时写入时等待锁而不是立即返回 SQLITE_BUSY
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库 %s 失败: %w", path, err)
+	}
+	store := &dataStore{db: db, path: path}
+	if err := store.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// Close 关闭数据库
+func (s *dataStore) Close() error {
+	return s.db.Close()
+}
+
+// version 当前的结构版本，没有执行过迁移时为 0
+func (s *dataStore) version() (int, error) {
+	var version sql.NullInt64
+	if err := s.db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("读取数据库结构版本失败: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
+// migrate 依次执行未应用的迁移，每个迁移和它的版本记录在同一个事务中提交
+func (s *dataStore) migrate() error {
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, applied_at TEXT NOT NULL)`); err != nil {
+		return fmt.Errorf("创建迁移记录表失败: %w", err)
+	}
+	current, err := s.version()
+	if err != nil {
+		return err
+	}
+	if current > len(dataStoreMigrations) {
+		return fmt.Errorf("数据库 %s 的结构版本 %d 高于程序支持的版本 %d，请升级程序", s.path, current, len(dataStoreMigrations))
+	}
+	for version := current + 1; version <= len(dataStoreMigrations); version++ {
+		err := s.inTx(func(tx *sql.Tx) error {
+			if _, err := tx.Exec(dataStoreMigrations[version-1]); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, version, time.Now().UTC().Format(time.RFC3339))
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("执行数据库迁移 %d 失败: %w", version, err)
+		}
+		log.Printf("[DataStore] 数据库结构已升级到版本 %d", version)
+	}
+	return nil
+}
+
+// inTx 在事务中执行 fn，fn 返回错误时回滚
+func (s *dataStore) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// upsertRows 在一个事务中逐行执行 upsert 语句
+func (s *dataStore) upsertRows(query string, n int, args func(i int) ([]any, error)) error {
+	if n == 0 {
+		return nil
+	}
+	return s.inTx(func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(query)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for i := range n {
+			values, err := args(i)
+			if err != nil {
+				return err
+			}
+			if _, err := stmt.Exec(values...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// fetchedAt 写入时间，UTC RFC3339
+func fetchedAt() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+// savePrices 保存日线价格
+func (s *dataStore) savePrices(symbol string, prices []provider.Price) error {
+	now := fetchedAt()
+	return s.upsertRows(`INSERT INTO prices (symbol, time, open, high, low, close, volume, fetched_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (symbol, time) DO UPDATE SET open = excluded.open, high = excluded.high, low = excluded.low,
+		close = excluded.close, volume = excluded.volume, fetched_at = excluded.fetched_at`,
+		len(prices), func(i int) ([]any, error) {
+			p := prices[i]
+			return []any{symbol, p.Time, p.Open, p.High, p.Low, p.Close, p.Volume, now}, nil
+		})
+}
+
+// saveMetrics 保存财务指标（已统一为小数形式的比例）
+func (s *dataStore) saveMetrics(symbol string, metrics []tools.FinancialMetrics) error {
+	now := fetchedAt()
+	return s.upsertRows(`INSERT INTO financial_metrics (symbol, period, report_period, currency, market_cap, pe_ratio, gross_margin, net_margin, roe, debt_to_equity, data, fetched_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (symbol, period, report_period) DO UPDATE SET currency = excluded.currency, market_cap = excluded.market_cap,
+		pe_ratio = excluded.pe_ratio, gross_margin = excluded.gross_margin, net_margin = excluded.net_margin, roe = excluded.roe,
+		debt_to_equity = excluded.debt_to_equity, data = excluded.data, fetched_at = excluded.fetched_at`,
+		len(metrics), func(i int) ([]any, error) {
+			m := metrics[i]
+			data, err := json.Marshal(m)
+			if err != nil {
+				return nil, err
+			}
+			return []any{symbol, m.Period, m.ReportPeriod, m.Currency, m.MarketCap, m.PriceToEarningsRatio, m.GrossMargin,
+				m.NetMargin, m.ReturnOnEquity, m.DebtToEquity, string(data), now}, nil
+		})
+}
+
+// saveNews 保存新闻，同一只股票按链接去重
+func (s *dataStore) saveNews(symbol string, news []tools.CompanyNews) error {
+	now := fetchedAt()
+	return s.upsertRows(`INSERT INTO news (symbol, url, datetime, title, source, data, fetched_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (symbol, url) DO UPDATE SET datetime = excluded.datetime, title = excluded.title, source = excluded.source,
+		data = excluded.data, fetched_at = excluded.fetched_at`,
+		len(news), func(i int) ([]any, error) {
+			n := news[i]
+			data, err := json.Marshal(n)
+			if err != nil {
+				return nil, err
+			}
+			// 没有链接的新闻用 ID 或标题区分
+			key := n.URL
+			if key == "" {
+				key = "id:" + n.ID
+				if n.ID == "" {
+					key = "title:" + n.DateTime + "|" + n.Title
+				}
+			}
+			return []any{symbol, key, n.DateTime, n.Title, n.Source, string(data), now}, nil
+		})
+}
+
+// saveInsiderTrades 保存内部人交易，按分页去重使用的交易标识区分
+func (s *dataStore) saveInsiderTrades(symbol string, trades []tools.InsiderTrade) error {
+	now := fetchedAt()
+	return s.upsertRows(`INSERT INTO insider_trades (symbol, trade_key, filing_date, name, transaction_date, transaction_value, data, fetched_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (symbol, trade_key) DO UPDATE SET data = excluded.data, fetched_at = excluded.fetched_at`,
+		len(trades), func(i int) ([]any, error) {
+			t := trades[i]
+			data, err := json.Marshal(t)
+			if err != nil {
+				return nil, err
+			}
+			return []any{symbol, insiderTradeKey(t), t.FilingDate, t.Name, t.TransactionDate, t.TransactionValue, string(data), now}, nil
+		})
+}
+
+// saveRun 保存一次分析的运行记录
+func (s *dataStore) saveRun(run *RunRecord) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	var finishedAt any
+	if !run.FinishedAt.IsZero() {
+		finishedAt = run.FinishedAt.UTC().Format(time.RFC3339)
+	}
+	_, err = s.db.Exec(`INSERT INTO analysis_runs (id, symbol, portfolio, model, rating, truncated, started_at, finished_at, report_path, data)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET rating = excluded.rating, truncated = excluded.truncated, finished_at = excluded.finished_at,
+		report_path = excluded.report_path, data = excluded.data`,
+		run.ID, run.Symbol, run.Portfolio, run.Model, run.Rating, run.Truncated, run.StartedAt.UTC().Format(time.RFC3339), finishedAt, run.ReportPath, string(data))
+	return err
+}
+
+// storeData 设置了 DATA_STORE 时把数据写入数据库；写入失败只记录日志，不影响分析
+func storeData(what string, save func(s *dataStore) error) {
+	store := sharedDataStore()
+	if store == nil {
+		return
+	}
+	if err := save(store); err != nil {
+		log.Printf("[DataStore] 保存%s失败: %v", what, err)
+	}
+}
+
+// runStore 处理 store 子命令：store status 显示数据库位置、结构版本和各表行数；store query 执行只读 SQL 并按列输出
+func runStore(args []string) error {
+	usage := fmt.Errorf("用法: store status | store query \"SELECT ...\" [--limit 100]")
+	if len(args) == 0 {
+		return usage
+	}
+	store, err := openDataStore(dataStoreFile())
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	switch args[0] {
+	case "status":
+		version, err := store.version()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("数据库: %s（结构版本 %d）\n", store.path, version)
+		if dataStoreKind() != DataStoreSQLite {
+			fmt.Println("⚠️ 未设置 DATA_STORE=sqlite，分析时不会写入数据库")
+		}
+		for _, table := range dataStoreTables {
+			var count int
+			if err := store.db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count); err != nil {
+				return fmt.Errorf("统计 %s 失败: %w", table, err)
+			}
+			fmt.Printf("  %-18s %8d 行\n", table, count)
+		}
+		return nil
+	case "query":
+		if len(args) < 2 || strings.HasPrefix(args[1], "-") {
+			return usage
+		}
+		fs := flag.NewFlagSet("store query", flag.ExitOnError)
+		limit := fs.Int("limit", 100, "最多输出的行数")
+		if err := fs.Parse(args[2:]); err != nil {
+			return err
+		}
+		return store.printQuery(args[1], *limit)
+	default:
+		return usage
+	}
+}
+
+// printQuery 在只读连接（PRAGMA query_only）上执行查询，以制表符分隔输出列名和前 limit 行
+func (s *dataStore) printQuery(query string, limit int) error {
+	ctx := context.Background()
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "PRAGMA query_only = ON"); err != nil {
+		return err
+	}
+	// 连接归还连接池前恢复可写，避免影响之后的写入
+	defer conn.ExecContext(ctx, "PRAGMA query_only = OFF")
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("查询失败: %w", err)
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	fmt.Println(strings.Join(columns, "\t"))
+	values := make([]any, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	n := 0
+	for rows.Next() {
+		if n == limit {
+			fmt.Printf("……只显示前 %d 行（--limit 调整）\n", limit)
+			break
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return err
+		}
+		cells := make([]string, len(values))
+		for i, value := range values {
+			switch v := value.(type) {
+			case nil:
+				cells[i] = "NULL"
+			case []byte:
+				cells[i] = string(v)
+			default:
+				cells[i] = fmt.Sprint(v)
+			}
+		}
+		fmt.Println(strings.Join(cells, "\t"))
+		n++
+	}
+	return rows.Err()
+}
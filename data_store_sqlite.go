@@ -0,0 +1,4 @@
+package main
+
+// SQLite 驱动（纯 Go 实现，不需要 cgo），DATA_STORE=sqlite 时由 database/sql 按名称 "sqlite" 使用
+import _ "modernc.org/sqlite"
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"investment/tools"
+)
+
+// datasetPageSize 分页拉取数据集时每页请求的条数
+const datasetPageSize = 500
+
+// StreamDataset 分页拉取数据集，每页经 ForEachCompanyNewsPage / ForEachInsiderTradesPage 写入数据库（DATA_STORE=sqlite），内存中只保留摘要
+// sentiment 不为 nil 时逐页为新闻情绪评分，评分随新闻一起保存
+// 部分页面处理后出错或 ctx 取消时，返回已处理部分的摘要和错误
+func StreamDataset(ctx context.Context, dataset, symbol, startDate, endDate string, sentiment *tools.SentimentBatcher) (*tools.DatasetSummary, error) {
+	summary := tools.NewDatasetSummary(symbol, dataset, startDate, endDate)
+	if store := sharedDataStore(); store != nil {
+		summary.Store = fmt.Sprintf("%s（%s 表）", store.path, dataset)
+	}
+
+	var err error
+	switch dataset {
+	case tools.DatasetNews:
+		err = ForEachCompanyNewsPage(ctx, symbol, endDate, &startDate, datasetPageSize, func(page []tools.CompanyNews) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			sentiment.Score(ctx, page)
+			summary.AddNewsPage(page)
+			return nil
+		})
+	case tools.DatasetInsiderTrades:
+		summary.Truncated, err = ForEachInsiderTradesPage(ctx, symbol, endDate, &startDate, datasetPageSize, func(page []tools.InsiderTrade) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			summary.AddInsiderTradesPage(page)
+			return nil
+		})
+	default:
+		err = fmt.Errorf("不支持的数据集: %s", dataset)
+	}
+	if err != nil {
+		return summary, err
+	}
+
+	if summary.Store == "" {
+		tools.Logger(ctx).Printf("[DatasetStream] %s 共 %d 条, %d 页；未设置 DATA_STORE=sqlite，数据未保存", dataset, summary.Count, summary.Pages)
+	} else {
+		tools.Logger(ctx).Printf("[DatasetStream] %s 数据已保存到: %s (共 %d 条, %d 页)", dataset, summary.Store, summary.Count, summary.Pages)
+	}
+	return summary, nil
+}
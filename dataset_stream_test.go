@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"investment/tools"
+)
+
+func TestStreamDatasetStopsOnCancel(t *testing.T) {
+	t.Run("取消后不再请求", func(t *testing.T) {
+		requests := stubFinancialDatasets(t, func(r *http.Request) (int, string) {
+			return http.StatusOK, newsJSON("A@2025-01-10")
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		summary, err := StreamDataset(ctx, tools.DatasetNews, "AAPL", "2025-01-01", "2025-01-10", nil)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("err = %v, want context.Canceled", err)
+		}
+		if len(*requests) != 0 || summary.Pages != 0 {
+			t.Errorf("requests = %d, pages = %d, want 0", len(*requests), summary.Pages)
+		}
+	})
+
+	t.Run("页面处理时已取消", func(t *testing.T) {
+		// 请求返回时调用方已经取消，这一页不再计入摘要，也不再翻到下一页
+		ctx, cancel := context.WithCancel(context.Background())
+		requests := stubFinancialDatasets(t, func(r *http.Request) (int, string) {
+			cancel()
+			return http.StatusOK, `{"insider_trades":[{"ticker":"AAPL","filing_date":"2025-01-10"}]}`
+		})
+		summary, err := StreamDataset(ctx, tools.DatasetInsiderTrades, "AAPL", "2025-01-01", "2025-01-10", nil)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("err = %v, want context.Canceled", err)
+		}
+		if len(*requests) != 1 || summary.Pages != 0 {
+			t.Errorf("requests = %d, pages = %d, want 1 and 0", len(*requests), summary.Pages)
+		}
+	})
+}
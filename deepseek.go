@@ -14,9 +14,10 @@ func createDeepseekChatModel(ctx context.Context) model.ToolCallingChatModel {
 	modelName := os.Getenv("DEEPSEEK_MODEL_NAME")
 	baseURL := os.Getenv("DEEPSEEK_BASE_URL")
 	chatModel, err := deepseek.NewChatModel(ctx, &deepseek.ChatModelConfig{
-		BaseURL: baseURL,
-		Model:   modelName,
-		APIKey:  key,
+		BaseURL:    baseURL,
+		Model:      modelName,
+		APIKey:     key,
+		HTTPClient: newProxiedHTTPClient(0, deepseekProxyEnv...),
 	})
 	log.Printf("create deepseek chat model, baseURL=%s, modelName=%s, key=%s", baseURL, modelName, key)
 	if err != nil {
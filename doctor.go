@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"investment/provider"
+	"investment/tools"
+)
+
+// doctorCheck 一项环境检查的结果，Problems 为空表示通过
+type doctorCheck struct {
+	Name     string
+	Detail   string
+	Problems []string
+}
+
+// runDoctor 处理 doctor 子命令：检查配置文件、分析配置、提示词、输出目录，可选检查数据源连接，一次列出全部问题
+func runDoctor(args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	online := fs.Bool("online", false, "请求一次实时行情，检查数据源密钥和网络")
+	symbol := fs.String("symbol", "AAPL", "--online 时请求行情的股票代码")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	checks := []doctorCheck{doctorConfigFiles(), doctorAnalysisConfig(), doctorPrompts(), doctorOutputDir()}
+	if *online {
+		checks = append(checks, doctorDataSource(context.Background(), canonicalSymbol(*symbol)))
+	}
+
+	failed := 0
+	for _, check := range checks {
+		if len(check.Problems) == 0 {
+			fmt.Printf("✅ %s", check.Name)
+			if check.Detail != "" {
+				fmt.Printf("：%s", check.Detail)
+			}
+			fmt.Println()
+			continue
+		}
+		failed++
+		fmt.Printf("❌ %s\n", check.Name)
+		for _, problem := range check.Problems {
+			fmt.Printf("   - %s\n", problem)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d/%d 项检查未通过", failed, len(checks))
+	}
+	fmt.Println("全部检查通过")
+	return nil
+}
+
+// doctorConfigFiles 列出实际加载的配置文件
+func doctorConfigFiles() doctorCheck {
+	check := doctorCheck{Name: "配置文件"}
+	var loaded []string
+	for _, path := range appEnv.files {
+		if _, err := os.Stat(path); err == nil {
+			loaded = append(loaded, path)
+		}
+	}
+	if len(loaded) == 0 {
+		check.Detail = fmt.Sprintf("未找到（%s），只使用环境变量", strings.Join(appEnv.files, "、"))
+	} else {
+		check.Detail = strings.Join(loaded, "、")
+	}
+	return check
+}
+
+// doctorAnalysisConfig 分析开始前的配置检查，与 validateAnalysisConfig 相同
+func doctorAnalysisConfig() doctorCheck {
+	check := doctorCheck{Name: "分析配置"}
+	err := validateAnalysisConfig()
+	var configErr *ConfigError
+	switch {
+	case errors.As(err, &configErr):
+		check.Problems = configErr.Problems
+	case err != nil:
+		check.Problems = []string{err.Error()}
+	default:
+		modelType := os.Getenv("MODEL_TYPE")
+		if modelType == "" {
+			modelType = "deepseek"
+		}
+		check.Detail = "MODEL_TYPE=" + modelType
+	}
+	return check
+}
+
+// doctorPrompts 加载提示词文件
+func doctorPrompts() doctorCheck {
+	dir := promptsDir()
+	check := doctorCheck{Name: "提示词", Detail: dir}
+	if _, err := loadPromptSet(dir); err != nil {
+		check.Problems = []string{err.Error()}
+	}
+	return check
+}
+
+// doctorOutputDir 本地输出目录是否可写；运行记录、缓存和交易日志总是写在本地
+func doctorOutputDir() doctorCheck {
+	dir := tools.OutputDir()
+	check := doctorCheck{Name: "输出目录", Detail: dir}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		check.Problems = []string{fmt.Sprintf("创建 %s 失败: %v", dir, err)}
+		return check
+	}
+	file, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		check.Problems = []string{fmt.Sprintf("%s 不可写: %v", dir, err)}
+		return check
+	}
+	file.Close()
+	os.Remove(file.Name())
+	if sink := os.Getenv("OUTPUT_SINK"); sink != "" && sink != OutputSinkFile {
+		check.Detail += fmt.Sprintf("（报告写入 OUTPUT_SINK=%s）", sink)
+	}
+	return check
+}
+
+// doctorDataSource 请求一次实时行情，检查数据源密钥和网络；实时行情只有 FinancialDatasets 提供，其他数据提供方改为请求最近的日线价格
+func doctorDataSource(ctx context.Context, symbol string) doctorCheck {
+	check := doctorCheck{Name: "数据源连接"}
+	if name := dataProviderName(); name != providerFinancialDatasets {
+		end := time.Now()
+		prices, err := GetPrices(ctx, symbol, end.AddDate(0, 0, -10).Format("2006-01-02"), end.Format("2006-01-02"))
+		if err != nil {
+			check.Problems = []string{fmt.Sprintf("%s: %v", name, err)}
+			return check
+		}
+		last := slices.MaxFunc(prices, func(a, b provider.Price) int { return strings.Compare(a.Time, b.Time) })
+		check.Detail = fmt.Sprintf("%s %s 收盘价 %.2f（%s）", symbol, last.Time, last.Close, name)
+		return check
+	}
+	snapshot, err := GetPriceSnapshot(ctx, symbol)
+	if err != nil {
+		problem := err.Error()
+		if errors.Is(err, tools.ErrUnauthorized) {
+			problem = "数据源 API 密钥无效或无权限，请检查 FINANCIAL_DATASETS_API_KEY（" + problem + "）"
+		}
+		check.Problems = []string{problem}
+		return check
+	}
+	check.Detail = fmt.Sprintf("%s 最新价格 %.2f", symbol, snapshot.Price)
+	return check
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/segmentio/kafka-go"
+)
+
+// 支持的消息总线
+const (
+	EventBusKafka = "kafka"
+	EventBusNATS  = "nats"
+)
+
+// 消息主题后缀，完整主题为 <前缀>.<后缀>
+const (
+	topicRuns      = "runs"       // 分析完成后的运行记录和结构化结论
+	topicToolCalls = "tool_calls" // 工具调用遥测
+)
+
+// eventBusQueueSize 待发送消息的缓冲数量，队列已满时丢弃消息，不阻塞分析
+const eventBusQueueSize = 256
+
+// eventBusCloseTimeout 关闭时等待剩余消息发送完成的最长时间
+const eventBusCloseTimeout = 10 * time.Second
+
+// busBackend 消息总线的具体实现
+type busBackend interface {
+	send(ctx context.Context, topic, key string, payload []byte) error
+	close() error
+}
+
+// busMessage 待发送的消息
+type busMessage struct {
+	topic   string
+	key     string
+	payload []byte
+}
+
+// eventBus 将分析结果和工具遥测异步发布到 Kafka 或 NATS，为 nil 时所有方法都不做任何事
+type eventBus struct {
+	backend busBackend
+	prefix  string
+	queue   chan busMessage
+	done    chan struct{}
+}
+
+// runResultMessage 发布到 <前缀>.runs 的分析结果
+type runResultMessage struct {
+	Run        *RunRecord        `json:"run"`
+	Structured *StructuredReport `json:"structured,omitempty"` // 设置 STRUCTURED_REPORT=true 时的结构化结论
+	Card       *SummaryCard      `json:"card,omitempty"`       // 设置 SUMMARY_CARD=true 时的摘要卡片，可直接用于聊天通知
+}
+
+// toolCallMessage 发布到 <前缀>.tool_calls 的工具调用遥测
+type toolCallMessage struct {
+	RunID   string    `json:"run_id"`
+	Symbol  string    `json:"symbol"`
+	Step    int       `json:"step"`
+	Tool    string    `json:"tool"`
+	Preview string    `json:"preview,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// newEventBusFromEnv EVENT_BUS: kafka 或 nats，为空时不发布（返回 nil）；
+// EVENT_BUS_URL: Kafka 为逗号分隔的 broker 地址（默认 localhost:9092），NATS 为服务器地址（默认 nats://127.0.0.1:4222）；
+// EVENT_BUS_TOPIC_PREFIX: 主题前缀，默认 investment
+func newEventBusFromEnv() (*eventBus, error) {
+	kind := strings.ToLower(os.Getenv("EVENT_BUS"))
+	url := os.Getenv("EVENT_BUS_URL")
+	var backend busBackend
+	switch kind {
+	case "":
+		return nil, nil
+	case EventBusKafka:
+		if url == "" {
+			url = "localhost:9092"
+		}
+		backend = &kafkaBackend{writer: &kafka.Writer{
+			Addr:                   kafka.TCP(strings.Split(url, ",")...),
+			Balancer:               &kafka.Hash{},
+			BatchTimeout:           50 * time.Millisecond,
+			AllowAutoTopicCreation: true,
+		}}
+	case EventBusNATS:
+		if url == "" {
+			url = nats.DefaultURL
+		}
+		conn, err := nats.Connect(url, nats.Name("investment-buddy"))
+		if err != nil {
+			return nil, fmt.Errorf("连接 NATS 失败: %w", err)
+		}
+		backend = &natsBackend{conn: conn}
+	default:
+		return nil, fmt.Errorf("不支持的 EVENT_BUS: %s（可选 %s、%s）", kind, EventBusKafka, EventBusNATS)
+	}
+
+	prefix := os.Getenv("EVENT_BUS_TOPIC_PREFIX")
+	if prefix == "" {
+		prefix = "investment"
+	}
+	bus := &eventBus{
+		backend: backend,
+		prefix:  prefix,
+		queue:   make(chan busMessage, eventBusQueueSize),
+		done:    make(chan struct{}),
+	}
+	go bus.loop()
+	log.Printf("分析结果将发布到 %s（%s），主题前缀 %s", kind, url, prefix)
+	return bus, nil
+}
+
+// loop 在后台依次发送队列中的消息，发送失败只记录日志
+func (b *eventBus) loop() {
+	defer close(b.done)
+	for msg := range b.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), eventBusCloseTimeout)
+		if err := b.backend.send(ctx, msg.topic, msg.key, msg.payload); err != nil {
+			log.Printf("[EventBus] 发布到 %s 失败: %v", msg.topic, err)
+		}
+		cancel()
+	}
+}
+
+// publish 将消息序列化为 JSON 放入发送队列，队列已满时丢弃
+func (b *eventBus) publish(topic, key string, v any) {
+	if b == nil {
+		return
+	}
+	payload, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("[EventBus] 序列化消息失败: %v", err)
+		return
+	}
+	select {
+	case b.queue <- busMessage{topic: b.prefix + "." + topic, key: key, payload: payload}:
+	default:
+		log.Printf("[EventBus] 发送队列已满，丢弃 %s 消息", topic)
+	}
+}
+
+// publishRun 发布分析结果，以股票代码作为消息键，同一股票的结果进入同一分区
+func (b *eventBus) publishRun(run *RunRecord, structured *StructuredReport, card *SummaryCard) {
+	b.publish(topicRuns, run.Symbol, runResultMessage{Run: run, Structured: structured, Card: card})
+}
+
+// withToolTelemetry 返回同时把 tool_called 事件发布到消息总线的进度回调，next 可以为 nil
+func (b *eventBus) withToolTelemetry(runID, symbol string, next ProgressFunc) ProgressFunc {
+	if b == nil {
+		return next
+	}
+	return func(event ProgressEvent) {
+		if event.Type == EventToolCalled {
+			b.publish(topicToolCalls, symbol, toolCallMessage{
+				RunID:   runID,
+				Symbol:  symbol,
+				Step:    event.Step,
+				Tool:    event.Tool,
+				Preview: event.Preview,
+				Time:    event.Time,
+			})
+		}
+		if next != nil {
+			next(event)
+		}
+	}
+}
+
+// Close 等待队列中剩余的消息发送完成（最多 eventBusCloseTimeout）后关闭连接
+func (b *eventBus) Close() {
+	if b == nil {
+		return
+	}
+	close(b.queue)
+	select {
+	case <-b.done:
+	case <-time.After(eventBusCloseTimeout):
+		log.Printf("[EventBus] 等待消息发送超时，剩余消息被丢弃")
+	}
+	if err := b.backend.close(); err != nil {
+		log.Printf("[EventBus] 关闭连接失败: %v", err)
+	}
+}
+
+// kafkaBackend 通过 kafka-go 发布消息，主题不存在时自动创建
+type kafkaBackend struct {
+	writer *kafka.Writer
+}
+
+func (k *kafkaBackend) send(ctx context.Context, topic, key string, payload []byte) error {
+	return k.writer.WriteMessages(ctx, kafka.Message{Topic: topic, Key: []byte(key), Value: payload})
+}
+
+func (k *kafkaBackend) close() error {
+	return k.writer.Close()
+}
+
+// natsBackend 通过 NATS 核心发布订阅发送消息，主题即 subject
+type natsBackend struct {
+	conn *nats.Conn
+}
+
+func (n *natsBackend) send(ctx context.Context, topic, key string, payload []byte) error {
+	return n.conn.Publish(topic, payload)
+}
+
+func (n *natsBackend) close() error {
+	return n.conn.Drain()
+}
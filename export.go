@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"investment/tools"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// priceRow Parquet 价格数据行
+type priceRow struct {
+	Date   time.Time `parquet:"date,timestamp(millisecond)"`
+	Open   float64   `parquet:"open"`
+	Close  float64   `parquet:"close"`
+	High   float64   `parquet:"high"`
+	Low    float64   `parquet:"low"`
+	Volume int64     `parquet:"volume"`
+}
+
+// ExportPriceDataFrameToParquet 将价格数据框架写入 Parquet 文件
+func ExportPriceDataFrameToParquet(df *tools.PriceDataFrame, path string) error {
+	rows := make([]priceRow, len(df.Dates))
+	for i := range df.Dates {
+		rows[i] = priceRow{
+			Date:   df.Dates[i],
+			Open:   df.Open[i],
+			Close:  df.Close[i],
+			High:   df.High[i],
+			Low:    df.Low[i],
+			Volume: df.Volume[i],
+		}
+	}
+	if err := parquet.WriteFile(path, rows); err != nil {
+		return fmt.Errorf("写入 Parquet 文件失败: %w", err)
+	}
+	return nil
+}
+
+// ExportMetricsToParquet 将财务指标历史写入 Parquet 文件，列名与 JSON 字段名一致
+func ExportMetricsToParquet(metrics []tools.FinancialMetrics, path string) error {
+	if err := parquet.WriteFile(path, metrics); err != nil {
+		return fmt.Errorf("写入 Parquet 文件失败: %w", err)
+	}
+	return nil
+}
+
+// runExport 导出股票的价格历史和财务指标历史为 Parquet 文件，便于在 pandas/DuckDB 中继续分析
+func runExport(ctx context.Context, symbol string, years int) error {
+	if dataLicenses().forProvider(tools.ProviderFinancialDatasets).NoRawData {
+		return fmt.Errorf("%s 的数据授权条款不允许导出原始数据（见 DATA_LICENSE_FILE）", tools.ProviderFinancialDatasets)
+	}
+	if years > tools.MaxPriceHistoryYears {
+		return fmt.Errorf("最多导出 %d 年历史: %d", tools.MaxPriceHistoryYears, years)
+	}
+	outputDir := filepath.Join(tools.OutputDir(), "export")
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %v", err)
+	}
+
+	endDate := time.Now().Format("2006-01-02")
+	startDate := time.Now().AddDate(-years, 0, 0).Format("2006-01-02")
+
+	// 导出价格历史
+	df, err := GetPriceData(ctx, symbol, startDate, endDate)
+	if err != nil {
+		return fmt.Errorf("获取价格数据失败: %w", err)
+	}
+	pricePath := filepath.Join(outputDir, fmt.Sprintf("prices_%s_%s_%s.parquet", symbol, startDate, endDate))
+	if err := ExportPriceDataFrameToParquet(df, pricePath); err != nil {
+		return err
+	}
+	log.Printf("[Export] 价格数据已导出: %s (%d 行)", pricePath, len(df.Dates))
+
+	// 导出年度和季度财务指标历史
+	for _, period := range []string{"annual", "quarterly"} {
+		limit := years
+		if period == "quarterly" {
+			limit = years * 4
+		}
+		metrics, err := GetFinancialMetrics(ctx, symbol, endDate, period, limit)
+		if errors.Is(err, tools.ErrNoData) {
+			log.Printf("[Export] 跳过%s财务指标: %v", period, err)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("获取%s财务指标失败: %w", period, err)
+		}
+		metricsPath := filepath.Join(outputDir, fmt.Sprintf("metrics_%s_%s_%s.parquet", symbol, period, endDate))
+		if err := ExportMetricsToParquet(metrics, metricsPath); err != nil {
+			return err
+		}
+		log.Printf("[Export] 财务指标已导出: %s (%d 行)", metricsPath, len(metrics))
+	}
+
+	return nil
+}
@@ -0,0 +1,394 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"investment/provider"
+	"investment/tools"
+)
+
+// financialDatasets FinancialDatasets.ai 的 provider.DataProvider 实现，请求经过 dataAPIClient（密钥轮换、限速、响应缓存和快照）
+type financialDatasets struct{}
+
+// Name 实现 provider.DataProvider
+func (financialDatasets) Name() string {
+	return providerFinancialDatasets
+}
+
+// priceChunkDays 单次价格请求覆盖的最长天数，超过时按区间分段请求，避免触发数据源的单次返回上限
+const priceChunkDays = 365
+
+// Prices 获取价格数据，多年区间自动分段请求并拼接
+func (p financialDatasets) Prices(ctx context.Context, ticker, startDate, endDate string) ([]provider.Price, error) {
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		return nil, fmt.Errorf("无效的开始日期: %s", startDate)
+	}
+	end, err := time.Parse("2006-01-02", endDate)
+	if err != nil {
+		return nil, fmt.Errorf("无效的结束日期: %s", endDate)
+	}
+
+	var prices []provider.Price
+	seen := make(map[string]bool)
+	for chunkStart := start; !chunkStart.After(end); chunkStart = chunkStart.AddDate(0, 0, priceChunkDays) {
+		chunkEnd := chunkStart.AddDate(0, 0, priceChunkDays-1)
+		if chunkEnd.After(end) {
+			chunkEnd = end
+		}
+		chunk, err := p.pricesChunk(ctx, ticker, chunkStart.Format("2006-01-02"), chunkEnd.Format("2006-01-02"))
+		if err != nil {
+			return nil, fmt.Errorf("获取 %s ~ %s 价格失败: %w", chunkStart.Format("2006-01-02"), chunkEnd.Format("2006-01-02"), err)
+		}
+		// 相邻分段在边界上可能重复返回同一天的数据
+		for _, price := range chunk {
+			if !seen[price.Time] {
+				seen[price.Time] = true
+				prices = append(prices, price)
+			}
+		}
+	}
+	return prices, nil
+}
+
+// pricesChunk 获取单个区间的价格数据
+func (financialDatasets) pricesChunk(ctx context.Context, ticker, startDate, endDate string) ([]provider.Price, error) {
+	endpoint := fmt.Sprintf("/prices/?ticker=%s&interval=day&interval_multiplier=1&start_date=%s&end_date=%s",
+		ticker, startDate, endDate)
+
+	resp, err := fetchFinancialDatasets(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("API 请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("获取数据错误: %s: %w", ticker, tools.StatusError(resp.StatusCode, body))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应体失败: %w", err)
+	}
+
+	var priceResponse PriceResponse
+	if err := json.Unmarshal(body, &priceResponse); err != nil {
+		return nil, fmt.Errorf("解析价格响应失败: %w", err)
+	}
+	return priceResponse.Prices, nil
+}
+
+// Metrics 获取财务指标数据，比例字段按 MetricUnits 统一为小数形式
+func (financialDatasets) Metrics(ctx context.Context, ticker, endDate, period string, limit int) ([]tools.FinancialMetrics, error) {
+	endpoint := fmt.Sprintf("/financial-metrics/?ticker=%s&report_period_lte=%s&limit=%d&period=%s",
+		ticker, endDate, limit, period)
+
+	resp, err := fetchFinancialDatasets(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("API 请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("获取数据错误: %s: %w", ticker, tools.StatusError(resp.StatusCode, body))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应体失败: %w", err)
+	}
+
+	var metricsResponse FinancialMetricsResponse
+	if err := json.Unmarshal(body, &metricsResponse); err != nil {
+		return nil, fmt.Errorf("解析财务指标响应失败: %w", err)
+	}
+
+	if len(metricsResponse.FinancialMetrics) == 0 {
+		return nil, fmt.Errorf("%s 财务指标: %w", ticker, tools.ErrNoData)
+	}
+
+	// 比例统一为小数形式，评分阈值按小数编写
+	units := dataAPIClient().providers[providerFinancialDatasets].MetricUnits
+	for _, note := range tools.NormalizeFinancialMetrics(metricsResponse.FinancialMetrics, units) {
+		log.Printf("[Metrics] %s %s", ticker, note)
+	}
+	return metricsResponse.FinancialMetrics, nil
+}
+
+// News 分页获取公司新闻，按本页最早的发布日期向前翻页
+// 同一天的新闻超过一页时按日期翻页无法推进，此时停止分页；上一页最早发布日的新闻会在下一页再次返回，按标识去重
+func (financialDatasets) News(ctx context.Context, ticker, endDate string, startDate *string, limit int, handle func(page []tools.CompanyNews) error) error {
+	currentEndDate := endDate
+	boundary := make(map[string]bool) // 上一页最早发布日的新闻，按日期向前翻页时下一页会再次返回
+
+	for {
+		// 每页请求前检查 ctx，调用方取消后不再翻页（缓存命中的页面不会经过 HTTP 请求）
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		endpoint := fmt.Sprintf("/news/?ticker=%s&end_date=%s", ticker, currentEndDate)
+		if startDate != nil {
+			endpoint += fmt.Sprintf("&start_date=%s", *startDate)
+		}
+		endpoint += fmt.Sprintf("&limit=%d", limit)
+
+		resp, err := fetchFinancialDatasets(ctx, "GET", endpoint, nil)
+		if err != nil {
+			return fmt.Errorf("API 请求失败: %w", err)
+		}
+
+		if resp.StatusCode != 200 {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return fmt.Errorf("获取数据错误: %s: %w", ticker, tools.StatusError(resp.StatusCode, body))
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("读取响应体失败: %w", err)
+		}
+
+		var newsResponse CompanyNewsResponse
+		if err := json.Unmarshal(body, &newsResponse); err != nil {
+			return fmt.Errorf("解析公司新闻响应失败: %w", err)
+		}
+
+		if len(newsResponse.News) == 0 {
+			break
+		}
+		fullPage := len(newsResponse.News) >= limit
+
+		// 最早发布日（去除时间）和该日的新闻，用于下一页的结束日期和去重
+		minDate := newsResponse.News[0].DateTime
+		for _, news := range newsResponse.News {
+			if news.DateTime < minDate {
+				minDate = news.DateTime
+			}
+		}
+		if strings.Contains(minDate, "T") {
+			minDate = strings.Split(minDate, "T")[0]
+		}
+
+		page := make([]tools.CompanyNews, 0, len(newsResponse.News))
+		nextBoundary := make(map[string]bool)
+		for _, news := range newsResponse.News {
+			key := newsKey(news)
+			if strings.HasPrefix(news.DateTime, minDate) {
+				nextBoundary[key] = true
+			}
+			if !boundary[key] {
+				page = append(page, news)
+			}
+		}
+		if len(page) > 0 {
+			if err := handle(page); err != nil {
+				return err
+			}
+		}
+
+		// 只有在设置了开始日期且获得了完整页面时才继续分页
+		if startDate == nil || !fullPage {
+			break
+		}
+		// 同一发布日的新闻超过一页时按日期翻页无法推进：跳过该日其余的新闻，从前一天继续获取更早的新闻
+		if minDate >= currentEndDate && len(page) == 0 {
+			day, err := time.Parse("2006-01-02", minDate)
+			if err != nil {
+				return fmt.Errorf("解析新闻发布日期失败: %s, %w", minDate, err)
+			}
+			log.Printf("[CompanyNews] %s 在 %s 发布的新闻超过单页 %d 条，该日其余新闻未获取", ticker, minDate, limit)
+			currentEndDate = day.AddDate(0, 0, -1).Format("2006-01-02")
+			boundary = make(map[string]bool)
+		} else {
+			currentEndDate = minDate
+			boundary = nextBoundary
+		}
+
+		// 如果已达到或超过开始日期，停止
+		if currentEndDate <= *startDate {
+			break
+		}
+	}
+
+	return nil
+}
+
+// InsiderTrades 分页获取内部交易数据，按本页最早的申报日向前翻页
+// 交易较多的股票按整页向前翻页可能持续很久，因此：开始日期早于 INSIDER_TRADES_MAX_WINDOW_DAYS（默认5年）时只获取窗口内的数据，
+// 累计达到 INSIDER_TRADES_MAX_RECORDS（默认2000条）或翻页不再推进时停止；发生截断时返回说明，否则返回空字符串
+func (financialDatasets) InsiderTrades(ctx context.Context, ticker, endDate string, startDate *string, limit int, handle func(page []tools.InsiderTrade) error) (string, error) {
+	maxRecords, err := positiveIntEnv("INSIDER_TRADES_MAX_RECORDS", defaultInsiderTradesMaxRecords)
+	if err != nil {
+		return "", err
+	}
+	maxWindowDays, err := positiveIntEnv("INSIDER_TRADES_MAX_WINDOW_DAYS", defaultInsiderTradesMaxWindowDays)
+	if err != nil {
+		return "", err
+	}
+
+	var notices []string
+	if startDate != nil {
+		if end, err := time.Parse("2006-01-02", endDate); err == nil {
+			earliest := end.AddDate(0, 0, -maxWindowDays).Format("2006-01-02")
+			if *startDate < earliest {
+				notices = append(notices, fmt.Sprintf("时间窗口超过 %d 天，只获取 %s 之后申报的交易", maxWindowDays, earliest))
+				startDate = &earliest
+			}
+		}
+	}
+
+	currentEndDate := endDate
+	total := 0
+	boundary := make(map[string]bool) // 上一页最早申报日的交易，按日期向前翻页时下一页会再次返回
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		endpoint := fmt.Sprintf("/insider-trades/?ticker=%s&filing_date_lte=%s", ticker, currentEndDate)
+		if startDate != nil {
+			endpoint += fmt.Sprintf("&filing_date_gte=%s", *startDate)
+		}
+		endpoint += fmt.Sprintf("&limit=%d", limit)
+
+		resp, err := fetchFinancialDatasets(ctx, "GET", endpoint, nil)
+		if err != nil {
+			return "", fmt.Errorf("API 请求失败: %w", err)
+		}
+
+		if resp.StatusCode != 200 {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return "", fmt.Errorf("获取数据错误: %s: %w", ticker, tools.StatusError(resp.StatusCode, body))
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return "", fmt.Errorf("读取响应体失败: %w", err)
+		}
+
+		var tradeResponse InsiderTradeResponse
+		if err := json.Unmarshal(body, &tradeResponse); err != nil {
+			return "", fmt.Errorf("解析内部交易响应失败: %w", err)
+		}
+
+		if len(tradeResponse.InsiderTrades) == 0 {
+			break
+		}
+		fullPage := len(tradeResponse.InsiderTrades) >= limit
+
+		// 最早申报日（去除时间）和该日的交易，用于下一页的结束日期和去重
+		minDate := tradeResponse.InsiderTrades[0].FilingDate
+		for _, trade := range tradeResponse.InsiderTrades {
+			if trade.FilingDate < minDate {
+				minDate = trade.FilingDate
+			}
+		}
+		if strings.Contains(minDate, "T") {
+			minDate = strings.Split(minDate, "T")[0]
+		}
+
+		page := make([]tools.InsiderTrade, 0, len(tradeResponse.InsiderTrades))
+		nextBoundary := make(map[string]bool)
+		for _, trade := range tradeResponse.InsiderTrades {
+			key := insiderTradeKey(trade)
+			if strings.HasPrefix(trade.FilingDate, minDate) {
+				nextBoundary[key] = true
+			}
+			if !boundary[key] {
+				page = append(page, trade)
+			}
+		}
+		trimmed := total+len(page) > maxRecords
+		if trimmed {
+			page = page[:maxRecords-total]
+		}
+		total += len(page)
+		if total >= maxRecords && (trimmed || (fullPage && startDate != nil)) {
+			notices = append(notices, fmt.Sprintf("交易达到 %d 条上限，只返回最近申报的 %d 条", maxRecords, maxRecords))
+		}
+		if len(page) > 0 {
+			if err := handle(page); err != nil {
+				return "", err
+			}
+		}
+
+		// 只有在设置了开始日期且获得了完整页面时才继续分页
+		if startDate == nil || !fullPage || total >= maxRecords {
+			break
+		}
+		// 同一申报日的交易超过一页时按日期翻页无法推进
+		if minDate >= currentEndDate && len(page) == 0 {
+			notices = append(notices, fmt.Sprintf("%s 申报的交易超过单页 %d 条，之前的交易未获取", minDate, limit))
+			break
+		}
+		currentEndDate = minDate
+		boundary = nextBoundary
+
+		// 如果已达到或超过开始日期，停止
+		if currentEndDate <= *startDate {
+			break
+		}
+	}
+
+	return strings.Join(notices, "；"), nil
+}
+
+// MarketCap 当天的市值取自公司信息，历史日期取自截至该日的 TTM 财务指标
+func (p financialDatasets) MarketCap(ctx context.Context, ticker, endDate string) (float64, error) {
+	if endDate == time.Now().Format("2006-01-02") {
+		facts, err := p.Facts(ctx, ticker)
+		if err != nil {
+			return 0, err
+		}
+		if facts.MarketCap <= 0 {
+			return 0, fmt.Errorf("%s 公司事实中的市值: %w", ticker, tools.ErrNoData)
+		}
+		return facts.MarketCap, nil
+	}
+
+	metrics, err := p.Metrics(ctx, ticker, endDate, "ttm", 10)
+	if err != nil {
+		return 0, err
+	}
+	if metrics[0].MarketCap <= 0 {
+		return 0, fmt.Errorf("%s 截至 %s 的市值: %w", ticker, endDate, tools.ErrNoData)
+	}
+	return metrics[0].MarketCap, nil
+}
+
+// Facts 获取公司基本信息（行业、板块、上市日期等）
+func (financialDatasets) Facts(ctx context.Context, ticker string) (*provider.CompanyFacts, error) {
+	endpoint := fmt.Sprintf("/company/facts/?ticker=%s", ticker)
+	resp, err := fetchFinancialDatasets(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("API 请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("获取公司事实错误: %s: %w", ticker, tools.StatusError(resp.StatusCode, body))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应体失败: %w", err)
+	}
+
+	var factsResponse CompanyFactsResponse
+	if err := json.Unmarshal(body, &factsResponse); err != nil {
+		return nil, fmt.Errorf("解析公司事实响应失败: %w", err)
+	}
+
+	return &factsResponse.CompanyFacts, nil
+}
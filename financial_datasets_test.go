@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"investment/tools"
+)
+
+func TestMain(m *testing.M) {
+	// 测试不读写磁盘上的响应缓存
+	os.Setenv("API_CACHE_TTL", "0")
+	os.Exit(m.Run())
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// stubDataAPI 把数据源客户端替换为只有 name 一个提供方、由 handle 返回状态码和响应体的客户端，返回已发出的请求
+func stubDataAPI(t *testing.T, name string, p *dataProvider, handle func(r *http.Request) (int, string)) *[]*http.Request {
+	t.Helper()
+	t.Setenv("DATA_PROVIDER", name)
+	var requests []*http.Request
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		requests = append(requests, r)
+		status, body := handle(r)
+		return &http.Response{
+			StatusCode: status,
+			Header:     make(http.Header),
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    r,
+		}, nil
+	})
+	dataAPIMu.Lock()
+	dataAPI = &dataClient{
+		http:      &http.Client{Transport: transport},
+		providers: map[string]*dataProvider{name: p},
+	}
+	dataAPIMu.Unlock()
+	t.Cleanup(resetDataAPIClient)
+	return &requests
+}
+
+// stubFinancialDatasets 把数据源替换为由 handle 响应的 FinancialDatasets.ai
+func stubFinancialDatasets(t *testing.T, handle func(r *http.Request) (int, string)) *[]*http.Request {
+	t.Helper()
+	return stubDataAPI(t, providerFinancialDatasets, &dataProvider{BaseURL: "https://fd.test", Header: "X-API-KEY", keys: []string{"test"}}, handle)
+}
+
+// newsJSON 生成新闻接口的响应，items 为 "ID@日期"
+func newsJSON(items ...string) string {
+	var news []string
+	for _, item := range items {
+		id, date, _ := strings.Cut(item, "@")
+		news = append(news, fmt.Sprintf(`{"id":%q,"title":"news %s","datetime":"%sT12:00:00Z"}`, id, id, date))
+	}
+	return `{"news":[` + strings.Join(news, ",") + `]}`
+}
+
+func TestFinancialDatasetsNewsPaging(t *testing.T) {
+	// 按结束日期返回的页面；2025-01-07 的新闻超过一页，按日期翻页会一直返回同一页，需要跳到前一天继续
+	pages := map[string]string{
+		"2025-01-10": newsJSON("A@2025-01-10", "B@2025-01-09", "C@2025-01-08"),
+		"2025-01-08": newsJSON("C@2025-01-08", "D@2025-01-08", "E@2025-01-07"),
+		"2025-01-07": newsJSON("E@2025-01-07", "F@2025-01-07", "G@2025-01-07"),
+		"2025-01-06": newsJSON("H@2025-01-05", "I@2025-01-02"),
+	}
+	requests := stubFinancialDatasets(t, func(r *http.Request) (int, string) {
+		return http.StatusOK, pages[r.URL.Query().Get("end_date")]
+	})
+
+	startDate := "2025-01-01"
+	var ids []string
+	err := financialDatasets{}.News(context.Background(), "AAPL", "2025-01-10", &startDate, 3, func(page []tools.CompanyNews) error {
+		for _, news := range page {
+			ids = append(ids, news.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(ids, ""); got != "ABCDEFGHI" {
+		t.Errorf("得到新闻 %s，期望 ABCDEFGHI（边界日的新闻不重复，更早的新闻不丢失）", got)
+	}
+	var ends []string
+	for _, r := range *requests {
+		ends = append(ends, r.URL.Query().Get("end_date"))
+	}
+	if got := strings.Join(ends, ","); got != "2025-01-10,2025-01-08,2025-01-07,2025-01-07,2025-01-06" {
+		t.Errorf("请求的结束日期为 %s，期望翻页不再推进时从前一天 2025-01-06 继续", got)
+	}
+}
+
+func TestGetCompanyNewsStopsAtLimit(t *testing.T) {
+	requests := stubFinancialDatasets(t, func(r *http.Request) (int, string) {
+		return http.StatusOK, newsJSON("A@2025-01-10", "B@2025-01-09", "C@2025-01-08")
+	})
+
+	startDate := "2024-01-01"
+	news, err := GetCompanyNews(context.Background(), "AAPL", "2025-01-10", &startDate, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(news) != 3 {
+		t.Errorf("返回 %d 条新闻，期望 3 条", len(news))
+	}
+	if len(*requests) != 1 {
+		t.Errorf("发出 %d 次请求，期望获取到 limit 条后停止翻页", len(*requests))
+	}
+	if got := (*requests)[0].URL.Query().Get("start_date"); got != startDate {
+		t.Errorf("请求的开始日期为 %q，期望 %q", got, startDate)
+	}
+}
+
+func TestGetMarketCap(t *testing.T) {
+	today := time.Now().Format("2006-01-02")
+	tests := []struct {
+		name    string
+		endDate string
+		status  int
+		body    string
+		path    string // 期望请求的接口
+		want    float64
+		wantErr error
+	}{
+		{name: "当天取自公司信息", endDate: today, status: http.StatusOK, body: `{"company_facts":{"ticker":"AAPL","market_cap":3.2e12}}`,
+			path: "/company/facts/", want: 3.2e12},
+		{name: "历史日期取自 TTM 指标", endDate: "2024-06-30", status: http.StatusOK, body: `{"financial_metrics":[{"ticker":"AAPL","market_cap":2.9e12}]}`,
+			path: "/financial-metrics/", want: 2.9e12},
+		{name: "公司信息没有市值", endDate: today, status: http.StatusOK, body: `{"company_facts":{"ticker":"AAPL"}}`,
+			path: "/company/facts/", wantErr: tools.ErrNoData},
+		{name: "指标为空", endDate: "2024-06-30", status: http.StatusOK, body: `{"financial_metrics":[]}`,
+			path: "/financial-metrics/", wantErr: tools.ErrNoData},
+		{name: "指标没有市值", endDate: "2024-06-30", status: http.StatusOK, body: `{"financial_metrics":[{"ticker":"AAPL"}]}`,
+			path: "/financial-metrics/", wantErr: tools.ErrNoData},
+		{name: "密钥无效", endDate: today, status: http.StatusUnauthorized, body: `{"error":"invalid key"}`,
+			path: "/company/facts/", wantErr: tools.ErrUnauthorized},
+		{name: "股票不存在", endDate: "2024-06-30", status: http.StatusNotFound, body: `{"error":"not found"}`,
+			path: "/financial-metrics/", wantErr: tools.ErrNotFound},
+		{name: "服务错误", endDate: today, status: http.StatusInternalServerError, body: `internal error`,
+			path: "/company/facts/", wantErr: tools.ErrUpstream},
+		{name: "网关错误", endDate: "2024-06-30", status: http.StatusBadGateway, body: `bad gateway`,
+			path: "/financial-metrics/", wantErr: tools.ErrUpstream},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := stubFinancialDatasets(t, func(r *http.Request) (int, string) {
+				return tt.status, tt.body
+			})
+
+			got, err := GetMarketCap(context.Background(), "AAPL", tt.endDate)
+			if len(*requests) != 1 || (*requests)[0].URL.Path != tt.path {
+				t.Fatalf("期望请求一次 %s，实际请求: %v", tt.path, *requests)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("得到错误 %v，期望 %v", err, tt.wantErr)
+				}
+				if got != 0 {
+					t.Errorf("出错时返回市值 %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("意外的错误: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("得到市值 %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
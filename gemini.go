@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 
@@ -11,17 +12,11 @@ import (
 )
 
 func createGeminiChatModel(ctx context.Context) model.ToolCallingChatModel {
-	key := os.Getenv("GEMINI_API_KEY")
-	if key == "" {
-		log.Fatalf("GEMINI_API_KEY is not set")
-	}
 	modelName := os.Getenv("GEMINI_MODEL_NAME")
 	if modelName == "" {
 		log.Fatalf("GEMINI_MODEL_NAME is not set")
 	}
-	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey: key,
-	})
+	client, err := newGeminiClient(ctx)
 	if err != nil {
 		log.Fatalf("create gemini client failed, err=%v", err)
 	}
@@ -34,3 +29,15 @@ func createGeminiChatModel(ctx context.Context) model.ToolCallingChatModel {
 	}
 	return chatModel
 }
+
+// newGeminiClient 使用 GEMINI_API_KEY 创建 Gemini 客户端，模型调用和文件上传共用
+func newGeminiClient(ctx context.Context) (*genai.Client, error) {
+	key := os.Getenv("GEMINI_API_KEY")
+	if key == "" {
+		return nil, fmt.Errorf("GEMINI_API_KEY is not set")
+	}
+	return genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:     key,
+		HTTPClient: newProxiedHTTPClient(0, geminiProxyEnv...),
+	})
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// historyEvent 股票历史中的一条记录：一次分析或一次投资决策
+type historyEvent struct {
+	Time  time.Time
+	Kind  string // 分析 / 决策
+	Text  string
+	Trend string // 评级相对上一次分析的变化：↑、↓ 或空
+}
+
+// runHistory 处理 history 子命令：按时间倒序合并列出一只股票的分析评级和交易日志决策
+func runHistory(args []string) error {
+	if len(args) < 1 || strings.HasPrefix(args[0], "-") {
+		return fmt.Errorf("用法: history <stock_symbol> [--limit 20]")
+	}
+	symbol := canonicalSymbol(args[0])
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	limit := fs.Int("limit", 20, "最多列出的记录数（0 表示全部）")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	records, err := listRunRecords(runFilter{Symbol: symbol})
+	if err != nil {
+		return err
+	}
+	journal, err := loadTradeJournal(symbol)
+	if err != nil {
+		return err
+	}
+
+	var events []historyEvent
+	// 运行记录按时间倒序，从旧到新遍历才能和上一次的评级比较
+	previous := ""
+	for i := len(records) - 1; i >= 0; i-- {
+		r := records[i]
+		event := historyEvent{
+			Time: r.StartedAt,
+			Kind: "分析",
+			Text: fmt.Sprintf("评级=%s  模型=%s  %s  %s", orDash(r.Rating), r.Model, runStatus(r), r.ReportPath),
+		}
+		if r.Rating != "" {
+			event.Trend = ratingTrend(previous, r.Rating)
+			previous = r.Rating
+		}
+		events = append(events, event)
+	}
+	if journal != nil {
+		for _, e := range journal.Entries {
+			events = append(events, historyEvent{Time: e.Date, Kind: "决策", Text: e.describe()})
+		}
+	}
+	if len(events) == 0 {
+		fmt.Printf("%s 没有分析记录或决策记录\n", symbol)
+		return nil
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.After(events[j].Time) })
+
+	fmt.Printf("=== %s 的历史（%d 次分析，%d 条决策）===\n", symbol, len(records), len(events)-len(records))
+	for i, e := range events {
+		if *limit > 0 && i >= *limit {
+			fmt.Printf("…… 另有 %d 条较早的记录，可调大 --limit 查看\n", len(events)-*limit)
+			break
+		}
+		fmt.Printf("%s  %s %-1s  %s\n", e.Time.Format("2006-01-02 15:04"), e.Kind, e.Trend, e.Text)
+	}
+	return nil
+}
+
+// ratingTrend 评级相对上一次的变化，按 ratingOrder 排列，越靠前越看好
+func ratingTrend(previous, current string) string {
+	from, to := slices.Index(ratingOrder, previous), slices.Index(ratingOrder, current)
+	switch {
+	case from < 0 || to < 0 || from == to:
+		return ""
+	case to < from:
+		return "↑"
+	default:
+		return "↓"
+	}
+}
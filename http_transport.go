@@ -0,0 +1,155 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// sharedTransport 所有数据源和模型客户端共用的连接池
+// 批量分析时会发出数百次请求，复用连接可以省去重复的 TCP/TLS 握手；
+// 未手动设置 Accept-Encoding，因此响应的 gzip 压缩由 Transport 透明处理
+var sharedTransport = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
+	DialContext: (&net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext,
+	ForceAttemptHTTP2:     true,
+	DisableCompression:    false,
+	MaxIdleConns:          100,
+	MaxIdleConnsPerHost:   20,
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ExpectContinueTimeout: 1 * time.Second,
+}
+
+// 各客户端的代理配置，按顺序取第一个已设置的变量；都未设置时按 HTTPS_PROXY / HTTP_PROXY / NO_PROXY 环境变量
+var (
+	geminiProxyEnv   = []string{"GEMINI_PROXY", "LLM_PROXY"}
+	openAIProxyEnv   = []string{"OPENAI_PROXY", "LLM_PROXY"}
+	deepseekProxyEnv = []string{"DEEPSEEK_PROXY", "LLM_PROXY"}
+	dataProxyEnv     = []string{"FINANCIAL_DATASETS_PROXY", "DATA_API_PROXY"}
+)
+
+// proxySchemes 支持的代理协议，socks5h 由代理服务器解析域名
+var proxySchemes = []string{"http", "https", "socks5", "socks5h"}
+
+var (
+	proxyTransportsMu sync.Mutex
+	proxyTransports   = make(map[string]*http.Transport) // 代理地址 -> Transport，相同代理共用连接池
+)
+
+// proxyFromEnv 返回 names 中第一个已设置的代理变量及其解析结果，都未设置时返回 nil
+func proxyFromEnv(names ...string) (string, *url.URL, error) {
+	for _, name := range names {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		proxyURL, err := url.Parse(value)
+		if err != nil || proxyURL.Host == "" || !slices.Contains(proxySchemes, proxyURL.Scheme) {
+			return name, nil, fmt.Errorf("无效的 %s: %q（支持 http、https、socks5、socks5h 代理）", name, value)
+		}
+		return name, proxyURL, nil
+	}
+	return "", nil, nil
+}
+
+// transportFor 返回按 names 中第一个已设置的代理变量配置的 Transport，都未设置时返回 sharedTransport
+// 代理地址无效时返回错误和 sharedTransport
+func transportFor(names ...string) (*http.Transport, error) {
+	_, proxyURL, err := proxyFromEnv(names...)
+	if err != nil || proxyURL == nil {
+		return sharedTransport, err
+	}
+	proxyTransportsMu.Lock()
+	defer proxyTransportsMu.Unlock()
+	key := proxyURL.String()
+	if transport, ok := proxyTransports[key]; ok {
+		return transport, nil
+	}
+	transport := sharedTransport.Clone()
+	transport.Proxy = http.ProxyURL(proxyURL)
+	proxyTransports[key] = transport
+	return transport, nil
+}
+
+// newHTTPClient 创建使用共享连接池的客户端，timeout 为 0 表示不限制（用于模型的流式输出）
+// 请求经过 snapshotTransport，录制或回放数据快照时会被拦截
+func newHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: snapshotTransport{},
+		Timeout:   timeout,
+	}
+}
+
+// newProxiedHTTPClient 与 newHTTPClient 相同，但按 proxyEnv 中的代理变量发出请求；代理地址无效时记录日志并不使用该代理
+func newProxiedHTTPClient(timeout time.Duration, proxyEnv ...string) *http.Client {
+	transport, err := transportFor(proxyEnv...)
+	if err != nil {
+		log.Printf("%v，使用默认代理设置", err)
+	}
+	return &http.Client{
+		Transport: snapshotTransport{base: transport},
+		Timeout:   timeout,
+	}
+}
+
+// configureTLS 把 CA_BUNDLE（PEM 文件）中的证书加入所有客户端信任的根证书，用于企业网络中解密 TLS 的代理
+// 启动时调用一次，修改 CA_BUNDLE 后需要重启；未设置时使用系统根证书（也可以用标准的 SSL_CERT_FILE）
+func configureTLS() error {
+	path := os.Getenv("CA_BUNDLE")
+	if path == "" {
+		return nil
+	}
+	pool, err := caBundlePool(path)
+	if err != nil {
+		return err
+	}
+	sharedTransport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	log.Printf("已加载自定义 CA 证书: %s", path)
+	return nil
+}
+
+// caBundlePool 系统根证书加上 path 中的证书
+func caBundlePool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取 CA_BUNDLE 失败: %v", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("CA_BUNDLE %s 中没有有效的 PEM 证书", path)
+	}
+	return pool, nil
+}
+
+// networkConfigProblems 检查代理和 CA 证书配置
+func networkConfigProblems() []string {
+	var problems []string
+	for _, names := range [][]string{geminiProxyEnv, openAIProxyEnv, deepseekProxyEnv, dataProxyEnv} {
+		for _, name := range names {
+			if _, _, err := proxyFromEnv(name); err != nil && !slices.Contains(problems, err.Error()) {
+				problems = append(problems, err.Error())
+			}
+		}
+	}
+	if path := os.Getenv("CA_BUNDLE"); path != "" {
+		if _, err := caBundlePool(path); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	return problems
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"investment/tools"
+)
+
+// defaultBenchmarkUniverse 默认的行业基准股票池，覆盖主要板块的大中盘股
+var defaultBenchmarkUniverse = []string{
+	"AAPL", "MSFT", "GOOGL", "META", "NVDA", "AMD", "INTC", "AVGO", "ORCL", "CRM", "ADBE", "CSCO",
+	"AMZN", "TSLA", "HD", "NKE", "MCD", "SBUX",
+	"JPM", "BAC", "WFC", "GS", "MS", "C",
+	"JNJ", "PFE", "MRK", "ABBV", "LLY", "UNH",
+	"XOM", "CVX", "COP",
+	"PG", "KO", "PEP", "WMT", "COST",
+	"CAT", "BA", "GE", "HON", "UPS",
+	"NEE", "DUK", "SO",
+	"VZ", "T", "DIS", "NFLX",
+}
+
+// industryClassification 股票所属的板块与行业
+type industryClassification struct {
+	Sector   string `json:"sector"`
+	Industry string `json:"industry"`
+}
+
+// industryBenchmarkCache 行业基准的本地缓存内容
+type industryBenchmarkCache struct {
+	UpdatedAt  time.Time                           `json:"updated_at"`
+	Universe   []string                            `json:"universe"`
+	Industries map[string]*tools.IndustryBenchmark `json:"industries"`
+	Sectors    map[string]*tools.IndustryBenchmark `json:"sectors"`
+	Tickers    map[string]industryClassification   `json:"tickers"`
+}
+
+// industryBenchmarkConcurrency 计算行业基准时同时拉取数据的股票数
+const industryBenchmarkConcurrency = 4
+
+// IndustryBenchmarkService 行业基准统计服务
+// 基于可配置的股票池计算各行业/板块的指标中位数，并缓存到本地文件
+type IndustryBenchmarkService struct {
+	universe  []string
+	cachePath string
+	ttl       time.Duration
+	minSample int
+
+	buildMu sync.Mutex // 同一时间只计算一次行业基准，计算期间不持有 mu
+	mu      sync.Mutex // 保护 cache 和 classes；cache 发布后不再修改，可以在锁外读取
+	cache   *industryBenchmarkCache
+	classes map[string]industryClassification // 股票池之外的股票的行业信息
+}
+
+// NewIndustryBenchmarkServiceFromEnv 根据环境变量创建行业基准统计服务
+// INDUSTRY_BENCHMARK_UNIVERSE: 逗号分隔的股票池，默认使用内置大盘股列表
+// INDUSTRY_BENCHMARK_TTL_HOURS: 缓存有效期（小时），默认 168 小时
+func NewIndustryBenchmarkServiceFromEnv() *IndustryBenchmarkService {
+	universe := defaultBenchmarkUniverse
+	if raw := os.Getenv("INDUSTRY_BENCHMARK_UNIVERSE"); raw != "" {
+		universe = nil
+		for _, symbol := range strings.Split(raw, ",") {
+			symbol = strings.ToUpper(strings.TrimSpace(symbol))
+			if symbol != "" {
+				universe = append(universe, symbol)
+			}
+		}
+	}
+
+	ttl := 7 * 24 * time.Hour
+	if raw := os.Getenv("INDUSTRY_BENCHMARK_TTL_HOURS"); raw != "" {
+		if hours, err := strconv.Atoi(raw); err == nil && hours > 0 {
+			ttl = time.Duration(hours) * time.Hour
+		} else {
+			log.Printf("[IndustryBenchmark] 无效的 INDUSTRY_BENCHMARK_TTL_HOURS=%s，使用默认值", raw)
+		}
+	}
+
+	return &IndustryBenchmarkService{
+		universe:  universe,
+		cachePath: filepath.Join(tools.OutputDir(), "benchmark", "industry_benchmarks.json"),
+		ttl:       ttl,
+		minSample: 3,
+		classes:   make(map[string]industryClassification),
+	}
+}
+
+// Get 获取指定股票所属行业的基准中位数，行业样本不足时退回板块中位数
+func (s *IndustryBenchmarkService) Get(ticker string) (*tools.IndustryBenchmark, error) {
+	ticker = strings.ToUpper(ticker)
+
+	cache, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+
+	class, err := s.classify(cache, ticker)
+	if err != nil {
+		return nil, err
+	}
+
+	if b, ok := cache.Industries[class.Industry]; ok && b.SampleSize >= s.minSample {
+		return b, nil
+	}
+	if b, ok := cache.Sectors[class.Sector]; ok && b.SampleSize >= s.minSample {
+		return b, nil
+	}
+	return nil, fmt.Errorf("行业 %q / 板块 %q 没有足够的基准样本", class.Industry, class.Sector)
+}
+
+// Peers 从基准股票池中自动发现同行业的可比公司，同行业数量不足时退回同板块
+// 返回的 level 为 industry 或 sector，表示可比公司的匹配口径
+func (s *IndustryBenchmarkService) Peers(ticker string, limit int) ([]string, string, error) {
+	ticker = strings.ToUpper(ticker)
+
+	cache, err := s.current()
+	if err != nil {
+		return nil, "", err
+	}
+
+	class, err := s.classify(cache, ticker)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var industryPeers, sectorPeers []string
+	for symbol, c := range cache.Tickers {
+		if symbol == ticker {
+			continue
+		}
+		if class.Industry != "" && c.Industry == class.Industry {
+			industryPeers = append(industryPeers, symbol)
+		}
+		if class.Sector != "" && c.Sector == class.Sector {
+			sectorPeers = append(sectorPeers, symbol)
+		}
+	}
+
+	peers, level := industryPeers, "industry"
+	if len(industryPeers) < s.minSample-1 {
+		peers, level = sectorPeers, "sector"
+	}
+	if len(peers) == 0 {
+		return nil, "", fmt.Errorf("股票池中没有与 %s 同行业 %q / 同板块 %q 的公司", ticker, class.Industry, class.Sector)
+	}
+	sort.Strings(peers)
+	if limit > 0 && len(peers) > limit {
+		peers = peers[:limit]
+	}
+	return peers, level, nil
+}
+
+// classify 获取股票的板块与行业，优先使用股票池的缓存，股票池之外的股票获取一次后缓存在内存中
+func (s *IndustryBenchmarkService) classify(cache *industryBenchmarkCache, ticker string) (industryClassification, error) {
+	if class, ok := cache.Tickers[ticker]; ok {
+		return class, nil
+	}
+	s.mu.Lock()
+	class, ok := s.classes[ticker]
+	s.mu.Unlock()
+	if ok {
+		return class, nil
+	}
+
+	facts, err := GetCompanyFacts(context.Background(), ticker)
+	if err != nil {
+		return industryClassification{}, fmt.Errorf("获取公司行业信息失败: %w", err)
+	}
+	class = industryClassification{Sector: facts.Sector, Industry: facts.Industry}
+	s.mu.Lock()
+	s.classes[ticker] = class
+	s.mu.Unlock()
+	return class, nil
+}
+
+// fresh 返回未过期的内存缓存，没有时返回 nil
+func (s *IndustryBenchmarkService) fresh() *industryBenchmarkCache {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cache != nil && time.Since(s.cache.UpdatedAt) < s.ttl {
+		return s.cache
+	}
+	return nil
+}
+
+// current 返回有效的行业基准：依次使用内存缓存、本地缓存文件，都不可用时重新计算
+// 重新计算只持有 buildMu，并发的调用等待同一次计算完成，不会重复拉取股票池
+func (s *IndustryBenchmarkService) current() (*industryBenchmarkCache, error) {
+	if cache := s.fresh(); cache != nil {
+		return cache, nil
+	}
+
+	s.buildMu.Lock()
+	defer s.buildMu.Unlock()
+	// 等待期间其他调用可能已经完成计算
+	if cache := s.fresh(); cache != nil {
+		return cache, nil
+	}
+
+	cache := s.load()
+	if cache == nil {
+		var err error
+		if cache, err = s.build(); err != nil {
+			return nil, err
+		}
+		if err := s.save(cache); err != nil {
+			log.Printf("[IndustryBenchmark] 保存缓存失败: %v", err)
+		}
+	}
+
+	s.mu.Lock()
+	s.cache = cache
+	s.mu.Unlock()
+	return cache, nil
+}
+
+// load 读取本地缓存文件，文件不存在、无法解析、已过期或股票池已变化时返回 nil
+func (s *IndustryBenchmarkService) load() *industryBenchmarkCache {
+	data, err := os.ReadFile(s.cachePath)
+	if err != nil {
+		return nil
+	}
+	var cache industryBenchmarkCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		log.Printf("[IndustryBenchmark] 解析缓存失败，将重新计算: %v", err)
+		return nil
+	}
+	if time.Since(cache.UpdatedAt) >= s.ttl || !sameUniverse(cache.Universe, s.universe) {
+		return nil
+	}
+	log.Printf("[IndustryBenchmark] 使用本地缓存: %s (更新于 %s)", s.cachePath, cache.UpdatedAt.Format("2006-01-02 15:04:05"))
+	return &cache
+}
+
+// benchmarkSample 股票池中一只股票的行业信息和最新 TTM 财务指标
+type benchmarkSample struct {
+	class   industryClassification
+	metrics *tools.FinancialMetrics // 数据不可用时为 nil
+}
+
+// build 拉取股票池中每只股票的行业信息和最新财务指标（最多 industryBenchmarkConcurrency 只同时进行），计算行业/板块中位数
+// 结果由并发的调用共享并写入缓存文件，不随单次调用的 ctx 取消
+func (s *IndustryBenchmarkService) build() (*industryBenchmarkCache, error) {
+	ctx := context.Background()
+	log.Printf("[IndustryBenchmark] 开始计算行业基准: 股票池数量=%d", len(s.universe))
+	today := time.Now().Format("2006-01-02")
+
+	samples := make([]benchmarkSample, len(s.universe))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, industryBenchmarkConcurrency)
+	for i, symbol := range s.universe {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			facts, err := GetCompanyFacts(ctx, symbol)
+			if err != nil {
+				log.Printf("[IndustryBenchmark] 跳过 %s: %v", symbol, err)
+				return
+			}
+			metrics, err := GetFinancialMetrics(ctx, symbol, today, "ttm", 1)
+			if err != nil || len(metrics) == 0 {
+				log.Printf("[IndustryBenchmark] 跳过 %s: 无财务指标 (%v)", symbol, err)
+				return
+			}
+			samples[i] = benchmarkSample{
+				class:   industryClassification{Sector: facts.Sector, Industry: facts.Industry},
+				metrics: &metrics[0],
+			}
+		}()
+	}
+	wg.Wait()
+
+	tickers := make(map[string]industryClassification)
+	industryValues := make(map[string]map[string][]float64)
+	sectorValues := make(map[string]map[string][]float64)
+	industrySectors := make(map[string]string)
+
+	// 按股票池顺序汇总，结果与拉取完成的先后无关
+	for i, symbol := range s.universe {
+		sample := samples[i]
+		if sample.metrics == nil {
+			continue
+		}
+		class := sample.class
+		tickers[symbol] = class
+		industrySectors[class.Industry] = class.Sector
+
+		for key, value := range tools.BenchmarkMetricValues(*sample.metrics) {
+			appendBenchmarkValue(industryValues, class.Industry, key, value)
+			appendBenchmarkValue(sectorValues, class.Sector, key, value)
+		}
+	}
+
+	if len(tickers) == 0 {
+		return nil, fmt.Errorf("股票池中没有可用数据，无法计算行业基准")
+	}
+
+	updatedAt := time.Now()
+	cache := &industryBenchmarkCache{
+		UpdatedAt:  updatedAt,
+		Universe:   s.universe,
+		Industries: make(map[string]*tools.IndustryBenchmark),
+		Sectors:    make(map[string]*tools.IndustryBenchmark),
+		Tickers:    tickers,
+	}
+	for industry, values := range industryValues {
+		cache.Industries[industry] = newIndustryBenchmark(industrySectors[industry], industry, "industry", values, updatedAt)
+	}
+	for sector, values := range sectorValues {
+		cache.Sectors[sector] = newIndustryBenchmark(sector, "", "sector", values, updatedAt)
+	}
+
+	log.Printf("[IndustryBenchmark] 行业基准计算完成: 行业=%d, 板块=%d, 有效股票=%d", len(cache.Industries), len(cache.Sectors), len(tickers))
+	return cache, nil
+}
+
+// save 将行业基准缓存写入本地文件
+func (s *IndustryBenchmarkService) save(cache *industryBenchmarkCache) error {
+	if err := os.MkdirAll(filepath.Dir(s.cachePath), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %v", err)
+	}
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSON序列化失败: %v", err)
+	}
+	if err := os.WriteFile(s.cachePath, data, 0644); err != nil {
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+	log.Printf("[IndustryBenchmark] 行业基准已保存到: %s", s.cachePath)
+	return nil
+}
+
+// appendBenchmarkValue 将指标值追加到分组中
+func appendBenchmarkValue(groups map[string]map[string][]float64, group, key string, value float64) {
+	if group == "" {
+		return
+	}
+	if groups[group] == nil {
+		groups[group] = make(map[string][]float64)
+	}
+	groups[group][key] = append(groups[group][key], value)
+}
+
+// newIndustryBenchmark 根据分组内的指标值计算中位数
+func newIndustryBenchmark(sector, industry, level string, values map[string][]float64, updatedAt time.Time) *tools.IndustryBenchmark {
+	b := &tools.IndustryBenchmark{
+		Sector:    sector,
+		Industry:  industry,
+		Level:     level,
+		Medians:   make(map[string]float64),
+		UpdatedAt: updatedAt.Format("2006-01-02 15:04:05"),
+	}
+	for _, key := range tools.BenchmarkMetricKeys {
+		if len(values[key]) == 0 {
+			continue
+		}
+		b.Medians[key] = tools.MedianOf(values[key])
+		// 样本数以覆盖最广的指标为准
+		if len(values[key]) > b.SampleSize {
+			b.SampleSize = len(values[key])
+		}
+	}
+	return b
+}
+
+// sameUniverse 判断缓存的股票池与当前配置是否一致
+func sameUniverse(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,468 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"investment/tools"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// industryMetric 行业报告中比较的一项指标，key 与 FinancialMetrics 的 json 字段名一致
+type industryMetric struct {
+	Key          string
+	Label        string
+	Percent      bool
+	HigherBetter bool // 数值越高排名越靠前；为 false 时越低越好（负债、估值）
+}
+
+// industryMetrics 偏好排序使用的指标，每项权重相同
+var industryMetrics = []industryMetric{
+	{Key: "revenue_growth", Label: "营收增长", Percent: true, HigherBetter: true},
+	{Key: "gross_margin", Label: "毛利率", Percent: true, HigherBetter: true},
+	{Key: "operating_margin", Label: "营业利润率", Percent: true, HigherBetter: true},
+	{Key: "return_on_equity", Label: "ROE", Percent: true, HigherBetter: true},
+	{Key: "debt_to_equity", Label: "负债权益比", HigherBetter: false},
+	{Key: "price_to_earnings_ratio", Label: "市盈率", HigherBetter: false},
+}
+
+// industryTrendKeys 行业趋势表中按年统计中位数的指标
+var industryTrendKeys = []string{"revenue_growth", "gross_margin", "operating_margin", "net_margin"}
+
+// IndustryCompany 行业报告中的一家公司
+type IndustryCompany struct {
+	Symbol        string             `json:"symbol"`
+	Name          string             `json:"name,omitempty"`
+	MarketCap     float64            `json:"market_cap,omitempty"`
+	Currency      string             `json:"currency,omitempty"` // 财务指标的币种，未知时按 USD 显示
+	Metrics       map[string]float64 `json:"metrics,omitempty"`  // 最近 TTM 指标，缺失的指标不出现
+	OneYearReturn tools.SafeFloat    `json:"one_year_return"`    // 近一年股价涨跌幅，只展示不参与排序
+	Score         tools.SafeFloat    `json:"score"`              // 各指标在行业内百分位的平均值（0-100）
+	Rank          int                `json:"rank,omitempty"`     // 偏好排序，从 1 开始，数据不足时为 0
+	Error         string             `json:"error,omitempty"`    // 获取数据失败的原因
+
+	annual []tools.FinancialMetrics // 年度指标，用于计算行业趋势
+}
+
+// IndustryTrendYear 一个财年的行业中位数
+type IndustryTrendYear struct {
+	Year       string             `json:"year"`
+	SampleSize int                `json:"sample_size"`
+	Medians    map[string]float64 `json:"medians"`
+}
+
+// IndustryReport 多只股票组成的行业概览
+type IndustryReport struct {
+	ID          string              `json:"id"`
+	Industry    string              `json:"industry"`
+	GeneratedAt time.Time           `json:"generated_at"`
+	Companies   []IndustryCompany   `json:"companies"` // 按偏好排序，数据不足的公司排在最后
+	Medians     map[string]float64  `json:"medians"`   // 各指标的行业中位数（TTM）
+	Trends      []IndustryTrendYear `json:"trends"`
+	Commentary  string              `json:"commentary,omitempty"` // 模型撰写的行业分析，生成失败时为空
+	ReportPath  string              `json:"report_path,omitempty"`
+}
+
+// runIndustry 处理 industry 子命令：收集多只股票的数据，计算行业中位数和趋势，
+// 按指标百分位给出偏好排序，并由模型撰写一份行业概览报告（与单只股票的报告分开保存在 industry/ 下）
+func runIndustry(args []string) error {
+	usage := "用法: industry <行业名称> --tickers NVDA,AMD,INTC,TSM [--years 3] [--timeout 5m]"
+	if len(args) < 1 || strings.HasPrefix(args[0], "-") {
+		return errors.New(usage)
+	}
+	industry := strings.TrimSpace(args[0])
+	fs := flag.NewFlagSet("industry", flag.ExitOnError)
+	tickersFlag := fs.String("tickers", "", "行业内的股票代码，用逗号分隔，至少 2 只")
+	years := fs.Int("years", 3, "行业趋势回溯的财年数（1-10）")
+	timeout := fs.Duration("timeout", 5*time.Minute, "模型撰写行业分析的最长时间（0 表示不限制）")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	tickers := parseSymbolList(*tickersFlag)
+	if len(tickers) < 2 {
+		return errors.New(usage)
+	}
+	if *years < 1 || *years > 10 {
+		return fmt.Errorf("无效的 --years: %d（1-10）", *years)
+	}
+	if err := validateAnalysisConfig(); err != nil {
+		return err
+	}
+
+	report := &IndustryReport{
+		ID:          fmt.Sprintf("%s_%s", industrySlug(industry), time.Now().Format("2006-01-02_15-04-05")),
+		Industry:    industry,
+		GeneratedAt: time.Now(),
+	}
+	fmt.Printf("=== 行业报告：%s（%s）===\n", industry, strings.Join(tickers, "、"))
+	for i, symbol := range tickers {
+		fmt.Printf("[%d/%d] 收集 %s 的数据...\n", i+1, len(tickers), symbol)
+		company := collectIndustryCompany(context.Background(), symbol, *years)
+		if company.Error != "" {
+			fmt.Printf("⚠️ %s 数据不完整: %s\n", symbol, company.Error)
+		}
+		report.Companies = append(report.Companies, company)
+	}
+	if err := rankIndustryCompanies(report.Companies); err != nil {
+		return err
+	}
+	report.Medians = industryMedians(report.Companies)
+	report.Trends = industryTrends(report.Companies, *years)
+
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	format := tools.NumberFormat{Locale: tools.DefaultLocale}
+	fmt.Printf("🤖 撰写行业分析...\n")
+	chatModel, _ := createChatModel(ctx)
+	commentary, err := writeIndustryCommentary(ctx, chatModel, report, format)
+	if err != nil {
+		log.Printf("[Industry] 撰写行业分析失败，报告只包含数据表: %v", err)
+	} else {
+		report.Commentary = commentary
+	}
+
+	content := renderIndustryReport(report, format)
+	location, err := tools.Output().WriteReport(path.Join("industry", report.ID+".md"), []byte(content))
+	if err != nil {
+		return fmt.Errorf("保存行业报告失败: %v", err)
+	}
+	report.ReportPath = location
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化行业报告失败: %v", err)
+	}
+	if _, err := tools.Output().WriteArtifact(path.Join("industry", report.ID+".json"), data); err != nil {
+		log.Printf("[Industry] 保存行业数据失败: %v", err)
+	}
+
+	fmt.Print(strings.Repeat("=", 50) + "\n")
+	for _, c := range report.Companies {
+		if c.Rank > 0 {
+			fmt.Printf("%d. %s（综合得分 %s）\n", c.Rank, c.Symbol, c.Score.Sprintf("%.0f"))
+		}
+	}
+	fmt.Printf("📄 行业报告已保存: %s\n", location)
+	return nil
+}
+
+// industrySlugPattern 文件名中不允许的字符
+var industrySlugPattern = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// industrySlug 行业名称转换为文件名，如 "Semiconductors & Equipment" -> semiconductors-equipment
+func industrySlug(industry string) string {
+	slug := strings.Trim(industrySlugPattern.ReplaceAllString(strings.ToLower(industry), "-"), "-")
+	if slug == "" {
+		return "industry"
+	}
+	return slug
+}
+
+// collectIndustryCompany 获取一家公司的基本信息、TTM 指标、年度指标和近一年涨跌幅，部分数据失败时记录在 Error 中
+func collectIndustryCompany(ctx context.Context, symbol string, years int) IndustryCompany {
+	company := IndustryCompany{Symbol: symbol, OneYearReturn: tools.NaN(), Score: tools.NaN()}
+	var problems []string
+	today := time.Now().Format("2006-01-02")
+
+	if facts, err := GetCompanyFacts(ctx, symbol); err != nil {
+		problems = append(problems, fmt.Sprintf("公司信息: %v", err))
+	} else {
+		company.Name = facts.Name
+		company.MarketCap = facts.MarketCap
+	}
+	if metrics, err := GetFinancialMetrics(ctx, symbol, today, "ttm", 1); err != nil || len(metrics) == 0 {
+		problems = append(problems, fmt.Sprintf("TTM 指标: %v", orNoData(err)))
+	} else {
+		company.Metrics = tools.BenchmarkMetricValues(metrics[0])
+		company.Currency = metrics[0].Currency
+		if company.MarketCap == 0 {
+			company.MarketCap = metrics[0].MarketCap
+		}
+	}
+	if annual, err := GetFinancialMetrics(ctx, symbol, today, "annual", years); err != nil {
+		problems = append(problems, fmt.Sprintf("年度指标: %v", err))
+	} else {
+		company.annual = annual
+	}
+	if series, err := GetPriceSeries(ctx, symbol, 1); err != nil {
+		problems = append(problems, fmt.Sprintf("价格: %v", err))
+	} else if n := len(series.Close); n > 1 {
+		company.OneYearReturn = tools.SafeGrowth(series.Close[n-1], series.Close[0])
+	}
+	company.Error = strings.Join(problems, "；")
+	return company
+}
+
+// orNoData 没有错误但返回为空时的说明
+func orNoData(err error) error {
+	if err != nil {
+		return err
+	}
+	return errors.New("无数据")
+}
+
+// rankIndustryCompanies 计算每家公司各指标在行业内的百分位（最好为 100），取平均作为综合得分并排序；
+// 有效指标不足一半的公司不参与排序，排在最后
+func rankIndustryCompanies(companies []IndustryCompany) error {
+	for i := range companies {
+		var percentiles []float64
+		for _, metric := range industryMetrics {
+			value, ok := companies[i].Metrics[metric.Key]
+			if !ok {
+				continue
+			}
+			var others []float64
+			for j := range companies {
+				if other, ok := companies[j].Metrics[metric.Key]; ok && i != j {
+					others = append(others, other)
+				}
+			}
+			if p, ok := peerPercentile(value, others, metric.HigherBetter); ok {
+				percentiles = append(percentiles, p)
+			}
+		}
+		if len(percentiles) > 0 && len(percentiles)*2 >= len(industryMetrics) {
+			companies[i].Score = tools.SafeDiv(sumOf(percentiles), float64(len(percentiles)))
+		}
+	}
+
+	sort.SliceStable(companies, func(i, j int) bool {
+		a, b := companies[i].Score, companies[j].Score
+		if a.Valid() != b.Valid() {
+			return a.Valid()
+		}
+		return a > b
+	})
+	ranked := 0
+	for i := range companies {
+		if companies[i].Score.Valid() {
+			ranked++
+			companies[i].Rank = ranked
+		}
+	}
+	if ranked == 0 {
+		return errors.New("所有股票的数据都不足，无法生成行业报告")
+	}
+	return nil
+}
+
+// industryMedians 各指标的行业中位数（TTM）
+func industryMedians(companies []IndustryCompany) map[string]float64 {
+	values := make(map[string][]float64)
+	for _, c := range companies {
+		for key, value := range c.Metrics {
+			values[key] = append(values[key], value)
+		}
+	}
+	medians := make(map[string]float64)
+	for key, v := range values {
+		medians[key] = tools.MedianOf(v)
+	}
+	return medians
+}
+
+// industryTrends 最近 years 个财年的行业中位数，按年份升序
+func industryTrends(companies []IndustryCompany, years int) []IndustryTrendYear {
+	byYear := make(map[string]map[string][]float64)
+	samples := make(map[string]int)
+	for _, c := range companies {
+		seen := make(map[string]bool)
+		for _, m := range c.annual {
+			if len(m.ReportPeriod) < 4 {
+				continue
+			}
+			year := m.ReportPeriod[:4]
+			if seen[year] {
+				continue
+			}
+			seen[year] = true
+			samples[year]++
+			if byYear[year] == nil {
+				byYear[year] = make(map[string][]float64)
+			}
+			values := tools.BenchmarkMetricValues(m)
+			if m.NetMargin != nil {
+				values["net_margin"] = *m.NetMargin
+			}
+			for _, key := range industryTrendKeys {
+				if value, ok := values[key]; ok {
+					byYear[year][key] = append(byYear[year][key], value)
+				}
+			}
+		}
+	}
+
+	var yearsSorted []string
+	for year := range byYear {
+		yearsSorted = append(yearsSorted, year)
+	}
+	sort.Strings(yearsSorted)
+	if len(yearsSorted) > years {
+		yearsSorted = yearsSorted[len(yearsSorted)-years:]
+	}
+	var trends []IndustryTrendYear
+	for _, year := range yearsSorted {
+		trend := IndustryTrendYear{Year: year, SampleSize: samples[year], Medians: make(map[string]float64)}
+		for key, values := range byYear[year] {
+			trend.Medians[key] = tools.MedianOf(values)
+		}
+		trends = append(trends, trend)
+	}
+	return trends
+}
+
+// industryMetricLabel 行业报告中各指标的名称，包括只用于趋势的指标
+func industryMetricLabel(key string) string {
+	for _, m := range industryMetrics {
+		if m.Key == key {
+			return m.Label
+		}
+	}
+	if key == "net_margin" {
+		return "净利率"
+	}
+	return key
+}
+
+// industryValue 格式化指标值，缺失时为 n/a
+func industryValue(values map[string]float64, key string, percent bool) string {
+	value, ok := values[key]
+	if !ok {
+		return "n/a"
+	}
+	if percent {
+		return fmt.Sprintf("%.1f%%", value*100)
+	}
+	return fmt.Sprintf("%.1f", value)
+}
+
+// renderIndustryDataTables 偏好排序、指标对比和行业趋势表，写入报告并提供给模型
+func renderIndustryDataTables(report *IndustryReport, format tools.NumberFormat) string {
+	var sb strings.Builder
+	sb.WriteString("## 偏好排序\n\n")
+	sb.WriteString("| 排名 | 股票 | 公司 | 市值 | 综合得分 | 近一年涨跌 |\n")
+	sb.WriteString("|---|---|---|---|---|---|\n")
+	for _, c := range report.Companies {
+		rank := "—"
+		if c.Rank > 0 {
+			rank = fmt.Sprintf("%d", c.Rank)
+		}
+		marketCap := "n/a"
+		if c.MarketCap > 0 {
+			currency := c.Currency
+			if currency == "" {
+				currency = "USD"
+			}
+			marketCap = format.MoneyCompact(c.MarketCap, currency)
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s | %s |\n",
+			rank, c.Symbol, orNA(c.Name), marketCap, c.Score.Sprintf("%.0f"), (c.OneYearReturn * 100).Sprintf("%+.1f%%")))
+	}
+	var labels []string
+	for _, m := range industryMetrics {
+		labels = append(labels, m.Label)
+	}
+	sb.WriteString(fmt.Sprintf("\n综合得分为%s在行业内百分位（最好为 100）的平均值，负债权益比和市盈率越低越好；有效指标不足一半的公司不参与排序（—）。\n", strings.Join(labels, "、")))
+
+	sb.WriteString("\n## 关键指标对比（TTM）\n\n| 股票 |")
+	for _, m := range industryMetrics {
+		sb.WriteString(" " + m.Label + " |")
+	}
+	sb.WriteString("\n|---|" + strings.Repeat("---|", len(industryMetrics)) + "\n")
+	for _, c := range report.Companies {
+		sb.WriteString("| " + c.Symbol + " |")
+		for _, m := range industryMetrics {
+			sb.WriteString(" " + industryValue(c.Metrics, m.Key, m.Percent) + " |")
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("| **行业中位数** |")
+	for _, m := range industryMetrics {
+		sb.WriteString(" " + industryValue(report.Medians, m.Key, m.Percent) + " |")
+	}
+	sb.WriteString("\n")
+
+	if len(report.Trends) > 0 {
+		sb.WriteString("\n## 行业趋势（年度中位数）\n\n| 财年 | 样本数 |")
+		for _, key := range industryTrendKeys {
+			sb.WriteString(" " + industryMetricLabel(key) + " |")
+		}
+		sb.WriteString("\n|---|---|" + strings.Repeat("---|", len(industryTrendKeys)) + "\n")
+		for _, t := range report.Trends {
+			sb.WriteString(fmt.Sprintf("| %s | %d |", t.Year, t.SampleSize))
+			for _, key := range industryTrendKeys {
+				sb.WriteString(" " + industryValue(t.Medians, key, true) + " |")
+			}
+			sb.WriteString("\n")
+		}
+	}
+
+	var incomplete []string
+	for _, c := range report.Companies {
+		if c.Error != "" {
+			incomplete = append(incomplete, fmt.Sprintf("- %s: %s", c.Symbol, c.Error))
+		}
+	}
+	if len(incomplete) > 0 {
+		sb.WriteString("\n**数据不完整**:\n\n" + strings.Join(incomplete, "\n") + "\n")
+	}
+	return sb.String()
+}
+
+// industryCommentaryPrompt 撰写行业分析的提示词，排序由程序计算，模型只解释不改动
+const industryCommentaryPrompt = `你是一名专业的行业研究分析师。以下是「%s」行业 %d 家公司的数据，偏好排序已由程序根据指标百分位计算。
+
+%s
+
+请基于以上数据用中文撰写行业分析，包含以下章节（使用 ### 三级标题）：
+### 行业概览
+### 行业趋势
+### 竞争格局
+### 偏好排序理由
+### 主要风险
+
+要求：
+- 只使用上面提供的数据，不要编造数字；数据缺失时明确说明
+- 「偏好排序理由」按表中的排名顺序逐一说明每家公司的优势和不足，不要改变排名
+- 不要重复输出数据表`
+
+// writeIndustryCommentary 请模型根据数据表撰写行业分析
+func writeIndustryCommentary(ctx context.Context, chatModel model.BaseChatModel, report *IndustryReport, format tools.NumberFormat) (string, error) {
+	prompt := fmt.Sprintf(industryCommentaryPrompt, report.Industry, len(report.Companies), renderIndustryDataTables(report, format))
+	resp, err := chatModel.Generate(ctx, []*schema.Message{schema.UserMessage(prompt)})
+	if err != nil {
+		return "", fmt.Errorf("调用模型失败: %w", err)
+	}
+	return strings.TrimSpace(resp.Content), nil
+}
+
+// renderIndustryReport 生成行业报告的 markdown
+func renderIndustryReport(report *IndustryReport, format tools.NumberFormat) string {
+	var sb strings.Builder
+	var symbols []string
+	for _, c := range report.Companies {
+		symbols = append(symbols, c.Symbol)
+	}
+	sb.WriteString(fmt.Sprintf("# %s 行业报告\n\n生成时间: %s\n\n覆盖公司: %s\n\n",
+		report.Industry, report.GeneratedAt.Format("2006-01-02 15:04:05"), strings.Join(symbols, "、")))
+	sb.WriteString(renderIndustryDataTables(report, format))
+	if report.Commentary != "" {
+		sb.WriteString("\n## 行业分析\n\n" + report.Commentary + "\n")
+	}
+	sb.WriteString("\n---\n\n> 偏好排序只基于上表中的财务和估值指标，不构成投资建议；单只股票的深入分析请使用单独的分析报告。\n")
+	return sb.String()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"investment/tools"
+)
+
+// 配额名称中不属于工具配额的部分，工具配额见 tools.Quota*
+const (
+	QuotaTokens   = "tokens"
+	QuotaWallTime = "wall_time"
+)
+
+// jobQuota 服务模式下每个分析任务的资源配额，为 0 的项不限制；任务的最长运行时间由 serve --timeout（ANALYSIS_TIMEOUT）限制
+// JOB_MAX_TOOL_CALLS: 工具调用次数；JOB_MAX_TOOL_BYTES: 工具调用期间从数据源获取的响应累计字节数；
+// JOB_MAX_RESULT_BYTES: 单次工具结果的字节数；JOB_MAX_TOKENS: 模型 token 用量（输入加输出，LLM 缓存命中不计）
+type jobQuota struct {
+	MaxToolCalls   int
+	MaxToolBytes   int
+	MaxResultBytes int
+	MaxTokens      int
+}
+
+// jobQuotaFromEnv 读取任务配额，未设置的项不限制
+func jobQuotaFromEnv() (jobQuota, error) {
+	var quota jobQuota
+	for _, item := range []struct {
+		name  string
+		value *int
+	}{
+		{"JOB_MAX_TOOL_CALLS", &quota.MaxToolCalls},
+		{"JOB_MAX_TOOL_BYTES", &quota.MaxToolBytes},
+		{"JOB_MAX_RESULT_BYTES", &quota.MaxResultBytes},
+		{"JOB_MAX_TOKENS", &quota.MaxTokens},
+	} {
+		n, err := positiveIntEnv(item.name, 0)
+		if err != nil {
+			return jobQuota{}, err
+		}
+		*item.value = n
+	}
+	return quota, nil
+}
+
+// jobQuotaProblems 检查任务配额和同时运行的任务数上限
+func jobQuotaProblems() []string {
+	var problems []string
+	if _, err := jobQuotaFromEnv(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if _, err := positiveIntEnv("JOB_MAX_RUNNING", 0); err != nil {
+		problems = append(problems, err.Error())
+	}
+	return problems
+}
+
+// toolQuota 本任务的工具配额，没有工具相关的限制时为 nil
+func (q jobQuota) toolQuota() *tools.ToolQuota {
+	if q.MaxToolCalls == 0 && q.MaxToolBytes == 0 && q.MaxResultBytes == 0 {
+		return nil
+	}
+	return &tools.ToolQuota{MaxCalls: q.MaxToolCalls, MaxBytes: int64(q.MaxToolBytes), MaxResultBytes: q.MaxResultBytes}
+}
+
+// jobQuotaError 任务的模型 token 用量达到配额，作为分析 context 的取消原因，分析按超时一样输出部分报告
+type jobQuotaError struct {
+	Used  int
+	Limit int
+}
+
+func (e *jobQuotaError) Error() string {
+	return fmt.Sprintf("任务的模型 token 用量达到配额上限（已用 %d，上限 %d）", e.Used, e.Limit)
+}
+
+// jobQuotaStatus 任务的配额和当前用量，随任务进度更新
+type jobQuotaStatus struct {
+	MaxToolCalls   int      `json:"max_tool_calls,omitempty" description:"工具调用次数上限，未设置时不限制"`
+	MaxToolBytes   int      `json:"max_tool_bytes,omitempty" description:"工具从数据源获取的响应累计字节数上限"`
+	MaxResultBytes int      `json:"max_result_bytes,omitempty" description:"单次工具结果的字节数上限"`
+	MaxTokens      int      `json:"max_tokens,omitempty" description:"模型 token 用量上限"`
+	MaxWallTime    string   `json:"max_wall_time,omitempty" description:"任务的最长运行时间"`
+	ToolCalls      int      `json:"tool_calls" description:"已执行的工具调用次数（不含检查点回放）"`
+	ToolBytes      int64    `json:"tool_bytes" description:"工具从数据源获取的响应累计字节数（包括响应缓存命中）"`
+	Tokens         int      `json:"tokens" description:"模型 token 用量（输入加输出）"`
+	Exceeded       []string `json:"exceeded,omitempty" description:"达到上限的配额：tool_calls、tool_bytes、result_bytes、tokens、wall_time；工具配额用完后 Agent 以已获取的数据完成报告，tokens 和 wall_time 达到上限时输出部分报告"`
+}
+
+// status 按当前用量生成任务的配额状态，wallTime 为 0 时不限制运行时间；没有任何限制时返回 nil
+func (q jobQuota) status(tool *tools.ToolQuota, usage ModelUsage, wallTime time.Duration) *jobQuotaStatus {
+	if q == (jobQuota{}) && wallTime == 0 {
+		return nil
+	}
+	toolUsage := tool.Usage()
+	status := &jobQuotaStatus{
+		MaxToolCalls:   q.MaxToolCalls,
+		MaxToolBytes:   q.MaxToolBytes,
+		MaxResultBytes: q.MaxResultBytes,
+		MaxTokens:      q.MaxTokens,
+		ToolCalls:      toolUsage.Calls,
+		ToolBytes:      toolUsage.Bytes,
+		Tokens:         usage.PromptTokens + usage.CompletionTokens,
+		Exceeded:       toolUsage.Exceeded,
+	}
+	if wallTime > 0 {
+		status.MaxWallTime = wallTime.String()
+	}
+	if q.MaxTokens > 0 && status.Tokens >= q.MaxTokens {
+		status.Exceeded = append(status.Exceeded, QuotaTokens)
+	}
+	return status
+}
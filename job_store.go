@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"investment/tools"
+)
+
+// jobsDir 服务模式任务及其检查点的保存目录，服务重启后从这里恢复未完成的任务
+// 始终保存在本地磁盘，不经过 OUTPUT_SINK
+var jobsDir = filepath.Join("output", "jobs")
+
+// jobRequest 创建任务时的请求参数，恢复任务时用于重新发起分析
+type jobRequest struct {
+	Portfolio  string   `json:"portfolio,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	Transcript string   `json:"transcript"`
+}
+
+// jobCheckpoint 任务的进度检查点
+type jobCheckpoint struct {
+	ToolCalls []tools.CompletedToolCall `json:"tool_calls,omitempty"` // 已成功完成的工具调用及结果
+	Sections  []string                  `json:"sections,omitempty"`   // 报告中已输出完成的章节
+}
+
+// jobFile 任务文件的内容
+type jobFile struct {
+	Job        *analysisJob  `json:"job"`
+	Request    jobRequest    `json:"request"`
+	Checkpoint jobCheckpoint `json:"checkpoint"`
+}
+
+// saveJob 将任务、请求参数和检查点写入 jobsDir/<id>.json，先写临时文件再重命名，服务中途退出不会留下不完整的文件
+// 调用方持有 analysisServer.mu
+func saveJob(job *analysisJob) error {
+	data, err := json.MarshalIndent(jobFile{Job: job, Request: job.request, Checkpoint: job.checkpoint}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSON序列化失败: %v", err)
+	}
+	if err := os.MkdirAll(jobsDir, 0755); err != nil {
+		return fmt.Errorf("创建任务目录失败: %v", err)
+	}
+	path := filepath.Join(jobsDir, job.ID+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("写入任务文件失败: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("写入任务文件失败: %v", err)
+	}
+	return nil
+}
+
+// loadJobs 读取 jobsDir 中保存的所有任务，目录不存在时返回空
+func loadJobs() ([]*analysisJob, error) {
+	entries, err := os.ReadDir(jobsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取任务目录失败: %v", err)
+	}
+
+	var jobs []*analysisJob
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(jobsDir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("读取任务文件失败: %v", err)
+		}
+		var file jobFile
+		if err := json.Unmarshal(data, &file); err != nil || file.Job == nil {
+			return nil, fmt.Errorf("解析任务文件 %s 失败: %v", entry.Name(), err)
+		}
+		file.Job.request = file.Request
+		file.Job.checkpoint = file.Checkpoint
+		jobs = append(jobs, file.Job)
+	}
+	return jobs, nil
+}
+
+// jobNumber 任务 ID job-N 中的序号，格式不符时为 0
+func jobNumber(id string) int {
+	n, err := strconv.Atoi(strings.TrimPrefix(id, "job-"))
+	if err != nil {
+		return 0
+	}
+	return n
+}
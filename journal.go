@@ -0,0 +1,364 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// journalDir 交易想法日志的保存目录，每只股票一个文件，始终在本地磁盘，不经过 OUTPUT_SINK
+var journalDir = filepath.Join("output", "journal")
+
+// journalPromptEntries 重新分析时注入提示词的最近日志条数
+const journalPromptEntries = 3
+
+// 日志中的投资决策
+const (
+	DecisionBuy   = "buy"   // 建仓
+	DecisionAdd   = "add"   // 加仓
+	DecisionHold  = "hold"  // 继续持有
+	DecisionTrim  = "trim"  // 减仓
+	DecisionSell  = "sell"  // 清仓
+	DecisionWatch = "watch" // 观察，暂不操作
+	DecisionPass  = "pass"  // 放弃
+)
+
+// journalDecisions 支持的决策及中文名称，顺序用于用法说明
+var journalDecisions = []struct{ Name, Label string }{
+	{DecisionBuy, "建仓"},
+	{DecisionAdd, "加仓"},
+	{DecisionHold, "持有"},
+	{DecisionTrim, "减仓"},
+	{DecisionSell, "清仓"},
+	{DecisionWatch, "观察"},
+	{DecisionPass, "放弃"},
+}
+
+// decisionLabel 决策的中文名称，未知决策原样返回
+func decisionLabel(decision string) string {
+	for _, d := range journalDecisions {
+		if d.Name == decision {
+			return d.Label
+		}
+	}
+	return decision
+}
+
+// JournalEntry 一次投资决策的记录：用户的决策和理由，以及做决策时参考的分析
+type JournalEntry struct {
+	Date     time.Time `json:"date"`
+	Symbol   string    `json:"symbol"`
+	Decision string    `json:"decision"`
+	Rating   string    `json:"rating,omitempty"` // 参考的分析报告的评级
+	RunID    string    `json:"run_id,omitempty"` // 参考的分析运行
+	Price    *float64  `json:"price,omitempty"`  // 做决策时的价格
+	Thesis   string    `json:"thesis"`           // 投资逻辑和备注
+	Tags     []string  `json:"tags,omitempty"`
+}
+
+// TradeJournal 一只股票的决策记录，按时间先后排列
+type TradeJournal struct {
+	Symbol  string         `json:"symbol"`
+	Entries []JournalEntry `json:"entries"`
+}
+
+// journalPath 股票日志文件的路径
+func journalPath(symbol string) string {
+	return filepath.Join(journalDir, strings.ToUpper(symbol)+".json")
+}
+
+// loadTradeJournal 读取股票的决策记录，代码变更前旧代码下的记录一并读取，没有记录时返回 nil
+func loadTradeJournal(symbol string) (*TradeJournal, error) {
+	journal := &TradeJournal{Symbol: canonicalSymbol(symbol)}
+	for _, alias := range symbolHistory().Aliases(symbol) {
+		stored, err := readJournalFile(alias)
+		if err != nil {
+			return nil, err
+		}
+		journal.Entries = append(journal.Entries, stored.Entries...)
+	}
+	if len(journal.Entries) == 0 {
+		return nil, nil
+	}
+	sort.SliceStable(journal.Entries, func(i, j int) bool {
+		return journal.Entries[i].Date.Before(journal.Entries[j].Date)
+	})
+	return journal, nil
+}
+
+// readJournalFile 读取一个代码下保存的日志文件，文件不存在时返回空日志
+func readJournalFile(symbol string) (*TradeJournal, error) {
+	journal := &TradeJournal{Symbol: strings.ToUpper(symbol)}
+	data, err := os.ReadFile(journalPath(symbol))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return journal, nil
+		}
+		return nil, fmt.Errorf("读取交易日志失败: %v", err)
+	}
+	if err := json.Unmarshal(data, journal); err != nil {
+		return nil, fmt.Errorf("解析交易日志 %s 失败: %v", journalPath(symbol), err)
+	}
+	return journal, nil
+}
+
+// appendJournalEntry 把一条决策追加到当前代码的日志文件，旧代码下的记录保留在原文件中
+func appendJournalEntry(entry JournalEntry) error {
+	journal, err := readJournalFile(entry.Symbol)
+	if err != nil {
+		return err
+	}
+	journal.Entries = append(journal.Entries, entry)
+
+	data, err := json.MarshalIndent(journal, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSON序列化失败: %v", err)
+	}
+	if err := os.MkdirAll(journalDir, 0755); err != nil {
+		return fmt.Errorf("创建交易日志目录失败: %v", err)
+	}
+	if err := os.WriteFile(journalPath(entry.Symbol), data, 0644); err != nil {
+		return fmt.Errorf("写入交易日志失败: %v", err)
+	}
+	return nil
+}
+
+// latest 最近一条决策记录
+func (j *TradeJournal) latest() JournalEntry {
+	return j.Entries[len(j.Entries)-1]
+}
+
+// promptContext 追加到系统提示词的用户投资逻辑：最近几次决策和理由，要求 Agent 逐条检验是否仍然成立
+func (j *TradeJournal) promptContext() string {
+	entries := j.Entries
+	if len(entries) > journalPromptEntries {
+		entries = entries[len(entries)-journalPromptEntries:]
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "## 用户的投资日志（%s）\n\n", j.Symbol)
+	sb.WriteString("用户此前对该股票的决策和投资逻辑（由新到旧）：\n")
+	for i := len(entries) - 1; i >= 0; i-- {
+		fmt.Fprintf(&sb, "\n- %s\n", entries[i].describe())
+	}
+	sb.WriteString("\n请在报告中用「与你之前的投资逻辑对照」一节逐条检验最近一次决策的理由是否仍然成立、哪些已被证实或证伪，" +
+		"并说明本次评级是否支持用户继续执行该决策。不要因为用户的观点而改变基于数据的判断。")
+	return sb.String()
+}
+
+// adjustedPrice 决策时的价格换算到当前股本（之后发生过拆分时）
+func (e JournalEntry) adjustedPrice() *float64 {
+	return adjustForSplits(e.Symbol, e.Date, e.Price)
+}
+
+// describe 一条决策的单行描述：日期、决策、价格、参考评级和投资逻辑
+func (e JournalEntry) describe() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s", e.Date.Format("2006-01-02"), decisionLabel(e.Decision))
+	if price := e.adjustedPrice(); price != nil {
+		fmt.Fprintf(&sb, "（价格 %.2f）", *price)
+	}
+	if e.Rating != "" {
+		fmt.Fprintf(&sb, "，参考评级「%s」", e.Rating)
+	}
+	if e.Thesis != "" {
+		fmt.Fprintf(&sb, "：%s", e.Thesis)
+	}
+	return sb.String()
+}
+
+// renderPreviousThesis 报告末尾的「你之前的投资逻辑」：最近一次决策原文，便于和本次结论对照
+func (j *TradeJournal) renderPreviousThesis() string {
+	e := j.latest()
+	var sb strings.Builder
+	sb.WriteString("\n\n## 你之前的投资逻辑\n\n")
+	fmt.Fprintf(&sb, "- 日期：%s\n", e.Date.Format("2006-01-02"))
+	fmt.Fprintf(&sb, "- 决策：%s\n", decisionLabel(e.Decision))
+	if price := e.adjustedPrice(); price != nil {
+		fmt.Fprintf(&sb, "- 决策时价格：%.2f\n", *price)
+	}
+	if e.Rating != "" {
+		fmt.Fprintf(&sb, "- 当时参考的评级：%s\n", e.Rating)
+	}
+	if e.Thesis != "" {
+		fmt.Fprintf(&sb, "\n> %s\n", strings.ReplaceAll(strings.TrimSpace(e.Thesis), "\n", "\n> "))
+	}
+	if n := len(j.Entries); n > 1 {
+		fmt.Fprintf(&sb, "\n共 %d 条决策记录，完整记录见 `%s`。\n", n, journalPath(j.Symbol))
+	}
+	return sb.String()
+}
+
+// runJournal 处理 journal 子命令
+// journal add <SYMBOL> --decision buy --note "..." [--run id] [--price 0] [--tag a,b]：记录一次决策，评级默认取该股票最近一次分析
+// journal list [--symbol AAPL] [--decision buy]：按时间倒序列出决策记录
+// journal show <SYMBOL>：显示一只股票的全部决策记录
+func runJournal(args []string) error {
+	usage := fmt.Errorf("用法: journal add <stock_symbol> --decision <%s> --note \"投资逻辑\" [--run id] [--price 0] [--tag a,b] | journal list [--symbol AAPL] [--decision buy] | journal show <stock_symbol>", strings.Join(decisionNames(), "|"))
+	if len(args) == 0 {
+		return usage
+	}
+
+	switch args[0] {
+	case "add":
+		if len(args) < 2 || strings.HasPrefix(args[1], "-") {
+			return fmt.Errorf("请指定股票代码")
+		}
+		fs := flag.NewFlagSet("journal add", flag.ExitOnError)
+		decision := fs.String("decision", "", "投资决策: "+strings.Join(decisionNames(), ", "))
+		note := fs.String("note", "", "投资逻辑和备注")
+		runID := fs.String("run", "", "参考的分析运行 ID（默认该股票最近一次分析）")
+		price := fs.Float64("price", 0, "决策时的价格（0 表示不记录）")
+		tag := fs.String("tag", "", "标签，多个标签用逗号分隔")
+		if err := fs.Parse(args[2:]); err != nil {
+			return err
+		}
+		entry := JournalEntry{
+			Date:     time.Now(),
+			Symbol:   canonicalSymbol(args[1]),
+			Decision: *decision,
+			Thesis:   strings.TrimSpace(*note),
+			Tags:     parseTags(*tag),
+		}
+		if *price > 0 {
+			entry.Price = price
+		}
+		return addJournalEntry(entry, *runID)
+
+	case "list":
+		fs := flag.NewFlagSet("journal list", flag.ExitOnError)
+		symbol := fs.String("symbol", "", "按股票代码筛选")
+		decision := fs.String("decision", "", "按决策筛选")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		return listJournal(*symbol, *decision)
+
+	case "show":
+		if len(args) < 2 {
+			return fmt.Errorf("请指定股票代码")
+		}
+		journal, err := loadTradeJournal(args[1])
+		if err != nil {
+			return err
+		}
+		if journal == nil {
+			fmt.Printf("%s 没有决策记录\n", strings.ToUpper(args[1]))
+			return nil
+		}
+		fmt.Printf("=== %s 交易日志 ===\n", journal.Symbol)
+		for _, e := range journal.Entries {
+			fmt.Printf("- %s\n", e.describe())
+			if e.RunID != "" {
+				fmt.Printf("  参考分析: %s\n", e.RunID)
+			}
+		}
+		return nil
+	}
+	return usage
+}
+
+// decisionNames 支持的决策名称
+func decisionNames() []string {
+	names := make([]string, len(journalDecisions))
+	for i, d := range journalDecisions {
+		names[i] = d.Name
+	}
+	return names
+}
+
+// addJournalEntry 校验并保存一条决策，参考评级取自指定的分析运行，未指定时取该股票最近一次分析
+func addJournalEntry(entry JournalEntry, runID string) error {
+	if decisionLabel(entry.Decision) == entry.Decision {
+		return fmt.Errorf("无效的决策 %q，可选: %s", entry.Decision, strings.Join(decisionNames(), ", "))
+	}
+	if entry.Thesis == "" {
+		return fmt.Errorf("请用 --note 写下本次决策的投资逻辑")
+	}
+
+	records, err := listRunRecords(runFilter{Symbol: entry.Symbol})
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		if runID == "" || r.ID == runID {
+			entry.RunID = r.ID
+			entry.Rating = r.Rating
+			break
+		}
+	}
+	if runID != "" && entry.RunID == "" {
+		return fmt.Errorf("没有找到 %s 的分析运行 %s", entry.Symbol, runID)
+	}
+
+	if err := appendJournalEntry(entry); err != nil {
+		return err
+	}
+	fmt.Printf("📓 已记录: %s %s\n", entry.Symbol, entry.describe())
+	return nil
+}
+
+// listJournal 按时间倒序列出所有股票满足条件的决策记录
+func listJournal(symbol, decision string) error {
+	var entries []JournalEntry
+	if symbol != "" {
+		journal, err := loadTradeJournal(symbol)
+		if err != nil {
+			return err
+		}
+		if journal != nil {
+			entries = journal.Entries
+		}
+	} else {
+		files, err := os.ReadDir(journalDir)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("读取交易日志目录失败: %v", err)
+		}
+		// 旧代码的文件和当前代码的文件合并读取，每家公司只读一次
+		seen := make(map[string]bool)
+		for _, file := range files {
+			if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+				continue
+			}
+			current := canonicalSymbol(strings.TrimSuffix(file.Name(), ".json"))
+			if seen[current] {
+				continue
+			}
+			seen[current] = true
+			journal, err := loadTradeJournal(current)
+			if err != nil {
+				return err
+			}
+			if journal != nil {
+				entries = append(entries, journal.Entries...)
+			}
+		}
+	}
+
+	var matched []JournalEntry
+	for _, e := range entries {
+		if decision == "" || e.Decision == decision {
+			matched = append(matched, e)
+		}
+	}
+	if len(matched) == 0 {
+		fmt.Println("没有符合条件的决策记录")
+		return nil
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].Date.After(matched[j].Date)
+	})
+	for _, e := range matched {
+		tags := strings.Join(e.Tags, ",")
+		if tags == "" {
+			tags = "-"
+		}
+		fmt.Printf("%-6s  %s  标签=%s\n", e.Symbol, e.describe(), tags)
+	}
+	fmt.Printf("共 %d 条记录\n", len(matched))
+	return nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// knowledgeDir 每只股票历史分析结论的保存目录，始终在本地磁盘，不经过 OUTPUT_SINK
+var knowledgeDir = filepath.Join("output", "knowledge")
+
+const (
+	maxKnowledgeEntries  = 5   // 每只股票保留的最近分析次数
+	knowledgePromptRuns  = 3   // 注入提示词的最近分析次数
+	knowledgeSummaryRune = 200 // 注入提示词时结论摘要的最大字数
+	knowledgeListItems   = 3   // 注入提示词时每类要点的最大条数
+)
+
+// KnowledgeEntry 一次分析的要点，来自结构化结论
+type KnowledgeEntry struct {
+	RunID         string    `json:"run_id"`
+	Date          time.Time `json:"date"`
+	Rating        string    `json:"rating"`
+	Summary       string    `json:"summary"`
+	FairValueP50  *float64  `json:"fair_value_p50,omitempty"`
+	Strengths     []string  `json:"strengths,omitempty"`
+	Risks         []string  `json:"risks,omitempty"`
+	OpenQuestions []string  `json:"open_questions,omitempty"` // 报告中说明缺失或待核实的数据
+}
+
+// CompanyKnowledge 一只股票的历史分析要点，按时间先后排列
+type CompanyKnowledge struct {
+	Symbol  string           `json:"symbol"`
+	Entries []KnowledgeEntry `json:"entries"`
+}
+
+// knowledgeBaseEnabled KNOWLEDGE_BASE=false 时不注入历史结论，也不记录新的结论
+func knowledgeBaseEnabled() bool {
+	return os.Getenv("KNOWLEDGE_BASE") != "false"
+}
+
+// knowledgePath 股票知识文件的路径
+func knowledgePath(symbol string) string {
+	return filepath.Join(knowledgeDir, strings.ToUpper(symbol)+".json")
+}
+
+// loadCompanyKnowledge 读取股票的历史分析要点，代码变更前旧代码下的记录一并读取（按运行 ID 去重），没有记录时返回 nil
+func loadCompanyKnowledge(symbol string) (*CompanyKnowledge, error) {
+	knowledge := &CompanyKnowledge{Symbol: canonicalSymbol(symbol)}
+	seen := make(map[string]bool)
+	for _, alias := range symbolHistory().Aliases(symbol) {
+		data, err := os.ReadFile(knowledgePath(alias))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("读取知识库失败: %v", err)
+		}
+		var stored CompanyKnowledge
+		if err := json.Unmarshal(data, &stored); err != nil {
+			return nil, fmt.Errorf("解析知识库 %s 失败: %v", knowledgePath(alias), err)
+		}
+		for _, e := range stored.Entries {
+			if !seen[e.RunID] {
+				seen[e.RunID] = true
+				knowledge.Entries = append(knowledge.Entries, e)
+			}
+		}
+	}
+	if len(knowledge.Entries) == 0 {
+		return nil, nil
+	}
+	sort.SliceStable(knowledge.Entries, func(i, j int) bool {
+		return knowledge.Entries[i].Date.Before(knowledge.Entries[j].Date)
+	})
+	return knowledge, nil
+}
+
+// recordKnowledge 把一次分析的结构化结论追加到股票的知识库，只保留最近 maxKnowledgeEntries 次
+func recordKnowledge(run *RunRecord, structured *StructuredReport) error {
+	knowledge, err := loadCompanyKnowledge(run.Symbol)
+	if err != nil {
+		return err
+	}
+	if knowledge == nil {
+		knowledge = &CompanyKnowledge{Symbol: canonicalSymbol(run.Symbol)}
+	}
+	knowledge.Entries = append(knowledge.Entries, KnowledgeEntry{
+		RunID:         run.ID,
+		Date:          run.FinishedAt,
+		Rating:        structured.Rating,
+		Summary:       structured.Summary,
+		FairValueP50:  structured.FairValueP50,
+		Strengths:     structured.Strengths,
+		Risks:         structured.Risks,
+		OpenQuestions: structured.DataGaps,
+	})
+	if n := len(knowledge.Entries); n > maxKnowledgeEntries {
+		knowledge.Entries = knowledge.Entries[n-maxKnowledgeEntries:]
+	}
+
+	data, err := json.MarshalIndent(knowledge, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSON序列化失败: %v", err)
+	}
+	if err := os.MkdirAll(knowledgeDir, 0755); err != nil {
+		return fmt.Errorf("创建知识库目录失败: %v", err)
+	}
+	if err := os.WriteFile(knowledgePath(knowledge.Symbol), data, 0644); err != nil {
+		return fmt.Errorf("写入知识库失败: %v", err)
+	}
+	return nil
+}
+
+// promptContext 追加到系统提示词的历史分析摘要：最近几次的评级、结论、主要风险和待解决问题，
+// 要求 Agent 核实这些问题的进展，而不是沿用以前的结论
+func (k *CompanyKnowledge) promptContext() string {
+	entries := k.Entries
+	if len(entries) > knowledgePromptRuns {
+		entries = entries[len(entries)-knowledgePromptRuns:]
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "## 以往分析要点（%s）\n\n", k.Symbol)
+	sb.WriteString("以下是对该股票最近几次分析的要点（由新到旧），供本次分析参考：\n")
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		fmt.Fprintf(&sb, "\n- %s，评级「%s」", e.Date.Format("2006-01-02"), e.Rating)
+		// 估值按记录时的股本保存，之后发生拆分时换算到当前股本
+		if p50 := adjustForSplits(k.Symbol, e.Date, e.FairValueP50); p50 != nil {
+			fmt.Fprintf(&sb, "，估值中位数 %.2f", *p50)
+		}
+		fmt.Fprintf(&sb, "：%s\n", truncateRunes(e.Summary, knowledgeSummaryRune))
+		if len(e.Risks) > 0 {
+			fmt.Fprintf(&sb, "  - 关注的风险：%s\n", strings.Join(firstN(e.Risks, knowledgeListItems), "；"))
+		}
+		if len(e.OpenQuestions) > 0 {
+			fmt.Fprintf(&sb, "  - 待解决的问题：%s\n", strings.Join(firstN(e.OpenQuestions, knowledgeListItems), "；"))
+		}
+	}
+	sb.WriteString("\n请在本次分析中核实上述风险和待解决问题是否已有变化（例如上次提示存货上升，本次确认是否已缓解），" +
+		"并在报告中用「与以往分析相比」一节说明评级和主要判断的变化及原因。以往结论只作为线索，所有数字和判断以本次工具返回的数据为准。")
+	return sb.String()
+}
+
+// truncateRunes 超过 n 个字符时截断并加省略号
+func truncateRunes(s string, n int) string {
+	r := []rune(strings.TrimSpace(s))
+	if len(r) <= n {
+		return string(r)
+	}
+	return string(r[:n]) + "…"
+}
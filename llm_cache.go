@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// llmCacheDir 模型响应的磁盘缓存目录
+var llmCacheDir = filepath.Join("output", "cache", "llm")
+
+// llmCacheEntry 一条缓存的模型响应
+type llmCacheEntry struct {
+	Model    string          `json:"model"`
+	Response *schema.Message `json:"response"`
+	StoredAt time.Time       `json:"stored_at"`
+}
+
+// llmCacheKey 参与计算缓存键的请求内容
+type llmCacheKey struct {
+	Model    string            `json:"model"`
+	Messages []*schema.Message `json:"messages"`
+	Tools    []llmCacheTool    `json:"tools,omitempty"`
+}
+
+// llmCacheTool 绑定工具的名称、描述和参数定义
+type llmCacheTool struct {
+	Name   string `json:"name"`
+	Desc   string `json:"desc"`
+	Params any    `json:"params,omitempty"`
+}
+
+// LLMCacheStats 模型响应缓存的命中次数
+type LLMCacheStats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
+
+// llmCache 模型响应的磁盘缓存，键为模型、完整消息列表和绑定工具的哈希
+// 提示词和工具结果完全相同时直接返回上次的响应（如修复报告渲染问题后重跑同一分析），不再调用模型
+type llmCache struct {
+	dir    string
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// llmCacheEnabled LLM_CACHE=true 时启用模型响应缓存，默认关闭
+func llmCacheEnabled() bool {
+	return os.Getenv("LLM_CACHE") == "true"
+}
+
+// Stats 返回命中统计
+func (c *llmCache) Stats() LLMCacheStats {
+	return LLMCacheStats{Hits: c.hits.Load(), Misses: c.misses.Load()}
+}
+
+// key 计算请求的缓存键
+func (c *llmCache) key(modelID string, messages []*schema.Message, tools []*schema.ToolInfo) (string, error) {
+	k := llmCacheKey{Model: modelID, Messages: messages}
+	for _, tool := range tools {
+		entry := llmCacheTool{Name: tool.Name, Desc: tool.Desc}
+		if tool.ParamsOneOf != nil {
+			params, err := tool.ParamsOneOf.ToJSONSchema()
+			if err != nil {
+				return "", fmt.Errorf("读取工具 %s 的参数定义失败: %w", tool.Name, err)
+			}
+			entry.Params = params
+		}
+		k.Tools = append(k.Tools, entry)
+	}
+	data, err := json.Marshal(k)
+	if err != nil {
+		return "", fmt.Errorf("序列化模型请求失败: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (c *llmCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// get 读取缓存的响应，不存在或无法解析时返回 nil
+func (c *llmCache) get(key string) *schema.Message {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		c.misses.Add(1)
+		return nil
+	}
+	var entry llmCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Response == nil {
+		c.misses.Add(1)
+		return nil
+	}
+	c.hits.Add(1)
+	return entry.Response
+}
+
+// put 保存响应，写入失败只记录日志
+func (c *llmCache) put(key, modelID string, msg *schema.Message) {
+	data, err := json.MarshalIndent(llmCacheEntry{Model: modelID, Response: msg, StoredAt: time.Now()}, "", "  ")
+	if err != nil {
+		log.Printf("[LLMCache] 序列化模型响应失败: %v", err)
+		return
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		log.Printf("[LLMCache] 创建缓存目录失败: %v", err)
+		return
+	}
+	if err := os.WriteFile(c.path(key), data, 0644); err != nil {
+		log.Printf("[LLMCache] 写入缓存失败: %v", err)
+	}
+}
+
+// llmModelID 模型类型和模型名称，不同模型的响应分开缓存
+func llmModelID(modelType string) string {
+	if modelType == "" {
+		modelType = "deepseek"
+	}
+	return modelType + "/" + os.Getenv(strings.ToUpper(modelType)+"_MODEL_NAME")
+}
+
+// cachedChatModel 带响应缓存的 ToolCallingChatModel，命中时不调用底层模型
+type cachedChatModel struct {
+	inner   model.ToolCallingChatModel
+	cache   *llmCache
+	modelID string
+	tools   []*schema.ToolInfo
+}
+
+// withLLMCache 为模型加上响应缓存
+func withLLMCache(chatModel model.ToolCallingChatModel, modelType string) *cachedChatModel {
+	return &cachedChatModel{
+		inner:   chatModel,
+		cache:   &llmCache{dir: llmCacheDir},
+		modelID: llmModelID(modelType),
+	}
+}
+
+// WithTools 实现 model.ToolCallingChatModel，绑定的工具参与缓存键计算
+func (m *cachedChatModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	inner, err := m.inner.WithTools(tools)
+	if err != nil {
+		return nil, err
+	}
+	return &cachedChatModel{inner: inner, cache: m.cache, modelID: m.modelID, tools: tools}, nil
+}
+
+// Generate 实现 model.BaseChatModel
+func (m *cachedChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	key, err := m.cache.key(m.modelID, input, m.tools)
+	if err != nil {
+		log.Printf("[LLMCache] %v，本次不使用缓存", err)
+		return m.inner.Generate(ctx, input, opts...)
+	}
+	if msg := m.cache.get(key); msg != nil {
+		return msg, nil
+	}
+	msg, err := m.inner.Generate(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	m.cache.put(key, m.modelID, msg)
+	return msg, nil
+}
+
+// Stream 实现 model.BaseChatModel；命中时一次性返回缓存的完整消息，未命中时在流结束后保存拼接的响应
+func (m *cachedChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	key, err := m.cache.key(m.modelID, input, m.tools)
+	if err != nil {
+		log.Printf("[LLMCache] %v，本次不使用缓存", err)
+		return m.inner.Stream(ctx, input, opts...)
+	}
+	if msg := m.cache.get(key); msg != nil {
+		return schema.StreamReaderFromArray([]*schema.Message{msg}), nil
+	}
+	stream, err := m.inner.Stream(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	copies := stream.Copy(2)
+	go m.saveStream(key, copies[1])
+	return copies[0], nil
+}
+
+// saveStream 读完流后保存拼接的响应，流中途出错时不缓存
+func (m *cachedChatModel) saveStream(key string, stream *schema.StreamReader[*schema.Message]) {
+	defer stream.Close()
+	var chunks []*schema.Message
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return
+		}
+		chunks = append(chunks, chunk)
+	}
+	if len(chunks) == 0 {
+		return
+	}
+	msg, err := schema.ConcatMessages(chunks)
+	if err != nil {
+		log.Printf("[LLMCache] 拼接模型响应失败: %v", err)
+		return
+	}
+	m.cache.put(key, m.modelID, msg)
+}
@@ -3,11 +3,13 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
-	"path/filepath"
+	"path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -18,137 +20,521 @@ import (
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/flow/agent/react"
 	"github.com/cloudwego/eino/schema"
-	"github.com/joho/godotenv"
 )
 
 func main() {
-	// 检查命令行参数
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: investment_assistant <stock_symbol>")
-		fmt.Println("Example: investment_assistant AAPL")
-		fmt.Println("Example: investment_assistant TSLA")
+	analyzeDefaults = defaultAnalyzeFlags()
+	analyzeDefaults.register(flag.CommandLine)
+	envFile := flag.String("env-file", "", "配置文件路径，替代默认的 .env.local 和 .env（文件必须存在）")
+	configFile := flag.String("config", "", "YAML/TOML 分节配置文件路径，替代默认的 investment.yaml（文件必须存在，优先级低于 .env 文件和环境变量）")
+	flag.Usage = printUsage
+	flag.Parse()
+	args := flag.Args()
+	// 全局参数也可以写在子命令或股票代码之后，加载配置之前取出
+	if len(args) > 0 {
+		rest, err := extractGlobalFlags(flag.CommandLine, args[1:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		args = append(args[:1], rest...)
+	}
+
+	// 加载配置，优先级：命令行参数 > 环境变量 > 配置文件（.env.local > .env > investment.yaml） > 默认值；没有配置文件时只使用环境变量
+	if err := loadEnvConfig(*envFile, *configFile); err != nil {
+		log.Fatal(err)
+	}
+	if dir := os.Getenv("OUTPUT_DIR"); dir != "" {
+		setOutputDir(dir)
+	}
+	if err := configureTLS(); err != nil {
+		log.Fatal(err)
+	}
+	if err := applyEnvDefaults(flag.CommandLine, analyzeFlagEnv); err != nil {
+		log.Fatal(err)
+	}
+
+	// 报告、工具中间结果和运行记录统一写入 OUTPUT_SINK 指定的输出目标
+	sink, err := newOutputSinkFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	tools.SetOutputSink(sink)
+
+	// 检查命令行参数，只传入 --watchlist 时分析关注列表中的股票
+	if len(args) < 1 && analyzeDefaults.Watchlist != "" {
+		if err := runAnalyzeCommand(nil); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(args) < 1 {
+		flag.Usage()
 		os.Exit(1)
 	}
+	if command := findCommand(args[0]); command != nil {
+		if err := command.Run(args[1:]); err != nil {
+			log.Fatalf("%s: %v", command.Failure, err)
+		}
+		return
+	}
+
+	// 兼容直接传入股票代码的用法，等同于 analyze
+	if err := runAnalyzeCommand(args); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runAnalyze 用 React Agent 分析一只股票并保存报告；分析本身失败时只记录日志，配置错误时返回错误
+func runAnalyze(opts analyzeFlags, rawSymbol string) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+
+	prompts, err := loadPromptSet(promptsDir())
+	if err != nil {
+		return fmt.Errorf("加载提示词失败: %v", err)
+	}
 
-	// load env from .env file
-	err := godotenv.Load()
+	// 按股票代码后缀或 --market 选择市场配置，未设置的模型、报告单位和基准使用市场默认值
+	symbol := canonicalSymbol(rawSymbol)
+	if symbol != strings.ToUpper(rawSymbol) {
+		fmt.Printf("ℹ️ %s 已更名为 %s，按新代码分析\n", strings.ToUpper(rawSymbol), symbol)
+	}
+	profile, err := resolveMarket(symbol, opts.Market)
 	if err != nil {
-		log.Fatalf("Error loading .env file")
+		return err
+	}
+	profile.apply()
+
+	// 创建模型和 Agent 之前检查配置，一次列出所有问题
+	if err := validateAnalysisConfig(); err != nil {
+		return err
 	}
 
 	ctx := context.Background()
-	// 创建聊天模型 使用Gemini模型
-	modelType := os.Getenv("MODEL_TYPE")
-	var chatModel model.ToolCallingChatModel
-	switch modelType {
-	case "gemini":
-		chatModel = createGeminiChatModel(ctx)
-	case "openai":
-		chatModel = createOpenAIChatModel(ctx)
-	case "deepseek":
-		chatModel = createDeepseekChatModel(ctx)
-	default:
-		chatModel = createDeepseekChatModel(ctx)
-	}
-	log.Printf("Using model: %s", modelType)
-
-	symbol := strings.ToUpper(os.Args[1])
+	chatModel, modelType := createChatModel(ctx)
+	// LLM_CACHE=true 时复用提示词完全相同的模型响应，--no-llm-cache 跳过缓存
+	var llmCache *cachedChatModel
+	if llmCacheEnabled() && !opts.NoLLMCache {
+		llmCache = withLLMCache(chatModel, modelType)
+		chatModel = llmCache
+	}
+
 	fmt.Printf("=== 智能投资助手 - 股票分析：%s ===\n", symbol)
 	fmt.Printf("正在初始化 React Agent 并准备分析工具...\n")
 
-	// 使用 React Agent 进行分析
-	result, err := analyzeWithReactAgent(ctx, chatModel, symbol)
+	// 录制本次分析的全部数据源和模型请求，供 snapshot export 导出（SNAPSHOT_RECORD=false 关闭）
+	var recorder *snapshotRecorder
+	if os.Getenv("SNAPSHOT_RECORD") != "false" {
+		recorder = &snapshotRecorder{}
+		setActiveSnapshot(recorder)
+		defer setActiveSnapshot(nil)
+	}
+
+	// 配置 EVENT_BUS 时把分析结果和工具遥测发布到 Kafka / NATS
+	bus, err := newEventBusFromEnv()
+	if err != nil {
+		log.Printf("消息总线不可用，本次不发布分析结果: %v", err)
+	}
+	defer bus.Close()
+
+	_, err = runAnalysis(ctx, chatModel, analysisRequest{
+		Symbol:    symbol,
+		Portfolio: opts.Portfolio,
+		Tags:      parseTags(opts.Tag),
+		Timeout:   opts.Timeout,
+		WarmStart: true,
+		Options: analysisOptions{
+			ToolTimeout: opts.ToolTimeout,
+			Transcript:  opts.Transcript,
+			Stream:      opts.Stream,
+			ModelType:   modelType,
+			Prompts:     prompts,
+			Market:      profile.Name,
+			Depth:       opts.Depth,
+		},
+		Snapshot: recorder,
+		Bus:      bus,
+	})
+	if llmCache != nil {
+		stats := llmCache.cache.Stats()
+		log.Printf("模型响应缓存: 命中 %d，未命中 %d", stats.Hits, stats.Misses)
+	}
 	if err != nil {
 		log.Printf("投资分析失败: %v", err)
-		return
+		if errors.Is(err, tools.ErrUnauthorized) {
+			fmt.Println("❌ 数据源 API 密钥无效或无权限，请检查 FINANCIAL_DATASETS_API_KEY")
+		}
 	}
+	return nil
+}
 
-	// 输出分析结果
-	// fmt.Print("\n" + strings.Repeat("=", 50) + "\n")
-	// fmt.Printf("📊 投资分析报告\n")
-	// fmt.Print(strings.Repeat("=", 50) + "\n")
-	// fmt.Printf("%s\n", result)
-	fmt.Print(strings.Repeat("=", 50) + "\n")
-	fmt.Printf("✅ 分析完成\n")
+// sentimentRequestInterval 新闻情绪评分相邻两批模型请求的最小间隔
+const sentimentRequestInterval = 500 * time.Millisecond
 
-	// 保存分析结果为 markdown 文件
-	if err := saveReportAsMarkdown(symbol, result); err != nil {
-		log.Printf("保存报告失败: %v", err)
-		return
+// positiveIntEnv 读取正整数环境变量，未设置时返回默认值
+func positiveIntEnv(name string, fallback int) (int, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback, nil
 	}
-
-	fmt.Printf("📄 报告已保存为 markdown 文件: %s_report.md\n", symbol)
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("无效的 %s: %s", name, raw)
+	}
+	return n, nil
 }
 
-// 保存分析结果为 markdown 文件
-func saveReportAsMarkdown(symbol, result string) error {
-	// 生成文件名
-	outputDir := "output/report"
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("创建目录失败: %v", err)
+// getFilingSections 获取多个年度 SEC 文件中指定章节的文本，部分年度失败时返回已获取的章节
+func getFilingSections(ctx context.Context, symbol, filingType string, years []int, items []string) ([]tools.FilingSection, error) {
+	var sections []tools.FilingSection
+	var lastErr error
+	for _, year := range years {
+		resp, err := GetFilingItems(ctx, symbol, filingType, year, items)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		for _, item := range resp.Items {
+			sections = append(sections, tools.FilingSection{
+				FilingType: filingType,
+				Year:       year,
+				Item:       item.Number,
+				Name:       item.Name,
+				URL:        resp.URL,
+				Text:       item.Text,
+			})
+		}
 	}
-	filename := fmt.Sprintf("%s_report.md", symbol)
+	if len(sections) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return sections, nil
+}
 
+// reportName 报告在输出目标中的路径，portfolio 不为空时位于该组合的子目录下
+func reportName(symbol, portfolio string) string {
+	return path.Join("report", portfolio, fmt.Sprintf("%s_report.md", symbol))
+}
+
+// 保存分析结果为 markdown 报告，写入当前输出目标，返回写入位置
+func saveReportAsMarkdown(symbol, portfolio, result string) (string, error) {
 	// 构建完整的 markdown 内容
 	timestamp := fmt.Sprintf("分析时间: %s", time.Now().Format("2006-01-02 15:04:05"))
 	reportContent := fmt.Sprintf("# %s 投资分析报告\n\n%s\n\n%s", symbol, timestamp, result)
 
-	// 写入文件
-	filePath := filepath.Join(outputDir, filename)
-	if err := os.WriteFile(filePath, []byte(reportContent), 0644); err != nil {
-		return fmt.Errorf("写入文件失败: %v", err)
+	location, err := tools.Output().WriteReport(reportName(symbol, portfolio), []byte(reportContent))
+	if err != nil {
+		return "", fmt.Errorf("写入文件失败: %v", err)
 	}
-
-	return nil
+	return location, nil
 }
 
 // 使用 React Agent 进行分析
-func analyzeWithReactAgent(ctx context.Context, chatModel model.ToolCallingChatModel, symbol string) (string, error) {
+// ctx 到期时停止等待，返回带截断说明的部分报告
+func analyzeWithReactAgent(ctx context.Context, chatModel model.ToolCallingChatModel, symbol string, options analysisOptions) (*analysisResult, error) {
+	// 市场配置决定交易时段、报告单位和比较基准的默认值，未指定市场时按代码后缀识别
+	profile, err := resolveMarket(symbol, options.Market)
+	if err != nil {
+		return nil, err
+	}
+
 	fmt.Printf("🔧 创建投资分析工具集...\n")
 	// 创建工具集
 	var investmentTools []tool.BaseTool
 
 	// 创建市值查询工具
-	marketCapToolFunc := func(symbol, date string) (float64, error) {
-		return GetMarketCap(symbol, date)
+	marketCapToolFunc := func(ctx context.Context, symbol, date string) (float64, error) {
+		return GetMarketCap(ctx, symbol, date)
 	}
 	marketCapTool, err := tools.NewMarketCapTool(marketCapToolFunc)
 	if err != nil {
-		return "", fmt.Errorf("创建市值工具失败: %v", err)
+		return nil, fmt.Errorf("创建市值工具失败: %v", err)
 	}
 	investmentTools = append(investmentTools, marketCapTool)
 
-	// 创建财务指标工具
-	metricsToolFunc := func(symbol, date, period string, limit int) ([]tools.FinancialMetrics, error) {
-		return GetFinancialMetrics(symbol, date, period, limit)
+	// 创建公司简介工具，业务描述来自年报业务章节或公司官网，报告开头据此介绍公司
+	profileTool, err := tools.NewCompanyProfileTool(getCompanyOverview)
+	if err != nil {
+		return nil, fmt.Errorf("创建公司简介工具失败: %v", err)
+	}
+	investmentTools = append(investmentTools, profileTool)
+
+	// 创建财务指标工具，最新一期债务股权比超过 CREDIT_RISK_DE_THRESHOLD 时要求做信用风险评估
+	creditRiskDE, err := creditRiskDebtToEquity()
+	if err != nil {
+		return nil, err
+	}
+	metricsToolFunc := func(ctx context.Context, symbol, date, period string, limit int) ([]tools.FinancialMetrics, error) {
+		return GetFinancialMetrics(ctx, symbol, date, period, limit)
 	}
-	metricsTool, err := tools.NewFinancialMetricsTool(metricsToolFunc)
+	metricsTool, err := tools.NewFinancialMetricsTool(metricsToolFunc, creditRiskDE)
 	if err != nil {
-		return "", fmt.Errorf("创建财务指标工具失败: %v", err)
+		return nil, fmt.Errorf("创建财务指标工具失败: %v", err)
 	}
 	investmentTools = append(investmentTools, metricsTool)
 
+	// 创建破产与信用风险评估工具，计算 Altman Z″-score 和利息保障倍数趋势
+	creditRiskTool, err := tools.NewCreditRiskTool(getCreditRiskData)
+	if err != nil {
+		return nil, fmt.Errorf("创建信用风险评估工具失败: %v", err)
+	}
+	investmentTools = append(investmentTools, creditRiskTool)
+
+	// 创建营运资本趋势工具，按季度计算周转天数和现金转换周期
+	workingCapitalTool, err := tools.NewWorkingCapitalTool(getWorkingCapital)
+	if err != nil {
+		return nil, fmt.Errorf("创建营运资本工具失败: %v", err)
+	}
+	investmentTools = append(investmentTools, workingCapitalTool)
+
 	// 创建新闻工具
-	newsToolFunc := func(symbol, date string, since *string, limit int) ([]tools.CompanyNews, error) {
-		news, err := GetCompanyNews(symbol, date, since, limit)
+	newsToolFunc := func(ctx context.Context, symbol, date string, since *string, limit int) ([]tools.CompanyNews, error) {
+		news, err := GetCompanyNews(ctx, symbol, date, since, limit)
 		if err != nil {
 			return nil, err
 		}
 		return news, nil
 	}
-	newsTool, err := tools.NewCompanyNewsTool(newsToolFunc)
+	// 设置 NEWS_LLM_CATEGORIZE=true 时，使用模型对关键词规则无法识别的新闻补充分类
+	generator := newStructuredGenerator(chatModel, options.ModelType)
+	var newsClassifier tools.NewsClassifier
+	if os.Getenv("NEWS_LLM_CATEGORIZE") == "true" {
+		newsClassifier = newLLMNewsClassifier(generator)
+	}
+	// 设置 NEWS_SENTIMENT=true 时，使用模型为新闻批量评分情绪，批大小和并发数决定调用次数和速率
+	var sentiment *tools.SentimentBatcher
+	if os.Getenv("NEWS_SENTIMENT") == "true" {
+		batchSize, err := positiveIntEnv("NEWS_SENTIMENT_BATCH_SIZE", 20)
+		if err != nil {
+			return nil, err
+		}
+		concurrency, err := positiveIntEnv("NEWS_SENTIMENT_CONCURRENCY", 2)
+		if err != nil {
+			return nil, err
+		}
+		sentiment = tools.NewSentimentBatcher(newLLMNewsSentimentScorer(generator), batchSize, concurrency, sentimentRequestInterval)
+	}
+	newsTool, err := tools.NewCompanyNewsTool(newsToolFunc, newsClassifier, sentiment)
 	if err != nil {
-		return "", fmt.Errorf("创建新闻工具失败: %v", err)
+		return nil, fmt.Errorf("创建新闻工具失败: %v", err)
 	}
 	investmentTools = append(investmentTools, newsTool)
 
-	// 创建基本面分析工具
-	fundamentalTool, err := tools.NewFundamentalAnalysisTool(ctx)
+	// 创建内部人交易工具
+	insiderToolFunc := func(ctx context.Context, symbol, endDate string, startDate *string, limit int) ([]tools.InsiderTrade, string, error) {
+		return GetInsiderTrades(ctx, symbol, endDate, startDate, limit)
+	}
+	insiderTool, err := tools.NewInsiderTradesTool(insiderToolFunc)
+	if err != nil {
+		return nil, fmt.Errorf("创建内部人交易工具失败: %v", err)
+	}
+	investmentTools = append(investmentTools, insiderTool)
+
+	// 创建管理层质量评估工具，综合内部人持股、股权激励、股本变化、资本配置和高管变动
+	managementTool, err := tools.NewManagementQualityTool(getCapitalAllocation, insiderToolFunc, newsToolFunc)
+	if err != nil {
+		return nil, fmt.Errorf("创建管理层质量评估工具失败: %v", err)
+	}
+	investmentTools = append(investmentTools, managementTool)
+
+	// 创建长周期价格统计工具，多年区间的价格按年分段拉取
+	priceHistoryTool, err := tools.NewPriceHistoryTool(func(ctx context.Context, symbol string, years int) (*tools.PriceHistoryStats, error) {
+		return GetPriceHistoryStats(ctx, symbol, years)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("创建价格历史工具失败: %v", err)
+	}
+	investmentTools = append(investmentTools, priceHistoryTool)
+
+	// 创建大数据集摘要工具，长周期新闻和内部人交易逐页写入数据库，只向 Agent 返回摘要
+	datasetSummaryTool, err := tools.NewDatasetSummaryTool(func(ctx context.Context, dataset, symbol, startDate, endDate string) (*tools.DatasetSummary, error) {
+		return StreamDataset(ctx, dataset, symbol, startDate, endDate, sentiment)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("创建数据集摘要工具失败: %v", err)
+	}
+	investmentTools = append(investmentTools, datasetSummaryTool)
+
+	// 创建长周期新闻时间线工具，6 到 12 个月的新闻经分块摘要、季度合并后以叙事时间线交给 Agent
+	newsTimelineTool, err := tools.NewNewsTimelineTool(getNewsWindow, newLLMNewsTimelineSummarizer(generator))
+	if err != nil {
+		return nil, fmt.Errorf("创建新闻时间线工具失败: %v", err)
+	}
+	investmentTools = append(investmentTools, newsTimelineTool)
+
+	// 创建法律与监管风险跟踪工具，法律诉讼章节来自年报 Item-3
+	legalFilingsFunc := func(ctx context.Context, symbol string, years []int) ([]tools.FilingSection, error) {
+		return getFilingSections(ctx, symbol, "10-K", years, []string{"Item-3"})
+	}
+	legalRiskTool, err := tools.NewLegalRiskTool(newsToolFunc, legalFilingsFunc)
+	if err != nil {
+		return nil, fmt.Errorf("创建法律风险工具失败: %v", err)
+	}
+	investmentTools = append(investmentTools, legalRiskTool)
+
+	// 创建并购与公司行动历史工具，年报段落来自业务和管理层讨论章节，并购支出来自现金流量表
+	corporateActionFilingsFunc := func(ctx context.Context, symbol string, years []int) ([]tools.FilingSection, error) {
+		return getFilingSections(ctx, symbol, "10-K", years, []string{"Item-1", "Item-7"})
+	}
+	corporateActionsTool, err := tools.NewCorporateActionsTool(newsToolFunc, corporateActionFilingsFunc, getCapitalAllocation, newLLMCorporateActionExtractor(generator))
+	if err != nil {
+		return nil, fmt.Errorf("创建公司行动历史工具失败: %v", err)
+	}
+	investmentTools = append(investmentTools, corporateActionsTool)
+
+	// 创建客户与供应链集中度提取工具，段落来自年报的业务、风险因素和管理层讨论章节
+	dependencySectionsFunc := func(ctx context.Context, symbol string, year int) ([]tools.FilingSection, error) {
+		return getFilingSections(ctx, symbol, "10-K", []int{year}, []string{"Item-1", "Item-1A", "Item-7"})
+	}
+	concentrationTool, err := tools.NewConcentrationTool(dependencySectionsFunc, newLLMConcentrationExtractor(generator))
 	if err != nil {
-		return "", fmt.Errorf("创建基本面分析工具失败: %v", err)
+		return nil, fmt.Errorf("创建集中度提取工具失败: %v", err)
+	}
+	investmentTools = append(investmentTools, concentrationTool)
+
+	// 创建基本面分析工具，使用行业基准中位数作为评分阈值，并根据上市年限决定是否做趋势类检查
+	benchmarkService := NewIndustryBenchmarkServiceFromEnv()
+	listingDateFunc := func(ctx context.Context, ticker string) (string, error) {
+		facts, err := GetCompanyFacts(ctx, ticker)
+		if err != nil {
+			return "", err
+		}
+		return facts.ListingDate, nil
+	}
+	fundamentalTool, err := tools.NewFundamentalAnalysisTool(ctx, benchmarkService.Get, listingDateFunc)
+	if err != nil {
+		return nil, fmt.Errorf("创建基本面分析工具失败: %v", err)
 	}
 	investmentTools = append(investmentTools, fundamentalTool)
 
+	// 创建相似公司工具，按公司画像（行业、业务描述嵌入和财务指标）在股票池中查找最相似的公司
+	universeProvider := NewUniverseProvider()
+	similarityService, err := NewSimilarityServiceFromEnv(universeProvider)
+	if err != nil {
+		return nil, fmt.Errorf("创建相似公司服务失败: %v", err)
+	}
+	similarTool, err := tools.NewSimilarCompaniesTool(func(ctx context.Context, symbol string, limit int) (*tools.SimilarCompaniesOutput, error) {
+		return similarityService.Find(ctx, symbol, limit)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("创建相似公司工具失败: %v", err)
+	}
+	investmentTools = append(investmentTools, similarTool)
+
+	// 创建同行对比工具，可比公司优先使用配置的组合，未配置时按画像相似度查找，再退回行业基准股票池
+	peerService, err := NewPeerServiceFromEnv(benchmarkService, similarityService)
+	if err != nil {
+		return nil, fmt.Errorf("加载可比公司配置失败: %v", err)
+	}
+	peerTool, err := tools.NewPeerComparisonTool(peerService.Get, metricsToolFunc)
+	if err != nil {
+		return nil, fmt.Errorf("创建同行对比工具失败: %v", err)
+	}
+	investmentTools = append(investmentTools, peerTool)
+
+	// 创建竞争格局分析工具，竞争对手默认使用可比公司组，由模型比较市场地位和定价权并给出竞争地位评分
+	competitionTool, err := tools.NewCompetitiveAnalysisTool(peerService.Get, getCompanyOverview, metricsToolFunc, newLLMCompetitiveAssessor(generator))
+	if err != nil {
+		return nil, fmt.Errorf("创建竞争格局分析工具失败: %v", err)
+	}
+	investmentTools = append(investmentTools, competitionTool)
+
+	// 日线价格和成交量，实时行情的 52 周区间和流动性评估共用
+	dailyBarsFunc := func(ctx context.Context, symbol, startDate, endDate string) ([]tools.LiquidityBar, error) {
+		prices, err := GetPrices(ctx, symbol, startDate, endDate)
+		if err != nil {
+			return nil, err
+		}
+		bars := make([]tools.LiquidityBar, len(prices))
+		for i, p := range prices {
+			bars[i] = tools.LiquidityBar{Date: p.Time, High: p.High, Low: p.Low, Close: p.Close, Volume: float64(p.Volume)}
+		}
+		return bars, nil
+	}
+
+	// 创建实时行情工具，估值部分引用实时价格而不是财务指标中滞后的市值；盘前/盘后显著异动时附带相关新闻
+	quoteFunc := func(ctx context.Context, symbol string) (*tools.QuoteSnapshot, error) {
+		snapshot, err := GetPriceSnapshot(ctx, symbol)
+		if err != nil {
+			return nil, err
+		}
+		return &tools.QuoteSnapshot{Price: snapshot.Price, DayChange: snapshot.DayChange, DayChangePercent: snapshot.DayChangePercent, Volume: snapshot.Volume, Time: snapshot.Time}, nil
+	}
+	quoteTool, err := tools.NewQuoteTool(quoteFunc, dailyBarsFunc, newsToolFunc, profile.tradingSession())
+	if err != nil {
+		return nil, fmt.Errorf("创建实时行情工具失败: %v", err)
+	}
+	investmentTools = append(investmentTools, quoteTool)
+
+	// 创建目标价合理性检查工具，把目标价分解为隐含市盈率和 EPS 增长，与历史和可比公司比较
+	priceTargetTool, err := tools.NewPriceTargetCheckTool(currentPrice, metricsToolFunc, peerService.Get)
+	if err != nil {
+		return nil, fmt.Errorf("创建目标价检查工具失败: %v", err)
+	}
+	investmentTools = append(investmentTools, priceTargetTool)
+
+	// 创建流动性评估工具，根据近 3 个月的成交额、估算价差和自由流通股本给出仓位的流动性约束
+	liquidityFactsFunc := func(ctx context.Context, symbol string) (float64, float64, error) {
+		facts, err := GetCompanyFacts(ctx, symbol)
+		if err != nil {
+			return 0, 0, err
+		}
+		return float64(facts.WeightedAverageShares), facts.MarketCap, nil
+	}
+	liquidityTool, err := tools.NewLiquidityTool(dailyBarsFunc, liquidityFactsFunc, insiderToolFunc)
+	if err != nil {
+		return nil, fmt.Errorf("创建流动性评估工具失败: %v", err)
+	}
+	investmentTools = append(investmentTools, liquidityTool)
+
+	// 创建组合相关性分析工具，评估持仓之间的相关性和组合波动率
+	correlationTool, err := tools.NewPortfolioCorrelationTool(func(ctx context.Context, symbol string, years int) (*tools.PriceSeries, error) {
+		return GetPriceSeries(ctx, symbol, years)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("创建组合相关性工具失败: %v", err)
+	}
+	investmentTools = append(investmentTools, correlationTool)
+
+	// 创建指数成分股工具，成分股缓存在 output/universe/，通过 universe refresh 更新
+	indexTool, err := tools.NewIndexConstituentsTool(universeProvider.Get)
+	if err != nil {
+		return nil, fmt.Errorf("创建指数成分股工具失败: %v", err)
+	}
+	investmentTools = append(investmentTools, indexTool)
+
+	// 创建蒙特卡洛估值工具
+	valuationTool, err := tools.NewMonteCarloValuationTool()
+	if err != nil {
+		return nil, fmt.Errorf("创建蒙特卡洛估值工具失败: %v", err)
+	}
+	investmentTools = append(investmentTools, valuationTool)
+
+	// 分析前确定工具和调用顺序：按分析深度、市场和可用数据跳过不需要或没有数据的工具，Agent 只看到计划中的工具
+	depth, err := analysisDepth(options.Depth)
+	if err != nil {
+		return nil, err
+	}
+	statements, err := loadCompanyStatements(symbol)
+	if err != nil {
+		return nil, err
+	}
+	names, err := toolNames(ctx, investmentTools)
+	if err != nil {
+		return nil, err
+	}
+	plan := planAnalysis(planInputs{Depth: depth, Market: profile.Name, Private: statements != nil, Portfolio: len(options.PortfolioSymbols) > 0}, names)
+	if investmentTools, err = plan.filterTools(ctx, investmentTools); err != nil {
+		return nil, err
+	}
+	fmt.Printf("🗺️ 分析计划：%s\n", plan.summary())
+
+	// TOOL_SCHEMA_LOCALE=en 时用英文向模型描述工具和参数，与英文提示词或对中文说明理解较差的模型配合使用
+	if investmentTools, err = tools.WithSchemaLocale(ctx, investmentTools, toolSchemaLocale()); err != nil {
+		return nil, err
+	}
+
 	toolCallChecker := func(ctx context.Context, sr *schema.StreamReader[*schema.Message]) (bool, error) {
 		defer sr.Close()
 		for {
@@ -170,56 +556,55 @@ func analyzeWithReactAgent(ctx context.Context, chatModel model.ToolCallingChatM
 		return false, nil
 	}
 
+	// 模型在同一轮中请求多个工具时并行执行，TOOL_MAX_PARALLELISM 控制最大并发数（默认4，设为1则顺序执行）
+	maxParallelism, err := positiveIntEnv("TOOL_MAX_PARALLELISM", 4)
+	if err != nil {
+		return nil, err
+	}
+	tools.Logger(ctx).Printf("Tool max parallelism: %d", maxParallelism)
+	maxSteps, err := positiveIntEnv("AGENT_MAX_STEPS", defaultAgentMaxSteps)
+	if err != nil {
+		return nil, err
+	}
+
+	// 检测相同参数的重复工具调用，直接返回缓存结果并提示模型继续分析
+	watchdog := tools.NewLoopWatchdog()
+
 	// 创建 React Agent
 	agent, err := react.NewAgent(ctx, &react.AgentConfig{
 		ToolCallingModel: chatModel,
 		ToolsConfig: compose.ToolsNodeConfig{
-			Tools: investmentTools,
+			Tools:               watchdog.Wrap(tools.WithCheckpoint(tools.WithConcurrencyLimit(tools.WithTimeout(tools.WithSandbox(tools.WithInputValidation(investmentTools), options.ToolQuota), options.ToolTimeout), maxParallelism), options.Checkpoint)),
+			ExecuteSequentially: maxParallelism == 1,
 		},
+		MessageModifier:       watchdog.MessageModifier,
 		StreamToolCallChecker: toolCallChecker,
-		MaxStep:               10, // 最大推理步数，允许多步骤分析
+		MaxStep:               maxSteps, // 最大推理步数，达到上限时由收尾步骤根据已收集的数据撰写报告
 	})
 	if err != nil {
-		return "", fmt.Errorf("创建 React Agent 失败: %v", err)
+		return nil, fmt.Errorf("创建 React Agent 失败: %v", err)
 	}
 
-	// 构建系统提示词，指导 Agent 进行投资分析
-	systemPrompt := `你是一个专业的股票投资分析师，具有深厚的价值投资理念和丰富的分析经验。你会系统性地收集和分析数据，遵循严格的投资分析流程。
-
-## 你可以使用的工具：
-
-- get_market_cap: 获取股票市值信息
-- get_financial_metrics: 获取财务指标数据（ROE、债务比率、营运利润率等）
-- get_company_news: 获取公司最新新闻动态
-- analyze_fundamentals: 进行巴菲特式基本面分析
-
-## 分析步骤：
-
-- 先思考分析计划，然后获取股票基本信息（市值）
-- 获取财务指标数据，重点关注过去5年的趋势
-- 获取公司最新新闻，了解业务动态和市场情绪
-- 使用基本面分析工具，输入财务指标进行量化评估
-- 综合所有信息，形成最终投资建议
-
-## 分析原则：
-
-- 数据驱动：所有结论都要基于具体的财务数据
-- 质量优先：重视ROE稳定性、低债务、强现金流
-- 长期视角：关注公司的护城河和持续竞争优势
-- 估值理性：不追高，寻找价值被低估的机会
-- 风险管控：明确指出投资风险和注意事项
-
-## 输出要求：
-
-- 输出格式为 markdown
-- 清晰说明每步分析的思路
-- 展示关键财务数据和趋势
-- 提供明确的投资评级（强烈推荐/推荐/中性/谨慎/避免）
-- 给出目标价位和风险提示
-
-请按照以上流程进行分析，确保每个步骤都有充分的数据支撑。`
-
-	userPrompt := fmt.Sprintf("请分析股票 %s 的投资价值。请按照标准的投资分析流程，收集必要的数据并进行综合评估，最后给出投资建议。", symbol)
+	// 系统提示词指导 Agent 进行投资分析，用户提示词中的 {symbol} 替换为股票代码
+	systemPrompt := options.Prompts.System
+	if options.PriorFindings != "" {
+		systemPrompt += "\n\n" + options.PriorFindings
+	}
+	userPrompt := strings.ReplaceAll(options.Prompts.User, "{symbol}", symbol)
+	// REPORT_LOCALE 决定报告的数字单位（zh-CN：万/亿，en-US：K/M/B），模型撰写的章节和程序渲染的表格保持一致，未设置时使用市场默认值
+	format, err := tools.NewNumberFormat(profile.reportLocale())
+	if err != nil {
+		return nil, err
+	}
+	userPrompt += "\n\n" + format.UnitInstruction()
+	userPrompt += "\n\n" + profile.promptContext(time.Now())
+	userPrompt += "\n\n" + plan.promptContext()
+	if len(options.PortfolioSymbols) > 0 {
+		userPrompt += fmt.Sprintf("\n\n该股票属于组合分析，组合现有持仓：%s。请使用 analyze_portfolio_correlation 评估持有该股票后组合的相关性和分散化质量，并在报告中单独说明。", strings.Join(options.PortfolioSymbols, ", "))
+	}
+	if options.Note != "" {
+		userPrompt += fmt.Sprintf("\n\n用户在关注列表中为该股票写了备注：「%s」。请在分析中留意备注提到的问题，并在报告中单独回应。", options.Note)
+	}
 
 	// 创建消息
 	messages := []*schema.Message{
@@ -239,38 +624,112 @@ func analyzeWithReactAgent(ctx context.Context, chatModel model.ToolCallingChatM
 	// 使用 React Agent 的流式输出能力
 	opts, future := react.WithMessageFuture()
 	stream, err := agent.Stream(ctx, messages, opts)
-	if err != nil {
-		return "", fmt.Errorf("analyze failed with React Agent stream: %v", err)
+	stepLimited := errors.Is(err, compose.ErrExceedMaxSteps)
+	if err != nil && !stepLimited {
+		return nil, fmt.Errorf("analyze failed with React Agent stream: %v", err)
+	}
+	if stream != nil {
+		defer stream.Close()
 	}
-	defer stream.Close()
 
+	// 在后台消费消息流，以便超时后不再等待卡住的模型或工具
+	// VERIFY_NUMBERS=false 时不核对报告中的数值
+	progress := &analysisProgress{start: time.Now(), symbol: symbol, plan: plan, transcript: options.Transcript, format: format, verify: os.Getenv("VERIFY_NUMBERS") != "false", creditRiskDE: creditRiskDE}
+	// 股东回报章节由程序根据价格和分红数据计算，不依赖模型，与 Agent 分析并行进行
+	returnsDone := startShareholderReturns(ctx, symbol, profile.benchmark(), progress)
+	events := newProgressEmitter(options.Progress, defaultProgressThrottle)
+	var printer *terminalPrinter
+	if options.Stream != "" {
+		printer = newTerminalPrinter(options.Stream)
+	}
+	done := make(chan error, 1)
+	go func() {
+		err := consumeAgentStream(future, stream, progress, events, printer)
+		limited := errors.Is(err, compose.ErrExceedMaxSteps) || (err == nil && stepLimited)
+		if limited {
+			err = nil
+			fmt.Printf("\n⚠️ 已达到最大推理步数 %d，根据已收集的数据撰写报告\n", maxSteps)
+		}
+		switch {
+		case err != nil:
+		case sectionedReportEnabled():
+			// SECTIONED_REPORT=true 时分章节撰写最终报告，每章单独校验和重试，Agent 的最终回复只作为草稿
+			fmt.Printf("\n📝 分章节撰写最终报告...\n")
+			var report string
+			files := newModelFileUploaderFromEnv(ctx, options.ModelType)
+			report, err = writeSectionedReport(ctx, chatModel, files, systemPrompt, userPrompt, progress, events, printer)
+			if err == nil && limited {
+				progress.setStepLimitedFinal(report, maxSteps)
+			} else if err == nil {
+				progress.setFinal(report)
+			}
+		case limited:
+			// 达到最大推理步数仍未给出最终回复时，根据已收集的数据单独撰写报告
+			files := newModelFileUploaderFromEnv(ctx, options.ModelType)
+			err = completeReportFromGatheredData(ctx, chatModel, files, systemPrompt, userPrompt, maxSteps, progress, events, printer)
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			select {
+			case <-returnsDone:
+			case <-ctx.Done():
+			}
+			events.finished(false)
+			return progress.result(progress.report()), nil
+		}
+		if ctx.Err() == nil {
+			return nil, err
+		}
+		tools.Logger(ctx).Printf("分析超时中断: %v", err)
+	case <-ctx.Done():
+		tools.Logger(ctx).Printf("分析超时中断: %v", context.Cause(ctx))
+	}
+	events.finished(true)
+	return progress.result(progress.truncatedReport(context.Cause(ctx))), nil
+}
+
+// consumeAgentStream 读取 Agent 的中间消息和最终回复，记录到 progress 中、发送进度事件并流式输出到终端
+// stream 为 nil 时（Agent 已因达到最大推理步数结束）只读取中间消息
+func consumeAgentStream(future react.MessageFuture, stream *schema.StreamReader[*schema.Message], progress *analysisProgress, events *progressEmitter, printer *terminalPrinter) error {
 	// Get message streams from future
 	sIter := future.GetMessageStreams()
 	for {
 		s, hasNext, err := sIter.Next()
 		if err != nil {
-			return "", err
+			return err
 		}
 		if !hasNext {
 			break
 		}
 
-		msg, err := schema.ConcatMessageStream(s)
+		msg, err := readMessageStream(s, events, printer)
 		if err != nil {
-			return "", err
+			return err
 		}
+		progress.addMessage(msg)
 		if msg.Role == schema.Tool {
-			fmt.Printf("Tool %s called\n", msg.ToolName)
+			preview := tools.PreviewToolResult(msg.ToolName, msg.Content)
+			fmt.Printf("🔧 %s\n", preview)
+			events.toolCalled(msg.ToolName, preview)
+			progress.addToolResult(msg)
 			continue
 		}
 		if msg.Content != "" {
-			fmt.Println(msg.Content)
+			progress.addContent(msg.Content)
 		}
 		// fmt.Printf("recv msg: role: %v, content: %v\n", msg.Role, msg.Content)
 	}
+	if stream == nil {
+		return nil
+	}
 	finalResponse, err := schema.ConcatMessageStream(stream)
 	if err != nil {
-		return "", err
+		return err
 	}
-	return finalResponse.Content, nil
+	progress.setFinal(finalResponse.Content)
+	return nil
 }
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"investment/tools"
+)
+
+// capitalAllocationLineItems 资本配置分析使用的财务报表项目
+var capitalAllocationLineItems = []string{
+	"revenue",
+	"free_cash_flow",
+	"share_based_compensation",
+	"issuance_or_purchase_of_equity_shares",
+	"dividends_and_other_cash_distributions",
+	"business_acquisitions_and_disposals",
+	"outstanding_shares",
+}
+
+// getCapitalAllocation 获取最近 years 个年度的资本配置数据，最新的在前
+// 现金流量表中的流出为负数，回购、分红和并购统一转换为正的流出金额，净发行股票或净出售业务时记为 0
+func getCapitalAllocation(ctx context.Context, symbol string, years int) ([]tools.CapitalAllocationPeriod, error) {
+	items, err := SearchLineItems(ctx, symbol, capitalAllocationLineItems, time.Now().Format("2006-01-02"), "annual", years)
+	if err != nil {
+		return nil, err
+	}
+	periods := make([]tools.CapitalAllocationPeriod, 0, len(items))
+	for _, item := range items {
+		outflow := func(key string) *float64 {
+			v := lineItemValue(item, key)
+			if v == nil {
+				return nil
+			}
+			out := math.Max(-*v, 0)
+			return &out
+		}
+		periods = append(periods, tools.CapitalAllocationPeriod{
+			ReportPeriod:           item.ReportPeriod,
+			Revenue:                lineItemValue(item, "revenue"),
+			FreeCashFlow:           lineItemValue(item, "free_cash_flow"),
+			ShareBasedCompensation: lineItemValue(item, "share_based_compensation"),
+			Buybacks:               outflow("issuance_or_purchase_of_equity_shares"),
+			Dividends:              outflow("dividends_and_other_cash_distributions"),
+			Acquisitions:           outflow("business_acquisitions_and_disposals"),
+			SharesOutstanding:      lineItemValue(item, "outstanding_shares"),
+		})
+	}
+	return periods, nil
+}
+
+// lineItemValue 读取数值型报表项目，缺失或不是数值时返回 nil
+func lineItemValue(item LineItem, key string) *float64 {
+	v, ok := item.Data[key].(float64)
+	if !ok || !tools.IsFinite(v) {
+		return nil
+	}
+	return &v
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"investment/tools"
+)
+
+// 市场代码，--market / MARKET 的取值；auto 按股票代码后缀识别
+const (
+	MarketAuto = "auto"
+	MarketUS   = "us"
+	MarketCN   = "cn"
+	MarketHK   = "hk"
+)
+
+// marketProfile 一个市场的默认配置：模型、报告区域设置、默认币种、交易日历和股东回报基准
+// 配置项只在对应的环境变量未设置时生效，优先级：命令行参数 > 环境变量 > 配置文件 > 市场默认值 > 内置默认值
+type marketProfile struct {
+	Name      string
+	Label     string   // 报告和提示词中的市场名称
+	Suffixes  []string // 识别市场的股票代码后缀
+	ModelType string   // 默认模型（MODEL_TYPE），为空时使用内置默认
+	Locale    string   // 默认报告区域设置（REPORT_LOCALE），同时决定提示词中的数字单位约定
+	Currency  string   // 数据未标明币种时使用的币种
+	Benchmark string   // 默认股东回报基准（SHAREHOLDER_RETURN_BENCHMARK）
+	Timezone  string   // 交易日历使用的时区
+	Open      string   // 常规交易开始时间（当地时间）
+	Close     string   // 常规交易结束时间（当地时间）
+	Extended  bool     // 是否有盘前盘后交易
+}
+
+// marketProfiles 内置的市场配置
+var marketProfiles = map[string]marketProfile{
+	MarketUS: {Name: MarketUS, Label: "美股", Locale: tools.LocaleEnUS, Currency: "USD", Benchmark: "SPY", Timezone: "America/New_York", Open: "09:30", Close: "16:00", Extended: true},
+	MarketCN: {Name: MarketCN, Label: "A股", Suffixes: []string{".SS", ".SH", ".SZ"}, ModelType: "deepseek", Locale: tools.LocaleZhCN, Currency: "CNY", Benchmark: "000300.SS", Timezone: "Asia/Shanghai", Open: "09:30", Close: "15:00"},
+	MarketHK: {Name: MarketHK, Label: "港股", Suffixes: []string{".HK"}, ModelType: "deepseek", Locale: tools.LocaleZhCN, Currency: "HKD", Benchmark: "2800.HK", Timezone: "Asia/Hong_Kong", Open: "09:30", Close: "16:00"},
+}
+
+// validMarket 检查 --market / MARKET 的取值
+func validMarket(market string) error {
+	market = strings.ToLower(strings.TrimSpace(market))
+	if market == MarketAuto {
+		return nil
+	}
+	if _, ok := marketProfiles[market]; !ok {
+		return fmt.Errorf("无效的市场: %s（可选 auto、us、cn、hk）", market)
+	}
+	return nil
+}
+
+// resolveMarket 返回股票代码使用的市场配置；market 为 auto 或空时按代码后缀识别，没有已知后缀时视为美股
+func resolveMarket(symbol, market string) (marketProfile, error) {
+	market = strings.ToLower(strings.TrimSpace(market))
+	if market != "" && market != MarketAuto {
+		profile, ok := marketProfiles[market]
+		if !ok {
+			return marketProfile{}, validMarket(market)
+		}
+		return profile, nil
+	}
+	symbol = strings.ToUpper(symbol)
+	for _, profile := range marketProfiles {
+		if slices.ContainsFunc(profile.Suffixes, func(suffix string) bool { return strings.HasSuffix(symbol, suffix) }) {
+			return profile, nil
+		}
+	}
+	return marketProfiles[MarketUS], nil
+}
+
+// apply 命令行单次分析时调用：MODEL_TYPE 未设置时使用市场默认模型，并设置全局默认币种
+// 服务模式同时分析多个市场，不调用 apply，只按股票代码使用报告单位和基准的市场默认值
+func (p marketProfile) apply() {
+	if os.Getenv("MODEL_TYPE") == "" && p.ModelType != "" {
+		os.Setenv("MODEL_TYPE", p.ModelType)
+	}
+	tools.SetDefaultCurrency(p.Currency)
+	log.Printf("市场配置: %s（%s），币种 %s，报告单位 %s，基准 %s", p.Name, p.Label, p.Currency, p.reportLocale(), orNA(p.benchmark()))
+}
+
+// reportLocale 报告区域设置：REPORT_LOCALE 优先，未设置时使用市场默认值
+func (p marketProfile) reportLocale() string {
+	if locale := os.Getenv("REPORT_LOCALE"); locale != "" {
+		return locale
+	}
+	return p.Locale
+}
+
+// benchmark 股东回报基准：SHAREHOLDER_RETURN_BENCHMARK 优先（为 none 时不与基准比较），未设置时使用市场默认值
+func (p marketProfile) benchmark() string {
+	benchmark := strings.ToUpper(strings.TrimSpace(os.Getenv("SHAREHOLDER_RETURN_BENCHMARK")))
+	switch benchmark {
+	case "":
+		return p.Benchmark
+	case "NONE":
+		return ""
+	}
+	return benchmark
+}
+
+// location 交易日历的时区，无法加载时区数据时使用 UTC
+func (p marketProfile) location() *time.Location {
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// lastTradingDay 市场当地时间 now 当天或之前最近的交易日；只排除周末，不包含节假日
+func (p marketProfile) lastTradingDay(now time.Time) time.Time {
+	day := now.In(p.location())
+	for day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		day = day.AddDate(0, 0, -1)
+	}
+	return day
+}
+
+// tradingSession 常规交易时间，行情工具据此判断报价是否来自盘前/盘后
+func (p marketProfile) tradingSession() tools.TradingSession {
+	return tools.TradingSession{Location: p.location(), Open: p.Open, Close: p.Close, ExtendedHours: p.Extended}
+}
+
+// promptContext 写入用户提示词的市场说明，让模型使用正确的币种、交易日和基准
+func (p marketProfile) promptContext(now time.Time) string {
+	return fmt.Sprintf("市场：%s（币种 %s，交易时区 %s，最近交易日 %s）。涉及价格和金额时使用该币种，比较市场表现时以 %s 为基准。",
+		p.Label, p.Currency, p.Timezone, p.lastTradingDay(now).Format("2006-01-02"), orNA(p.benchmark()))
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"investment/tools"
+
+	"github.com/cloudwego/eino/schema"
+	"google.golang.org/genai"
+)
+
+// modelFileUploader 将较大的数据上传为模型可直接读取的文件，代替内联在提示词中
+type modelFileUploader interface {
+	// Upload 上传文件，返回可放入消息 MultiContent 的文件引用
+	Upload(ctx context.Context, name, mimeType string, data []byte) (schema.ChatMessagePart, error)
+}
+
+// newModelFileUploaderFromEnv MODEL_FILE_INPUTS=true 时返回当前模型的文件上传器，默认关闭；
+// 支持 Gemini（Files API）和 OpenAI（Files API，purpose=user_data），DeepSeek 没有文件接口，继续内联数据
+func newModelFileUploaderFromEnv(ctx context.Context, modelType string) modelFileUploader {
+	if os.Getenv("MODEL_FILE_INPUTS") != "true" {
+		return nil
+	}
+	switch modelType {
+	case "gemini":
+		client, err := newGeminiClient(ctx)
+		if err != nil {
+			log.Printf("MODEL_FILE_INPUTS: 创建 Gemini 客户端失败，数据继续内联在提示词中: %v", err)
+			return nil
+		}
+		return &geminiFileUploader{client: client}
+	case "openai":
+		baseURL := os.Getenv("OPENAI_BASE_URL")
+		if baseURL == "" {
+			baseURL = "https://api.openai.com/v1"
+		}
+		return &openAIFileUploader{baseURL: strings.TrimSuffix(baseURL, "/"), apiKey: os.Getenv("OPENAI_API_KEY"), client: newProxiedHTTPClient(2*time.Minute, openAIProxyEnv...)}
+	default:
+		log.Printf("MODEL_FILE_INPUTS: 模型 %s 不支持文件输入，数据继续内联在提示词中", modelType)
+		return nil
+	}
+}
+
+// fileMessage 带附件的用户消息；提示词放在 MultiContent 的文本部分而不是 Content，
+// OpenAI 的请求不允许同时设置 Content 和 MultiContent
+func fileMessage(prompt string, part schema.ChatMessagePart) *schema.Message {
+	return &schema.Message{
+		Role: schema.User,
+		MultiContent: []schema.ChatMessagePart{
+			{Type: schema.ChatMessagePartTypeText, Text: prompt},
+			part,
+		},
+	}
+}
+
+// uploadGatheredData 已收集的数据超出内联长度（inline 是截断后的数据）时上传未截断的数据，返回附件；
+// files 为空、数据已能完整内联或上传失败时返回 nil，调用方继续内联 inline
+func uploadGatheredData(ctx context.Context, files modelFileUploader, progress *analysisProgress, inline string) *schema.ChatMessagePart {
+	if files == nil {
+		return nil
+	}
+	full := progress.gatheredData(0)
+	if full == inline {
+		return nil
+	}
+	part, err := files.Upload(ctx, gatheredDataFileName, "text/plain", []byte(full))
+	if err != nil {
+		tools.Logger(ctx).Printf("数据附件上传失败，改为内联截断后的数据: %v", err)
+		return nil
+	}
+	return &part
+}
+
+// geminiFileUploader 通过 Gemini Files API 上传文件，文件在服务端保存 48 小时后自动删除
+type geminiFileUploader struct {
+	client *genai.Client
+}
+
+// Upload 实现 modelFileUploader
+func (u *geminiFileUploader) Upload(ctx context.Context, name, mimeType string, data []byte) (schema.ChatMessagePart, error) {
+	file, err := u.client.Files.Upload(ctx, bytes.NewReader(data), &genai.UploadFileConfig{
+		MIMEType:    mimeType,
+		DisplayName: name,
+	})
+	if err != nil {
+		return schema.ChatMessagePart{}, fmt.Errorf("上传文件 %s 失败: %w", name, err)
+	}
+	if file.State == genai.FileStateFailed {
+		return schema.ChatMessagePart{}, fmt.Errorf("文件 %s 处理失败", name)
+	}
+	return schema.ChatMessagePart{
+		Type: schema.ChatMessagePartTypeFileURL,
+		FileURL: &schema.ChatMessageFileURL{
+			URI:      file.URI,
+			MIMEType: file.MIMEType,
+			Name:     name,
+		},
+	}, nil
+}
+
+// openAIFileMaxAge OpenAI 上传文件的保留时间，与 Gemini Files API 一致，过期后由服务端删除
+const openAIFileMaxAge = 48 * time.Hour
+
+// openAIFilePattern 匹配 openAIFileUploader 返回的文件引用占位文本，捕获文件 ID
+var openAIFilePattern = regexp.MustCompile(`^\[\[openai-file:([\w-]+)\]\]$`)
+
+// openAIFileUploader 通过 OpenAI 兼容的 /files 接口上传文件（purpose=user_data）
+// eino 的 OpenAI 适配器不支持文件类型的消息部分，Upload 返回一段占位文本，
+// 由 openAIFileTransport 在发出 /chat/completions 请求前替换为 {"type":"file"} 部分
+type openAIFileUploader struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+// Upload 实现 modelFileUploader
+func (u *openAIFileUploader) Upload(ctx context.Context, name, mimeType string, data []byte) (schema.ChatMessagePart, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("purpose", "user_data")
+	form.WriteField("expires_after[anchor]", "created_at")
+	form.WriteField("expires_after[seconds]", strconv.Itoa(int(openAIFileMaxAge.Seconds())))
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, name))
+	header.Set("Content-Type", mimeType)
+	part, err := form.CreatePart(header)
+	if err != nil {
+		return schema.ChatMessagePart{}, fmt.Errorf("创建上传请求失败: %w", err)
+	}
+	part.Write(data)
+	if err := form.Close(); err != nil {
+		return schema.ChatMessagePart{}, fmt.Errorf("创建上传请求失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.baseURL+"/files", &body)
+	if err != nil {
+		return schema.ChatMessagePart{}, fmt.Errorf("创建上传请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+u.apiKey)
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return schema.ChatMessagePart{}, fmt.Errorf("上传文件 %s 失败: %w", name, err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return schema.ChatMessagePart{}, fmt.Errorf("读取上传响应失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return schema.ChatMessagePart{}, fmt.Errorf("上传文件 %s 失败: %w", name, tools.StatusError(resp.StatusCode, respBody))
+	}
+	var file struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(respBody, &file); err != nil {
+		return schema.ChatMessagePart{}, fmt.Errorf("解析上传响应失败: %w", err)
+	}
+	if file.ID == "" || file.Status == "error" {
+		return schema.ChatMessagePart{}, fmt.Errorf("文件 %s 处理失败（id=%q, status=%s）", name, file.ID, file.Status)
+	}
+	return schema.ChatMessagePart{Type: schema.ChatMessagePartTypeText, Text: openAIFileRef(file.ID)}, nil
+}
+
+// openAIFileRef 文件 ID 对应的占位文本
+func openAIFileRef(id string) string {
+	return "[[openai-file:" + id + "]]"
+}
+
+// openAIFileTransport 把 /chat/completions 请求中内容恰好是文件引用占位文本的 text 部分改写为
+// {"type":"file","file":{"file_id":...}}，其余请求原样转发
+type openAIFileTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t openAIFileTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPost || !strings.HasSuffix(req.URL.Path, "/chat/completions") || req.Body == nil {
+		return t.base.RoundTrip(req)
+	}
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if rewritten, ok := rewriteOpenAIFileParts(data); ok {
+		data = rewritten
+	}
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	req.ContentLength = int64(len(data))
+	req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(data)), nil }
+	return t.base.RoundTrip(req)
+}
+
+// rewriteOpenAIFileParts 改写请求体中的文件引用，没有需要改写的部分时返回 false
+func rewriteOpenAIFileParts(data []byte) ([]byte, bool) {
+	if !bytes.Contains(data, []byte("[[openai-file:")) {
+		return nil, false
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var body map[string]any
+	if err := decoder.Decode(&body); err != nil {
+		return nil, false
+	}
+	messages, _ := body["messages"].([]any)
+	changed := false
+	for _, m := range messages {
+		message, _ := m.(map[string]any)
+		parts, _ := message["content"].([]any)
+		for i, p := range parts {
+			part, _ := p.(map[string]any)
+			if part["type"] != "text" {
+				continue
+			}
+			text, _ := part["text"].(string)
+			if match := openAIFilePattern.FindStringSubmatch(text); match != nil {
+				parts[i] = map[string]any{"type": "file", "file": map[string]any{"file_id": match[1]}}
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return nil, false
+	}
+	rewritten, err := json.Marshal(body)
+	if err != nil {
+		return nil, false
+	}
+	return rewritten, true
+}
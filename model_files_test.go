@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino-ext/components/model/openai"
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestOpenAIFileUploader(t *testing.T) {
+	var form map[string][]string
+	var content string
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.String() != "https://openai.test/v1/files" || r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("请求 %s %s", r.URL, r.Header.Get("Authorization"))
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatal(err)
+		}
+		form = r.MultipartForm.Value
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(file)
+		content = string(data)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"id":"file-abc123","status":"processed"}`)), Request: r}, nil
+	})}
+	uploader := &openAIFileUploader{baseURL: "https://openai.test/v1", apiKey: "key", client: client}
+
+	part, err := uploader.Upload(context.Background(), gatheredDataFileName, "text/plain", []byte("数据"))
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if part.Type != schema.ChatMessagePartTypeText || part.Text != "[[openai-file:file-abc123]]" {
+		t.Errorf("part = %+v", part)
+	}
+	if content != "数据" || form["purpose"][0] != "user_data" || form["expires_after[seconds]"][0] != "172800" {
+		t.Errorf("content = %q, form = %v", content, form)
+	}
+}
+
+func TestOpenAIFileTransport(t *testing.T) {
+	// 带附件的消息经过 eino 的 OpenAI 适配器后，占位文本应被改写为文件部分，提示词保留为文本部分
+	var body map[string]any
+	transport := openAIFileTransport{base: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		resp := `{"id":"c","object":"chat.completion","model":"m","choices":[{"index":0,"message":{"role":"assistant","content":"报告"},"finish_reason":"stop"}]}`
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Content-Type": {"application/json"}}, Body: io.NopCloser(strings.NewReader(resp)), Request: r}, nil
+	})}
+	chatModel, err := openai.NewChatModel(context.Background(), &openai.ChatModelConfig{
+		BaseURL:    "https://openai.test/v1",
+		Model:      "m",
+		APIKey:     "key",
+		HTTPClient: &http.Client{Transport: transport},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	part := schema.ChatMessagePart{Type: schema.ChatMessagePartTypeText, Text: openAIFileRef("file-abc123")}
+	if _, err := chatModel.Generate(context.Background(), []*schema.Message{fileMessage("撰写报告", part)}); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	messages := body["messages"].([]any)
+	parts := messages[0].(map[string]any)["content"].([]any)
+	if len(parts) != 2 {
+		t.Fatalf("content = %v", parts)
+	}
+	if text := parts[0].(map[string]any); text["type"] != "text" || text["text"] != "撰写报告" {
+		t.Errorf("parts[0] = %v", text)
+	}
+	file := parts[1].(map[string]any)
+	if file["type"] != "file" || file["file"].(map[string]any)["file_id"] != "file-abc123" {
+		t.Errorf("parts[1] = %v", file)
+	}
+}
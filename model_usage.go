@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"investment/tools"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// ModelUsage 一次分析中模型调用的次数、token 用量和累计耗时
+type ModelUsage struct {
+	Calls            int           `json:"calls"`
+	PromptTokens     int           `json:"prompt_tokens"`
+	CompletionTokens int           `json:"completion_tokens"`
+	ModelTime        time.Duration `json:"model_time"` // 等待模型响应的累计时间，并发调用会重复计入
+}
+
+// modelUsageRecorder 在多个模型实例（WithTools 返回的副本）之间共享的用量统计
+type modelUsageRecorder struct {
+	mu       sync.Mutex
+	usage    ModelUsage
+	onRecord func(ModelUsage) // 每次记录后以累计用量调用，服务模式用于检查任务的 token 配额
+}
+
+// Usage 返回当前的用量统计
+func (r *modelUsageRecorder) Usage() ModelUsage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.usage
+}
+
+// record 记录一次模型调用，模型没有返回用量时只计次数和耗时
+func (r *modelUsageRecorder) record(msg *schema.Message, elapsed time.Duration) {
+	r.mu.Lock()
+	r.usage.Calls++
+	r.usage.ModelTime += elapsed
+	if msg != nil && msg.ResponseMeta != nil && msg.ResponseMeta.Usage != nil {
+		r.usage.PromptTokens += msg.ResponseMeta.Usage.PromptTokens
+		r.usage.CompletionTokens += msg.ResponseMeta.Usage.CompletionTokens
+	}
+	usage := r.usage
+	r.mu.Unlock()
+	if r.onRecord != nil {
+		r.onRecord(usage)
+	}
+}
+
+// usageChatModel 统计 token 用量和耗时的 ToolCallingChatModel
+type usageChatModel struct {
+	inner    model.ToolCallingChatModel
+	recorder *modelUsageRecorder
+}
+
+// withUsageTracking 为模型加上用量统计，返回模型和统计结果
+func withUsageTracking(chatModel model.ToolCallingChatModel) (*usageChatModel, *modelUsageRecorder) {
+	recorder := &modelUsageRecorder{}
+	return &usageChatModel{inner: chatModel, recorder: recorder}, recorder
+}
+
+// WithTools 实现 model.ToolCallingChatModel，绑定工具后的模型共享同一份统计
+func (m *usageChatModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	inner, err := m.inner.WithTools(tools)
+	if err != nil {
+		return nil, err
+	}
+	return &usageChatModel{inner: inner, recorder: m.recorder}, nil
+}
+
+// Generate 实现 model.BaseChatModel
+func (m *usageChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	start := time.Now()
+	msg, err := m.inner.Generate(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	m.recorder.record(msg, time.Since(start))
+	return msg, nil
+}
+
+// Stream 实现 model.BaseChatModel，在流结束后按拼接的响应记录用量和耗时
+func (m *usageChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	start := time.Now()
+	stream, err := m.inner.Stream(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	copies := stream.Copy(2)
+	go m.recordStream(start, copies[1])
+	return copies[0], nil
+}
+
+// recordStream 读完流后记录用量，流中途出错时不计入
+func (m *usageChatModel) recordStream(start time.Time, stream *schema.StreamReader[*schema.Message]) {
+	defer stream.Close()
+	var chunks []*schema.Message
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return
+		}
+		chunks = append(chunks, chunk)
+	}
+	var msg *schema.Message
+	if len(chunks) > 0 {
+		msg, _ = schema.ConcatMessages(chunks)
+	}
+	m.recorder.record(msg, time.Since(start))
+}
+
+// modelPricing 模型每百万 token 的价格（美元），来自 <MODEL_TYPE>_PRICE_INPUT / <MODEL_TYPE>_PRICE_OUTPUT
+type modelPricing struct {
+	Input  float64
+	Output float64
+}
+
+// modelPricingFromEnv 读取模型类型的价格配置，两项都未设置时返回 nil（不计算成本）
+func modelPricingFromEnv(modelType string) (*modelPricing, error) {
+	prefix := strings.ToUpper(modelType)
+	var pricing modelPricing
+	configured := false
+	for _, item := range []struct {
+		name  string
+		value *float64
+	}{
+		{prefix + "_PRICE_INPUT", &pricing.Input},
+		{prefix + "_PRICE_OUTPUT", &pricing.Output},
+	} {
+		raw := os.Getenv(item.name)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("无效的 %s: %s", item.name, raw)
+		}
+		*item.value = v
+		configured = true
+	}
+	if !configured {
+		return nil, nil
+	}
+	return &pricing, nil
+}
+
+// Cost 按用量计算成本（美元），没有价格配置时为 n/a
+func (p *modelPricing) Cost(usage ModelUsage) tools.SafeFloat {
+	if p == nil {
+		return tools.NaN()
+	}
+	return tools.Sanitize((float64(usage.PromptTokens)*p.Input + float64(usage.CompletionTokens)*p.Output) / 1e6)
+}
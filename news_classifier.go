@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"investment/tools"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// newsTopicsResult 新闻分类的模型输出，JSON 模式要求顶层为对象
+type newsTopicsResult struct {
+	Topics [][]string `json:"topics"`
+}
+
+// newLLMNewsClassifier 创建基于大模型的新闻主题分类器
+func newLLMNewsClassifier(generator *structuredGenerator) tools.NewsClassifier {
+	return func(ctx context.Context, news []tools.CompanyNews) ([][]string, error) {
+		var sb strings.Builder
+		sb.WriteString("请将以下公司新闻按主题分类。可用主题：")
+		sb.WriteString(strings.Join(tools.NewsTopics, ", "))
+		sb.WriteString("（earnings=业绩财报, mna=并购重组, litigation=诉讼, regulatory=监管, product=产品业务, management=管理层, other=其他）。\n")
+		sb.WriteString("每条新闻可以属于多个主题。只输出 JSON 对象，topics 数组第 i 个元素为第 i 条新闻的主题列表，例如 {\"topics\":[[\"earnings\"],[\"litigation\",\"regulatory\"]]}。\n\n")
+		for i, item := range news {
+			sb.WriteString(fmt.Sprintf("%d. %s\n", i, item.Title))
+			if item.Summary != "" {
+				sb.WriteString(fmt.Sprintf("   %s\n", item.Summary))
+			}
+		}
+
+		var result newsTopicsResult
+		validate := func() error {
+			if len(result.Topics) != len(news) {
+				return fmt.Errorf("分类结果数量不匹配: 期望 %d，实际 %d", len(news), len(result.Topics))
+			}
+			for i, topics := range result.Topics {
+				for _, topic := range topics {
+					if !slices.Contains(tools.NewsTopics, topic) {
+						return fmt.Errorf("第 %d 条新闻的主题无效: %s", i, topic)
+					}
+				}
+			}
+			return nil
+		}
+		err := generator.generate(ctx, []*schema.Message{
+			schema.UserMessage(sb.String()),
+		}, &result, validate)
+		if err != nil {
+			return nil, fmt.Errorf("模型分类失败: %w", err)
+		}
+		return result.Topics, nil
+	}
+}
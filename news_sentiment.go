@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"investment/tools"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// newsSentimentResult 新闻情绪评分的模型输出
+type newsSentimentResult struct {
+	Scores []tools.NewsSentiment `json:"scores"`
+}
+
+// newLLMNewsSentimentScorer 创建基于大模型的新闻情绪评分器，一次请求为一批新闻打分
+func newLLMNewsSentimentScorer(generator *structuredGenerator) tools.NewsSentimentScorer {
+	return func(ctx context.Context, news []tools.CompanyNews) ([]tools.NewsSentiment, error) {
+		var sb strings.Builder
+		sb.WriteString("请评估以下每条公司新闻对该公司股价的情绪倾向。label 取 positive、negative 或 neutral，score 取 -1（非常负面）到 1（非常正面）之间的数值。\n")
+		sb.WriteString(`只输出 JSON 对象，scores 数组第 i 个元素为第 i 条新闻的评分，例如 {"scores":[{"label":"positive","score":0.6},{"label":"neutral","score":0}]}。`)
+		sb.WriteString("\n\n")
+		for i, item := range news {
+			sb.WriteString(fmt.Sprintf("%d. %s\n", i, item.Title))
+			if item.Summary != "" {
+				sb.WriteString(fmt.Sprintf("   %s\n", item.Summary))
+			}
+		}
+
+		var result newsSentimentResult
+		validate := func() error {
+			if len(result.Scores) != len(news) {
+				return fmt.Errorf("评分数量不匹配: 期望 %d，实际 %d", len(news), len(result.Scores))
+			}
+			for i, s := range result.Scores {
+				switch s.Label {
+				case tools.SentimentPositive, tools.SentimentNegative, tools.SentimentNeutral:
+				default:
+					return fmt.Errorf("第 %d 条新闻的 label 无效: %s", i, s.Label)
+				}
+				if math.IsNaN(s.Score) || s.Score < -1 || s.Score > 1 {
+					return fmt.Errorf("第 %d 条新闻的 score 超出 [-1, 1]: %v", i, s.Score)
+				}
+			}
+			return nil
+		}
+		err := generator.generate(ctx, []*schema.Message{
+			schema.UserMessage(sb.String()),
+		}, &result, validate)
+		if err != nil {
+			return nil, fmt.Errorf("模型情绪评分失败: %w", err)
+		}
+		return result.Scores, nil
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"investment/tools"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// newsTimelineDigestFormat 分块摘要和季度合并共用的输出格式说明
+const newsTimelineDigestFormat = `只输出 JSON，格式为 {"narrative":"","events":[{"date":"YYYY-MM-DD","event":""}]}`
+
+// llmNewsTimelineSummarizer 基于大模型的新闻分层摘要
+type llmNewsTimelineSummarizer struct {
+	generator *structuredGenerator
+}
+
+// newLLMNewsTimelineSummarizer 创建基于大模型的新闻分层摘要
+func newLLMNewsTimelineSummarizer(generator *structuredGenerator) tools.NewsTimelineSummarizer {
+	return &llmNewsTimelineSummarizer{generator: generator}
+}
+
+// SummarizeChunk 实现 tools.NewsTimelineSummarizer
+func (s *llmNewsTimelineSummarizer) SummarizeChunk(ctx context.Context, symbol, quarter string, news []tools.CompanyNews) (*tools.NewsDigest, error) {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("以下是 %s 在 %s 的 %d 条新闻（按时间排列）。请整理这段时间发生了什么：\n", symbol, quarter, len(news)))
+	sb.WriteString("1. narrative：用 3 到 5 句中文概括这些新闻反映的公司经营、战略、产品、管理层、监管和市场预期变化，重复报道只算一次\n")
+	sb.WriteString("2. events：最多 6 个对投资判断最重要的事件，date 为事件日期，event 为一句话描述\n\n")
+	sb.WriteString("只使用新闻中明确提到的信息，不要推测，不要引入新闻以外的背景知识。\n")
+	sb.WriteString(newsTimelineDigestFormat)
+	sb.WriteString("\n\n")
+	for i, item := range news {
+		sb.WriteString(fmt.Sprintf("[%d] %s\n", i+1, tools.NewsTimelineText(item)))
+	}
+	return s.generate(ctx, sb.String())
+}
+
+// MergeDigests 实现 tools.NewsTimelineSummarizer
+func (s *llmNewsTimelineSummarizer) MergeDigests(ctx context.Context, symbol, quarter string, digests []tools.NewsDigest) (*tools.NewsDigest, error) {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("以下是 %s 在 %s 的新闻分段摘要。请合并为这个季度的叙述：\n", symbol, quarter))
+	sb.WriteString("1. narrative：用 4 到 6 句中文按时间顺序讲述这个季度的主线，指出前后变化（如指引上调后又下调、问题反复出现）\n")
+	sb.WriteString("2. events：合并重复事件后最多 8 个最重要的事件，按日期排列\n\n")
+	sb.WriteString("只使用摘要中的信息，不要推测。\n")
+	sb.WriteString(newsTimelineDigestFormat)
+	sb.WriteString("\n\n")
+	for i, d := range digests {
+		sb.WriteString(fmt.Sprintf("[分段 %d]\n%s\n", i+1, d.Narrative))
+		for _, e := range d.Events {
+			sb.WriteString(fmt.Sprintf("- %s %s\n", e.Date, e.Event))
+		}
+		sb.WriteString("\n")
+	}
+	return s.generate(ctx, sb.String())
+}
+
+// generate 调用模型生成摘要并校验
+func (s *llmNewsTimelineSummarizer) generate(ctx context.Context, prompt string) (*tools.NewsDigest, error) {
+	var digest tools.NewsDigest
+	err := s.generator.generate(ctx, []*schema.Message{
+		schema.UserMessage(prompt),
+	}, &digest, digest.Validate)
+	if err != nil {
+		return nil, fmt.Errorf("模型摘要失败: %w", err)
+	}
+	return &digest, nil
+}
+
+// getNewsWindow 分页拉取窗口内的全部新闻
+func getNewsWindow(ctx context.Context, symbol, startDate, endDate string) ([]tools.CompanyNews, error) {
+	var news []tools.CompanyNews
+	err := ForEachCompanyNewsPage(ctx, symbol, endDate, &startDate, datasetPageSize, func(page []tools.CompanyNews) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		news = append(news, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return news, nil
+}
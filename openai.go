@@ -13,10 +13,14 @@ func createOpenAIChatModel(ctx context.Context) model.ToolCallingChatModel {
 	key := os.Getenv("OPENAI_API_KEY")
 	modelName := os.Getenv("OPENAI_MODEL_NAME")
 	baseURL := os.Getenv("OPENAI_BASE_URL")
+	// MODEL_FILE_INPUTS 上传的文件以占位文本传给适配器，发送前改写为文件部分
+	client := newProxiedHTTPClient(0, openAIProxyEnv...)
+	client.Transport = openAIFileTransport{base: client.Transport}
 	chatModel, err := openai.NewChatModel(ctx, &openai.ChatModelConfig{
-		BaseURL: baseURL,
-		Model:   modelName,
-		APIKey:  key,
+		BaseURL:    baseURL,
+		Model:      modelName,
+		APIKey:     key,
+		HTTPClient: client,
 	})
 	if err != nil {
 		log.Fatalf("create openai chat model failed, err=%v", err)
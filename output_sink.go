@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"investment/tools"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// 支持的输出目标
+const (
+	OutputSinkFile   = "file"
+	OutputSinkS3     = "s3"
+	OutputSinkMemory = "memory"
+)
+
+// s3WriteTimeout 单次上传 S3 的最长时间
+const s3WriteTimeout = 30 * time.Second
+
+// setOutputDir 把本地输出根目录改为 OUTPUT_DIR，运行记录、缓存、日志等本地状态的目录随之移动；
+// 启动时在创建输出目标之前调用，服务模式热加载配置时不会重新调用
+func setOutputDir(root string) {
+	tools.SetOutputDir(root)
+	runsDir = filepath.Join(root, "runs")
+	jobsDir = filepath.Join(root, "jobs")
+	journalDir = filepath.Join(root, "journal")
+	knowledgeDir = filepath.Join(root, "knowledge")
+	portfolioDir = filepath.Join(root, "portfolio")
+	snapshotsDir = filepath.Join(root, "snapshots")
+	statementsDir = filepath.Join(root, "statements")
+	universeDir = filepath.Join(root, "universe")
+	watchlistsDir = filepath.Join(root, "watchlists")
+	apiCacheDir = filepath.Join(root, "cache", "api")
+	llmCacheDir = filepath.Join(root, "cache", "llm")
+	similarityCachePath = filepath.Join(root, "similarity", "profiles.json")
+	dataStorePath = filepath.Join(root, "investment.db")
+}
+
+// newOutputSinkFromEnv OUTPUT_SINK: file（默认，写入 OUTPUT_DIR，默认 output/）、s3 或 memory（只保存在进程内存中）；
+// S3 使用 OUTPUT_S3_BUCKET、OUTPUT_S3_PREFIX，可选 OUTPUT_S3_ENDPOINT（MinIO 等兼容服务，使用路径风格地址），
+// 凭证和区域来自 AWS 默认配置链（AWS_ACCESS_KEY_ID、AWS_REGION、~/.aws 等）
+func newOutputSinkFromEnv() (tools.OutputSink, error) {
+	kind := strings.ToLower(os.Getenv("OUTPUT_SINK"))
+	switch kind {
+	case "", OutputSinkFile:
+		return tools.NewFileSink(tools.OutputDir()), nil
+	case OutputSinkMemory:
+		log.Printf("OUTPUT_SINK=memory: 报告和中间结果只保存在内存中，进程退出后丢失")
+		return tools.NewMemorySink(), nil
+	case OutputSinkS3:
+		return newS3SinkFromEnv()
+	}
+	return nil, fmt.Errorf("不支持的 OUTPUT_SINK: %s（可选 %s、%s、%s）", kind, OutputSinkFile, OutputSinkS3, OutputSinkMemory)
+}
+
+// s3Sink 写入 S3 bucket 的输出目标，对象键为 <prefix>/<name>
+type s3Sink struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+// newS3SinkFromEnv 根据 OUTPUT_S3_* 创建 S3 输出目标
+func newS3SinkFromEnv() (*s3Sink, error) {
+	bucket := os.Getenv("OUTPUT_S3_BUCKET")
+	if bucket == "" {
+		return nil, fmt.Errorf("OUTPUT_SINK=s3 需要设置 OUTPUT_S3_BUCKET")
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("加载 AWS 配置失败: %w", err)
+	}
+	endpoint := os.Getenv("OUTPUT_S3_ENDPOINT")
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = true
+		}
+	})
+	return &s3Sink{
+		client: client,
+		bucket: bucket,
+		prefix: strings.Trim(os.Getenv("OUTPUT_S3_PREFIX"), "/"),
+	}, nil
+}
+
+// WriteReport 实现 tools.OutputSink
+func (s *s3Sink) WriteReport(name string, content []byte) (string, error) {
+	return s.put(name, content, "text/markdown; charset=utf-8")
+}
+
+// WriteArtifact 实现 tools.OutputSink
+func (s *s3Sink) WriteArtifact(name string, data []byte) (string, error) {
+	return s.put(name, data, "application/json")
+}
+
+// WriteRunManifest 实现 tools.OutputSink
+func (s *s3Sink) WriteRunManifest(runID string, data []byte) (string, error) {
+	return s.put(tools.RunManifestName(runID), data, "application/json")
+}
+
+func (s *s3Sink) put(name string, data []byte, contentType string) (string, error) {
+	key := name
+	if s.prefix != "" {
+		key = path.Join(s.prefix, name)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s3WriteTimeout)
+	defer cancel()
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return "", fmt.Errorf("上传到 S3 失败: %w", err)
+	}
+	return fmt.Sprintf("s3://%s/%s", s.bucket, key), nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"investment/tools"
+)
+
+// defaultPeerSets 内置的可比公司组，自动按板块匹配的同行往往并不可比（如 AAPL 与硬件制造商）
+var defaultPeerSets = map[string][]string{
+	"AAPL":  {"MSFT", "GOOGL", "META"},
+	"MSFT":  {"AAPL", "GOOGL", "ORCL"},
+	"GOOGL": {"META", "MSFT", "AMZN"},
+	"META":  {"GOOGL", "SNAP", "PINS"},
+	"AMZN":  {"WMT", "GOOGL", "MSFT"},
+	"NVDA":  {"AMD", "AVGO", "INTC"},
+	"TSLA":  {"GM", "F", "RIVN"},
+	"NFLX":  {"DIS", "WBD", "SPOT"},
+	"JPM":   {"BAC", "WFC", "C"},
+	"KO":    {"PEP", "KDP", "MNST"},
+}
+
+// defaultPeerLimit 自动发现可比公司时的最大数量
+const defaultPeerLimit = 5
+
+// PeerService 可比公司组服务
+// 优先使用配置的可比公司组，未配置时按公司画像相似度查找，相似度不可用时基于行业基准股票池按行业/板块自动发现
+type PeerService struct {
+	sets      map[string][]string
+	similar   *SimilarityService // 可以为 nil
+	benchmark *IndustryBenchmarkService
+}
+
+// NewPeerServiceFromEnv 根据环境变量创建可比公司组服务
+// PEER_SETS_FILE: 自定义可比公司组的 JSON 文件，格式为 {"AAPL": ["MSFT", "GOOGL", "META"]}，
+// 与内置组合并，同一股票以文件中的配置为准；默认读取 peers.json（不存在时忽略）
+func NewPeerServiceFromEnv(benchmark *IndustryBenchmarkService, similar *SimilarityService) (*PeerService, error) {
+	sets := make(map[string][]string, len(defaultPeerSets))
+	for symbol, peers := range defaultPeerSets {
+		sets[symbol] = peers
+	}
+
+	path := os.Getenv("PEER_SETS_FILE")
+	explicit := path != ""
+	if !explicit {
+		path = "peers.json"
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		var custom map[string][]string
+		if err := json.Unmarshal(data, &custom); err != nil {
+			return nil, fmt.Errorf("解析可比公司配置 %s 失败: %w", path, err)
+		}
+		for symbol, peers := range custom {
+			sets[strings.ToUpper(strings.TrimSpace(symbol))] = normalizeSymbols(peers)
+		}
+		log.Printf("[Peers] 已加载自定义可比公司组: %s (%d 组)", path, len(custom))
+	case explicit || !os.IsNotExist(err):
+		return nil, fmt.Errorf("读取可比公司配置 %s 失败: %w", path, err)
+	}
+
+	return &PeerService{sets: sets, similar: similar, benchmark: benchmark}, nil
+}
+
+// Get 获取股票的可比公司组，未配置时按画像相似度查找，失败时再按行业/板块自动发现
+func (s *PeerService) Get(symbol string) (*tools.PeerGroup, error) {
+	symbol = strings.ToUpper(symbol)
+	if peers, ok := s.sets[symbol]; ok && len(peers) > 0 {
+		return &tools.PeerGroup{Symbol: symbol, Peers: peers, Source: tools.PeerSourceConfig}, nil
+	}
+
+	if s.similar != nil {
+		peers, err := s.similar.Peers(symbol, defaultPeerLimit)
+		if err == nil && len(peers) > 0 {
+			return &tools.PeerGroup{Symbol: symbol, Peers: peers, Source: tools.PeerSourceSimilarity}, nil
+		}
+		log.Printf("[Peers] 按相似度查找 %s 的可比公司失败，改为按行业发现: %v", symbol, err)
+	}
+
+	peers, level, err := s.benchmark.Peers(symbol, defaultPeerLimit)
+	if err != nil {
+		return nil, fmt.Errorf("未配置 %s 的可比公司组，自动发现失败: %w", symbol, err)
+	}
+	source := tools.PeerSourceIndustry
+	if level == "sector" {
+		source = tools.PeerSourceSector
+	}
+	return &tools.PeerGroup{Symbol: symbol, Peers: peers, Source: source}, nil
+}
+
+// normalizeSymbols 统一股票代码为大写并去掉空值
+func normalizeSymbols(symbols []string) []string {
+	var out []string
+	for _, symbol := range symbols {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if symbol != "" {
+			out = append(out, symbol)
+		}
+	}
+	return out
+}
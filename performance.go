@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"investment/tools"
+)
+
+// performanceHorizon 评级后的观察期
+type performanceHorizon struct {
+	Label  string
+	Months int
+}
+
+// performanceHorizons 统计评级后 1/3/6 个月的实际收益
+var performanceHorizons = []performanceHorizon{
+	{Label: "1M", Months: 1},
+	{Label: "3M", Months: 3},
+	{Label: "6M", Months: 6},
+}
+
+// ratingDirection 评级对应的预期方向：1 看涨，-1 看跌，0 中性（不参与准确率统计）
+var ratingDirection = map[string]int{
+	RatingStrongBuy: 1,
+	RatingBuy:       1,
+	RatingNeutral:   0,
+	RatingCautious:  -1,
+	RatingAvoid:     -1,
+}
+
+// ratingOrder 输出时评级的排列顺序
+var ratingOrder = []string{RatingStrongBuy, RatingBuy, RatingNeutral, RatingCautious, RatingAvoid}
+
+// PerformanceStats 一组评级在某个观察期的表现
+type PerformanceStats struct {
+	Count      int             `json:"count"`      // 观察期已结束的评级数
+	AvgReturn  tools.SafeFloat `json:"avg_return"` // 平均实际收益
+	HitRate    tools.SafeFloat `json:"hit_rate"`   // 方向正确的比例，中性评级为 n/a
+	WinCount   int             `json:"win_count"`  // 方向正确的数量
+	returnsSum float64
+}
+
+// PerformanceGroup 按评级或模型分组的表现统计
+type PerformanceGroup struct {
+	Group    string                       `json:"group"`
+	Runs     int                          `json:"runs"`
+	Horizons map[string]*PerformanceStats `json:"horizons"`
+}
+
+// PerformanceReport 评级表现统计结果
+type PerformanceReport struct {
+	GeneratedAt time.Time           `json:"generated_at"`
+	Runs        int                 `json:"runs"`
+	ByRating    []*PerformanceGroup `json:"by_rating"`
+	ByModel     []*PerformanceGroup `json:"by_model"`
+}
+
+// runPerformance 处理 performance 子命令：统计历史评级在之后 1/3/6 个月的实际收益和方向准确率
+func runPerformance(args []string) error {
+	fs := flag.NewFlagSet("performance", flag.ExitOnError)
+	var filter runFilter
+	fs.StringVar(&filter.Symbol, "symbol", "", "按股票代码筛选")
+	fs.StringVar(&filter.Portfolio, "portfolio", "", "按组合筛选")
+	fs.StringVar(&filter.Tag, "tag", "", "按标签筛选")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	records, err := listRunRecords(filter)
+	if err != nil {
+		return err
+	}
+	var rated []RunRecord
+	for _, r := range records {
+		if r.Rating != "" && !r.Truncated {
+			rated = append(rated, r)
+		}
+	}
+	if len(rated) == 0 {
+		fmt.Println("没有带评级的历史分析记录")
+		return nil
+	}
+
+	report, err := buildPerformanceReport(context.Background(), rated, time.Now())
+	if err != nil {
+		return err
+	}
+	printPerformanceReport(report)
+	if err := savePerformanceReport(report); err != nil {
+		log.Printf("[Performance] 保存统计结果失败: %v", err)
+	}
+	return nil
+}
+
+// buildPerformanceReport 拉取每只股票自最早评级以来的价格，计算各观察期的实际收益
+func buildPerformanceReport(ctx context.Context, records []RunRecord, now time.Time) (*PerformanceReport, error) {
+	// 每只股票只拉取一次价格，代码变更前的记录使用当前代码的价格
+	earliest := make(map[string]time.Time)
+	for _, r := range records {
+		symbol := canonicalSymbol(r.Symbol)
+		if t, ok := earliest[symbol]; !ok || r.StartedAt.Before(t) {
+			earliest[symbol] = r.StartedAt
+		}
+	}
+	prices := make(map[string]*tools.PriceDataFrame)
+	for symbol, start := range earliest {
+		df, err := GetPriceData(ctx, symbol, start.Format("2006-01-02"), now.Format("2006-01-02"))
+		if err != nil {
+			log.Printf("[Performance] 跳过 %s: 获取价格失败: %v", symbol, err)
+			continue
+		}
+		prices[symbol] = df
+	}
+
+	byRating := make(map[string]*PerformanceGroup)
+	byModel := make(map[string]*PerformanceGroup)
+	for _, r := range records {
+		df, ok := prices[canonicalSymbol(r.Symbol)]
+		if !ok {
+			continue
+		}
+		model := r.Model
+		if model == "" {
+			model = "default"
+		}
+		groups := []*PerformanceGroup{performanceGroup(byRating, r.Rating), performanceGroup(byModel, model)}
+		for _, g := range groups {
+			g.Runs++
+		}
+
+		for _, h := range performanceHorizons {
+			end := r.StartedAt.AddDate(0, h.Months, 0)
+			if end.After(now) {
+				continue // 观察期尚未结束
+			}
+			ret, ok := periodReturn(df, r.StartedAt, end)
+			if !ok {
+				continue
+			}
+			hit := ratingDirection[r.Rating]*sign(ret) > 0
+			for _, g := range groups {
+				g.Horizons[h.Label].add(ret, hit)
+			}
+		}
+	}
+
+	report := &PerformanceReport{GeneratedAt: now, Runs: len(records)}
+	for _, rating := range ratingOrder {
+		if g, ok := byRating[rating]; ok {
+			g.finish(ratingDirection[rating] != 0)
+			report.ByRating = append(report.ByRating, g)
+		}
+	}
+	models := make([]string, 0, len(byModel))
+	for model := range byModel {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	for _, model := range models {
+		// 模型分组混合了不同评级，中性评级不计入方向正确数
+		byModel[model].finish(true)
+		report.ByModel = append(report.ByModel, byModel[model])
+	}
+	return report, nil
+}
+
+// performanceGroup 获取或创建分组
+func performanceGroup(groups map[string]*PerformanceGroup, name string) *PerformanceGroup {
+	if g, ok := groups[name]; ok {
+		return g
+	}
+	g := &PerformanceGroup{Group: name, Horizons: make(map[string]*PerformanceStats)}
+	for _, h := range performanceHorizons {
+		g.Horizons[h.Label] = &PerformanceStats{}
+	}
+	groups[name] = g
+	return g
+}
+
+// add 累计一个已结束观察期的收益
+func (s *PerformanceStats) add(ret float64, hit bool) {
+	s.Count++
+	s.returnsSum += ret
+	if hit {
+		s.WinCount++
+	}
+}
+
+// finish 计算平均收益和准确率，directional 为 false 时不计算准确率
+func (g *PerformanceGroup) finish(directional bool) {
+	for _, s := range g.Horizons {
+		s.AvgReturn = tools.SafeDiv(s.returnsSum, float64(s.Count))
+		s.HitRate = tools.NaN()
+		if directional {
+			s.HitRate = tools.SafeDiv(float64(s.WinCount), float64(s.Count))
+		}
+	}
+}
+
+// periodReturn 计算从 start 当天或之后第一个交易日收盘到 end 当天或之前最后一个交易日收盘的收益
+func periodReturn(df *tools.PriceDataFrame, start, end time.Time) (float64, bool) {
+	startDay := start.Truncate(24 * time.Hour)
+	first, last := -1, -1
+	for i, date := range df.Dates {
+		if first < 0 && !date.Before(startDay) {
+			first = i
+		}
+		if !date.After(end) {
+			last = i
+		}
+	}
+	if first < 0 || last <= first {
+		return 0, false
+	}
+	ret := tools.SafeGrowth(df.Close[last], df.Close[first])
+	return float64(ret), ret.Valid()
+}
+
+// sign 返回数值的符号
+func sign(v float64) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
+
+// printPerformanceReport 打印统计表
+func printPerformanceReport(report *PerformanceReport) {
+	fmt.Printf("📈 评级表现统计（共 %d 条带评级的分析）\n", report.Runs)
+	printPerformanceGroups("按评级", report.ByRating)
+	printPerformanceGroups("按模型", report.ByModel)
+	fmt.Println("准确率：看涨评级之后上涨、看跌评级之后下跌视为方向正确；中性评级不计入准确率")
+}
+
+// printPerformanceGroups 打印一组统计
+func printPerformanceGroups(title string, groups []*PerformanceGroup) {
+	fmt.Printf("\n%s:\n", title)
+	header := fmt.Sprintf("%-10s %5s", "分组", "次数")
+	for _, h := range performanceHorizons {
+		header += fmt.Sprintf("  %-24s", h.Label+" 样本/平均收益/准确率")
+	}
+	fmt.Println(header)
+	for _, g := range groups {
+		line := fmt.Sprintf("%-10s %5d", g.Group, g.Runs)
+		for _, h := range performanceHorizons {
+			s := g.Horizons[h.Label]
+			cell := fmt.Sprintf("%d / %s / %s", s.Count, percentText(s.AvgReturn), percentText(s.HitRate))
+			line += fmt.Sprintf("  %-24s", cell)
+		}
+		fmt.Println(line)
+	}
+}
+
+// percentText 将比例格式化为百分比，不可用时输出 n/a
+func percentText(v tools.SafeFloat) string {
+	if !v.Valid() {
+		return tools.NotAvailable
+	}
+	return fmt.Sprintf("%.1f%%", float64(v)*100)
+}
+
+// savePerformanceReport 将统计结果保存为JSON文件，写入当前输出目标
+func savePerformanceReport(report *PerformanceReport) error {
+	name := fmt.Sprintf("performance/performance_%s.json", report.GeneratedAt.Format("2006-01-02_15-04-05"))
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSON序列化失败: %v", err)
+	}
+	location, err := tools.Output().WriteArtifact(name, data)
+	if err != nil {
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+
+	log.Printf("[Performance] 统计结果已保存到: %s", location)
+	return nil
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// 分析深度，--depth / ANALYSIS_DEPTH 的取值，决定计划中包含哪些工具
+const (
+	DepthQuick    = "quick"    // 只看公司概况、核心财务、新闻和估值
+	DepthStandard = "standard" // 增加信用、营运资本、内部人、管理层、同行和交易层面的检查
+	DepthFull     = "full"     // 全部工具（默认，与没有计划步骤时相同）
+)
+
+// analysisDepths 分析深度从浅到深
+var analysisDepths = []string{DepthQuick, DepthStandard, DepthFull}
+
+// validateDepth 检查 --depth / ANALYSIS_DEPTH 的取值
+func validateDepth(depth string) error {
+	if !slices.Contains(analysisDepths, depth) {
+		return fmt.Errorf("无效的分析深度: %s（可选 %s）", depth, strings.Join(analysisDepths, "、"))
+	}
+	return nil
+}
+
+// planStage 计划中的一个阶段，阶段按顺序执行，阶段内的工具可以在同一轮并行调用
+type planStage struct {
+	Name  string
+	Goal  string // 提示词中说明这一阶段要回答的问题
+	Rules []planRule
+}
+
+// planRule 一个工具进入计划的条件
+type planRule struct {
+	Tool      string
+	Depth     string // 最浅需要的分析深度
+	Filings   bool   // 依赖 10-K 年报章节，数据源只覆盖美股
+	Insider   bool   // 依赖内部人交易数据，数据源只覆盖美股
+	Market    bool   // 依赖行情数据，只有导入财务报表的未上市公司没有
+	Portfolio bool   // 只在组合分析时有意义
+}
+
+// analysisPlanStages 默认的分析顺序：先了解公司和价格，再看财务质量和风险，然后是新闻治理、同行比较，最后估值
+var analysisPlanStages = []planStage{
+	{Name: "公司与行情", Goal: "公司做什么、当前价格和规模", Rules: []planRule{
+		{Tool: "get_company_profile", Depth: DepthQuick},
+		{Tool: "get_quote", Depth: DepthQuick, Market: true},
+		{Tool: "get_market_cap", Depth: DepthQuick, Market: true},
+	}},
+	{Name: "财务质量", Goal: "盈利能力、成长性、资产负债表和现金流是否健康", Rules: []planRule{
+		{Tool: "get_financial_metrics", Depth: DepthQuick},
+		{Tool: "analyze_fundamentals", Depth: DepthQuick},
+		{Tool: "assess_credit_risk", Depth: DepthStandard},
+		{Tool: "analyze_working_capital", Depth: DepthStandard},
+	}},
+	{Name: "新闻与治理", Goal: "近期事件、管理层行为和法律风险", Rules: []planRule{
+		{Tool: "get_company_news", Depth: DepthQuick},
+		{Tool: "get_insider_trades", Depth: DepthStandard, Insider: true},
+		{Tool: "assess_management", Depth: DepthStandard, Insider: true},
+		{Tool: "track_legal_risks", Depth: DepthStandard, Filings: true},
+		{Tool: "summarize_news_timeline", Depth: DepthFull},
+		{Tool: "summarize_dataset", Depth: DepthFull},
+		{Tool: "track_corporate_actions", Depth: DepthFull, Filings: true},
+		{Tool: "extract_dependencies", Depth: DepthFull, Filings: true},
+	}},
+	{Name: "同行与竞争", Goal: "与可比公司相比处于什么位置", Rules: []planRule{
+		{Tool: "compare_peers", Depth: DepthStandard},
+		{Tool: "analyze_competition", Depth: DepthFull},
+		{Tool: "find_similar_companies", Depth: DepthFull},
+		{Tool: "get_index_constituents", Depth: DepthFull},
+	}},
+	{Name: "估值与交易", Goal: "价格是否合理、能否顺利建仓", Rules: []planRule{
+		{Tool: "get_price_history_stats", Depth: DepthStandard, Market: true},
+		{Tool: "monte_carlo_valuation", Depth: DepthQuick},
+		{Tool: "check_price_target", Depth: DepthStandard, Market: true},
+		{Tool: "assess_liquidity", Depth: DepthStandard, Market: true},
+		{Tool: "analyze_portfolio_correlation", Depth: DepthQuick, Market: true, Portfolio: true},
+	}},
+}
+
+// AnalysisPlan 分析开始前确定的工具和调用顺序，记录在运行清单中
+type AnalysisPlan struct {
+	Depth   string            `json:"depth"`
+	Market  string            `json:"market"`
+	Stages  []PlannedStage    `json:"stages"`
+	Skipped []PlanSkippedTool `json:"skipped,omitempty"`
+}
+
+// PlannedStage 计划中的一个阶段和要调用的工具
+type PlannedStage struct {
+	Name  string   `json:"name"`
+	Tools []string `json:"tools"`
+}
+
+// PlanSkippedTool 按计划不提供给 Agent 的工具和原因
+type PlanSkippedTool struct {
+	Tool   string `json:"tool"`
+	Reason string `json:"reason"`
+}
+
+// planInputs 决定计划的条件
+type planInputs struct {
+	Depth     string
+	Market    string // 市场配置名称（us/cn/hk）
+	Private   bool   // 使用导入的财务报表，没有行情数据
+	Portfolio bool   // 组合分析
+}
+
+// analysisDepth 本次分析的深度：参数为空时使用 ANALYSIS_DEPTH，都未设置时为 full
+func analysisDepth(depth string) (string, error) {
+	if depth == "" {
+		depth = os.Getenv("ANALYSIS_DEPTH")
+	}
+	if depth == "" {
+		return DepthFull, nil
+	}
+	depth = strings.ToLower(strings.TrimSpace(depth))
+	return depth, validateDepth(depth)
+}
+
+// planAnalysis 根据分析深度、市场和可用数据生成计划；没有规则的工具（如自定义工具）总是保留，放在最后一个阶段
+func planAnalysis(in planInputs, available []string) *AnalysisPlan {
+	plan := &AnalysisPlan{Depth: in.Depth, Market: in.Market}
+	depthRank := slices.Index(analysisDepths, in.Depth)
+	planned := make(map[string]bool)
+	for _, stage := range analysisPlanStages {
+		next := PlannedStage{Name: stage.Name}
+		for _, rule := range stage.Rules {
+			if !slices.Contains(available, rule.Tool) {
+				continue
+			}
+			planned[rule.Tool] = true
+			if reason := rule.skipReason(in, depthRank); reason != "" {
+				plan.Skipped = append(plan.Skipped, PlanSkippedTool{Tool: rule.Tool, Reason: reason})
+				continue
+			}
+			next.Tools = append(next.Tools, rule.Tool)
+		}
+		if len(next.Tools) > 0 {
+			plan.Stages = append(plan.Stages, next)
+		}
+	}
+	var unplanned []string
+	for _, name := range available {
+		if !planned[name] {
+			unplanned = append(unplanned, name)
+		}
+	}
+	if len(unplanned) > 0 {
+		plan.Stages = append(plan.Stages, PlannedStage{Name: "其他", Tools: unplanned})
+	}
+	return plan
+}
+
+// skipReason 工具不进入计划的原因，进入计划时返回空字符串
+func (r planRule) skipReason(in planInputs, depthRank int) string {
+	switch {
+	case r.Portfolio && !in.Portfolio:
+		return "不是组合分析"
+	case r.Market && in.Private:
+		return "使用导入的财务报表，没有行情数据"
+	case r.Insider && in.Market != MarketUS:
+		return "数据源只提供美股的内部人交易"
+	case r.Filings && in.Market != MarketUS:
+		return "数据源只提供美股的 10-K 年报章节"
+	case slices.Index(analysisDepths, r.Depth) > depthRank:
+		return fmt.Sprintf("分析深度 %s 不包含（需要 %s）", in.Depth, r.Depth)
+	}
+	return ""
+}
+
+// includes 工具是否在计划中
+func (p *AnalysisPlan) includes(name string) bool {
+	for _, stage := range p.Stages {
+		if slices.Contains(stage.Tools, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// toolNames 工具的名称，与 Agent 看到的一致
+func toolNames(ctx context.Context, all []tool.BaseTool) ([]string, error) {
+	names := make([]string, len(all))
+	for i, t := range all {
+		info, err := t.Info(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("读取工具信息失败: %v", err)
+		}
+		names[i] = info.Name
+	}
+	return names, nil
+}
+
+// filterTools 只保留计划中的工具，Agent 看不到被跳过的工具，也就不会调用
+func (p *AnalysisPlan) filterTools(ctx context.Context, all []tool.BaseTool) ([]tool.BaseTool, error) {
+	var kept []tool.BaseTool
+	for _, t := range all {
+		info, err := t.Info(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("读取工具信息失败: %v", err)
+		}
+		if p.includes(info.Name) {
+			kept = append(kept, t)
+		}
+	}
+	return kept, nil
+}
+
+// promptContext 追加到用户提示词的分析计划：按阶段顺序调用工具，说明被跳过的工具，避免 Agent 自行猜测顺序或反复尝试不可用的数据
+func (p *AnalysisPlan) promptContext() string {
+	goals := make(map[string]string)
+	for _, stage := range analysisPlanStages {
+		goals[stage.Name] = stage.Goal
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "## 分析计划（深度：%s）\n\n请按以下阶段顺序收集数据，同一阶段内的工具可以在同一轮中并行调用；前一阶段的结果足以回答后面的问题时可以少调用，但不要跳过整个阶段：\n", p.Depth)
+	for i, stage := range p.Stages {
+		fmt.Fprintf(&sb, "%d. %s", i+1, stage.Name)
+		if goal := goals[stage.Name]; goal != "" {
+			fmt.Fprintf(&sb, "（%s）", goal)
+		}
+		fmt.Fprintf(&sb, "：%s\n", strings.Join(stage.Tools, "、"))
+	}
+	if len(p.Skipped) > 0 {
+		sb.WriteString("\n以下数据本次不提供，报告中如需提及请说明原因，不要臆测：\n")
+		for _, s := range p.Skipped {
+			fmt.Fprintf(&sb, "- %s：%s\n", s.Tool, s.Reason)
+		}
+	}
+	return sb.String()
+}
+
+// summary 终端和日志中的一行计划摘要
+func (p *AnalysisPlan) summary() string {
+	n := 0
+	for _, stage := range p.Stages {
+		n += len(stage.Tools)
+	}
+	return fmt.Sprintf("深度 %s，%d 个阶段 %d 个工具，跳过 %d 个", p.Depth, len(p.Stages), n, len(p.Skipped))
+}
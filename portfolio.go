@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"investment/tools"
+)
+
+// portfolioDir 组合持仓的保存目录
+var portfolioDir = filepath.Join("output", "portfolio")
+
+// defaultPortfolioName 未指定组合名称时使用的组合
+const defaultPortfolioName = "default"
+
+// Position 单个持仓
+type Position struct {
+	Symbol    string   `json:"symbol"`
+	Quantity  float64  `json:"quantity"`
+	CostBasis float64  `json:"cost_basis"` // 每股平均成本
+	Currency  string   `json:"currency"`
+	Source    string   `json:"source"`         // 持仓来源：券商名称或 manual
+	Lots      []TaxLot `json:"lots,omitempty"` // 按买入批次记录的持仓明细，用于区分长期/短期持有
+}
+
+// Portfolio 投资组合持仓
+type Portfolio struct {
+	Name      string             `json:"name"`
+	Positions []Position         `json:"positions"`
+	Realized  []RealizedLot      `json:"realized,omitempty"` // 已卖出的批次，用于计算已实现盈亏
+	Targets   map[string]float64 `json:"targets,omitempty"`  // 再平衡的目标权重，如 {"AAPL": 0.3}
+	UpdatedAt time.Time          `json:"updated_at"`
+}
+
+// LoadPortfolio 读取组合持仓，文件不存在时返回空组合
+func LoadPortfolio(name string) (*Portfolio, error) {
+	data, err := os.ReadFile(portfolioPath(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Portfolio{Name: name}, nil
+		}
+		return nil, fmt.Errorf("读取组合失败: %v", err)
+	}
+	var portfolio Portfolio
+	if err := json.Unmarshal(data, &portfolio); err != nil {
+		return nil, fmt.Errorf("解析组合 %s 失败: %v", name, err)
+	}
+	return &portfolio, nil
+}
+
+// Save 保存组合持仓
+func (p *Portfolio) Save() error {
+	if err := os.MkdirAll(portfolioDir, 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %v", err)
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSON序列化失败: %v", err)
+	}
+	if err := os.WriteFile(portfolioPath(p.Name), data, 0644); err != nil {
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+	return nil
+}
+
+// ReplaceSource 用新的持仓替换指定来源的全部持仓，其他来源的持仓保持不变
+// 券商接口不返回买入批次，新持仓沿用该来源同一股票此前手工维护的批次
+func (p *Portfolio) ReplaceSource(source string, positions []Position) {
+	kept := make([]Position, 0, len(p.Positions)+len(positions))
+	previousLots := make(map[string][]TaxLot)
+	for _, position := range p.Positions {
+		if position.Source != source {
+			kept = append(kept, position)
+		} else if len(position.Lots) > 0 {
+			previousLots[position.Symbol] = position.Lots
+		}
+	}
+	for i := range positions {
+		if len(positions[i].Lots) == 0 {
+			positions[i].Lots = previousLots[positions[i].Symbol]
+		}
+	}
+	kept = append(kept, positions...)
+	sort.Slice(kept, func(i, j int) bool {
+		if kept[i].Symbol != kept[j].Symbol {
+			return kept[i].Symbol < kept[j].Symbol
+		}
+		return kept[i].Source < kept[j].Source
+	})
+	p.Positions = kept
+	p.UpdatedAt = time.Now()
+}
+
+// Symbols 返回组合中的全部股票代码（去重）
+func (p *Portfolio) Symbols() []string {
+	var symbols []string
+	seen := make(map[string]bool)
+	for _, position := range p.Positions {
+		if !seen[position.Symbol] {
+			seen[position.Symbol] = true
+			symbols = append(symbols, position.Symbol)
+		}
+	}
+	return symbols
+}
+
+// Currencies 返回各股票的持仓币种
+func (p *Portfolio) Currencies() map[string]string {
+	currencies := make(map[string]string)
+	for _, position := range p.Positions {
+		if currencies[position.Symbol] == "" {
+			currencies[position.Symbol] = position.Currency
+		}
+	}
+	return currencies
+}
+
+// BaseCurrency 组合汇总金额使用的币种：第一个标明币种的持仓的币种，默认 USD
+func (p *Portfolio) BaseCurrency() string {
+	for _, position := range p.Positions {
+		if position.Currency != "" {
+			return position.Currency
+		}
+	}
+	return "USD"
+}
+
+// portfolioPath 组合持仓文件路径
+func portfolioPath(name string) string {
+	return filepath.Join(portfolioDir, name+".json")
+}
+
+// validPortfolioName 组合名称会作为文件名使用，不能包含路径分隔符
+func validPortfolioName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+// runPortfolio 处理 portfolio 子命令
+// portfolio import <broker> [--name default]：从券商 API 导入当前持仓
+// portfolio show [--name default]：显示组合持仓
+// portfolio report [--name default] [--years 1]：生成包含分散化分析的组合报告
+// portfolio rebalance [--name default] [--targets AAPL=0.3,MSFT=0.2] [--max-weight 0] [--min-trade 0] [--max-turnover 0] [--cash 0]：生成再平衡交易建议
+func runPortfolio(args []string) error {
+	ctx := context.Background()
+	if len(args) == 0 {
+		return fmt.Errorf("用法: portfolio import <%s> [--name default] | portfolio show [--name default] | portfolio report [--name default] [--years 1] | portfolio rebalance [--name default] [--targets AAPL=0.3,MSFT=0.2] [--max-weight 0.25] [--min-trade 100] [--max-turnover 0.2] [--cash 0]", strings.Join(brokerNames(), "|"))
+	}
+
+	switch args[0] {
+	case "import":
+		if len(args) < 2 {
+			return fmt.Errorf("请指定券商: %s", strings.Join(brokerNames(), ", "))
+		}
+		fs := flag.NewFlagSet("portfolio import", flag.ExitOnError)
+		name := fs.String("name", defaultPortfolioName, "导入到的组合名称")
+		if err := fs.Parse(args[2:]); err != nil {
+			return err
+		}
+		return importPortfolio(ctx, args[1], *name)
+
+	case "show":
+		fs := flag.NewFlagSet("portfolio show", flag.ExitOnError)
+		name := fs.String("name", defaultPortfolioName, "组合名称")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if !validPortfolioName(*name) {
+			return fmt.Errorf("无效的组合名称: %s", *name)
+		}
+		portfolio, err := LoadPortfolio(*name)
+		if err != nil {
+			return err
+		}
+		printPortfolio(portfolio)
+		return nil
+
+	case "report":
+		fs := flag.NewFlagSet("portfolio report", flag.ExitOnError)
+		name := fs.String("name", defaultPortfolioName, "组合名称")
+		years := fs.Int("years", 1, fmt.Sprintf("相关性分析回溯年数（最多%d年）", tools.MaxCorrelationYears))
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if !validPortfolioName(*name) {
+			return fmt.Errorf("无效的组合名称: %s", *name)
+		}
+		if *years <= 0 || *years > tools.MaxCorrelationYears {
+			return fmt.Errorf("回溯年数需在 1-%d 之间: %d", tools.MaxCorrelationYears, *years)
+		}
+		portfolio, err := LoadPortfolio(*name)
+		if err != nil {
+			return err
+		}
+		return reportPortfolio(ctx, portfolio, *years)
+
+	case "rebalance":
+		fs := flag.NewFlagSet("portfolio rebalance", flag.ExitOnError)
+		name := fs.String("name", defaultPortfolioName, "组合名称")
+		targets := fs.String("targets", "", "目标权重，如 AAPL=0.3,MSFT=20%（默认使用组合文件中的 targets，都没有时以当前权重为目标）")
+		maxWeight := fs.Float64("max-weight", 0, "单只股票的权重上限，如 0.25（0 表示不限制）")
+		minTrade := fs.Float64("min-trade", 0, "最小交易金额，低于该金额的交易不执行")
+		maxTurnover := fs.Float64("max-turnover", 0, "换手率上限（买卖金额之和 / 组合总值），如 0.2（0 表示不限制）")
+		cash := fs.Float64("cash", 0, "可用现金，计入组合总值")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if !validPortfolioName(*name) {
+			return fmt.Errorf("无效的组合名称: %s", *name)
+		}
+		if *maxWeight < 0 || *maxWeight > 1 || *minTrade < 0 || *maxTurnover < 0 || *cash < 0 {
+			return fmt.Errorf("max-weight 需在 0-1 之间，min-trade、max-turnover 和 cash 不能为负数")
+		}
+		portfolio, err := LoadPortfolio(*name)
+		if err != nil {
+			return err
+		}
+		return rebalancePortfolio(ctx, portfolio, *targets, rebalanceOptions{
+			MaxWeight:   *maxWeight,
+			MinTrade:    *minTrade,
+			MaxTurnover: *maxTurnover,
+			Cash:        *cash,
+		})
+	}
+	return fmt.Errorf("未知的 portfolio 子命令: %s", args[0])
+}
+
+// importPortfolio 从券商拉取持仓并写入组合，替换该券商此前导入的持仓
+func importPortfolio(ctx context.Context, broker, name string) error {
+	if !validPortfolioName(name) {
+		return fmt.Errorf("无效的组合名称: %s", name)
+	}
+	importer, err := NewBrokerImporter(broker)
+	if err != nil {
+		return err
+	}
+	positions, err := importer.FetchPositions(ctx)
+	if err != nil {
+		return fmt.Errorf("从 %s 导入持仓失败: %w", broker, err)
+	}
+
+	portfolio, err := LoadPortfolio(name)
+	if err != nil {
+		return err
+	}
+	portfolio.ReplaceSource(importer.Name(), positions)
+	if err := portfolio.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("📥 已从 %s 导入 %d 个持仓到组合 %s: %s\n", importer.Name(), len(positions), name, portfolioPath(name))
+	return nil
+}
+
+// reportPortfolio 生成组合报告：持仓市值和权重、收益率相关性和组合波动率、风格因子暴露，以及按批次的税务盈亏
+// 权重按最新收盘价计算的持仓市值确定，报告保存到 output/report/<name>/portfolio_report.md
+func reportPortfolio(ctx context.Context, p *Portfolio, years int) error {
+	quantities := make(map[string]float64)
+	for _, position := range p.Positions {
+		quantities[position.Symbol] += position.Quantity
+	}
+	symbols := p.Symbols()
+	if len(symbols) < 2 {
+		return fmt.Errorf("组合 %s 的持仓不足2只，无法进行分散化分析", p.Name)
+	}
+
+	var series []tools.PriceSeries
+	var weights []float64
+	var missing []string
+	for _, symbol := range symbols {
+		s, err := GetPriceSeries(ctx, symbol, years)
+		if err != nil || len(s.Close) == 0 {
+			log.Printf("获取 %s 价格失败: %v", symbol, err)
+			missing = append(missing, symbol)
+			continue
+		}
+		series = append(series, *s)
+		weights = append(weights, quantities[symbol]*s.Close[len(s.Close)-1])
+	}
+	correlation, err := tools.ComputePortfolioCorrelation(series, weights)
+	if err != nil {
+		return fmt.Errorf("分散化分析失败: %w", err)
+	}
+	correlation.Missing = missing
+
+	format, err := tools.NewNumberFormat(os.Getenv("REPORT_LOCALE"))
+	if err != nil {
+		return err
+	}
+	currencies := p.Currencies()
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# 组合 %s 报告\n\n生成时间: %s\n\n", p.Name, time.Now().Format("2006-01-02 15:04:05")))
+	sb.WriteString("## 持仓\n\n| 股票 | 数量 | 最新市值 | 权重 |\n|---|---|---|---|\n")
+	for i, s := range series {
+		sb.WriteString(fmt.Sprintf("| %s | %.4g | %s | %.1f%% |\n", s.Symbol, quantities[s.Symbol], format.Money(weights[i], currencies[s.Symbol]), correlation.Weights[i]*100))
+	}
+	sb.WriteString("\n")
+	sb.WriteString(tools.RenderPortfolioCorrelation(correlation))
+	if exposure, err := portfolioFactorExposure(ctx, series, correlation.Weights); err != nil {
+		log.Printf("因子暴露分析失败: %v", err)
+	} else {
+		sb.WriteString("\n")
+		sb.WriteString(tools.RenderFactorExposure(exposure))
+	}
+	latestPrices := make(map[string]float64, len(series))
+	for _, s := range series {
+		latestPrices[s.Symbol] = s.Close[len(s.Close)-1]
+	}
+	sb.WriteString("\n")
+	sb.WriteString(renderTaxGains(computeTaxGains(p, latestPrices, time.Now()), format))
+	report := sb.String()
+	fmt.Print(report)
+
+	location, err := tools.Output().WriteReport(path.Join("report", p.Name, "portfolio_report.md"), []byte(report))
+	if err != nil {
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+	fmt.Printf("📄 组合报告已保存: %s\n", location)
+	return nil
+}
+
+// portfolioFactorExposure 获取各持仓最新的 TTM 财务指标，结合价格序列计算组合风格因子暴露
+// 某只股票的财务指标获取失败时只缺少价值、成长、质量得分，不影响其他持仓
+func portfolioFactorExposure(ctx context.Context, series []tools.PriceSeries, weights []float64) (*tools.PortfolioFactorExposure, error) {
+	today := time.Now().Format("2006-01-02")
+	holdings := make([]tools.FactorHolding, len(series))
+	for i := range series {
+		holdings[i] = tools.FactorHolding{Symbol: series[i].Symbol, Weight: weights[i], Prices: &series[i]}
+		metrics, err := GetFinancialMetrics(ctx, series[i].Symbol, today, "ttm", 1)
+		if err != nil || len(metrics) == 0 {
+			log.Printf("获取 %s 财务指标失败: %v", series[i].Symbol, err)
+			continue
+		}
+		holdings[i].Metrics = &metrics[0]
+	}
+	return tools.ComputeFactorExposure(holdings)
+}
+
+// printPortfolio 打印组合持仓
+func printPortfolio(p *Portfolio) {
+	if len(p.Positions) == 0 {
+		fmt.Printf("组合 %s 没有持仓\n", p.Name)
+		return
+	}
+	fmt.Printf("组合 %s（更新于 %s）\n", p.Name, p.UpdatedAt.Format("2006-01-02 15:04:05"))
+	for _, position := range p.Positions {
+		fmt.Printf("%-8s  数量=%-12.4g  成本=%-10.2f  %s  来源=%s\n", position.Symbol, position.Quantity, position.CostBasis, position.Currency, position.Source)
+	}
+}
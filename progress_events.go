@@ -0,0 +1,223 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// ProgressEventType 分析进度事件类型
+type ProgressEventType string
+
+const (
+	EventStepStarted      ProgressEventType = "step_started"      // 模型开始新一轮推理
+	EventToolCalled       ProgressEventType = "tool_called"       // 工具调用返回结果
+	EventTokensStreamed   ProgressEventType = "tokens_streamed"   // 模型输出流式推进（已节流）
+	EventSectionCompleted ProgressEventType = "section_completed" // 报告中的一个 markdown 章节输出完成
+	EventAnalysisFinished ProgressEventType = "analysis_finished" // 分析结束（包括超时截断）
+)
+
+// ProgressEvent 分析进度事件，供 TUI、Web、机器人等嵌入方渲染进度，无需解析标准输出
+type ProgressEvent struct {
+	Type          ProgressEventType `json:"type"`
+	Time          time.Time         `json:"time"`
+	Step          int               `json:"step"`                     // 当前推理轮次，从 1 开始
+	Tool          string            `json:"tool,omitempty"`           // tool_called 时的工具名
+	Preview       string            `json:"preview,omitempty"`        // tool_called 时工具返回结果的一行预览
+	StreamedChars int               `json:"streamed_chars,omitempty"` // tokens_streamed 时本轮已输出的字符数
+	Section       string            `json:"section,omitempty"`        // section_completed 时的章节标题
+	Truncated     bool              `json:"truncated,omitempty"`      // analysis_finished 时是否因超时截断
+}
+
+// ProgressFunc 接收进度事件的回调，在分析的后台协程中同步调用，应尽快返回
+type ProgressFunc func(ProgressEvent)
+
+// ProgressChannel 将进度事件转发到 channel，channel 已满时丢弃事件，不阻塞分析
+func ProgressChannel(ch chan<- ProgressEvent) ProgressFunc {
+	return func(event ProgressEvent) {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// defaultProgressThrottle tokens_streamed 事件的最小间隔
+const defaultProgressThrottle = 200 * time.Millisecond
+
+// progressEmitter 发送进度事件，tokens_streamed 事件按间隔节流，其他事件全部发送
+// 为 nil 时所有方法都不做任何事
+type progressEmitter struct {
+	mu         sync.Mutex
+	handle     ProgressFunc
+	throttle   time.Duration
+	step       int
+	lastStream time.Time
+	closed     bool // 分析结束（包括超时后后台协程仍在运行）后不再发送事件
+}
+
+// newProgressEmitter handle 为 nil 时返回 nil
+func newProgressEmitter(handle ProgressFunc, throttle time.Duration) *progressEmitter {
+	if handle == nil {
+		return nil
+	}
+	return &progressEmitter{handle: handle, throttle: throttle}
+}
+
+func (e *progressEmitter) emit(event ProgressEvent) {
+	if e.closed {
+		return
+	}
+	event.Time = time.Now()
+	event.Step = e.step
+	e.handle(event)
+}
+
+func (e *progressEmitter) stepStarted() {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.step++
+	e.lastStream = time.Time{}
+	e.emit(ProgressEvent{Type: EventStepStarted})
+}
+
+func (e *progressEmitter) toolCalled(tool, preview string) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.emit(ProgressEvent{Type: EventToolCalled, Tool: tool, Preview: preview})
+}
+
+func (e *progressEmitter) tokensStreamed(chars int) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if time.Since(e.lastStream) < e.throttle {
+		return
+	}
+	e.lastStream = time.Now()
+	e.emit(ProgressEvent{Type: EventTokensStreamed, StreamedChars: chars})
+}
+
+func (e *progressEmitter) sectionCompleted(section string) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.emit(ProgressEvent{Type: EventSectionCompleted, Section: section})
+}
+
+func (e *progressEmitter) finished(truncated bool) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.emit(ProgressEvent{Type: EventAnalysisFinished, Truncated: truncated})
+	e.closed = true
+}
+
+// completedSection 输出完成的 markdown 章节
+type completedSection struct {
+	Title    string
+	Duration time.Duration // 从标题出现到章节结束的用时
+}
+
+// sectionTracker 从流式输出中识别 markdown 标题，新标题出现时上一章节视为完成
+type sectionTracker struct {
+	line    strings.Builder
+	current string
+	started time.Time
+}
+
+// feed 输入一段增量内容，返回已完成的章节
+func (t *sectionTracker) feed(delta string) []completedSection {
+	var completed []completedSection
+	for _, r := range delta {
+		if r != '\n' {
+			t.line.WriteRune(r)
+			continue
+		}
+		line := strings.TrimSpace(t.line.String())
+		t.line.Reset()
+		if !strings.HasPrefix(line, "#") {
+			continue
+		}
+		now := time.Now()
+		if t.current != "" {
+			completed = append(completed, completedSection{Title: t.current, Duration: now.Sub(t.started)})
+		}
+		t.current = strings.TrimSpace(strings.TrimLeft(line, "#"))
+		t.started = now
+	}
+	return completed
+}
+
+// flush 消息结束时返回最后一个章节
+func (t *sectionTracker) flush() []completedSection {
+	completed := t.feed("\n")
+	if t.current != "" {
+		completed = append(completed, completedSection{Title: t.current, Duration: time.Since(t.started)})
+		t.current = ""
+	}
+	return completed
+}
+
+// readMessageStream 逐块读取一条消息的流，同时发送进度事件并输出到终端，返回拼接后的完整消息
+// printer 为 nil 时不输出
+func readMessageStream(s *schema.StreamReader[*schema.Message], events *progressEmitter, printer *terminalPrinter) (*schema.Message, error) {
+	defer s.Close()
+
+	var chunks []*schema.Message
+	var sections sectionTracker
+	chars := 0
+	for {
+		chunk, err := s.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(chunks) == 0 && chunk.Role != schema.Tool {
+			events.stepStarted()
+		}
+		chunks = append(chunks, chunk)
+
+		if chunk.Role != schema.Tool && chunk.Content != "" {
+			chars += utf8.RuneCountInString(chunk.Content)
+			printer.delta(chunk.Content)
+			events.tokensStreamed(chars)
+			for _, section := range sections.feed(chunk.Content) {
+				printer.sectionCompleted(section)
+				events.sectionCompleted(section.Title)
+			}
+		}
+	}
+	if chars > 0 {
+		printer.messageEnd()
+	}
+	for _, section := range sections.flush() {
+		printer.sectionCompleted(section)
+		events.sectionCompleted(section.Title)
+	}
+
+	msg, err := schema.ConcatMessages(chunks)
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 提示词文件，位于 PROMPTS_DIR（默认 prompts/）下，不存在时使用内置提示词
+const (
+	systemPromptFile = "system.md"
+	userPromptFile   = "user.md" // {symbol} 会被替换为股票代码
+)
+
+// defaultSystemPrompt 内置的系统提示词，指导 Agent 进行投资分析
+const defaultSystemPrompt = `你是一个专业的股票投资分析师，具有深厚的价值投资理念和丰富的分析经验。你会系统性地收集和分析数据，遵循严格的投资分析流程。
+
+## 你可以使用的工具：
+
+- get_market_cap: 获取股票市值信息
+- get_quote: 获取实时行情（最新价、当日涨跌、成交量、52周最高/最低价），盘前/盘后显著异动时附带相关新闻标题
+- get_company_profile: 获取公司业务描述（来自年报业务章节或官网）以及板块、行业、员工人数等基本信息
+- get_financial_metrics: 获取财务指标数据（ROE、债务比率、营运利润率等）
+- assess_credit_risk: 计算 Altman Z''-score、利息保障倍数趋势，以及计入经营租赁和养老金缺口的调整债务股权比和调整债务/EBITDA，给出破产与信用风险结论（safe/grey/distress）
+- analyze_working_capital: 计算最近8个季度的 DSO/DIO/DPO 和现金转换周期，与去年同季度比较并标记营运资本恶化
+- get_company_news: 获取公司最新新闻动态，新闻已按主题分类（业绩财报、并购重组、诉讼、监管、产品业务、管理层）
+- get_insider_trades: 获取指定日期窗口内的内部人交易记录及净买卖汇总
+- assess_management: 综合内部人持股、股权激励、股本变化、资本配置（回购/分红/并购）和高管变动，给出管理层质量评分（满分10）
+- get_price_history_stats: 获取最长20年的价格历史，计算年化复合收益率、最大回撤和年化波动率
+- summarize_dataset: 拉取长周期（如一年）的全部新闻或内部人交易并返回摘要（按月分布、主题分布、情绪分布、净买卖）
+- summarize_news_timeline: 对6到12个月的全部新闻分块摘要再按季度合并，返回按季度排列的叙事时间线和关键事件
+- track_legal_risks: 检索过去2年的诉讼、监管处罚和调查事件，并维护风险登记簿
+- track_corporate_actions: 整理过去5年的收购、出售、分拆和合并历史，汇总交易金额、整合结果以及并购支出占自由现金流的比例
+- extract_dependencies: 从年报中提取主要客户、供应商及集中度披露
+- analyze_fundamentals: 进行巴菲特式基本面分析，并与行业中位数对比
+- compare_peers: 将关键财务指标与可比公司对比，给出组内排名和可比公司中位数
+- analyze_competition: 获取3到5家主要竞争对手的业务描述和指标，评估市场地位和定价权，给出竞争地位评分（满分10）
+- find_similar_companies: 按公司画像（行业、业务描述和财务指标）在股票池中查找最相似的公司
+- analyze_portfolio_correlation: 计算组合内股票的收益率相关系数矩阵、组合波动率和分散化比率（组合分析时使用）
+- get_index_constituents: 获取标普500、纳斯达克100、沪深300的成分股列表
+- monte_carlo_valuation: 对增长率、净利率和退出市盈率进行蒙特卡洛模拟，得到合理价值分布（P10/P50/P90）
+- check_price_target: 将你给出的目标价分解为隐含市盈率和隐含 EPS 增长率，与历史区间和可比公司比较，检查目标价是否合理
+- assess_liquidity: 计算近3个月的日均成交额、估算买卖价差、自由流通比例和换手率，给出流动性结论（liquid/thin/illiquid）和可承受的最大仓位
+
+## 分析步骤：
+
+- 互不依赖的数据（如市值、财务指标、新闻）请在同一轮中同时调用多个工具获取，以缩短分析时间
+
+- 先思考分析计划，然后获取股票基本信息（市值、实时行情）和公司简介
+- 估值部分的当前股价、上涨空间和市值以实时行情工具返回的价格为准（市值按该价格和总股本重新计算），不要使用财务指标中滞后的市值或估值倍数推算当前股价；realtime 为 false 时注明价格为最近收盘价
+- 实时行情结果中 extended_move_flag 为 true（盘前/盘后涨跌超过3%）时，结合返回的 headlines 说明异动的原因和对投资逻辑的影响，必要时用新闻工具查看详情；报告开头会自动附加异动提示，估值结论需说明是否已考虑该异动
+- 获取财务指标数据，重点关注过去5年的趋势
+- 财务指标结果中 credit_risk_required 为 true（债务股权比超过阈值）时，必须使用信用风险工具评估破产与信用风险；结论为 grey 或 distress 时在风险部分重点说明，distress 时评级不得高于"谨慎"；讨论杠杆时以调整后债务股权比和调整债务/EBITDA（经济口径债务）为准，并说明与报告口径的差异
+- 使用营运资本工具检查最近8个季度的现金转换周期趋势；deteriorating 为 true 时，在风险部分将 warnings 作为早期预警信号逐条列出
+- 获取公司最新新闻，了解业务动态和市场情绪，按新闻主题分别评估影响
+- 需要长周期的定性判断时（如战略转型、反复出现的问题、管理层表态前后是否一致），使用新闻时间线工具获取过去一年按季度的叙事，在报告中引用季度和关键事件，而不是只依据最近的新闻
+- 获取最近的内部人交易，了解管理层买卖动向
+- 使用管理层质量评估工具，评估管理层的利益一致性、资本配置能力和稳定性
+- 获取长周期价格统计，评估长期股东回报和历史最大回撤
+- 使用法律风险工具检索诉讼、监管和调查事件，评估潜在的法律与合规风险
+- 使用公司行动历史工具整理过去5年的并购、出售和分拆，在资本配置评价中引用交易金额和整合结果，整合失败（如商誉减值、很快再出售）需列入风险
+- 提取主要客户和供应商依赖，在护城河与风险分析中引用具体的依赖关系
+- 使用基本面分析工具，输入多期财务指标进行量化评估；如果返回的 history.sufficient 为 false，需在报告中注明公司上市时间较短、历史数据不足，不做增长和稳定性等趋势类结论
+- 使用同行对比工具，评估公司相对可比公司的盈利能力、财务稳健性和估值水平
+- 使用竞争格局分析工具，评估公司相对主要竞争对手的市场地位和定价权；可以根据公司简介自行指定3到5家直接竞争对手
+- 使用蒙特卡洛估值工具，根据你对增长、利润率和估值倍数的判断设置假设分布，得到估值区间
+- 确定目标价（通常为 P50）后使用目标价检查工具核对隐含假设；结论为 stretched 或 implausible 时，在报告中说明实现目标价需要的条件或下调目标价后重新检查
+- 给出仓位建议前使用流动性评估工具；结论为 thin 或 illiquid 时（尤其是小盘股），仓位建议不得超过返回的 max_position_value，并说明需要分批建仓和退出
+- 综合所有信息，形成最终投资建议
+
+## 分析原则：
+
+- 数据驱动：所有结论都要基于具体的财务数据
+- 工具返回"没有可用数据"时跳过该步骤继续分析，并在报告中说明缺少哪类数据
+- 质量优先：重视ROE稳定性、低债务、强现金流
+- 长期视角：关注公司的护城河和持续竞争优势
+- 估值理性：不追高，寻找价值被低估的机会
+- 风险管控：明确指出投资风险和注意事项，风险部分需单独列出诉讼和监管类新闻（risk_news）
+
+## 输出要求：
+
+- 输出格式为 markdown
+- 报告开头根据公司简介工具返回的业务描述介绍公司实际从事的业务和主要收入来源，不要凭记忆描述
+- 清晰说明每步分析的思路
+- 展示关键财务数据和趋势
+- 单独撰写"管理层质量"章节，列出管理层质量评分和各项检查结论，并说明数据缺口（如高管薪酬明细）
+- 按新闻主题分类说明新闻影响（业绩财报、并购重组、诉讼/监管、产品业务、管理层）
+- 提供明确的投资评级（强烈推荐/推荐/中性/谨慎/避免），评级需综合基本面评分和竞争地位评分，并说明竞争地位评分对评级的影响
+- 以估值区间（P10/P50/P90）的形式给出目标价位，而不是单一价格，并给出风险提示
+- 报告末尾会根据工具调用记录自动附加数据来源附录，无需自行罗列数据来源
+- 报告末尾会自动附加程序计算的 1/3/5/10 年股东回报历史（股价 + 分红，与基准比较），讨论历史回报时以该章节为准，不要自行计算
+
+请按照以上流程进行分析，确保每个步骤都有充分的数据支撑。`
+
+// defaultUserPrompt 内置的用户提示词
+const defaultUserPrompt = "请分析股票 {symbol} 的投资价值。请按照标准的投资分析流程，收集必要的数据并进行综合评估，最后给出投资建议。"
+
+// PromptSet 一组提示词及其版本，版本为内容哈希，记录在运行记录中以追溯报告使用的提示词
+type PromptSet struct {
+	System  string
+	User    string
+	Version string
+}
+
+// promptsDir 返回提示词目录
+func promptsDir() string {
+	if dir := os.Getenv("PROMPTS_DIR"); dir != "" {
+		return dir
+	}
+	return "prompts"
+}
+
+// loadPromptSet 从目录读取提示词，缺少的文件使用内置提示词
+func loadPromptSet(dir string) (PromptSet, error) {
+	system, err := readPromptFile(filepath.Join(dir, systemPromptFile), defaultSystemPrompt)
+	if err != nil {
+		return PromptSet{}, err
+	}
+	user, err := readPromptFile(filepath.Join(dir, userPromptFile), defaultUserPrompt)
+	if err != nil {
+		return PromptSet{}, err
+	}
+	return PromptSet{System: system, User: user, Version: contentVersion(system, user)}, nil
+}
+
+// readPromptFile 读取提示词文件，文件不存在时返回默认值
+func readPromptFile(path, fallback string) (string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fallback, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("读取提示词文件 %s 失败: %v", path, err)
+	}
+	return string(data), nil
+}
+
+// contentVersion 计算内容的短哈希，作为提示词或配置的版本号
+func contentVersion(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// configVersion 返回配置文件（.env.local、.env 等）内容的版本号，不存在的文件跳过，都不存在时为空
+func configVersion(paths ...string) string {
+	var parts []string
+	for _, path := range paths {
+		if data, err := os.ReadFile(path); err == nil {
+			parts = append(parts, string(data))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return contentVersion(parts...)
+}
+
+// promptStore 持有当前生效的提示词，服务模式下由 watch 定期检查文件变化并热加载
+type promptStore struct {
+	dir     string
+	mu      sync.RWMutex
+	current PromptSet
+}
+
+// newPromptStore 创建提示词存储并加载一次
+func newPromptStore(dir string) (*promptStore, error) {
+	prompts, err := loadPromptSet(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &promptStore{dir: dir, current: prompts}, nil
+}
+
+// Get 返回当前提示词
+func (s *promptStore) Get() PromptSet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current
+}
+
+// reload 重新读取提示词，版本变化时返回 true；读取失败时保留当前提示词
+func (s *promptStore) reload() (bool, error) {
+	prompts, err := loadPromptSet(s.dir)
+	if err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if prompts.Version == s.current.Version {
+		return false, nil
+	}
+	s.current = prompts
+	return true, nil
+}
+
+// watch 按 interval 检查提示词文件，直到 stop 关闭
+func (s *promptStore) watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			changed, err := s.reload()
+			if err != nil {
+				log.Printf("重新加载提示词失败，继续使用版本 %s: %v", s.Get().Version, err)
+			} else if changed {
+				log.Printf("提示词已重新加载，版本 %s", s.Get().Version)
+			}
+		}
+	}
+}
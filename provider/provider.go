@@ -0,0 +1,60 @@
+// Package provider 定义行情、财务指标、新闻等市场数据的提供方接口；分析流程和工具只通过 DataProvider 取数，不依赖具体数据源的 HTTP 接口
+package provider
+
+import (
+	"context"
+
+	"investment/tools"
+)
+
+// Price 一个交易日的价格
+type Price struct {
+	Open   float64 `json:"open"`
+	Close  float64 `json:"close"`
+	High   float64 `json:"high"`
+	Low    float64 `json:"low"`
+	Volume int64   `json:"volume"`
+	Time   string  `json:"time"`
+}
+
+// CompanyFacts 公司基本信息
+type CompanyFacts struct {
+	Ticker                string  `json:"ticker"`
+	Name                  string  `json:"name"`
+	CIK                   string  `json:"cik"`
+	Industry              string  `json:"industry"`
+	Sector                string  `json:"sector"`
+	Category              string  `json:"category"`
+	Exchange              string  `json:"exchange"`
+	IsActive              bool    `json:"is_active"`
+	ListingDate           string  `json:"listing_date"`
+	Location              string  `json:"location"`
+	MarketCap             float64 `json:"market_cap"`
+	NumberOfEmployees     int     `json:"number_of_employees"`
+	SecFilingsURL         string  `json:"sec_filings_url"`
+	SicCode               string  `json:"sic_code"`
+	SicIndustry           string  `json:"sic_industry"`
+	SicSector             string  `json:"sic_sector"`
+	WebsiteURL            string  `json:"website_url"`
+	WeightedAverageShares int     `json:"weighted_average_shares"`
+}
+
+// DataProvider 市场数据的提供方。日期均为 YYYY-MM-DD；请求失败时返回用 %w 包装了 tools.ErrRateLimited、tools.ErrUnauthorized 等错误类型的错误，
+// 数据源没有该股票或该区间的数据时返回 tools.ErrNoData（Prices 可以返回空切片，由调用方判断）
+// ctx 取消（分析结束、工具超时）时实现应停止请求和翻页，返回 ctx 的错误
+type DataProvider interface {
+	// Name 提供方名称，用于日志和配置
+	Name() string
+	// Prices 获取 [startDate, endDate] 内的日线价格，多年区间由实现自行分段请求，返回的数据不要求有序
+	Prices(ctx context.Context, ticker, startDate, endDate string) ([]Price, error)
+	// Metrics 获取截至 endDate 的最近 limit 期财务指标（period 为 ttm、quarterly 或 annual），从新到旧排列；比例类字段统一为小数形式
+	Metrics(ctx context.Context, ticker, endDate, period string, limit int) ([]tools.FinancialMetrics, error)
+	// News 分页获取截至 endDate 的公司新闻，每获取一页调用一次 handle；startDate 为 nil 时只获取一页，handle 返回错误时停止并返回该错误
+	News(ctx context.Context, ticker, endDate string, startDate *string, limit int, handle func(page []tools.CompanyNews) error) error
+	// InsiderTrades 分页获取截至 endDate 申报的内部人交易，约定与 News 相同；数据因条数上限或时间窗口被截断时返回说明，否则返回空字符串
+	InsiderTrades(ctx context.Context, ticker, endDate string, startDate *string, limit int, handle func(page []tools.InsiderTrade) error) (string, error)
+	// MarketCap 获取 endDate 的市值，没有数据时返回 tools.ErrNoData，不会返回 (0, nil)
+	MarketCap(ctx context.Context, ticker, endDate string) (float64, error)
+	// Facts 获取公司基本信息（名称、行业、上市日期、市值、股本等）
+	Facts(ctx context.Context, ticker string) (*CompanyFacts, error)
+}
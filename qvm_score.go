@@ -0,0 +1,391 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"investment/tools"
+)
+
+// qvmWeights 综合评分中质量、估值、动量三项子得分的权重，按比例使用，合计不必为 1
+type qvmWeights struct {
+	Quality  float64
+	Value    float64
+	Momentum float64
+}
+
+// defaultQVMWeights 未设置 QVM_WEIGHTS 时的权重
+var defaultQVMWeights = qvmWeights{Quality: 0.4, Value: 0.3, Momentum: 0.3}
+
+// String 以 QVM_WEIGHTS 的格式输出
+func (w qvmWeights) String() string {
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	return fmt.Sprintf("quality=%s,value=%s,momentum=%s", format(w.Quality), format(w.Value), format(w.Momentum))
+}
+
+// parseQVMWeights 解析 quality=0.4,value=0.3,momentum=0.3 形式的权重，未列出的项使用默认权重，为 0 时该项不参与评分
+func parseQVMWeights(value string) (qvmWeights, error) {
+	weights := defaultQVMWeights
+	if strings.TrimSpace(value) == "" {
+		return weights, nil
+	}
+	for _, item := range strings.Split(value, ",") {
+		name, raw, ok := strings.Cut(strings.TrimSpace(item), "=")
+		weight, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if !ok || err != nil || weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return qvmWeights{}, fmt.Errorf("无效的权重 %q，格式应为 quality=0.4,value=0.3,momentum=0.3，权重为非负数", item)
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "quality":
+			weights.Quality = weight
+		case "value":
+			weights.Value = weight
+		case "momentum":
+			weights.Momentum = weight
+		default:
+			return qvmWeights{}, fmt.Errorf("未知的评分项 %q（可选 quality、value、momentum）", name)
+		}
+	}
+	if weights.Quality+weights.Value+weights.Momentum == 0 {
+		return qvmWeights{}, fmt.Errorf("权重 %q 全部为 0", value)
+	}
+	return weights, nil
+}
+
+// qvmWeightsFromEnv 读取 QVM_WEIGHTS
+func qvmWeightsFromEnv() (qvmWeights, error) {
+	weights, err := parseQVMWeights(os.Getenv("QVM_WEIGHTS"))
+	if err != nil {
+		return qvmWeights{}, fmt.Errorf("QVM_WEIGHTS %v", err)
+	}
+	return weights, nil
+}
+
+// qvmGroup 一项子得分及其使用的指标，指标的 HigherBetter 决定百分位的方向
+type qvmGroup struct {
+	Name    string
+	Label   string
+	Metrics []industryMetric
+}
+
+// qvmGroups 质量、估值、动量三项子得分；估值和动量的指标都在参与评分的股票之间比较，得分是相对排名而不是绝对水平
+var qvmGroups = []qvmGroup{
+	{Name: "quality", Label: "质量", Metrics: []industryMetric{
+		{Key: "return_on_equity", Label: "ROE", Percent: true, HigherBetter: true},
+		{Key: "gross_margin", Label: "毛利率", Percent: true, HigherBetter: true},
+		{Key: "operating_margin", Label: "营业利润率", Percent: true, HigherBetter: true},
+		{Key: "debt_to_equity", Label: "负债权益比", HigherBetter: false},
+	}},
+	{Name: "value", Label: "估值", Metrics: []industryMetric{
+		{Key: "price_to_earnings_ratio", Label: "市盈率", HigherBetter: false},
+		{Key: "price_to_book_ratio", Label: "市净率", HigherBetter: false},
+		{Key: "enterprise_value_to_ebitda_ratio", Label: "EV/EBITDA", HigherBetter: false},
+		{Key: "free_cash_flow_yield", Label: "自由现金流收益率", Percent: true, HigherBetter: true},
+	}},
+	{Name: "momentum", Label: "动量", Metrics: []industryMetric{
+		{Key: "momentum_12_1", Label: "12-1 个月涨跌幅", Percent: true, HigherBetter: true},
+		{Key: "momentum_6m", Label: "6 个月涨跌幅", Percent: true, HigherBetter: true},
+	}},
+}
+
+// 动量按交易日计算：12-1 个月涨跌幅跳过最近一个月（约 21 个交易日）以避开短期反转，6 个月约 126 个交易日
+const (
+	qvmSkipDays       = 21
+	qvmSixMonthDays   = 126
+	qvmMinHistoryDays = 200 // 计算 12-1 个月涨跌幅至少需要的价格数量（上市不足一年的股票不计算）
+)
+
+// QVMScore 一只股票的质量-估值-动量综合评分，子得分和综合得分为参与评分的股票之间的百分位（0-100，越高越好）
+type QVMScore struct {
+	Symbol   string             `json:"symbol"`
+	Note     string             `json:"note,omitempty"`    // 关注列表中的备注
+	Metrics  map[string]float64 `json:"metrics,omitempty"` // 参与评分的指标，缺失的不出现
+	Quality  tools.SafeFloat    `json:"quality"`
+	Value    tools.SafeFloat    `json:"value"`
+	Momentum tools.SafeFloat    `json:"momentum"`
+	Score    tools.SafeFloat    `json:"score"`          // 按权重合成的综合得分，数据不足时为 n/a
+	Rank     int                `json:"rank,omitempty"` // 按综合得分的排名，从 1 开始；没有综合得分时为 0
+	Error    string             `json:"error,omitempty"`
+}
+
+// sub 按名称取子得分
+func (s *QVMScore) sub(name string) *tools.SafeFloat {
+	switch name {
+	case "quality":
+		return &s.Quality
+	case "value":
+		return &s.Value
+	default:
+		return &s.Momentum
+	}
+}
+
+// newQVMScore 从最新 TTM 指标和近一年收盘价提取评分使用的指标；closes 为空时没有动量指标
+func newQVMScore(symbol string, m *tools.FinancialMetrics, closes []float64) QVMScore {
+	score := QVMScore{Symbol: symbol, Metrics: make(map[string]float64), Quality: tools.NaN(), Value: tools.NaN(), Momentum: tools.NaN(), Score: tools.NaN()}
+	if m != nil {
+		for key, value := range tools.BenchmarkMetricValues(*m) {
+			score.Metrics[key] = value
+		}
+		// 亏损或数据缺失时 EV/EBITDA 为 0 或负数，没有比较意义
+		if m.EnterpriseValueToEbitdaRatio > 0 {
+			score.Metrics["enterprise_value_to_ebitda_ratio"] = m.EnterpriseValueToEbitdaRatio
+		}
+		if m.FreeCashFlowYield != 0 {
+			score.Metrics["free_cash_flow_yield"] = m.FreeCashFlowYield
+		}
+	}
+	n := len(closes)
+	if n >= qvmMinHistoryDays {
+		if r := tools.SafeGrowth(closes[n-1-qvmSkipDays], closes[0]); r.Valid() {
+			score.Metrics["momentum_12_1"] = float64(r)
+		}
+	}
+	if n > qvmSixMonthDays {
+		if r := tools.SafeGrowth(closes[n-1], closes[n-1-qvmSixMonthDays]); r.Valid() {
+			score.Metrics["momentum_6m"] = float64(r)
+		}
+	}
+	return score
+}
+
+// collectQVMScore 获取一只股票的最新 TTM 指标（metrics 为 nil 时）和近一年价格，部分数据失败时记录在 Error 中
+func collectQVMScore(ctx context.Context, symbol string, metrics *tools.FinancialMetrics) QVMScore {
+	var problems []string
+	if metrics == nil {
+		if list, err := GetFinancialMetrics(ctx, symbol, time.Now().Format("2006-01-02"), "ttm", 1); err != nil || len(list) == 0 {
+			problems = append(problems, fmt.Sprintf("TTM 指标: %v", orNoData(err)))
+		} else {
+			metrics = &list[0]
+		}
+	}
+	var closes []float64
+	if series, err := GetPriceSeries(ctx, symbol, 1); err != nil {
+		problems = append(problems, fmt.Sprintf("价格: %v", err))
+	} else {
+		closes = series.Close
+	}
+	score := newQVMScore(symbol, metrics, closes)
+	score.Error = strings.Join(problems, "；")
+	return score
+}
+
+// rankQVMScores 计算各指标在参与评分的股票之间的百分位，组内取平均作为子得分（有效指标不足一半时没有子得分），
+// 再按权重合成综合得分：缺少的子得分不计入，剩余子得分的权重按比例放大，但剩余权重不足一半时没有综合得分。
+// 按综合得分从高到低排序，没有综合得分的排在最后
+func rankQVMScores(scores []QVMScore, weights qvmWeights) {
+	total := weights.Quality + weights.Value + weights.Momentum
+	for i := range scores {
+		var sum, used float64
+		for _, group := range qvmGroups {
+			var percentiles []float64
+			for _, metric := range group.Metrics {
+				value, ok := scores[i].Metrics[metric.Key]
+				if !ok {
+					continue
+				}
+				var others []float64
+				for j := range scores {
+					if other, ok := scores[j].Metrics[metric.Key]; ok && i != j {
+						others = append(others, other)
+					}
+				}
+				if p, ok := peerPercentile(value, others, metric.HigherBetter); ok {
+					percentiles = append(percentiles, p)
+				}
+			}
+			if len(percentiles) == 0 || len(percentiles)*2 < len(group.Metrics) {
+				continue
+			}
+			sub := tools.SafeDiv(sumOf(percentiles), float64(len(percentiles)))
+			*scores[i].sub(group.Name) = sub
+			weight := weights.forGroup(group.Name)
+			sum += weight * float64(sub)
+			used += weight
+		}
+		if used > 0 && used*2 >= total {
+			scores[i].Score = tools.SafeDiv(sum, used)
+		}
+	}
+
+	sort.SliceStable(scores, func(i, j int) bool {
+		a, b := scores[i].Score, scores[j].Score
+		if a.Valid() != b.Valid() {
+			return a.Valid()
+		}
+		return a > b
+	})
+	ranked := 0
+	for i := range scores {
+		if scores[i].Score.Valid() {
+			ranked++
+			scores[i].Rank = ranked
+		}
+	}
+}
+
+// forGroup 按子得分名称取权重
+func (w qvmWeights) forGroup(name string) float64 {
+	switch name {
+	case "quality":
+		return w.Quality
+	case "value":
+		return w.Value
+	default:
+		return w.Momentum
+	}
+}
+
+// peerPercentile value 在 others 中的百分位（最好为 100），相同数值各算一半；others 为空时返回 false
+func peerPercentile(value float64, others []float64, higherBetter bool) (float64, bool) {
+	if len(others) == 0 {
+		return 0, false
+	}
+	var better, worse int
+	for _, other := range others {
+		switch {
+		case other == value:
+		case (other < value) == higherBetter:
+			worse++
+		default:
+			better++
+		}
+	}
+	return (float64(worse) + float64(len(others)-better-worse)/2) / float64(len(others)) * 100, true
+}
+
+// sumOf 求和
+func sumOf(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum
+}
+
+// printQVMTable 打印排名表，最多 top 行；showNote 时在每行后列出关注列表备注
+func printQVMTable(scores []QVMScore, top int, showNote bool) {
+	fmt.Printf("\n%-4s %-10s %8s %8s %8s %8s\n", "排名", "股票", "综合", "质量", "估值", "动量")
+	for i, s := range scores {
+		if i >= top {
+			fmt.Printf("…… 另有 %d 只，可调大 --top 查看\n", len(scores)-top)
+			break
+		}
+		rank := "-"
+		if s.Rank > 0 {
+			rank = strconv.Itoa(s.Rank)
+		}
+		fmt.Printf("%-4s %-10s %8s %8s %8s %8s\n", rank, s.Symbol, qvmPoints(s.Score), qvmPoints(s.Quality), qvmPoints(s.Value), qvmPoints(s.Momentum))
+		if showNote && s.Note != "" {
+			fmt.Printf("%-15s 备注: %s\n", "", s.Note)
+		}
+	}
+}
+
+// qvmPoints 格式化 0-100 的得分，缺失时为 n/a
+func qvmPoints(v tools.SafeFloat) string {
+	if !v.Valid() {
+		return tools.NotAvailable
+	}
+	return fmt.Sprintf("%.0f", float64(v))
+}
+
+// runRank 处理 rank 子命令：按质量-估值-动量综合评分对关注列表、给定股票或指数成分股排序，不调用模型
+func runRank(args []string) error {
+	fs := flag.NewFlagSet("rank", flag.ExitOnError)
+	watchlist := fs.String("watchlist", "", "股票池：关注列表文件（每行一只股票，代码后可写备注）")
+	tickers := fs.String("tickers", "", "股票池：逗号分隔的股票代码")
+	index := fs.String("index", "", "股票池：指数名称（"+strings.Join(stockIndexNames(), "、")+"）")
+	weightsFlag := fs.String("weights", "", "子得分权重，如 quality=0.5,value=0.3,momentum=0.2，默认使用 QVM_WEIGHTS")
+	top := fs.Int("top", 50, "最多列出的股票数")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	sources := 0
+	for _, s := range []string{*watchlist, *tickers, *index} {
+		if s != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return fmt.Errorf("用法: %s", rankUsage)
+	}
+	if *top <= 0 {
+		return fmt.Errorf("无效的 --top: %d", *top)
+	}
+	weights, err := qvmWeightsFromEnv()
+	if *weightsFlag != "" {
+		weights, err = parseQVMWeights(*weightsFlag)
+	}
+	if err != nil {
+		return err
+	}
+	if err := configError(sharedConfigProblems()); err != nil {
+		return err
+	}
+
+	var entries []watchlistEntry
+	source := *tickers
+	switch {
+	case *watchlist != "":
+		if entries, err = readWatchlist(*watchlist); err != nil {
+			return err
+		}
+		source = *watchlist
+	case *index != "":
+		constituents, err := NewUniverseProvider().Get(*index)
+		if err != nil {
+			return err
+		}
+		source = constituents.Name
+		for _, symbol := range constituents.Symbols {
+			entries = append(entries, watchlistEntry{Symbol: symbol})
+		}
+	default:
+		for _, symbol := range parseSymbolList(*tickers) {
+			entries = append(entries, watchlistEntry{Symbol: symbol})
+		}
+	}
+
+	// 同一只股票只评分一次，备注合并
+	var scores []QVMScore
+	seen := make(map[string]int)
+	for _, entry := range entries {
+		symbol := canonicalSymbol(entry.Symbol)
+		if i, ok := seen[symbol]; ok {
+			if entry.Note != "" {
+				scores[i].Note = strings.TrimPrefix(scores[i].Note+"；"+entry.Note, "；")
+			}
+			continue
+		}
+		seen[symbol] = len(scores)
+		scores = append(scores, QVMScore{Symbol: symbol, Note: entry.Note})
+	}
+	if len(scores) < 2 {
+		return fmt.Errorf("综合评分是股票之间的相对排名，至少需要 2 只股票")
+	}
+
+	ctx := context.Background()
+	fmt.Printf("=== 质量-估值-动量综合评分：%s（%d 只，权重 %s）===\n", source, len(scores), weights)
+	for i := range scores {
+		if (i+1)%25 == 0 {
+			fmt.Printf("已获取 %d/%d\n", i+1, len(scores))
+		}
+		collected := collectQVMScore(ctx, scores[i].Symbol, nil)
+		collected.Note = scores[i].Note
+		if collected.Error != "" {
+			fmt.Printf("⚠️ %s 数据不完整: %s\n", collected.Symbol, collected.Error)
+		}
+		scores[i] = collected
+	}
+	rankQVMScores(scores, weights)
+	printQVMTable(scores, *top, *watchlist != "")
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestParseQVMWeights(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    qvmWeights
+		wantErr bool
+	}{
+		{value: "", want: defaultQVMWeights},
+		{value: "quality=0.5, value=0.5,momentum=0", want: qvmWeights{Quality: 0.5, Value: 0.5}},
+		{value: "momentum=1", want: qvmWeights{Quality: defaultQVMWeights.Quality, Value: defaultQVMWeights.Value, Momentum: 1}},
+		{value: "quality=-0.1", wantErr: true},
+		{value: "quality=NaN", wantErr: true},
+		{value: "value=nan,momentum=0.3", wantErr: true},
+		{value: "momentum=+Inf", wantErr: true},
+		{value: "quality", wantErr: true},
+		{value: "growth=0.3", wantErr: true},
+		{value: "quality=0,value=0,momentum=0", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseQVMWeights(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: 得到 %v，期望返回错误", tt.value, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%q: 得到 %v, %v，期望 %v", tt.value, got, err, tt.want)
+		}
+	}
+}
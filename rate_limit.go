@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxRetryAfter 数据源要求的等待时间上限，防止异常的响应头让分析长时间挂起
+const maxRetryAfter = 10 * time.Minute
+
+// RateLimitQuota 数据源最近一次响应中的限流配额
+type RateLimitQuota struct {
+	Host      string
+	Limit     int
+	Remaining int
+	Reset     time.Time // 配额重置时间，未知时为零值
+	UpdatedAt time.Time
+}
+
+var (
+	quotaMu sync.Mutex
+	quotas  = make(map[string]RateLimitQuota)
+)
+
+// CurrentRateLimitQuota 返回指定数据源主机最近记录的配额
+func CurrentRateLimitQuota(host string) (RateLimitQuota, bool) {
+	quotaMu.Lock()
+	defer quotaMu.Unlock()
+	q, ok := quotas[host]
+	return q, ok
+}
+
+// forgetRateLimitQuota 清除主机记录的配额，换用其他密钥后旧密钥的配额不再适用
+func forgetRateLimitQuota(host string) {
+	quotaMu.Lock()
+	defer quotaMu.Unlock()
+	delete(quotas, host)
+}
+
+// recordRateLimitQuota 从 X-RateLimit-* 响应头中记录剩余配额，没有相关响应头时不做记录
+func recordRateLimitQuota(resp *http.Response, now time.Time) {
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	q := RateLimitQuota{
+		Host:      resp.Request.URL.Host,
+		Remaining: remaining,
+		UpdatedAt: now,
+	}
+	if limit, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit")); err == nil {
+		q.Limit = limit
+	}
+	if reset, ok := parseRateLimitReset(resp.Header.Get("X-RateLimit-Reset"), now); ok {
+		q.Reset = reset
+	}
+
+	quotaMu.Lock()
+	defer quotaMu.Unlock()
+	quotas[q.Host] = q
+}
+
+// quotaWait 主机最近记录的配额已用完且重置时间未到时，返回需要等待的时间（不超过 maxRetryAfter），否则返回 0
+// 回放快照时响应来自录制，其中的配额已经过时，不等待；重置时间未知时不等待，由 429 响应的退避处理
+func quotaWait(host string, now time.Time) time.Duration {
+	if rt := activeSnapshot.Load(); rt != nil && !isSnapshotRecorder(*rt) {
+		return 0
+	}
+	q, ok := CurrentRateLimitQuota(host)
+	if !ok || q.Remaining > 0 || !q.Reset.After(now) {
+		return 0
+	}
+	return min(q.Reset.Sub(now), maxRetryAfter)
+}
+
+// sleepContext 等待 d，ctx 先结束时返回 context.Cause(ctx)
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retryDelay 计算限流后的等待时间：优先使用 Retry-After，其次使用 X-RateLimit-Reset，
+// 都没有时退回线性退避 60s, 90s, 120s...
+func retryDelay(resp *http.Response, attempt int, now time.Time) time.Duration {
+	delay := time.Duration(60+30*attempt) * time.Second
+	if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now); ok {
+		delay = d
+	} else if reset, ok := parseRateLimitReset(resp.Header.Get("X-RateLimit-Reset"), now); ok {
+		delay = reset.Sub(now)
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	if delay > maxRetryAfter {
+		delay = maxRetryAfter
+	}
+	return delay
+}
+
+// parseRetryAfter 解析 Retry-After，支持秒数和 HTTP 日期两种格式
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return t.Sub(now), true
+	}
+	return 0, false
+}
+
+// parseRateLimitReset 解析 X-RateLimit-Reset，大于 10 亿视为 Unix 时间戳，否则视为距今的秒数
+func parseRateLimitReset(value string, now time.Time) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if n > 1_000_000_000 {
+		return time.Unix(n, 0), true
+	}
+	return now.Add(time.Duration(n) * time.Second), true
+}
+
+// requestPacer 按固定间隔放行请求，同一数据源客户端上并发的分析（批量分析、服务模式）共用，避免同时发出大量请求触发限流
+type requestPacer struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time // 下一个请求最早的发出时间
+}
+
+// newRequestPacer 每分钟最多放行 perMinute 个请求
+func newRequestPacer(perMinute int) *requestPacer {
+	return &requestPacer{interval: time.Minute / time.Duration(perMinute)}
+}
+
+// wait 等到本请求的发出时间，ctx 结束时提前返回
+func (p *requestPacer) wait(ctx context.Context) error {
+	p.mu.Lock()
+	now := time.Now()
+	slot := p.next
+	if slot.Before(now) {
+		slot = now
+	}
+	p.next = slot.Add(p.interval)
+	p.mu.Unlock()
+
+	delay := slot.Sub(now)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// pacedTransport 经过 requestPacer 限速后再发出请求；回放快照时不请求数据源，不限速
+type pacedTransport struct {
+	next  http.RoundTripper
+	pacer *requestPacer
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t pacedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if rt := activeSnapshot.Load(); rt == nil || isSnapshotRecorder(*rt) {
+		if err := t.pacer.wait(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+	return t.next.RoundTrip(req)
+}
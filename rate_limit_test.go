@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"investment/tools"
+)
+
+// exhaustQuota 记录 fd.test 的配额已用完，60 秒后重置
+func exhaustQuota(t *testing.T) {
+	t.Helper()
+	quotaMu.Lock()
+	quotas["fd.test"] = RateLimitQuota{Host: "fd.test", Remaining: 0, Reset: time.Now().Add(time.Minute), UpdatedAt: time.Now()}
+	quotaMu.Unlock()
+	t.Cleanup(func() { forgetRateLimitQuota("fd.test") })
+}
+
+func TestRateLimitQuotaStopsEarly(t *testing.T) {
+	// 配额已用完时不再发出注定被限流的请求；等待超过重试预算时直接跳过这份数据
+	requests := stubFinancialDatasets(t, func(r *http.Request) (int, string) {
+		return http.StatusOK, newsJSON("A@2025-01-10")
+	})
+	exhaustQuota(t)
+
+	ctx := withRetryBudget(context.Background(), &retryBudget{limit: time.Second})
+	_, err := GetCompanyNews(ctx, "AAPL", "2025-01-10", nil, 10)
+	if !errors.Is(err, tools.ErrRateLimited) {
+		t.Fatalf("err = %v, want ErrRateLimited", err)
+	}
+	if len(*requests) != 0 {
+		t.Errorf("requests = %d, want 0", len(*requests))
+	}
+}
+
+func TestRateLimitQuotaRotatesKey(t *testing.T) {
+	// 有多个密钥时换用下一个密钥，不等待配额重置
+	var keys []string
+	stubDataAPI(t, providerFinancialDatasets, &dataProvider{BaseURL: "https://fd.test", Header: "X-API-KEY", keys: []string{"first", "second"}}, func(r *http.Request) (int, string) {
+		keys = append(keys, r.Header.Get("X-API-KEY"))
+		return http.StatusOK, newsJSON("A@2025-01-10")
+	})
+	exhaustQuota(t)
+
+	ctx := withRetryBudget(context.Background(), &retryBudget{limit: time.Second})
+	if _, err := GetCompanyNews(ctx, "AAPL", "2025-01-10", nil, 10); err != nil {
+		t.Fatalf("GetCompanyNews: %v", err)
+	}
+	if len(keys) != 1 || keys[0] != "second" {
+		t.Errorf("keys = %v, want [second]", keys)
+	}
+	if _, ok := CurrentRateLimitQuota("fd.test"); ok {
+		t.Error("换用密钥后旧密钥的配额应被清除")
+	}
+}
+
+func TestQuotaWait(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name  string
+		quota RateLimitQuota
+		want  time.Duration
+	}{
+		{"还有剩余", RateLimitQuota{Remaining: 5, Reset: now.Add(time.Minute)}, 0},
+		{"已到重置时间", RateLimitQuota{Remaining: 0, Reset: now.Add(-time.Second)}, 0},
+		{"重置时间未知", RateLimitQuota{Remaining: 0}, 0},
+		{"等到重置", RateLimitQuota{Remaining: 0, Reset: now.Add(30 * time.Second)}, 30 * time.Second},
+		{"不超过上限", RateLimitQuota{Remaining: 0, Reset: now.Add(time.Hour)}, maxRetryAfter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quotaMu.Lock()
+			quotas["quota.test"] = tt.quota
+			quotaMu.Unlock()
+			t.Cleanup(func() { forgetRateLimitQuota("quota.test") })
+			if got := quotaWait("quota.test", now); got != tt.want {
+				t.Errorf("quotaWait = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"investment/tools"
+)
+
+// rebalanceOptions 再平衡约束
+type rebalanceOptions struct {
+	Targets     map[string]float64 // 目标权重，为空时以当前权重为目标，只执行 MaxWeight 限制
+	MaxWeight   float64            // 单只股票的权重上限，0 表示不限制
+	MinTrade    float64            // 最小交易金额，低于该金额的交易不执行
+	MaxTurnover float64            // 换手率上限（买卖金额之和 / 组合总值），0 表示不限制
+	Cash        float64            // 可用现金，计入组合总值
+}
+
+// rebalanceTrade 一笔建议交易，Shares 为正表示买入，为负表示卖出
+type rebalanceTrade struct {
+	Symbol        string
+	Price         float64
+	CurrentShares float64
+	Shares        float64
+	Value         float64
+	CurrentWeight float64
+	TargetWeight  float64
+	ResultWeight  float64 // 执行建议交易后的权重
+}
+
+// rebalancePlan 再平衡方案
+type rebalancePlan struct {
+	TotalValue  float64
+	Trades      []rebalanceTrade // 每只股票一行，包括不需要交易的股票
+	Turnover    float64
+	CashAfter   float64
+	ScaledDown  bool // 是否因换手率上限按比例缩减了交易
+	TargetsFrom string
+}
+
+// parseTargetWeights 解析 AAPL=0.3,MSFT=0.2 形式的目标权重
+func parseTargetWeights(spec string) (map[string]float64, error) {
+	targets := make(map[string]float64)
+	if strings.TrimSpace(spec) == "" {
+		return targets, nil
+	}
+	for _, item := range strings.Split(spec, ",") {
+		symbol, value, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			return nil, fmt.Errorf("无效的目标权重 %q，格式应为 SYMBOL=权重", item)
+		}
+		weight, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("无效的目标权重 %q: %v", item, err)
+		}
+		if strings.HasSuffix(value, "%") {
+			weight /= 100
+		}
+		targets[strings.ToUpper(strings.TrimSpace(symbol))] = weight
+	}
+	return targets, nil
+}
+
+// capWeights 将超过上限的权重截断，超出部分按比例分配给未达上限的股票，直到没有股票超过上限
+func capWeights(weights map[string]float64, maxWeight float64) map[string]float64 {
+	capped := make(map[string]float64, len(weights))
+	for symbol, weight := range weights {
+		capped[symbol] = weight
+	}
+	if maxWeight <= 0 {
+		return capped
+	}
+	for range len(capped) {
+		excess, free := 0.0, 0.0
+		for _, weight := range capped {
+			if weight > maxWeight {
+				excess += weight - maxWeight
+			} else if weight < maxWeight {
+				free += weight
+			}
+		}
+		if excess <= 1e-12 {
+			break
+		}
+		for symbol, weight := range capped {
+			switch {
+			case weight >= maxWeight:
+				capped[symbol] = maxWeight
+			case free > 0:
+				capped[symbol] = weight + excess*weight/free
+			}
+		}
+		if free == 0 {
+			break // 所有股票都已达到上限，剩余部分保留为现金
+		}
+	}
+	return capped
+}
+
+// computeRebalance 按目标权重计算建议交易：股数向零取整，低于最小交易金额的交易不执行，
+// 换手率超过上限时按比例缩减所有交易，买入金额不超过现金和卖出所得
+func computeRebalance(shares, prices map[string]float64, opts rebalanceOptions) (*rebalancePlan, error) {
+	total := opts.Cash
+	for symbol, quantity := range shares {
+		price, ok := prices[symbol]
+		if !ok || price <= 0 {
+			return nil, fmt.Errorf("%s 缺少有效价格", symbol)
+		}
+		total += quantity * price
+	}
+	if total <= 0 {
+		return nil, fmt.Errorf("组合总值为0，无法再平衡")
+	}
+
+	plan := &rebalancePlan{TotalValue: total, TargetsFrom: "目标权重"}
+	targets := opts.Targets
+	if len(targets) == 0 {
+		plan.TargetsFrom = "当前权重"
+		targets = make(map[string]float64, len(shares))
+		for symbol, quantity := range shares {
+			targets[symbol] = quantity * prices[symbol] / total
+		}
+	}
+	sum := 0.0
+	for symbol, weight := range targets {
+		if weight < 0 {
+			return nil, fmt.Errorf("%s 的目标权重不能为负数", symbol)
+		}
+		if prices[symbol] <= 0 {
+			return nil, fmt.Errorf("%s 缺少有效价格", symbol)
+		}
+		sum += weight
+	}
+	if sum > 1+1e-9 {
+		return nil, fmt.Errorf("目标权重之和 %.4f 超过 1", sum)
+	}
+	targets = capWeights(targets, opts.MaxWeight)
+
+	// 持有但不在目标中的股票目标权重为0
+	symbols := make([]string, 0, len(targets)+len(shares))
+	for symbol := range targets {
+		symbols = append(symbols, symbol)
+	}
+	for symbol := range shares {
+		if _, ok := targets[symbol]; !ok {
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.Strings(symbols)
+
+	desired := make([]float64, len(symbols)) // 未取整的目标交易金额
+	gross := 0.0
+	for i, symbol := range symbols {
+		desired[i] = targets[symbol]*total - shares[symbol]*prices[symbol]
+		gross += math.Abs(desired[i])
+	}
+	scale := 1.0
+	if opts.MaxTurnover > 0 && gross/total > opts.MaxTurnover {
+		scale = opts.MaxTurnover * total / gross
+		plan.ScaledDown = true
+	}
+
+	cash := opts.Cash
+	for i, symbol := range symbols {
+		price := prices[symbol]
+		trade := rebalanceTrade{
+			Symbol:        symbol,
+			Price:         price,
+			CurrentShares: shares[symbol],
+			CurrentWeight: shares[symbol] * price / total,
+			TargetWeight:  targets[symbol],
+		}
+		trade.Shares = math.Trunc(desired[i] * scale / price)
+		if targets[symbol] == 0 && scale == 1 {
+			trade.Shares = -shares[symbol] // 清仓时包括零碎股
+		}
+		trade.Value = trade.Shares * price
+		if trade.Shares == 0 || math.Abs(trade.Value) < opts.MinTrade {
+			trade.Shares, trade.Value = 0, 0
+		}
+		cash -= trade.Value
+		plan.Trades = append(plan.Trades, trade)
+	}
+
+	// 取整后买入金额可能超过卖出所得和现金，从金额最大的买入开始减少股数
+	order := make([]int, len(plan.Trades))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return plan.Trades[order[a]].Value > plan.Trades[order[b]].Value })
+	for _, i := range order {
+		trade := &plan.Trades[i]
+		if cash >= 0 || trade.Shares <= 0 {
+			break
+		}
+		reduce := math.Min(trade.Shares, math.Ceil(-cash/trade.Price))
+		trade.Shares -= reduce
+		cash += reduce * trade.Price
+		trade.Value = trade.Shares * trade.Price
+		if trade.Value > 0 && trade.Value < opts.MinTrade {
+			cash += trade.Value
+			trade.Shares, trade.Value = 0, 0
+		}
+	}
+
+	for i := range plan.Trades {
+		trade := &plan.Trades[i]
+		plan.Turnover += math.Abs(trade.Value) / total
+		trade.ResultWeight = (trade.CurrentShares + trade.Shares) * trade.Price / total
+	}
+	plan.CashAfter = cash
+	return plan, nil
+}
+
+// renderRebalancePlan 将再平衡方案渲染为 markdown
+func renderRebalancePlan(name string, plan *rebalancePlan, opts rebalanceOptions, format tools.NumberFormat, currency string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# 组合 %s 再平衡建议\n\n生成时间: %s\n\n", name, time.Now().Format("2006-01-02 15:04:05")))
+	sb.WriteString(fmt.Sprintf("- 组合总值（含现金）：%s\n- 目标来源：%s\n", format.Money(plan.TotalValue, currency), plan.TargetsFrom))
+	if opts.MaxWeight > 0 {
+		sb.WriteString(fmt.Sprintf("- 单只股票权重上限：%.1f%%\n", opts.MaxWeight*100))
+	}
+	if opts.MinTrade > 0 {
+		sb.WriteString(fmt.Sprintf("- 最小交易金额：%s\n", format.Money(opts.MinTrade, currency)))
+	}
+	if opts.MaxTurnover > 0 {
+		sb.WriteString(fmt.Sprintf("- 换手率上限：%.1f%%\n", opts.MaxTurnover*100))
+	}
+	sb.WriteString(fmt.Sprintf("- 建议交易换手率：%.1f%%\n", plan.Turnover*100))
+	if plan.ScaledDown {
+		sb.WriteString("- ⚠️ 完全再平衡所需的换手率超过上限，交易已按比例缩减，执行后仍未完全达到目标\n")
+	}
+	sb.WriteString(fmt.Sprintf("- 交易后现金：%s\n", format.Money(plan.CashAfter, currency)))
+
+	sb.WriteString("\n| 股票 | 价格 | 当前股数 | 当前权重 | 目标权重 | 建议交易（股） | 交易金额 | 交易后权重 |\n|---|---|---|---|---|---|---|---|\n")
+	for _, trade := range plan.Trades {
+		action := "-"
+		switch {
+		case trade.Shares > 0:
+			action = fmt.Sprintf("买入 %.0f", trade.Shares)
+		case trade.Shares < 0:
+			action = fmt.Sprintf("卖出 %.4g", -trade.Shares)
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %s | %.4g | %.1f%% | %.1f%% | %s | %s | %.1f%% |\n",
+			trade.Symbol, format.Money(trade.Price, currency), trade.CurrentShares, trade.CurrentWeight*100, trade.TargetWeight*100, action, signedMoney(format, trade.Value, currency), trade.ResultWeight*100))
+	}
+	sb.WriteString("\n以上为基于规则的建议，未考虑交易费用、税费和买卖价差，不构成投资建议。\n")
+	return sb.String()
+}
+
+// rebalancePortfolio 按组合中保存的目标权重（或 targetSpec 覆盖）和约束生成再平衡建议，
+// 保存到 output/report/<name>/rebalance_<时间>.md
+func rebalancePortfolio(ctx context.Context, p *Portfolio, targetSpec string, opts rebalanceOptions) error {
+	targets, err := parseTargetWeights(targetSpec)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		targets = p.Targets
+	}
+	opts.Targets = targets
+
+	shares := make(map[string]float64)
+	for _, position := range p.Positions {
+		shares[position.Symbol] += position.Quantity
+	}
+	symbols := p.Symbols()
+	for symbol := range targets {
+		if _, ok := shares[symbol]; !ok {
+			symbols = append(symbols, symbol)
+		}
+	}
+	currency, err := rebalanceCurrency(p, symbols)
+	if err != nil {
+		return err
+	}
+	prices := make(map[string]float64)
+	for _, symbol := range symbols {
+		price, err := latestClose(ctx, symbol)
+		if err != nil {
+			return fmt.Errorf("获取 %s 最新价格失败: %w", symbol, err)
+		}
+		prices[symbol] = price
+	}
+
+	plan, err := computeRebalance(shares, prices, opts)
+	if err != nil {
+		return fmt.Errorf("计算再平衡方案失败: %w", err)
+	}
+	format, err := tools.NewNumberFormat(os.Getenv("REPORT_LOCALE"))
+	if err != nil {
+		return err
+	}
+	report := renderRebalancePlan(p.Name, plan, opts, format, currency)
+	fmt.Print(report)
+
+	name := path.Join("report", p.Name, fmt.Sprintf("rebalance_%s.md", time.Now().Format("2006-01-02_15-04-05")))
+	location, err := tools.Output().WriteReport(name, []byte(report))
+	if err != nil {
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+	fmt.Printf("📄 再平衡建议已保存: %s\n", location)
+	return nil
+}
+
+// rebalanceCurrency 再平衡涉及的股票（持仓和目标）共同的币种：持仓按记录的币种，只在目标中的股票按代码所属市场的币种
+// 再平衡按价格直接计算权重和交易金额，不做汇率换算，涉及多个币种时返回错误
+func rebalanceCurrency(p *Portfolio, symbols []string) (string, error) {
+	held := p.Currencies()
+	bySymbol := make(map[string][]string)
+	for _, symbol := range symbols {
+		currency := held[symbol]
+		if currency == "" {
+			profile, _ := resolveMarket(symbol, MarketAuto)
+			currency = profile.Currency
+		}
+		bySymbol[currency] = append(bySymbol[currency], symbol)
+	}
+	if len(bySymbol) <= 1 {
+		for currency := range bySymbol {
+			return currency, nil
+		}
+		return p.BaseCurrency(), nil
+	}
+	currencies := make([]string, 0, len(bySymbol))
+	for currency, list := range bySymbol {
+		currencies = append(currencies, fmt.Sprintf("%s（%s）", currency, strings.Join(list, ", ")))
+	}
+	sort.Strings(currencies)
+	return "", fmt.Errorf("组合 %s 涉及多个币种：%s；再平衡不做汇率换算，请按币种拆分组合后分别再平衡", p.Name, strings.Join(currencies, "、"))
+}
+
+// latestClose 返回最近两周内最后一个交易日的收盘价
+func latestClose(ctx context.Context, symbol string) (float64, error) {
+	now := time.Now()
+	prices, err := GetPrices(ctx, symbol, now.AddDate(0, 0, -14).Format("2006-01-02"), now.Format("2006-01-02"))
+	if err != nil {
+		return 0, err
+	}
+	if len(prices) == 0 {
+		return 0, fmt.Errorf("最近两周没有价格数据")
+	}
+	return prices[len(prices)-1].Close, nil
+}
+
+// currentPrice 获取实时价格，实时行情不可用时使用最近收盘价
+func currentPrice(ctx context.Context, symbol string) (float64, error) {
+	snapshot, err := GetPriceSnapshot(ctx, symbol)
+	if err == nil {
+		return snapshot.Price, nil
+	}
+	if errors.Is(err, tools.ErrUnauthorized) {
+		return 0, err
+	}
+	return latestClose(ctx, symbol)
+}
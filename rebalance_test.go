@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRebalanceCurrency(t *testing.T) {
+	p := &Portfolio{
+		Name: "main",
+		Positions: []Position{
+			{Symbol: "AAPL", Quantity: 10, Currency: "USD"},
+			{Symbol: "MSFT", Quantity: 5, Currency: "USD"},
+		},
+	}
+	if got, err := rebalanceCurrency(p, []string{"AAPL", "MSFT", "GOOGL"}); err != nil || got != "USD" {
+		t.Errorf("得到 %q, %v，期望 USD", got, err)
+	}
+
+	// 目标中的港股按代码后缀识别为 HKD
+	_, err := rebalanceCurrency(p, []string{"AAPL", "MSFT", "0700.HK"})
+	if err == nil || !strings.Contains(err.Error(), "HKD（0700.HK）") || !strings.Contains(err.Error(), "USD（AAPL, MSFT）") {
+		t.Errorf("持仓和目标涉及 USD 和 HKD 时应返回错误，得到 %v", err)
+	}
+
+	p.Positions = append(p.Positions, Position{Symbol: "600519.SS", Quantity: 100, Currency: "CNY"})
+	if _, err := rebalanceCurrency(p, p.Symbols()); err == nil {
+		t.Error("持仓涉及 USD 和 CNY 时应返回错误")
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"investment/tools"
+)
+
+// defaultRetryBudget 一次分析中所有限流重试累计等待时间的默认上限
+const defaultRetryBudget = 5 * time.Minute
+
+// retryBudget 一次分析共用的重试预算：所有数据请求的限流等待累计不超过 limit，
+// 用完后不再等待，直接跳过被限流的数据，分析以已获取的数据完成
+type retryBudget struct {
+	limit time.Duration // 0 表示不限制
+
+	mu      sync.Mutex
+	waited  time.Duration
+	skipped []string // 因预算用完而跳过的数据，按首次跳过的顺序
+}
+
+// retryBudgetKey context 中保存重试预算的键
+type retryBudgetKey struct{}
+
+// retryBudgetFromEnv RETRY_BUDGET: 每次分析限流重试累计等待的上限（如 5m、90s），默认 5m，为 0 时不限制
+func retryBudgetFromEnv() *retryBudget {
+	limit, err := retryBudgetLimit()
+	if err != nil {
+		log.Printf("%v，使用默认值 %s", err, defaultRetryBudget)
+	}
+	return &retryBudget{limit: limit}
+}
+
+// retryBudgetLimit 解析 RETRY_BUDGET，无效时返回错误和默认值
+func retryBudgetLimit() (time.Duration, error) {
+	value := os.Getenv("RETRY_BUDGET")
+	if value == "" {
+		return defaultRetryBudget, nil
+	}
+	limit, err := time.ParseDuration(value)
+	if err != nil || limit < 0 {
+		return defaultRetryBudget, fmt.Errorf("无效的 RETRY_BUDGET: %s", value)
+	}
+	return limit, nil
+}
+
+// withRetryBudget 返回带有重试预算的 context，随分析的 ctx 传给工具和数据请求，
+// 并发的分析各自使用自己的预算
+func withRetryBudget(ctx context.Context, b *retryBudget) context.Context {
+	return context.WithValue(ctx, retryBudgetKey{}, b)
+}
+
+// retryBudgetFrom 返回 context 中的重试预算，没有时返回 nil（不限制）
+func retryBudgetFrom(ctx context.Context) *retryBudget {
+	b, _ := ctx.Value(retryBudgetKey{}).(*retryBudget)
+	return b
+}
+
+// reserve 为一次等待预留 delay，预算不足时返回 false 并记录跳过的数据；没有预算时总是允许
+func (b *retryBudget) reserve(rawURL string, delay time.Duration) bool {
+	if b == nil || b.limit == 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.waited+delay <= b.limit {
+		b.waited += delay
+		return true
+	}
+	dataset := budgetDataset(rawURL)
+	for _, s := range b.skipped {
+		if s == dataset {
+			return false
+		}
+	}
+	b.skipped = append(b.skipped, dataset)
+	return false
+}
+
+// Waited 已经用掉的等待时间
+func (b *retryBudget) Waited() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.waited
+}
+
+// Skipped 因预算用完而跳过的数据
+func (b *retryBudget) Skipped() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.skipped...)
+}
+
+// reportNote 有数据被跳过时附加到报告末尾的说明，否则为空
+func (b *retryBudget) reportNote() string {
+	skipped := b.Skipped()
+	if len(skipped) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("\n\n---\n\n")
+	fmt.Fprintf(&sb, "> ⚠️ **部分数据缺失**：数据源持续限流，本次分析的重试等待已达到上限 %s（RETRY_BUDGET），以下数据被跳过，相关结论仅基于已获取的数据：\n", b.limit)
+	for _, s := range skipped {
+		fmt.Fprintf(&sb, "> - `%s`\n", s)
+	}
+	return sb.String()
+}
+
+// budgetDataset 用于说明被跳过数据的名称：接口路径加股票代码，如 /financial-metrics/ (AAPL)
+func budgetDataset(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	if ticker := u.Query().Get("ticker"); ticker != "" {
+		return fmt.Sprintf("%s (%s)", u.Path, ticker)
+	}
+	return u.Path
+}
+
+// errRetryBudgetExhausted 预算用完时返回的错误，包装 ErrRateLimited，工具按普通限流错误处理并在输出中说明
+func errRetryBudgetExhausted(rawURL string) error {
+	return fmt.Errorf("本次分析的限流重试时间已用完，跳过 %s: %w", budgetDataset(rawURL), tools.ErrRateLimited)
+}
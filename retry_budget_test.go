@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"investment/tools"
+)
+
+func TestRetryBudgetPerRun(t *testing.T) {
+	// 两个并发的分析各有自己的预算，一个用完预算不影响另一个，跳过的数据只记在自己的报告里
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		header := make(http.Header)
+		header.Set("Retry-After", "60")
+		return &http.Response{StatusCode: http.StatusTooManyRequests, Header: header, Body: io.NopCloser(strings.NewReader("")), Request: r}, nil
+	})}
+	first := &retryBudget{limit: time.Second}
+	second := &retryBudget{limit: time.Second}
+	firstCtx := withRetryBudget(context.Background(), first)
+
+	_, err := makeAPIRequest(firstCtx, client, "https://api.test/prices/?ticker=AAPL", nil, "GET", nil, 3)
+	if !errors.Is(err, tools.ErrRateLimited) {
+		t.Fatalf("err = %v, want ErrRateLimited", err)
+	}
+	if got := first.Skipped(); len(got) != 1 || got[0] != "/prices/ (AAPL)" {
+		t.Errorf("first.Skipped() = %v, want [/prices/ (AAPL)]", got)
+	}
+	if got := second.Skipped(); len(got) != 0 {
+		t.Errorf("second.Skipped() = %v, want none", got)
+	}
+	if note := second.reportNote(); note != "" {
+		t.Errorf("second.reportNote() = %q, want empty", note)
+	}
+	if retryBudgetFrom(context.Background()) != nil {
+		t.Error("没有预算的 context 应不限制重试")
+	}
+}
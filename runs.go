@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"investment/tools"
+)
+
+// runsDir 分析运行元数据的保存目录
+var runsDir = filepath.Join("output", "runs")
+
+// RunRecord 一次分析运行的元数据，用于按组合和标签整理、查询历史分析
+type RunRecord struct {
+	ID          string    `json:"id"`
+	Symbol      string    `json:"symbol"`
+	Portfolio   string    `json:"portfolio,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	Note        string    `json:"note,omitempty"` // 关注列表中的备注
+	Model       string    `json:"model"`
+	StartedAt   time.Time `json:"started_at"`
+	FinishedAt  time.Time `json:"finished_at"`
+	Truncated   bool      `json:"truncated"`
+	StepLimited bool      `json:"step_limited,omitempty"` // Agent 达到最大推理步数，报告根据已收集的数据撰写
+	Rating      string    `json:"rating,omitempty"`       // 报告中的投资评级，未识别时为空
+	ReportPath  string    `json:"report_path"`
+
+	PromptVersion string `json:"prompt_version,omitempty"` // 生成报告所用提示词的内容哈希
+	ConfigVersion string `json:"config_version,omitempty"` // 运行时 .env 文件的内容哈希
+	SnapshotPath  string `json:"snapshot_path,omitempty"`  // 本次分析录制的数据快照包
+	CardPath      string `json:"card_path,omitempty"`      // 设置 SUMMARY_CARD=true 时的摘要卡片（同名 .json 为 JSON 版本）
+
+	PriorRuns      int      `json:"prior_runs,omitempty"`      // 注入系统提示词的以往分析次数（知识库），提示词与 prompt_version 不完全一致
+	JournalEntries int      `json:"journal_entries,omitempty"` // 注入系统提示词的交易日志决策条数，报告末尾附有最近一次的投资逻辑
+	SkippedData    []string `json:"skipped_data,omitempty"`    // 重试预算（RETRY_BUDGET）用完后跳过的数据，报告末尾有说明
+
+	Plan *AnalysisPlan `json:"plan,omitempty"` // 分析前按深度、市场和可用数据确定的工具和调用顺序
+
+	Cache *APICacheStats `json:"cache,omitempty"` // 本次分析期间数据源缓存各层的命中次数（服务模式下并发的分析会互相计入）
+}
+
+// 投资评级，与系统提示词中的评级一致
+const (
+	RatingStrongBuy = "强烈推荐"
+	RatingBuy       = "推荐"
+	RatingNeutral   = "中性"
+	RatingCautious  = "谨慎"
+	RatingAvoid     = "避免"
+)
+
+// ratingPattern 匹配报告中的 "投资评级：推荐"、"**评级**: 强烈推荐" 等写法，长的评级放在前面优先匹配
+var ratingPattern = regexp.MustCompile(`评级[*\s]*[：:][*\s]*(强烈推荐|推荐|中性|谨慎|避免)`)
+
+// extractRating 从报告中提取投资评级
+func extractRating(report string) string {
+	if m := ratingPattern.FindStringSubmatch(report); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// runFilter 历史运行的筛选条件，空字段表示不限制
+type runFilter struct {
+	Symbol    string
+	Portfolio string
+	Tag       string
+}
+
+// match 判断运行记录是否满足筛选条件
+func (f runFilter) match(r RunRecord) bool {
+	// 代码变更前的运行记录归到当前代码下（如按 META 查询时包括 FB 的记录）
+	if f.Symbol != "" && canonicalSymbol(r.Symbol) != canonicalSymbol(f.Symbol) {
+		return false
+	}
+	if f.Portfolio != "" && r.Portfolio != f.Portfolio {
+		return false
+	}
+	if f.Tag != "" {
+		for _, tag := range r.Tags {
+			if tag == f.Tag {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// parseTags 解析逗号分隔的标签，去除空白和重复项
+func parseTags(raw string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range strings.Split(raw, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// saveRunRecord 将运行元数据作为运行清单写入当前输出目标
+func saveRunRecord(record *RunRecord) error {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSON序列化失败: %v", err)
+	}
+	if _, err := tools.Output().WriteRunManifest(record.ID, data); err != nil {
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+	storeData("运行记录", func(s *dataStore) error { return s.saveRun(record) })
+	return nil
+}
+
+// loadRunRecord 按 ID 读取本地的运行记录，不存在时返回的错误满足 errors.Is(err, os.ErrNotExist)
+func loadRunRecord(id string) (*RunRecord, error) {
+	if !validPortfolioName(id) {
+		return nil, fmt.Errorf("无效的运行记录 ID: %s: %w", id, os.ErrNotExist)
+	}
+	data, err := os.ReadFile(filepath.Join(runsDir, id+".json"))
+	if err != nil {
+		return nil, err
+	}
+	var record RunRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("解析运行记录 %s 失败: %v", id, err)
+	}
+	return &record, nil
+}
+
+// listRunRecords 读取满足条件的运行记录，按开始时间倒序排列
+func listRunRecords(filter runFilter) ([]RunRecord, error) {
+	entries, err := os.ReadDir(runsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取运行记录目录失败: %v", err)
+	}
+
+	var records []RunRecord
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(runsDir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("读取运行记录失败: %v", err)
+		}
+		var record RunRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, fmt.Errorf("解析运行记录 %s 失败: %v", entry.Name(), err)
+		}
+		if filter.match(record) {
+			records = append(records, record)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].StartedAt.After(records[j].StartedAt)
+	})
+	return records, nil
+}
+
+// runListRuns 处理 runs 子命令：按股票、组合、标签筛选并列出历史分析
+func runListRuns(args []string) error {
+	fs := flag.NewFlagSet("runs", flag.ExitOnError)
+	var filter runFilter
+	fs.StringVar(&filter.Symbol, "symbol", "", "按股票代码筛选")
+	fs.StringVar(&filter.Portfolio, "portfolio", "", "按组合筛选")
+	fs.StringVar(&filter.Tag, "tag", "", "按标签筛选")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	records, err := listRunRecords(filter)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		fmt.Println("没有符合条件的分析记录")
+		return nil
+	}
+
+	for _, r := range records {
+		fmt.Printf("%s  %-6s  评级=%s  组合=%s  标签=%s  模型=%s  %s  %s\n",
+			r.StartedAt.Format("2006-01-02 15:04"), r.Symbol, orDash(r.Rating), orDash(r.Portfolio), orDash(strings.Join(r.Tags, ",")), r.Model, runStatus(r), r.ReportPath)
+	}
+	fmt.Printf("共 %d 条记录\n", len(records))
+	return nil
+}
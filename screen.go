@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"investment/tools"
+)
+
+// screenCandidate 筛选时一只股票的最新 TTM 指标
+type screenCandidate struct {
+	Symbol        string
+	ROE           float64
+	PE            float64
+	DebtToEquity  float64
+	RevenueGrowth float64
+	NetMargin     float64
+	MarketCap     float64
+	Currency      string
+}
+
+// screenCriteria 筛选条件，NaN 表示不限制
+type screenCriteria struct {
+	MinROE           float64
+	MaxPE            float64
+	MaxDebtToEquity  float64
+	MinRevenueGrowth float64
+}
+
+// match 判断是否满足筛选条件；条件对应的指标缺失时视为不满足
+func (c screenCriteria) match(s screenCandidate) bool {
+	check := func(limit, value float64, atLeast bool) bool {
+		if math.IsNaN(limit) {
+			return true
+		}
+		if math.IsNaN(value) {
+			return false
+		}
+		if atLeast {
+			return value >= limit
+		}
+		return value <= limit
+	}
+	// 亏损公司的市盈率没有意义，设置了市盈率上限时排除
+	if !math.IsNaN(c.MaxPE) && !(s.PE > 0) {
+		return false
+	}
+	return check(c.MinROE, s.ROE, true) && check(c.MaxPE, s.PE, false) &&
+		check(c.MaxDebtToEquity, s.DebtToEquity, false) && check(c.MinRevenueGrowth, s.RevenueGrowth, true)
+}
+
+// runScreen 处理 screen 子命令：获取指数成分股或给定股票的最新 TTM 指标，按条件筛选后以 ROE 从高到低列出；
+// --sort qvm 时再获取符合条件的股票的近一年价格，按它们之间的质量-估值-动量综合评分排序
+func runScreen(args []string) error {
+	fs := flag.NewFlagSet("screen", flag.ExitOnError)
+	index := fs.String("index", "", "股票池：指数名称（"+strings.Join(stockIndexNames(), "、")+"）")
+	tickers := fs.String("tickers", "", "股票池：逗号分隔的股票代码，与 --index 二选一")
+	minROE := fs.Float64("min-roe", math.NaN(), "ROE 下限，小数形式，如 0.15")
+	maxPE := fs.Float64("max-pe", math.NaN(), "市盈率上限，设置后排除亏损公司")
+	maxDE := fs.Float64("max-de", math.NaN(), "债务股权比上限")
+	minGrowth := fs.Float64("min-revenue-growth", math.NaN(), "营收增长率下限，小数形式，如 0.1")
+	top := fs.Int("top", 20, "最多列出的股票数")
+	sortBy := fs.String("sort", "roe", "排序方式：roe（ROE 从高到低）、qvm（质量-估值-动量综合评分，需要另外获取价格）")
+	weightsFlag := fs.String("weights", "", "--sort qvm 的子得分权重，如 quality=0.5,value=0.3,momentum=0.2，默认使用 QVM_WEIGHTS")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if (*index == "") == (*tickers == "") {
+		return fmt.Errorf("用法: %s", screenUsage)
+	}
+	if *top <= 0 {
+		return fmt.Errorf("无效的 --top: %d", *top)
+	}
+	if *sortBy != "roe" && *sortBy != "qvm" {
+		return fmt.Errorf("无效的 --sort: %s（可选 roe、qvm）", *sortBy)
+	}
+	weights, err := qvmWeightsFromEnv()
+	if *weightsFlag != "" {
+		weights, err = parseQVMWeights(*weightsFlag)
+	}
+	if err != nil {
+		return err
+	}
+	if err := configError(sharedConfigProblems()); err != nil {
+		return err
+	}
+
+	var symbols []string
+	source := *tickers
+	if *index != "" {
+		constituents, err := NewUniverseProvider().Get(*index)
+		if err != nil {
+			return err
+		}
+		symbols, source = constituents.Symbols, constituents.Name
+		if constituents.Stale {
+			fmt.Printf("⚠️ %s 成分股缓存较旧（更新于 %s），可运行 universe refresh %s\n", constituents.Name, constituents.UpdatedAt, *index)
+		}
+	} else {
+		symbols = parseSymbolList(*tickers)
+	}
+	criteria := screenCriteria{MinROE: *minROE, MaxPE: *maxPE, MaxDebtToEquity: *maxDE, MinRevenueGrowth: *minGrowth}
+
+	fmt.Printf("=== 股票筛选：%s（%d 只）===\n", source, len(symbols))
+	ctx := context.Background()
+	today := time.Now().Format("2006-01-02")
+	var matched []screenCandidate
+	var matchedMetrics []tools.FinancialMetrics
+	failed := 0
+	for i, symbol := range symbols {
+		if (i+1)%25 == 0 {
+			fmt.Printf("已检查 %d/%d，符合条件 %d 只\n", i+1, len(symbols), len(matched))
+		}
+		metrics, err := GetFinancialMetrics(ctx, symbol, today, "ttm", 1)
+		if err != nil {
+			if errors.Is(err, tools.ErrUnauthorized) {
+				return err
+			}
+			failed++
+			continue
+		}
+		candidate := newScreenCandidate(metrics[0])
+		if criteria.match(candidate) {
+			matched = append(matched, candidate)
+			matchedMetrics = append(matchedMetrics, metrics[0])
+		}
+	}
+
+	var scores map[string]QVMScore
+	if *sortBy == "qvm" && len(matched) > 0 {
+		// 没有综合得分的股票排在最后
+		scores = screenQVMScores(ctx, matched, matchedMetrics, weights)
+		sort.SliceStable(matched, func(i, j int) bool {
+			a, b := scores[matched[i].Symbol].Rank, scores[matched[j].Symbol].Rank
+			if (a > 0) != (b > 0) {
+				return a > 0
+			}
+			return a < b
+		})
+	} else {
+		sort.SliceStable(matched, func(i, j int) bool { return matched[i].ROE > matched[j].ROE })
+	}
+	fmt.Printf("\n符合条件 %d 只", len(matched))
+	if failed > 0 {
+		fmt.Printf("（%d 只没有获取到指标）", failed)
+	}
+	fmt.Println()
+	if len(matched) == 0 {
+		return nil
+	}
+	format, err := tools.NewNumberFormat(os.Getenv("REPORT_LOCALE"))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\n%-10s %8s %8s %8s %10s %8s %14s", "股票", "ROE", "P/E", "D/E", "营收增长", "净利率", "市值")
+	if scores != nil {
+		fmt.Printf(" %8s", "QVM")
+	}
+	fmt.Println()
+	for i, s := range matched {
+		if i >= *top {
+			fmt.Printf("…… 另有 %d 只，可调大 --top 查看\n", len(matched)-*top)
+			break
+		}
+		marketCap := tools.NotAvailable
+		if s.MarketCap > 0 {
+			marketCap = format.MoneyCompact(s.MarketCap, s.Currency)
+		}
+		fmt.Printf("%-10s %8s %8s %8s %10s %8s %14s", s.Symbol, screenPercent(s.ROE), screenNumber(s.PE), screenNumber(s.DebtToEquity),
+			screenPercent(s.RevenueGrowth), screenPercent(s.NetMargin), marketCap)
+		if scores != nil {
+			fmt.Printf(" %8s", qvmPoints(scores[s.Symbol].Score))
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// screenQVMScores 获取符合条件的股票的近一年价格，计算它们之间的综合评分，按股票代码返回
+func screenQVMScores(ctx context.Context, matched []screenCandidate, metrics []tools.FinancialMetrics, weights qvmWeights) map[string]QVMScore {
+	fmt.Printf("获取 %d 只股票的价格，计算综合评分（权重 %s）...\n", len(matched), weights)
+	scores := make([]QVMScore, len(matched))
+	for i, c := range matched {
+		scores[i] = collectQVMScore(ctx, c.Symbol, &metrics[i])
+	}
+	rankQVMScores(scores, weights)
+	bySymbol := make(map[string]QVMScore, len(scores))
+	for _, s := range scores {
+		bySymbol[s.Symbol] = s
+	}
+	return bySymbol
+}
+
+// newScreenCandidate 从财务指标中取出筛选使用的指标，缺失的为 NaN
+func newScreenCandidate(m tools.FinancialMetrics) screenCandidate {
+	value := func(v *float64) float64 {
+		if v == nil {
+			return math.NaN()
+		}
+		return *v
+	}
+	positive := func(v float64) float64 {
+		if v == 0 {
+			return math.NaN()
+		}
+		return v
+	}
+	currency := m.Currency
+	if currency == "" {
+		currency = tools.DefaultCurrency()
+	}
+	return screenCandidate{
+		Symbol:        m.Ticker,
+		ROE:           value(m.ReturnOnEquity),
+		PE:            positive(m.PriceToEarningsRatio),
+		DebtToEquity:  value(m.DebtToEquity),
+		RevenueGrowth: m.RevenueGrowth,
+		NetMargin:     value(m.NetMargin),
+		MarketCap:     m.MarketCap,
+		Currency:      currency,
+	}
+}
+
+// screenPercent 小数形式的比例格式化为百分数，缺失时为 n/a
+func screenPercent(v float64) string {
+	if math.IsNaN(v) {
+		return tools.NotAvailable
+	}
+	return fmt.Sprintf("%.1f%%", v*100)
+}
+
+// screenNumber 格式化倍数，缺失时为 n/a
+func screenNumber(v float64) string {
+	if math.IsNaN(v) {
+		return tools.NotAvailable
+	}
+	return fmt.Sprintf("%.2f", v)
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"investment/tools"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// maxSectionRetries 单个章节输出不完整或未通过校验时最多重新生成的次数
+const maxSectionRetries = 2
+
+// sectionPrompt 分段撰写报告时每个章节的用户提示词，依次为章节标题、章节要求、已收集的数据（sectionDataPrompt 或 sectionFilePrompt）、
+// Agent 的分析草稿和已完成的章节
+const sectionPrompt = `数据收集和分析已经完成，现在分章节撰写最终报告。请只撰写「%[1]s」这一章，以二级标题 "## %[1]s" 开头，不要撰写其他章节，不能再调用任何工具。
+
+本章要求：%[2]s
+
+%[3]s
+
+## Agent 的分析草稿
+
+%[4]s
+
+## 已完成的章节
+
+%[5]s`
+
+// sectionDataPrompt 内联已收集数据时的数据部分，%s 为截断后的数据
+const sectionDataPrompt = `只使用下面已收集的数据，缺少的数据请在本章中说明，不要编造数据。
+
+## 已收集的数据
+
+%s`
+
+// sectionFilePrompt 已收集的数据以附件提供时的数据部分，%s 为附件文件名
+const sectionFilePrompt = `只使用附件 %s 中已收集的数据（markdown，未截断，包含全部工具结果和中间分析），缺少的数据请在本章中说明，不要编造数据。`
+
+// reportSection 分段生成报告的一个章节
+type reportSection struct {
+	Title    string
+	Guidance string
+	MinRunes int                // 正文（不含标题）的最少字数，少于该值视为输出不完整
+	Validate func(string) error // 可选，章节特有的校验
+}
+
+// listItemPattern 匹配 markdown 列表项
+var listItemPattern = regexp.MustCompile(`(?m)^\s*([-*]|\d+[.、)])\s+`)
+
+// reportSections 分段生成的章节，按报告中的顺序排列；评级放在最后，依据前面已完成的章节给出
+var reportSections = []reportSection{
+	{
+		Title:    "公司概况",
+		Guidance: "根据公司简介工具返回的业务描述介绍公司实际从事的业务、主要收入来源、所属行业和市场地位，不要凭记忆描述。",
+		MinRunes: 150,
+	},
+	{
+		Title:    "财务与经营分析",
+		Guidance: "展示关键财务数据和多年趋势（盈利能力、成长性、财务稳健性、营运资本），包含管理层质量评分和各项检查结论，按新闻主题分类说明新闻影响，并引用同行对比和竞争地位评分。",
+		MinRunes: 400,
+	},
+	{
+		Title:    "估值分析",
+		Guidance: "以实时行情的价格为当前股价，结合估值倍数、同行对比和蒙特卡洛估值结果，以 P10/P50/P90 估值区间的形式给出目标价位，并说明目标价合理性检查的结论。",
+		MinRunes: 200,
+		Validate: func(content string) error {
+			if !strings.Contains(content, "P50") && !strings.Contains(content, "目标价") {
+				return fmt.Errorf("没有给出估值区间（P10/P50/P90）或目标价")
+			}
+			return nil
+		},
+	},
+	{
+		Title:    "风险提示",
+		Guidance: "以列表逐条列出主要风险，单独列出诉讼和监管风险，包含信用风险、营运资本预警和流动性限制（如有）。",
+		MinRunes: 150,
+		Validate: func(content string) error {
+			if len(listItemPattern.FindAllString(content, -1)) < 2 {
+				return fmt.Errorf("风险没有以列表形式逐条列出")
+			}
+			return nil
+		},
+	},
+	{
+		Title:    "投资结论与评级",
+		Guidance: "综合以上章节，用 \"投资评级：<评级>\" 的格式给出明确的投资评级（强烈推荐/推荐/中性/谨慎/避免），说明基本面评分和竞争地位评分对评级的影响，并给出仓位建议。结论必须与已完成章节中的估值和风险一致。",
+		MinRunes: 100,
+		Validate: func(content string) error {
+			if extractRating(content) == "" {
+				return fmt.Errorf("没有按 \"投资评级：<评级>\" 的格式给出评级（强烈推荐/推荐/中性/谨慎/避免）")
+			}
+			return nil
+		},
+	},
+}
+
+// sectionedReportEnabled SECTIONED_REPORT=true 时在 Agent 完成数据收集后分章节撰写最终报告
+func sectionedReportEnabled() bool {
+	return os.Getenv("SECTIONED_REPORT") == "true"
+}
+
+// writeSectionedReport 不绑定工具、逐章调用模型撰写最终报告，每章单独校验（标题、长度、是否被截断、章节特有要求），
+// 不通过时把问题反馈给模型重新生成，重试后仍失败的章节替换为说明；Agent 的最终回复作为草稿提供给每一章
+// files 不为空且数据超出内联长度时，只上传一次未截断的数据，每一章都引用同一个附件
+func writeSectionedReport(ctx context.Context, chatModel model.BaseChatModel, files modelFileUploader, systemPrompt, userPrompt string, progress *analysisProgress, events *progressEmitter, printer *terminalPrinter) (string, error) {
+	data := progress.gatheredData(completionToolResultChars)
+	dataPrompt := fmt.Sprintf(sectionDataPrompt, data)
+	attachment := uploadGatheredData(ctx, files, progress, data)
+	if attachment != nil {
+		dataPrompt = fmt.Sprintf(sectionFilePrompt, gatheredDataFileName)
+	}
+	draft := strings.TrimSpace(progress.draft())
+	if draft == "" {
+		draft = "（没有草稿）"
+	}
+
+	var written []string
+	failed := 0
+	for _, section := range reportSections {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		previous := "（无）"
+		if len(written) > 0 {
+			previous = strings.Join(written, "\n\n")
+		}
+		request := schema.UserMessage(fmt.Sprintf(sectionPrompt, section.Title, section.Guidance, dataPrompt, draft, previous))
+		if attachment != nil {
+			request = fileMessage(request.Content, *attachment)
+		}
+		messages := []*schema.Message{
+			schema.SystemMessage(systemPrompt),
+			schema.UserMessage(userPrompt),
+			request,
+		}
+		content, err := generateSection(ctx, chatModel, messages, section, events, printer)
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", err
+			}
+			failed++
+			tools.Logger(ctx).Printf("[SectionedReport] 章节「%s」生成失败: %v", section.Title, err)
+			content = fmt.Sprintf("## %s\n\n> 说明: 本章生成失败（%v），请参考其他章节或重新分析。", section.Title, err)
+		}
+		written = append(written, content)
+	}
+	if failed == len(reportSections) {
+		return "", fmt.Errorf("全部 %d 个章节生成失败", failed)
+	}
+	return strings.Join(written, "\n\n"), nil
+}
+
+// generateSection 生成并校验一个章节，不通过时附上模型的输出和问题重新请求，最多重试 maxSectionRetries 次
+func generateSection(ctx context.Context, chatModel model.BaseChatModel, messages []*schema.Message, section reportSection, events *progressEmitter, printer *terminalPrinter) (string, error) {
+	for attempt := 0; ; attempt++ {
+		stream, err := chatModel.Stream(ctx, messages)
+		if err != nil {
+			return "", fmt.Errorf("调用模型失败: %w", err)
+		}
+		msg, err := readMessageStream(stream, events, printer)
+		if err != nil {
+			return "", fmt.Errorf("读取模型输出失败: %w", err)
+		}
+		content, err := validateSection(msg, section)
+		if err == nil {
+			return content, nil
+		}
+		if attempt >= maxSectionRetries {
+			return "", fmt.Errorf("已重试%d次: %w", attempt, err)
+		}
+		tools.Logger(ctx).Printf("[SectionedReport] 章节「%s」不完整，重新生成（第%d次）: %v", section.Title, attempt+1, err)
+		messages = append(messages,
+			schema.AssistantMessage(msg.Content, nil),
+			schema.UserMessage(fmt.Sprintf("上面的输出有问题：%v。请重新完整撰写「%s」这一章，以 \"## %s\" 开头。", err, section.Title, section.Title)),
+		)
+	}
+}
+
+// validateSection 检查章节输出：没有因长度上限被截断、以本章标题开头（缺少标题时补上）、正文达到最少字数并满足章节特有要求
+func validateSection(msg *schema.Message, section reportSection) (string, error) {
+	if msg.ResponseMeta != nil {
+		switch strings.ToLower(msg.ResponseMeta.FinishReason) {
+		case "length", "max_tokens":
+			return "", fmt.Errorf("输出达到长度上限被截断")
+		}
+	}
+	content := strings.TrimSpace(msg.Content)
+	heading := "## " + section.Title
+	if !strings.HasPrefix(content, "#") {
+		content = heading + "\n\n" + content
+	} else if firstLine, _, _ := strings.Cut(content, "\n"); !strings.Contains(firstLine, section.Title) {
+		return "", fmt.Errorf("标题不是 \"%s\"", heading)
+	}
+	_, body, _ := strings.Cut(content, "\n")
+	body = strings.TrimSpace(body)
+	if n := utf8.RuneCountInString(body); n < section.MinRunes {
+		return "", fmt.Errorf("正文只有 %d 字，少于 %d 字", n, section.MinRunes)
+	}
+	if section.Validate != nil {
+		if err := section.Validate(body); err != nil {
+			return "", err
+		}
+	}
+	return content, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// sectionModel 按章节顺序返回能通过校验的章节，记录每次请求的最后一条消息
+type sectionModel struct {
+	requests []*schema.Message
+}
+
+func (m *sectionModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	section := reportSections[len(m.requests)]
+	m.requests = append(m.requests, input[len(input)-1])
+	body := strings.Repeat("数据", section.MinRunes) + "\n\nP50 目标价\n\n- 风险一\n- 风险二\n\n投资评级：推荐"
+	return schema.AssistantMessage("## "+section.Title+"\n\n"+body, nil), nil
+}
+
+func (m *sectionModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	msg, err := m.Generate(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return schema.StreamReaderFromArray([]*schema.Message{msg}), nil
+}
+
+// countingUploader 记录上传次数，返回固定的文件引用
+type countingUploader struct {
+	uploads int
+}
+
+func (u *countingUploader) Upload(ctx context.Context, name, mimeType string, data []byte) (schema.ChatMessagePart, error) {
+	u.uploads++
+	return schema.ChatMessagePart{Type: schema.ChatMessagePartTypeText, Text: openAIFileRef("file-abc123")}, nil
+}
+
+func TestSectionedReportUploadsOnce(t *testing.T) {
+	// 数据超出内联长度时只上传一次，每一章都引用附件而不是内联数据
+	progress := &analysisProgress{}
+	progress.addMessage(&schema.Message{Role: schema.Tool, ToolName: "get_financial_metrics", Content: strings.Repeat("x", completionToolResultChars+1)})
+	chatModel := &sectionModel{}
+	files := &countingUploader{}
+
+	if _, err := writeSectionedReport(context.Background(), chatModel, files, "system", "user", progress, nil, nil); err != nil {
+		t.Fatalf("writeSectionedReport: %v", err)
+	}
+	if files.uploads != 1 {
+		t.Errorf("uploads = %d, want 1", files.uploads)
+	}
+	if len(chatModel.requests) != len(reportSections) {
+		t.Fatalf("requests = %d, want %d", len(chatModel.requests), len(reportSections))
+	}
+	for i, request := range chatModel.requests {
+		if request.Content != "" || len(request.MultiContent) != 2 || request.MultiContent[1].Text != openAIFileRef("file-abc123") {
+			t.Fatalf("章节 %d 没有引用附件: %+v", i, request)
+		}
+		if prompt := request.MultiContent[0].Text; strings.Contains(prompt, "xxxx") || !strings.Contains(prompt, gatheredDataFileName) {
+			t.Errorf("章节 %d 的提示词仍内联了数据", i)
+		}
+	}
+}
@@ -0,0 +1,554 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"investment/tools"
+)
+
+// 服务模式下分析任务的状态
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// analysisJob 服务模式下的一次异步分析任务，保存在 jobsDir 中，服务重启后未完成的任务从检查点恢复
+type analysisJob struct {
+	ID            string          `json:"id" description:"任务 ID"`
+	Symbol        string          `json:"symbol" description:"股票代码"`
+	Status        string          `json:"status" enum:"running,succeeded,failed" description:"任务状态"`
+	Error         string          `json:"error,omitempty" description:"任务失败的原因"`
+	PromptVersion string          `json:"prompt_version" description:"任务使用的提示词版本"`
+	CreatedAt     time.Time       `json:"created_at" description:"任务创建时间"`
+	Progress      jobProgress     `json:"progress" description:"任务进度，每完成一次工具调用或一个章节时保存检查点"`
+	Quota         *jobQuotaStatus `json:"quota,omitempty" description:"任务的配额和当前用量，配置了 JOB_MAX_* 或任务时间上限时返回"`
+	Run           *RunRecord      `json:"run,omitempty"` // 任务成功后的运行记录
+
+	request    jobRequest    // 创建任务时的请求参数
+	checkpoint jobCheckpoint // 已完成的工具调用和章节
+}
+
+// snapshot 返回任务的副本，调用方持有 s.mu；配额状态和进度中的切片也复制一份，
+// 释放锁后编码副本时 runJob 仍可能更新原任务
+func (j *analysisJob) snapshot() analysisJob {
+	snapshot := *j
+	snapshot.Progress.Sections = slices.Clone(j.Progress.Sections)
+	if j.Quota != nil {
+		quota := *j.Quota
+		quota.Exceeded = slices.Clone(j.Quota.Exceeded)
+		snapshot.Quota = &quota
+	}
+	return snapshot
+}
+
+// jobProgress 任务进度和恢复状态
+type jobProgress struct {
+	ToolCalls     int        `json:"tool_calls" description:"已成功完成的工具调用次数"`
+	Sections      []string   `json:"sections,omitempty" description:"报告中已输出完成的章节"`
+	Resumed       int        `json:"resumed,omitempty" description:"服务重启后恢复任务的次数"`
+	ResumedAt     *time.Time `json:"resumed_at,omitempty" description:"最近一次恢复的时间"`
+	ReplayedCalls int        `json:"replayed_calls,omitempty" description:"最近一次恢复后直接从检查点返回的工具调用次数"`
+}
+
+// analyzeRequestBody POST /analyze 的请求体
+type analyzeRequestBody struct {
+	Symbol     string   `json:"symbol" description:"股票代码，如 AAPL、600519.SS"`
+	Portfolio  string   `json:"portfolio,omitempty" description:"组合名称"`
+	Tags       []string `json:"tags,omitempty" description:"运行记录的标签"`
+	Transcript string   `json:"transcript,omitempty" enum:"none,reasoning,full" description:"推理过程记录级别，默认 none"`
+}
+
+// analysisServer 以 HTTP 接口提供分析服务，每个任务开始时读取当前的提示词和配置
+type analysisServer struct {
+	prompts     *promptStore
+	bus         *eventBus // 服务启动时按 EVENT_BUS 创建，修改后需重启服务
+	timeout     time.Duration
+	toolTimeout time.Duration
+	routes      []serverRoute // 注册的接口，GET /openapi.json 据此生成文档
+
+	mu     sync.Mutex
+	jobs   map[string]*analysisJob
+	nextID int
+
+	watchlistMu sync.Mutex // 串行修改 watchlistsDir 中的关注列表
+}
+
+// runServe 处理 serve 子命令
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "监听地址")
+	timeout := fs.Duration("timeout", 10*time.Minute, "单个分析任务的最长时间（0 表示不限制）")
+	toolTimeout := fs.Duration("tool-timeout", 2*time.Minute, "单次工具调用的最长时间（0 表示不限制）")
+	reloadInterval := fs.Duration("reload-interval", 2*time.Second, "检查提示词和 .env 文件变化的间隔")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := applyEnvDefaults(fs, map[string]string{"timeout": "ANALYSIS_TIMEOUT", "tool-timeout": "TOOL_TIMEOUT"}); err != nil {
+		return err
+	}
+
+	// 启动时检查配置；运行中修改配置文件后，每个任务开始前会再次检查
+	if err := validateAnalysisConfig(); err != nil {
+		return err
+	}
+
+	prompts, err := newPromptStore(promptsDir())
+	if err != nil {
+		return err
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go prompts.watch(*reloadInterval, stop)
+	go watchConfig(appEnv, *reloadInterval, stop)
+
+	bus, err := newEventBusFromEnv()
+	if err != nil {
+		return err
+	}
+	defer bus.Close()
+
+	server := &analysisServer{
+		prompts:     prompts,
+		bus:         bus,
+		timeout:     *timeout,
+		toolTimeout: *toolTimeout,
+		routes:      serverRoutes,
+		jobs:        make(map[string]*analysisJob),
+	}
+	if err := server.resumeJobs(); err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	registerRoutes(mux, server, server.routes)
+
+	log.Printf("分析服务已启动: %s，提示词版本 %s", *addr, prompts.Get().Version)
+	return http.ListenAndServe(*addr, mux)
+}
+
+// watchConfig 按 interval 检查配置文件，内容变化时重新加载到环境变量，之后启动的任务使用新配置
+// 启动时已存在的环境变量仍然优先，不会被配置文件覆盖
+func watchConfig(config *envConfig, interval time.Duration, stop <-chan struct{}) {
+	version := config.version()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			current := config.version()
+			if current == version || current == "" {
+				continue
+			}
+			if _, err := config.apply(); err != nil {
+				log.Printf("重新加载配置失败: %v", err)
+				continue
+			}
+			version = current
+			resetDataAPIClient()
+			log.Printf("配置已重新加载，版本 %s", version)
+		}
+	}
+}
+
+// handleAnalyze 创建分析任务，立即返回任务信息，分析在后台执行
+func (s *analysisServer) handleAnalyze(w http.ResponseWriter, r *http.Request) {
+	var body analyzeRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("无效的请求体: %v", err))
+		return
+	}
+	symbol := canonicalSymbol(body.Symbol)
+	if symbol == "" {
+		writeJSONError(w, http.StatusBadRequest, "symbol 不能为空")
+		return
+	}
+	if body.Portfolio != "" && !validPortfolioName(body.Portfolio) {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("无效的组合名称: %s", body.Portfolio))
+		return
+	}
+	if body.Transcript == "" {
+		body.Transcript = TranscriptNone
+	}
+	if err := validateTranscriptLevel(body.Transcript); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// 同时运行的任务数达到 JOB_MAX_RUNNING 时拒绝新任务，客户端按 Retry-After 稍后重试
+	maxRunning, err := positiveIntEnv("JOB_MAX_RUNNING", 0)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	prompts := s.prompts.Get()
+	s.mu.Lock()
+	if running := s.runningJobs(); maxRunning > 0 && running >= maxRunning {
+		s.mu.Unlock()
+		w.Header().Set("Retry-After", "30")
+		writeJSONError(w, http.StatusTooManyRequests, fmt.Sprintf("同时运行的分析任务已达上限 %d 个（JOB_MAX_RUNNING），请稍后重试", maxRunning))
+		return
+	}
+	s.nextID++
+	job := &analysisJob{
+		ID:            fmt.Sprintf("job-%d", s.nextID),
+		Symbol:        symbol,
+		Status:        JobRunning,
+		PromptVersion: prompts.Version,
+		CreatedAt:     time.Now(),
+		request: jobRequest{
+			Portfolio:  body.Portfolio,
+			Tags:       body.Tags,
+			Transcript: body.Transcript,
+		},
+	}
+	s.jobs[job.ID] = job
+	s.persist(job)
+	snapshot := job.snapshot()
+	s.mu.Unlock()
+
+	go s.runJob(job, s.jobAnalysisRequest(job, prompts))
+	writeJSON(w, http.StatusAccepted, snapshot)
+}
+
+// runningJobs 正在运行的任务数，调用方持有 s.mu
+func (s *analysisServer) runningJobs() int {
+	n := 0
+	for _, job := range s.jobs {
+		if job.Status == JobRunning {
+			n++
+		}
+	}
+	return n
+}
+
+// jobAnalysisRequest 按任务的请求参数创建分析请求，调用方持有 s.mu
+func (s *analysisServer) jobAnalysisRequest(job *analysisJob, prompts PromptSet) analysisRequest {
+	return analysisRequest{
+		Symbol:    job.Symbol,
+		Portfolio: job.request.Portfolio,
+		Tags:      job.request.Tags,
+		Timeout:   s.timeout,
+		Bus:       s.bus,
+		WarmStart: true,
+		Options: analysisOptions{
+			ToolTimeout: s.toolTimeout,
+			Transcript:  job.request.Transcript,
+			Prompts:     prompts,
+		},
+	}
+}
+
+// resumeJobs 读取保存的任务：已结束的任务可继续查询，服务退出时仍在运行的任务按当前提示词和配置重新发起，
+// 已完成的工具调用从检查点直接返回，不再请求数据源
+func (s *analysisServer) resumeJobs() error {
+	jobs, err := loadJobs()
+	if err != nil {
+		return err
+	}
+	prompts := s.prompts.Get()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, job := range jobs {
+		s.jobs[job.ID] = job
+		s.nextID = max(s.nextID, jobNumber(job.ID))
+		if job.Status != JobRunning {
+			continue
+		}
+		now := time.Now()
+		job.PromptVersion = prompts.Version
+		job.Progress.Resumed++
+		job.Progress.ResumedAt = &now
+		job.Progress.ReplayedCalls = 0
+		s.persist(job)
+		log.Printf("恢复分析任务 %s（%s），检查点中已完成 %d 次工具调用", job.ID, job.Symbol, len(job.checkpoint.ToolCalls))
+		go s.runJob(job, s.jobAnalysisRequest(job, prompts))
+	}
+	return nil
+}
+
+// persist 保存任务和检查点，失败时只记录日志，调用方持有 s.mu
+func (s *analysisServer) persist(job *analysisJob) {
+	if err := saveJob(job); err != nil {
+		log.Printf("保存分析任务 %s 失败: %v", job.ID, err)
+	}
+}
+
+// withCheckpoint 为任务的分析加上检查点：成功的工具调用和完成的章节随时保存，恢复时从检查点返回已完成的调用
+func (s *analysisServer) withCheckpoint(job *analysisJob, req analysisRequest) analysisRequest {
+	s.mu.Lock()
+	completed := append([]tools.CompletedToolCall(nil), job.checkpoint.ToolCalls...)
+	s.mu.Unlock()
+
+	req.Options.Checkpoint = &tools.ToolCheckpoint{
+		Completed: completed,
+		Record: func(call tools.CompletedToolCall) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			job.checkpoint.ToolCalls = append(job.checkpoint.ToolCalls, call)
+			job.Progress.ToolCalls = len(job.checkpoint.ToolCalls)
+			s.persist(job)
+		},
+		Replayed: func(tools.CompletedToolCall) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			job.Progress.ReplayedCalls++
+		},
+	}
+	next := req.Options.Progress
+	req.Options.Progress = func(event ProgressEvent) {
+		if event.Type == EventSectionCompleted {
+			s.mu.Lock()
+			if !slices.Contains(job.checkpoint.Sections, event.Section) {
+				job.checkpoint.Sections = append(job.checkpoint.Sections, event.Section)
+				job.Progress.Sections = append([]string(nil), job.checkpoint.Sections...)
+				s.persist(job)
+			}
+			s.mu.Unlock()
+		}
+		if next != nil {
+			next(event)
+		}
+	}
+	return req
+}
+
+// runJob 执行分析任务，模型在任务开始时按当前配置创建
+func (s *analysisServer) runJob(job *analysisJob, req analysisRequest) {
+	// 配置文件可能在服务运行中被修改，配置有误时任务直接失败，不创建模型
+	if err := validateAnalysisConfig(); err != nil {
+		log.Printf("分析任务 %s 失败: %v", job.ID, err)
+		s.mu.Lock()
+		job.Status = JobFailed
+		job.Error = err.Error()
+		s.persist(job)
+		s.mu.Unlock()
+		return
+	}
+	req = s.withCheckpoint(job, req)
+
+	// 任务配额：工具配额由工具包装检查，token 用量达到上限时以 jobQuotaError 取消分析，和超时一样输出部分报告
+	quota, _ := jobQuotaFromEnv()
+	req.Options.ToolQuota = quota.toolQuota()
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	chatModel, modelType := createChatModel(ctx)
+	// 用量统计放在 LLM 缓存之内，缓存命中的响应不计入 token 配额
+	tracked, usage := withUsageTracking(chatModel)
+	if quota.MaxTokens > 0 {
+		usage.onRecord = func(u ModelUsage) {
+			if used := u.PromptTokens + u.CompletionTokens; used >= quota.MaxTokens {
+				cancel(&jobQuotaError{Used: used, Limit: quota.MaxTokens})
+			}
+		}
+	}
+	chatModel = tracked
+	if llmCacheEnabled() {
+		chatModel = withLLMCache(chatModel, modelType)
+	}
+	req.Options.ModelType = modelType
+	next := req.Options.Progress
+	req.Options.Progress = func(event ProgressEvent) {
+		s.mu.Lock()
+		job.Quota = quota.status(req.Options.ToolQuota, usage.Usage(), req.Timeout)
+		s.mu.Unlock()
+		if next != nil {
+			next(event)
+		}
+	}
+	run, err := runAnalysis(ctx, chatModel, req)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.persist(job)
+	status := quota.status(req.Options.ToolQuota, usage.Usage(), req.Timeout)
+	if run != nil && run.Truncated && req.Timeout > 0 && context.Cause(ctx) == nil {
+		status.Exceeded = append(status.Exceeded, QuotaWallTime)
+	}
+	job.Quota = status
+	if err != nil {
+		log.Printf("分析任务 %s 失败: %v", job.ID, err)
+		job.Status = JobFailed
+		job.Error = err.Error()
+		return
+	}
+	job.Status = JobSucceeded
+	job.Run = run
+}
+
+// handleJob 查询任务状态
+func (s *analysisServer) handleJob(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	job, ok := s.jobs[r.PathValue("id")]
+	var snapshot analysisJob
+	if ok {
+		snapshot = job.snapshot()
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, "任务不存在")
+		return
+	}
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+// handleRuns 按 symbol、portfolio、tag 查询参数筛选历史运行记录
+func (s *analysisServer) handleRuns(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	records, err := listRunRecords(runFilter{
+		Symbol:    strings.ToUpper(query.Get("symbol")),
+		Portfolio: query.Get("portfolio"),
+		Tag:       query.Get("tag"),
+	})
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, records)
+}
+
+// handleRunReport 返回运行记录对应的报告全文
+func (s *analysisServer) handleRunReport(w http.ResponseWriter, r *http.Request) {
+	record, err := loadRunRecord(r.PathValue("id"))
+	if errors.Is(err, os.ErrNotExist) {
+		writeJSONError(w, http.StatusNotFound, "运行记录不存在")
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	// 使用 S3 等远程输出时 report_path 不是本地文件，按不存在处理
+	content, err := os.ReadFile(record.ReportPath)
+	if errors.Is(err, os.ErrNotExist) || record.ReportPath == "" {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("报告文件不存在: %s", record.ReportPath))
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("读取报告失败: %v", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, reportResponse{
+		RunID:      record.ID,
+		Symbol:     record.Symbol,
+		Rating:     record.Rating,
+		ReportPath: record.ReportPath,
+		Content:    string(content),
+	})
+}
+
+// handleWatchlists 列出全部关注列表
+func (s *analysisServer) handleWatchlists(w http.ResponseWriter, r *http.Request) {
+	lists, err := listWatchlists()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, lists)
+}
+
+// handleGetWatchlist 查询关注列表
+func (s *analysisServer) handleGetWatchlist(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if !validPortfolioName(name) {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("无效的关注列表名称: %s", name))
+		return
+	}
+	list, err := loadWatchlist(name)
+	if errors.Is(err, os.ErrNotExist) {
+		writeJSONError(w, http.StatusNotFound, "关注列表不存在")
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// handlePutWatchlist 创建关注列表或整体替换已有列表
+func (s *analysisServer) handlePutWatchlist(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if !validPortfolioName(name) {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("无效的关注列表名称: %s", name))
+		return
+	}
+	var body watchlistRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("无效的请求体: %v", err))
+		return
+	}
+	entries, err := normalizeWatchlistEntries(body.Entries)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.watchlistMu.Lock()
+	defer s.watchlistMu.Unlock()
+	list, err := saveWatchlist(name, entries)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// handleDeleteWatchlist 删除关注列表，返回删除前的内容
+func (s *analysisServer) handleDeleteWatchlist(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if !validPortfolioName(name) {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("无效的关注列表名称: %s", name))
+		return
+	}
+	s.watchlistMu.Lock()
+	defer s.watchlistMu.Unlock()
+	list, err := loadWatchlist(name)
+	if errors.Is(err, os.ErrNotExist) {
+		writeJSONError(w, http.StatusNotFound, "关注列表不存在")
+		return
+	}
+	if err == nil {
+		err = os.Remove(list.Path)
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// handlePrompts 返回当前生效的提示词版本和内容
+func (s *analysisServer) handlePrompts(w http.ResponseWriter, r *http.Request) {
+	prompts := s.prompts.Get()
+	writeJSON(w, http.StatusOK, promptsResponse{
+		Version: prompts.Version,
+		System:  prompts.System,
+		User:    prompts.User,
+	})
+}
+
+// writeJSON 输出 JSON 响应
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("写入响应失败: %v", err)
+	}
+}
+
+// writeJSONError 输出 {"error": ...} 形式的错误响应
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"investment/client"
+	"investment/tools"
+)
+
+func TestAnalysisJobSnapshotCopiesQuota(t *testing.T) {
+	// runJob 在锁外编码的副本不能与原任务共用配额状态
+	job := &analysisJob{ID: "AAPL_1", Quota: &jobQuotaStatus{ToolCalls: 1, Exceeded: make([]string, 0, 4)}}
+	snapshot := job.snapshot()
+	job.Quota.ToolCalls = 2
+	job.Quota.Exceeded = append(job.Quota.Exceeded, tools.QuotaToolBytes)
+	if snapshot.Quota == job.Quota || snapshot.Quota.ToolCalls != 1 || len(snapshot.Quota.Exceeded) != 0 {
+		t.Errorf("snapshot.Quota = %+v，随原任务一起改变", snapshot.Quota)
+	}
+}
+
+// newTestServer 用 serverRoutes 启动服务，关注列表和运行记录保存在临时目录
+func newTestServer(t *testing.T) *client.Client {
+	t.Helper()
+	dir := t.TempDir()
+	oldWatchlists, oldRuns := watchlistsDir, runsDir
+	watchlistsDir, runsDir = filepath.Join(dir, "watchlists"), filepath.Join(dir, "runs")
+	t.Cleanup(func() { watchlistsDir, runsDir = oldWatchlists, oldRuns })
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, &analysisServer{jobs: make(map[string]*analysisJob)}, serverRoutes)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return client.New(server.URL)
+}
+
+func TestWatchlistRoutes(t *testing.T) {
+	api := newTestServer(t)
+	ctx := context.Background()
+
+	list, err := api.PutWatchlist(ctx, "core", client.WatchlistRequestBody{Entries: []client.WatchlistEntry{
+		{Symbol: "aapl", Note: "关注服务收入"},
+		{Symbol: "NVDA"},
+		{Symbol: "AAPL", Note: "重复"},
+	}})
+	if err != nil {
+		t.Fatalf("PutWatchlist: %v", err)
+	}
+	if len(list.Entries) != 2 || list.Entries[0] != (client.WatchlistEntry{Symbol: "AAPL", Note: "关注服务收入"}) {
+		t.Errorf("entries = %+v", list.Entries)
+	}
+	// 保存的文件与 --watchlist 的格式相同
+	entries, err := readWatchlist(list.Path)
+	if err != nil || len(entries) != 2 || entries[0].Note != "关注服务收入" {
+		t.Errorf("readWatchlist = %+v, %v", entries, err)
+	}
+
+	lists, err := api.ListWatchlists(ctx)
+	if err != nil || len(lists) != 1 || lists[0].Name != "core" {
+		t.Errorf("ListWatchlists = %+v, %v", lists, err)
+	}
+	var apiErr *client.APIError
+	if _, err := api.PutWatchlist(ctx, "core", client.WatchlistRequestBody{Entries: []client.WatchlistEntry{{Symbol: "A/B"}}}); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("无效代码: err = %v, want 400", err)
+	}
+	if _, err := api.DeleteWatchlist(ctx, "core"); err != nil {
+		t.Fatalf("DeleteWatchlist: %v", err)
+	}
+	if _, err := api.GetWatchlist(ctx, "core"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("删除后: err = %v, want 404", err)
+	}
+}
+
+func TestRunReportRoute(t *testing.T) {
+	api := newTestServer(t)
+	report := filepath.Join(t.TempDir(), "AAPL.md")
+	if err := os.WriteFile(report, []byte("# AAPL\n\n投资评级：推荐"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(runsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(runsDir, "AAPL_1.json"), []byte(`{"id":"AAPL_1","symbol":"AAPL","rating":"推荐","report_path":"`+report+`"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := api.GetRunReport(context.Background(), "AAPL_1")
+	if err != nil {
+		t.Fatalf("GetRunReport: %v", err)
+	}
+	if got.Symbol != "AAPL" || got.Rating != "推荐" || got.Content != "# AAPL\n\n投资评级：推荐" {
+		t.Errorf("report = %+v", got)
+	}
+	var apiErr *client.APIError
+	if _, err := api.GetRunReport(context.Background(), "missing"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("不存在的记录: err = %v, want 404", err)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"investment/tools"
+)
+
+// loadShareholderReturns 从数据源（经过 API 缓存）获取最长 10 年的日收盘价和年度分红，计算股东回报
+// benchmark 为空时不与基准比较；分红或基准数据获取失败时仍返回只含股价收益或不含基准比较的结果
+func loadShareholderReturns(ctx context.Context, symbol, benchmarkSymbol string) (*tools.ShareholderReturns, error) {
+	years := slices.Max(tools.ShareholderReturnHorizons)
+	prices, err := GetPriceSeries(ctx, symbol, years)
+	if err != nil {
+		return nil, fmt.Errorf("获取价格数据失败: %v", err)
+	}
+
+	// 多取一个年度，让 10 年区间起点所在的财年也能按比例计入
+	var dividends []tools.DividendPerShare
+	periods, err := getCapitalAllocation(ctx, symbol, years+1)
+	if err != nil {
+		tools.Logger(ctx).Printf("[ShareholderReturns] 获取 %s 的分红数据失败，只计算股价收益: %v", symbol, err)
+	}
+	for _, p := range periods {
+		if p.Dividends == nil || p.SharesOutstanding == nil || *p.SharesOutstanding <= 0 {
+			continue
+		}
+		dividends = append(dividends, tools.DividendPerShare{Date: p.ReportPeriod, Amount: *p.Dividends / *p.SharesOutstanding})
+	}
+
+	var benchmark *tools.PriceSeries
+	if benchmarkSymbol != "" && benchmarkSymbol != symbol {
+		if benchmark, err = GetPriceSeries(ctx, benchmarkSymbol, years); err != nil {
+			tools.Logger(ctx).Printf("[ShareholderReturns] 获取基准 %s 的价格失败，不做基准比较: %v", benchmarkSymbol, err)
+			benchmark = nil
+		}
+	}
+	return tools.ComputeShareholderReturns(prices, dividends, benchmark), nil
+}
+
+// startShareholderReturns 在后台计算股东回报并记录到 progress，与 Agent 分析并行进行；返回的通道在计算完成后关闭
+func startShareholderReturns(ctx context.Context, symbol, benchmark string, progress *analysisProgress) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		start := time.Now()
+		returns, err := loadShareholderReturns(ctx, symbol, benchmark)
+		if err != nil {
+			tools.Logger(ctx).Printf("[ShareholderReturns] 计算 %s 的股东回报失败: %v", symbol, err)
+		}
+		progress.setShareholderReturns(returns, err)
+		tools.Logger(ctx).Printf("[ShareholderReturns] %s 的股东回报计算完成，用时 %s", symbol, time.Since(start).Round(time.Millisecond))
+	}()
+	return done
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/cloudwego/eino/components/embedding"
+
+	"investment/tools"
+)
+
+// 支持的文本嵌入提供方
+const (
+	EmbeddingLocal  = "local"  // 本地特征哈希词袋，不调用外部服务
+	EmbeddingOpenAI = "openai" // OpenAI 兼容的 /embeddings 接口
+)
+
+// 相似公司查找的默认配置
+const (
+	similarityProfileTTL       = 30 * 24 * time.Hour // 公司画像的缓存有效期
+	similarityDescriptionRunes = 2000                // 业务描述截取的最大字符数
+	localEmbeddingDims         = 512
+	embeddingBatchSize         = 64
+	defaultEmbeddingModel      = "text-embedding-3-small"
+)
+
+// similarityCachePath 公司画像和嵌入向量的本地缓存
+var similarityCachePath = filepath.Join("output", "similarity", "profiles.json")
+
+// similarityProfileEntry 缓存的公司画像，Model 变化或超过有效期时重新计算
+type similarityProfileEntry struct {
+	Profile   tools.CompanyProfile `json:"profile"`
+	Model     string               `json:"model"`
+	UpdatedAt time.Time            `json:"updated_at"`
+}
+
+// SimilarityService 基于公司画像嵌入在股票池中查找相似公司
+// 股票池默认为行业基准股票池，也可以是指数成分股或自定义列表；画像按股票缓存，只补齐缺失或过期的部分
+type SimilarityService struct {
+	universeName string
+	universe     func() ([]string, error)
+	embedder     embedding.Embedder
+	model        string
+	cachePath    string
+	ttl          time.Duration
+
+	mu       sync.Mutex
+	profiles map[string]*similarityProfileEntry
+}
+
+// NewSimilarityServiceFromEnv 根据环境变量创建相似公司服务
+// SIMILARITY_UNIVERSE: 候选股票池，指数名称（sp500、nasdaq100、csi300）或逗号分隔的股票代码，默认使用行业基准股票池；
+// EMBEDDING_PROVIDER: local（默认，本地词袋哈希）或 openai（使用 OPENAI_API_KEY，
+// EMBEDDING_BASE_URL 默认取 OPENAI_BASE_URL，EMBEDDING_MODEL_NAME 默认 text-embedding-3-small）
+func NewSimilarityServiceFromEnv(universeProvider *UniverseProvider) (*SimilarityService, error) {
+	s := &SimilarityService{cachePath: similarityCachePath, ttl: similarityProfileTTL}
+
+	raw := strings.TrimSpace(os.Getenv("SIMILARITY_UNIVERSE"))
+	switch _, isIndex := stockIndexes[strings.ToLower(raw)]; {
+	case raw == "":
+		benchmarkUniverse := NewIndustryBenchmarkServiceFromEnv().universe
+		s.universeName = "benchmark"
+		s.universe = func() ([]string, error) { return benchmarkUniverse, nil }
+	case isIndex:
+		index := strings.ToLower(raw)
+		s.universeName = index
+		s.universe = func() ([]string, error) {
+			constituents, err := universeProvider.Get(index)
+			if err != nil {
+				return nil, err
+			}
+			return constituents.Symbols, nil
+		}
+	default:
+		symbols := normalizeSymbols(strings.Split(raw, ","))
+		s.universeName = "custom"
+		s.universe = func() ([]string, error) { return symbols, nil }
+	}
+
+	switch provider := strings.ToLower(os.Getenv("EMBEDDING_PROVIDER")); provider {
+	case "", EmbeddingLocal:
+		s.embedder = localEmbedder{dims: localEmbeddingDims}
+		s.model = fmt.Sprintf("%s-hash-%d", EmbeddingLocal, localEmbeddingDims)
+	case EmbeddingOpenAI:
+		key := os.Getenv("OPENAI_API_KEY")
+		if key == "" {
+			return nil, fmt.Errorf("EMBEDDING_PROVIDER=openai 需要设置 OPENAI_API_KEY")
+		}
+		baseURL := os.Getenv("EMBEDDING_BASE_URL")
+		if baseURL == "" {
+			baseURL = os.Getenv("OPENAI_BASE_URL")
+		}
+		if baseURL == "" {
+			baseURL = "https://api.openai.com/v1"
+		}
+		modelName := os.Getenv("EMBEDDING_MODEL_NAME")
+		if modelName == "" {
+			modelName = defaultEmbeddingModel
+		}
+		s.embedder = &openAIEmbedder{baseURL: strings.TrimSuffix(baseURL, "/"), apiKey: key, model: modelName, client: newProxiedHTTPClient(60*time.Second, openAIProxyEnv...)}
+		s.model = EmbeddingOpenAI + "/" + modelName
+	default:
+		return nil, fmt.Errorf("不支持的 EMBEDDING_PROVIDER: %s（可选 %s、%s）", provider, EmbeddingLocal, EmbeddingOpenAI)
+	}
+	return s, nil
+}
+
+// Find 返回股票池中与 symbol 最相似的 limit 家公司
+func (s *SimilarityService) Find(ctx context.Context, symbol string, limit int) (*tools.SimilarCompaniesOutput, error) {
+	symbol = strings.ToUpper(symbol)
+	universe, err := s.universe()
+	if err != nil {
+		return nil, fmt.Errorf("获取股票池 %s 失败: %w", s.universeName, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.ensureProfiles(ctx, append([]string{symbol}, universe...)); err != nil {
+		return nil, err
+	}
+	target, ok := s.profiles[symbol]
+	if !ok {
+		return nil, fmt.Errorf("无法生成 %s 的公司画像", symbol)
+	}
+	candidates := make([]tools.CompanyProfile, 0, len(universe))
+	for _, candidate := range universe {
+		if entry, ok := s.profiles[candidate]; ok {
+			candidates = append(candidates, entry.Profile)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("股票池 %s 中没有可用的公司画像", s.universeName)
+	}
+	return &tools.SimilarCompaniesOutput{
+		Symbol:    symbol,
+		Universe:  s.universeName,
+		Embedding: s.model,
+		Companies: tools.RankSimilarCompanies(target.Profile, candidates, limit),
+	}, nil
+}
+
+// Peers 返回最相似的 limit 家公司代码，供同行对比在未配置可比公司组时使用
+func (s *SimilarityService) Peers(symbol string, limit int) ([]string, error) {
+	result, err := s.Find(context.Background(), symbol, limit)
+	if err != nil {
+		return nil, err
+	}
+	peers := make([]string, 0, len(result.Companies))
+	for _, company := range result.Companies {
+		peers = append(peers, company.Symbol)
+	}
+	return peers, nil
+}
+
+// ensureProfiles 加载本地缓存，并为缺失、过期或嵌入模型不一致的股票生成画像
+// 股票池中个别股票获取失败时跳过；目标股票（symbols[0]）获取失败时返回错误
+func (s *SimilarityService) ensureProfiles(ctx context.Context, symbols []string) error {
+	if s.profiles == nil {
+		s.profiles = make(map[string]*similarityProfileEntry)
+		if data, err := os.ReadFile(s.cachePath); err == nil {
+			if err := json.Unmarshal(data, &s.profiles); err != nil {
+				log.Printf("[Similarity] 解析缓存失败，将重新生成: %v", err)
+				s.profiles = make(map[string]*similarityProfileEntry)
+			}
+		}
+	}
+
+	var pending []tools.CompanyProfile
+	var texts []string
+	for i, symbol := range symbols {
+		if entry, ok := s.profiles[symbol]; ok && entry.Model == s.model && time.Since(entry.UpdatedAt) < s.ttl {
+			continue
+		}
+		if slices.ContainsFunc(pending, func(p tools.CompanyProfile) bool { return p.Symbol == symbol }) {
+			continue
+		}
+		profile, text, err := fetchCompanyProfile(ctx, symbol)
+		if err != nil {
+			if i == 0 || errors.Is(err, tools.ErrUnauthorized) {
+				return err
+			}
+			log.Printf("[Similarity] 跳过 %s: %v", symbol, err)
+			continue
+		}
+		pending = append(pending, profile)
+		texts = append(texts, text)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	log.Printf("[Similarity] 生成公司画像嵌入: 数量=%d, 模型=%s", len(pending), s.model)
+	now := time.Now()
+	for start := 0; start < len(pending); start += embeddingBatchSize {
+		end := min(start+embeddingBatchSize, len(pending))
+		vectors, err := s.embedder.EmbedStrings(ctx, texts[start:end])
+		if err != nil {
+			return fmt.Errorf("生成文本嵌入失败: %w", err)
+		}
+		if len(vectors) != end-start {
+			return fmt.Errorf("文本嵌入数量不一致: 请求 %d 条，返回 %d 条", end-start, len(vectors))
+		}
+		for i, vector := range vectors {
+			profile := pending[start+i]
+			profile.Embedding = vector
+			s.profiles[profile.Symbol] = &similarityProfileEntry{Profile: profile, Model: s.model, UpdatedAt: now}
+		}
+	}
+
+	if err := s.save(); err != nil {
+		log.Printf("[Similarity] 保存缓存失败: %v", err)
+	}
+	return nil
+}
+
+// save 将公司画像缓存写入本地文件
+func (s *SimilarityService) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.cachePath), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %v", err)
+	}
+	data, err := json.Marshal(s.profiles)
+	if err != nil {
+		return fmt.Errorf("JSON序列化失败: %v", err)
+	}
+	if err := os.WriteFile(s.cachePath, data, 0644); err != nil {
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+	return nil
+}
+
+// fetchCompanyProfile 获取公司的行业信息、最新 TTM 指标和年报业务描述，返回画像及用于嵌入的文本
+// 指标和业务描述获取失败时仍返回只含行业信息的画像
+func fetchCompanyProfile(ctx context.Context, symbol string) (tools.CompanyProfile, string, error) {
+	facts, err := GetCompanyFacts(ctx, symbol)
+	if err != nil {
+		return tools.CompanyProfile{}, "", fmt.Errorf("获取公司信息失败: %w", err)
+	}
+	profile := tools.CompanyProfile{Symbol: symbol, Name: facts.Name, Sector: facts.Sector, Industry: facts.Industry}
+	if metrics, err := GetFinancialMetrics(ctx, symbol, time.Now().Format("2006-01-02"), "ttm", 1); err == nil && len(metrics) > 0 {
+		profile.Metrics = tools.BenchmarkMetricValues(metrics[0])
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s\nSector: %s\nIndustry: %s\n", facts.Name, facts.Sector, facts.Industry))
+	if facts.SicIndustry != "" {
+		sb.WriteString(fmt.Sprintf("SIC: %s / %s\n", facts.SicSector, facts.SicIndustry))
+	}
+	sb.WriteString(businessDescription(ctx, symbol))
+	return profile, sb.String(), nil
+}
+
+// businessDescription 取最近一份 10-K 的业务描述（Item 1）开头部分，没有年报时返回空
+func businessDescription(ctx context.Context, symbol string) string {
+	text, _, _ := latestBusinessSection(ctx, symbol, similarityDescriptionRunes)
+	return text
+}
+
+// latestBusinessSection 取最近一份 10-K 业务章节（Item 1）的开头 maxRunes 个字符（空白已合并），
+// 返回文本、年报年度和文件地址，最近两年都没有年报时返回空
+func latestBusinessSection(ctx context.Context, symbol string, maxRunes int) (string, int, string) {
+	year := time.Now().Year()
+	for _, y := range []int{year - 1, year - 2} {
+		resp, err := GetFilingItems(ctx, symbol, "10-K", y, []string{"Item-1"})
+		if err != nil || len(resp.Items) == 0 {
+			continue
+		}
+		text := []rune(strings.Join(strings.Fields(resp.Items[0].Text), " "))
+		if len(text) > maxRunes {
+			text = text[:maxRunes]
+		}
+		return string(text), y, resp.URL
+	}
+	return "", 0, ""
+}
+
+// localEmbedder 本地特征哈希词袋嵌入：英文按单词、中文按单字切分，词频取对数后哈希到固定维度并归一化
+// 不需要外部服务，适合离线使用；语义理解弱于模型嵌入，但对同行业公司的业务描述已有足够区分度
+type localEmbedder struct {
+	dims int
+}
+
+// localStopWords 英文常见虚词，不参与嵌入
+var localStopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "our": true, "are": true, "that": true,
+	"this": true, "from": true, "which": true, "its": true, "inc": true, "company": true, "also": true,
+}
+
+// EmbedStrings 实现 embedding.Embedder
+func (e localEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		counts := make(map[string]int)
+		for _, token := range tokenize(text) {
+			counts[token]++
+		}
+		vector := make([]float64, e.dims)
+		for token, count := range counts {
+			h := fnv.New64a()
+			h.Write([]byte(token))
+			sum := h.Sum64()
+			weight := 1 + math.Log(float64(count))
+			if sum>>63 == 1 {
+				weight = -weight
+			}
+			vector[sum%uint64(e.dims)] += weight
+		}
+		norm := 0.0
+		for _, v := range vector {
+			norm += v * v
+		}
+		if norm > 0 {
+			norm = math.Sqrt(norm)
+			for j := range vector {
+				vector[j] /= norm
+			}
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+// tokenize 将文本切分为小写英文单词（至少3个字母，去掉虚词）和单个汉字
+func tokenize(text string) []string {
+	var tokens []string
+	var word []rune
+	flush := func() {
+		if token := string(word); len(word) >= 3 && !localStopWords[token] {
+			tokens = append(tokens, token)
+		}
+		word = word[:0]
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// openAIEmbedder 调用 OpenAI 兼容的 /embeddings 接口
+type openAIEmbedder struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+// EmbedStrings 实现 embedding.Embedder，按返回的 index 对齐输入顺序
+func (e *openAIEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	body, err := json.Marshal(map[string]any{"model": e.model, "input": texts})
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+e.apiKey)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("执行 HTTP 请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应体失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("嵌入接口返回错误: %w", tools.StatusError(resp.StatusCode, data))
+	}
+
+	var parsed struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("解析嵌入响应失败: %w", err)
+	}
+	vectors := make([][]float64, len(texts))
+	for _, item := range parsed.Data {
+		if item.Index < 0 || item.Index >= len(vectors) {
+			return nil, fmt.Errorf("嵌入响应的 index 越界: %d", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	return vectors, nil
+}
@@ -0,0 +1,494 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// snapshotsDir 每次分析录制的数据快照保存目录
+var snapshotsDir = filepath.Join("output", "snapshots")
+
+// snapshotManifestFile 快照包中的清单文件名，响应体保存在 bodies/ 下
+const snapshotManifestFile = "manifest.json"
+
+// snapshotSecretParams 保存到快照前从 URL 中移除的密钥参数（也不计入响应缓存的键），请求头（含 API Key）不会保存；
+// crumb 为 Yahoo Finance 的会话凭证
+var snapshotSecretParams = []string{"key", "api_key", "apikey", "token", "access_token", "crumb"}
+
+// SnapshotManifest 快照包清单，记录一次分析中所有 HTTP 请求及响应
+type SnapshotManifest struct {
+	RunID     string             `json:"run_id"`
+	Symbol    string             `json:"symbol"`
+	Model     string             `json:"model"`
+	CreatedAt time.Time          `json:"created_at"`
+	Exchanges []SnapshotExchange `json:"exchanges"`
+}
+
+// SnapshotExchange 一次 HTTP 请求及其响应，Seq 为发出请求的顺序
+type SnapshotExchange struct {
+	Seq         int               `json:"seq"`
+	Method      string            `json:"method"`
+	URL         string            `json:"url"`
+	RequestBody string            `json:"request_body,omitempty"`
+	Status      int               `json:"status"`
+	Header      map[string]string `json:"header,omitempty"`
+	BodyFile    string            `json:"body_file"`
+
+	body []byte
+}
+
+// activeSnapshot 当前生效的录制或回放 RoundTripper，为空时直接使用 sharedTransport
+var activeSnapshot atomic.Pointer[http.RoundTripper]
+
+// snapshotTransport 所有 HTTP 客户端的 Transport，录制或回放时转交给 activeSnapshot
+// 只有一次分析独占进程时（CLI）才启用录制，服务模式下多个任务并发时不录制
+type snapshotTransport struct {
+	base *http.Transport // 实际发出请求的 Transport，为空时使用 sharedTransport
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t snapshotTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if rt := activeSnapshot.Load(); rt != nil {
+		// 录制时仍通过该客户端的 Transport（代理配置）发出请求
+		if recorder, ok := (*rt).(*snapshotRecorder); ok {
+			return recorder.roundTrip(req, t.transport())
+		}
+		return (*rt).RoundTrip(req)
+	}
+	return t.transport().RoundTrip(req)
+}
+
+// transport 实际发出请求的 Transport
+func (t snapshotTransport) transport() *http.Transport {
+	if t.base != nil {
+		return t.base
+	}
+	return sharedTransport
+}
+
+// setActiveSnapshot 启用录制或回放，传入 nil 时恢复直接请求
+func setActiveSnapshot(rt http.RoundTripper) {
+	if rt == nil {
+		activeSnapshot.Store(nil)
+		return
+	}
+	activeSnapshot.Store(&rt)
+}
+
+// snapshotRecorder 透传请求并录制请求和响应，响应体在调用方读取时同步记录，不影响流式输出
+type snapshotRecorder struct {
+	mu        sync.Mutex
+	seq       int
+	exchanges []SnapshotExchange
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (r *snapshotRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r.roundTrip(req, sharedTransport)
+}
+
+// roundTrip 通过 transport 发出请求并录制
+func (r *snapshotRecorder) roundTrip(req *http.Request, transport http.RoundTripper) (*http.Response, error) {
+	requestBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	r.seq++
+	seq := r.seq
+	r.mu.Unlock()
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	exchange := SnapshotExchange{
+		Seq:         seq,
+		Method:      req.Method,
+		URL:         redactURL(req.URL),
+		RequestBody: string(requestBody),
+		Status:      resp.StatusCode,
+		Header:      snapshotHeader(resp.Header),
+	}
+	resp.Body = &recordingBody{ReadCloser: resp.Body, done: func(body []byte) {
+		exchange.body = body
+		r.mu.Lock()
+		r.exchanges = append(r.exchanges, exchange)
+		r.mu.Unlock()
+	}}
+	return resp, nil
+}
+
+// add 记录未经过 Transport 的响应（如数据源缓存命中），保证快照回放时仍能找到该请求
+func (r *snapshotRecorder) add(method, redactedURL, requestBody string, status int, header map[string]string, body []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	r.exchanges = append(r.exchanges, SnapshotExchange{
+		Seq:         r.seq,
+		Method:      method,
+		URL:         redactedURL,
+		RequestBody: requestBody,
+		Status:      status,
+		Header:      header,
+		body:        body,
+	})
+}
+
+// recordingBody 边读边记录响应体，关闭时提交记录
+type recordingBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	done func(body []byte)
+	once sync.Once
+}
+
+// Read 读取并记录响应体
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	return n, err
+}
+
+// Close 读完剩余内容后提交记录，调用方提前关闭时仍保存完整响应
+func (b *recordingBody) Close() error {
+	b.once.Do(func() {
+		_, _ = io.Copy(&b.buf, b.ReadCloser)
+		b.done(b.buf.Bytes())
+	})
+	return b.ReadCloser.Close()
+}
+
+// save 将录制内容写为 zip 快照包
+func (r *snapshotRecorder) save(path string, manifest SnapshotManifest) error {
+	r.mu.Lock()
+	exchanges := append([]SnapshotExchange(nil), r.exchanges...)
+	r.mu.Unlock()
+	sort.Slice(exchanges, func(i, j int) bool { return exchanges[i].Seq < exchanges[j].Seq })
+	for i := range exchanges {
+		exchanges[i].BodyFile = fmt.Sprintf("bodies/%04d", exchanges[i].Seq)
+	}
+	manifest.Exchanges = exchanges
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %v", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("创建快照文件失败: %v", err)
+	}
+	defer file.Close()
+
+	zw := zip.NewWriter(file)
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSON序列化失败: %v", err)
+	}
+	if err := writeZipFile(zw, snapshotManifestFile, data); err != nil {
+		return err
+	}
+	for _, exchange := range exchanges {
+		if err := writeZipFile(zw, exchange.BodyFile, exchange.body); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("写入快照文件失败: %v", err)
+	}
+	return nil
+}
+
+// writeZipFile 向 zip 包写入一个文件
+func writeZipFile(zw *zip.Writer, name string, data []byte) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("写入快照文件 %s 失败: %v", name, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("写入快照文件 %s 失败: %v", name, err)
+	}
+	return nil
+}
+
+// loadSnapshot 读取快照包
+func loadSnapshot(path string) (*SnapshotManifest, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开快照文件失败: %v", err)
+	}
+	defer zr.Close()
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	readFile := func(name string) ([]byte, error) {
+		f, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("快照中缺少文件: %s", name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("读取快照文件 %s 失败: %v", name, err)
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+
+	data, err := readFile(snapshotManifestFile)
+	if err != nil {
+		return nil, err
+	}
+	var manifest SnapshotManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("解析快照清单失败: %v", err)
+	}
+	for i := range manifest.Exchanges {
+		body, err := readFile(manifest.Exchanges[i].BodyFile)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Exchanges[i].body = body
+	}
+	return &manifest, nil
+}
+
+// snapshotReplayer 从快照中返回响应，不发起任何外部请求
+// 匹配顺序：完全相同的请求 → 忽略日期后相同的请求（默认日期取决于运行当天）→ 同一接口下一个未使用的响应（模型请求）
+type snapshotReplayer struct {
+	mu        sync.Mutex
+	exchanges []SnapshotExchange
+	used      []bool
+}
+
+// newSnapshotReplayer 创建快照回放
+func newSnapshotReplayer(manifest *SnapshotManifest) *snapshotReplayer {
+	return &snapshotReplayer{
+		exchanges: manifest.Exchanges,
+		used:      make([]bool, len(manifest.Exchanges)),
+	}
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (r *snapshotReplayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	requestBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	method, rawURL, body := req.Method, redactURL(req.URL), string(requestBody)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	index := r.find(func(e SnapshotExchange) bool {
+		return e.Method == method && e.URL == rawURL && e.RequestBody == body
+	})
+	if index < 0 {
+		index = r.find(func(e SnapshotExchange) bool {
+			return e.Method == method && withoutDates(e.URL) == withoutDates(rawURL) && withoutDates(e.RequestBody) == withoutDates(body)
+		})
+	}
+	if index < 0 {
+		index = r.find(func(e SnapshotExchange) bool {
+			recorded, err := url.Parse(e.URL)
+			return err == nil && e.Method == method && recorded.Host == req.URL.Host && recorded.Path == req.URL.Path
+		})
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("快照中没有请求 %s %s（回放模式不发起外部调用）", method, rawURL)
+	}
+	r.used[index] = true
+
+	exchange := r.exchanges[index]
+	header := make(http.Header, len(exchange.Header))
+	for key, value := range exchange.Header {
+		header.Set(key, value)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", exchange.Status, http.StatusText(exchange.Status)),
+		StatusCode:    exchange.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(exchange.body)),
+		ContentLength: int64(len(exchange.body)),
+		Request:       req,
+	}, nil
+}
+
+// find 返回第一个未使用且满足条件的记录，调用方需持有锁
+func (r *snapshotReplayer) find(match func(SnapshotExchange) bool) int {
+	for i, exchange := range r.exchanges {
+		if !r.used[i] && match(exchange) {
+			return i
+		}
+	}
+	return -1
+}
+
+// readRequestBody 读取请求体并重新设置，便于继续发送
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("读取请求体失败: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// redactURL 去除 URL 中的密钥参数
+func redactURL(u *url.URL) string {
+	redacted := *u
+	query := redacted.Query()
+	for _, key := range snapshotSecretParams {
+		query.Del(key)
+	}
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
+
+// snapshotHeader 保存回放需要的响应头
+func snapshotHeader(header http.Header) map[string]string {
+	kept := make(map[string]string)
+	for _, key := range []string{"Content-Type", "Retry-After", "X-Ratelimit-Limit", "X-Ratelimit-Remaining", "X-Ratelimit-Reset"} {
+		if value := header.Get(key); value != "" {
+			kept[key] = value
+		}
+	}
+	return kept
+}
+
+// datePattern 匹配 YYYY-MM-DD 格式的日期
+var datePattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}`)
+
+// withoutDates 将日期替换为占位符，用于匹配默认日期不同的请求
+func withoutDates(s string) string {
+	return datePattern.ReplaceAllString(s, "DATE")
+}
+
+// runSnapshot 处理 snapshot 子命令：export 导出某次分析的快照包，import 从快照包离线重跑分析
+func runSnapshot(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("用法: snapshot export <stock_symbol> [--run id] [--out file] | snapshot import <file> [--timeout 10m]")
+	}
+	switch args[0] {
+	case "export":
+		return exportSnapshot(args[1:])
+	case "import":
+		return importSnapshot(args[1:])
+	}
+	return fmt.Errorf("未知的 snapshot 子命令: %s", args[0])
+}
+
+// exportSnapshot 将股票最近一次（或指定）分析的快照包复制到指定路径
+func exportSnapshot(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("用法: snapshot export <stock_symbol> [--run id] [--out file]")
+	}
+	symbol := strings.ToUpper(args[0])
+	fs := flag.NewFlagSet("snapshot export", flag.ExitOnError)
+	runID := fs.String("run", "", "运行记录 ID，默认使用该股票最近一次带快照的分析")
+	out := fs.String("out", "", "输出文件，默认为当前目录下的 <symbol>_snapshot_<时间>.zip")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	records, err := listRunRecords(runFilter{Symbol: symbol})
+	if err != nil {
+		return err
+	}
+	var record *RunRecord
+	for i := range records {
+		if records[i].SnapshotPath != "" && (*runID == "" || records[i].ID == *runID) {
+			record = &records[i]
+			break
+		}
+	}
+	if record == nil {
+		return fmt.Errorf("没有找到 %s 带数据快照的分析记录", symbol)
+	}
+
+	data, err := os.ReadFile(record.SnapshotPath)
+	if err != nil {
+		return fmt.Errorf("读取快照失败: %v", err)
+	}
+	path := *out
+	if path == "" {
+		path = fmt.Sprintf("%s_snapshot_%s.zip", symbol, record.StartedAt.Format("2006-01-02_15-04-05"))
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+	fmt.Printf("📦 已导出 %s 的数据快照（运行 %s）: %s\n", symbol, record.ID, path)
+	return nil
+}
+
+// importSnapshot 使用快照包回放所有数据源和模型响应重跑分析，不发起外部调用
+func importSnapshot(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("用法: snapshot import <file> [--timeout 10m]")
+	}
+	fs := flag.NewFlagSet("snapshot import", flag.ExitOnError)
+	timeout := fs.Duration("timeout", 10*time.Minute, "整个分析的最长时间（0 表示不限制）")
+	transcript := fs.String("transcript", TranscriptNone, "报告附录中保存的分析过程：none、reasoning、full")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if err := validateTranscriptLevel(*transcript); err != nil {
+		return err
+	}
+
+	manifest, err := loadSnapshot(args[0])
+	if err != nil {
+		return err
+	}
+	prompts, err := loadPromptSet(promptsDir())
+	if err != nil {
+		return err
+	}
+
+	// 回放时使用录制时的模型类型；本机没有配置密钥也可以回放，使用占位值创建客户端
+	os.Setenv("MODEL_TYPE", manifest.Model)
+	for _, key := range []string{"GEMINI_API_KEY", "GEMINI_MODEL_NAME", "OPENAI_API_KEY", "DEEPSEEK_API_KEY"} {
+		if os.Getenv(key) == "" {
+			os.Setenv(key, "snapshot-replay")
+		}
+	}
+	setActiveSnapshot(newSnapshotReplayer(manifest))
+	defer setActiveSnapshot(nil)
+
+	fmt.Printf("=== 从数据快照重跑分析：%s（原运行 %s，共 %d 个请求）===\n", manifest.Symbol, manifest.RunID, len(manifest.Exchanges))
+	ctx := context.Background()
+	chatModel, modelType := createChatModel(ctx)
+	_, err = runAnalysis(ctx, chatModel, analysisRequest{
+		Symbol:  manifest.Symbol,
+		Tags:    []string{"snapshot-replay"},
+		Timeout: *timeout,
+		Options: analysisOptions{
+			Transcript: *transcript,
+			Stream:     StreamFormatted,
+			ModelType:  modelType,
+			Prompts:    prompts,
+		},
+	})
+	return err
+}
@@ -0,0 +1,621 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"investment/tools"
+)
+
+// statementsDir 用户导入的财务报表的保存目录，始终在本地磁盘
+var statementsDir = filepath.Join("output", "statements")
+
+// defaultTaxRate 报表中没有所得税费用时计算 ROIC 使用的税率
+const defaultTaxRate = 0.21
+
+// flowLineItems 期间发生额类报表项目，ttm 由最近四个季度相加；其余项目（资产、负债、股本等）取期末值
+var flowLineItems = map[string]bool{
+	"revenue":                                true,
+	"cost_of_revenue":                        true,
+	"gross_profit":                           true,
+	"operating_income":                       true,
+	"ebit":                                   true,
+	"ebitda":                                 true,
+	"net_income":                             true,
+	"interest_expense":                       true,
+	"income_tax_expense":                     true,
+	"depreciation_and_amortization":          true,
+	"net_cash_flow_from_operations":          true,
+	"capital_expenditure":                    true,
+	"free_cash_flow":                         true,
+	"share_based_compensation":               true,
+	"dividends_and_other_cash_distributions": true,
+	"issuance_or_purchase_of_equity_shares":  true,
+	"business_acquisitions_and_disposals":    true,
+}
+
+// StatementPeriod 一期财务报表，Items 的字段名与 FinancialDatasets 的报表项目一致（如 revenue、net_income、total_assets、
+// shareholders_equity），现金流出为负数；可选 share_price 或 market_cap 用于计算估值倍数
+type StatementPeriod struct {
+	ReportPeriod string             `json:"report_period"` // YYYY-MM-DD
+	Period       string             `json:"period"`        // annual 或 quarterly
+	Items        map[string]float64 `json:"items"`
+}
+
+// CompanyStatements 用户为未上市或数据源未覆盖的公司导入的财务报表，存在时替代数据源的财务指标和报表项目
+type CompanyStatements struct {
+	Symbol     string            `json:"symbol"`
+	Currency   string            `json:"currency"`
+	Source     string            `json:"source"` // 导入的文件
+	ImportedAt time.Time         `json:"imported_at"`
+	Periods    []StatementPeriod `json:"periods"` // 按报告期先后排列
+}
+
+// statementsPath 股票报表文件的路径
+func statementsPath(symbol string) string {
+	return filepath.Join(statementsDir, strings.ToUpper(symbol)+".json")
+}
+
+// loadCompanyStatements 读取导入的财务报表，没有导入时返回 nil
+func loadCompanyStatements(symbol string) (*CompanyStatements, error) {
+	data, err := os.ReadFile(statementsPath(symbol))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取导入的财务报表失败: %v", err)
+	}
+	var statements CompanyStatements
+	if err := json.Unmarshal(data, &statements); err != nil {
+		return nil, fmt.Errorf("解析导入的财务报表 %s 失败: %v", statementsPath(symbol), err)
+	}
+	return &statements, nil
+}
+
+// importCompanyStatements 从 CSV 或 JSON 文件导入财务报表，替换该股票以前导入的报表
+// CSV 每行一期，表头为 report_period、period（可省略，默认 annual）和报表项目名，空单元格表示缺失；
+// JSON 为 {"currency": "EUR", "periods": [{"report_period": "2024-12-31", "period": "annual", "items": {"revenue": 1000}}]}
+func importCompanyStatements(symbol, path, currency string) (*CompanyStatements, error) {
+	var statements CompanyStatements
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = readStatementsJSON(path, &statements)
+	} else {
+		statements.Periods, err = readStatementsCSV(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	statements.Symbol = strings.ToUpper(symbol)
+	statements.Source = path
+	statements.ImportedAt = time.Now()
+	if currency != "" {
+		statements.Currency = strings.ToUpper(currency)
+	}
+	if statements.Currency == "" {
+		statements.Currency = "USD"
+	}
+	if err := statements.validate(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(statements.Periods, func(i, j int) bool {
+		return statements.Periods[i].ReportPeriod < statements.Periods[j].ReportPeriod
+	})
+
+	data, err := json.MarshalIndent(&statements, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("JSON序列化失败: %v", err)
+	}
+	if err := os.MkdirAll(statementsDir, 0755); err != nil {
+		return nil, fmt.Errorf("创建目录失败: %v", err)
+	}
+	if err := os.WriteFile(statementsPath(symbol), data, 0644); err != nil {
+		return nil, fmt.Errorf("写入财务报表失败: %v", err)
+	}
+	return &statements, nil
+}
+
+// readStatementsJSON 读取 JSON 格式的报表
+func readStatementsJSON(path string, statements *CompanyStatements) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取文件失败: %v", err)
+	}
+	if err := json.Unmarshal(data, statements); err != nil {
+		return fmt.Errorf("解析 JSON 失败: %v", err)
+	}
+	return nil
+}
+
+// readStatementsCSV 读取 CSV 格式的报表，数值允许千分位逗号
+func readStatementsCSV(path string) ([]StatementPeriod, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %v", err)
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("解析 CSV 失败: %v", err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("CSV 文件没有数据行")
+	}
+	header := make([]string, len(records[0]))
+	for i, name := range records[0] {
+		header[i] = strings.ToLower(strings.TrimSpace(name))
+	}
+
+	var periods []StatementPeriod
+	for line, record := range records[1:] {
+		period := StatementPeriod{Period: "annual", Items: make(map[string]float64)}
+		for i, cell := range record {
+			if i >= len(header) {
+				break
+			}
+			cell = strings.TrimSpace(cell)
+			switch header[i] {
+			case "report_period":
+				period.ReportPeriod = cell
+			case "period":
+				if cell != "" {
+					period.Period = strings.ToLower(cell)
+				}
+			default:
+				if cell == "" || header[i] == "" {
+					continue
+				}
+				v, err := strconv.ParseFloat(strings.ReplaceAll(cell, ",", ""), 64)
+				if err != nil {
+					return nil, fmt.Errorf("第 %d 行 %s 不是数值: %q", line+2, header[i], cell)
+				}
+				period.Items[header[i]] = v
+			}
+		}
+		periods = append(periods, period)
+	}
+	return periods, nil
+}
+
+// validate 检查报告期、期间类型和报表项目
+func (s *CompanyStatements) validate() error {
+	if len(s.Periods) == 0 {
+		return fmt.Errorf("没有任何报表期间")
+	}
+	seen := make(map[string]bool)
+	for _, p := range s.Periods {
+		if _, err := time.Parse("2006-01-02", p.ReportPeriod); err != nil {
+			return fmt.Errorf("报告期无效: %q（格式为 YYYY-MM-DD）", p.ReportPeriod)
+		}
+		if p.Period != "annual" && p.Period != "quarterly" {
+			return fmt.Errorf("%s 的期间类型无效: %q（可选 annual、quarterly）", p.ReportPeriod, p.Period)
+		}
+		key := p.Period + "|" + p.ReportPeriod
+		if seen[key] {
+			return fmt.Errorf("%s 的 %s 报表重复", p.ReportPeriod, p.Period)
+		}
+		seen[key] = true
+		if len(p.Items) == 0 {
+			return fmt.Errorf("%s 的 %s 报表没有任何项目", p.ReportPeriod, p.Period)
+		}
+		for name, v := range p.Items {
+			if !tools.IsFinite(v) {
+				return fmt.Errorf("%s 的 %s 不是有限数", p.ReportPeriod, name)
+			}
+		}
+	}
+	return nil
+}
+
+// statementRow 计算指标用的一期数据：年度、季度，或由连续四个季度合成的 ttm
+type statementRow struct {
+	date  time.Time
+	items map[string]float64
+}
+
+// rows period（annual、quarterly、ttm）对应的数据，按报告期先后排列；
+// 没有连续四个季度时 ttm 使用年度报表
+func (s *CompanyStatements) rows(period string) []statementRow {
+	series := func(kind string) []statementRow {
+		var rows []statementRow
+		for _, p := range s.Periods {
+			if p.Period != kind {
+				continue
+			}
+			date, _ := time.Parse("2006-01-02", p.ReportPeriod)
+			rows = append(rows, statementRow{date: date, items: p.Items})
+		}
+		return rows
+	}
+	if period != "ttm" {
+		return series(period)
+	}
+
+	quarters := series("quarterly")
+	var ttm []statementRow
+	for i := 3; i < len(quarters); i++ {
+		// 四个季度的报告期应在一年之内，中间缺季度时不合成
+		if quarters[i].date.Sub(quarters[i-3].date) > 300*24*time.Hour {
+			continue
+		}
+		items := make(map[string]float64)
+		for name, v := range quarters[i].items {
+			if !flowLineItems[name] {
+				items[name] = v
+			}
+		}
+		for name := range flowLineItems {
+			sum, complete := 0.0, true
+			for _, q := range quarters[i-3 : i+1] {
+				v, ok := q.items[name]
+				if !ok {
+					complete = false
+					break
+				}
+				sum += v
+			}
+			if complete {
+				items[name] = sum
+			}
+		}
+		ttm = append(ttm, statementRow{date: quarters[i].date, items: items})
+	}
+	if len(ttm) == 0 {
+		return series("annual")
+	}
+	return ttm
+}
+
+// yearAgo 约一年前的一期，用于计算同比增长，没有时返回 nil
+func yearAgo(rows []statementRow, i int) *statementRow {
+	for j := i - 1; j >= 0; j-- {
+		days := rows[i].date.Sub(rows[j].date).Hours() / 24
+		if days >= 320 && days <= 410 {
+			return &rows[j]
+		}
+	}
+	return nil
+}
+
+// financialMetrics 由报表计算的财务指标，截至 endDate 的最近 limit 期，最新的在前，比例为小数形式
+func (s *CompanyStatements) financialMetrics(endDate, period string, limit int) ([]tools.FinancialMetrics, error) {
+	rows := s.rows(period)
+	end, err := time.Parse("2006-01-02", endDate)
+	if err != nil {
+		end = time.Now()
+	}
+	var metrics []tools.FinancialMetrics
+	for i := len(rows) - 1; i >= 0 && len(metrics) < limit; i-- {
+		if rows[i].date.After(end) {
+			continue
+		}
+		metrics = append(metrics, s.computeMetrics(period, rows[i], yearAgo(rows, i)))
+	}
+	if len(metrics) == 0 {
+		return nil, fmt.Errorf("%s 导入的报表中没有 %s 期间的数据: %w", s.Symbol, period, tools.ErrNoData)
+	}
+	return metrics, nil
+}
+
+// computeMetrics 计算一期的财务指标，缺少所需项目的指标留空（指针字段为 nil，其余为 0）
+func (s *CompanyStatements) computeMetrics(period string, row statementRow, prior *statementRow) tools.FinancialMetrics {
+	item := func(r *statementRow, name string) (float64, bool) {
+		if r == nil {
+			return 0, false
+		}
+		v, ok := r.items[name]
+		return v, ok
+	}
+	get := func(name string) (float64, bool) { return item(&row, name) }
+	ratio := func(a float64, aok bool, b float64, bok bool) (float64, bool) {
+		if !aok || !bok || b == 0 {
+			return 0, false
+		}
+		return a / b, true
+	}
+	value := func(v float64, ok bool) float64 {
+		if !ok {
+			return 0
+		}
+		return v
+	}
+	ptr := func(v float64, ok bool) *float64 {
+		if !ok {
+			return nil
+		}
+		return &v
+	}
+	// derivedOf 报表中没有直接给出时按其他项目推算
+	derivedOf := func(r *statementRow, name string) (float64, bool) {
+		if v, ok := item(r, name); ok {
+			return v, true
+		}
+		switch name {
+		case "gross_profit":
+			revenue, rok := item(r, "revenue")
+			cost, cok := item(r, "cost_of_revenue")
+			return revenue - cost, rok && cok
+		case "operating_income":
+			return item(r, "ebit")
+		case "ebitda":
+			ebit, ok := item(r, "operating_income")
+			if !ok {
+				ebit, ok = item(r, "ebit")
+			}
+			da, dok := item(r, "depreciation_and_amortization")
+			return ebit + math.Abs(da), ok && dok
+		case "free_cash_flow":
+			ocf, ok := item(r, "net_cash_flow_from_operations")
+			capex, cok := item(r, "capital_expenditure")
+			return ocf - math.Abs(capex), ok && cok
+		case "total_debt":
+			return item(r, "total_liabilities")
+		}
+		return 0, false
+	}
+	derived := func(name string) (float64, bool) { return derivedOf(&row, name) }
+	growth := func(name string) float64 {
+		current, ok := derivedOf(&row, name)
+		previous, pok := derivedOf(prior, name)
+		if !ok || !pok || previous == 0 {
+			return 0
+		}
+		return (current - previous) / math.Abs(previous)
+	}
+
+	revenue, revOK := get("revenue")
+	netIncome, niOK := get("net_income")
+	operating, opOK := derived("operating_income")
+	equity, eqOK := get("shareholders_equity")
+	assets, asOK := get("total_assets")
+	currentAssets, caOK := get("current_assets")
+	currentLiabilities, clOK := get("current_liabilities")
+	inventory, invOK := get("inventory")
+	receivables, recOK := get("trade_and_non_trade_receivables")
+	cash, cashOK := get("cash_and_equivalents")
+	debt, debtOK := derived("total_debt")
+	shares, shOK := get("outstanding_shares")
+	fcf, fcfOK := derived("free_cash_flow")
+	ebitda, ebitdaOK := derived("ebitda")
+
+	days := 365.0
+	if period == "quarterly" {
+		days = 91
+	}
+
+	m := tools.FinancialMetrics{
+		Ticker:       s.Symbol,
+		ReportPeriod: row.date.Format("2006-01-02"),
+		Period:       period,
+		Currency:     s.Currency,
+	}
+	if gp, ok := derived("gross_profit"); ok {
+		m.GrossMargin = value(ratio(gp, ok, revenue, revOK))
+	}
+	m.OperatingMargin = ptr(ratio(operating, opOK, revenue, revOK))
+	m.NetMargin = ptr(ratio(netIncome, niOK, revenue, revOK))
+	m.ReturnOnEquity = ptr(ratio(netIncome, niOK, equity, eqOK))
+	m.ReturnOnAssets = ptr(ratio(netIncome, niOK, assets, asOK))
+	taxRate := defaultTaxRate
+	if tax, ok := get("income_tax_expense"); ok && niOK && netIncome+tax > 0 {
+		taxRate = tax / (netIncome + tax)
+	}
+	investedCapital := equity + value(debt, debtOK) - value(cash, cashOK)
+	m.ReturnOnInvestedCapital = value(ratio(operating*(1-taxRate), opOK, investedCapital, eqOK))
+	m.AssetTurnover = value(ratio(revenue, revOK, assets, asOK))
+	if cost, ok := get("cost_of_revenue"); ok {
+		m.InventoryTurnover = value(ratio(cost, ok, inventory, invOK))
+	}
+	m.ReceivablesTurnover = value(ratio(revenue, revOK, receivables, recOK))
+	if m.ReceivablesTurnover != 0 {
+		m.DaysSalesOutstanding = days / m.ReceivablesTurnover
+		m.OperatingCycle = m.DaysSalesOutstanding
+		if m.InventoryTurnover != 0 {
+			m.OperatingCycle += days / m.InventoryTurnover
+		}
+	}
+	m.WorkingCapitalTurnover = value(ratio(revenue, revOK, currentAssets-currentLiabilities, caOK && clOK))
+	m.CurrentRatio = ptr(ratio(currentAssets, caOK, currentLiabilities, clOK))
+	m.QuickRatio = ptr(ratio(currentAssets-value(inventory, invOK), caOK, currentLiabilities, clOK))
+	m.CashRatio = ptr(ratio(cash, cashOK, currentLiabilities, clOK))
+	if ocf, ok := get("net_cash_flow_from_operations"); ok {
+		m.OperatingCashFlowRatio = value(ratio(ocf, ok, currentLiabilities, clOK))
+	}
+	m.DebtToEquity = ptr(ratio(debt, debtOK, equity, eqOK))
+	if liabilities, ok := get("total_liabilities"); ok {
+		m.DebtToAssets = value(ratio(liabilities, ok, assets, asOK))
+	}
+	if interest, ok := get("interest_expense"); ok {
+		m.InterestCoverage = ptr(ratio(operating, opOK, math.Abs(interest), ok))
+	}
+	if dividends, ok := get("dividends_and_other_cash_distributions"); ok {
+		m.PayoutRatio = value(ratio(math.Abs(dividends), ok, netIncome, niOK))
+	}
+
+	if eps, ok := get("earnings_per_share"); ok {
+		m.EarningsPerShare = eps
+	} else {
+		m.EarningsPerShare = value(ratio(netIncome, niOK, shares, shOK))
+	}
+	m.BookValuePerShare = value(ratio(equity, eqOK, shares, shOK))
+	m.FreeCashFlowPerShare = value(ratio(fcf, fcfOK, shares, shOK))
+
+	m.RevenueGrowth = growth("revenue")
+	m.EarningsGrowth = growth("net_income")
+	m.BookValueGrowth = growth("shareholders_equity")
+	m.OperatingIncomeGrowth = growth("operating_income")
+	m.EbitdaGrowth = growth("ebitda")
+	m.FreeCashFlowGrowth = growth("free_cash_flow")
+	if prior != nil {
+		if previousShares, ok := item(prior, "outstanding_shares"); ok && shOK && previousShares > 0 && shares > 0 {
+			previousNI, pok := item(prior, "net_income")
+			if pok && niOK && previousNI != 0 {
+				previousEPS := previousNI / previousShares
+				m.EarningsPerShareGrowth = (netIncome/shares - previousEPS) / math.Abs(previousEPS)
+			}
+		}
+	}
+
+	// 估值倍数需要股价或市值，未上市公司可以填写最近一轮融资或交易的估值
+	marketCap, mcOK := get("market_cap")
+	if price, ok := get("share_price"); !mcOK && ok && shOK {
+		marketCap, mcOK = price*shares, true
+	}
+	if mcOK && marketCap > 0 {
+		m.MarketCap = marketCap
+		m.EnterpriseValue = marketCap + value(debt, debtOK) - value(cash, cashOK)
+		if niOK && netIncome > 0 {
+			m.PriceToEarningsRatio = marketCap / netIncome
+			if m.EarningsGrowth > 0 {
+				m.PegRatio = m.PriceToEarningsRatio / (m.EarningsGrowth * 100)
+			}
+		}
+		m.PriceToBookRatio = value(ratio(marketCap, true, equity, eqOK))
+		m.PriceToSalesRatio = value(ratio(marketCap, true, revenue, revOK))
+		m.EnterpriseValueToEbitdaRatio = value(ratio(m.EnterpriseValue, true, ebitda, ebitdaOK))
+		m.EnterpriseValueToRevenueRatio = value(ratio(m.EnterpriseValue, true, revenue, revOK))
+		m.FreeCashFlowYield = value(ratio(fcf, fcfOK, marketCap, true))
+	}
+	return m
+}
+
+// lineItems 按 SearchLineItems 的格式返回导入的报表项目，截至 endDate 的最近 limit 期，最新的在前
+func (s *CompanyStatements) lineItems(names []string, endDate, period string, limit int) []LineItem {
+	rows := s.rows(period)
+	end, err := time.Parse("2006-01-02", endDate)
+	if err != nil {
+		end = time.Now()
+	}
+	items := []LineItem{}
+	for i := len(rows) - 1; i >= 0 && len(items) < limit; i-- {
+		if rows[i].date.After(end) {
+			continue
+		}
+		data := make(map[string]any)
+		for _, name := range names {
+			if v, ok := rows[i].items[name]; ok {
+				data[name] = v
+			}
+		}
+		items = append(items, LineItem{
+			Ticker:       s.Symbol,
+			ReportPeriod: rows[i].date.Format("2006-01-02"),
+			Period:       period,
+			Currency:     s.Currency,
+			Data:         data,
+		})
+	}
+	return items
+}
+
+// attributeImportedStatements 把由导入报表计算的财务指标和报表项目的数据来源改为导入的文件
+func attributeImportedStatements(records []tools.ProvenanceRecord) []tools.ProvenanceRecord {
+	for i, r := range records {
+		if !strings.Contains(r.Dataset, "财务") {
+			continue
+		}
+		statements, err := loadCompanyStatements(r.Symbol)
+		if err != nil || statements == nil {
+			continue
+		}
+		records[i].Provider = "用户导入的财务报表（" + filepath.Base(statements.Source) + "）"
+	}
+	return records
+}
+
+// runStatements 导入、查看和删除用户提供的财务报表
+func runStatements(args []string) error {
+	usage := fmt.Errorf("用法: statements import <stock_symbol> <file.csv|file.json> [--currency EUR] | statements show <stock_symbol> [--period annual|quarterly|ttm] | statements list | statements remove <stock_symbol>")
+	if len(args) == 0 {
+		return usage
+	}
+
+	switch args[0] {
+	case "import":
+		if len(args) < 3 || strings.HasPrefix(args[1], "-") || strings.HasPrefix(args[2], "-") {
+			return usage
+		}
+		fs := flag.NewFlagSet("statements import", flag.ExitOnError)
+		currency := fs.String("currency", "", "报表货币（默认使用文件中的 currency，都没有时为 USD）")
+		if err := fs.Parse(args[3:]); err != nil {
+			return err
+		}
+		statements, err := importCompanyStatements(args[1], args[2], *currency)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("已导入 %s 的 %d 期报表（%s），保存到 %s\n", statements.Symbol, len(statements.Periods), statements.Currency, statementsPath(statements.Symbol))
+		fmt.Println("分析该代码时财务指标和报表项目改为由这些报表计算，不再请求数据源")
+		return nil
+
+	case "show":
+		if len(args) < 2 || strings.HasPrefix(args[1], "-") {
+			return fmt.Errorf("请指定股票代码")
+		}
+		fs := flag.NewFlagSet("statements show", flag.ExitOnError)
+		period := fs.String("period", "annual", "期间：annual、quarterly、ttm")
+		if err := fs.Parse(args[2:]); err != nil {
+			return err
+		}
+		statements, err := loadCompanyStatements(args[1])
+		if err != nil {
+			return err
+		}
+		if statements == nil {
+			return fmt.Errorf("%s 没有导入的财务报表", strings.ToUpper(args[1]))
+		}
+		metrics, err := statements.financialMetrics(time.Now().Format("2006-01-02"), *period, tools.MetricsTablePeriods)
+		if err != nil {
+			return err
+		}
+		format, err := tools.NewNumberFormat(os.Getenv("REPORT_LOCALE"))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s 导入自 %s（%s）\n\n", statements.Symbol, statements.Source, statements.ImportedAt.Format("2006-01-02 15:04"))
+		fmt.Println(tools.RenderMetricsTable(&tools.FinancialMetricsOutput{Symbol: statements.Symbol, Period: *period, Metrics: metrics, Count: len(metrics)}, format))
+		return nil
+
+	case "list":
+		entries, err := os.ReadDir(statementsDir)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("读取目录失败: %v", err)
+		}
+		if len(entries) == 0 {
+			fmt.Println("没有导入的财务报表")
+			return nil
+		}
+		for _, entry := range entries {
+			statements, err := loadCompanyStatements(strings.TrimSuffix(entry.Name(), ".json"))
+			if err != nil || statements == nil {
+				continue
+			}
+			first, last := statements.Periods[0].ReportPeriod, statements.Periods[len(statements.Periods)-1].ReportPeriod
+			fmt.Printf("%-8s %d 期 %s ~ %s  %s  导入于 %s\n", statements.Symbol, len(statements.Periods), first, last, statements.Currency, statements.ImportedAt.Format("2006-01-02"))
+		}
+		return nil
+
+	case "remove":
+		if len(args) < 2 {
+			return fmt.Errorf("请指定股票代码")
+		}
+		if err := os.Remove(statementsPath(args[1])); err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf("%s 没有导入的财务报表", strings.ToUpper(args[1]))
+			}
+			return fmt.Errorf("删除失败: %v", err)
+		}
+		fmt.Printf("已删除 %s 导入的财务报表，之后的分析重新使用数据源\n", strings.ToUpper(args[1]))
+		return nil
+	}
+	return usage
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+
+	"github.com/cloudwego/eino-ext/components/model/gemini"
+	"github.com/cloudwego/eino-ext/components/model/openai"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/eino-contrib/jsonschema"
+)
+
+// maxJSONRepairAttempts 模型输出的 JSON 无效时最多请求修复的次数
+const maxJSONRepairAttempts = 2
+
+// structuredGenerator 请求模型输出 JSON 并做结构校验，输出无效时把错误反馈给模型重新生成
+type structuredGenerator struct {
+	chatModel model.BaseChatModel
+	modelType string // 与 MODEL_TYPE 一致，决定使用哪种 JSON 模式参数
+}
+
+// newStructuredGenerator 创建结构化输出生成器
+func newStructuredGenerator(chatModel model.BaseChatModel, modelType string) *structuredGenerator {
+	return &structuredGenerator{chatModel: chatModel, modelType: modelType}
+}
+
+// generate 生成 JSON 并解析到 out（结构体指针），validate 可选，用于字段取值、数量等业务校验
+func (g *structuredGenerator) generate(ctx context.Context, messages []*schema.Message, out any, validate func() error) error {
+	messages = append([]*schema.Message(nil), messages...)
+	opts := jsonModeOptions(g.modelType, out)
+	for attempt := 0; ; attempt++ {
+		resp, err := g.chatModel.Generate(ctx, messages, opts...)
+		if err != nil {
+			return fmt.Errorf("调用模型失败: %w", err)
+		}
+		err = decodeStructured(resp.Content, out, validate)
+		if err == nil {
+			return nil
+		}
+		if attempt >= maxJSONRepairAttempts {
+			return fmt.Errorf("模型输出的 JSON 无效（已修复重试%d次）: %w", attempt, err)
+		}
+		log.Printf("模型输出的 JSON 无效，请求修复（第%d次）: %v", attempt+1, err)
+		messages = append(messages,
+			schema.AssistantMessage(resp.Content, nil),
+			schema.UserMessage(fmt.Sprintf("上面的输出无效：%v。请修正后只输出完整的 JSON，不要包含其他文字。", err)),
+		)
+	}
+}
+
+// jsonModeOptions 返回请求 JSON 输出的模型参数
+// openai 使用 json_object 模式，gemini 使用由 out 类型生成的 JSON Schema；
+// 其他模型（如 deepseek-reasoner）不支持按请求开启，依赖提示词约束和修复重试
+func jsonModeOptions(modelType string, out any) []model.Option {
+	switch modelType {
+	case "openai":
+		return []model.Option{openai.WithExtraFields(map[string]any{
+			"response_format": map[string]string{"type": "json_object"},
+		})}
+	case "gemini":
+		reflector := jsonschema.Reflector{DoNotReference: true, ExpandedStruct: true}
+		return []model.Option{gemini.WithResponseJSONSchema(reflector.Reflect(out))}
+	}
+	return nil
+}
+
+// decodeStructured 严格解析 JSON（不允许未知字段）并执行业务校验，解析前清空 out 以免残留上次的结果
+func decodeStructured(content string, out any, validate func() error) error {
+	reflect.ValueOf(out).Elem().SetZero()
+	decoder := json.NewDecoder(bytes.NewReader([]byte(extractJSON(content))))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(out); err != nil {
+		return fmt.Errorf("解析 JSON 失败: %w", err)
+	}
+	if validate != nil {
+		if err := validate(); err != nil {
+			return fmt.Errorf("校验失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// extractJSON 去除模型输出中的 markdown 代码块标记和前后说明文字，提取 JSON 内容
+func extractJSON(content string) string {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, "```") {
+		content = strings.TrimPrefix(content, "```json")
+		content = strings.TrimPrefix(content, "```")
+		content = strings.TrimSuffix(content, "```")
+	}
+	content = strings.TrimSpace(content)
+	start := strings.IndexAny(content, "{[")
+	end := strings.LastIndexAny(content, "}]")
+	if start >= 0 && end > start {
+		content = content[start : end+1]
+	}
+	return content
+}
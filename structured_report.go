@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"investment/tools"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// StructuredReport 从最终报告中抽取的结构化结论，与 markdown 报告一起保存，便于程序化使用
+type StructuredReport struct {
+	Symbol       string   `json:"symbol"`
+	Rating       string   `json:"rating"`  // 强烈推荐/推荐/中性/谨慎/避免
+	Summary      string   `json:"summary"` // 一段话的投资结论
+	FairValueP10 *float64 `json:"fair_value_p10,omitempty"`
+	FairValueP50 *float64 `json:"fair_value_p50,omitempty"`
+	FairValueP90 *float64 `json:"fair_value_p90,omitempty"`
+	Strengths    []string `json:"strengths"`
+	Risks        []string `json:"risks"`
+	DataGaps     []string `json:"data_gaps"` // 报告中说明缺失的数据
+}
+
+// validate 校验评级取值和估值区间的顺序
+func (r *StructuredReport) validate(symbol string) error {
+	if !strings.EqualFold(r.Symbol, symbol) {
+		return fmt.Errorf("symbol 应为 %s，实际为 %s", symbol, r.Symbol)
+	}
+	if !slices.Contains(ratingOrder, r.Rating) {
+		return fmt.Errorf("rating 必须是 %s 之一，实际为 %q", strings.Join(ratingOrder, "/"), r.Rating)
+	}
+	if strings.TrimSpace(r.Summary) == "" {
+		return fmt.Errorf("summary 不能为空")
+	}
+	if r.FairValueP10 != nil && r.FairValueP50 != nil && r.FairValueP90 != nil &&
+		(*r.FairValueP10 > *r.FairValueP50 || *r.FairValueP50 > *r.FairValueP90) {
+		return fmt.Errorf("估值区间应满足 P10 <= P50 <= P90")
+	}
+	return nil
+}
+
+// extractStructuredReport 使用 JSON 模式从 markdown 报告中抽取结构化结论
+func extractStructuredReport(ctx context.Context, generator *structuredGenerator, symbol, report string) (*StructuredReport, error) {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("以下是 %s 的投资分析报告。请提取报告的结论，只使用报告中明确出现的信息，不要推测。\n", symbol))
+	sb.WriteString(fmt.Sprintf("rating 必须是 %s 之一；报告没有给出估值区间时省略 fair_value_* 字段。\n", strings.Join(ratingOrder, "/")))
+	sb.WriteString(`只输出 JSON，格式为 {"symbol":"","rating":"","summary":"","fair_value_p10":0,"fair_value_p50":0,"fair_value_p90":0,"strengths":[""],"risks":[""],"data_gaps":[""]}`)
+	sb.WriteString("\n\n")
+	sb.WriteString(report)
+
+	var structured StructuredReport
+	err := generator.generate(ctx, []*schema.Message{
+		schema.UserMessage(sb.String()),
+	}, &structured, func() error { return structured.validate(symbol) })
+	if err != nil {
+		return nil, fmt.Errorf("提取结构化报告失败: %w", err)
+	}
+	structured.Symbol = symbol
+	return &structured, nil
+}
+
+// saveStructuredReport 将结构化结论保存到 markdown 报告旁边的同名 .json 文件，返回写入位置
+// reportName 为报告在输出目标中的路径
+func saveStructuredReport(reportName string, structured *StructuredReport) (string, error) {
+	data, err := json.MarshalIndent(structured, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("序列化结构化报告失败: %v", err)
+	}
+	location, err := tools.Output().WriteArtifact(strings.TrimSuffix(reportName, ".md")+".json", data)
+	if err != nil {
+		return "", fmt.Errorf("写入文件失败: %v", err)
+	}
+	return location, nil
+}
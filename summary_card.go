@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"investment/tools"
+)
+
+// summaryCardBullets 摘要卡片中优势和风险各保留的条数
+const summaryCardBullets = 3
+
+// SummaryCard 一页摘要卡片，与完整报告一起保存为 markdown 和 JSON，便于聊天通知和索引页展示
+type SummaryCard struct {
+	Symbol      string       `json:"symbol"`
+	RunID       string       `json:"run_id"`
+	GeneratedAt time.Time    `json:"generated_at"`
+	Price       *float64     `json:"price,omitempty"` // 最新收盘价，获取失败时为空
+	Currency    string       `json:"currency,omitempty"`
+	Rating      string       `json:"rating,omitempty"`
+	Score       *int         `json:"score,omitempty"` // 基本面评分，满分为 MaxScore
+	MaxScore    int          `json:"max_score"`
+	Compete     *int         `json:"competitive_score,omitempty"` // 竞争地位评分，满分为 MaxCompete
+	MaxCompete  int          `json:"max_competitive_score"`
+	Manage      *int         `json:"management_score,omitempty"` // 管理层质量评分，满分为 MaxManage
+	MaxManage   int          `json:"max_management_score"`
+	Summary     string       `json:"summary,omitempty"`
+	Strengths   []string     `json:"strengths"`
+	Risks       []string     `json:"risks"`
+	Target      *TargetRange `json:"target_range,omitempty"`
+	Truncated   bool         `json:"truncated"`
+	ReportPath  string       `json:"report_path"`
+}
+
+// TargetRange 目标价区间，Source 为 report（报告结论）或 monte_carlo（蒙特卡洛估值工具）
+type TargetRange struct {
+	Low    float64 `json:"low"`
+	Mid    float64 `json:"mid"`
+	High   float64 `json:"high"`
+	Source string  `json:"source"`
+}
+
+// buildSummaryCard 汇总运行记录、结构化结论和工具结果生成摘要卡片，structured 和 price 可以为空
+// 目标区间优先使用报告结论中的 P10/P50/P90，没有时使用蒙特卡洛估值结果
+func buildSummaryCard(run *RunRecord, result *analysisResult, structured *StructuredReport, price *float64) *SummaryCard {
+	card := &SummaryCard{
+		Symbol:      run.Symbol,
+		RunID:       run.ID,
+		GeneratedAt: run.FinishedAt,
+		Price:       price,
+		Rating:      run.Rating,
+		Score:       result.Score,
+		MaxScore:    tools.FundamentalMaxScore,
+		Compete:     result.Compete,
+		MaxCompete:  tools.CompetitiveMaxScore,
+		Manage:      result.Manage,
+		MaxManage:   tools.ManagementMaxScore,
+		Strengths:   []string{},
+		Risks:       []string{},
+		Truncated:   run.Truncated,
+		ReportPath:  run.ReportPath,
+	}
+	if result.Valuation != nil {
+		card.Currency = result.Valuation.Currency
+	}
+	if structured != nil {
+		card.Summary = structured.Summary
+		card.Strengths = firstN(structured.Strengths, summaryCardBullets)
+		card.Risks = firstN(structured.Risks, summaryCardBullets)
+		if structured.FairValueP10 != nil && structured.FairValueP50 != nil && structured.FairValueP90 != nil {
+			card.Target = &TargetRange{Low: *structured.FairValueP10, Mid: *structured.FairValueP50, High: *structured.FairValueP90, Source: "report"}
+		}
+	}
+	if v := result.Valuation; card.Target == nil && v != nil && v.P10.Valid() && v.P50.Valid() && v.P90.Valid() {
+		card.Target = &TargetRange{Low: float64(v.P10), Mid: float64(v.P50), High: float64(v.P90), Source: "monte_carlo"}
+	}
+	return card
+}
+
+// firstN 返回去掉空白项后的前 n 项
+func firstN(items []string, n int) []string {
+	out := make([]string, 0, n)
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" && len(out) < n {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// renderSummaryCard 生成摘要卡片的 markdown
+func renderSummaryCard(card *SummaryCard, format tools.NumberFormat) string {
+	orNA := func(s string) string {
+		if s == "" {
+			return tools.NotAvailable
+		}
+		return s
+	}
+	price := tools.NotAvailable
+	if card.Price != nil {
+		price = format.Money(*card.Price, card.Currency)
+	}
+	score := tools.NotAvailable
+	if card.Score != nil {
+		score = fmt.Sprintf("%d/%d", *card.Score, card.MaxScore)
+	}
+	compete := tools.NotAvailable
+	if card.Compete != nil {
+		compete = fmt.Sprintf("%d/%d", *card.Compete, card.MaxCompete)
+	}
+	manage := tools.NotAvailable
+	if card.Manage != nil {
+		manage = fmt.Sprintf("%d/%d", *card.Manage, card.MaxManage)
+	}
+	target := tools.NotAvailable
+	if t := card.Target; t != nil {
+		target = fmt.Sprintf("%s / %s / %s", format.Money(t.Low, card.Currency), format.Money(t.Mid, card.Currency), format.Money(t.High, card.Currency))
+		if card.Price != nil && *card.Price > 0 {
+			target += fmt.Sprintf("（中值较现价 %+.1f%%）", (t.Mid / *card.Price - 1)*100)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# %s 摘要卡片\n\n", card.Symbol))
+	sb.WriteString("| 最新价格 | 投资评级 | 基本面评分 | 竞争地位评分 | 管理层评分 | 目标区间（P10 / P50 / P90） |\n")
+	sb.WriteString("|----------|----------|------------|--------------|------------|-----------------------------|\n")
+	sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s | %s |\n\n", price, orNA(card.Rating), score, compete, manage, target))
+	if card.Truncated {
+		sb.WriteString("> ⚠️ 分析已截断，卡片内容不完整，请勿直接作为投资依据。\n\n")
+	}
+	if card.Summary != "" {
+		sb.WriteString(fmt.Sprintf("> %s\n\n", card.Summary))
+	}
+	writeBullets := func(title string, items []string) {
+		sb.WriteString(fmt.Sprintf("**%s**\n\n", title))
+		if len(items) == 0 {
+			sb.WriteString("- " + tools.NotAvailable + "\n")
+		}
+		for _, item := range items {
+			sb.WriteString("- " + item + "\n")
+		}
+		sb.WriteString("\n")
+	}
+	writeBullets("主要优势", card.Strengths)
+	writeBullets("主要风险", card.Risks)
+	sb.WriteString(fmt.Sprintf("分析时间: %s ｜ 完整报告: %s\n", card.GeneratedAt.Format("2006-01-02 15:04:05"), card.ReportPath))
+	return sb.String()
+}
+
+// saveSummaryCard 将摘要卡片保存为报告旁边的 <报告名>_card.md 和 <报告名>_card.json，返回 markdown 的写入位置
+// reportName 为报告在输出目标中的路径
+func saveSummaryCard(reportName string, card *SummaryCard, format tools.NumberFormat) (string, error) {
+	base := strings.TrimSuffix(reportName, ".md") + "_card"
+	data, err := json.MarshalIndent(card, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("序列化摘要卡片失败: %v", err)
+	}
+	sink := tools.Output()
+	if _, err := sink.WriteArtifact(base+".json", data); err != nil {
+		return "", fmt.Errorf("写入文件失败: %v", err)
+	}
+	location, err := sink.WriteReport(base+".md", []byte(renderSummaryCard(card, format)))
+	if err != nil {
+		return "", fmt.Errorf("写入文件失败: %v", err)
+	}
+	return location, nil
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"investment/tools"
+)
+
+// TickerChange 股票代码变更，变更日起使用新代码
+type TickerChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Date string `json:"date"` // YYYY-MM-DD
+}
+
+// StockSplit 股票拆分（Ratio < 1 为合股），拆分日起每股价格除以 Ratio
+type StockSplit struct {
+	Symbol string  `json:"symbol"` // 拆分时的股票代码
+	Date   string  `json:"date"`   // YYYY-MM-DD
+	Ratio  float64 `json:"ratio"`  // 如 4 表示 1 拆 4
+}
+
+// SymbolHistory 代码变更和拆分的映射表，读取历史数据（运行记录、知识库、交易日志、风险登记簿）时使用：
+// 旧代码的记录归到当前代码下，拆分前记录的每股价格按拆分比例换算到当前股本
+type SymbolHistory struct {
+	Renames []TickerChange `json:"renames"`
+	Splits  []StockSplit   `json:"splits"`
+}
+
+// defaultSymbolHistory 内置的代码变更和近年大型公司的拆分
+var defaultSymbolHistory = SymbolHistory{
+	Renames: []TickerChange{
+		{From: "FB", To: "META", Date: "2022-06-09"},
+		{From: "SQ", To: "XYZ", Date: "2025-01-21"},
+		{From: "ANTM", To: "ELV", Date: "2022-06-28"},
+		{From: "FISV", To: "FI", Date: "2023-06-07"},
+	},
+	Splits: []StockSplit{
+		{Symbol: "AAPL", Date: "2014-06-09", Ratio: 7},
+		{Symbol: "AAPL", Date: "2020-08-31", Ratio: 4},
+		{Symbol: "TSLA", Date: "2020-08-31", Ratio: 5},
+		{Symbol: "TSLA", Date: "2022-08-25", Ratio: 3},
+		{Symbol: "NVDA", Date: "2021-07-20", Ratio: 4},
+		{Symbol: "NVDA", Date: "2024-06-10", Ratio: 10},
+		{Symbol: "AMZN", Date: "2022-06-06", Ratio: 20},
+		{Symbol: "GOOGL", Date: "2022-07-18", Ratio: 20},
+		{Symbol: "GOOG", Date: "2022-07-18", Ratio: 20},
+		{Symbol: "AVGO", Date: "2024-07-15", Ratio: 10},
+		{Symbol: "WMT", Date: "2024-02-26", Ratio: 3},
+		{Symbol: "CMG", Date: "2024-06-26", Ratio: 50},
+	},
+}
+
+var (
+	symbolHistoryOnce sync.Once
+	symbolHistoryData *SymbolHistory
+)
+
+// symbolHistory 进程内共享的映射表，第一次使用时加载；配置文件有误时记录日志并只使用内置表
+func symbolHistory() *SymbolHistory {
+	symbolHistoryOnce.Do(func() {
+		var err error
+		symbolHistoryData, err = loadSymbolHistory()
+		if err != nil {
+			log.Printf("[SymbolHistory] %v，只使用内置的代码变更和拆分表", err)
+			builtin := defaultSymbolHistory
+			symbolHistoryData = &builtin
+		} else if n := len(symbolHistoryData.Renames) + len(symbolHistoryData.Splits) - len(defaultSymbolHistory.Renames) - len(defaultSymbolHistory.Splits); n > 0 {
+			log.Printf("[SymbolHistory] 已加载自定义代码变更和拆分 %d 条", n)
+		}
+	})
+	return symbolHistoryData
+}
+
+// loadSymbolHistory 合并内置表和 SYMBOL_HISTORY_FILE（默认 symbol_history.json，不存在时忽略），
+// 文件格式与 SymbolHistory 相同；同一旧代码的变更、同一股票同一天的拆分以文件为准
+func loadSymbolHistory() (*SymbolHistory, error) {
+	history := &SymbolHistory{
+		Renames: append([]TickerChange(nil), defaultSymbolHistory.Renames...),
+		Splits:  append([]StockSplit(nil), defaultSymbolHistory.Splits...),
+	}
+
+	path := os.Getenv("SYMBOL_HISTORY_FILE")
+	explicit := path != ""
+	if !explicit {
+		path = "symbol_history.json"
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		var custom SymbolHistory
+		if err := json.Unmarshal(data, &custom); err != nil {
+			return nil, fmt.Errorf("解析代码变更配置 %s 失败: %w", path, err)
+		}
+		if err := custom.validate(); err != nil {
+			return nil, fmt.Errorf("代码变更配置 %s: %w", path, err)
+		}
+		history.merge(custom)
+	case explicit || !os.IsNotExist(err):
+		return nil, fmt.Errorf("读取代码变更配置 %s 失败: %w", path, err)
+	}
+	return history, nil
+}
+
+// validate 检查代码、日期和拆分比例
+func (h *SymbolHistory) validate() error {
+	for _, r := range h.Renames {
+		if strings.TrimSpace(r.From) == "" || strings.TrimSpace(r.To) == "" {
+			return fmt.Errorf("代码变更缺少 from 或 to: %+v", r)
+		}
+		if _, err := time.Parse("2006-01-02", r.Date); err != nil {
+			return fmt.Errorf("%s → %s 的日期无效: %q", r.From, r.To, r.Date)
+		}
+	}
+	for _, s := range h.Splits {
+		if strings.TrimSpace(s.Symbol) == "" || s.Ratio <= 0 {
+			return fmt.Errorf("拆分缺少股票代码或比例无效: %+v", s)
+		}
+		if _, err := time.Parse("2006-01-02", s.Date); err != nil {
+			return fmt.Errorf("%s 拆分的日期无效: %q", s.Symbol, s.Date)
+		}
+	}
+	return nil
+}
+
+// merge 把 custom 合并到映射表，代码统一为大写
+func (h *SymbolHistory) merge(custom SymbolHistory) {
+	for _, r := range custom.Renames {
+		r.From, r.To = strings.ToUpper(strings.TrimSpace(r.From)), strings.ToUpper(strings.TrimSpace(r.To))
+		replaced := false
+		for i := range h.Renames {
+			if h.Renames[i].From == r.From {
+				h.Renames[i], replaced = r, true
+			}
+		}
+		if !replaced {
+			h.Renames = append(h.Renames, r)
+		}
+	}
+	for _, s := range custom.Splits {
+		s.Symbol = strings.ToUpper(strings.TrimSpace(s.Symbol))
+		replaced := false
+		for i := range h.Splits {
+			if h.Splits[i].Symbol == s.Symbol && h.Splits[i].Date == s.Date {
+				h.Splits[i], replaced = s, true
+			}
+		}
+		if !replaced {
+			h.Splits = append(h.Splits, s)
+		}
+	}
+}
+
+// Canonical 股票的当前代码，沿代码变更链查找（最多 10 次，防止配置成环）
+func (h *SymbolHistory) Canonical(symbol string) string {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	for range 10 {
+		next := ""
+		for _, r := range h.Renames {
+			if r.From == symbol {
+				next = r.To
+			}
+		}
+		if next == "" || next == symbol {
+			break
+		}
+		symbol = next
+	}
+	return symbol
+}
+
+// Aliases 与 symbol 指同一家公司的全部代码，当前代码在前，其余按字母排序
+func (h *SymbolHistory) Aliases(symbol string) []string {
+	canonical := h.Canonical(symbol)
+	aliases := []string{canonical}
+	var previous []string
+	for _, r := range h.Renames {
+		if r.From != canonical && h.Canonical(r.From) == canonical {
+			previous = append(previous, r.From)
+		}
+	}
+	sort.Strings(previous)
+	return append(aliases, previous...)
+}
+
+// SplitFactor since 之后生效的累计拆分比例（拆分日当天记录的价格已是拆分后的价格），since 当时的每股价格除以该值即为当前股本下的价格
+func (h *SymbolHistory) SplitFactor(symbol string, since time.Time) float64 {
+	aliases := h.Aliases(symbol)
+	factor := 1.0
+	for _, s := range h.Splits {
+		date, err := time.Parse("2006-01-02", s.Date)
+		if err != nil || !date.After(since) {
+			continue
+		}
+		for _, alias := range aliases {
+			if s.Symbol == alias {
+				factor *= s.Ratio
+				break
+			}
+		}
+	}
+	return factor
+}
+
+// canonicalSymbol 股票的当前代码
+func canonicalSymbol(symbol string) string {
+	return symbolHistory().Canonical(symbol)
+}
+
+// adjustForSplits 把 recordedAt 时记录的每股价格换算到当前股本，没有拆分时原样返回
+func adjustForSplits(symbol string, recordedAt time.Time, price *float64) *float64 {
+	if price == nil {
+		return nil
+	}
+	factor := symbolHistory().SplitFactor(symbol, recordedAt)
+	if factor == 1 {
+		return price
+	}
+	adjusted := *price / factor
+	return &adjusted
+}
+
+// symbolHistoryProblems 配置校验：SYMBOL_HISTORY_FILE 无法读取或格式有误
+func symbolHistoryProblems() []string {
+	if _, err := loadSymbolHistory(); err != nil {
+		return []string{err.Error()}
+	}
+	return nil
+}
+
+func init() {
+	// 工具包读取按股票保存的历史数据（如风险登记簿）时，同样合并旧代码下的记录
+	tools.SetSymbolAliases(func(symbol string) []string {
+		return symbolHistory().Aliases(symbol)
+	})
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"investment/tools"
+)
+
+// 持有期限分类：持有超过一年为长期，否则为短期；缺少买入日期时无法判断
+const (
+	TermLong    = "长期"
+	TermShort   = "短期"
+	TermUnknown = "未知"
+)
+
+// TaxLot 一个买入批次
+type TaxLot struct {
+	Quantity     float64 `json:"quantity"`
+	CostBasis    float64 `json:"cost_basis"`    // 每股成本
+	PurchaseDate string  `json:"purchase_date"` // 买入日期，格式 YYYY-MM-DD
+}
+
+// RealizedLot 已卖出的批次
+type RealizedLot struct {
+	Symbol       string  `json:"symbol"`
+	Quantity     float64 `json:"quantity"`
+	CostBasis    float64 `json:"cost_basis"` // 每股成本
+	PurchaseDate string  `json:"purchase_date"`
+	SalePrice    float64 `json:"sale_price"`
+	SaleDate     string  `json:"sale_date"`
+	Source       string  `json:"source,omitempty"`
+}
+
+// lotGain 单个批次的盈亏
+type lotGain struct {
+	Symbol       string
+	Quantity     float64
+	CostBasis    float64
+	PurchaseDate string
+	Currency     string
+	Price        float64 // 未实现为最新收盘价，已实现为卖出价
+	SaleDate     string  // 仅已实现批次
+	Gain         float64
+	Term         string
+}
+
+// taxGainReport 组合的已实现和未实现盈亏，按持有期限分类汇总
+// 不做汇率换算，不同币种的盈亏分别汇总
+type taxGainReport struct {
+	Unrealized       []lotGain
+	Realized         []lotGain
+	UnrealizedByTerm currencyTotals
+	RealizedByYear   map[string]currencyTotals // 卖出年份 -> 各币种按持有期限的盈亏
+	Warnings         []string
+}
+
+// currencyTotals 按币种和持有期限汇总的盈亏：币种 -> 持有期限 -> 盈亏
+type currencyTotals map[string]map[string]float64
+
+// add 累计一个批次的盈亏
+func (t currencyTotals) add(currency, term string, gain float64) {
+	if t[currency] == nil {
+		t[currency] = make(map[string]float64)
+	}
+	t[currency][term] += gain
+}
+
+// format 某个持有期限在各币种的合计，按币种排序，如 +$1,200.00、+HK$300.00；没有该期限的批次时返回空字符串
+func (t currencyTotals) format(format tools.NumberFormat, term string) string {
+	currencies := make([]string, 0, len(t))
+	for currency := range t {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	var parts []string
+	for _, currency := range currencies {
+		if gain, ok := t[currency][term]; ok {
+			parts = append(parts, signedMoney(format, gain, currency))
+		}
+	}
+	return strings.Join(parts, "、")
+}
+
+// holdingTerm 按买入日期和卖出（或估值）日期判断持有期限，持有超过一年为长期
+func holdingTerm(purchaseDate string, asOf time.Time) (string, error) {
+	if purchaseDate == "" {
+		return TermUnknown, nil
+	}
+	purchased, err := time.Parse("2006-01-02", purchaseDate)
+	if err != nil {
+		return TermUnknown, fmt.Errorf("无效的买入日期 %q", purchaseDate)
+	}
+	if asOf.After(purchased.AddDate(1, 0, 0)) {
+		return TermLong, nil
+	}
+	return TermShort, nil
+}
+
+// computeTaxGains 计算各批次的未实现盈亏（按 prices 中的最新价格）和已实现盈亏
+// 没有批次明细的持仓按平均成本视为一个买入日期未知的批次；批次数量之和与持仓数量不一致时给出提示
+func computeTaxGains(p *Portfolio, prices map[string]float64, asOf time.Time) *taxGainReport {
+	report := &taxGainReport{
+		UnrealizedByTerm: make(currencyTotals),
+		RealizedByYear:   make(map[string]currencyTotals),
+	}
+	currencies := p.Currencies()
+
+	for _, position := range p.Positions {
+		price, ok := prices[position.Symbol]
+		if !ok {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("%s 缺少最新价格，未计算未实现盈亏", position.Symbol))
+			continue
+		}
+		lots := position.Lots
+		if len(lots) == 0 {
+			lots = []TaxLot{{Quantity: position.Quantity, CostBasis: position.CostBasis}}
+		} else {
+			total := 0.0
+			for _, lot := range lots {
+				total += lot.Quantity
+			}
+			if math.Abs(total-position.Quantity) > 1e-6 {
+				report.Warnings = append(report.Warnings, fmt.Sprintf("%s（%s）批次数量合计 %.4g 与持仓数量 %.4g 不一致，按批次计算", position.Symbol, position.Source, total, position.Quantity))
+			}
+		}
+		for _, lot := range lots {
+			term, err := holdingTerm(lot.PurchaseDate, asOf)
+			if err != nil {
+				report.Warnings = append(report.Warnings, fmt.Sprintf("%s: %v", position.Symbol, err))
+			}
+			gain := (price - lot.CostBasis) * lot.Quantity
+			report.Unrealized = append(report.Unrealized, lotGain{
+				Symbol:       position.Symbol,
+				Quantity:     lot.Quantity,
+				CostBasis:    lot.CostBasis,
+				PurchaseDate: lot.PurchaseDate,
+				Currency:     position.Currency,
+				Price:        price,
+				Gain:         gain,
+				Term:         term,
+			})
+			report.UnrealizedByTerm.add(position.Currency, term, gain)
+		}
+	}
+
+	for _, sale := range p.Realized {
+		saleDate, err := time.Parse("2006-01-02", sale.SaleDate)
+		if err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("%s 的卖出记录日期无效 %q，已跳过", sale.Symbol, sale.SaleDate))
+			continue
+		}
+		term, err := holdingTerm(sale.PurchaseDate, saleDate)
+		if err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("%s: %v", sale.Symbol, err))
+		}
+		gain := (sale.SalePrice - sale.CostBasis) * sale.Quantity
+		currency := currencies[sale.Symbol]
+		report.Realized = append(report.Realized, lotGain{
+			Symbol:       sale.Symbol,
+			Quantity:     sale.Quantity,
+			CostBasis:    sale.CostBasis,
+			PurchaseDate: sale.PurchaseDate,
+			Currency:     currency,
+			Price:        sale.SalePrice,
+			SaleDate:     sale.SaleDate,
+			Gain:         gain,
+			Term:         term,
+		})
+		year := saleDate.Format("2006")
+		if report.RealizedByYear[year] == nil {
+			report.RealizedByYear[year] = make(currencyTotals)
+		}
+		report.RealizedByYear[year].add(currency, term, gain)
+	}
+	sort.Slice(report.Realized, func(i, j int) bool { return report.Realized[i].SaleDate < report.Realized[j].SaleDate })
+	return report
+}
+
+// renderTaxGains 将盈亏渲染为 markdown，用于组合报告
+func renderTaxGains(r *taxGainReport, format tools.NumberFormat) string {
+	terms := []string{TermShort, TermLong, TermUnknown}
+	var sb strings.Builder
+	sb.WriteString("## 税务批次盈亏\n\n")
+	sb.WriteString("持有超过一年的批次计为长期，否则为短期；没有买入日期的持仓计为未知。金额为持仓币种，不同币种分别合计，未考虑手续费、分红和汇率。\n\n")
+
+	sb.WriteString("### 未实现盈亏\n\n| 股票 | 买入日期 | 数量 | 成本 | 最新价 | 盈亏 | 期限 |\n|---|---|---|---|---|---|---|\n")
+	for _, lot := range r.Unrealized {
+		sb.WriteString(fmt.Sprintf("| %s | %s | %.4g | %s | %s | %s | %s |\n", lot.Symbol, dateOrNA(lot.PurchaseDate), lot.Quantity,
+			format.Money(lot.CostBasis, lot.Currency), format.Money(lot.Price, lot.Currency), signedMoney(format, lot.Gain, lot.Currency), lot.Term))
+	}
+	sb.WriteString("\n")
+	for _, term := range terms {
+		if totals := r.UnrealizedByTerm.format(format, term); totals != "" {
+			sb.WriteString(fmt.Sprintf("- %s未实现盈亏：%s\n", term, totals))
+		}
+	}
+
+	if len(r.Realized) > 0 {
+		sb.WriteString("\n### 已实现盈亏\n\n| 股票 | 买入日期 | 卖出日期 | 数量 | 成本 | 卖出价 | 盈亏 | 期限 |\n|---|---|---|---|---|---|---|---|\n")
+		for _, lot := range r.Realized {
+			sb.WriteString(fmt.Sprintf("| %s | %s | %s | %.4g | %s | %s | %s | %s |\n", lot.Symbol, dateOrNA(lot.PurchaseDate), lot.SaleDate, lot.Quantity,
+				format.Money(lot.CostBasis, lot.Currency), format.Money(lot.Price, lot.Currency), signedMoney(format, lot.Gain, lot.Currency), lot.Term))
+		}
+		sb.WriteString("\n")
+		years := make([]string, 0, len(r.RealizedByYear))
+		for year := range r.RealizedByYear {
+			years = append(years, year)
+		}
+		sort.Strings(years)
+		for _, year := range years {
+			var parts []string
+			for _, term := range terms {
+				if totals := r.RealizedByYear[year].format(format, term); totals != "" {
+					parts = append(parts, fmt.Sprintf("%s %s", term, totals))
+				}
+			}
+			sb.WriteString(fmt.Sprintf("- %s 年已实现盈亏：%s\n", year, strings.Join(parts, "，")))
+		}
+	}
+
+	for _, warning := range r.Warnings {
+		sb.WriteString(fmt.Sprintf("- ⚠️ %s\n", warning))
+	}
+	return sb.String()
+}
+
+// signedMoney 带正负号的金额，如 +$1,200.00
+func signedMoney(format tools.NumberFormat, v float64, currency string) string {
+	if v > 0 {
+		return "+" + format.Money(v, currency)
+	}
+	return format.Money(v, currency)
+}
+
+// dateOrNA 空日期显示为 n/a
+func dateOrNA(date string) string {
+	if date == "" {
+		return tools.NotAvailable
+	}
+	return date
+}
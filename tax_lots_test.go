@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"investment/tools"
+)
+
+func TestComputeTaxGainsGroupsByCurrency(t *testing.T) {
+	p := &Portfolio{
+		Positions: []Position{
+			{Symbol: "AAPL", Quantity: 10, CostBasis: 100, Currency: "USD", Lots: []TaxLot{{Quantity: 10, CostBasis: 100, PurchaseDate: "2020-01-02"}}},
+			{Symbol: "0700.HK", Quantity: 100, CostBasis: 300, Currency: "HKD", Lots: []TaxLot{{Quantity: 100, CostBasis: 300, PurchaseDate: "2020-01-02"}}},
+		},
+		Realized: []RealizedLot{
+			{Symbol: "AAPL", Quantity: 1, CostBasis: 100, PurchaseDate: "2020-01-02", SalePrice: 150, SaleDate: "2023-05-01"},
+			{Symbol: "0700.HK", Quantity: 10, CostBasis: 300, PurchaseDate: "2020-01-02", SalePrice: 350, SaleDate: "2023-06-01"},
+		},
+	}
+	prices := map[string]float64{"AAPL": 120, "0700.HK": 400}
+	report := computeTaxGains(p, prices, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+
+	if got := report.UnrealizedByTerm["USD"][TermLong]; got != 200 {
+		t.Errorf("USD 长期未实现盈亏为 %v，期望 200", got)
+	}
+	if got := report.UnrealizedByTerm["HKD"][TermLong]; got != 10000 {
+		t.Errorf("HKD 长期未实现盈亏为 %v，期望 10000", got)
+	}
+	if got := report.RealizedByYear["2023"]["USD"][TermLong]; got != 50 {
+		t.Errorf("2023 年 USD 已实现盈亏为 %v，期望 50", got)
+	}
+	if got := report.RealizedByYear["2023"]["HKD"][TermLong]; got != 500 {
+		t.Errorf("2023 年 HKD 已实现盈亏为 %v，期望 500", got)
+	}
+
+	format, err := tools.NewNumberFormat(tools.LocaleEnUS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered := renderTaxGains(report, format)
+	for _, want := range []string{
+		"长期未实现盈亏：+HK$10,000.00、+$200.00",
+		"2023 年已实现盈亏：长期 +HK$500.00、+$50.00",
+	} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("报告缺少 %q:\n%s", want, rendered)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// 终端流式输出模式
+const (
+	StreamFormatted = "formatted" // 流式输出内容，并在每个章节结束时标注章节名和用时
+	StreamRaw       = "raw"       // 原样流式输出模型内容
+)
+
+// validateStreamMode 校验 --stream 参数
+func validateStreamMode(mode string) error {
+	switch mode {
+	case StreamFormatted, StreamRaw:
+		return nil
+	}
+	return fmt.Errorf("无效的 --stream: %s，可选值为 %s、%s", mode, StreamFormatted, StreamRaw)
+}
+
+// terminalPrinter 将模型输出实时打印到终端，为 nil 时不输出
+type terminalPrinter struct {
+	mode string
+}
+
+// newTerminalPrinter 创建终端输出
+func newTerminalPrinter(mode string) *terminalPrinter {
+	return &terminalPrinter{mode: mode}
+}
+
+// delta 打印增量内容
+func (p *terminalPrinter) delta(content string) {
+	if p == nil {
+		return
+	}
+	fmt.Fprint(os.Stdout, content)
+}
+
+// sectionCompleted 格式化模式下标注刚完成的章节和用时，如 "⏱ 估值分析 12s"
+func (p *terminalPrinter) sectionCompleted(section completedSection) {
+	if p == nil || p.mode != StreamFormatted {
+		return
+	}
+	fmt.Fprintf(os.Stdout, "\n⏱ [%s] %s\n", section.Title, section.Duration.Round(100*time.Millisecond))
+}
+
+// messageEnd 一条消息输出结束
+func (p *terminalPrinter) messageEnd() {
+	if p == nil {
+		return
+	}
+	fmt.Fprintln(os.Stdout)
+}
@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// CompletedToolCall 一次成功完成的工具调用及其结果
+type CompletedToolCall struct {
+	Tool      string `json:"tool"`
+	Arguments string `json:"arguments"` // 规范化后的 JSON 参数
+	Result    string `json:"result"`
+}
+
+// ToolCheckpoint 工具调用检查点，用于服务重启后恢复分析任务：
+// Completed 中相同参数的调用直接返回记录的结果，不再请求数据源；新完成的调用通过 Record 交给调用方持久化
+type ToolCheckpoint struct {
+	Completed []CompletedToolCall
+	Record    func(CompletedToolCall) // 可选，返回 error 字段的结果不记录
+	Replayed  func(CompletedToolCall) // 可选，调用从检查点返回时通知
+}
+
+// WithCheckpoint 为一组工具加上检查点，checkpoint 为 nil 时原样返回
+func WithCheckpoint(tools []tool.BaseTool, checkpoint *ToolCheckpoint) []tool.BaseTool {
+	if checkpoint == nil {
+		return tools
+	}
+	completed := make(map[string]CompletedToolCall, len(checkpoint.Completed))
+	for _, call := range checkpoint.Completed {
+		completed[call.Tool+":"+call.Arguments] = call
+	}
+	wrapped := make([]tool.BaseTool, len(tools))
+	for i, t := range tools {
+		if invokable, ok := t.(tool.InvokableTool); ok {
+			wrapped[i] = &checkpointTool{InvokableTool: invokable, checkpoint: checkpoint, completed: completed}
+			continue
+		}
+		wrapped[i] = t
+	}
+	return wrapped
+}
+
+// checkpointTool 带检查点的工具包装
+type checkpointTool struct {
+	tool.InvokableTool
+	checkpoint *ToolCheckpoint
+	completed  map[string]CompletedToolCall // 只读
+}
+
+// InvokableRun 检查点中已有的调用直接返回记录的结果，否则执行工具并记录成功的结果
+func (t *checkpointTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	info, err := t.Info(ctx)
+	if err != nil {
+		return t.InvokableTool.InvokableRun(ctx, argumentsInJSON, opts...)
+	}
+	arguments := canonicalArguments(argumentsInJSON)
+	if call, ok := t.completed[info.Name+":"+arguments]; ok {
+		Logger(ctx).Printf("[Checkpoint] 从检查点恢复工具调用: %s %s", info.Name, arguments)
+		if t.checkpoint.Replayed != nil {
+			t.checkpoint.Replayed(call)
+		}
+		return call.Result, nil
+	}
+
+	result, err := t.InvokableTool.InvokableRun(ctx, argumentsInJSON, opts...)
+	if err == nil && t.checkpoint.Record != nil && !hasErrorField(result) {
+		t.checkpoint.Record(CompletedToolCall{Tool: info.Name, Arguments: arguments, Result: result})
+	}
+	return result, err
+}
+
+// hasErrorField 工具结果是否带有非空的 error 字段
+func hasErrorField(result string) bool {
+	var output struct {
+		Error string `json:"error"`
+	}
+	return json.Unmarshal([]byte(result), &output) == nil && output.Error != ""
+}
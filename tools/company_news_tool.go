@@ -2,65 +2,80 @@ package tools
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
-	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/cloudwego/eino/components/tool"
-	"github.com/cloudwego/eino/components/tool/utils"
 )
 
 // CompanyNews 公司新闻结构体
 type CompanyNews struct {
-	ID       string `json:"id"`
-	Title    string `json:"title"`
-	Summary  string `json:"summary"`
-	URL      string `json:"url"`
-	Source   string `json:"source"`
-	Category string `json:"category"`
-	DateTime string `json:"datetime"`
+	ID       string   `json:"id"`
+	Title    string   `json:"title"`
+	Summary  string   `json:"summary"`
+	URL      string   `json:"url"`
+	Source   string   `json:"source"`
+	Category string   `json:"category"`
+	DateTime string   `json:"datetime"`
+	Topics   []string `json:"topics,omitempty"`
+
+	Sentiment *NewsSentiment `json:"sentiment,omitempty"` // 启用情绪评分时填充
 }
 
 // CompanyNewsInput 公司新闻查询的输入参数
 type CompanyNewsInput struct {
-	Symbol string `json:"symbol" description:"股票代码，如 AAPL, TSLA, GOOG"`
-	Date   string `json:"date,omitempty" description:"查询日期，格式为 YYYY-MM-DD，如果不提供则使用当前日期"`
-	Limit  int    `json:"limit,omitempty" description:"返回新闻条数，默认为10条，最大20条"`
+	Symbol    string `json:"symbol" description:"股票代码，如 AAPL, TSLA, GOOG"`
+	StartDate string `json:"start_date,omitempty" description:"开始日期，格式为 YYYY-MM-DD，如果不提供则不限制开始日期，只返回截至结束日期的最新新闻"`
+	EndDate   string `json:"end_date,omitempty" description:"结束日期，格式为 YYYY-MM-DD，如果不提供则使用当前日期"`
+	Limit     int    `json:"limit,omitempty" description:"返回新闻条数，默认为10条，最大20条"`
 }
 
 // CompanyNewsOutput 公司新闻查询的输出结果
 type CompanyNewsOutput struct {
-	Symbol string        `json:"symbol"`
-	Date   string        `json:"date"`
-	News   []CompanyNews `json:"news"`
-	Count  int           `json:"count"`
-	Error  string        `json:"error,omitempty"`
+	Symbol      string            `json:"symbol"`
+	StartDate   string            `json:"start_date,omitempty"`
+	EndDate     string            `json:"end_date"`
+	News        []CompanyNews     `json:"news"`
+	Count       int               `json:"count"`
+	TopicCounts map[string]int    `json:"topic_counts,omitempty"`
+	RiskNews    []CompanyNews     `json:"risk_news,omitempty"`
+	Sentiment   *SentimentSummary `json:"sentiment,omitempty"`
+	Error       string            `json:"error,omitempty"`
 }
 
 // NewCompanyNewsTool 创建新的公司新闻查询工具
-func NewCompanyNewsTool(getNewsFunc func(symbol, date string, since *string, limit int) ([]CompanyNews, error)) (tool.BaseTool, error) {
-	tool, err := utils.InferTool("get_company_news",
-		"获取指定股票公司的最新新闻信息。这些新闻可以帮助分析公司的最新动态、市场情绪和潜在影响因素。",
+// classifier 为可选的新闻分类器，用于对关键词规则无法识别的新闻进行补充分类，可为 nil
+// sentiment 为可选的情绪批量评分器，可为 nil
+func NewCompanyNewsTool(getNewsFunc func(ctx context.Context, symbol, date string, since *string, limit int) ([]CompanyNews, error), classifier NewsClassifier, sentiment *SentimentBatcher) (tool.BaseTool, error) {
+	tool, err := inferTool("get_company_news",
+		"获取指定股票公司的最新新闻信息，并按主题分类（业绩财报、并购重组、诉讼、监管、产品业务、管理层）。这些新闻可以帮助分析公司的最新动态、市场情绪和潜在影响因素，诉讼和监管类新闻会单独列出供风险分析使用。",
 		func(ctx context.Context, req *CompanyNewsInput) (*CompanyNewsOutput, error) {
-			log.Printf("[CompanyNewsTool] 接收到请求: Symbol=%s, Date=%s, Limit=%d", req.Symbol, req.Date, req.Limit)
+			Logger(ctx).Printf("[CompanyNewsTool] 接收到请求: Symbol=%s, StartDate=%s, EndDate=%s, Limit=%d", req.Symbol, req.StartDate, req.EndDate, req.Limit)
 
 			// 验证必需参数
 			if req.Symbol == "" {
-				log.Printf("[CompanyNewsTool] 错误: 股票代码为空")
+				Logger(ctx).Printf("[CompanyNewsTool] 错误: 股票代码为空")
 				return &CompanyNewsOutput{
 					Error: "股票代码不能为空",
 				}, nil
 			}
 
-			// 设置默认值
-			date := req.Date
-			if date == "" {
-				date = time.Now().Format("2006-01-02")
+			// 解析并校验日期窗口
+			startDate, endDate, err := resolveDateWindow(req.StartDate, req.EndDate, 0, time.Now())
+			if err != nil {
+				Logger(ctx).Printf("[CompanyNewsTool] 错误: %v", err)
+				return &CompanyNewsOutput{
+					Symbol: req.Symbol,
+					Error:  err.Error(),
+				}, nil
+			}
+			var since *string
+			if startDate != "" {
+				since = &startDate
 			}
 
+			// 设置默认值
 			limit := req.Limit
 			if limit <= 0 {
 				limit = 10
@@ -69,35 +84,52 @@ func NewCompanyNewsTool(getNewsFunc func(symbol, date string, since *string, lim
 				limit = 20
 			}
 
-			log.Printf("[CompanyNewsTool] 准备调用API: Symbol=%s, Date=%s, Limit=%d", req.Symbol, date, limit)
+			Logger(ctx).Printf("[CompanyNewsTool] 准备调用API: Symbol=%s, StartDate=%s, EndDate=%s, Limit=%d", req.Symbol, startDate, endDate, limit)
 
 			// 调用API获取新闻
-			news, err := getNewsFunc(req.Symbol, date, nil, limit)
+			news, err := getNewsFunc(ctx, req.Symbol, endDate, since, limit)
 			if err != nil {
-				log.Printf("[CompanyNewsTool] API调用失败: %v", err)
+				Logger(ctx).Printf("[CompanyNewsTool] API调用失败: %v", err)
+				if isFatalAPIError(err) {
+					return nil, err
+				}
 				return &CompanyNewsOutput{
-					Symbol: req.Symbol,
-					Date:   date,
-					Error:  fmt.Sprintf("获取新闻失败: %v", err),
+					Symbol:    req.Symbol,
+					StartDate: startDate,
+					EndDate:   endDate,
+					Error:     fmt.Sprintf("获取新闻失败: %v", err),
 				}, nil
 			}
 
-			log.Printf("[CompanyNewsTool] API调用成功: 获取到 %d 条新闻", len(news))
+			// 按开始日期分页时可能返回超过 limit 条新闻
+			if len(news) > limit {
+				news = news[:limit]
+			}
+
+			Logger(ctx).Printf("[CompanyNewsTool] API调用成功: 获取到 %d 条新闻", len(news))
+
+			// 新闻主题分类
+			categorizeNewsWithClassifier(ctx, news, classifier)
+			sentiment.Score(ctx, news)
 
 			result := &CompanyNewsOutput{
-				Symbol: req.Symbol,
-				Date:   date,
-				News:   news,
-				Count:  len(news),
+				Symbol:      req.Symbol,
+				StartDate:   startDate,
+				EndDate:     endDate,
+				News:        news,
+				Count:       len(news),
+				TopicCounts: countNewsTopics(news),
+				RiskNews:    riskNews(news),
+				Sentiment:   SummarizeSentiment(news),
 			}
 
 			// 保存新闻到本地文件
 			if err := saveNewsToFile(result); err != nil {
-				log.Printf("[CompanyNewsTool] 保存文件失败: %v", err)
+				Logger(ctx).Printf("[CompanyNewsTool] 保存文件失败: %v", err)
 				// 不返回错误，继续返回新闻数据
 			}
 
-			log.Printf("[CompanyNewsTool] 返回响应: Symbol=%s, Date=%s, Count=%d", result.Symbol, result.Date, result.Count)
+			Logger(ctx).Printf("[CompanyNewsTool] 返回响应: Symbol=%s, StartDate=%s, EndDate=%s, Count=%d", result.Symbol, result.StartDate, result.EndDate, result.Count)
 			return result, nil
 		})
 	if err != nil {
@@ -106,30 +138,57 @@ func NewCompanyNewsTool(getNewsFunc func(symbol, date string, since *string, lim
 	return tool, nil
 }
 
-// saveNewsToFile 将新闻保存到本地文件
-func saveNewsToFile(newsOutput *CompanyNewsOutput) error {
-	// 创建news目录
-	dirPath := "output/news"
-	if err := os.MkdirAll(dirPath, 0755); err != nil {
-		return fmt.Errorf("创建目录失败: %v", err)
+// categorizeNewsWithClassifier 先使用关键词规则分类，再用可选的分类器补充规则未识别的新闻
+func categorizeNewsWithClassifier(ctx context.Context, news []CompanyNews, classifier NewsClassifier) {
+	CategorizeNews(news)
+	if classifier == nil {
+		return
 	}
 
-	// 生成文件名：news_AAPL_2025-09-25.json
-	timeSuffix := time.Now().Format("2006-01-02_15-04-05")
-	fileName := fmt.Sprintf("news_%s_%s.json", newsOutput.Symbol, timeSuffix)
-	filePath := filepath.Join(dirPath, fileName)
+	var pending []CompanyNews
+	var indexes []int
+	for i, item := range news {
+		if len(item.Topics) == 1 && item.Topics[0] == NewsTopicOther {
+			pending = append(pending, item)
+			indexes = append(indexes, i)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
 
-	// 将新闻数据转换为JSON
-	data, err := json.MarshalIndent(newsOutput, "", "  ")
+	Logger(ctx).Printf("[CompanyNewsTool] 使用分类器补充分类: %d 条新闻", len(pending))
+	topics, err := classifier(ctx, pending)
 	if err != nil {
-		return fmt.Errorf("JSON序列化失败: %v", err)
+		Logger(ctx).Printf("[CompanyNewsTool] 分类器调用失败，保留关键词分类结果: %v", err)
+		return
+	}
+	for i, index := range indexes {
+		if i >= len(topics) {
+			break
+		}
+		var valid []string
+		for _, topic := range topics[i] {
+			if isValidNewsTopic(topic) {
+				valid = append(valid, topic)
+			}
+		}
+		if len(valid) > 0 {
+			news[index].Topics = valid
+		}
 	}
+}
 
-	// 写入文件
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
-		return fmt.Errorf("写入文件失败: %v", err)
+// saveNewsToFile 将新闻写入输出目标
+func saveNewsToFile(newsOutput *CompanyNewsOutput) error {
+	// 生成文件名：news/news_AAPL_2025-09-25_15-04-05.json
+	timeSuffix := time.Now().Format("2006-01-02_15-04-05")
+	name := fmt.Sprintf("news/news_%s_%s.json", newsOutput.Symbol, timeSuffix)
+	location, err := writeJSONArtifact(name, newsOutput)
+	if err != nil {
+		return err
 	}
 
-	log.Printf("[CompanyNewsTool] 新闻已保存到: %s", filePath)
+	log.Printf("[CompanyNewsTool] 新闻已保存到: %s", location)
 	return nil
 }
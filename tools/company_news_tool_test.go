@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+func TestCompanyNewsToolDateWindow(t *testing.T) {
+	previous := Output()
+	SetOutputSink(NewMemorySink())
+	t.Cleanup(func() { SetOutputSink(previous) })
+
+	today := time.Now().Format(dateLayout)
+	tests := []struct {
+		name      string
+		input     string
+		wantCall  bool
+		wantSince string // 为空表示不限制开始日期
+		wantEnd   string
+		wantLimit int
+		wantError bool
+	}{
+		{name: "传递开始和结束日期", input: `{"symbol":"AAPL","start_date":"2025-01-01","end_date":"2025-01-31","limit":5}`,
+			wantCall: true, wantSince: "2025-01-01", wantEnd: "2025-01-31", wantLimit: 5},
+		{name: "未提供日期时只限制结束日期", input: `{"symbol":"AAPL"}`,
+			wantCall: true, wantEnd: today, wantLimit: 10},
+		{name: "条数上限为 20", input: `{"symbol":"AAPL","end_date":"2025-01-31","limit":50}`,
+			wantCall: true, wantEnd: "2025-01-31", wantLimit: 20},
+		{name: "无效窗口不请求数据", input: `{"symbol":"AAPL","start_date":"2025-02-01","end_date":"2025-01-31"}`,
+			wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var called bool
+			var gotSince *string
+			var gotEnd string
+			var gotLimit int
+			getNews := func(ctx context.Context, symbol, date string, since *string, limit int) ([]CompanyNews, error) {
+				called, gotSince, gotEnd, gotLimit = true, since, date, limit
+				// 返回超过 limit 条的新闻，工具按 limit 截断
+				news := make([]CompanyNews, limit+3)
+				for i := range news {
+					news[i] = CompanyNews{ID: fmt.Sprint(i), Title: "新闻", DateTime: date}
+				}
+				return news, nil
+			}
+			newsTool, err := NewCompanyNewsTool(getNews, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := newsTool.(tool.InvokableTool).InvokableRun(context.Background(), tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var output CompanyNewsOutput
+			if err := json.Unmarshal([]byte(result), &output); err != nil {
+				t.Fatal(err)
+			}
+
+			if called != tt.wantCall {
+				t.Fatalf("调用数据函数: %v，期望 %v", called, tt.wantCall)
+			}
+			if tt.wantError {
+				if output.Error == "" {
+					t.Error("期望输出错误信息")
+				}
+				return
+			}
+			since := ""
+			if gotSince != nil {
+				since = *gotSince
+			}
+			if since != tt.wantSince || gotEnd != tt.wantEnd || gotLimit != tt.wantLimit {
+				t.Errorf("传递的窗口为 (%q, %q, %d)，期望 (%q, %q, %d)", since, gotEnd, gotLimit, tt.wantSince, tt.wantEnd, tt.wantLimit)
+			}
+			if output.StartDate != tt.wantSince || output.EndDate != tt.wantEnd {
+				t.Errorf("输出的窗口为 (%q, %q)，期望 (%q, %q)", output.StartDate, output.EndDate, tt.wantSince, tt.wantEnd)
+			}
+			if output.Count != tt.wantLimit {
+				t.Errorf("返回 %d 条新闻，期望 %d 条", output.Count, tt.wantLimit)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// 业务描述的来源
+const (
+	DescriptionSourceFiling  = "10-K"    // 最近一份年报的业务章节（Item 1）
+	DescriptionSourceWebsite = "website" // 公司官网页面的描述信息
+)
+
+// CompanyOverview 公司基本信息和业务描述
+type CompanyOverview struct {
+	Name              string `json:"name"`
+	Sector            string `json:"sector,omitempty"`
+	Industry          string `json:"industry,omitempty"`
+	Exchange          string `json:"exchange,omitempty"`
+	Location          string `json:"location,omitempty"`
+	ListingDate       string `json:"listing_date,omitempty"`
+	Employees         int    `json:"employees,omitempty"`
+	Website           string `json:"website,omitempty"`
+	Description       string `json:"description,omitempty"`
+	DescriptionSource string `json:"description_source,omitempty"` // 10-K 或 website，没有业务描述时为空
+	DescriptionYear   int    `json:"description_year,omitempty"`   // 来源为年报时的年度
+	SourceURL         string `json:"source_url,omitempty"`         // 年报或官网页面地址
+}
+
+// CompanyProfileInput 公司简介查询的输入参数
+type CompanyProfileInput struct {
+	Symbol string `json:"symbol" description:"股票代码，如 AAPL, TSLA, GOOG"`
+}
+
+// CompanyProfileOutput 公司简介查询的输出结果
+type CompanyProfileOutput struct {
+	Symbol string `json:"symbol"`
+	CompanyOverview
+	Error string `json:"error,omitempty"`
+}
+
+// NewCompanyProfileTool 创建公司简介工具
+func NewCompanyProfileTool(getOverviewFunc func(ctx context.Context, symbol string) (*CompanyOverview, error)) (tool.BaseTool, error) {
+	tool, err := inferTool("get_company_profile",
+		"获取公司的业务描述（来自最近一份年报的业务章节，没有年报时取自公司官网）以及板块、行业、交易所、员工人数等基本信息。用于报告开头介绍公司实际从事的业务，不要依赖可能过时的记忆。",
+		func(ctx context.Context, req *CompanyProfileInput) (*CompanyProfileOutput, error) {
+			Logger(ctx).Printf("[CompanyProfileTool] 接收到请求: Symbol=%s", req.Symbol)
+
+			// 验证必需参数
+			if req.Symbol == "" {
+				Logger(ctx).Printf("[CompanyProfileTool] 错误: 股票代码为空")
+				return &CompanyProfileOutput{
+					Error: "股票代码不能为空",
+				}, nil
+			}
+
+			overview, err := getOverviewFunc(ctx, req.Symbol)
+			if err != nil {
+				Logger(ctx).Printf("[CompanyProfileTool] API调用失败: %v", err)
+				if isFatalAPIError(err) {
+					return nil, err
+				}
+				return &CompanyProfileOutput{
+					Symbol: req.Symbol,
+					Error:  fmt.Sprintf("获取公司简介失败: %v", err),
+				}, nil
+			}
+
+			result := &CompanyProfileOutput{
+				Symbol:          req.Symbol,
+				CompanyOverview: *overview,
+			}
+			if result.Description == "" {
+				result.Description = "没有找到年报业务章节或官网描述，请只根据行业信息介绍公司，并在报告中说明"
+			}
+			Logger(ctx).Printf("[CompanyProfileTool] 返回响应: Name=%s, DescriptionSource=%s, 描述长度=%d",
+				result.Name, result.DescriptionSource, len([]rune(result.Description)))
+			return result, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return tool, nil
+}
@@ -0,0 +1,236 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// CompetitiveMaxScore 竞争地位评分的满分
+const CompetitiveMaxScore = 10
+
+// 竞争对手数量范围，少于最少数量时仍然分析，但在结果中提示样本不足
+const (
+	minCompetitors = 3
+	maxCompetitors = 5
+)
+
+// competitorDescriptionRunes 传给评估模型的每家公司业务描述最大字符数
+const competitorDescriptionRunes = 600
+
+// 市场地位和定价权的取值
+var (
+	MarketPositions = []string{"领导者", "挑战者", "跟随者", "细分市场"}
+	PricingPowers   = []string{"强", "中", "弱"}
+)
+
+// CompetitorSnapshot 参与竞争分析的一家公司的简介和最新 TTM 指标
+type CompetitorSnapshot struct {
+	PeerCompany
+	Name        string `json:"name,omitempty"`
+	Industry    string `json:"industry,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// CompetitiveAssessment 竞争格局评估结论（通常由大模型给出）
+type CompetitiveAssessment struct {
+	MarketPosition string   `json:"market_position"` // 领导者/挑战者/跟随者/细分市场
+	PricingPower   string   `json:"pricing_power"`   // 强/中/弱
+	Score          int      `json:"score"`           // 竞争地位评分，1 到 CompetitiveMaxScore
+	Advantages     []string `json:"advantages"`
+	Disadvantages  []string `json:"disadvantages"`
+	Threats        []string `json:"threats"`
+	Rationale      string   `json:"rationale"`
+}
+
+// Validate 校验评估结论的取值范围
+func (a *CompetitiveAssessment) Validate() error {
+	if !slices.Contains(MarketPositions, a.MarketPosition) {
+		return fmt.Errorf("market_position 必须是 %s 之一，实际为 %q", strings.Join(MarketPositions, "/"), a.MarketPosition)
+	}
+	if !slices.Contains(PricingPowers, a.PricingPower) {
+		return fmt.Errorf("pricing_power 必须是 %s 之一，实际为 %q", strings.Join(PricingPowers, "/"), a.PricingPower)
+	}
+	if a.Score < 1 || a.Score > CompetitiveMaxScore {
+		return fmt.Errorf("score 必须在 1 到 %d 之间，实际为 %d", CompetitiveMaxScore, a.Score)
+	}
+	if strings.TrimSpace(a.Rationale) == "" {
+		return fmt.Errorf("rationale 不能为空")
+	}
+	return nil
+}
+
+// CompetitiveAssessor 根据公司简介、指标和组内排名评估市场地位和定价权的评估器（通常由大模型实现）
+// companies[0] 为目标公司
+type CompetitiveAssessor func(ctx context.Context, symbol string, companies []CompetitorSnapshot, rankings []PeerMetricComparison) (*CompetitiveAssessment, error)
+
+// CompetitiveAnalysisInput 竞争格局分析的输入参数
+type CompetitiveAnalysisInput struct {
+	Symbol      string   `json:"symbol" description:"股票代码，如 AAPL, TSLA, GOOG"`
+	Competitors []string `json:"competitors,omitempty" description:"可选，3 到 5 家主要竞争对手的股票代码；不提供时使用可比公司组"`
+}
+
+// CompetitiveAnalysisOutput 竞争格局分析的输出结果
+type CompetitiveAnalysisOutput struct {
+	Symbol           string                 `json:"symbol"`
+	CompetitorSource string                 `json:"competitor_source"`
+	Competitors      []string               `json:"competitors"`
+	Companies        []CompetitorSnapshot   `json:"companies"`
+	Rankings         []PeerMetricComparison `json:"rankings"`
+	Assessment       *CompetitiveAssessment `json:"assessment,omitempty"`
+	MaxScore         int                    `json:"max_score"`
+	Note             string                 `json:"note,omitempty"`
+	Error            string                 `json:"error,omitempty"`
+}
+
+// NewCompetitiveAnalysisTool 创建竞争格局分析工具：获取 3 到 5 家竞争对手的简介和指标，
+// 由评估器比较市场地位和定价权，给出竞争地位评分
+func NewCompetitiveAnalysisTool(
+	getPeersFunc func(symbol string) (*PeerGroup, error),
+	getOverviewFunc func(ctx context.Context, symbol string) (*CompanyOverview, error),
+	getMetricsFunc func(ctx context.Context, symbol, date, period string, limit int) ([]FinancialMetrics, error),
+	assessor CompetitiveAssessor,
+) (tool.BaseTool, error) {
+	tool, err := inferTool("analyze_competition",
+		fmt.Sprintf("竞争格局分析：获取 %d 到 %d 家主要竞争对手的业务描述和关键指标，比较市场地位和定价权（毛利率、营运利润率等在组内的排名），给出竞争地位评分（满分 %d），最终评级需参考该评分。",
+			minCompetitors, maxCompetitors, CompetitiveMaxScore),
+		func(ctx context.Context, req *CompetitiveAnalysisInput) (*CompetitiveAnalysisOutput, error) {
+			Logger(ctx).Printf("[CompetitiveAnalysisTool] 接收到请求: Symbol=%s, Competitors=%v", req.Symbol, req.Competitors)
+
+			// 验证必需参数
+			if req.Symbol == "" {
+				Logger(ctx).Printf("[CompetitiveAnalysisTool] 错误: 股票代码为空")
+				return &CompetitiveAnalysisOutput{
+					Error: "股票代码不能为空",
+				}, nil
+			}
+			symbol := strings.ToUpper(req.Symbol)
+
+			group := &PeerGroup{Symbol: symbol, Source: PeerSourceRequest}
+			for _, competitor := range req.Competitors {
+				competitor = strings.ToUpper(strings.TrimSpace(competitor))
+				if competitor != "" && competitor != symbol && !slices.Contains(group.Peers, competitor) {
+					group.Peers = append(group.Peers, competitor)
+				}
+			}
+			if len(group.Peers) == 0 {
+				var err error
+				group, err = getPeersFunc(symbol)
+				if err != nil {
+					Logger(ctx).Printf("[CompetitiveAnalysisTool] 获取竞争对手失败: %v", err)
+					return &CompetitiveAnalysisOutput{
+						Symbol: symbol,
+						Error:  fmt.Sprintf("获取竞争对手失败: %v", err),
+					}, nil
+				}
+			}
+			competitors := group.Peers
+			if len(competitors) > maxCompetitors {
+				competitors = competitors[:maxCompetitors]
+			}
+			Logger(ctx).Printf("[CompetitiveAnalysisTool] 竞争对手: %v (来源: %s)", competitors, group.Source)
+
+			companies := fetchCompetitorSnapshots(ctx, getOverviewFunc, getMetricsFunc, append([]string{symbol}, competitors...))
+			result := &CompetitiveAnalysisOutput{
+				Symbol:           symbol,
+				CompetitorSource: group.Source,
+				Competitors:      competitors,
+				Companies:        companies,
+				MaxScore:         CompetitiveMaxScore,
+			}
+			if companies[0].Error != "" {
+				result.Error = companies[0].Error
+				return result, nil
+			}
+
+			peers := make([]PeerCompany, len(companies))
+			available := 0
+			for i, company := range companies {
+				peers[i] = company.PeerCompany
+				if i > 0 && company.Error == "" {
+					available++
+				}
+			}
+			result.Rankings = comparePeerMetrics(peers)
+			if available < minCompetitors {
+				result.Note = fmt.Sprintf("只有 %d 家竞争对手有可用数据（建议至少 %d 家），竞争地位结论仅供参考", available, minCompetitors)
+			}
+
+			assessment, err := assessor(ctx, symbol, companies, result.Rankings)
+			if err != nil {
+				Logger(ctx).Printf("[CompetitiveAnalysisTool] 竞争地位评估失败: %v", err)
+				result.Error = fmt.Sprintf("竞争地位评估失败: %v", err)
+				return result, nil
+			}
+			result.Assessment = assessment
+
+			if err := saveCompetitiveAnalysisToFile(result); err != nil {
+				Logger(ctx).Printf("[CompetitiveAnalysisTool] 保存分析结果失败: %v", err)
+			}
+
+			Logger(ctx).Printf("[CompetitiveAnalysisTool] 返回响应: Symbol=%s, 竞争对手=%d, 市场地位=%s, 定价权=%s, 评分=%d/%d",
+				symbol, len(competitors), assessment.MarketPosition, assessment.PricingPower, assessment.Score, CompetitiveMaxScore)
+			return result, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return tool, nil
+}
+
+// fetchCompetitorSnapshots 并行获取每家公司的简介和最新 TTM 指标，结果顺序与 symbols 一致
+// 简介获取失败不影响指标，指标获取失败时记录在 Error 中
+func fetchCompetitorSnapshots(
+	ctx context.Context,
+	getOverviewFunc func(ctx context.Context, symbol string) (*CompanyOverview, error),
+	getMetricsFunc func(ctx context.Context, symbol, date, period string, limit int) ([]FinancialMetrics, error),
+	symbols []string,
+) []CompetitorSnapshot {
+	date := time.Now().Format("2006-01-02")
+	companies := make([]CompetitorSnapshot, len(symbols))
+	var wg sync.WaitGroup
+	for i, symbol := range symbols {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			company := CompetitorSnapshot{PeerCompany: latestPeerCompany(ctx, getMetricsFunc, symbol, date)}
+			if overview, err := getOverviewFunc(ctx, symbol); err != nil {
+				log.Printf("[CompetitiveAnalysisTool] %s: 获取公司简介失败: %v", symbol, err)
+			} else {
+				company.Name = overview.Name
+				company.Industry = overview.Industry
+				description := []rune(overview.Description)
+				if len(description) > competitorDescriptionRunes {
+					description = append(description[:competitorDescriptionRunes], '…')
+				}
+				company.Description = string(description)
+			}
+			if company.Error != "" {
+				log.Printf("[CompetitiveAnalysisTool] %s: %s", symbol, company.Error)
+			}
+			companies[i] = company
+		}()
+	}
+	wg.Wait()
+	return companies
+}
+
+// saveCompetitiveAnalysisToFile 将竞争格局分析结果写入输出目标
+func saveCompetitiveAnalysisToFile(analysis *CompetitiveAnalysisOutput) error {
+	// 生成文件名：competition/competition_AAPL_2025-09-25_15-04-05.json
+	timeSuffix := time.Now().Format("2006-01-02_15-04-05")
+	name := fmt.Sprintf("competition/competition_%s_%s.json", analysis.Symbol, timeSuffix)
+	location, err := writeJSONArtifact(name, analysis)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[CompetitiveAnalysisTool] 分析结果已保存到: %s", location)
+	return nil
+}
@@ -0,0 +1,170 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// concentrationPattern 客户/供应商集中度相关段落的关键词规则
+var concentrationPattern = regexp.MustCompile(`(?i)\b(customers?|suppliers?|vendors?|concentration|single[- ]source|sole[- ]source|distributors?|contract manufactur\w*|largest|significant portion|% of (our )?(total )?(net )?(revenue|sales))\b`)
+
+// Counterparty 主要客户或供应商
+type Counterparty struct {
+	Name         string `json:"name"`
+	Relationship string `json:"relationship"` // customer 或 supplier
+	Share        string `json:"share,omitempty"`
+	Notes        string `json:"notes,omitempty"`
+}
+
+// ConcentrationDisclosure 客户与供应链集中度披露
+type ConcentrationDisclosure struct {
+	Customers          []Counterparty `json:"customers"`
+	Suppliers          []Counterparty `json:"suppliers"`
+	ConcentrationNotes []string       `json:"concentration_notes"`
+}
+
+// ConcentrationExtractor 从文件段落中提取集中度信息的抽取器（通常由大模型实现）
+type ConcentrationExtractor func(ctx context.Context, symbol string, excerpts []string) (*ConcentrationDisclosure, error)
+
+// ConcentrationInput 客户/供应链集中度查询的输入参数
+type ConcentrationInput struct {
+	Symbol string `json:"symbol" description:"股票代码，如 AAPL, TSLA, GOOG"`
+	Year   int    `json:"year,omitempty" description:"年报年度，如 2024，如果不提供则使用最近一个年度"`
+}
+
+// ConcentrationOutput 客户/供应链集中度查询的输出结果
+type ConcentrationOutput struct {
+	Symbol     string `json:"symbol"`
+	FilingType string `json:"filing_type"`
+	Year       int    `json:"year"`
+	URL        string `json:"url,omitempty"`
+	ConcentrationDisclosure
+	Error string `json:"error,omitempty"`
+}
+
+// NewConcentrationTool 创建客户与供应链集中度提取工具
+func NewConcentrationTool(
+	getSectionsFunc func(ctx context.Context, symbol string, year int) ([]FilingSection, error),
+	extractor ConcentrationExtractor,
+) (tool.BaseTool, error) {
+	tool, err := inferTool("extract_dependencies",
+		"从公司年报（业务、风险因素、管理层讨论章节）中提取主要客户、主要供应商以及客户/供应商集中度披露，用于评估护城河和依赖风险。",
+		func(ctx context.Context, req *ConcentrationInput) (*ConcentrationOutput, error) {
+			Logger(ctx).Printf("[ConcentrationTool] 接收到请求: Symbol=%s, Year=%d", req.Symbol, req.Year)
+
+			// 验证必需参数
+			if req.Symbol == "" {
+				Logger(ctx).Printf("[ConcentrationTool] 错误: 股票代码为空")
+				return &ConcentrationOutput{
+					Error: "股票代码不能为空",
+				}, nil
+			}
+
+			// 未指定年度时从最近一年开始向前查找
+			years := []int{req.Year}
+			if req.Year == 0 {
+				now := time.Now().Year()
+				years = []int{now, now - 1}
+			}
+
+			var sections []FilingSection
+			var year int
+			var lastErr error
+			for _, y := range years {
+				s, err := getSectionsFunc(ctx, req.Symbol, y)
+				if err != nil {
+					lastErr = err
+					continue
+				}
+				if len(s) > 0 {
+					sections, year = s, y
+					break
+				}
+			}
+			if len(sections) == 0 {
+				errMsg := "未找到年报章节"
+				if lastErr != nil {
+					errMsg = fmt.Sprintf("获取年报章节失败: %v", lastErr)
+				}
+				Logger(ctx).Printf("[ConcentrationTool] %s", errMsg)
+				return &ConcentrationOutput{
+					Symbol: req.Symbol,
+					Year:   req.Year,
+					Error:  errMsg,
+				}, nil
+			}
+
+			// 只保留与客户/供应商相关的段落，减少模型输入
+			excerpts := concentrationExcerpts(sections, 40)
+			Logger(ctx).Printf("[ConcentrationTool] 年报章节数量=%d, 相关段落数量=%d", len(sections), len(excerpts))
+
+			result := &ConcentrationOutput{
+				Symbol:     req.Symbol,
+				FilingType: sections[0].FilingType,
+				Year:       year,
+				URL:        sections[0].URL,
+			}
+			if len(excerpts) == 0 {
+				result.ConcentrationNotes = []string{"年报中未发现客户或供应商集中度相关披露"}
+				return result, nil
+			}
+
+			disclosure, err := extractor(ctx, req.Symbol, excerpts)
+			if err != nil {
+				Logger(ctx).Printf("[ConcentrationTool] 提取失败: %v", err)
+				result.Error = fmt.Sprintf("提取集中度信息失败: %v", err)
+				return result, nil
+			}
+			result.ConcentrationDisclosure = *disclosure
+
+			// 保存提取结果到本地文件
+			if err := saveConcentrationToFile(result); err != nil {
+				Logger(ctx).Printf("[ConcentrationTool] 保存文件失败: %v", err)
+				// 不返回错误，继续返回提取结果
+			}
+
+			Logger(ctx).Printf("[ConcentrationTool] 返回响应: Symbol=%s, Customers=%d, Suppliers=%d", result.Symbol, len(result.Customers), len(result.Suppliers))
+			return result, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return tool, nil
+}
+
+// concentrationExcerpts 从章节中筛选包含客户/供应商关键词的段落，最多返回 limit 段
+func concentrationExcerpts(sections []FilingSection, limit int) []string {
+	var excerpts []string
+	for _, section := range sections {
+		for _, paragraph := range strings.Split(section.Text, "\n") {
+			paragraph = strings.TrimSpace(paragraph)
+			if len(paragraph) < 40 || !concentrationPattern.MatchString(paragraph) {
+				continue
+			}
+			excerpts = append(excerpts, truncateText(paragraph, 1500))
+			if len(excerpts) >= limit {
+				return excerpts
+			}
+		}
+	}
+	return excerpts
+}
+
+// saveConcentrationToFile 将集中度提取结果写入输出目标
+func saveConcentrationToFile(output *ConcentrationOutput) error {
+	// 生成文件名：dependencies/dependencies_AAPL_2024.json
+	name := fmt.Sprintf("dependencies/dependencies_%s_%d.json", output.Symbol, output.Year)
+	location, err := writeJSONArtifact(name, output)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[ConcentrationTool] 集中度信息已保存到: %s", location)
+	return nil
+}
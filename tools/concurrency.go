@@ -0,0 +1,42 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// limitedTool 共享信号量限制并发执行数量的工具包装
+type limitedTool struct {
+	tool.InvokableTool
+	sem chan struct{}
+}
+
+// InvokableRun 获取信号量后执行工具，等待期间 ctx 取消时直接返回
+func (t *limitedTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	select {
+	case t.sem <- struct{}{}:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	defer func() { <-t.sem }()
+	return t.InvokableTool.InvokableRun(ctx, argumentsInJSON, opts...)
+}
+
+// WithConcurrencyLimit 为一组工具包装共享的并发上限，模型在同一轮中发起多个工具调用时最多同时执行 max 个
+// max <= 0 时不做限制，原样返回
+func WithConcurrencyLimit(tools []tool.BaseTool, max int) []tool.BaseTool {
+	if max <= 0 {
+		return tools
+	}
+	sem := make(chan struct{}, max)
+	limited := make([]tool.BaseTool, len(tools))
+	for i, t := range tools {
+		if invokable, ok := t.(tool.InvokableTool); ok {
+			limited[i] = &limitedTool{InvokableTool: invokable, sem: sem}
+			continue
+		}
+		limited[i] = t
+	}
+	return limited
+}
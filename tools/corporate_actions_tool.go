@@ -0,0 +1,306 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// 公司行动类型
+const (
+	CorporateActionAcquisition = "acquisition"
+	CorporateActionDivestiture = "divestiture"
+	CorporateActionSpinOff     = "spin_off"
+	CorporateActionMerger      = "merger"
+)
+
+// CorporateActionTypes 所有可用的公司行动类型
+var CorporateActionTypes = []string{
+	CorporateActionAcquisition,
+	CorporateActionDivestiture,
+	CorporateActionSpinOff,
+	CorporateActionMerger,
+}
+
+// CorporateActionStatuses 交易状态：已宣布、已完成、已终止
+var CorporateActionStatuses = []string{"announced", "completed", "terminated"}
+
+// corporateActionPattern 年报中收购、出售、分拆相关段落的关键词规则
+var corporateActionPattern = regexp.MustCompile(`(?i)\b(acquir(e|es|ed|ing)|acquisitions?|merger|divest(s|ed|iture|itures)?|spin-?off|spun off|split-?off|disposals?|sold (our|the|its)|sale of (our|the|its)|purchase price allocation|goodwill impairment)\b`)
+
+// 传给抽取器的新闻和年报段落数量上限，减少模型输入
+const (
+	maxCorporateActionNews     = 40
+	maxCorporateActionExcerpts = 30
+)
+
+// CorporateAction 一笔并购、出售或分拆交易
+type CorporateAction struct {
+	Date          string   `json:"date"` // 宣布或完成日期，只知道年份时为 YYYY
+	Type          string   `json:"type"` // acquisition/divestiture/spin_off/merger
+	Counterparty  string   `json:"counterparty"`
+	Description   string   `json:"description,omitempty"`
+	DealValue     *float64 `json:"deal_value,omitempty"`      // 交易金额（美元），未披露时为空
+	DealValueText string   `json:"deal_value_text,omitempty"` // 原文中的金额描述
+	Status        string   `json:"status"`                    // announced/completed/terminated
+	Integration   string   `json:"integration,omitempty"`     // 整合结果，如收入贡献、商誉减值、后续出售
+	Sources       []int    `json:"sources"`                   // 对应 CorporateActionsOutput.Sources 的编号
+}
+
+// Validate 校验交易条目的取值
+func (a *CorporateAction) Validate() error {
+	if !slices.Contains(CorporateActionTypes, a.Type) {
+		return fmt.Errorf("type 必须是 %s 之一，实际为 %q", strings.Join(CorporateActionTypes, "/"), a.Type)
+	}
+	if !slices.Contains(CorporateActionStatuses, a.Status) {
+		return fmt.Errorf("status 必须是 %s 之一，实际为 %q", strings.Join(CorporateActionStatuses, "/"), a.Status)
+	}
+	if strings.TrimSpace(a.Counterparty) == "" {
+		return fmt.Errorf("counterparty 不能为空")
+	}
+	if a.DealValue != nil && (!IsFinite(*a.DealValue) || *a.DealValue < 0) {
+		return fmt.Errorf("deal_value 必须是非负数，实际为 %v", *a.DealValue)
+	}
+	if len(a.Sources) == 0 {
+		return fmt.Errorf("sources 不能为空")
+	}
+	return nil
+}
+
+// CorporateActionSource 交给抽取器的一条新闻或年报段落，编号从 1 开始
+type CorporateActionSource struct {
+	ID     int    `json:"id"`
+	Source string `json:"source"` // news 或 filing
+	Date   string `json:"date"`
+	Title  string `json:"title"`
+	URL    string `json:"url,omitempty"`
+	Text   string `json:"-"`
+}
+
+// CorporateActionExtractor 从新闻和年报段落中整理交易列表的抽取器（通常由大模型实现）
+// 同一笔交易在多条来源中出现时应合并为一条
+type CorporateActionExtractor func(ctx context.Context, symbol string, sources []CorporateActionSource) ([]CorporateAction, error)
+
+// AcquisitionSpend 一个年度现金流量表中的并购支出
+type AcquisitionSpend struct {
+	ReportPeriod string   `json:"report_period"`
+	Acquisitions *float64 `json:"acquisitions,omitempty"`
+	FreeCashFlow *float64 `json:"free_cash_flow,omitempty"`
+}
+
+// CorporateActionsInput 并购与公司行动历史查询的输入参数
+type CorporateActionsInput struct {
+	Symbol        string `json:"symbol" description:"股票代码，如 AAPL, TSLA, GOOG"`
+	LookbackYears int    `json:"lookback_years,omitempty" description:"回溯年数，默认为5年，最大5年"`
+}
+
+// CorporateActionsOutput 并购与公司行动历史查询的输出结果
+type CorporateActionsOutput struct {
+	Symbol             string                  `json:"symbol"`
+	StartDate          string                  `json:"start_date"`
+	EndDate            string                  `json:"end_date"`
+	Actions            []CorporateAction       `json:"actions"`
+	TypeCounts         map[string]int          `json:"type_counts"`
+	DisclosedDealValue float64                 `json:"disclosed_deal_value"` // 已披露金额的交易合计（美元）
+	AcquisitionSpend   []AcquisitionSpend      `json:"acquisition_spend,omitempty"`
+	AcquisitionsToFCF  SafeFloat               `json:"acquisitions_to_fcf"` // 窗口内并购支出合计 / 自由现金流合计
+	Sources            []CorporateActionSource `json:"sources"`
+	Warnings           []string                `json:"warnings,omitempty"`
+	Error              string                  `json:"error,omitempty"`
+}
+
+// NewCorporateActionsTool 创建并购与公司行动历史工具
+// getNewsFunc 用于检索并购相关新闻，getFilingsFunc 用于获取年报中的业务和管理层讨论章节，
+// getCapitalAllocationFunc 用于获取现金流量表中的并购支出，后两者可为 nil
+func NewCorporateActionsTool(
+	getNewsFunc func(ctx context.Context, symbol, date string, since *string, limit int) ([]CompanyNews, error),
+	getFilingsFunc func(ctx context.Context, symbol string, years []int) ([]FilingSection, error),
+	getCapitalAllocationFunc func(ctx context.Context, symbol string, years int) ([]CapitalAllocationPeriod, error),
+	extractor CorporateActionExtractor,
+) (tool.BaseTool, error) {
+	tool, err := inferTool("track_corporate_actions",
+		"整理过去几年（默认5年）公司的收购、出售、分拆和合并历史（来源于新闻和年报），汇总交易金额、交易状态和整合结果（如收入贡献、商誉减值、后续出售），并给出现金流量表中的年度并购支出及其占自由现金流的比例，用于资本配置和风险评估。",
+		func(ctx context.Context, req *CorporateActionsInput) (*CorporateActionsOutput, error) {
+			Logger(ctx).Printf("[CorporateActionsTool] 接收到请求: Symbol=%s, LookbackYears=%d", req.Symbol, req.LookbackYears)
+
+			// 验证必需参数
+			if req.Symbol == "" {
+				Logger(ctx).Printf("[CorporateActionsTool] 错误: 股票代码为空")
+				return &CorporateActionsOutput{
+					Error: "股票代码不能为空",
+				}, nil
+			}
+			symbol := strings.ToUpper(req.Symbol)
+
+			years := req.LookbackYears
+			if years <= 0 || years > 5 {
+				years = 5
+			}
+
+			now := time.Now()
+			result := &CorporateActionsOutput{
+				Symbol:            symbol,
+				StartDate:         now.AddDate(-years, 0, 0).Format("2006-01-02"),
+				EndDate:           now.Format("2006-01-02"),
+				TypeCounts:        make(map[string]int),
+				AcquisitionsToFCF: NaN(),
+			}
+
+			// 检索并购相关新闻
+			news, err := getNewsFunc(ctx, symbol, result.EndDate, &result.StartDate, 1000)
+			if err != nil {
+				Logger(ctx).Printf("[CorporateActionsTool] 获取新闻失败: %v", err)
+				if isFatalAPIError(err) {
+					return nil, err
+				}
+				result.Warnings = append(result.Warnings, fmt.Sprintf("获取新闻失败: %v", err))
+			}
+			CategorizeNews(news)
+			var sources []CorporateActionSource
+			for _, item := range news {
+				if newsDate(item) < result.StartDate || !slices.Contains(item.Topics, NewsTopicMergers) {
+					continue
+				}
+				sources = append(sources, CorporateActionSource{
+					Source: "news",
+					Date:   newsDate(item),
+					Title:  item.Title,
+					URL:    item.URL,
+					Text:   truncateText(item.Summary, 500),
+				})
+			}
+			if len(sources) > maxCorporateActionNews {
+				sort.SliceStable(sources, func(i, j int) bool {
+					return sources[i].Date > sources[j].Date
+				})
+				result.Warnings = append(result.Warnings, fmt.Sprintf("并购相关新闻共 %d 条，只使用最新的 %d 条", len(sources), maxCorporateActionNews))
+				sources = sources[:maxCorporateActionNews]
+			}
+
+			// 检索年报中提到收购、出售或分拆的段落
+			if getFilingsFunc != nil {
+				var filingYears []int
+				for y := now.Year(); y >= now.Year()-years; y-- {
+					filingYears = append(filingYears, y)
+				}
+				filings, err := getFilingsFunc(ctx, symbol, filingYears)
+				if err != nil {
+					Logger(ctx).Printf("[CorporateActionsTool] 获取年报章节失败: %v", err)
+					result.Warnings = append(result.Warnings, fmt.Sprintf("获取年报章节失败: %v", err))
+				}
+				sources = append(sources, corporateActionExcerpts(filings, maxCorporateActionExcerpts)...)
+			}
+			for i := range sources {
+				sources[i].ID = i + 1
+			}
+			result.Sources = sources
+
+			// 现金流量表中的并购支出
+			if getCapitalAllocationFunc != nil {
+				periods, err := getCapitalAllocationFunc(ctx, symbol, years)
+				if err != nil {
+					Logger(ctx).Printf("[CorporateActionsTool] 获取并购支出失败: %v", err)
+					result.Warnings = append(result.Warnings, fmt.Sprintf("获取现金流量表中的并购支出失败: %v", err))
+				}
+				var acquisitions, fcf float64
+				complete := len(periods) > 0
+				for _, p := range periods {
+					result.AcquisitionSpend = append(result.AcquisitionSpend, AcquisitionSpend{
+						ReportPeriod: p.ReportPeriod,
+						Acquisitions: p.Acquisitions,
+						FreeCashFlow: p.FreeCashFlow,
+					})
+					if p.Acquisitions == nil || p.FreeCashFlow == nil {
+						complete = false
+						continue
+					}
+					acquisitions += *p.Acquisitions
+					fcf += *p.FreeCashFlow
+				}
+				if complete && fcf > 0 {
+					result.AcquisitionsToFCF = SafeDiv(acquisitions, fcf)
+				}
+			}
+
+			Logger(ctx).Printf("[CorporateActionsTool] 新闻和年报段落数量=%d, 并购支出年度数=%d", len(sources), len(result.AcquisitionSpend))
+			if len(sources) == 0 {
+				result.Warnings = append(result.Warnings, "窗口内的新闻和年报中未发现收购、出售或分拆相关内容")
+				return result, nil
+			}
+
+			actions, err := extractor(ctx, symbol, sources)
+			if err != nil {
+				Logger(ctx).Printf("[CorporateActionsTool] 提取交易失败: %v", err)
+				result.Error = fmt.Sprintf("提取交易失败: %v", err)
+				return result, nil
+			}
+			sort.SliceStable(actions, func(i, j int) bool {
+				return actions[i].Date > actions[j].Date
+			})
+			result.Actions = actions
+			for _, action := range actions {
+				result.TypeCounts[action.Type]++
+				if action.DealValue != nil && action.Status != "terminated" {
+					result.DisclosedDealValue += *action.DealValue
+				}
+			}
+
+			if err := saveCorporateActionsToFile(result); err != nil {
+				Logger(ctx).Printf("[CorporateActionsTool] 保存文件失败: %v", err)
+				// 不返回错误，继续返回提取结果
+			}
+
+			Logger(ctx).Printf("[CorporateActionsTool] 返回响应: Symbol=%s, Actions=%d, DisclosedDealValue=%.0f",
+				symbol, len(result.Actions), result.DisclosedDealValue)
+			return result, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return tool, nil
+}
+
+// corporateActionExcerpts 从年报章节中筛选提到收购、出售或分拆的段落，最多返回 limit 段
+func corporateActionExcerpts(sections []FilingSection, limit int) []CorporateActionSource {
+	var excerpts []CorporateActionSource
+	for _, section := range sections {
+		for _, paragraph := range strings.Split(section.Text, "\n") {
+			paragraph = strings.TrimSpace(paragraph)
+			if len(paragraph) < 40 || !corporateActionPattern.MatchString(paragraph) {
+				continue
+			}
+			excerpts = append(excerpts, CorporateActionSource{
+				Source: "filing",
+				Date:   fmt.Sprintf("%d", section.Year),
+				Title:  fmt.Sprintf("%d 年 %s %s", section.Year, section.FilingType, section.Item),
+				URL:    section.URL,
+				Text:   truncateText(paragraph, 1500),
+			})
+			if len(excerpts) >= limit {
+				return excerpts
+			}
+		}
+	}
+	return excerpts
+}
+
+// saveCorporateActionsToFile 将公司行动历史写入输出目标
+func saveCorporateActionsToFile(output *CorporateActionsOutput) error {
+	// 生成文件名：corporate_actions/corporate_actions_AAPL_2025-09-25_15-04-05.json
+	timeSuffix := time.Now().Format("2006-01-02_15-04-05")
+	name := fmt.Sprintf("corporate_actions/corporate_actions_%s_%s.json", output.Symbol, timeSuffix)
+	location, err := writeJSONArtifact(name, output)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[CorporateActionsTool] 公司行动历史已保存到: %s", location)
+	return nil
+}
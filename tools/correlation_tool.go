@@ -0,0 +1,363 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// MaxCorrelationYears 相关性分析最多回溯的年数
+const MaxCorrelationYears = 5
+
+// highCorrelation 相关系数达到该值的两只股票视为高度相关
+const highCorrelation = 0.8
+
+// 组合分散程度评级
+const (
+	DiversificationGood = "良好"
+	DiversificationFair = "一般"
+	DiversificationPoor = "较差"
+)
+
+// PriceSeries 一只股票按日期升序排列的日线价格
+type PriceSeries struct {
+	Symbol string
+	PriceDataFrame
+}
+
+// PortfolioCorrelationInput 组合相关性分析的输入参数
+type PortfolioCorrelationInput struct {
+	Symbols []string  `json:"symbols" description:"组合中的股票代码列表，至少2只，如 [\"AAPL\", \"MSFT\", \"KO\"]"`
+	Weights []float64 `json:"weights,omitempty" description:"与 symbols 一一对应的持仓权重，不提供则等权"`
+	Years   int       `json:"years,omitempty" description:"回溯年数，默认1年，最多5年"`
+}
+
+// CorrelationPair 两只股票的收益率相关系数
+type CorrelationPair struct {
+	A           string    `json:"a"`
+	B           string    `json:"b"`
+	Correlation SafeFloat `json:"correlation"`
+}
+
+// PortfolioCorrelationOutput 组合相关性和波动率分析结果
+type PortfolioCorrelationOutput struct {
+	Symbols              []string             `json:"symbols"`
+	Weights              []float64            `json:"weights"`
+	StartDate            string               `json:"start_date"`
+	EndDate              string               `json:"end_date"`
+	Observations         int                  `json:"observations"` // 所有股票都有价格的交易日收益率样本数
+	Matrix               [][]SafeFloat        `json:"matrix"`       // 日收益率相关系数矩阵，顺序与 symbols 一致
+	AssetVolatility      map[string]SafeFloat `json:"asset_volatility"`
+	PortfolioVolatility  SafeFloat            `json:"portfolio_volatility"`
+	AverageCorrelation   SafeFloat            `json:"average_correlation"`
+	DiversificationRatio SafeFloat            `json:"diversification_ratio"` // 加权个股波动率 / 组合波动率，越大分散效果越好
+	HighlyCorrelated     []CorrelationPair    `json:"highly_correlated,omitempty"`
+	Diversification      string               `json:"diversification"`   // 良好、一般或较差
+	Missing              []string             `json:"missing,omitempty"` // 没有价格数据、未参与计算的股票
+	Error                string               `json:"error,omitempty"`
+}
+
+// NewPortfolioCorrelationTool 创建组合相关性分析工具
+func NewPortfolioCorrelationTool(getPricesFunc func(ctx context.Context, symbol string, years int) (*PriceSeries, error)) (tool.BaseTool, error) {
+	tool, err := inferTool("analyze_portfolio_correlation",
+		"计算组合内股票两两之间的日收益率相关系数、个股和组合的年化波动率以及分散化比率，评估组合的分散化质量，并找出高度相关的持仓。",
+		func(ctx context.Context, req *PortfolioCorrelationInput) (*PortfolioCorrelationOutput, error) {
+			Logger(ctx).Printf("[PortfolioCorrelationTool] 接收到请求: Symbols=%v, Weights=%v, Years=%d", req.Symbols, req.Weights, req.Years)
+
+			symbols := normalizeSymbolList(req.Symbols)
+			if len(symbols) < 2 {
+				Logger(ctx).Printf("[PortfolioCorrelationTool] 错误: 股票数量不足")
+				return &PortfolioCorrelationOutput{
+					Symbols: symbols,
+					Error:   "至少需要2只不同的股票",
+				}, nil
+			}
+			if len(req.Weights) > 0 && len(req.Weights) != len(req.Symbols) {
+				return &PortfolioCorrelationOutput{
+					Symbols: symbols,
+					Error:   fmt.Sprintf("weights 数量（%d）与 symbols 数量（%d）不一致", len(req.Weights), len(req.Symbols)),
+				}, nil
+			}
+			years := req.Years
+			if years <= 0 {
+				years = 1
+			}
+			if years > MaxCorrelationYears {
+				years = MaxCorrelationYears
+			}
+
+			weightOf := make(map[string]float64)
+			for i, symbol := range req.Symbols {
+				if len(req.Weights) > 0 {
+					weightOf[strings.ToUpper(strings.TrimSpace(symbol))] += req.Weights[i]
+				}
+			}
+
+			var series []PriceSeries
+			var weights []float64
+			var missing []string
+			for _, symbol := range symbols {
+				s, err := getPricesFunc(ctx, symbol, years)
+				if err != nil {
+					Logger(ctx).Printf("[PortfolioCorrelationTool] 获取 %s 价格失败: %v", symbol, err)
+					if isFatalAPIError(err) {
+						return nil, err
+					}
+					missing = append(missing, symbol)
+					continue
+				}
+				series = append(series, *s)
+				weights = append(weights, weightOf[symbol])
+			}
+			if len(req.Weights) == 0 {
+				weights = nil
+			}
+
+			result, err := ComputePortfolioCorrelation(series, weights)
+			if err != nil {
+				Logger(ctx).Printf("[PortfolioCorrelationTool] 计算失败: %v", err)
+				return &PortfolioCorrelationOutput{
+					Symbols: symbols,
+					Missing: missing,
+					Error:   err.Error(),
+				}, nil
+			}
+			result.Missing = missing
+
+			if err := savePortfolioCorrelationToFile(result); err != nil {
+				Logger(ctx).Printf("[PortfolioCorrelationTool] 保存文件失败: %v", err)
+			}
+
+			Logger(ctx).Printf("[PortfolioCorrelationTool] 返回响应: Symbols=%v, Observations=%d, PortfolioVolatility=%.4f, Diversification=%s", result.Symbols, result.Observations, float64(result.PortfolioVolatility), result.Diversification)
+			return result, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return tool, nil
+}
+
+// ComputePortfolioCorrelation 按共同交易日对齐价格，计算相关系数矩阵、年化波动率和分散化指标
+// weights 为空时等权，权重会归一化
+func ComputePortfolioCorrelation(series []PriceSeries, weights []float64) (*PortfolioCorrelationOutput, error) {
+	if len(series) < 2 {
+		return nil, fmt.Errorf("有价格数据的股票不足2只")
+	}
+	weights, err := normalizeWeights(weights, len(series))
+	if err != nil {
+		return nil, err
+	}
+
+	// 只保留所有股票都有价格的交易日
+	aligned := alignSeries(series)
+	dates := aligned[0].Dates
+	if len(dates) < 3 {
+		return nil, fmt.Errorf("共同交易日不足，无法计算相关性")
+	}
+	returns := make([][]float64, len(series))
+	for i, df := range aligned {
+		for j := 0; j < df.Len()-1; j++ {
+			if df.Close[j] <= 0 {
+				return nil, fmt.Errorf("%s 在 %s 的价格无效", series[i].Symbol, df.Dates[j].Format(dateLayout))
+			}
+		}
+		returns[i] = df.Returns()
+	}
+
+	n := len(series)
+	result := &PortfolioCorrelationOutput{
+		Weights:         weights,
+		StartDate:       dates[0].Format(dateLayout),
+		EndDate:         dates[len(dates)-1].Format(dateLayout),
+		Observations:    len(dates) - 1,
+		Matrix:          make([][]SafeFloat, n),
+		AssetVolatility: make(map[string]SafeFloat, n),
+	}
+	for _, s := range series {
+		result.Symbols = append(result.Symbols, s.Symbol)
+	}
+
+	// 协方差矩阵（日收益率），用于相关系数和组合波动率
+	cov := make([][]float64, n)
+	for i := range cov {
+		cov[i] = make([]float64, n)
+		for j := range cov[i] {
+			cov[i][j] = covariance(returns[i], returns[j])
+		}
+	}
+
+	annualize := math.Sqrt(252)
+	weightedVol := 0.0
+	for i := range series {
+		vol := math.Sqrt(cov[i][i]) * annualize
+		result.AssetVolatility[series[i].Symbol] = SafeFloat(vol)
+		weightedVol += weights[i] * vol
+	}
+
+	pairSum, pairs := 0.0, 0
+	for i := 0; i < n; i++ {
+		result.Matrix[i] = make([]SafeFloat, n)
+		for j := 0; j < n; j++ {
+			corr := math.NaN()
+			if denom := math.Sqrt(cov[i][i] * cov[j][j]); denom > 0 {
+				corr = cov[i][j] / denom
+			}
+			result.Matrix[i][j] = SafeFloat(corr)
+			if j > i && !math.IsNaN(corr) {
+				pairSum += corr
+				pairs++
+				if corr >= highCorrelation {
+					result.HighlyCorrelated = append(result.HighlyCorrelated, CorrelationPair{A: series[i].Symbol, B: series[j].Symbol, Correlation: SafeFloat(corr)})
+				}
+			}
+		}
+	}
+
+	variance := 0.0
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			variance += weights[i] * weights[j] * cov[i][j]
+		}
+	}
+	portfolioVol := math.Sqrt(variance) * annualize
+	result.PortfolioVolatility = SafeFloat(portfolioVol)
+	result.DiversificationRatio = SafeDiv(weightedVol, portfolioVol)
+
+	avg := math.NaN()
+	if pairs > 0 {
+		avg = pairSum / float64(pairs)
+	}
+	result.AverageCorrelation = SafeFloat(avg)
+	switch {
+	case math.IsNaN(avg):
+		result.Diversification = ""
+	case avg < 0.3:
+		result.Diversification = DiversificationGood
+	case avg < 0.6:
+		result.Diversification = DiversificationFair
+	default:
+		result.Diversification = DiversificationPoor
+	}
+	return result, nil
+}
+
+// RenderPortfolioCorrelation 将相关性分析渲染为 markdown，用于组合报告
+func RenderPortfolioCorrelation(r *PortfolioCorrelationOutput) string {
+	var sb strings.Builder
+	sb.WriteString("## 组合分散化分析\n\n")
+	sb.WriteString(fmt.Sprintf("样本区间 %s 至 %s，共 %d 个日收益率样本。\n\n", r.StartDate, r.EndDate, r.Observations))
+	sb.WriteString(fmt.Sprintf("- 组合年化波动率：%s\n", (r.PortfolioVolatility * 100).Sprintf("%.1f%%")))
+	sb.WriteString(fmt.Sprintf("- 平均两两相关系数：%s\n", r.AverageCorrelation.Sprintf("%.2f")))
+	sb.WriteString(fmt.Sprintf("- 分散化比率：%s\n", r.DiversificationRatio.Sprintf("%.2f")))
+	if r.Diversification != "" {
+		sb.WriteString(fmt.Sprintf("- 分散化质量：%s\n", r.Diversification))
+	}
+	for _, pair := range r.HighlyCorrelated {
+		sb.WriteString(fmt.Sprintf("- ⚠️ %s 与 %s 高度相关（%.2f）\n", pair.A, pair.B, float64(pair.Correlation)))
+	}
+	if len(r.Missing) > 0 {
+		sb.WriteString(fmt.Sprintf("- 缺少价格数据、未参与计算：%s\n", strings.Join(r.Missing, ", ")))
+	}
+
+	sb.WriteString("\n| | 权重 | 年化波动率 |")
+	for _, symbol := range r.Symbols {
+		sb.WriteString(" " + symbol + " |")
+	}
+	sb.WriteString("\n|---|---|---|" + strings.Repeat("---|", len(r.Symbols)) + "\n")
+	for i, symbol := range r.Symbols {
+		sb.WriteString(fmt.Sprintf("| %s | %.1f%% | %s |", symbol, r.Weights[i]*100, (r.AssetVolatility[symbol] * 100).Sprintf("%.1f%%")))
+		for _, corr := range r.Matrix[i] {
+			sb.WriteString(" " + corr.Sprintf("%.2f") + " |")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// alignSeries 按交易日期对齐所有序列，只保留都有价格的日期，返回的数据框架与 series 一一对应
+func alignSeries(series []PriceSeries) []*PriceDataFrame {
+	common := &series[0].PriceDataFrame
+	for i := 1; i < len(series); i++ {
+		common, _ = common.AlignWith(&series[i].PriceDataFrame)
+	}
+	aligned := make([]*PriceDataFrame, len(series))
+	for i := range series {
+		_, aligned[i] = common.AlignWith(&series[i].PriceDataFrame)
+	}
+	return aligned
+}
+
+// covariance 样本协方差
+func covariance(a, b []float64) float64 {
+	if len(a) < 2 || len(a) != len(b) {
+		return math.NaN()
+	}
+	meanA, meanB := 0.0, 0.0
+	for i := range a {
+		meanA += a[i]
+		meanB += b[i]
+	}
+	meanA /= float64(len(a))
+	meanB /= float64(len(b))
+	sum := 0.0
+	for i := range a {
+		sum += (a[i] - meanA) * (b[i] - meanB)
+	}
+	return sum / float64(len(a)-1)
+}
+
+// normalizeWeights 权重归一化，为空时等权
+func normalizeWeights(weights []float64, n int) ([]float64, error) {
+	if len(weights) == 0 {
+		equal := make([]float64, n)
+		for i := range equal {
+			equal[i] = 1 / float64(n)
+		}
+		return equal, nil
+	}
+	if len(weights) != n {
+		return nil, fmt.Errorf("权重数量（%d）与股票数量（%d）不一致", len(weights), n)
+	}
+	total := 0.0
+	for _, w := range weights {
+		if w < 0 {
+			return nil, fmt.Errorf("权重不能为负数")
+		}
+		total += w
+	}
+	if total <= 0 {
+		return nil, fmt.Errorf("权重之和必须大于0")
+	}
+	normalized := make([]float64, n)
+	for i, w := range weights {
+		normalized[i] = w / total
+	}
+	return normalized, nil
+}
+
+// normalizeSymbolList 股票代码转大写并去重
+func normalizeSymbolList(symbols []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, symbol := range symbols {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if symbol != "" && !seen[symbol] {
+			seen[symbol] = true
+			result = append(result, symbol)
+		}
+	}
+	return result
+}
+
+// savePortfolioCorrelationToFile 将相关性分析写入输出目标
+func savePortfolioCorrelationToFile(result *PortfolioCorrelationOutput) error {
+	// 生成文件名：correlation/correlation_AAPL-MSFT-KO_2025-09-25_15-04-05.json
+	timeSuffix := time.Now().Format("2006-01-02_15-04-05")
+	name := fmt.Sprintf("correlation/correlation_%s_%s.json", strings.Join(result.Symbols, "-"), timeSuffix)
+	_, err := writeJSONArtifact(name, result)
+	return err
+}
@@ -0,0 +1,410 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// 信用风险结论
+const (
+	CreditRiskSafe     = "safe"
+	CreditRiskGrey     = "grey"
+	CreditRiskDistress = "distress"
+)
+
+// Altman Z″-score（非制造业/新兴市场版本）的分区阈值：高于 AltmanZSafe 为安全区，低于 AltmanZDistress 为困境区
+const (
+	AltmanZSafe     = 2.60
+	AltmanZDistress = 1.10
+)
+
+// 利息保障倍数（EBIT / 利息费用）的阈值：不低于 CoverageSafe 为安全，低于 CoverageDistress 为困境
+const (
+	CoverageSafe     = 5.0
+	CoverageDistress = 1.5
+)
+
+// 调整后债务/EBITDA 的阈值：不高于 LeverageSafe 为安全，高于 LeverageDistress 为困境
+const (
+	LeverageSafe     = 3.0
+	LeverageDistress = 5.0
+)
+
+// coverageTrendChange 利息保障倍数首尾变化超过该比例时判断为改善或恶化
+const coverageTrendChange = 0.2
+
+// DefaultCreditRiskDebtToEquity 默认的债务股权比阈值，超过时必须做信用风险评估
+const DefaultCreditRiskDebtToEquity = 1.0
+
+// CreditRiskPeriod 一个年度计算 Z″-score 和利息保障倍数所需的报表项目及计算结果
+// 利息费用为正数；计算结果在数据缺失或分母为 0 时为 n/a
+type CreditRiskPeriod struct {
+	ReportPeriod       string   `json:"report_period"`
+	CurrentAssets      *float64 `json:"current_assets,omitempty"`
+	CurrentLiabilities *float64 `json:"current_liabilities,omitempty"`
+	RetainedEarnings   *float64 `json:"retained_earnings,omitempty"`
+	EBIT               *float64 `json:"ebit,omitempty"`
+	InterestExpense    *float64 `json:"interest_expense,omitempty"`
+	TotalAssets        *float64 `json:"total_assets,omitempty"`
+	TotalLiabilities   *float64 `json:"total_liabilities,omitempty"`
+	ShareholdersEquity *float64 `json:"shareholders_equity,omitempty"`
+	EBITDA             *float64 `json:"ebitda,omitempty"`
+	TotalDebt          *float64 `json:"total_debt,omitempty"`
+	OperatingLeases    *float64 `json:"operating_lease_liabilities,omitempty"` // 经营租赁负债
+	PensionDeficit     *float64 `json:"pension_deficit,omitempty"`             // 养老金及退休后福利的净负债（资金缺口）
+
+	ZScore           SafeFloat `json:"z_score"`
+	Zone             string    `json:"zone,omitempty"`
+	InterestCoverage SafeFloat `json:"interest_coverage"`
+
+	// 报告口径与计入租赁和养老金缺口后的经济口径杠杆
+	DebtToEquity         SafeFloat `json:"debt_to_equity"`
+	AdjustedDebtToEquity SafeFloat `json:"adjusted_debt_to_equity"`
+	DebtToEBITDA         SafeFloat `json:"debt_to_ebitda"`
+	AdjustedDebtToEBITDA SafeFloat `json:"adjusted_debt_to_ebitda"`
+}
+
+// CreditRiskInput 信用风险评估的输入参数
+type CreditRiskInput struct {
+	Symbol string `json:"symbol" description:"股票代码，如 AAPL, TSLA, GOOG"`
+	Years  int    `json:"years,omitempty" description:"年度数量，默认为5年，最大10年"`
+}
+
+// CreditRiskOutput 信用风险评估的输出结果
+type CreditRiskOutput struct {
+	Symbol               string             `json:"symbol"`
+	Periods              []CreditRiskPeriod `json:"periods"`
+	ZScore               SafeFloat          `json:"z_score"`
+	Zone                 string             `json:"zone,omitempty"`
+	InterestCoverage     SafeFloat          `json:"interest_coverage"`
+	CoverageTrend        string             `json:"coverage_trend,omitempty"` // improving/stable/deteriorating
+	AdjustedDebtToEquity SafeFloat          `json:"adjusted_debt_to_equity"`
+	AdjustedDebtToEBITDA SafeFloat          `json:"adjusted_debt_to_ebitda"`
+	LeverageZone         string             `json:"leverage_zone,omitempty"`
+	Verdict              string             `json:"verdict,omitempty"` // safe/grey/distress
+	Reasons              []string           `json:"reasons"`
+	Thresholds           string             `json:"thresholds"`
+	Error                string             `json:"error,omitempty"`
+}
+
+// NewCreditRiskTool 创建破产与信用风险评估工具
+// getCreditDataFunc 返回最近 years 个年度的报表项目，最新的在前
+func NewCreditRiskTool(getCreditDataFunc func(ctx context.Context, symbol string, years int) ([]CreditRiskPeriod, error)) (tool.BaseTool, error) {
+	tool, err := inferTool("assess_credit_risk",
+		fmt.Sprintf("破产与信用风险评估：按年度计算 Altman Z''-score（非制造业版本）、利息保障倍数（EBIT/利息费用）的趋势，以及计入经营租赁负债和养老金缺口后的调整债务股权比和调整债务/EBITDA，给出信用风险结论（safe/grey/distress）。债务股权比超过阈值（财务指标结果中 credit_risk_required 为 true）时必须调用。阈值：%s",
+			creditRiskThresholds()),
+		func(ctx context.Context, req *CreditRiskInput) (*CreditRiskOutput, error) {
+			Logger(ctx).Printf("[CreditRiskTool] 接收到请求: Symbol=%s, Years=%d", req.Symbol, req.Years)
+
+			// 验证必需参数
+			if req.Symbol == "" {
+				Logger(ctx).Printf("[CreditRiskTool] 错误: 股票代码为空")
+				return &CreditRiskOutput{
+					Error: "股票代码不能为空",
+				}, nil
+			}
+			symbol := strings.ToUpper(req.Symbol)
+
+			years := req.Years
+			if years <= 0 {
+				years = 5
+			}
+			if years > 10 {
+				years = 10
+			}
+
+			periods, err := getCreditDataFunc(ctx, symbol, years)
+			if err != nil {
+				Logger(ctx).Printf("[CreditRiskTool] API调用失败: %v", err)
+				if isFatalAPIError(err) {
+					return nil, err
+				}
+				return &CreditRiskOutput{
+					Symbol: symbol,
+					Error:  fmt.Sprintf("获取资产负债表和利润表数据失败: %v", err),
+				}, nil
+			}
+			if len(periods) == 0 {
+				return &CreditRiskOutput{
+					Symbol: symbol,
+					Error:  "没有可用的年度报表数据",
+				}, nil
+			}
+
+			result := EvaluateCreditRisk(symbol, periods)
+			if err := saveCreditRiskToFile(result); err != nil {
+				Logger(ctx).Printf("[CreditRiskTool] 保存文件失败: %v", err)
+				// 不返回错误，继续返回评估结果
+			}
+
+			Logger(ctx).Printf("[CreditRiskTool] 返回响应: Symbol=%s, Z''=%s (%s), 利息保障倍数=%s (%s), 结论=%s",
+				symbol, result.ZScore.Sprintf("%.2f"), result.Zone, result.InterestCoverage.Sprintf("%.1f"), result.CoverageTrend, result.Verdict)
+			return result, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return tool, nil
+}
+
+// EvaluateCreditRisk 计算每个年度的 Z″-score、利息保障倍数和调整后杠杆，以最新年度给出信用风险结论
+// Z″ = 6.56×营运资本/总资产 + 3.26×留存收益/总资产 + 6.72×EBIT/总资产 + 1.05×股东权益/总负债
+// 调整后债务 = 报告债务 + 经营租赁负债 + 养老金缺口
+// 结论取 Z″ 分区、利息保障倍数和调整后债务/EBITDA 中最差的一项；都无法计算时不给结论
+func EvaluateCreditRisk(symbol string, periods []CreditRiskPeriod) *CreditRiskOutput {
+	result := &CreditRiskOutput{
+		Symbol:           symbol,
+		ZScore:           NaN(),
+		InterestCoverage: NaN(),
+		Thresholds:       creditRiskThresholds(),
+	}
+	for _, p := range periods {
+		p.ZScore = altmanZDoublePrime(p)
+		p.Zone = zScoreZone(p.ZScore)
+		p.InterestCoverage = interestCoverage(p)
+		p.DebtToEquity, p.AdjustedDebtToEquity, p.DebtToEBITDA, p.AdjustedDebtToEBITDA = adjustedLeverage(p)
+		result.Periods = append(result.Periods, p)
+	}
+
+	latest := result.Periods[0]
+	result.ZScore = latest.ZScore
+	result.Zone = latest.Zone
+	result.InterestCoverage = latest.InterestCoverage
+	result.AdjustedDebtToEquity = latest.AdjustedDebtToEquity
+	result.AdjustedDebtToEBITDA = latest.AdjustedDebtToEBITDA
+
+	var verdicts []string
+	if latest.Zone != "" {
+		verdicts = append(verdicts, latest.Zone)
+		result.Reasons = append(result.Reasons, fmt.Sprintf("%s Z''-score 为 %s，处于%s", latest.ReportPeriod, latest.ZScore.Sprintf("%.2f"), creditRiskLabel(latest.Zone)))
+	} else {
+		result.Reasons = append(result.Reasons, fmt.Sprintf("%s 缺少计算 Z''-score 所需的资产负债表项目", latest.ReportPeriod))
+	}
+
+	switch {
+	case latest.InterestCoverage.Valid():
+		coverage := float64(latest.InterestCoverage)
+		zone := CreditRiskGrey
+		switch {
+		case coverage >= CoverageSafe:
+			zone = CreditRiskSafe
+		case coverage < CoverageDistress:
+			zone = CreditRiskDistress
+		}
+		verdicts = append(verdicts, zone)
+		result.Reasons = append(result.Reasons, fmt.Sprintf("%s 利息保障倍数为 %s 倍（%s）", latest.ReportPeriod, latest.InterestCoverage.Sprintf("%.1f"), creditRiskLabel(zone)))
+	case latest.InterestExpense != nil && *latest.InterestExpense == 0:
+		verdicts = append(verdicts, CreditRiskSafe)
+		result.Reasons = append(result.Reasons, fmt.Sprintf("%s 没有利息费用", latest.ReportPeriod))
+	default:
+		result.Reasons = append(result.Reasons, fmt.Sprintf("%s 缺少 EBIT 或利息费用，无法计算利息保障倍数", latest.ReportPeriod))
+	}
+
+	if zone, reason := leverageZone(latest); zone != "" {
+		result.LeverageZone = zone
+		verdicts = append(verdicts, zone)
+		result.Reasons = append(result.Reasons, reason)
+	} else if reason != "" {
+		result.Reasons = append(result.Reasons, reason)
+	}
+
+	// 利息保障倍数趋势：比较最新和最早一个可计算的年度
+	var oldest *CreditRiskPeriod
+	for i := len(result.Periods) - 1; i > 0; i-- {
+		if result.Periods[i].InterestCoverage.Valid() {
+			oldest = &result.Periods[i]
+			break
+		}
+	}
+	if latest.InterestCoverage.Valid() && oldest != nil {
+		from, to := float64(oldest.InterestCoverage), float64(latest.InterestCoverage)
+		change := (to - from) / math.Abs(from)
+		switch {
+		case from == 0 || math.IsInf(change, 0) || math.IsNaN(change):
+			result.CoverageTrend = "stable"
+		case change > coverageTrendChange:
+			result.CoverageTrend = "improving"
+		case change < -coverageTrendChange:
+			result.CoverageTrend = "deteriorating"
+		default:
+			result.CoverageTrend = "stable"
+		}
+		result.Reasons = append(result.Reasons, fmt.Sprintf("利息保障倍数从 %s 的 %s 倍变为 %s 的 %s 倍（%s）",
+			oldest.ReportPeriod, oldest.InterestCoverage.Sprintf("%.1f"), latest.ReportPeriod, latest.InterestCoverage.Sprintf("%.1f"), coverageTrendLabel(result.CoverageTrend)))
+	}
+
+	for _, v := range verdicts {
+		if creditRiskRank(v) > creditRiskRank(result.Verdict) {
+			result.Verdict = v
+		}
+	}
+	// 处于灰色区且利息保障倍数持续恶化时，结论不变但需提示
+	if result.Verdict == CreditRiskGrey && result.CoverageTrend == "deteriorating" {
+		result.Reasons = append(result.Reasons, "处于灰色区且偿债能力在恶化，需关注债务到期和再融资风险")
+	}
+	return result
+}
+
+// adjustedLeverage 计算报告口径和调整后的债务股权比、债务/EBITDA
+// 没有披露的租赁负债或养老金缺口按 0 计入；股东权益为负或 EBITDA 不为正时对应倍数为 n/a
+func adjustedLeverage(p CreditRiskPeriod) (de, adjustedDE, debtToEBITDA, adjustedDebtToEBITDA SafeFloat) {
+	de, adjustedDE, debtToEBITDA, adjustedDebtToEBITDA = NaN(), NaN(), NaN(), NaN()
+	if p.TotalDebt == nil {
+		return
+	}
+	adjusted := *p.TotalDebt
+	if p.OperatingLeases != nil {
+		adjusted += *p.OperatingLeases
+	}
+	if p.PensionDeficit != nil && *p.PensionDeficit > 0 {
+		adjusted += *p.PensionDeficit
+	}
+	if p.ShareholdersEquity != nil && *p.ShareholdersEquity > 0 {
+		de = SafeDiv(*p.TotalDebt, *p.ShareholdersEquity)
+		adjustedDE = SafeDiv(adjusted, *p.ShareholdersEquity)
+	}
+	if p.EBITDA != nil && *p.EBITDA > 0 {
+		debtToEBITDA = SafeDiv(*p.TotalDebt, *p.EBITDA)
+		adjustedDebtToEBITDA = SafeDiv(adjusted, *p.EBITDA)
+	}
+	return
+}
+
+// leverageZone 根据调整后债务/EBITDA 判断杠杆分区，并说明租赁和养老金对杠杆的影响
+// 有债务但 EBITDA 不为正时判为困境；缺少债务数据时分区为空
+func leverageZone(p CreditRiskPeriod) (zone, reason string) {
+	if p.TotalDebt == nil {
+		return "", fmt.Sprintf("%s 缺少总债务数据，无法计算调整后杠杆", p.ReportPeriod)
+	}
+	format := NumberFormat{Locale: DefaultLocale}
+	var parts []string
+	if p.OperatingLeases != nil && *p.OperatingLeases > 0 {
+		parts = append(parts, "经营租赁负债 "+format.Compact(*p.OperatingLeases))
+	}
+	if p.PensionDeficit != nil && *p.PensionDeficit > 0 {
+		parts = append(parts, "养老金缺口 "+format.Compact(*p.PensionDeficit))
+	}
+	adjustment := "没有需要计入的租赁负债或养老金缺口"
+	if len(parts) > 0 {
+		adjustment = "计入" + strings.Join(parts, "、")
+	}
+
+	switch {
+	case p.AdjustedDebtToEBITDA.Valid():
+		ratio := float64(p.AdjustedDebtToEBITDA)
+		zone = CreditRiskGrey
+		switch {
+		case ratio <= LeverageSafe:
+			zone = CreditRiskSafe
+		case ratio > LeverageDistress:
+			zone = CreditRiskDistress
+		}
+		return zone, fmt.Sprintf("%s %s后，调整债务/EBITDA 为 %s 倍（报告口径 %s 倍，%s），调整债务股权比为 %s（报告口径 %s）",
+			p.ReportPeriod, adjustment, p.AdjustedDebtToEBITDA.Sprintf("%.1f"), p.DebtToEBITDA.Sprintf("%.1f"), creditRiskLabel(zone),
+			p.AdjustedDebtToEquity.Sprintf("%.2f"), p.DebtToEquity.Sprintf("%.2f"))
+	case *p.TotalDebt > 0 && p.EBITDA != nil:
+		return CreditRiskDistress, fmt.Sprintf("%s EBITDA 不为正但有债务，%s后调整债务股权比为 %s（困境区）",
+			p.ReportPeriod, adjustment, p.AdjustedDebtToEquity.Sprintf("%.2f"))
+	}
+	return "", fmt.Sprintf("%s 缺少 EBITDA，%s后调整债务股权比为 %s（报告口径 %s）",
+		p.ReportPeriod, adjustment, p.AdjustedDebtToEquity.Sprintf("%.2f"), p.DebtToEquity.Sprintf("%.2f"))
+}
+
+// altmanZDoublePrime 计算 Altman Z″-score，任一项目缺失或分母为 0 时返回 n/a
+func altmanZDoublePrime(p CreditRiskPeriod) SafeFloat {
+	if p.CurrentAssets == nil || p.CurrentLiabilities == nil || p.RetainedEarnings == nil || p.EBIT == nil ||
+		p.TotalAssets == nil || p.TotalLiabilities == nil || p.ShareholdersEquity == nil {
+		return NaN()
+	}
+	x1 := SafeDiv(*p.CurrentAssets-*p.CurrentLiabilities, *p.TotalAssets)
+	x2 := SafeDiv(*p.RetainedEarnings, *p.TotalAssets)
+	x3 := SafeDiv(*p.EBIT, *p.TotalAssets)
+	x4 := SafeDiv(*p.ShareholdersEquity, *p.TotalLiabilities)
+	return Sanitize(6.56*float64(x1) + 3.26*float64(x2) + 6.72*float64(x3) + 1.05*float64(x4))
+}
+
+// interestCoverage 计算利息保障倍数，缺少数据或没有利息费用时返回 n/a
+func interestCoverage(p CreditRiskPeriod) SafeFloat {
+	if p.EBIT == nil || p.InterestExpense == nil {
+		return NaN()
+	}
+	return SafeDiv(*p.EBIT, *p.InterestExpense)
+}
+
+// zScoreZone 返回 Z″-score 所在的分区，无法计算时为空
+func zScoreZone(z SafeFloat) string {
+	switch {
+	case !z.Valid():
+		return ""
+	case float64(z) > AltmanZSafe:
+		return CreditRiskSafe
+	case float64(z) < AltmanZDistress:
+		return CreditRiskDistress
+	default:
+		return CreditRiskGrey
+	}
+}
+
+// creditRiskRank 结论的严重程度，用于取较差的一项
+func creditRiskRank(verdict string) int {
+	switch verdict {
+	case CreditRiskSafe:
+		return 1
+	case CreditRiskGrey:
+		return 2
+	case CreditRiskDistress:
+		return 3
+	}
+	return 0
+}
+
+// creditRiskLabel 结论的中文名称
+func creditRiskLabel(verdict string) string {
+	switch verdict {
+	case CreditRiskSafe:
+		return "安全区"
+	case CreditRiskGrey:
+		return "灰色区"
+	case CreditRiskDistress:
+		return "困境区"
+	}
+	return verdict
+}
+
+// coverageTrendLabel 利息保障倍数趋势的中文名称
+func coverageTrendLabel(trend string) string {
+	switch trend {
+	case "improving":
+		return "改善"
+	case "deteriorating":
+		return "恶化"
+	}
+	return "稳定"
+}
+
+// creditRiskThresholds 返回阈值说明，写入工具描述和结果
+func creditRiskThresholds() string {
+	return fmt.Sprintf("Z'' > %.2f 安全区，%.2f 到 %.2f 灰色区，< %.2f 困境区；利息保障倍数 ≥ %.0f 安全，%.1f 到 %.0f 灰色，< %.1f 困境；调整债务/EBITDA ≤ %.0f 安全，%.0f 到 %.0f 灰色，> %.0f 困境；结论取三者中最差的一项",
+		AltmanZSafe, AltmanZDistress, AltmanZSafe, AltmanZDistress, CoverageSafe, CoverageDistress, CoverageSafe, CoverageDistress,
+		LeverageSafe, LeverageSafe, LeverageDistress, LeverageDistress)
+}
+
+// saveCreditRiskToFile 将信用风险评估结果写入输出目标
+func saveCreditRiskToFile(output *CreditRiskOutput) error {
+	// 生成文件名：credit/credit_AAPL_2025-09-25_15-04-05.json
+	timeSuffix := time.Now().Format("2006-01-02_15-04-05")
+	name := fmt.Sprintf("credit/credit_%s_%s.json", output.Symbol, timeSuffix)
+	location, err := writeJSONArtifact(name, output)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[CreditRiskTool] 评估结果已保存到: %s", location)
+	return nil
+}
@@ -0,0 +1,215 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// PriceDataFrame 按日期升序排列的日线价格数据框架
+type PriceDataFrame struct {
+	Dates  []time.Time
+	Open   []float64
+	Close  []float64
+	High   []float64
+	Low    []float64
+	Volume []int64
+}
+
+// Len 返回数据框架的行数
+func (df *PriceDataFrame) Len() int {
+	return len(df.Dates)
+}
+
+// Returns 计算收盘价的简单日收益率，长度为 Len()-1，第 i 个值对应 Dates[i+1]
+// 前一日收盘价为 0 时收益率无法计算，记为 0
+func (df *PriceDataFrame) Returns() []float64 {
+	if df.Len() < 2 {
+		return []float64{}
+	}
+	returns := make([]float64, df.Len()-1)
+	for i := 1; i < df.Len(); i++ {
+		r := SafeGrowth(df.Close[i], df.Close[i-1])
+		if r.Valid() {
+			returns[i-1] = float64(r)
+		}
+	}
+	return returns
+}
+
+// RollingMean 计算收盘价的 n 日滚动均值，长度为 Len()-n+1，第 i 个值对应 Dates[i+n-1]
+func (df *PriceDataFrame) RollingMean(n int) []float64 {
+	if n <= 0 || df.Len() < n {
+		return []float64{}
+	}
+	means := make([]float64, df.Len()-n+1)
+	sum := 0.0
+	for i := 0; i < df.Len(); i++ {
+		sum += df.Close[i]
+		if i >= n {
+			sum -= df.Close[i-n]
+		}
+		if i >= n-1 {
+			means[i-n+1] = sum / float64(n)
+		}
+	}
+	return means
+}
+
+// LastRollingMean 返回最近 n 个交易日的收盘均价，数据不足 n 天时为 NaN
+func (df *PriceDataFrame) LastRollingMean(n int) SafeFloat {
+	means := df.RollingMean(n)
+	if len(means) == 0 {
+		return NaN()
+	}
+	return Sanitize(means[len(means)-1])
+}
+
+// Drawdowns 计算每个交易日收盘价相对历史最高收盘价的回撤（0 或负数），长度为 Len()
+func (df *PriceDataFrame) Drawdowns() []float64 {
+	drawdowns := make([]float64, df.Len())
+	peak := math.Inf(-1)
+	for i, price := range df.Close {
+		if price > peak {
+			peak = price
+		}
+		if peak > 0 {
+			drawdowns[i] = price/peak - 1
+		}
+	}
+	return drawdowns
+}
+
+// MaxDrawdown 返回区间内的最大回撤（负数）
+func (df *PriceDataFrame) MaxDrawdown() float64 {
+	maxDrawdown := 0.0
+	for _, drawdown := range df.Drawdowns() {
+		if drawdown < maxDrawdown {
+			maxDrawdown = drawdown
+		}
+	}
+	return maxDrawdown
+}
+
+// Resample 将日线数据重采样为周线（"W"）或月线（"M"）
+// 开盘价取周期内第一天，收盘价取最后一天，最高/最低取极值，成交量求和，日期为周期内最后一个交易日
+func (df *PriceDataFrame) Resample(freq string) (*PriceDataFrame, error) {
+	var bucket func(t time.Time) string
+	switch freq {
+	case "W":
+		bucket = func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}
+	case "M":
+		bucket = func(t time.Time) string {
+			return t.Format("2006-01")
+		}
+	default:
+		return nil, fmt.Errorf("不支持的重采样频率: %s，可选值为 W、M", freq)
+	}
+
+	out := &PriceDataFrame{}
+	current := ""
+	for i, date := range df.Dates {
+		key := bucket(date)
+		if key != current {
+			current = key
+			out.Dates = append(out.Dates, date)
+			out.Open = append(out.Open, df.Open[i])
+			out.Close = append(out.Close, df.Close[i])
+			out.High = append(out.High, df.High[i])
+			out.Low = append(out.Low, df.Low[i])
+			out.Volume = append(out.Volume, df.Volume[i])
+			continue
+		}
+		last := out.Len() - 1
+		out.Dates[last] = date
+		out.Close[last] = df.Close[i]
+		out.High[last] = math.Max(out.High[last], df.High[i])
+		out.Low[last] = math.Min(out.Low[last], df.Low[i])
+		out.Volume[last] += df.Volume[i]
+	}
+	return out, nil
+}
+
+// AlignWith 按交易日期对齐两个数据框架，只保留两者都存在的日期，重复的日期只保留一行
+func (df *PriceDataFrame) AlignWith(other *PriceDataFrame) (*PriceDataFrame, *PriceDataFrame) {
+	otherIndex := make(map[string]int, other.Len())
+	for i, date := range other.Dates {
+		otherIndex[date.Format(dateLayout)] = i
+	}
+
+	left, right := &PriceDataFrame{}, &PriceDataFrame{}
+	for i, date := range df.Dates {
+		key := date.Format(dateLayout)
+		j, ok := otherIndex[key]
+		if !ok {
+			continue
+		}
+		delete(otherIndex, key)
+		left.appendRow(df, i)
+		right.appendRow(other, j)
+	}
+	return left, right
+}
+
+// appendRow 追加 src 的第 i 行
+func (df *PriceDataFrame) appendRow(src *PriceDataFrame, i int) {
+	df.Dates = append(df.Dates, src.Dates[i])
+	df.Open = append(df.Open, src.Open[i])
+	df.Close = append(df.Close, src.Close[i])
+	df.High = append(df.High, src.High[i])
+	df.Low = append(df.Low, src.Low[i])
+	df.Volume = append(df.Volume, src.Volume[i])
+}
+
+// WorstPeriodReturn 按 freq（"W" 或 "M"）重采样后最差的周期收益率，周期不足两个时为 NaN
+// 第一个周期没有前一周期的收盘价，不参与比较
+func (df *PriceDataFrame) WorstPeriodReturn(freq string) SafeFloat {
+	resampled, err := df.Resample(freq)
+	if err != nil {
+		return NaN()
+	}
+	returns := resampled.Returns()
+	if len(returns) == 0 {
+		return NaN()
+	}
+	worst := math.Inf(1)
+	for _, r := range returns {
+		worst = math.Min(worst, r)
+	}
+	return Sanitize(worst)
+}
+
+// CAGR 计算区间内收盘价的年化复合增长率，按自然日折算年数
+func (df *PriceDataFrame) CAGR() SafeFloat {
+	if df.Len() < 2 {
+		return NaN()
+	}
+	years := df.Dates[df.Len()-1].Sub(df.Dates[0]).Hours() / 24 / 365.25
+	growth := SafeDiv(df.Close[df.Len()-1], df.Close[0])
+	if years <= 0 || !growth.Valid() || growth <= 0 {
+		return NaN()
+	}
+	return Sanitize(math.Pow(float64(growth), 1/years) - 1)
+}
+
+// AnnualizedVolatility 计算日收益率的年化波动率（按每年 252 个交易日）
+func (df *PriceDataFrame) AnnualizedVolatility() SafeFloat {
+	returns := df.Returns()
+	if len(returns) < 2 {
+		return NaN()
+	}
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	variance /= float64(len(returns) - 1)
+	return Sanitize(math.Sqrt(variance * 252))
+}
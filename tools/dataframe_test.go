@@ -0,0 +1,212 @@
+package tools
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// closesFrame 用从 2024-01-01 开始按天递增的收盘价构造数据框架
+func closesFrame(closes ...float64) *PriceDataFrame {
+	df := &PriceDataFrame{Close: closes}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range closes {
+		df.Dates = append(df.Dates, start.AddDate(0, 0, i))
+	}
+	return df
+}
+
+func floatsNear(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPriceDataFrameReturns(t *testing.T) {
+	tests := []struct {
+		name   string
+		closes []float64
+		want   []float64
+	}{
+		{name: "空数据", want: []float64{}},
+		{name: "只有一天", closes: []float64{100}, want: []float64{}},
+		{name: "日收益率", closes: []float64{100, 110, 99}, want: []float64{0.1, -0.1}},
+		{name: "前一日价格为 0 记为 0", closes: []float64{0, 10, 12}, want: []float64{0, 0.2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := closesFrame(tt.closes...).Returns(); !floatsNear(got, tt.want) {
+				t.Errorf("得到 %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPriceDataFrameDrawdowns(t *testing.T) {
+	df := closesFrame(100, 120, 90, 130, 104)
+	want := []float64{0, 0, -0.25, 0, -0.2}
+	if got := df.Drawdowns(); !floatsNear(got, want) {
+		t.Errorf("回撤为 %v，期望 %v", got, want)
+	}
+	if got := df.MaxDrawdown(); math.Abs(got-(-0.25)) > 1e-9 {
+		t.Errorf("最大回撤为 %v，期望 -0.25", got)
+	}
+	if got := closesFrame(1, 2, 3).MaxDrawdown(); got != 0 {
+		t.Errorf("单边上涨的最大回撤为 %v，期望 0", got)
+	}
+}
+
+func TestPriceDataFrameCAGR(t *testing.T) {
+	df := &PriceDataFrame{
+		Dates: []time.Time{time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)},
+		Close: []float64{100, 121},
+	}
+	// 两年间隔 731 天，按 365.25 天折算约为 2 年
+	if got := float64(df.CAGR()); math.Abs(got-0.1) > 1e-3 {
+		t.Errorf("CAGR 为 %v，期望约 0.1", got)
+	}
+	for name, df := range map[string]*PriceDataFrame{
+		"只有一天":    closesFrame(100),
+		"起点价格为 0": closesFrame(0, 100),
+		"价格跌到 0":  closesFrame(100, 0),
+	} {
+		if got := df.CAGR(); got.Valid() {
+			t.Errorf("%s: CAGR 为 %v，期望 NaN", name, got)
+		}
+	}
+}
+
+func TestPriceDataFrameAnnualizedVolatility(t *testing.T) {
+	if got := closesFrame(100, 101).AnnualizedVolatility(); got.Valid() {
+		t.Errorf("只有一个收益率时波动率为 %v，期望 NaN", got)
+	}
+	// 收益率交替为 +10% 和 -10%，样本标准差为 sqrt(4*0.01/3)
+	df := closesFrame(100, 110, 99, 108.9, 98.01)
+	want := math.Sqrt(4*0.01/3) * math.Sqrt(252)
+	if got := float64(df.AnnualizedVolatility()); math.Abs(got-want) > 1e-9 {
+		t.Errorf("年化波动率为 %v，期望 %v", got, want)
+	}
+}
+
+func TestPriceDataFrameRollingMean(t *testing.T) {
+	df := closesFrame(1, 2, 3, 4, 5)
+	tests := []struct {
+		n    int
+		want []float64
+	}{
+		{n: 1, want: []float64{1, 2, 3, 4, 5}},
+		{n: 3, want: []float64{2, 3, 4}},
+		{n: 5, want: []float64{3}},
+		{n: 6, want: []float64{}},
+		{n: 0, want: []float64{}},
+	}
+	for _, tt := range tests {
+		if got := df.RollingMean(tt.n); !floatsNear(got, tt.want) {
+			t.Errorf("%d 日均值为 %v，期望 %v", tt.n, got, tt.want)
+		}
+	}
+}
+
+// barsFrame 用给定日期和收盘价构造数据框架，开盘、最高、最低价与收盘价相同，成交量为 1
+func barsFrame(dates []string, closes ...float64) *PriceDataFrame {
+	df := &PriceDataFrame{}
+	for i, date := range dates {
+		d, _ := time.Parse(dateLayout, date)
+		df.Dates = append(df.Dates, d)
+		df.Open = append(df.Open, closes[i])
+		df.Close = append(df.Close, closes[i])
+		df.High = append(df.High, closes[i])
+		df.Low = append(df.Low, closes[i])
+		df.Volume = append(df.Volume, 1)
+	}
+	return df
+}
+
+func TestPriceDataFrameResample(t *testing.T) {
+	// 2024-01-05 是周五，01-08 是下周一；01-31 和 02-01 在同一周但跨月
+	df := barsFrame([]string{"2024-01-04", "2024-01-05", "2024-01-08", "2024-01-31", "2024-02-01"}, 10, 12, 8, 11, 9)
+
+	weekly, err := df.Resample("W")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if weekly.Len() != 3 {
+		t.Fatalf("周线有 %d 行，期望 3", weekly.Len())
+	}
+	if got := weekly.Dates[0].Format(dateLayout); got != "2024-01-05" {
+		t.Errorf("第一周的日期为 %s，期望周内最后一个交易日 2024-01-05", got)
+	}
+	if weekly.Open[0] != 10 || weekly.Close[0] != 12 || weekly.High[0] != 12 || weekly.Low[0] != 10 || weekly.Volume[0] != 2 {
+		t.Errorf("第一周为 O=%v C=%v H=%v L=%v V=%d，期望 10/12/12/10/2", weekly.Open[0], weekly.Close[0], weekly.High[0], weekly.Low[0], weekly.Volume[0])
+	}
+
+	monthly, err := df.Resample("M")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !floatsNear(monthly.Close, []float64{11, 9}) || monthly.Low[0] != 8 || monthly.Volume[0] != 4 {
+		t.Errorf("月线收盘价为 %v、1 月最低价 %v、成交量 %d，期望 [11 9]、8、4", monthly.Close, monthly.Low[0], monthly.Volume[0])
+	}
+
+	if _, err := df.Resample("D"); err == nil {
+		t.Error("不支持的频率应返回错误")
+	}
+}
+
+func TestPriceDataFrameAlignWith(t *testing.T) {
+	stock := barsFrame([]string{"2024-01-02", "2024-01-03", "2024-01-04", "2024-01-05"}, 1, 2, 3, 4)
+	index := barsFrame([]string{"2024-01-01", "2024-01-03", "2024-01-05", "2024-01-05"}, 10, 30, 50, 51)
+
+	left, right := stock.AlignWith(index)
+	if left.Len() != 2 || right.Len() != 2 {
+		t.Fatalf("对齐后有 %d / %d 行，期望 2 / 2", left.Len(), right.Len())
+	}
+	for i := range left.Dates {
+		if !left.Dates[i].Equal(right.Dates[i]) {
+			t.Errorf("第 %d 行日期不一致: %v / %v", i, left.Dates[i], right.Dates[i])
+		}
+	}
+	if !floatsNear(left.Close, []float64{2, 4}) || !floatsNear(right.Close, []float64{30, 51}) {
+		t.Errorf("对齐后的收盘价为 %v / %v，期望 [2 4] / [30 51]", left.Close, right.Close)
+	}
+}
+
+func TestPriceDataFrameLastRollingMeanAndWorstPeriod(t *testing.T) {
+	df := barsFrame([]string{"2024-01-30", "2024-01-31", "2024-02-28", "2024-02-29", "2024-03-29"}, 100, 100, 90, 80, 88)
+	if got := float64(df.LastRollingMean(2)); got != 84 {
+		t.Errorf("最近 2 日均价为 %v，期望 84", got)
+	}
+	if got := df.LastRollingMean(10); got.Valid() {
+		t.Errorf("数据不足时均价为 %v，期望 NaN", got)
+	}
+	// 月末收盘价 100 → 80 → 88，最差月份为 2 月的 -20%
+	if got := float64(df.WorstPeriodReturn("M")); math.Abs(got-(-0.2)) > 1e-9 {
+		t.Errorf("最差月度收益为 %v，期望 -0.2", got)
+	}
+	if got := barsFrame([]string{"2024-01-02", "2024-01-31"}, 1, 2).WorstPeriodReturn("M"); got.Valid() {
+		t.Errorf("只有一个月时最差月度收益为 %v，期望 NaN", got)
+	}
+}
+
+func TestComputePortfolioCorrelationAlignsDates(t *testing.T) {
+	a := PriceSeries{Symbol: "A", PriceDataFrame: *barsFrame([]string{"2024-01-02", "2024-01-03", "2024-01-04", "2024-01-05", "2024-01-08"}, 10, 11, 10, 12, 11)}
+	// B 缺少 01-04，且多出 A 没有的 01-06
+	b := PriceSeries{Symbol: "B", PriceDataFrame: *barsFrame([]string{"2024-01-02", "2024-01-03", "2024-01-05", "2024-01-06", "2024-01-08"}, 20, 22, 24, 30, 22)}
+	result, err := ComputePortfolioCorrelation([]PriceSeries{a, b}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Observations != 3 || result.StartDate != "2024-01-02" || result.EndDate != "2024-01-08" {
+		t.Errorf("样本为 %d 个（%s ~ %s），期望共同交易日上的 3 个（2024-01-02 ~ 2024-01-08）", result.Observations, result.StartDate, result.EndDate)
+	}
+	// 共同交易日上两只股票的收益率完全一致
+	if got := float64(result.Matrix[0][1]); math.Abs(got-1) > 1e-9 {
+		t.Errorf("相关系数为 %v，期望 1", got)
+	}
+}
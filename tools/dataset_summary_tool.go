@@ -0,0 +1,192 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// 支持分页拉取的数据集，取值与数据库的表名一致
+const (
+	DatasetNews          = "news"
+	DatasetInsiderTrades = "insider_trades"
+)
+
+// DatasetSummary 分页拉取后的数据集摘要，代替完整数据返回给 Agent
+type DatasetSummary struct {
+	Symbol        string            `json:"symbol"`
+	Dataset       string            `json:"dataset"`
+	StartDate     string            `json:"start_date"`
+	EndDate       string            `json:"end_date"`
+	Count         int               `json:"count"`
+	Pages         int               `json:"pages"`
+	FirstDate     string            `json:"first_date,omitempty"`
+	LastDate      string            `json:"last_date,omitempty"`
+	MonthlyCounts map[string]int    `json:"monthly_counts"`
+	TopicCounts   map[string]int    `json:"topic_counts,omitempty"`
+	Sentiment     *SentimentSummary `json:"sentiment,omitempty"`
+	BuyCount      int               `json:"buy_count,omitempty"`
+	SellCount     int               `json:"sell_count,omitempty"`
+	NetShares     float64           `json:"net_shares,omitempty"`
+	Samples       []string          `json:"samples"`
+	Store         string            `json:"store,omitempty"`            // 保存完整数据的数据库和表，未设置 DATA_STORE 时为空
+	Truncated     string            `json:"truncated_notice,omitempty"` // 分页因条数上限或时间窗口提前停止的原因
+	Error         string            `json:"error,omitempty"`
+
+	sentimentTotal float64 // 已评分新闻的情绪分之和，用于计算平均分
+}
+
+// maxSummarySamples 摘要中保留的样例条数
+const maxSummarySamples = 10
+
+// NewDatasetSummary 创建空的数据集摘要
+func NewDatasetSummary(symbol, dataset, startDate, endDate string) *DatasetSummary {
+	return &DatasetSummary{
+		Symbol:        symbol,
+		Dataset:       dataset,
+		StartDate:     startDate,
+		EndDate:       endDate,
+		MonthlyCounts: make(map[string]int),
+	}
+}
+
+// AddNewsPage 将一页新闻计入摘要（会为新闻打上主题标签），已评分的新闻计入情绪汇总
+func (s *DatasetSummary) AddNewsPage(page []CompanyNews) {
+	CategorizeNews(page)
+	if s.TopicCounts == nil {
+		s.TopicCounts = make(map[string]int)
+	}
+	s.Pages++
+	for _, item := range page {
+		s.addDate(newsDate(item))
+		for _, topic := range item.Topics {
+			s.TopicCounts[topic]++
+		}
+		if item.Sentiment != nil {
+			if s.Sentiment == nil {
+				s.Sentiment = &SentimentSummary{}
+			}
+			s.Sentiment.add(*item.Sentiment)
+			s.sentimentTotal += item.Sentiment.Score
+			s.Sentiment.AverageScore = SafeFloat(s.sentimentTotal / float64(s.Sentiment.Scored))
+		}
+		if len(s.Samples) < maxSummarySamples {
+			s.Samples = append(s.Samples, fmt.Sprintf("%s %s", newsDate(item), item.Title))
+		}
+	}
+}
+
+// AddInsiderTradesPage 将一页内部人交易计入摘要
+func (s *DatasetSummary) AddInsiderTradesPage(page []InsiderTrade) {
+	s.Pages++
+	for _, trade := range page {
+		date := trade.FilingDate
+		if len(date) > 10 {
+			date = date[:10]
+		}
+		s.addDate(date)
+		if trade.TransactionShares != nil {
+			shares := *trade.TransactionShares
+			if shares > 0 {
+				s.BuyCount++
+			} else if shares < 0 {
+				s.SellCount++
+			}
+			s.NetShares += shares
+		}
+		if len(s.Samples) < maxSummarySamples {
+			name := ""
+			if trade.Name != nil {
+				name = *trade.Name
+			}
+			shares := 0.0
+			if trade.TransactionShares != nil {
+				shares = *trade.TransactionShares
+			}
+			s.Samples = append(s.Samples, fmt.Sprintf("%s %s %.0f 股", date, name, shares))
+		}
+	}
+}
+
+// addDate 更新计数、日期范围和按月统计
+func (s *DatasetSummary) addDate(date string) {
+	s.Count++
+	if date == "" {
+		return
+	}
+	if s.FirstDate == "" || date < s.FirstDate {
+		s.FirstDate = date
+	}
+	if date > s.LastDate {
+		s.LastDate = date
+	}
+	if len(date) >= 7 {
+		s.MonthlyCounts[date[:7]]++
+	}
+}
+
+// DatasetSummaryInput 数据集摘要查询的输入参数
+type DatasetSummaryInput struct {
+	Symbol    string `json:"symbol" description:"股票代码，如 AAPL, TSLA, GOOG"`
+	Dataset   string `json:"dataset" description:"数据集类型：news(公司新闻)、insider_trades(内部人交易)"`
+	StartDate string `json:"start_date,omitempty" description:"开始日期，格式为 YYYY-MM-DD，如果不提供则使用结束日期前365天"`
+	EndDate   string `json:"end_date,omitempty" description:"结束日期，格式为 YYYY-MM-DD，如果不提供则使用当前日期"`
+}
+
+// NewDatasetSummaryTool 创建大数据集摘要工具
+// streamFunc 负责分页拉取数据并逐页写入数据库，返回汇总后的摘要
+func NewDatasetSummaryTool(streamFunc func(ctx context.Context, dataset, symbol, startDate, endDate string) (*DatasetSummary, error)) (tool.BaseTool, error) {
+	tool, err := inferTool("summarize_dataset",
+		"拉取较长时间窗口内的全部公司新闻或内部人交易（可能有数千条），逐页保存到数据库而不是全部返回，只返回摘要：总数、按月分布、新闻主题分布和情绪分布或内部人净买卖情况以及少量样例。适合长周期的趋势分析。",
+		func(ctx context.Context, req *DatasetSummaryInput) (*DatasetSummary, error) {
+			Logger(ctx).Printf("[DatasetSummaryTool] 接收到请求: Symbol=%s, Dataset=%s, StartDate=%s, EndDate=%s", req.Symbol, req.Dataset, req.StartDate, req.EndDate)
+
+			// 验证必需参数
+			if req.Symbol == "" {
+				Logger(ctx).Printf("[DatasetSummaryTool] 错误: 股票代码为空")
+				return &DatasetSummary{
+					Error: "股票代码不能为空",
+				}, nil
+			}
+			if req.Dataset != DatasetNews && req.Dataset != DatasetInsiderTrades {
+				Logger(ctx).Printf("[DatasetSummaryTool] 错误: 不支持的数据集 %q", req.Dataset)
+				return &DatasetSummary{
+					Symbol: req.Symbol,
+					Error:  fmt.Sprintf("不支持的数据集 %q，可选值为 news、insider_trades", req.Dataset),
+				}, nil
+			}
+
+			// 解析并校验日期窗口
+			startDate, endDate, err := resolveDateWindow(req.StartDate, req.EndDate, 365, time.Now())
+			if err != nil {
+				Logger(ctx).Printf("[DatasetSummaryTool] 错误: %v", err)
+				return &DatasetSummary{
+					Symbol:  req.Symbol,
+					Dataset: req.Dataset,
+					Error:   err.Error(),
+				}, nil
+			}
+
+			summary, err := streamFunc(ctx, req.Dataset, req.Symbol, startDate, endDate)
+			if err != nil {
+				Logger(ctx).Printf("[DatasetSummaryTool] 拉取数据失败: %v", err)
+				if isFatalAPIError(err) {
+					return nil, err
+				}
+				if summary == nil {
+					summary = NewDatasetSummary(req.Symbol, req.Dataset, startDate, endDate)
+				}
+				summary.Error = fmt.Sprintf("拉取数据失败: %v", err)
+				return summary, nil
+			}
+
+			Logger(ctx).Printf("[DatasetSummaryTool] 返回响应: Symbol=%s, Dataset=%s, Count=%d, Pages=%d, Store=%s", summary.Symbol, summary.Dataset, summary.Count, summary.Pages, summary.Store)
+			return summary, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return tool, nil
+}
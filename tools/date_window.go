@@ -0,0 +1,41 @@
+package tools
+
+import (
+	"fmt"
+	"time"
+)
+
+// dateLayout 工具输入输出使用的日期格式
+const dateLayout = "2006-01-02"
+
+// resolveDateWindow 解析并校验查询的日期窗口
+// endDate 为空时使用 now 当天；startDate 为空且 defaultDays > 0 时使用 endDate 往前 defaultDays 天，
+// defaultDays <= 0 时返回空的开始日期，表示不限制开始日期
+func resolveDateWindow(startDate, endDate string, defaultDays int, now time.Time) (string, string, error) {
+	today := now.Format(dateLayout)
+
+	if endDate == "" {
+		endDate = today
+	}
+	end, err := time.Parse(dateLayout, endDate)
+	if err != nil {
+		return "", "", fmt.Errorf("结束日期格式错误: %q，应为 YYYY-MM-DD", endDate)
+	}
+	if endDate > today {
+		return "", "", fmt.Errorf("结束日期 %s 不能晚于今天 %s", endDate, today)
+	}
+
+	if startDate == "" {
+		if defaultDays <= 0 {
+			return "", endDate, nil
+		}
+		return end.AddDate(0, 0, -defaultDays).Format(dateLayout), endDate, nil
+	}
+	if _, err := time.Parse(dateLayout, startDate); err != nil {
+		return "", "", fmt.Errorf("开始日期格式错误: %q，应为 YYYY-MM-DD", startDate)
+	}
+	if startDate > endDate {
+		return "", "", fmt.Errorf("开始日期 %s 不能晚于结束日期 %s", startDate, endDate)
+	}
+	return startDate, endDate, nil
+}
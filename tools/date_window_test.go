@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResolveDateWindow(t *testing.T) {
+	now := time.Date(2025, 3, 15, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		start, end  string
+		defaultDays int
+		wantStart   string
+		wantEnd     string
+		wantErr     bool
+	}{
+		{name: "默认结束日期为今天且不限制开始日期", wantEnd: "2025-03-15"},
+		{name: "默认开始日期按天数回推", end: "2025-03-10", defaultDays: 30, wantStart: "2025-02-08", wantEnd: "2025-03-10"},
+		{name: "显式窗口原样返回", start: "2025-01-01", end: "2025-02-01", defaultDays: 30, wantStart: "2025-01-01", wantEnd: "2025-02-01"},
+		{name: "开始日期等于结束日期", start: "2025-03-15", wantStart: "2025-03-15", wantEnd: "2025-03-15"},
+		{name: "结束日期格式错误", end: "2025/03/01", wantErr: true},
+		{name: "开始日期格式错误", start: "20250101", wantErr: true},
+		{name: "结束日期晚于今天", end: "2025-03-16", wantErr: true},
+		{name: "开始日期晚于结束日期", start: "2025-03-02", end: "2025-03-01", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := resolveDateWindow(tt.start, tt.end, tt.defaultDays, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("期望返回错误，得到 (%q, %q)", start, end)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("意外的错误: %v", err)
+			}
+			if start != tt.wantStart || end != tt.wantEnd {
+				t.Errorf("得到 (%q, %q)，期望 (%q, %q)", start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
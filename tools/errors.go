@@ -0,0 +1,37 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// 数据源 API 的错误类型，API 层用 %w 包装后返回，工具和 Agent 编排可以用 errors.Is 区分处理
+var (
+	ErrRateLimited  = errors.New("请求被限流")
+	ErrNotFound     = errors.New("数据不存在")
+	ErrUnauthorized = errors.New("API 密钥无效或无权限")
+	ErrNoData       = errors.New("没有可用数据")
+	ErrUpstream     = errors.New("数据源服务错误")
+)
+
+// StatusError 根据 HTTP 状态码返回包装了对应错误类型的错误
+func StatusError(statusCode int, body []byte) error {
+	var kind error
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusPaymentRequired:
+		kind = ErrUnauthorized
+	case http.StatusNotFound:
+		kind = ErrNotFound
+	case http.StatusTooManyRequests:
+		kind = ErrRateLimited
+	default:
+		kind = ErrUpstream
+	}
+	return fmt.Errorf("%w (HTTP %d): %s", kind, statusCode, string(body))
+}
+
+// isFatalAPIError 判断错误是否需要中止整个分析：API 密钥无效时后续所有数据请求都会失败，继续分析没有意义
+func isFatalAPIError(err error) bool {
+	return errors.Is(err, ErrUnauthorized)
+}
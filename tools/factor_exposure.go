@@ -0,0 +1,283 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// 风格因子名称
+const (
+	FactorValue    = "value"
+	FactorGrowth   = "growth"
+	FactorQuality  = "quality"
+	FactorMomentum = "momentum"
+)
+
+// FactorNames 报告中因子的展示顺序
+var FactorNames = []string{FactorValue, FactorGrowth, FactorQuality, FactorMomentum}
+
+// factorLabels 因子的中文名称
+var factorLabels = map[string]string{
+	FactorValue:    "价值",
+	FactorGrowth:   "成长",
+	FactorQuality:  "质量",
+	FactorMomentum: "动量",
+}
+
+// factorTiltThreshold 组合因子得分超过该值（标准差单位）视为存在明显倾向
+const factorTiltThreshold = 0.25
+
+// momentumSkipDays 动量计算跳过最近的交易日数（约1个月），避免短期反转影响
+const momentumSkipDays = 21
+
+// FactorHolding 计算因子暴露所需的单只持仓数据，Metrics 或 Prices 可以为空
+type FactorHolding struct {
+	Symbol  string
+	Weight  float64
+	Metrics *FinancialMetrics
+	Prices  *PriceSeries
+}
+
+// HoldingFactorScores 单只持仓的因子代理指标和组合内标准化得分
+type HoldingFactorScores struct {
+	Symbol  string               `json:"symbol"`
+	Weight  float64              `json:"weight"`
+	Proxies map[string]SafeFloat `json:"proxies"` // 原始代理指标，如 earnings_yield、momentum_return
+	Scores  map[string]SafeFloat `json:"scores"`  // 各因子的组合内 z 分数，数据不足时为 NaN
+}
+
+// FactorTilt 组合在单个因子上的加权暴露
+type FactorTilt struct {
+	Factor   string    `json:"factor"`
+	Exposure SafeFloat `json:"exposure"` // 持仓得分按权重加权（有得分的持仓权重重新归一化）
+	Coverage float64   `json:"coverage"` // 有该因子得分的持仓权重之和
+	Tilt     string    `json:"tilt"`     // 偏向、中性或背离
+}
+
+// PortfolioFactorExposure 组合的风格因子暴露
+type PortfolioFactorExposure struct {
+	Holdings []HoldingFactorScores `json:"holdings"`
+	Tilts    []FactorTilt          `json:"tilts"`
+}
+
+// factorProxy 因子代理指标：从持仓数据中取值，higherIsBetter 为 false 时取负后参与打分
+type factorProxy struct {
+	key            string
+	factor         string
+	higherIsBetter bool
+	value          func(h FactorHolding) float64
+}
+
+// factorProxies 四个风格因子使用的代理指标
+var factorProxies = []factorProxy{
+	{"earnings_yield", FactorValue, true, func(h FactorHolding) float64 {
+		if h.Metrics == nil || h.Metrics.PriceToEarningsRatio <= 0 {
+			return math.NaN()
+		}
+		return 1 / h.Metrics.PriceToEarningsRatio
+	}},
+	{"book_to_price", FactorValue, true, func(h FactorHolding) float64 {
+		if h.Metrics == nil || h.Metrics.PriceToBookRatio <= 0 {
+			return math.NaN()
+		}
+		return 1 / h.Metrics.PriceToBookRatio
+	}},
+	{"revenue_growth", FactorGrowth, true, func(h FactorHolding) float64 {
+		if h.Metrics == nil {
+			return math.NaN()
+		}
+		return h.Metrics.RevenueGrowth
+	}},
+	{"earnings_per_share_growth", FactorGrowth, true, func(h FactorHolding) float64 {
+		if h.Metrics == nil {
+			return math.NaN()
+		}
+		return h.Metrics.EarningsPerShareGrowth
+	}},
+	{"return_on_equity", FactorQuality, true, func(h FactorHolding) float64 {
+		if h.Metrics == nil {
+			return math.NaN()
+		}
+		return floatOrNaN(h.Metrics.ReturnOnEquity)
+	}},
+	{"gross_margin", FactorQuality, true, func(h FactorHolding) float64 {
+		if h.Metrics == nil {
+			return math.NaN()
+		}
+		return h.Metrics.GrossMargin
+	}},
+	{"debt_to_equity", FactorQuality, false, func(h FactorHolding) float64 {
+		if h.Metrics == nil {
+			return math.NaN()
+		}
+		return floatOrNaN(h.Metrics.DebtToEquity)
+	}},
+	{"momentum_return", FactorMomentum, true, func(h FactorHolding) float64 {
+		return momentumReturn(h.Prices)
+	}},
+}
+
+// ComputeFactorExposure 计算组合的价值、成长、质量、动量因子暴露
+// 每个代理指标先在组合持仓之间标准化为 z 分数，因子得分为其代理指标 z 分数的平均值，
+// 组合暴露为持仓因子得分的加权平均，正值表示组合（按权重）比等权持有这些股票更偏向该因子
+func ComputeFactorExposure(holdings []FactorHolding) (*PortfolioFactorExposure, error) {
+	if len(holdings) < 2 {
+		return nil, fmt.Errorf("持仓不足2只，无法计算因子暴露")
+	}
+	weights := make([]float64, len(holdings))
+	for i, h := range holdings {
+		weights[i] = h.Weight
+	}
+	weights, err := normalizeWeights(weights, len(holdings))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PortfolioFactorExposure{Holdings: make([]HoldingFactorScores, len(holdings))}
+	for i, h := range holdings {
+		result.Holdings[i] = HoldingFactorScores{
+			Symbol:  h.Symbol,
+			Weight:  weights[i],
+			Proxies: make(map[string]SafeFloat),
+			Scores:  make(map[string]SafeFloat),
+		}
+	}
+
+	// 各因子累计的代理指标 z 分数之和与个数
+	sums := make([]map[string]float64, len(holdings))
+	counts := make([]map[string]int, len(holdings))
+	for i := range holdings {
+		sums[i] = make(map[string]float64)
+		counts[i] = make(map[string]int)
+	}
+	for _, proxy := range factorProxies {
+		values := make([]float64, len(holdings))
+		for i, h := range holdings {
+			values[i] = proxy.value(h)
+			result.Holdings[i].Proxies[proxy.key] = Sanitize(values[i])
+		}
+		for i, z := range zScores(values) {
+			if math.IsNaN(z) {
+				continue
+			}
+			if !proxy.higherIsBetter {
+				z = -z
+			}
+			sums[i][proxy.factor] += z
+			counts[i][proxy.factor]++
+		}
+	}
+
+	for _, factor := range FactorNames {
+		exposure, coverage := 0.0, 0.0
+		for i := range holdings {
+			score := math.NaN()
+			if counts[i][factor] > 0 {
+				score = sums[i][factor] / float64(counts[i][factor])
+				exposure += weights[i] * score
+				coverage += weights[i]
+			}
+			result.Holdings[i].Scores[factor] = SafeFloat(score)
+		}
+		tilt := FactorTilt{Factor: factor, Exposure: SafeDiv(exposure, coverage), Coverage: coverage}
+		switch {
+		case !tilt.Exposure.Valid():
+		case float64(tilt.Exposure) > factorTiltThreshold:
+			tilt.Tilt = "偏向"
+		case float64(tilt.Exposure) < -factorTiltThreshold:
+			tilt.Tilt = "背离"
+		default:
+			tilt.Tilt = "中性"
+		}
+		result.Tilts = append(result.Tilts, tilt)
+	}
+	return result, nil
+}
+
+// RenderFactorExposure 将因子暴露渲染为 markdown，用于组合报告
+func RenderFactorExposure(r *PortfolioFactorExposure) string {
+	var sb strings.Builder
+	sb.WriteString("## 风格因子暴露\n\n")
+	sb.WriteString("因子得分为代理指标在组合持仓之间的 z 分数均值：价值（盈利收益率、账面市值比）、成长（营收增速、EPS 增速）、")
+	sb.WriteString("质量（ROE、毛利率、负债权益比取反）、动量（跳过最近1个月的区间收益率）。")
+	sb.WriteString("组合暴露为正表示持仓权重向该因子得分高的股票集中。\n\n")
+
+	sb.WriteString("| 因子 | 组合暴露 | 倾向 | 数据覆盖权重 |\n|---|---|---|---|\n")
+	for _, tilt := range r.Tilts {
+		label := tilt.Tilt
+		if label == "" {
+			label = NotAvailable
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %.0f%% |\n", factorLabels[tilt.Factor], tilt.Exposure.Sprintf("%+.2f"), label, tilt.Coverage*100))
+	}
+
+	sb.WriteString("\n| 股票 | 权重 |")
+	for _, factor := range FactorNames {
+		sb.WriteString(" " + factorLabels[factor] + " |")
+	}
+	sb.WriteString(" 盈利收益率 | 营收增速 | ROE | 动量收益率 |\n|---|---|" + strings.Repeat("---|", len(FactorNames)+4) + "\n")
+	for _, h := range r.Holdings {
+		sb.WriteString(fmt.Sprintf("| %s | %.1f%% |", h.Symbol, h.Weight*100))
+		for _, factor := range FactorNames {
+			sb.WriteString(" " + h.Scores[factor].Sprintf("%+.2f") + " |")
+		}
+		for _, key := range []string{"earnings_yield", "revenue_growth", "return_on_equity", "momentum_return"} {
+			sb.WriteString(" " + (h.Proxies[key] * 100).Sprintf("%.1f%%") + " |")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// momentumReturn 计算从序列起点到最近 momentumSkipDays 个交易日之前的收益率，数据不足3个月时返回 NaN
+func momentumReturn(prices *PriceSeries) float64 {
+	if prices == nil || len(prices.Close) < 3*momentumSkipDays {
+		return math.NaN()
+	}
+	first := prices.Close[0]
+	last := prices.Close[len(prices.Close)-1-momentumSkipDays]
+	if first <= 0 {
+		return math.NaN()
+	}
+	return last/first - 1
+}
+
+// zScores 对有效值做标准化，无效值或有效值不足2个时对应位置为 NaN
+func zScores(values []float64) []float64 {
+	scores := make([]float64, len(values))
+	sum, n := 0.0, 0
+	for _, v := range values {
+		if IsFinite(v) {
+			sum += v
+			n++
+		}
+	}
+	mean := sum / float64(n)
+	variance := 0.0
+	for _, v := range values {
+		if IsFinite(v) {
+			variance += (v - mean) * (v - mean)
+		}
+	}
+	std := math.Sqrt(variance / float64(n))
+	for i, v := range values {
+		switch {
+		case n < 2 || !IsFinite(v):
+			scores[i] = math.NaN()
+		case std == 0:
+			scores[i] = 0 // 所有持仓取值相同，没有相对差异
+		default:
+			scores[i] = (v - mean) / std
+		}
+	}
+	return scores
+}
+
+// floatOrNaN 将可空指标转换为 float64，nil 时返回 NaN
+func floatOrNaN(v *float64) float64 {
+	if v == nil {
+		return math.NaN()
+	}
+	return *v
+}
@@ -0,0 +1,11 @@
+package tools
+
+// FilingSection SEC 文件（如 10-K）中的一个章节
+type FilingSection struct {
+	FilingType string `json:"filing_type"`
+	Year       int    `json:"year"`
+	Item       string `json:"item"`
+	Name       string `json:"name,omitempty"`
+	URL        string `json:"url"`
+	Text       string `json:"text"`
+}
@@ -2,15 +2,12 @@ package tools
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
-	"os"
-	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/cloudwego/eino/components/tool"
-	"github.com/cloudwego/eino/components/tool/utils"
 )
 
 // FinancialMetricsInput 财务指标查询的输入参数
@@ -28,19 +25,25 @@ type FinancialMetricsOutput struct {
 	Period  string             `json:"period"`
 	Metrics []FinancialMetrics `json:"metrics"`
 	Count   int                `json:"count"`
-	Error   string             `json:"error,omitempty"`
+	// CreditRiskRequired 最新一期债务股权比超过阈值，必须调用 assess_credit_risk
+	CreditRiskRequired bool `json:"credit_risk_required,omitempty"`
+	// Anomalies 关键指标在相邻报告期之间的不利突变，要求在报告中解释原因
+	Anomalies []MetricAnomaly `json:"anomalies,omitempty"`
+	Notice    string          `json:"notice,omitempty"`
+	Error     string          `json:"error,omitempty"`
 }
 
 // NewFinancialMetricsTool 创建新的财务指标查询工具
-func NewFinancialMetricsTool(getMetricsFunc func(symbol, date, period string, limit int) ([]FinancialMetrics, error)) (tool.BaseTool, error) {
-	tool, err := utils.InferTool("get_financial_metrics",
+// creditRiskDebtToEquity 为债务股权比阈值，最新一期超过该值时在结果中要求做信用风险评估
+func NewFinancialMetricsTool(getMetricsFunc func(ctx context.Context, symbol, date, period string, limit int) ([]FinancialMetrics, error), creditRiskDebtToEquity float64) (tool.BaseTool, error) {
+	tool, err := inferTool("get_financial_metrics",
 		"获取指定股票的财务指标数据，包括估值比率、盈利能力、营运效率、财务健康状况等关键指标。这些数据是进行基本面分析的核心。",
 		func(ctx context.Context, req *FinancialMetricsInput) (*FinancialMetricsOutput, error) {
-			log.Printf("[FinancialMetricsTool] 接收到请求: Symbol=%s, Date=%s, Period=%s, Limit=%d", req.Symbol, req.Date, req.Period, req.Limit)
+			Logger(ctx).Printf("[FinancialMetricsTool] 接收到请求: Symbol=%s, Date=%s, Period=%s, Limit=%d", req.Symbol, req.Date, req.Period, req.Limit)
 
 			// 验证必需参数
 			if req.Symbol == "" {
-				log.Printf("[FinancialMetricsTool] 错误: 股票代码为空")
+				Logger(ctx).Printf("[FinancialMetricsTool] 错误: 股票代码为空")
 				return &FinancialMetricsOutput{
 					Error: "股票代码不能为空",
 				}, nil
@@ -65,12 +68,15 @@ func NewFinancialMetricsTool(getMetricsFunc func(symbol, date, period string, li
 				limit = 10
 			}
 
-			log.Printf("[FinancialMetricsTool] 准备调用API: Symbol=%s, Date=%s, Period=%s, Limit=%d", req.Symbol, date, period, limit)
+			Logger(ctx).Printf("[FinancialMetricsTool] 准备调用API: Symbol=%s, Date=%s, Period=%s, Limit=%d", req.Symbol, date, period, limit)
 
 			// 调用API获取财务指标
-			metrics, err := getMetricsFunc(req.Symbol, date, period, limit)
+			metrics, err := getMetricsFunc(ctx, req.Symbol, date, period, limit)
 			if err != nil {
-				log.Printf("[FinancialMetricsTool] API调用失败: %v", err)
+				Logger(ctx).Printf("[FinancialMetricsTool] API调用失败: %v", err)
+				if isFatalAPIError(err) {
+					return nil, err
+				}
 				return &FinancialMetricsOutput{
 					Symbol: req.Symbol,
 					Date:   date,
@@ -79,7 +85,7 @@ func NewFinancialMetricsTool(getMetricsFunc func(symbol, date, period string, li
 				}, nil
 			}
 
-			log.Printf("[FinancialMetricsTool] API调用成功: 获取到 %d 条财务指标", len(metrics))
+			Logger(ctx).Printf("[FinancialMetricsTool] API调用成功: 获取到 %d 条财务指标", len(metrics))
 
 			result := &FinancialMetricsOutput{
 				Symbol:  req.Symbol,
@@ -88,14 +94,28 @@ func NewFinancialMetricsTool(getMetricsFunc func(symbol, date, period string, li
 				Metrics: metrics,
 				Count:   len(metrics),
 			}
+			if len(metrics) > 0 && CreditRiskRequired(metrics[0], creditRiskDebtToEquity) {
+				result.CreditRiskRequired = true
+				result.Notice = fmt.Sprintf("最新一期债务股权比为 %.2f，超过 %.2f，必须调用 assess_credit_risk 评估破产与信用风险，并在风险部分说明结论",
+					*metrics[0].DebtToEquity, creditRiskDebtToEquity)
+			}
+			// 利润率骤降、应收账款激增、杠杆骤升等突变需要 Agent 结合新闻和财报解释原因
+			if anomalies := DetectMetricAnomalies(metrics); len(anomalies) > 0 {
+				result.Anomalies = anomalies
+				notice := fmt.Sprintf("检测到 %d 处关键指标异常变动（见 anomalies），请结合新闻、年报和其他工具的数据解释原因，并在风险部分说明是否为一次性因素", len(anomalies))
+				if result.Notice != "" {
+					notice = result.Notice + "；" + notice
+				}
+				result.Notice = notice
+			}
 
 			// 保存财务指标到本地文件
 			if err := saveMetricsToFile(result); err != nil {
-				log.Printf("[FinancialMetricsTool] 保存文件失败: %v", err)
+				Logger(ctx).Printf("[FinancialMetricsTool] 保存文件失败: %v", err)
 				// 不返回错误，继续返回财务指标数据
 			}
 
-			log.Printf("[FinancialMetricsTool] 返回响应: Symbol=%s, Date=%s, Period=%s, Count=%d", result.Symbol, result.Date, result.Period, result.Count)
+			Logger(ctx).Printf("[FinancialMetricsTool] 返回响应: Symbol=%s, Date=%s, Period=%s, Count=%d", result.Symbol, result.Date, result.Period, result.Count)
 			return result, nil
 		})
 	if err != nil {
@@ -104,30 +124,120 @@ func NewFinancialMetricsTool(getMetricsFunc func(symbol, date, period string, li
 	return tool, nil
 }
 
-// saveMetricsToFile 将财务指标保存到本地文件
+// CreditRiskRequired 判断债务股权比是否超过阈值，需要做信用风险评估
+func CreditRiskRequired(m FinancialMetrics, debtToEquity float64) bool {
+	return m.DebtToEquity != nil && *m.DebtToEquity > debtToEquity
+}
+
+// saveMetricsToFile 将财务指标写入输出目标
 func saveMetricsToFile(metricsOutput *FinancialMetricsOutput) error {
-	// 创建metrics目录
-	dirPath := "output/metrics"
-	if err := os.MkdirAll(dirPath, 0755); err != nil {
-		return fmt.Errorf("创建目录失败: %v", err)
+	// 生成文件名：metrics/metrics_AAPL_ttm_2025-09-25_15-04-05.json
+	timeSuffix := time.Now().Format("2006-01-02_15-04-05")
+	name := fmt.Sprintf("metrics/metrics_%s_%s_%s.json", metricsOutput.Symbol, metricsOutput.Period, timeSuffix)
+	location, err := writeJSONArtifact(name, metricsOutput)
+	if err != nil {
+		return err
 	}
 
-	// 生成文件名：metrics_AAPL_ttm_2025-09-25.json
-	timeSuffix := time.Now().Format("2006-01-02_15-04-05")
-	fileName := fmt.Sprintf("metrics_%s_%s_%s.json", metricsOutput.Symbol, metricsOutput.Period, timeSuffix)
-	filePath := filepath.Join(dirPath, fileName)
+	log.Printf("[FinancialMetricsTool] 财务指标已保存到: %s", location)
+	return nil
+}
 
-	// 将财务指标数据转换为JSON
-	data, err := json.MarshalIndent(metricsOutput, "", "  ")
-	if err != nil {
-		return fmt.Errorf("JSON序列化失败: %v", err)
+// MetricsTablePeriods 报告中财务指标表最多展示的报告期数
+const MetricsTablePeriods = 5
+
+// metricsTableRow 财务指标表的一行
+type metricsTableRow struct {
+	label string
+	value func(m FinancialMetrics) string
+}
+
+// optionalPercent 将可能缺失的比率格式化为百分比
+func optionalPercent(v *float64) string {
+	if v == nil {
+		return NotAvailable
+	}
+	return Sanitize(*v * 100).Sprintf("%.1f%%")
+}
+
+// optionalRatio 将可能缺失的倍数格式化为两位小数
+func optionalRatio(v *float64) string {
+	if v == nil {
+		return NotAvailable
 	}
+	return Sanitize(*v).Sprintf("%.2f")
+}
 
-	// 写入文件
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
-		return fmt.Errorf("写入文件失败: %v", err)
+// nonZeroPercent 数据源用 0 表示缺失的比率，0 显示为 n/a
+func nonZeroPercent(v float64) string {
+	if v == 0 {
+		return NotAvailable
 	}
+	return Sanitize(v * 100).Sprintf("%.1f%%")
+}
 
-	log.Printf("[FinancialMetricsTool] 财务指标已保存到: %s", filePath)
-	return nil
+// nonZeroRatio 数据源用 0 表示缺失的倍数，0 显示为 n/a
+func nonZeroRatio(v float64) string {
+	if v == 0 {
+		return NotAvailable
+	}
+	return Sanitize(v).Sprintf("%.2f")
+}
+
+// RenderMetricsTable 将财务指标工具返回的多期数据渲染为 markdown 表格（最近 MetricsTablePeriods 期 × 关键指标），
+// 附加在报告中，不依赖模型是否在正文中引用了这些数据
+func RenderMetricsTable(output *FinancialMetricsOutput, format NumberFormat) string {
+	periods := output.Metrics
+	if len(periods) > MetricsTablePeriods {
+		periods = periods[:MetricsTablePeriods]
+	}
+	rows := []metricsTableRow{
+		{"ROE", func(m FinancialMetrics) string { return optionalPercent(m.ReturnOnEquity) }},
+		{"ROIC", func(m FinancialMetrics) string { return nonZeroPercent(m.ReturnOnInvestedCapital) }},
+		{"毛利率", func(m FinancialMetrics) string { return nonZeroPercent(m.GrossMargin) }},
+		{"营运利润率", func(m FinancialMetrics) string { return optionalPercent(m.OperatingMargin) }},
+		{"净利率", func(m FinancialMetrics) string { return optionalPercent(m.NetMargin) }},
+		{"营收增长率", func(m FinancialMetrics) string { return nonZeroPercent(m.RevenueGrowth) }},
+		{"EPS 增长率", func(m FinancialMetrics) string { return nonZeroPercent(m.EarningsPerShareGrowth) }},
+		{"D/E", func(m FinancialMetrics) string { return optionalRatio(m.DebtToEquity) }},
+		{"流动比率", func(m FinancialMetrics) string { return optionalRatio(m.CurrentRatio) }},
+		{"利息覆盖倍数", func(m FinancialMetrics) string { return optionalRatio(m.InterestCoverage) }},
+		{"P/E", func(m FinancialMetrics) string { return nonZeroRatio(m.PriceToEarningsRatio) }},
+		{"P/B", func(m FinancialMetrics) string { return nonZeroRatio(m.PriceToBookRatio) }},
+		{"自由现金流收益率", func(m FinancialMetrics) string { return nonZeroPercent(m.FreeCashFlowYield) }},
+		{"EPS", func(m FinancialMetrics) string {
+			if m.EarningsPerShare == 0 {
+				return NotAvailable
+			}
+			return format.Money(m.EarningsPerShare, m.Currency)
+		}},
+		{"市值", func(m FinancialMetrics) string {
+			if m.MarketCap == 0 {
+				return NotAvailable
+			}
+			return format.MoneyCompact(m.MarketCap, m.Currency)
+		}},
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("## 📋 财务指标数据表（%s，%s）\n\n", output.Symbol, output.Period))
+	sb.WriteString("| 指标 |")
+	for _, m := range periods {
+		sb.WriteString(fmt.Sprintf(" %s |", m.ReportPeriod))
+	}
+	sb.WriteString("\n|------|")
+	sb.WriteString(strings.Repeat("------|", len(periods)))
+	sb.WriteString("\n")
+	for _, row := range rows {
+		sb.WriteString(fmt.Sprintf("| %s |", row.label))
+		for _, m := range periods {
+			sb.WriteString(fmt.Sprintf(" %s |", row.value(m)))
+		}
+		sb.WriteString("\n")
+	}
+	if anomalies := RenderMetricAnomalies(output.Anomalies); anomalies != "" {
+		sb.WriteString("\n" + anomalies)
+	}
+	sb.WriteString("\n数据由财务指标工具返回的原始数据自动生成。\n")
+	return sb.String()
 }
@@ -2,65 +2,64 @@ package tools
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/cloudwego/eino/components/tool"
-	"github.com/cloudwego/eino/components/tool/utils"
 )
 
 // FinancialMetrics 结构体
 type FinancialMetrics struct {
-	Ticker                        string   `json:"ticker"`
-	ReportPeriod                  string   `json:"report_period"`
-	Period                        string   `json:"period"`
-	Currency                      string   `json:"currency"`
-	MarketCap                     float64  `json:"market_cap"`
-	EnterpriseValue               float64  `json:"enterprise_value"`
-	PriceToEarningsRatio          float64  `json:"price_to_earnings_ratio"`
-	PriceToBookRatio              float64  `json:"price_to_book_ratio"`
-	PriceToSalesRatio             float64  `json:"price_to_sales_ratio"`
-	EnterpriseValueToEbitdaRatio  float64  `json:"enterprise_value_to_ebitda_ratio"`
-	EnterpriseValueToRevenueRatio float64  `json:"enterprise_value_to_revenue_ratio"`
-	FreeCashFlowYield             float64  `json:"free_cash_flow_yield"`
-	PegRatio                      float64  `json:"peg_ratio"`
-	GrossMargin                   float64  `json:"gross_margin"`
-	OperatingMargin               *float64 `json:"operating_margin"`
-	NetMargin                     *float64 `json:"net_margin"`
-	ReturnOnEquity                *float64 `json:"return_on_equity"`
-	ReturnOnAssets                *float64 `json:"return_on_assets"`
-	ReturnOnInvestedCapital       float64  `json:"return_on_invested_capital"`
-	AssetTurnover                 float64  `json:"asset_turnover"`
-	InventoryTurnover             float64  `json:"inventory_turnover"`
-	ReceivablesTurnover           float64  `json:"receivables_turnover"`
-	DaysSalesOutstanding          float64  `json:"days_sales_outstanding"`
-	OperatingCycle                float64  `json:"operating_cycle"`
-	WorkingCapitalTurnover        float64  `json:"working_capital_turnover"`
-	CurrentRatio                  *float64 `json:"current_ratio"`
-	QuickRatio                    *float64 `json:"quick_ratio"`
-	CashRatio                     *float64 `json:"cash_ratio"`
-	OperatingCashFlowRatio        float64  `json:"operating_cash_flow_ratio"`
-	DebtToEquity                  *float64 `json:"debt_to_equity"`
-	DebtToAssets                  float64  `json:"debt_to_assets"`
-	InterestCoverage              *float64 `json:"interest_coverage"`
-	RevenueGrowth                 float64  `json:"revenue_growth"`
-	EarningsGrowth                float64  `json:"earnings_growth"`
-	BookValueGrowth               float64  `json:"book_value_growth"`
-	EarningsPerShareGrowth        float64  `json:"earnings_per_share_growth"`
-	FreeCashFlowGrowth            float64  `json:"free_cash_flow_growth"`
-	OperatingIncomeGrowth         float64  `json:"operating_income_growth"`
-	EbitdaGrowth                  float64  `json:"ebitda_growth"`
-	PayoutRatio                   float64  `json:"payout_ratio"`
-	EarningsPerShare              float64  `json:"earnings_per_share"`
-	BookValuePerShare             float64  `json:"book_value_per_share"`
-	FreeCashFlowPerShare          float64  `json:"free_cash_flow_per_share"`
+	Ticker                        string   `json:"ticker" parquet:"ticker"`
+	ReportPeriod                  string   `json:"report_period" parquet:"report_period"`
+	Period                        string   `json:"period" parquet:"period"`
+	Currency                      string   `json:"currency" parquet:"currency"`
+	MarketCap                     float64  `json:"market_cap" parquet:"market_cap"`
+	EnterpriseValue               float64  `json:"enterprise_value" parquet:"enterprise_value"`
+	PriceToEarningsRatio          float64  `json:"price_to_earnings_ratio" parquet:"price_to_earnings_ratio"`
+	PriceToBookRatio              float64  `json:"price_to_book_ratio" parquet:"price_to_book_ratio"`
+	PriceToSalesRatio             float64  `json:"price_to_sales_ratio" parquet:"price_to_sales_ratio"`
+	EnterpriseValueToEbitdaRatio  float64  `json:"enterprise_value_to_ebitda_ratio" parquet:"enterprise_value_to_ebitda_ratio"`
+	EnterpriseValueToRevenueRatio float64  `json:"enterprise_value_to_revenue_ratio" parquet:"enterprise_value_to_revenue_ratio"`
+	FreeCashFlowYield             float64  `json:"free_cash_flow_yield" parquet:"free_cash_flow_yield"`
+	PegRatio                      float64  `json:"peg_ratio" parquet:"peg_ratio"`
+	GrossMargin                   float64  `json:"gross_margin" parquet:"gross_margin"`
+	OperatingMargin               *float64 `json:"operating_margin" parquet:"operating_margin"`
+	NetMargin                     *float64 `json:"net_margin" parquet:"net_margin"`
+	ReturnOnEquity                *float64 `json:"return_on_equity" parquet:"return_on_equity"`
+	ReturnOnAssets                *float64 `json:"return_on_assets" parquet:"return_on_assets"`
+	ReturnOnInvestedCapital       float64  `json:"return_on_invested_capital" parquet:"return_on_invested_capital"`
+	AssetTurnover                 float64  `json:"asset_turnover" parquet:"asset_turnover"`
+	InventoryTurnover             float64  `json:"inventory_turnover" parquet:"inventory_turnover"`
+	ReceivablesTurnover           float64  `json:"receivables_turnover" parquet:"receivables_turnover"`
+	DaysSalesOutstanding          float64  `json:"days_sales_outstanding" parquet:"days_sales_outstanding"`
+	OperatingCycle                float64  `json:"operating_cycle" parquet:"operating_cycle"`
+	WorkingCapitalTurnover        float64  `json:"working_capital_turnover" parquet:"working_capital_turnover"`
+	CurrentRatio                  *float64 `json:"current_ratio" parquet:"current_ratio"`
+	QuickRatio                    *float64 `json:"quick_ratio" parquet:"quick_ratio"`
+	CashRatio                     *float64 `json:"cash_ratio" parquet:"cash_ratio"`
+	OperatingCashFlowRatio        float64  `json:"operating_cash_flow_ratio" parquet:"operating_cash_flow_ratio"`
+	DebtToEquity                  *float64 `json:"debt_to_equity" parquet:"debt_to_equity"`
+	DebtToAssets                  float64  `json:"debt_to_assets" parquet:"debt_to_assets"`
+	InterestCoverage              *float64 `json:"interest_coverage" parquet:"interest_coverage"`
+	RevenueGrowth                 float64  `json:"revenue_growth" parquet:"revenue_growth"`
+	EarningsGrowth                float64  `json:"earnings_growth" parquet:"earnings_growth"`
+	BookValueGrowth               float64  `json:"book_value_growth" parquet:"book_value_growth"`
+	EarningsPerShareGrowth        float64  `json:"earnings_per_share_growth" parquet:"earnings_per_share_growth"`
+	FreeCashFlowGrowth            float64  `json:"free_cash_flow_growth" parquet:"free_cash_flow_growth"`
+	OperatingIncomeGrowth         float64  `json:"operating_income_growth" parquet:"operating_income_growth"`
+	EbitdaGrowth                  float64  `json:"ebitda_growth" parquet:"ebitda_growth"`
+	PayoutRatio                   float64  `json:"payout_ratio" parquet:"payout_ratio"`
+	EarningsPerShare              float64  `json:"earnings_per_share" parquet:"earnings_per_share"`
+	BookValuePerShare             float64  `json:"book_value_per_share" parquet:"book_value_per_share"`
+	FreeCashFlowPerShare          float64  `json:"free_cash_flow_per_share" parquet:"free_cash_flow_per_share"`
 }
 
+// FundamentalMaxScore 基本面评分的满分：ROE、债务、营运利润率各 2 分，流动比率、P/E、P/B、ROE 稳定性各 1 分
+const FundamentalMaxScore = 10
+
 // FundamentalAnalysisRequest 基本面分析请求
 type FundamentalAnalysisRequest struct {
 	Metrics []FinancialMetrics `json:"metrics" jsonschema:"description=List of financial metrics for fundamental analysis"`
@@ -68,21 +67,25 @@ type FundamentalAnalysisRequest struct {
 
 // FundamentalAnalysisResponse 基本面分析响应
 type FundamentalAnalysisResponse struct {
-	Score   int            `json:"score" jsonschema:"description=Overall fundamental score based on Buffett's criteria"`
-	Details string         `json:"details" jsonschema:"description=Detailed reasoning for the analysis"`
-	Metrics map[string]any `json:"metrics,omitempty" jsonschema:"description=Latest financial metrics used in analysis"`
-	Error   string         `json:"error,omitempty" jsonschema:"description=Error message if analysis fails"`
+	Score     int                `json:"score" jsonschema:"description=Overall fundamental score based on Buffett's criteria"`
+	Details   string             `json:"details" jsonschema:"description=Detailed reasoning for the analysis"`
+	Metrics   map[string]any     `json:"metrics,omitempty" jsonschema:"description=Latest financial metrics used in analysis"`
+	Benchmark *IndustryBenchmark `json:"benchmark,omitempty" jsonschema:"description=Industry median metrics used as scoring thresholds"`
+	History   *ListingHistory    `json:"history,omitempty" jsonschema:"description=Listing age; trend-based checks are skipped when history is insufficient"`
+	Error     string             `json:"error,omitempty" jsonschema:"description=Error message if analysis fails"`
 }
 
 // NewFundamentalAnalysisTool 创建基本面分析工具
-func NewFundamentalAnalysisTool(ctx context.Context) (tool.BaseTool, error) {
-	return utils.InferTool("analyze_fundamentals",
-		"根据巴菲特的投资标准分析公司基本面，评估ROE、债务比率、营运利润率和流动比率等关键指标",
+// getBenchmarkFunc 用于获取行业基准中位数，为 nil 或获取失败时使用固定阈值评分
+// getListingDateFunc 用于获取上市日期（YYYY-MM-DD），上市不足3年时跳过趋势类检查，为 nil 或获取失败时不做调整
+func NewFundamentalAnalysisTool(ctx context.Context, getBenchmarkFunc func(ticker string) (*IndustryBenchmark, error), getListingDateFunc func(ctx context.Context, ticker string) (string, error)) (tool.BaseTool, error) {
+	return inferTool("analyze_fundamentals",
+		"根据巴菲特的投资标准分析公司基本面，评估ROE、债务比率、营运利润率和流动比率等关键指标，并与行业中位数进行对比",
 		func(ctx context.Context, req *FundamentalAnalysisRequest) (*FundamentalAnalysisResponse, error) {
-			log.Printf("[FundamentalAnalysisTool] 接收到请求: 财务指标数量=%d", len(req.Metrics))
+			Logger(ctx).Printf("[FundamentalAnalysisTool] 接收到请求: 财务指标数量=%d", len(req.Metrics))
 
 			if len(req.Metrics) == 0 {
-				log.Printf("[FundamentalAnalysisTool] 错误: 未提供财务指标数据")
+				Logger(ctx).Printf("[FundamentalAnalysisTool] 错误: 未提供财务指标数据")
 				return &FundamentalAnalysisResponse{
 					Score:   0,
 					Details: "基本面数据不足",
@@ -92,65 +95,124 @@ func NewFundamentalAnalysisTool(ctx context.Context) (tool.BaseTool, error) {
 
 			// 使用最新的财务指标进行分析
 			latestMetrics := req.Metrics[0]
-			log.Printf("[FundamentalAnalysisTool] 开始分析: Ticker=%s, ReportPeriod=%s", latestMetrics.Ticker, latestMetrics.ReportPeriod)
+			Logger(ctx).Printf("[FundamentalAnalysisTool] 开始分析: Ticker=%s, ReportPeriod=%s", latestMetrics.Ticker, latestMetrics.ReportPeriod)
+
+			// 获取行业基准，失败时退回固定阈值
+			var benchmark *IndustryBenchmark
+			if getBenchmarkFunc != nil {
+				b, err := getBenchmarkFunc(latestMetrics.Ticker)
+				if err != nil {
+					Logger(ctx).Printf("[FundamentalAnalysisTool] 获取行业基准失败，使用固定阈值: %v", err)
+				} else {
+					benchmark = b
+					Logger(ctx).Printf("[FundamentalAnalysisTool] 使用行业基准: Industry=%s, Level=%s, SampleSize=%d", b.Industry, b.Level, b.SampleSize)
+				}
+			}
+
+			// 获取上市年限，上市时间过短的公司不做趋势类判断
+			var history *ListingHistory
+			if getListingDateFunc != nil {
+				listingDate, err := getListingDateFunc(ctx, latestMetrics.Ticker)
+				if err == nil {
+					history, err = newListingHistory(listingDate, time.Now())
+				}
+				if err != nil {
+					Logger(ctx).Printf("[FundamentalAnalysisTool] 获取上市年限失败，不做调整: %v", err)
+				} else {
+					Logger(ctx).Printf("[FundamentalAnalysisTool] 上市日期: %s, 上市年限: %s", history.ListingDate, history.YearsListed.Sprintf("%.1f"))
+				}
+			}
 
 			score := 0
 			var reasoning []string
 
 			// 检查ROE (股本回报率)
-			if latestMetrics.ReturnOnEquity != nil && *latestMetrics.ReturnOnEquity > 0.15 {
+			roeThreshold := benchmarkThreshold(benchmark, "return_on_equity", 0.15)
+			roeNote := benchmarkNote(benchmark, "return_on_equity", true)
+			if latestMetrics.ReturnOnEquity != nil && *latestMetrics.ReturnOnEquity > roeThreshold {
 				score += 2
-				reasoning = append(reasoning, fmt.Sprintf("强劲的ROE为%.1f%%", *latestMetrics.ReturnOnEquity*100))
+				reasoning = append(reasoning, fmt.Sprintf("强劲的ROE为%.1f%%%s", *latestMetrics.ReturnOnEquity*100, roeNote))
 			} else if latestMetrics.ReturnOnEquity != nil {
-				reasoning = append(reasoning, fmt.Sprintf("ROE较弱为%.1f%%", *latestMetrics.ReturnOnEquity*100))
+				reasoning = append(reasoning, fmt.Sprintf("ROE较弱为%.1f%%%s", *latestMetrics.ReturnOnEquity*100, roeNote))
 			} else {
 				reasoning = append(reasoning, "ROE数据不可用")
 			}
 
 			// 检查债务股权比
-			if latestMetrics.DebtToEquity != nil && *latestMetrics.DebtToEquity < 0.5 {
+			debtThreshold := benchmarkThreshold(benchmark, "debt_to_equity", 0.5)
+			debtNote := benchmarkNote(benchmark, "debt_to_equity", false)
+			if latestMetrics.DebtToEquity != nil && *latestMetrics.DebtToEquity < debtThreshold {
 				score += 2
-				reasoning = append(reasoning, "保守的债务水平")
+				reasoning = append(reasoning, fmt.Sprintf("保守的债务水平，债务股权比为%.1f%s", *latestMetrics.DebtToEquity, debtNote))
 			} else if latestMetrics.DebtToEquity != nil {
-				reasoning = append(reasoning, fmt.Sprintf("较高的债务股权比为%.1f", *latestMetrics.DebtToEquity))
+				reasoning = append(reasoning, fmt.Sprintf("较高的债务股权比为%.1f%s", *latestMetrics.DebtToEquity, debtNote))
 			} else {
 				reasoning = append(reasoning, "债务股权比数据不可用")
 			}
 
 			// 检查营运利润率
-			if latestMetrics.OperatingMargin != nil && *latestMetrics.OperatingMargin > 0.15 {
+			marginThreshold := benchmarkThreshold(benchmark, "operating_margin", 0.15)
+			marginNote := benchmarkNote(benchmark, "operating_margin", true)
+			if latestMetrics.OperatingMargin != nil && *latestMetrics.OperatingMargin > marginThreshold {
 				score += 2
-				reasoning = append(reasoning, "强劲的营运利润率")
+				reasoning = append(reasoning, fmt.Sprintf("强劲的营运利润率为%.1f%%%s", *latestMetrics.OperatingMargin*100, marginNote))
 			} else if latestMetrics.OperatingMargin != nil {
-				reasoning = append(reasoning, fmt.Sprintf("营运利润率较弱为%.1f%%", *latestMetrics.OperatingMargin*100))
+				reasoning = append(reasoning, fmt.Sprintf("营运利润率较弱为%.1f%%%s", *latestMetrics.OperatingMargin*100, marginNote))
 			} else {
 				reasoning = append(reasoning, "营运利润率数据不可用")
 			}
 
 			// 检查流动比率
-			if latestMetrics.CurrentRatio != nil && *latestMetrics.CurrentRatio > 1.5 {
+			currentThreshold := benchmarkThreshold(benchmark, "current_ratio", 1.5)
+			currentNote := benchmarkNote(benchmark, "current_ratio", false)
+			if latestMetrics.CurrentRatio != nil && *latestMetrics.CurrentRatio > currentThreshold {
 				score += 1
-				reasoning = append(reasoning, "良好的流动性状况")
+				reasoning = append(reasoning, fmt.Sprintf("良好的流动性状况，流动比率为%.1f%s", *latestMetrics.CurrentRatio, currentNote))
 			} else if latestMetrics.CurrentRatio != nil {
-				reasoning = append(reasoning, fmt.Sprintf("流动性较弱，流动比率为%.1f", *latestMetrics.CurrentRatio))
+				reasoning = append(reasoning, fmt.Sprintf("流动性较弱，流动比率为%.1f%s", *latestMetrics.CurrentRatio, currentNote))
 			} else {
 				reasoning = append(reasoning, "流动比率数据不可用")
 			}
 
 			// 额外检查：价格收益比 (P/E)
-			if latestMetrics.PriceToEarningsRatio > 0 && latestMetrics.PriceToEarningsRatio < 25 {
+			peThreshold := benchmarkThreshold(benchmark, "price_to_earnings_ratio", 25)
+			peNote := benchmarkNote(benchmark, "price_to_earnings_ratio", false)
+			if latestMetrics.PriceToEarningsRatio > 0 && latestMetrics.PriceToEarningsRatio < peThreshold {
 				score += 1
-				reasoning = append(reasoning, fmt.Sprintf("合理的P/E比率为%.1f", latestMetrics.PriceToEarningsRatio))
+				reasoning = append(reasoning, fmt.Sprintf("合理的P/E比率为%.1f%s", latestMetrics.PriceToEarningsRatio, peNote))
 			} else if latestMetrics.PriceToEarningsRatio > 0 {
-				reasoning = append(reasoning, fmt.Sprintf("P/E比率较高为%.1f", latestMetrics.PriceToEarningsRatio))
+				reasoning = append(reasoning, fmt.Sprintf("P/E比率较高为%.1f%s", latestMetrics.PriceToEarningsRatio, peNote))
 			}
 
 			// 额外检查：价格净值比 (P/B)
-			if latestMetrics.PriceToBookRatio > 0 && latestMetrics.PriceToBookRatio < 3 {
+			pbThreshold := benchmarkThreshold(benchmark, "price_to_book_ratio", 3)
+			pbNote := benchmarkNote(benchmark, "price_to_book_ratio", false)
+			if latestMetrics.PriceToBookRatio > 0 && latestMetrics.PriceToBookRatio < pbThreshold {
 				score += 1
-				reasoning = append(reasoning, fmt.Sprintf("合理的P/B比率为%.1f", latestMetrics.PriceToBookRatio))
+				reasoning = append(reasoning, fmt.Sprintf("合理的P/B比率为%.1f%s", latestMetrics.PriceToBookRatio, pbNote))
 			} else if latestMetrics.PriceToBookRatio > 0 {
-				reasoning = append(reasoning, fmt.Sprintf("P/B比率较高为%.1f", latestMetrics.PriceToBookRatio))
+				reasoning = append(reasoning, fmt.Sprintf("P/B比率较高为%.1f%s", latestMetrics.PriceToBookRatio, pbNote))
+			}
+
+			// 趋势检查：ROE稳定性，需要足够的上市年限和至少3期数据
+			switch {
+			case history != nil && !history.Sufficient:
+				reasoning = append(reasoning, history.Note)
+			case len(req.Metrics) < minHistoryYears:
+				reasoning = append(reasoning, fmt.Sprintf("仅提供了%d期财务数据，跳过ROE稳定性检查", len(req.Metrics)))
+			default:
+				stable := 0
+				for _, m := range req.Metrics {
+					if m.ReturnOnEquity != nil && *m.ReturnOnEquity > roeThreshold {
+						stable++
+					}
+				}
+				if stable == len(req.Metrics) {
+					score += 1
+					reasoning = append(reasoning, fmt.Sprintf("ROE连续%d期高于%.1f%%，盈利能力稳定", stable, roeThreshold*100))
+				} else {
+					reasoning = append(reasoning, fmt.Sprintf("ROE在%d期中有%d期高于%.1f%%，稳定性一般", len(req.Metrics), stable, roeThreshold*100))
+				}
 			}
 
 			// 创建指标字典
@@ -167,46 +229,34 @@ func NewFundamentalAnalysisTool(ctx context.Context) (tool.BaseTool, error) {
 			}
 
 			result := &FundamentalAnalysisResponse{
-				Score:   score,
-				Details: strings.Join(reasoning, "; "),
-				Metrics: metricsMap,
+				Score:     score,
+				Details:   strings.Join(reasoning, "; "),
+				Metrics:   metricsMap,
+				Benchmark: benchmark,
+				History:   history,
 			}
 
 			// 保存分析结果到本地文件
 			if err := saveAnalysisToFile(result, latestMetrics.Ticker); err != nil {
-				log.Printf("[FundamentalAnalysisTool] 保存文件失败: %v", err)
+				Logger(ctx).Printf("[FundamentalAnalysisTool] 保存文件失败: %v", err)
 				// 不返回错误，继续返回分析结果
 			}
 
-			log.Printf("[FundamentalAnalysisTool] 分析完成: Score=%d, Ticker=%s, Details=%s", result.Score, latestMetrics.Ticker, result.Details)
+			Logger(ctx).Printf("[FundamentalAnalysisTool] 分析完成: Score=%d, Ticker=%s, Details=%s", result.Score, latestMetrics.Ticker, result.Details)
 			return result, nil
 		})
 }
 
-// saveAnalysisToFile 将基本面分析结果保存到本地文件
+// saveAnalysisToFile 将基本面分析结果写入输出目标
 func saveAnalysisToFile(analysisResult *FundamentalAnalysisResponse, ticker string) error {
-	// 创建analysis目录
-	dirPath := "output/analysis"
-	if err := os.MkdirAll(dirPath, 0755); err != nil {
-		return fmt.Errorf("创建目录失败: %v", err)
-	}
-
-	// 生成文件名：analysis_AAPL_2025-09-25.json
+	// 生成文件名：analysis/analysis_AAPL_2025-09-25_15-04-05.json
 	timeSuffix := time.Now().Format("2006-01-02_15-04-05")
-	fileName := fmt.Sprintf("analysis_%s_%s.json", ticker, timeSuffix)
-	filePath := filepath.Join(dirPath, fileName)
-
-	// 将分析结果转换为JSON
-	data, err := json.MarshalIndent(analysisResult, "", "  ")
+	name := fmt.Sprintf("analysis/analysis_%s_%s.json", ticker, timeSuffix)
+	location, err := writeJSONArtifact(name, analysisResult)
 	if err != nil {
-		return fmt.Errorf("JSON序列化失败: %v", err)
-	}
-
-	// 写入文件
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
-		return fmt.Errorf("写入文件失败: %v", err)
+		return err
 	}
 
-	log.Printf("[FundamentalAnalysisTool] 分析结果已保存到: %s", filePath)
+	log.Printf("[FundamentalAnalysisTool] 分析结果已保存到: %s", location)
 	return nil
 }
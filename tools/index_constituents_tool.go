@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// IndexConstituentsInput 指数成分股查询的输入参数
+type IndexConstituentsInput struct {
+	Index string `json:"index" description:"指数名称：sp500（标普500）、nasdaq100（纳斯达克100）或 csi300（沪深300）"`
+}
+
+// IndexConstituents 指数成分股
+type IndexConstituents struct {
+	Index     string   `json:"index"`
+	Name      string   `json:"name"`
+	Symbols   []string `json:"symbols"`
+	Count     int      `json:"count"`
+	UpdatedAt string   `json:"updated_at"`
+	Stale     bool     `json:"stale,omitempty"` // 本地缓存已较久未更新，成分股可能有变化
+	Error     string   `json:"error,omitempty"`
+}
+
+// NewIndexConstituentsTool 创建指数成分股查询工具
+func NewIndexConstituentsTool(getConstituentsFunc func(index string) (*IndexConstituents, error)) (tool.BaseTool, error) {
+	tool, err := inferTool("get_index_constituents",
+		"获取指数（标普500、纳斯达克100、沪深300）的成分股代码列表，可作为筛选可比公司或评估股票是否属于主要指数的股票池。",
+		func(ctx context.Context, req *IndexConstituentsInput) (*IndexConstituents, error) {
+			Logger(ctx).Printf("[IndexConstituentsTool] 接收到请求: Index=%s", req.Index)
+
+			index := strings.ToLower(strings.TrimSpace(req.Index))
+			if index == "" {
+				Logger(ctx).Printf("[IndexConstituentsTool] 错误: 指数名称为空")
+				return &IndexConstituents{Error: "指数名称不能为空"}, nil
+			}
+
+			result, err := getConstituentsFunc(index)
+			if err != nil {
+				Logger(ctx).Printf("[IndexConstituentsTool] 获取成分股失败: %v", err)
+				if isFatalAPIError(err) {
+					return nil, err
+				}
+				return &IndexConstituents{Index: index, Error: fmt.Sprintf("获取指数成分股失败: %v", err)}, nil
+			}
+
+			Logger(ctx).Printf("[IndexConstituentsTool] 返回响应: Index=%s, Count=%d, UpdatedAt=%s", result.Index, result.Count, result.UpdatedAt)
+			return result, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return tool, nil
+}
@@ -0,0 +1,93 @@
+package tools
+
+import "fmt"
+
+// BenchmarkMetricKeys 参与行业中位数统计和同行对比的指标（FinancialMetrics 的 json 字段名）
+var BenchmarkMetricKeys = []string{
+	"return_on_equity",
+	"debt_to_equity",
+	"operating_margin",
+	"net_margin",
+	"gross_margin",
+	"current_ratio",
+	"price_to_earnings_ratio",
+	"price_to_book_ratio",
+	"revenue_growth",
+}
+
+// IndustryBenchmark 行业基准统计，记录同行业（或同板块）公司关键指标的中位数
+type IndustryBenchmark struct {
+	Sector     string             `json:"sector"`
+	Industry   string             `json:"industry"`
+	Level      string             `json:"level"` // industry 或 sector，表示中位数的统计口径
+	SampleSize int                `json:"sample_size"`
+	Medians    map[string]float64 `json:"medians"`
+	UpdatedAt  string             `json:"updated_at"`
+}
+
+// Median 返回指定指标的行业中位数，指标名与 FinancialMetrics 的 json 字段名一致
+func (b *IndustryBenchmark) Median(key string) (float64, bool) {
+	if b == nil || b.Medians == nil {
+		return 0, false
+	}
+	value, ok := b.Medians[key]
+	return value, ok
+}
+
+// benchmarkThreshold 返回检查使用的阈值：有行业中位数时使用中位数，否则使用固定阈值
+func benchmarkThreshold(benchmark *IndustryBenchmark, key string, fallback float64) float64 {
+	if median, ok := benchmark.Median(key); ok {
+		return median
+	}
+	return fallback
+}
+
+// benchmarkNote 生成与行业中位数对比的说明文字，如 "（行业中位数11.0%）"
+func benchmarkNote(benchmark *IndustryBenchmark, key string, percent bool) string {
+	median, ok := benchmark.Median(key)
+	if !ok {
+		return ""
+	}
+	label := "行业中位数"
+	if benchmark.Level == "sector" {
+		label = "板块中位数"
+	}
+	if percent {
+		return fmt.Sprintf("（%s%.1f%%）", label, median*100)
+	}
+	return fmt.Sprintf("（%s%.1f）", label, median)
+}
+
+// BenchmarkMetricValues 提取参与统计的指标值，缺失或无意义的值不参与统计
+func BenchmarkMetricValues(m FinancialMetrics) map[string]float64 {
+	values := make(map[string]float64)
+	if m.ReturnOnEquity != nil {
+		values["return_on_equity"] = *m.ReturnOnEquity
+	}
+	if m.DebtToEquity != nil {
+		values["debt_to_equity"] = *m.DebtToEquity
+	}
+	if m.OperatingMargin != nil {
+		values["operating_margin"] = *m.OperatingMargin
+	}
+	if m.NetMargin != nil {
+		values["net_margin"] = *m.NetMargin
+	}
+	if m.GrossMargin != 0 {
+		values["gross_margin"] = m.GrossMargin
+	}
+	if m.CurrentRatio != nil {
+		values["current_ratio"] = *m.CurrentRatio
+	}
+	// 负的市盈率/市净率没有比较意义
+	if m.PriceToEarningsRatio > 0 {
+		values["price_to_earnings_ratio"] = m.PriceToEarningsRatio
+	}
+	if m.PriceToBookRatio > 0 {
+		values["price_to_book_ratio"] = m.PriceToBookRatio
+	}
+	if m.RevenueGrowth != 0 {
+		values["revenue_growth"] = m.RevenueGrowth
+	}
+	return values
+}
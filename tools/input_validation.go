@@ -0,0 +1,218 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// 参数校验失败的原因代码，返回给模型用于修正工具调用
+const (
+	ParamMissing       = "missing"        // 缺少必填参数
+	ParamInvalidType   = "invalid_type"   // 类型错误，如整数参数传了字符串或小数
+	ParamInvalidEnum   = "invalid_enum"   // 不在允许的取值列表中
+	ParamOutOfRange    = "out_of_range"   // 超出允许的数值范围
+	ParamInvalidFormat = "invalid_format" // 格式错误，如日期不是 YYYY-MM-DD
+)
+
+// paramRule 单个工具参数的校验规则；参数省略或为零值时使用工具默认值，不做范围和取值检查
+type paramRule struct {
+	name     string
+	required bool
+	enum     []string // 字符串参数允许的取值
+	foldCase bool     // 取值比较时忽略大小写
+	min, max int      // 整数参数的范围，max 为 0 时不是整数参数
+	date     bool     // 日期参数，格式为 YYYY-MM-DD
+}
+
+// toolParamRules 各工具参数的校验规则，与工具 Input 结构体中的 description 保持一致
+var toolParamRules = map[string][]paramRule{
+	"get_financial_metrics": {
+		{name: "period", enum: []string{"ttm", "annual", "quarterly"}},
+		{name: "limit", min: 1, max: 10},
+		{name: "date", date: true},
+	},
+	"get_company_news": {
+		{name: "limit", min: 1, max: 20},
+		{name: "start_date", date: true},
+		{name: "end_date", date: true},
+	},
+	"get_insider_trades": {
+		{name: "limit", min: 1, max: 200},
+		{name: "start_date", date: true},
+		{name: "end_date", date: true},
+	},
+	"summarize_dataset": {
+		{name: "dataset", required: true, enum: []string{DatasetNews, DatasetInsiderTrades}},
+		{name: "start_date", date: true},
+		{name: "end_date", date: true},
+	},
+	"summarize_news_timeline": {
+		{name: "months", min: NewsTimelineMinMonths, max: NewsTimelineMaxMonths},
+		{name: "end_date", date: true},
+	},
+	"get_index_constituents": {
+		{name: "index", required: true, enum: []string{"sp500", "nasdaq100", "csi300"}, foldCase: true},
+	},
+	"find_similar_companies":        {{name: "limit", min: 1, max: 20}},
+	"get_price_history_stats":       {{name: "years", min: 1, max: 20}},
+	"analyze_portfolio_correlation": {{name: "years", min: 1, max: 5}},
+	"track_legal_risks":             {{name: "lookback_years", min: 1, max: 5}},
+	"track_corporate_actions":       {{name: "lookback_years", min: 1, max: 5}},
+	"assess_credit_risk":            {{name: "years", min: 1, max: 10}},
+	"assess_management":             {{name: "years", min: 1, max: 10}},
+	"check_price_target":            {{name: "horizon_years", min: 1, max: 5}},
+	"monte_carlo_valuation": {
+		{name: "years", min: 1, max: 10},
+		{name: "simulations", min: 1, max: 100000},
+	},
+}
+
+// InvalidParam 一个不合法的工具参数
+type InvalidParam struct {
+	Param   string   `json:"param"`
+	Code    string   `json:"code"`
+	Value   any      `json:"value,omitempty"`
+	Allowed []string `json:"allowed,omitempty"`
+	Min     *int     `json:"min,omitempty"`
+	Max     *int     `json:"max,omitempty"`
+	Message string   `json:"message"`
+}
+
+// ParamValidationResult 参数校验失败时代替工具结果返回给模型的内容
+type ParamValidationResult struct {
+	Error         string         `json:"error"`
+	InvalidParams []InvalidParam `json:"invalid_params"`
+	Hint          string         `json:"hint"`
+}
+
+// ValidateToolParams 按规则校验工具调用参数，返回全部不合法的参数；没有规则的工具不做校验
+func ValidateToolParams(toolName, argumentsInJSON string) []InvalidParam {
+	rules, ok := toolParamRules[toolName]
+	if !ok {
+		return nil
+	}
+	var args map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(argumentsInJSON), &args); err != nil {
+		return []InvalidParam{{Param: "*", Code: ParamInvalidFormat, Message: fmt.Sprintf("参数不是合法的 JSON 对象: %v", err)}}
+	}
+
+	var invalid []InvalidParam
+	for _, rule := range rules {
+		raw, present := args[rule.name]
+		if !present || string(raw) == "null" {
+			if rule.required {
+				invalid = append(invalid, InvalidParam{Param: rule.name, Code: ParamMissing, Allowed: rule.enum,
+					Message: fmt.Sprintf("缺少必填参数 %s", rule.name)})
+			}
+			continue
+		}
+		if problem := rule.check(raw); problem != nil {
+			invalid = append(invalid, *problem)
+		}
+	}
+	return invalid
+}
+
+// check 校验单个参数的值，合法时返回 nil
+func (r paramRule) check(raw json.RawMessage) *InvalidParam {
+	if r.max > 0 {
+		var v float64
+		if err := json.Unmarshal(raw, &v); err != nil || v != math.Trunc(v) {
+			return &InvalidParam{Param: r.name, Code: ParamInvalidType, Value: string(raw),
+				Message: fmt.Sprintf("%s 必须是整数", r.name)}
+		}
+		if v == 0 {
+			return nil
+		}
+		if v < float64(r.min) || v > float64(r.max) {
+			return &InvalidParam{Param: r.name, Code: ParamOutOfRange, Value: v, Min: &r.min, Max: &r.max,
+				Message: fmt.Sprintf("%s 必须在 %d 到 %d 之间，实际为 %v", r.name, r.min, r.max, v)}
+		}
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return &InvalidParam{Param: r.name, Code: ParamInvalidType, Value: string(raw),
+			Message: fmt.Sprintf("%s 必须是字符串", r.name)}
+	}
+	if s == "" {
+		if r.required {
+			return &InvalidParam{Param: r.name, Code: ParamMissing, Allowed: r.enum,
+				Message: fmt.Sprintf("缺少必填参数 %s", r.name)}
+		}
+		return nil
+	}
+	switch {
+	case len(r.enum) > 0:
+		match := slices.Contains(r.enum, s)
+		if r.foldCase {
+			match = slices.ContainsFunc(r.enum, func(v string) bool { return strings.EqualFold(v, strings.TrimSpace(s)) })
+		}
+		if !match {
+			return &InvalidParam{Param: r.name, Code: ParamInvalidEnum, Value: s, Allowed: r.enum,
+				Message: fmt.Sprintf("%s 必须是 %s 之一，实际为 %q", r.name, strings.Join(r.enum, "/"), s)}
+		}
+	case r.date:
+		if _, err := time.Parse(dateLayout, s); err != nil {
+			return &InvalidParam{Param: r.name, Code: ParamInvalidFormat, Value: s,
+				Message: fmt.Sprintf("%s 必须是 YYYY-MM-DD 格式的日期，实际为 %q", r.name, s)}
+		}
+	}
+	return nil
+}
+
+// validatedTool 调用前校验参数的工具包装，参数不合法时不执行工具，直接返回机器可读的错误
+type validatedTool struct {
+	tool.InvokableTool
+	name string
+}
+
+// InvokableRun 校验参数后执行工具
+func (t *validatedTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	invalid := ValidateToolParams(t.name, argumentsInJSON)
+	if len(invalid) == 0 {
+		return t.InvokableTool.InvokableRun(ctx, argumentsInJSON, opts...)
+	}
+	messages := make([]string, len(invalid))
+	for i, p := range invalid {
+		messages[i] = p.Message
+	}
+	Logger(ctx).Printf("[ToolValidation] 工具 %s 参数不合法: %s", t.name, strings.Join(messages, "; "))
+	output, err := json.Marshal(ParamValidationResult{
+		Error:         "参数校验失败: " + strings.Join(messages, "; "),
+		InvalidParams: invalid,
+		Hint:          "请按 invalid_params 中的 allowed/min/max 修正参数后重新调用，或省略该参数使用默认值",
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(output), nil
+}
+
+// WithInputValidation 为有校验规则的工具加上参数校验，其他工具原样返回
+func WithInputValidation(tools []tool.BaseTool) []tool.BaseTool {
+	wrapped := make([]tool.BaseTool, len(tools))
+	for i, t := range tools {
+		wrapped[i] = t
+		invokable, ok := t.(tool.InvokableTool)
+		if !ok {
+			continue
+		}
+		info, err := t.Info(context.Background())
+		if err != nil {
+			continue
+		}
+		if _, ok := toolParamRules[info.Name]; ok {
+			wrapped[i] = &validatedTool{InvokableTool: invokable, name: info.Name}
+		}
+	}
+	return wrapped
+}
@@ -0,0 +1,183 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// InsiderTrade 内部人交易结构体
+type InsiderTrade struct {
+	Ticker                       string   `json:"ticker"`
+	Issuer                       *string  `json:"issuer"`
+	Name                         *string  `json:"name"`
+	Title                        *string  `json:"title"`
+	IsBoardDirector              *bool    `json:"is_board_director"`
+	TransactionDate              *string  `json:"transaction_date"`
+	TransactionShares            *float64 `json:"transaction_shares"`
+	TransactionPricePerShare     *float64 `json:"transaction_price_per_share"`
+	TransactionValue             *float64 `json:"transaction_value"`
+	SharesOwnedBeforeTransaction *float64 `json:"shares_owned_before_transaction"`
+	SharesOwnedAfterTransaction  *float64 `json:"shares_owned_after_transaction"`
+	SecurityTitle                *string  `json:"security_title"`
+	FilingDate                   string   `json:"filing_date"`
+}
+
+// InsiderTradesFunc 获取内部人交易，startDate 不为 nil 时按日期分页；truncated 不为空时说明数据因条数上限或时间窗口被截断
+type InsiderTradesFunc func(ctx context.Context, symbol, endDate string, startDate *string, limit int) (trades []InsiderTrade, truncated string, err error)
+
+// InsiderTradesInput 内部人交易查询的输入参数
+type InsiderTradesInput struct {
+	Symbol    string `json:"symbol" description:"股票代码，如 AAPL, TSLA, GOOG"`
+	StartDate string `json:"start_date,omitempty" description:"申报开始日期，格式为 YYYY-MM-DD，如果不提供则使用结束日期前90天"`
+	EndDate   string `json:"end_date,omitempty" description:"申报结束日期，格式为 YYYY-MM-DD，如果不提供则使用当前日期"`
+	Limit     int    `json:"limit,omitempty" description:"返回交易条数，默认为50条，最大200条"`
+}
+
+// InsiderTradesOutput 内部人交易查询的输出结果
+type InsiderTradesOutput struct {
+	Symbol    string         `json:"symbol"`
+	StartDate string         `json:"start_date"`
+	EndDate   string         `json:"end_date"`
+	Trades    []InsiderTrade `json:"trades"`
+	Count     int            `json:"count"`
+	BuyCount  int            `json:"buy_count"`
+	SellCount int            `json:"sell_count"`
+	NetShares float64        `json:"net_shares"`
+	NetValue  float64        `json:"net_value"`
+	Truncated bool           `json:"truncated,omitempty"`        // 窗口内的交易没有全部返回，汇总只基于返回的交易
+	Notice    string         `json:"truncated_notice,omitempty"` // 截断原因
+	Error     string         `json:"error,omitempty"`
+}
+
+// NewInsiderTradesTool 创建内部人交易查询工具
+func NewInsiderTradesTool(getTradesFunc InsiderTradesFunc) (tool.BaseTool, error) {
+	tool, err := inferTool("get_insider_trades",
+		"获取指定股票在日期窗口内的内部人（高管、董事）交易记录，并汇总买入/卖出笔数和净买卖股数，用于判断管理层对公司前景的信心。",
+		func(ctx context.Context, req *InsiderTradesInput) (*InsiderTradesOutput, error) {
+			Logger(ctx).Printf("[InsiderTradesTool] 接收到请求: Symbol=%s, StartDate=%s, EndDate=%s, Limit=%d", req.Symbol, req.StartDate, req.EndDate, req.Limit)
+
+			// 验证必需参数
+			if req.Symbol == "" {
+				Logger(ctx).Printf("[InsiderTradesTool] 错误: 股票代码为空")
+				return &InsiderTradesOutput{
+					Error: "股票代码不能为空",
+				}, nil
+			}
+
+			// 解析并校验日期窗口
+			startDate, endDate, err := resolveDateWindow(req.StartDate, req.EndDate, 90, time.Now())
+			if err != nil {
+				Logger(ctx).Printf("[InsiderTradesTool] 错误: %v", err)
+				return &InsiderTradesOutput{
+					Symbol: req.Symbol,
+					Error:  err.Error(),
+				}, nil
+			}
+
+			// 设置默认值
+			limit := req.Limit
+			if limit <= 0 {
+				limit = 50
+			}
+			if limit > 200 {
+				limit = 200
+			}
+
+			Logger(ctx).Printf("[InsiderTradesTool] 准备调用API: Symbol=%s, StartDate=%s, EndDate=%s, Limit=%d", req.Symbol, startDate, endDate, limit)
+
+			// 调用API获取内部人交易
+			trades, truncated, err := getTradesFunc(ctx, req.Symbol, endDate, &startDate, limit)
+			if err != nil {
+				Logger(ctx).Printf("[InsiderTradesTool] API调用失败: %v", err)
+				if isFatalAPIError(err) {
+					return nil, err
+				}
+				return &InsiderTradesOutput{
+					Symbol:    req.Symbol,
+					StartDate: startDate,
+					EndDate:   endDate,
+					Error:     fmt.Sprintf("获取内部人交易失败: %v", err),
+				}, nil
+			}
+
+			// 按开始日期分页时可能返回超过 limit 条交易，只保留最近申报的 limit 条
+			var notices []string
+			if truncated != "" {
+				notices = append(notices, truncated)
+			}
+			if len(trades) > limit {
+				notices = append(notices, fmt.Sprintf("窗口内共 %d 条交易，只返回最近申报的 %d 条", len(trades), limit))
+				trades = trades[:limit]
+			}
+
+			Logger(ctx).Printf("[InsiderTradesTool] API调用成功: 获取到 %d 条交易", len(trades))
+
+			result := &InsiderTradesOutput{
+				Symbol:    req.Symbol,
+				StartDate: startDate,
+				EndDate:   endDate,
+				Trades:    trades,
+				Count:     len(trades),
+				Truncated: len(notices) > 0,
+				Notice:    strings.Join(notices, "；"),
+			}
+			summarizeInsiderTrades(result)
+
+			// 保存内部人交易到本地文件
+			if err := saveInsiderTradesToFile(result); err != nil {
+				Logger(ctx).Printf("[InsiderTradesTool] 保存文件失败: %v", err)
+				// 不返回错误，继续返回交易数据
+			}
+
+			Logger(ctx).Printf("[InsiderTradesTool] 返回响应: Symbol=%s, Count=%d, Buy=%d, Sell=%d, NetShares=%.0f", result.Symbol, result.Count, result.BuyCount, result.SellCount, result.NetShares)
+			return result, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return tool, nil
+}
+
+// summarizeInsiderTrades 汇总买入/卖出笔数和净买卖股数、金额
+func summarizeInsiderTrades(output *InsiderTradesOutput) {
+	for _, trade := range output.Trades {
+		if trade.TransactionShares == nil {
+			continue
+		}
+		shares := *trade.TransactionShares
+		switch {
+		case shares > 0:
+			output.BuyCount++
+		case shares < 0:
+			output.SellCount++
+		}
+		output.NetShares += shares
+		if trade.TransactionValue != nil {
+			// 交易金额为正数，方向以股数为准
+			if shares < 0 {
+				output.NetValue -= *trade.TransactionValue
+			} else {
+				output.NetValue += *trade.TransactionValue
+			}
+		}
+	}
+}
+
+// saveInsiderTradesToFile 将内部人交易写入输出目标
+func saveInsiderTradesToFile(output *InsiderTradesOutput) error {
+	// 生成文件名：insider/insider_AAPL_2025-09-25_15-04-05.json
+	timeSuffix := time.Now().Format("2006-01-02_15-04-05")
+	name := fmt.Sprintf("insider/insider_%s_%s.json", output.Symbol, timeSuffix)
+	location, err := writeJSONArtifact(name, output)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[InsiderTradesTool] 内部人交易已保存到: %s", location)
+	return nil
+}
@@ -0,0 +1,328 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// 法律与监管风险类型
+const (
+	LegalRiskLitigation    = "litigation"
+	LegalRiskRegulatory    = "regulatory"
+	LegalRiskInvestigation = "investigation"
+)
+
+// investigationPattern 调查类事件的关键词规则
+var investigationPattern = regexp.MustCompile(`\b(investigat(ion|ions|ing|ed)|probe[sd]?|subpoena(s|ed)?|inquiry|inquiries|wells notice)\b|调查|立案`)
+
+// LegalRiskEntry 风险登记簿中的一条记录
+type LegalRiskEntry struct {
+	ID        string   `json:"id"`
+	Types     []string `json:"types"`
+	Source    string   `json:"source"` // news 或 filing
+	Title     string   `json:"title"`
+	Excerpt   string   `json:"excerpt,omitempty"`
+	URL       string   `json:"url,omitempty"`
+	Date      string   `json:"date"`
+	FirstSeen string   `json:"first_seen"`
+	LastSeen  string   `json:"last_seen"`
+}
+
+// LegalRiskRegister 单只股票的法律与监管风险登记簿，每次运行时增量更新
+type LegalRiskRegister struct {
+	Symbol    string           `json:"symbol"`
+	UpdatedAt string           `json:"updated_at"`
+	Entries   []LegalRiskEntry `json:"entries"`
+}
+
+// LegalRiskInput 法律与监管风险查询的输入参数
+type LegalRiskInput struct {
+	Symbol        string `json:"symbol" description:"股票代码，如 AAPL, TSLA, GOOG"`
+	LookbackYears int    `json:"lookback_years,omitempty" description:"回溯年数，默认为2年，最大5年"`
+}
+
+// LegalRiskOutput 法律与监管风险查询的输出结果
+type LegalRiskOutput struct {
+	Symbol     string           `json:"symbol"`
+	StartDate  string           `json:"start_date"`
+	EndDate    string           `json:"end_date"`
+	Entries    []LegalRiskEntry `json:"entries"`
+	NewCount   int              `json:"new_count"`
+	TypeCounts map[string]int   `json:"type_counts"`
+	Warnings   []string         `json:"warnings,omitempty"`
+	Error      string           `json:"error,omitempty"`
+}
+
+// NewLegalRiskTool 创建法律与监管风险跟踪工具
+// getNewsFunc 用于检索新闻，getFilingsFunc 用于获取年报中的法律诉讼章节，可为 nil
+func NewLegalRiskTool(
+	getNewsFunc func(ctx context.Context, symbol, date string, since *string, limit int) ([]CompanyNews, error),
+	getFilingsFunc func(ctx context.Context, symbol string, years []int) ([]FilingSection, error),
+) (tool.BaseTool, error) {
+	tool, err := inferTool("track_legal_risks",
+		"检索过去几年（默认2年）与公司相关的诉讼、监管处罚和调查事件（来源于新闻和年报法律诉讼章节），并维护该股票的风险登记簿，返回窗口内的全部风险事件以及本次新增的事件数量。",
+		func(ctx context.Context, req *LegalRiskInput) (*LegalRiskOutput, error) {
+			Logger(ctx).Printf("[LegalRiskTool] 接收到请求: Symbol=%s, LookbackYears=%d", req.Symbol, req.LookbackYears)
+
+			// 验证必需参数
+			if req.Symbol == "" {
+				Logger(ctx).Printf("[LegalRiskTool] 错误: 股票代码为空")
+				return &LegalRiskOutput{
+					Error: "股票代码不能为空",
+				}, nil
+			}
+
+			years := req.LookbackYears
+			if years <= 0 {
+				years = 2
+			}
+			if years > 5 {
+				years = 5
+			}
+
+			now := time.Now()
+			endDate := now.Format("2006-01-02")
+			startDate := now.AddDate(-years, 0, 0).Format("2006-01-02")
+
+			var found []LegalRiskEntry
+			var warnings []string
+
+			// 检索新闻中的诉讼/监管/调查事件
+			news, err := getNewsFunc(ctx, req.Symbol, endDate, &startDate, 1000)
+			if err != nil {
+				Logger(ctx).Printf("[LegalRiskTool] 获取新闻失败: %v", err)
+				warnings = append(warnings, fmt.Sprintf("获取新闻失败: %v", err))
+			}
+			CategorizeNews(news)
+			for _, item := range news {
+				if newsDate(item) < startDate {
+					continue
+				}
+				if types := legalRiskTypes(item.Topics, item.Title+" "+item.Summary); len(types) > 0 {
+					found = append(found, LegalRiskEntry{
+						ID:      "news:" + newsID(item),
+						Types:   types,
+						Source:  "news",
+						Title:   item.Title,
+						Excerpt: truncateText(item.Summary, 300),
+						URL:     item.URL,
+						Date:    newsDate(item),
+					})
+				}
+			}
+
+			// 检索年报中的法律诉讼章节
+			if getFilingsFunc != nil {
+				var filingYears []int
+				for y := now.Year() - years; y <= now.Year(); y++ {
+					filingYears = append(filingYears, y)
+				}
+				filings, err := getFilingsFunc(ctx, req.Symbol, filingYears)
+				if err != nil {
+					Logger(ctx).Printf("[LegalRiskTool] 获取年报法律诉讼章节失败: %v", err)
+					warnings = append(warnings, fmt.Sprintf("获取年报法律诉讼章节失败: %v", err))
+				}
+				for _, filing := range filings {
+					if strings.TrimSpace(filing.Text) == "" {
+						continue
+					}
+					types := legalRiskTypes(nil, filing.Text)
+					if len(types) == 0 {
+						types = []string{LegalRiskLitigation}
+					}
+					found = append(found, LegalRiskEntry{
+						ID:      fmt.Sprintf("filing:%s:%d", filing.FilingType, filing.Year),
+						Types:   types,
+						Source:  "filing",
+						Title:   fmt.Sprintf("%d 年 %s 法律诉讼章节", filing.Year, filing.FilingType),
+						Excerpt: truncateText(filing.Text, 1000),
+						URL:     filing.URL,
+						Date:    fmt.Sprintf("%d-12-31", filing.Year),
+					})
+				}
+			}
+
+			// 更新风险登记簿
+			register, err := loadLegalRiskRegister(req.Symbol)
+			if err != nil {
+				Logger(ctx).Printf("[LegalRiskTool] 读取风险登记簿失败，将重新创建: %v", err)
+				register = &LegalRiskRegister{Symbol: req.Symbol}
+			}
+			newCount := mergeLegalRiskEntries(register, found, now)
+			if err := saveLegalRiskRegister(register); err != nil {
+				Logger(ctx).Printf("[LegalRiskTool] 保存风险登记簿失败: %v", err)
+				// 不返回错误，继续返回风险数据
+			}
+
+			// 返回窗口内的全部事件（包括历史运行中记录的事件）
+			var entries []LegalRiskEntry
+			typeCounts := make(map[string]int)
+			for _, entry := range register.Entries {
+				if entry.Date < startDate {
+					continue
+				}
+				entries = append(entries, entry)
+				for _, t := range entry.Types {
+					typeCounts[t]++
+				}
+			}
+
+			result := &LegalRiskOutput{
+				Symbol:     req.Symbol,
+				StartDate:  startDate,
+				EndDate:    endDate,
+				Entries:    entries,
+				NewCount:   newCount,
+				TypeCounts: typeCounts,
+				Warnings:   warnings,
+			}
+
+			Logger(ctx).Printf("[LegalRiskTool] 返回响应: Symbol=%s, Entries=%d, NewCount=%d", result.Symbol, len(result.Entries), result.NewCount)
+			return result, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return tool, nil
+}
+
+// legalRiskTypes 根据新闻主题和文本判断风险类型
+func legalRiskTypes(topics []string, text string) []string {
+	var types []string
+	for _, topic := range topics {
+		if topic == NewsTopicLitigation || topic == NewsTopicRegulatory {
+			types = append(types, topic)
+		}
+	}
+	if topics == nil {
+		lower := strings.ToLower(text)
+		if newsTopicPatterns[NewsTopicLitigation].MatchString(lower) {
+			types = append(types, LegalRiskLitigation)
+		}
+		if newsTopicPatterns[NewsTopicRegulatory].MatchString(lower) {
+			types = append(types, LegalRiskRegulatory)
+		}
+	}
+	if investigationPattern.MatchString(strings.ToLower(text)) {
+		types = append(types, LegalRiskInvestigation)
+	}
+	return types
+}
+
+// mergeLegalRiskEntries 将本次发现的事件合并到登记簿，返回新增事件数量
+func mergeLegalRiskEntries(register *LegalRiskRegister, found []LegalRiskEntry, now time.Time) int {
+	today := now.Format("2006-01-02")
+	index := make(map[string]int)
+	for i, entry := range register.Entries {
+		index[entry.ID] = i
+	}
+
+	newCount := 0
+	for _, entry := range found {
+		if i, ok := index[entry.ID]; ok {
+			register.Entries[i].LastSeen = today
+			register.Entries[i].Types = entry.Types
+			continue
+		}
+		entry.FirstSeen = today
+		entry.LastSeen = today
+		index[entry.ID] = len(register.Entries)
+		register.Entries = append(register.Entries, entry)
+		newCount++
+	}
+
+	// 按事件日期倒序排列
+	sort.SliceStable(register.Entries, func(i, j int) bool {
+		return register.Entries[i].Date > register.Entries[j].Date
+	})
+	register.UpdatedAt = now.Format("2006-01-02 15:04:05")
+	return newCount
+}
+
+// newsID 生成新闻的唯一标识
+func newsID(item CompanyNews) string {
+	if item.ID != "" {
+		return item.ID
+	}
+	if item.URL != "" {
+		return item.URL
+	}
+	return item.Title
+}
+
+// newsDate 返回新闻日期部分（YYYY-MM-DD）
+func newsDate(item CompanyNews) string {
+	if len(item.DateTime) >= 10 {
+		return item.DateTime[:10]
+	}
+	return item.DateTime
+}
+
+// truncateText 截断过长的文本
+func truncateText(text string, limit int) string {
+	runes := []rune(strings.TrimSpace(text))
+	if len(runes) <= limit {
+		return string(runes)
+	}
+	return string(runes[:limit]) + "..."
+}
+
+// legalRiskRegisterPath 返回风险登记簿文件路径
+func legalRiskRegisterPath(symbol string) string {
+	return filepath.Join(OutputDir(), "risk", fmt.Sprintf("legal_risk_%s.json", strings.ToUpper(symbol)))
+}
+
+// loadLegalRiskRegister 读取风险登记簿，当前代码没有登记簿时沿用代码变更前旧代码的登记簿（见 SymbolAliases），都不存在时返回空登记簿
+func loadLegalRiskRegister(symbol string) (*LegalRiskRegister, error) {
+	for _, alias := range SymbolAliases(symbol) {
+		data, err := os.ReadFile(legalRiskRegisterPath(alias))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取文件失败: %v", err)
+		}
+		var register LegalRiskRegister
+		if err := json.Unmarshal(data, &register); err != nil {
+			return nil, fmt.Errorf("JSON解析失败: %v", err)
+		}
+		// 之后保存到当前代码的登记簿
+		register.Symbol = symbol
+		return &register, nil
+	}
+	return &LegalRiskRegister{Symbol: symbol}, nil
+}
+
+// saveLegalRiskRegister 将风险登记簿保存到本地文件
+func saveLegalRiskRegister(register *LegalRiskRegister) error {
+	// 创建risk目录
+	filePath := legalRiskRegisterPath(register.Symbol)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %v", err)
+	}
+
+	// 将登记簿转换为JSON
+	data, err := json.MarshalIndent(register, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSON序列化失败: %v", err)
+	}
+
+	// 写入文件
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+
+	log.Printf("[LegalRiskTool] 风险登记簿已保存到: %s", filePath)
+	return nil
+}
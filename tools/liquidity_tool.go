@@ -0,0 +1,402 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// 流动性结论
+const (
+	LiquidityLiquid   = "liquid"   // 成交充足，仓位建议不需要额外约束
+	LiquidityThin     = "thin"     // 成交偏少，较大仓位需要分批建仓和退出
+	LiquidityIlliquid = "illiquid" // 流动性不足，仓位建议必须受成交额约束
+)
+
+// 流动性判断阈值：日均成交额低于 LiquidityIlliquidADV 为流动性不足，低于 LiquidityThinADV 为成交偏少；
+// 市值低于 LiquiditySmallCap 为小盘股，估算买卖价差超过 LiquidityWideSpread 或自由流通比例低于 LiquidityLowFloat 时降一档
+const (
+	LiquidityIlliquidADV = 1e6
+	LiquidityThinADV     = 10e6
+	LiquiditySmallCap    = 2e9
+	LiquidityWideSpread  = 0.01
+	LiquidityLowFloat    = 0.25
+)
+
+// 仓位约束假设：每天成交不超过日均成交额的 LiquidityParticipation，LiquidityExitDays 个交易日内可以完成建仓或退出
+const (
+	LiquidityParticipation = 0.10
+	LiquidityExitDays      = 5
+)
+
+const (
+	liquidityLookbackDays = 90 // 拉取价格的日历天数，约 60 个交易日
+	liquidityShortWindow  = 20 // 短期日均成交额的交易日数
+	liquidityMinBars      = 20
+)
+
+// LiquidityBar 一个交易日的价格和成交量
+type LiquidityBar struct {
+	Date   string
+	High   float64
+	Low    float64
+	Close  float64
+	Volume float64
+}
+
+// LiquidityInput 流动性评估的输入参数
+type LiquidityInput struct {
+	Symbol        string  `json:"symbol" description:"股票代码，如 AAPL, TSLA, GOOG"`
+	PositionValue float64 `json:"position_value,omitempty" description:"拟建仓位的金额（与股价同币种），提供时计算按成交额约束完成建仓或退出需要的交易日数"`
+}
+
+// LiquidityOutput 流动性评估结果
+type LiquidityOutput struct {
+	Symbol    string `json:"symbol"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+	Days      int    `json:"trading_days"`
+
+	LastClose       SafeFloat `json:"last_close"`
+	AvgVolume20     SafeFloat `json:"avg_volume_20d"`        // 近 20 个交易日日均成交量（股）
+	AvgDollarVol20  SafeFloat `json:"avg_dollar_volume_20d"` // 近 20 个交易日日均成交额
+	AvgDollarVol    SafeFloat `json:"avg_dollar_volume"`     // 整个窗口日均成交额
+	MedianDollarVol SafeFloat `json:"median_dollar_volume"`  // 整个窗口成交额中位数，不受个别放量日影响
+	ZeroVolumeDays  int       `json:"zero_volume_days"`
+
+	QuotedSpread    SafeFloat `json:"quoted_spread"`    // 报价买卖价差，数据源不提供时为 n/a
+	EstimatedSpread SafeFloat `json:"estimated_spread"` // 用日内最高最低价估算的买卖价差（Corwin-Schultz）
+
+	MarketCap         SafeFloat `json:"market_cap"`
+	SmallCap          bool      `json:"small_cap"`
+	SharesOutstanding SafeFloat `json:"shares_outstanding"`
+	InsiderOwnership  SafeFloat `json:"insider_ownership"` // 内部人申报持股占总股本比例
+	InsiderHolders    int       `json:"insider_holders"`
+	FreeFloatShares   SafeFloat `json:"free_float_shares"`
+	FreeFloatRatio    SafeFloat `json:"free_float_ratio"`
+	DailyTurnover     SafeFloat `json:"daily_turnover"` // 日均成交量占自由流通股本的比例
+
+	MaxPositionValue SafeFloat `json:"max_position_value"` // 按参与率和退出天数可承受的最大仓位金额
+	PositionValue    float64   `json:"position_value,omitempty"`
+	DaysToExit       SafeFloat `json:"days_to_exit"` // 按参与率完成仓位建仓或退出需要的交易日数
+
+	Verdict string   `json:"verdict"`
+	Flags   []string `json:"flags"`
+	Notes   []string `json:"notes"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// NewLiquidityTool 创建成交量与流动性评估工具
+// getPricesFunc 返回日线价格和成交量，getFactsFunc 返回总股本和市值，getTradesFunc 获取内部人交易用于估算自由流通股本
+func NewLiquidityTool(getPricesFunc func(ctx context.Context, symbol, startDate, endDate string) ([]LiquidityBar, error), getFactsFunc func(ctx context.Context, symbol string) (sharesOutstanding, marketCap float64, err error), getTradesFunc InsiderTradesFunc) (tool.BaseTool, error) {
+	tool, err := inferTool("assess_liquidity",
+		fmt.Sprintf("评估股票的成交量和流动性：近 20 日和近 3 个月的日均成交额、估算买卖价差、自由流通股本和换手率，给出流动性结论（liquid/thin/illiquid），并按每天不超过日均成交额 %.0f%%、%d 个交易日内退出计算可承受的最大仓位。给出仓位建议前调用，结果会附加到报告末尾。", LiquidityParticipation*100, LiquidityExitDays),
+		func(ctx context.Context, req *LiquidityInput) (*LiquidityOutput, error) {
+			Logger(ctx).Printf("[LiquidityTool] 接收到请求: Symbol=%s, PositionValue=%.2f", req.Symbol, req.PositionValue)
+
+			// 验证必需参数
+			if req.Symbol == "" {
+				Logger(ctx).Printf("[LiquidityTool] 错误: 股票代码为空")
+				return &LiquidityOutput{
+					Error: "股票代码不能为空",
+				}, nil
+			}
+			symbol := strings.ToUpper(req.Symbol)
+			if req.PositionValue < 0 || !IsFinite(req.PositionValue) {
+				return &LiquidityOutput{
+					Symbol: symbol,
+					Error:  "仓位金额不能为负数",
+				}, nil
+			}
+
+			now := time.Now()
+			endDate := now.Format(dateLayout)
+			startDate := now.AddDate(0, 0, -liquidityLookbackDays).Format(dateLayout)
+			bars, err := getPricesFunc(ctx, symbol, startDate, endDate)
+			if err != nil {
+				Logger(ctx).Printf("[LiquidityTool] 获取价格失败: %v", err)
+				if isFatalAPIError(err) {
+					return nil, err
+				}
+				return &LiquidityOutput{
+					Symbol: symbol,
+					Error:  fmt.Sprintf("获取价格和成交量失败: %v", err),
+				}, nil
+			}
+			if len(bars) < liquidityMinBars {
+				return &LiquidityOutput{
+					Symbol: symbol,
+					Error:  fmt.Sprintf("最近 %d 天只有 %d 个交易日的价格数据，不足 %d 个，无法评估流动性", liquidityLookbackDays, len(bars), liquidityMinBars),
+				}, nil
+			}
+
+			// 股本和内部人持股缺失时仍可根据成交额给出结论
+			var shares, marketCap *float64
+			if s, m, err := getFactsFunc(ctx, symbol); err != nil {
+				if isFatalAPIError(err) {
+					return nil, err
+				}
+				Logger(ctx).Printf("[LiquidityTool] 获取股本和市值失败，不计算自由流通股本: %v", err)
+			} else {
+				if s > 0 {
+					shares = &s
+				}
+				if m > 0 {
+					marketCap = &m
+				}
+			}
+			var trades []InsiderTrade
+			var truncated string
+			if shares != nil {
+				since := now.AddDate(-managementLookbackYears, 0, 0).Format(dateLayout)
+				trades, truncated, err = getTradesFunc(ctx, symbol, endDate, &since, managementTradeLimit)
+				if err != nil {
+					if isFatalAPIError(err) {
+						return nil, err
+					}
+					Logger(ctx).Printf("[LiquidityTool] 获取内部人交易失败，不扣除内部人持股: %v", err)
+					trades = nil
+				}
+			}
+
+			result := EvaluateLiquidity(symbol, bars, shares, marketCap, trades, req.PositionValue)
+			if truncated != "" {
+				result.Notes = append(result.Notes, fmt.Sprintf("内部人交易不完整（%s），内部人持股可能被低估", truncated))
+			}
+			result.StartDate, result.EndDate = startDate, endDate
+			if err := saveLiquidityToFile(result); err != nil {
+				Logger(ctx).Printf("[LiquidityTool] 保存文件失败: %v", err)
+				// 不返回错误，继续返回分析结果
+			}
+
+			Logger(ctx).Printf("[LiquidityTool] 返回响应: Symbol=%s, 日均成交额=%s, 估算价差=%s, 结论=%s",
+				symbol, result.AvgDollarVol20.Sprintf("%.0f"), (result.EstimatedSpread * 100).Sprintf("%.2f%%"), result.Verdict)
+			return result, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return tool, nil
+}
+
+// EvaluateLiquidity 根据日线价格和成交量（按日期升序）、总股本、市值和内部人持股评估流动性
+// shares、marketCap 缺失时为 nil；trades 为空时不扣除内部人持股
+func EvaluateLiquidity(symbol string, bars []LiquidityBar, shares, marketCap *float64, trades []InsiderTrade, positionValue float64) *LiquidityOutput {
+	result := &LiquidityOutput{
+		Symbol:            symbol,
+		Days:              len(bars),
+		LastClose:         NaN(),
+		AvgVolume20:       NaN(),
+		AvgDollarVol20:    NaN(),
+		AvgDollarVol:      NaN(),
+		MedianDollarVol:   NaN(),
+		QuotedSpread:      NaN(),
+		EstimatedSpread:   NaN(),
+		MarketCap:         NaN(),
+		SharesOutstanding: NaN(),
+		InsiderOwnership:  NaN(),
+		FreeFloatShares:   NaN(),
+		FreeFloatRatio:    NaN(),
+		DailyTurnover:     NaN(),
+		MaxPositionValue:  NaN(),
+		PositionValue:     positionValue,
+		DaysToExit:        NaN(),
+		Flags:             []string{},
+		Notes:             []string{"数据源不提供报价买卖价差，价差为根据日内最高最低价估算的近似值"},
+	}
+	if len(bars) == 0 {
+		result.Verdict = LiquidityIlliquid
+		result.Flags = append(result.Flags, "没有成交数据")
+		return result
+	}
+
+	dollarVolumes := make([]float64, len(bars))
+	var totalDollar float64
+	for i, bar := range bars {
+		dollarVolumes[i] = bar.Close * bar.Volume
+		totalDollar += dollarVolumes[i]
+		if bar.Volume <= 0 {
+			result.ZeroVolumeDays++
+		}
+	}
+	recent := bars[max(0, len(bars)-liquidityShortWindow):]
+	var recentVolume, recentDollar float64
+	for i, bar := range recent {
+		recentVolume += bar.Volume
+		recentDollar += dollarVolumes[len(bars)-len(recent)+i]
+	}
+	last := bars[len(bars)-1].Close
+	result.LastClose = Sanitize(last)
+	result.AvgVolume20 = SafeDiv(recentVolume, float64(len(recent)))
+	result.AvgDollarVol20 = SafeDiv(recentDollar, float64(len(recent)))
+	result.AvgDollarVol = SafeDiv(totalDollar, float64(len(bars)))
+	result.MedianDollarVol = Sanitize(MedianOf(dollarVolumes))
+	result.EstimatedSpread = Sanitize(estimateSpread(bars))
+
+	if shares != nil {
+		result.SharesOutstanding = SafeFloat(*shares)
+	}
+	switch {
+	case marketCap != nil:
+		result.MarketCap = SafeFloat(*marketCap)
+	case shares != nil:
+		result.MarketCap = Sanitize(*shares * last)
+		result.Notes = append(result.Notes, "缺少市值数据，按总股本乘以最新收盘价估算")
+	}
+	result.SmallCap = result.MarketCap.Valid() && float64(result.MarketCap) < LiquiditySmallCap
+
+	// 自由流通股本 = 总股本 - 内部人申报持股
+	if shares != nil && len(trades) > 0 {
+		ownership, holders := insiderOwnership(trades, shares)
+		if ownership.Valid() {
+			ownership = SafeFloat(math.Min(math.Max(float64(ownership), 0), 1))
+			result.InsiderOwnership, result.InsiderHolders = ownership, holders
+			result.FreeFloatRatio = 1 - ownership
+			result.FreeFloatShares = Sanitize(*shares * float64(result.FreeFloatRatio))
+			result.Notes = append(result.Notes, fmt.Sprintf("自由流通股本按 %d 名内部人最近申报的持股扣除，未包含大股东和机构锁定的股份", holders))
+		}
+	} else if shares != nil {
+		result.FreeFloatShares = SafeFloat(*shares)
+		result.Notes = append(result.Notes, "没有内部人持股数据，自由流通股本按总股本计算，可能高估")
+	} else {
+		result.Notes = append(result.Notes, "缺少总股本数据，未计算自由流通股本和换手率")
+	}
+	if result.FreeFloatShares.Valid() && result.FreeFloatShares > 0 {
+		result.DailyTurnover = SafeDiv(float64(result.AvgVolume20), float64(result.FreeFloatShares))
+	}
+
+	// 按近 20 日均值和整个窗口中位数中较低的一个判断，避免个别放量日高估流动性
+	liquidity := math.Min(float64(result.AvgDollarVol20), float64(result.MedianDollarVol))
+	if !IsFinite(liquidity) {
+		liquidity = float64(result.AvgDollarVol20)
+	}
+	result.MaxPositionValue = Sanitize(liquidity * LiquidityParticipation * LiquidityExitDays)
+	if positionValue > 0 {
+		result.DaysToExit = SafeDiv(positionValue, liquidity*LiquidityParticipation)
+	}
+
+	format := NumberFormat{Locale: DefaultLocale}
+	level := 0
+	switch {
+	case liquidity < LiquidityIlliquidADV:
+		level = 2
+		result.Flags = append(result.Flags, fmt.Sprintf("日均成交额约 %s，低于 %s", format.MoneyCompact(liquidity, ""), format.MoneyCompact(LiquidityIlliquidADV, "")))
+	case liquidity < LiquidityThinADV:
+		level = 1
+		result.Flags = append(result.Flags, fmt.Sprintf("日均成交额约 %s，低于 %s", format.MoneyCompact(liquidity, ""), format.MoneyCompact(LiquidityThinADV, "")))
+	}
+	if result.EstimatedSpread.Valid() && float64(result.EstimatedSpread) > LiquidityWideSpread {
+		level++
+		result.Flags = append(result.Flags, fmt.Sprintf("估算买卖价差 %s，超过 %.0f%%", (result.EstimatedSpread*100).Sprintf("%.2f%%"), LiquidityWideSpread*100))
+	}
+	if result.FreeFloatRatio.Valid() && float64(result.FreeFloatRatio) < LiquidityLowFloat {
+		level++
+		result.Flags = append(result.Flags, fmt.Sprintf("自由流通比例 %s，低于 %.0f%%", (result.FreeFloatRatio*100).Sprintf("%.1f%%"), LiquidityLowFloat*100))
+	}
+	if result.ZeroVolumeDays > 0 {
+		result.Flags = append(result.Flags, fmt.Sprintf("%d 个交易日没有成交", result.ZeroVolumeDays))
+	}
+	if result.SmallCap && level > 0 {
+		result.Flags = append(result.Flags, fmt.Sprintf("小盘股（市值 %s，低于 %s）且成交不活跃，仓位建议需受成交额约束",
+			format.MoneyCompact(float64(result.MarketCap), ""), format.MoneyCompact(LiquiditySmallCap, "")))
+	}
+
+	switch {
+	case level == 0 && result.ZeroVolumeDays == 0:
+		result.Verdict = LiquidityLiquid
+	case level <= 1:
+		result.Verdict = LiquidityThin
+	default:
+		result.Verdict = LiquidityIlliquid
+	}
+	return result
+}
+
+// estimateSpread 用相邻两个交易日的最高最低价估算买卖价差（Corwin-Schultz），负估计按 0 计，返回各估计的平均值
+func estimateSpread(bars []LiquidityBar) float64 {
+	k := 3 - 2*math.Sqrt2
+	var total float64
+	var n int
+	for i := 1; i < len(bars); i++ {
+		prev, cur := bars[i-1], bars[i]
+		if prev.Low <= 0 || cur.Low <= 0 || prev.High < prev.Low || cur.High < cur.Low {
+			continue
+		}
+		beta := math.Pow(math.Log(prev.High/prev.Low), 2) + math.Pow(math.Log(cur.High/cur.Low), 2)
+		gamma := math.Pow(math.Log(math.Max(prev.High, cur.High)/math.Min(prev.Low, cur.Low)), 2)
+		alpha := (math.Sqrt(2*beta)-math.Sqrt(beta))/k - math.Sqrt(gamma/k)
+		spread := 2 * (math.Exp(alpha) - 1) / (1 + math.Exp(alpha))
+		if !IsFinite(spread) {
+			continue
+		}
+		total += math.Max(spread, 0)
+		n++
+	}
+	if n == 0 {
+		return math.NaN()
+	}
+	return total / float64(n)
+}
+
+// liquidityVerdictText 流动性结论的中文描述
+var liquidityVerdictText = map[string]string{
+	LiquidityLiquid:   "✅ 流动性充足",
+	LiquidityThin:     "⚠️ 成交偏少：较大仓位需要分批建仓和退出",
+	LiquidityIlliquid: "❌ 流动性不足：仓位建议必须受成交额约束",
+}
+
+// RenderLiquidityAssessment 生成附加到报告末尾的流动性评估章节
+func RenderLiquidityAssessment(result *LiquidityOutput, format NumberFormat) string {
+	var sb strings.Builder
+	sb.WriteString("## 💧 流动性评估\n\n")
+	sb.WriteString(fmt.Sprintf("- 结论: %s\n", liquidityVerdictText[result.Verdict]))
+	sb.WriteString(fmt.Sprintf("- 统计区间: %s（%d 个交易日）\n\n", dateWindow(result.StartDate, result.EndDate), result.Days))
+	sb.WriteString("| 指标 | 数值 |\n")
+	sb.WriteString("|------|------|\n")
+	sb.WriteString(fmt.Sprintf("| 近 20 日日均成交额 | %s |\n", format.MoneyCompact(float64(result.AvgDollarVol20), "")))
+	sb.WriteString(fmt.Sprintf("| 区间成交额中位数 | %s |\n", format.MoneyCompact(float64(result.MedianDollarVol), "")))
+	sb.WriteString(fmt.Sprintf("| 近 20 日日均成交量（股） | %s |\n", format.Compact(float64(result.AvgVolume20))))
+	sb.WriteString(fmt.Sprintf("| 估算买卖价差 | %s |\n", (result.EstimatedSpread * 100).Sprintf("%.2f%%")))
+	sb.WriteString(fmt.Sprintf("| 市值 | %s |\n", format.MoneyCompact(float64(result.MarketCap), "")))
+	sb.WriteString(fmt.Sprintf("| 自由流通比例 | %s |\n", (result.FreeFloatRatio * 100).Sprintf("%.1f%%")))
+	sb.WriteString(fmt.Sprintf("| 日均换手率（自由流通） | %s |\n", (result.DailyTurnover * 100).Sprintf("%.2f%%")))
+	sb.WriteString(fmt.Sprintf("| 可承受的最大仓位（每天 ≤ %.0f%% 成交额，%d 日内退出） | %s |\n",
+		LiquidityParticipation*100, LiquidityExitDays, format.MoneyCompact(float64(result.MaxPositionValue), "")))
+	if result.PositionValue > 0 {
+		sb.WriteString(fmt.Sprintf("| 建仓或退出 %s 需要的交易日 | %s |\n",
+			format.MoneyCompact(result.PositionValue, ""), result.DaysToExit.Sprintf("%.1f")))
+	}
+	if len(result.Flags) > 0 {
+		sb.WriteString("\n")
+		for _, flag := range result.Flags {
+			sb.WriteString(fmt.Sprintf("- %s\n", flag))
+		}
+	}
+	if result.Verdict != LiquidityLiquid {
+		sb.WriteString(fmt.Sprintf("\n> 说明: 该股票成交不活跃，报告中的仓位建议需考虑流动性约束：单只股票仓位不宜超过约 %s，并分批建仓和退出。\n",
+			format.MoneyCompact(float64(result.MaxPositionValue), "")))
+	}
+	for _, note := range result.Notes {
+		sb.WriteString(fmt.Sprintf("\n> 说明: %s\n", note))
+	}
+	return sb.String()
+}
+
+// saveLiquidityToFile 将流动性评估结果写入输出目标
+func saveLiquidityToFile(output *LiquidityOutput) error {
+	// 生成文件名：liquidity/liquidity_AAPL_2025-09-25_15-04-05.json
+	timeSuffix := time.Now().Format("2006-01-02_15-04-05")
+	name := fmt.Sprintf("liquidity/liquidity_%s_%s.json", output.Symbol, timeSuffix)
+	location, err := writeJSONArtifact(name, output)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[LiquidityTool] 分析结果已保存到: %s", location)
+	return nil
+}
@@ -0,0 +1,35 @@
+package tools
+
+import (
+	"fmt"
+	"time"
+)
+
+// minHistoryYears 趋势类判断（增长、稳定性）需要的最短上市年限
+const minHistoryYears = 3
+
+// ListingHistory 公司上市年限，上市时间过短时增长和稳定性判断会产生误导
+type ListingHistory struct {
+	ListingDate string    `json:"listing_date"`
+	YearsListed SafeFloat `json:"years_listed"`
+	Sufficient  bool      `json:"sufficient"` // 上市年限是否足以做趋势类判断
+	Note        string    `json:"note,omitempty"`
+}
+
+// newListingHistory 根据上市日期（YYYY-MM-DD）计算上市年限
+func newListingHistory(listingDate string, now time.Time) (*ListingHistory, error) {
+	listed, err := time.Parse("2006-01-02", listingDate)
+	if err != nil {
+		return nil, fmt.Errorf("无效的上市日期 %q: %v", listingDate, err)
+	}
+	years := now.Sub(listed).Hours() / 24 / 365.25
+	history := &ListingHistory{
+		ListingDate: listingDate,
+		YearsListed: Sanitize(years),
+		Sufficient:  years >= minHistoryYears,
+	}
+	if !history.Sufficient {
+		history.Note = fmt.Sprintf("公司于 %s 上市，仅有 %.1f 年历史（不足 %d 年），增长和稳定性等趋势类判断参考价值有限，已跳过相关检查", listingDate, years, minHistoryYears)
+	}
+	return history, nil
+}
@@ -0,0 +1,32 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// loggerKey context 中保存运行日志器的键
+type loggerKey struct{}
+
+// NewRunLogger 创建一次分析运行的日志器，每行日志带上运行 ID 和股票代码，
+// 批量或服务模式下并发分析的日志可以按运行分开；输出目标和格式与标准 log 包相同
+func NewRunLogger(runID, symbol string) *log.Logger {
+	return log.New(log.Writer(), fmt.Sprintf("[run=%s symbol=%s] ", runID, symbol), log.Flags()|log.Lmsgprefix)
+}
+
+// WithLogger 返回带有运行日志器的 context，传给工具和数据获取函数
+func WithLogger(ctx context.Context, logger *log.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// Logger 返回 context 中的运行日志器，没有时返回标准 log 包的全局日志器
+// 新代码在有 context 的地方使用 Logger(ctx).Printf 代替 log.Printf
+func Logger(ctx context.Context) *log.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(loggerKey{}).(*log.Logger); ok && logger != nil {
+			return logger
+		}
+	}
+	return log.Default()
+}
@@ -0,0 +1,402 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// ManagementMaxScore 管理层质量评分的满分：内部人持股、股权激励占比、股本变化、资本配置各 2 分，
+// 内部人净买卖、管理层稳定性各 1 分
+const ManagementMaxScore = 10
+
+// 管理层质量分析的默认参数
+const (
+	defaultManagementYears  = 5 // 资本配置历史的年数
+	managementLookbackYears = 2 // 内部人持股和管理层变动的回溯年数
+	managementTradeLimit    = 1000
+	managementNewsLimit     = 500
+)
+
+// managementDeparturePattern 管理层离任类新闻（辞职、卸任、被解雇、临时接任等）
+var managementDeparturePattern = regexp.MustCompile(`(?i)\b(resign(s|ed|ation)?|steps? down|stepping down|depart(s|ed|ure)?|ousted|fired|retire(s|d|ment)?|interim (ceo|cfo)|succession)\b|辞职|离任|卸任|退休|临时`)
+
+// CapitalAllocationPeriod 一个年度的资本配置数据，缺失的项目为 nil
+// Buybacks、Dividends、Acquisitions 为现金流出金额（正数）
+type CapitalAllocationPeriod struct {
+	ReportPeriod           string   `json:"report_period"`
+	Revenue                *float64 `json:"revenue,omitempty"`
+	FreeCashFlow           *float64 `json:"free_cash_flow,omitempty"`
+	ShareBasedCompensation *float64 `json:"share_based_compensation,omitempty"`
+	Buybacks               *float64 `json:"buybacks,omitempty"`
+	Dividends              *float64 `json:"dividends,omitempty"`
+	Acquisitions           *float64 `json:"acquisitions,omitempty"`
+	SharesOutstanding      *float64 `json:"shares_outstanding,omitempty"`
+}
+
+// ManagementCheck 一项管理层质量检查
+type ManagementCheck struct {
+	Name      string `json:"name"`
+	Points    int    `json:"points"`
+	MaxPoints int    `json:"max_points"`
+	Detail    string `json:"detail"`
+}
+
+// ManagementEvent 管理层变动相关新闻
+type ManagementEvent struct {
+	Date  string `json:"date"`
+	Title string `json:"title"`
+	URL   string `json:"url,omitempty"`
+}
+
+// ManagementQualityInput 管理层质量评估的输入参数
+type ManagementQualityInput struct {
+	Symbol string `json:"symbol" description:"股票代码，如 AAPL, TSLA, GOOG"`
+	Years  int    `json:"years,omitempty" description:"资本配置历史的年数，默认5年"`
+}
+
+// ManagementQualityOutput 管理层质量评估的输出结果
+type ManagementQualityOutput struct {
+	Symbol            string                    `json:"symbol"`
+	Score             int                       `json:"score"`
+	MaxScore          int                       `json:"max_score"`
+	Checks            []ManagementCheck         `json:"checks"`
+	InsiderOwnership  SafeFloat                 `json:"insider_ownership"` // 申报过交易的内部人最新持股占总股本的比例
+	InsiderHolders    int                       `json:"insider_holders"`
+	NetInsiderShares  float64                   `json:"net_insider_shares_1y"` // 最近一年内部人净买卖股数
+	CapitalAllocation []CapitalAllocationPeriod `json:"capital_allocation"`
+	ManagementEvents  []ManagementEvent         `json:"management_events"`
+	DataGaps          []string                  `json:"data_gaps,omitempty"`
+	Error             string                    `json:"error,omitempty"`
+}
+
+// NewManagementQualityTool 创建管理层质量评估工具
+// getCapitalAllocationFunc 返回最近 years 个年度的资本配置数据，最新的在前
+func NewManagementQualityTool(
+	getCapitalAllocationFunc func(ctx context.Context, symbol string, years int) ([]CapitalAllocationPeriod, error),
+	getTradesFunc InsiderTradesFunc,
+	getNewsFunc func(ctx context.Context, symbol, date string, since *string, limit int) ([]CompanyNews, error),
+) (tool.BaseTool, error) {
+	tool, err := inferTool("assess_management",
+		fmt.Sprintf("评估管理层质量：综合内部人持股和买卖、股权激励占收入比例（薪酬代理指标）、股本变化、回购/分红/并购等资本配置历史以及过去%d年的高管变动，给出管理层质量评分（满分 %d）。", managementLookbackYears, ManagementMaxScore),
+		func(ctx context.Context, req *ManagementQualityInput) (*ManagementQualityOutput, error) {
+			Logger(ctx).Printf("[ManagementQualityTool] 接收到请求: Symbol=%s, Years=%d", req.Symbol, req.Years)
+
+			// 验证必需参数
+			if req.Symbol == "" {
+				Logger(ctx).Printf("[ManagementQualityTool] 错误: 股票代码为空")
+				return &ManagementQualityOutput{
+					Error: "股票代码不能为空",
+				}, nil
+			}
+			symbol := strings.ToUpper(req.Symbol)
+			years := req.Years
+			if years <= 0 {
+				years = defaultManagementYears
+			}
+
+			now := time.Now()
+			today := now.Format("2006-01-02")
+			since := now.AddDate(-managementLookbackYears, 0, 0).Format("2006-01-02")
+			result := &ManagementQualityOutput{Symbol: symbol, MaxScore: ManagementMaxScore}
+
+			periods, err := getCapitalAllocationFunc(ctx, symbol, years)
+			if err != nil {
+				if isFatalAPIError(err) {
+					return nil, err
+				}
+				Logger(ctx).Printf("[ManagementQualityTool] 获取资本配置数据失败: %v", err)
+				result.DataGaps = append(result.DataGaps, fmt.Sprintf("资本配置数据: %v", err))
+			}
+			result.CapitalAllocation = periods
+
+			trades, truncated, tradesErr := getTradesFunc(ctx, symbol, today, &since, managementTradeLimit)
+			if tradesErr != nil {
+				if isFatalAPIError(tradesErr) {
+					return nil, tradesErr
+				}
+				Logger(ctx).Printf("[ManagementQualityTool] 获取内部人交易失败: %v", tradesErr)
+				result.DataGaps = append(result.DataGaps, fmt.Sprintf("内部人交易: %v", tradesErr))
+			} else if truncated != "" {
+				result.DataGaps = append(result.DataGaps, fmt.Sprintf("内部人交易不完整（%s），持股和净买卖只基于已获取的交易", truncated))
+			}
+
+			news, newsErr := getNewsFunc(ctx, symbol, today, &since, managementNewsLimit)
+			if newsErr != nil {
+				if isFatalAPIError(newsErr) {
+					return nil, newsErr
+				}
+				Logger(ctx).Printf("[ManagementQualityTool] 获取新闻失败: %v", newsErr)
+				result.DataGaps = append(result.DataGaps, fmt.Sprintf("管理层变动新闻: %v", newsErr))
+			}
+			result.ManagementEvents = managementEvents(news)
+
+			var shares *float64
+			if len(periods) > 0 {
+				shares = periods[0].SharesOutstanding
+			}
+			ownership, holders := insiderOwnership(trades, shares)
+			result.InsiderOwnership, result.InsiderHolders = ownership, holders
+			result.NetInsiderShares = netInsiderShares(trades, now.AddDate(-1, 0, 0).Format("2006-01-02"))
+
+			result.Checks = []ManagementCheck{
+				checkInsiderOwnership(ownership, holders, tradesErr == nil),
+				checkInsiderNetTrading(result.NetInsiderShares, tradesErr == nil),
+				checkShareBasedCompensation(periods),
+				checkShareCount(periods),
+				checkCapitalReturns(periods),
+				checkManagementStability(result.ManagementEvents, newsErr == nil),
+			}
+			for _, check := range result.Checks {
+				result.Score += check.Points
+			}
+			result.DataGaps = append(result.DataGaps, "高管薪酬明细（委托书 DEF 14A）不在数据源范围内，以股权激励费用占收入比例作为薪酬代理指标")
+
+			if err := saveManagementQualityToFile(result); err != nil {
+				Logger(ctx).Printf("[ManagementQualityTool] 保存评估结果失败: %v", err)
+			}
+
+			Logger(ctx).Printf("[ManagementQualityTool] 返回响应: Symbol=%s, Score=%d/%d, 内部人持股=%s, 管理层变动新闻=%d",
+				symbol, result.Score, ManagementMaxScore, (ownership * 100).Sprintf("%.2f%%"), len(result.ManagementEvents))
+			return result, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return tool, nil
+}
+
+// insiderOwnership 取每位内部人最近一次申报后的持股，合计后除以总股本；没有总股本时比例无效
+func insiderOwnership(trades []InsiderTrade, sharesOutstanding *float64) (SafeFloat, int) {
+	latest := make(map[string]InsiderTrade)
+	for _, trade := range trades {
+		if trade.Name == nil || trade.SharesOwnedAfterTransaction == nil {
+			continue
+		}
+		if prev, ok := latest[*trade.Name]; !ok || tradeDate(trade) > tradeDate(prev) {
+			latest[*trade.Name] = trade
+		}
+	}
+	var total float64
+	for _, trade := range latest {
+		total += *trade.SharesOwnedAfterTransaction
+	}
+	if sharesOutstanding == nil {
+		return SafeDiv(total, 0), len(latest)
+	}
+	return SafeDiv(total, *sharesOutstanding), len(latest)
+}
+
+// netInsiderShares 统计 since 之后的内部人净买卖股数
+func netInsiderShares(trades []InsiderTrade, since string) float64 {
+	var net float64
+	for _, trade := range trades {
+		if trade.TransactionShares != nil && tradeDate(trade) >= since {
+			net += *trade.TransactionShares
+		}
+	}
+	return net
+}
+
+// tradeDate 交易日期，缺失时使用申报日期
+func tradeDate(trade InsiderTrade) string {
+	if trade.TransactionDate != nil && *trade.TransactionDate != "" {
+		return *trade.TransactionDate
+	}
+	return trade.FilingDate
+}
+
+// managementEvents 筛选管理层主题中涉及离任、接任的新闻
+func managementEvents(news []CompanyNews) []ManagementEvent {
+	events := []ManagementEvent{}
+	for _, item := range news {
+		topics := item.Topics
+		if len(topics) == 0 {
+			topics = matchNewsTopics(item)
+		}
+		isManagement := false
+		for _, topic := range topics {
+			if topic == NewsTopicManagement {
+				isManagement = true
+			}
+		}
+		if isManagement && managementDeparturePattern.MatchString(item.Title+" "+item.Summary) {
+			events = append(events, ManagementEvent{Date: item.DateTime, Title: item.Title, URL: item.URL})
+		}
+	}
+	return events
+}
+
+func checkInsiderOwnership(ownership SafeFloat, holders int, available bool) ManagementCheck {
+	check := ManagementCheck{Name: "内部人持股", MaxPoints: 2}
+	switch {
+	case !available || !ownership.Valid():
+		check.Detail = "内部人持股数据不可用"
+	case ownership >= 0.01:
+		check.Points = 2
+		check.Detail = fmt.Sprintf("%d 位申报过交易的内部人合计持股 %s，利益与股东高度一致", holders, (ownership * 100).Sprintf("%.2f%%"))
+	case ownership >= 0.001:
+		check.Points = 1
+		check.Detail = fmt.Sprintf("%d 位申报过交易的内部人合计持股 %s", holders, (ownership * 100).Sprintf("%.2f%%"))
+	default:
+		check.Detail = fmt.Sprintf("%d 位申报过交易的内部人合计持股仅 %s", holders, (ownership * 100).Sprintf("%.3f%%"))
+	}
+	return check
+}
+
+func checkInsiderNetTrading(net float64, available bool) ManagementCheck {
+	check := ManagementCheck{Name: "内部人净买卖（近一年）", MaxPoints: 1}
+	switch {
+	case !available:
+		check.Detail = "内部人交易数据不可用"
+	case net > 0:
+		check.Points = 1
+		check.Detail = fmt.Sprintf("近一年内部人净买入 %.0f 股", net)
+	case net == 0:
+		check.Points = 1
+		check.Detail = "近一年内部人没有净买卖"
+	default:
+		check.Detail = fmt.Sprintf("近一年内部人净卖出 %.0f 股（可能包含计划内减持和行权后出售）", -net)
+	}
+	return check
+}
+
+func checkShareBasedCompensation(periods []CapitalAllocationPeriod) ManagementCheck {
+	check := ManagementCheck{Name: "股权激励占收入比例", MaxPoints: 2}
+	var sbc, revenue float64
+	n := 0
+	for _, p := range periods {
+		if p.ShareBasedCompensation != nil && p.Revenue != nil && *p.Revenue > 0 {
+			sbc += *p.ShareBasedCompensation
+			revenue += *p.Revenue
+			n++
+		}
+	}
+	if n == 0 {
+		check.Detail = "股权激励或收入数据不可用"
+		return check
+	}
+	ratio := SafeDiv(sbc, revenue)
+	switch {
+	case ratio < 0.05:
+		check.Points = 2
+	case ratio < 0.10:
+		check.Points = 1
+	}
+	check.Detail = fmt.Sprintf("%d 个年度股权激励费用合计占收入 %s", n, (ratio * 100).Sprintf("%.1f%%"))
+	return check
+}
+
+func checkShareCount(periods []CapitalAllocationPeriod) ManagementCheck {
+	check := ManagementCheck{Name: "股本变化", MaxPoints: 2}
+	var first, last *CapitalAllocationPeriod
+	for i := range periods {
+		if periods[i].SharesOutstanding != nil && *periods[i].SharesOutstanding > 0 {
+			if last == nil {
+				last = &periods[i]
+			}
+			first = &periods[i]
+		}
+	}
+	if first == nil || first == last {
+		check.Detail = "股本数据不足两期"
+		return check
+	}
+	change := Sanitize(*last.SharesOutstanding / *first.SharesOutstanding - 1)
+	span := fmt.Sprintf("%s 至 %s", first.ReportPeriod, last.ReportPeriod)
+	switch {
+	case change <= -0.01:
+		check.Points = 2
+		check.Detail = fmt.Sprintf("%s 股本减少 %s，回购抵消稀释后仍在缩减股本", span, (-change * 100).Sprintf("%.1f%%"))
+	case change <= 0.01:
+		check.Points = 1
+		check.Detail = fmt.Sprintf("%s 股本基本持平（%s）", span, (change * 100).Sprintf("%+.1f%%"))
+	default:
+		check.Detail = fmt.Sprintf("%s 股本增加 %s，存在股权稀释", span, (change * 100).Sprintf("%.1f%%"))
+	}
+	return check
+}
+
+func checkCapitalReturns(periods []CapitalAllocationPeriod) ManagementCheck {
+	check := ManagementCheck{Name: "资本配置", MaxPoints: 2}
+	var fcf, returned, acquisitions float64
+	n, positive := 0, 0
+	for _, p := range periods {
+		if p.FreeCashFlow == nil {
+			continue
+		}
+		n++
+		fcf += *p.FreeCashFlow
+		if *p.FreeCashFlow > 0 {
+			positive++
+		}
+		for _, v := range []*float64{p.Buybacks, p.Dividends} {
+			if v != nil {
+				returned += *v
+			}
+		}
+		if p.Acquisitions != nil {
+			acquisitions += *p.Acquisitions
+		}
+	}
+	if n == 0 {
+		check.Detail = "自由现金流数据不可用"
+		return check
+	}
+	var notes []string
+	if positive == n {
+		check.Points++
+		notes = append(notes, fmt.Sprintf("%d 个年度自由现金流均为正", n))
+	} else {
+		notes = append(notes, fmt.Sprintf("%d 个年度中 %d 个自由现金流为正", n, positive))
+	}
+	if fcf > 0 {
+		payout := SafeDiv(returned, fcf)
+		if returned > 0 && payout <= 1.1 {
+			check.Points++
+			notes = append(notes, fmt.Sprintf("回购和分红合计为自由现金流的 %s，未依赖举债回报股东", (payout*100).Sprintf("%.0f%%")))
+		} else if returned > 0 {
+			notes = append(notes, fmt.Sprintf("回购和分红合计为自由现金流的 %s，超出部分可能依赖举债或现金储备", (payout*100).Sprintf("%.0f%%")))
+		} else {
+			notes = append(notes, "期间没有回购或分红")
+		}
+		if acquisitions > 0.5*fcf {
+			notes = append(notes, fmt.Sprintf("并购支出为自由现金流的 %s，需关注并购回报", (SafeDiv(acquisitions, fcf)*100).Sprintf("%.0f%%")))
+		}
+	}
+	check.Detail = strings.Join(notes, "；")
+	return check
+}
+
+func checkManagementStability(events []ManagementEvent, available bool) ManagementCheck {
+	check := ManagementCheck{Name: "管理层稳定性", MaxPoints: 1}
+	switch {
+	case !available:
+		check.Detail = "新闻数据不可用，无法判断高管变动"
+	case len(events) == 0:
+		check.Points = 1
+		check.Detail = fmt.Sprintf("过去%d年未发现高管离任相关新闻", managementLookbackYears)
+	default:
+		check.Detail = fmt.Sprintf("过去%d年有 %d 条高管离任或接任相关新闻，最新: %s", managementLookbackYears, len(events), events[0].Title)
+	}
+	return check
+}
+
+// saveManagementQualityToFile 将管理层质量评估结果写入输出目标
+func saveManagementQualityToFile(output *ManagementQualityOutput) error {
+	// 生成文件名：management/management_AAPL_2025-09-25_15-04-05.json
+	timeSuffix := time.Now().Format("2006-01-02_15-04-05")
+	name := fmt.Sprintf("management/management_%s_%s.json", output.Symbol, timeSuffix)
+	location, err := writeJSONArtifact(name, output)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[ManagementQualityTool] 评估结果已保存到: %s", location)
+	return nil
+}
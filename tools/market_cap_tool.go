@@ -2,12 +2,11 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/cloudwego/eino/components/tool"
-	"github.com/cloudwego/eino/components/tool/utils"
 )
 
 // MarketCapInput 市值查询的输入参数
@@ -26,15 +25,15 @@ type MarketCapOutput struct {
 }
 
 // NewMarketCapTool 创建新的市值查询工具
-func NewMarketCapTool(getMarketCapFunc func(symbol, date string) (float64, error)) (tool.BaseTool, error) {
-	tool, err := utils.InferTool("get_market_cap",
+func NewMarketCapTool(getMarketCapFunc func(ctx context.Context, symbol, date string) (float64, error)) (tool.BaseTool, error) {
+	tool, err := inferTool("get_market_cap",
 		"获取指定股票在指定日期的市值信息。这是投资分析的基础数据，用于评估公司规模。",
 		func(ctx context.Context, req *MarketCapInput) (*MarketCapOutput, error) {
-			log.Printf("[MarketCapTool] 接收到请求: Symbol=%s, Date=%s", req.Symbol, req.Date)
+			Logger(ctx).Printf("[MarketCapTool] 接收到请求: Symbol=%s, Date=%s", req.Symbol, req.Date)
 
 			// 验证必需参数
 			if req.Symbol == "" {
-				log.Printf("[MarketCapTool] 错误: 股票代码为空")
+				Logger(ctx).Printf("[MarketCapTool] 错误: 股票代码为空")
 				return &MarketCapOutput{
 					Error: "股票代码不能为空",
 				}, nil
@@ -46,20 +45,27 @@ func NewMarketCapTool(getMarketCapFunc func(symbol, date string) (float64, error
 				date = time.Now().Format("2006-01-02")
 			}
 
-			log.Printf("[MarketCapTool] 准备调用API: Symbol=%s, Date=%s", req.Symbol, date)
+			Logger(ctx).Printf("[MarketCapTool] 准备调用API: Symbol=%s, Date=%s", req.Symbol, date)
 
 			// 调用API获取市值
-			marketCap, err := getMarketCapFunc(req.Symbol, date)
+			marketCap, err := getMarketCapFunc(ctx, req.Symbol, date)
 			if err != nil {
-				log.Printf("[MarketCapTool] API调用失败: %v", err)
+				Logger(ctx).Printf("[MarketCapTool] API调用失败: %v", err)
+				if isFatalAPIError(err) {
+					return nil, err
+				}
+				message := fmt.Sprintf("获取市值失败: %v", err)
+				if errors.Is(err, ErrNoData) {
+					message = fmt.Sprintf("市值数据不可用（不代表市值为0）: %v", err)
+				}
 				return &MarketCapOutput{
 					Symbol: req.Symbol,
 					Date:   date,
-					Error:  fmt.Sprintf("获取市值失败: %v", err),
+					Error:  message,
 				}, nil
 			}
 
-			log.Printf("[MarketCapTool] API调用成功: MarketCap=%.2f", marketCap)
+			Logger(ctx).Printf("[MarketCapTool] API调用成功: MarketCap=%.2f", marketCap)
 
 			result := &MarketCapOutput{
 				Symbol:    req.Symbol,
@@ -68,7 +74,7 @@ func NewMarketCapTool(getMarketCapFunc func(symbol, date string) (float64, error
 				Currency:  "USD",
 			}
 
-			log.Printf("[MarketCapTool] 返回响应: Symbol=%s, Date=%s, MarketCap=%.2f, Currency=%s", result.Symbol, result.Date, result.MarketCap, result.Currency)
+			Logger(ctx).Printf("[MarketCapTool] 返回响应: Symbol=%s, Date=%s, MarketCap=%.2f, Currency=%s", result.Symbol, result.Date, result.MarketCap, result.Currency)
 			return result, nil
 		})
 	if err != nil {
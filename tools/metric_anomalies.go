@@ -0,0 +1,190 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// anomalyZScore 一期变化相对该指标其他各期变化的 z 分数达到该值时视为突变
+const anomalyZScore = 2.0
+
+// anomalyMinHistory 计算 z 分数至少需要的其他各期变化数，不足时只按变化幅度下限判断
+const anomalyMinHistory = 2
+
+// MetricAnomaly 关键指标在相邻两个报告期之间的异常变动
+type MetricAnomaly struct {
+	Metric         string   `json:"metric"`          // 指标的 json 字段名
+	Label          string   `json:"label"`           // 指标名称
+	Period         string   `json:"period"`          // 发生变动的报告期
+	PreviousPeriod string   `json:"previous_period"` // 比较的上一个报告期
+	Previous       float64  `json:"previous"`
+	Current        float64  `json:"current"`
+	Change         float64  `json:"change"`            // 百分比指标为百分点差（小数形式），其他为相对变化
+	ZScore         *float64 `json:"z_score,omitempty"` // 相对其他各期变化的 z 分数，历史不足时为空
+	Description    string   `json:"description"`
+}
+
+// anomalyRule 一个被监控的指标及其不利变动方向
+type anomalyRule struct {
+	Metric    string
+	Label     string
+	Value     func(m FinancialMetrics) (float64, bool)
+	Adverse   float64 // 1 表示上升不利，-1 表示下降不利
+	MinChange float64 // 变动幅度下限，小于该值的变动即使统计上显著也不提示
+	Percent   bool    // 比率以小数形式表示，变动按百分点计算；否则按相对变化计算
+}
+
+// anomalyRules 监控的指标：利润率骤降、应收账款激增、杠杆骤升、短期偿债能力骤降
+var anomalyRules = []anomalyRule{
+	{Metric: "gross_margin", Label: "毛利率", Value: nonZeroValue(func(m FinancialMetrics) float64 { return m.GrossMargin }), Adverse: -1, MinChange: 0.05, Percent: true},
+	{Metric: "operating_margin", Label: "营运利润率", Value: pointerValue(func(m FinancialMetrics) *float64 { return m.OperatingMargin }), Adverse: -1, MinChange: 0.05, Percent: true},
+	{Metric: "net_margin", Label: "净利率", Value: pointerValue(func(m FinancialMetrics) *float64 { return m.NetMargin }), Adverse: -1, MinChange: 0.05, Percent: true},
+	{Metric: "days_sales_outstanding", Label: "应收账款周转天数", Value: nonZeroValue(func(m FinancialMetrics) float64 { return m.DaysSalesOutstanding }), Adverse: 1, MinChange: 0.3},
+	{Metric: "debt_to_equity", Label: "债务股权比", Value: pointerValue(func(m FinancialMetrics) *float64 { return m.DebtToEquity }), Adverse: 1, MinChange: 0.5},
+	{Metric: "current_ratio", Label: "流动比率", Value: pointerValue(func(m FinancialMetrics) *float64 { return m.CurrentRatio }), Adverse: -1, MinChange: 0.3},
+	{Metric: "interest_coverage", Label: "利息覆盖倍数", Value: pointerValue(func(m FinancialMetrics) *float64 { return m.InterestCoverage }), Adverse: -1, MinChange: 0.5},
+}
+
+// nonZeroValue 数据源用 0 表示缺失的字段
+func nonZeroValue(field func(m FinancialMetrics) float64) func(m FinancialMetrics) (float64, bool) {
+	return func(m FinancialMetrics) (float64, bool) {
+		v := field(m)
+		return v, v != 0 && !math.IsNaN(v) && !math.IsInf(v, 0)
+	}
+}
+
+// pointerValue 可能缺失的字段
+func pointerValue(field func(m FinancialMetrics) *float64) func(m FinancialMetrics) (float64, bool) {
+	return func(m FinancialMetrics) (float64, bool) {
+		v := field(m)
+		if v == nil || math.IsNaN(*v) || math.IsInf(*v, 0) {
+			return 0, false
+		}
+		return *v, true
+	}
+}
+
+// DetectMetricAnomalies 找出关键指标在相邻报告期之间的不利突变，metrics 按报告期从新到旧排列（与数据源一致）
+// 一期变动需要同时满足：方向不利、幅度不小于规则的下限；其他各期变化足够多时，还要求 z 分数不小于 anomalyZScore，
+// 避免把一直大幅波动的指标误判为突变。结果按报告期从新到旧排列
+func DetectMetricAnomalies(metrics []FinancialMetrics) []MetricAnomaly {
+	var anomalies []MetricAnomaly
+	for _, rule := range anomalyRules {
+		anomalies = append(anomalies, rule.detect(metrics)...)
+	}
+	slices.SortStableFunc(anomalies, func(a, b MetricAnomaly) int { return strings.Compare(b.Period, a.Period) })
+	return anomalies
+}
+
+// anomalyPoint 一个报告期的指标值
+type anomalyPoint struct {
+	period string
+	value  float64
+}
+
+// detect 按规则检查一个指标的各期变化
+func (r anomalyRule) detect(metrics []FinancialMetrics) []MetricAnomaly {
+	var points []anomalyPoint
+	for i := len(metrics) - 1; i >= 0; i-- {
+		if v, ok := r.Value(metrics[i]); ok {
+			points = append(points, anomalyPoint{period: metrics[i].ReportPeriod, value: v})
+		}
+	}
+	if len(points) < 2 {
+		return nil
+	}
+	changes := make([]float64, len(points)-1)
+	for i := 1; i < len(points); i++ {
+		changes[i-1] = r.change(points[i-1].value, points[i].value)
+	}
+
+	var anomalies []MetricAnomaly
+	for i, change := range changes {
+		if math.IsNaN(change) || change*r.Adverse < r.MinChange {
+			continue
+		}
+		var others []float64
+		for j, c := range changes {
+			if j != i && !math.IsNaN(c) {
+				others = append(others, c)
+			}
+		}
+		anomaly := MetricAnomaly{
+			Metric:         r.Metric,
+			Label:          r.Label,
+			Period:         points[i+1].period,
+			PreviousPeriod: points[i].period,
+			Previous:       points[i].value,
+			Current:        points[i+1].value,
+			Change:         change,
+		}
+		if len(others) >= anomalyMinHistory {
+			mean, sd := meanStdDev(others)
+			if sd > 0 {
+				z := (change - mean) / sd
+				if z*r.Adverse < anomalyZScore {
+					continue
+				}
+				anomaly.ZScore = &z
+			}
+		}
+		anomaly.Description = r.describe(anomaly)
+		anomalies = append(anomalies, anomaly)
+	}
+	return anomalies
+}
+
+// change 相邻两期的变化：百分比指标为百分点差，其他为相对变化；上一期为负数时相对变化没有意义，返回 NaN
+func (r anomalyRule) change(previous, current float64) float64 {
+	if r.Percent {
+		return current - previous
+	}
+	if previous <= 0 {
+		return math.NaN()
+	}
+	return current/previous - 1
+}
+
+// describe 异常变动的一句话说明
+func (r anomalyRule) describe(a MetricAnomaly) string {
+	format := func(v float64) string { return Sanitize(v).Sprintf("%.2f") }
+	change := Sanitize(a.Change * 100).Sprintf("%+.0f%%")
+	if r.Percent {
+		format = func(v float64) string { return Sanitize(v * 100).Sprintf("%.1f%%") }
+		change = Sanitize(a.Change * 100).Sprintf("%+.1f 个百分点")
+	}
+	desc := fmt.Sprintf("%s %s 为 %s，较 %s 的 %s 变动 %s", r.Label, a.Period, format(a.Current), a.PreviousPeriod, format(a.Previous), change)
+	if a.ZScore != nil {
+		desc += Sanitize(*a.ZScore).Sprintf("（z=%.1f）")
+	}
+	return desc
+}
+
+// meanStdDev 均值和总体标准差
+func meanStdDev(values []float64) (float64, float64) {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	var sq float64
+	for _, v := range values {
+		sq += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(sq / float64(len(values)))
+}
+
+// RenderMetricAnomalies 把异常变动渲染为 markdown 列表，附加在财务指标数据表之后；没有异常时返回空字符串
+func RenderMetricAnomalies(anomalies []MetricAnomaly) string {
+	if len(anomalies) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("### ⚠️ 指标异常变动\n\n")
+	for _, a := range anomalies {
+		fmt.Fprintf(&sb, "- %s\n", a.Description)
+	}
+	return sb.String()
+}
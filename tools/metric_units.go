@@ -0,0 +1,133 @@
+package tools
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// MetricUnit 财务指标的单位
+type MetricUnit string
+
+const (
+	UnitFraction MetricUnit = "fraction" // 比例的小数形式，0.15 表示 15%
+	UnitPercent  MetricUnit = "percent"  // 比例的百分数形式，15 表示 15%
+	UnitMultiple MetricUnit = "multiple" // 倍数，如市盈率、流动比率
+	UnitDays     MetricUnit = "days"
+	UnitAmount   MetricUnit = "amount" // 金额或每股金额，按指标的币种
+)
+
+// MetricSpec 指标在程序内部使用的单位；比例一律使用小数形式，评分阈值（如 ROE > 0.15）按此编写
+type MetricSpec struct {
+	Unit MetricUnit
+	// MaxFraction 大于 0 时，小数形式下不可能超过的值（如毛利率不会超过 100%），
+	// 数据源未声明单位时，整个序列中出现超过该值的数即判断为百分数
+	MaxFraction float64
+}
+
+// FinancialMetricSpecs FinancialMetrics 各数值字段（json 字段名）的单位
+var FinancialMetricSpecs = map[string]MetricSpec{
+	"market_cap":                        {Unit: UnitAmount},
+	"enterprise_value":                  {Unit: UnitAmount},
+	"price_to_earnings_ratio":           {Unit: UnitMultiple},
+	"price_to_book_ratio":               {Unit: UnitMultiple},
+	"price_to_sales_ratio":              {Unit: UnitMultiple},
+	"enterprise_value_to_ebitda_ratio":  {Unit: UnitMultiple},
+	"enterprise_value_to_revenue_ratio": {Unit: UnitMultiple},
+	"free_cash_flow_yield":              {Unit: UnitFraction},
+	"peg_ratio":                         {Unit: UnitMultiple},
+	"gross_margin":                      {Unit: UnitFraction, MaxFraction: 1},
+	"operating_margin":                  {Unit: UnitFraction, MaxFraction: 1},
+	"net_margin":                        {Unit: UnitFraction, MaxFraction: 1},
+	"return_on_equity":                  {Unit: UnitFraction},
+	"return_on_assets":                  {Unit: UnitFraction, MaxFraction: 1},
+	"return_on_invested_capital":        {Unit: UnitFraction},
+	"asset_turnover":                    {Unit: UnitMultiple},
+	"inventory_turnover":                {Unit: UnitMultiple},
+	"receivables_turnover":              {Unit: UnitMultiple},
+	"days_sales_outstanding":            {Unit: UnitDays},
+	"operating_cycle":                   {Unit: UnitDays},
+	"working_capital_turnover":          {Unit: UnitMultiple},
+	"current_ratio":                     {Unit: UnitMultiple},
+	"quick_ratio":                       {Unit: UnitMultiple},
+	"cash_ratio":                        {Unit: UnitMultiple},
+	"operating_cash_flow_ratio":         {Unit: UnitMultiple},
+	"debt_to_equity":                    {Unit: UnitMultiple},
+	"debt_to_assets":                    {Unit: UnitFraction},
+	"interest_coverage":                 {Unit: UnitMultiple},
+	"revenue_growth":                    {Unit: UnitFraction},
+	"earnings_growth":                   {Unit: UnitFraction},
+	"book_value_growth":                 {Unit: UnitFraction},
+	"earnings_per_share_growth":         {Unit: UnitFraction},
+	"free_cash_flow_growth":             {Unit: UnitFraction},
+	"operating_income_growth":           {Unit: UnitFraction},
+	"ebitda_growth":                     {Unit: UnitFraction},
+	"payout_ratio":                      {Unit: UnitFraction},
+	"earnings_per_share":                {Unit: UnitAmount},
+	"book_value_per_share":              {Unit: UnitAmount},
+	"free_cash_flow_per_share":          {Unit: UnitAmount},
+}
+
+// NormalizeFinancialMetrics 把数据源返回的比例统一为小数形式，原地修改 metrics
+// providerUnits 为数据源声明的各字段单位（json 字段名），声明为 UnitPercent 的字段除以 100；
+// 未声明的字段按 MaxFraction 检查整个序列，出现不可能的值时按百分数处理。返回每个被转换字段的说明
+func NormalizeFinancialMetrics(metrics []FinancialMetrics, providerUnits map[string]MetricUnit) []string {
+	if len(metrics) == 0 {
+		return nil
+	}
+	var notes []string
+	t := reflect.TypeOf(FinancialMetrics{})
+	for i := 0; i < t.NumField(); i++ {
+		key := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		spec, ok := FinancialMetricSpecs[key]
+		if !ok || spec.Unit != UnitFraction {
+			continue
+		}
+		unit, declared := providerUnits[key]
+		if !declared {
+			unit = detectFractionUnit(metrics, i, spec)
+		}
+		if unit != UnitPercent {
+			continue
+		}
+		for j := range metrics {
+			if v := metricField(&metrics[j], i); v != nil {
+				*v /= 100
+			}
+		}
+		if declared {
+			notes = append(notes, fmt.Sprintf("%s 按数据源声明的百分数转换为小数", key))
+		} else {
+			notes = append(notes, fmt.Sprintf("%s 出现超过 %.0f%% 的值，判断为百分数并转换为小数", key, spec.MaxFraction*100))
+		}
+	}
+	return notes
+}
+
+// detectFractionUnit 数据源未声明单位时判断比例字段是百分数还是小数
+func detectFractionUnit(metrics []FinancialMetrics, field int, spec MetricSpec) MetricUnit {
+	if spec.MaxFraction <= 0 {
+		return UnitFraction
+	}
+	for j := range metrics {
+		if v := metricField(&metrics[j], field); v != nil && *v > spec.MaxFraction {
+			return UnitPercent
+		}
+	}
+	return UnitFraction
+}
+
+// metricField 返回第 field 个字段的数值指针，字段为空指针或不是数值时返回 nil
+func metricField(m *FinancialMetrics, field int) *float64 {
+	v := reflect.ValueOf(m).Elem().Field(field)
+	switch v.Kind() {
+	case reflect.Float64:
+		return v.Addr().Interface().(*float64)
+	case reflect.Pointer:
+		if v.IsNil() || v.Elem().Kind() != reflect.Float64 {
+			return nil
+		}
+		return v.Interface().(*float64)
+	}
+	return nil
+}
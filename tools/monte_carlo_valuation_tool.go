@@ -0,0 +1,354 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// DistributionInput 用户给出的估值假设分布，未提供的字段为 nil（显式的 0 是有效取值）
+type DistributionInput struct {
+	Type   string   `json:"type,omitempty" description:"分布类型：normal(正态)、uniform(均匀)、triangular(三角)，默认为normal"`
+	Mean   *float64 `json:"mean,omitempty" description:"正态分布均值，三角分布的众数（不提供时取上下限的中点）"`
+	StdDev *float64 `json:"std_dev,omitempty" description:"正态分布标准差，必须大于0"`
+	Min    *float64 `json:"min,omitempty" description:"均匀/三角分布的下限（必填），正态分布的截断下限"`
+	Max    *float64 `json:"max,omitempty" description:"均匀/三角分布的上限（必填，必须大于下限），正态分布的截断上限"`
+}
+
+// Distribution 模拟实际使用的分布，由用户给出的分布与默认分布合并而来
+type Distribution struct {
+	Type   string  `json:"type"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"std_dev,omitempty"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+}
+
+// MonteCarloValuationInput 蒙特卡洛估值的输入参数
+type MonteCarloValuationInput struct {
+	Symbol       string             `json:"symbol" description:"股票代码，如 AAPL, TSLA, GOOG"`
+	Metrics      []FinancialMetrics `json:"metrics" description:"财务指标数据，使用最新一期计算每股收入、利润率和当前市盈率"`
+	Growth       *DistributionInput `json:"growth,omitempty" description:"年化营收增长率分布（小数，如0.1表示10%），默认基于最新营收增长率"`
+	NetMargin    *DistributionInput `json:"net_margin,omitempty" description:"预测期末净利率分布（小数），默认基于最新净利率"`
+	ExitPE       *DistributionInput `json:"exit_pe,omitempty" description:"预测期末市盈率分布，默认基于当前市盈率"`
+	Years        int                `json:"years,omitempty" description:"预测年数，默认为5年，最大10年"`
+	DiscountRate float64            `json:"discount_rate,omitempty" description:"折现率（小数），默认为0.09"`
+	Simulations  int                `json:"simulations,omitempty" description:"模拟次数，默认为10000次，最大100000次"`
+	Seed         int64              `json:"seed,omitempty" description:"随机种子，用于复现结果，默认为当前时间"`
+}
+
+// MonteCarloValuationOutput 蒙特卡洛估值的输出结果
+type MonteCarloValuationOutput struct {
+	Symbol       string       `json:"symbol"`
+	Currency     string       `json:"currency"`
+	CurrentPrice SafeFloat    `json:"current_price"`
+	P10          SafeFloat    `json:"p10"`
+	P50          SafeFloat    `json:"p50"`
+	P90          SafeFloat    `json:"p90"`
+	Mean         SafeFloat    `json:"mean"`
+	UpsideP10    SafeFloat    `json:"upside_p10"`
+	UpsideP50    SafeFloat    `json:"upside_p50"`
+	UpsideP90    SafeFloat    `json:"upside_p90"`
+	Growth       Distribution `json:"growth"`
+	NetMargin    Distribution `json:"net_margin"`
+	ExitPE       Distribution `json:"exit_pe"`
+	Years        int          `json:"years"`
+	DiscountRate float64      `json:"discount_rate"`
+	Simulations  int          `json:"simulations"`
+	Seed         int64        `json:"seed"`
+	Error        string       `json:"error,omitempty"`
+}
+
+// NewMonteCarloValuationTool 创建蒙特卡洛估值工具
+func NewMonteCarloValuationTool() (tool.BaseTool, error) {
+	tool, err := inferTool("monte_carlo_valuation",
+		"基于蒙特卡洛模拟进行估值：对营收增长率、净利率和退出市盈率按分布随机抽样，得到每股合理价值的分布（P10/P50/P90），用于给出估值区间而非单一目标价。",
+		func(ctx context.Context, req *MonteCarloValuationInput) (*MonteCarloValuationOutput, error) {
+			Logger(ctx).Printf("[MonteCarloValuationTool] 接收到请求: Symbol=%s, 财务指标数量=%d, Years=%d, Simulations=%d", req.Symbol, len(req.Metrics), req.Years, req.Simulations)
+
+			if len(req.Metrics) == 0 {
+				Logger(ctx).Printf("[MonteCarloValuationTool] 错误: 未提供财务指标数据")
+				return &MonteCarloValuationOutput{
+					Symbol: req.Symbol,
+					Error:  "未提供财务指标数据",
+				}, nil
+			}
+
+			result, err := runMonteCarloValuation(req)
+			if err != nil {
+				Logger(ctx).Printf("[MonteCarloValuationTool] 估值失败: %v", err)
+				return &MonteCarloValuationOutput{
+					Symbol: req.Symbol,
+					Error:  err.Error(),
+				}, nil
+			}
+
+			// 保存估值结果到本地文件
+			if err := saveValuationToFile(result); err != nil {
+				Logger(ctx).Printf("[MonteCarloValuationTool] 保存文件失败: %v", err)
+				// 不返回错误，继续返回估值结果
+			}
+
+			Logger(ctx).Printf("[MonteCarloValuationTool] 估值完成: Symbol=%s, P10=%s, P50=%s, P90=%s", result.Symbol, result.P10.Sprintf("%.2f"), result.P50.Sprintf("%.2f"), result.P90.Sprintf("%.2f"))
+			return result, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return tool, nil
+}
+
+// runMonteCarloValuation 执行蒙特卡洛模拟
+// 每股价值 = 每股收入 × (1+增长率)^年数 × 净利率 × 退出市盈率 / (1+折现率)^年数
+func runMonteCarloValuation(req *MonteCarloValuationInput) (*MonteCarloValuationOutput, error) {
+	latest := req.Metrics[0]
+	symbol := req.Symbol
+	if symbol == "" {
+		symbol = latest.Ticker
+	}
+
+	if latest.EarningsPerShare <= 0 {
+		return nil, fmt.Errorf("每股收益为 %.2f，无法基于盈利进行估值", latest.EarningsPerShare)
+	}
+	if latest.NetMargin == nil || *latest.NetMargin <= 0 {
+		return nil, fmt.Errorf("净利率数据不可用或为负，无法推算每股收入")
+	}
+	revenuePerShare := latest.EarningsPerShare / *latest.NetMargin
+	currentPrice := Sanitize(latest.EarningsPerShare * latest.PriceToEarningsRatio)
+
+	// 未提供的假设使用最新财务指标推导默认分布
+	growth, err := resolveDistribution("growth", req.Growth, Distribution{
+		Type: "normal", Mean: clamp(latest.RevenueGrowth, -0.1, 0.3), StdDev: 0.05, Min: -0.3, Max: 0.6,
+	})
+	if err != nil {
+		return nil, err
+	}
+	margin, err := resolveDistribution("net_margin", req.NetMargin, Distribution{
+		Type: "normal", Mean: *latest.NetMargin, StdDev: 0.02, Min: 0, Max: 0.8,
+	})
+	if err != nil {
+		return nil, err
+	}
+	peMean := clamp(latest.PriceToEarningsRatio, 8, 40)
+	if latest.PriceToEarningsRatio <= 0 {
+		peMean = 15
+	}
+	exitPE, err := resolveDistribution("exit_pe", req.ExitPE, Distribution{
+		Type: "normal", Mean: peMean, StdDev: peMean * 0.2, Min: 3, Max: 80,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	years := req.Years
+	if years <= 0 {
+		years = 5
+	}
+	if years > 10 {
+		years = 10
+	}
+	discountRate := req.DiscountRate
+	if discountRate <= 0 {
+		discountRate = 0.09
+	}
+	simulations := req.Simulations
+	if simulations <= 0 {
+		simulations = 10000
+	}
+	if simulations > 100000 {
+		simulations = 100000
+	}
+	seed := req.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	discount := math.Pow(1+discountRate, float64(years))
+	values := make([]float64, 0, simulations)
+	sum := 0.0
+	for i := 0; i < simulations; i++ {
+		g := sampleDistribution(rng, growth)
+		m := sampleDistribution(rng, margin)
+		pe := sampleDistribution(rng, exitPE)
+		value := revenuePerShare * math.Pow(1+g, float64(years)) * m * pe / discount
+		// 极端假设（如增长率 <= -100%）可能产生非有限数，直接丢弃该样本
+		if !IsFinite(value) {
+			continue
+		}
+		values = append(values, value)
+		sum += value
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("所有模拟样本均无效，请检查假设分布")
+	}
+	sort.Float64s(values)
+
+	result := &MonteCarloValuationOutput{
+		Symbol:       symbol,
+		Currency:     latest.Currency,
+		CurrentPrice: currentPrice,
+		P10:          Sanitize(percentile(values, 0.10)),
+		P50:          Sanitize(percentile(values, 0.50)),
+		P90:          Sanitize(percentile(values, 0.90)),
+		Mean:         SafeDiv(sum, float64(len(values))),
+		Growth:       growth,
+		NetMargin:    margin,
+		ExitPE:       exitPE,
+		Years:        years,
+		DiscountRate: discountRate,
+		Simulations:  len(values),
+		Seed:         seed,
+		UpsideP10:    NaN(),
+		UpsideP50:    NaN(),
+		UpsideP90:    NaN(),
+	}
+	if currentPrice.Valid() && currentPrice > 0 {
+		result.UpsideP10 = SafeGrowth(float64(result.P10), float64(currentPrice))
+		result.UpsideP50 = SafeGrowth(float64(result.P50), float64(currentPrice))
+		result.UpsideP90 = SafeGrowth(float64(result.P90), float64(currentPrice))
+	}
+	return result, nil
+}
+
+// resolveDistribution 合并用户提供的分布与默认分布：正态分布未提供的字段使用默认值；
+// 均匀和三角分布必须给出上下限，三角分布的众数默认为上下限的中点；区间无效时返回错误而不是猜测
+func resolveDistribution(name string, given *DistributionInput, fallback Distribution) (Distribution, error) {
+	if given == nil {
+		return fallback, nil
+	}
+	value := func(v *float64, def float64) float64 {
+		if v == nil {
+			return def
+		}
+		return *v
+	}
+
+	d := Distribution{Type: strings.ToLower(given.Type)}
+	if d.Type == "" {
+		d.Type = fallback.Type
+	}
+	switch d.Type {
+	case "normal":
+		d.Mean = value(given.Mean, fallback.Mean)
+		d.StdDev = value(given.StdDev, fallback.StdDev)
+		d.Min = value(given.Min, fallback.Min)
+		d.Max = value(given.Max, fallback.Max)
+		if d.StdDev <= 0 {
+			return Distribution{}, fmt.Errorf("%s 的标准差必须大于0，当前为 %g", name, d.StdDev)
+		}
+	case "uniform", "triangular":
+		if given.Min == nil || given.Max == nil {
+			return Distribution{}, fmt.Errorf("%s 使用 %s 分布时必须提供 min 和 max", name, d.Type)
+		}
+		d.Min, d.Max = *given.Min, *given.Max
+		d.Mean = value(given.Mean, (d.Min+d.Max)/2)
+	default:
+		return Distribution{}, fmt.Errorf("%s 的分布类型 %q 无效，可选值为 normal、uniform、triangular", name, given.Type)
+	}
+	if d.Min >= d.Max {
+		return Distribution{}, fmt.Errorf("%s 的区间无效：下限 %g 必须小于上限 %g", name, d.Min, d.Max)
+	}
+	if d.Type == "triangular" && (d.Mean < d.Min || d.Mean > d.Max) {
+		return Distribution{}, fmt.Errorf("%s 的众数 %g 不在区间 [%g, %g] 内", name, d.Mean, d.Min, d.Max)
+	}
+	return d, nil
+}
+
+// sampleDistribution 按分布抽取一个样本
+func sampleDistribution(rng *rand.Rand, d Distribution) float64 {
+	switch d.Type {
+	case "uniform":
+		return d.Min + rng.Float64()*(d.Max-d.Min)
+	case "triangular":
+		// 逆变换采样，区间和众数已由 resolveDistribution 校验
+		u := rng.Float64()
+		cut := (d.Mean - d.Min) / (d.Max - d.Min)
+		if u < cut {
+			return d.Min + math.Sqrt(u*(d.Max-d.Min)*(d.Mean-d.Min))
+		}
+		return d.Max - math.Sqrt((1-u)*(d.Max-d.Min)*(d.Max-d.Mean))
+	default:
+		return clamp(d.Mean+rng.NormFloat64()*d.StdDev, d.Min, d.Max)
+	}
+}
+
+// percentile 计算已排序样本的分位数
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	index := p * float64(len(sorted)-1)
+	lower := int(math.Floor(index))
+	upper := int(math.Ceil(index))
+	if lower == upper {
+		return sorted[lower]
+	}
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(index-float64(lower))
+}
+
+// clamp 将数值限制在区间内
+func clamp(value, min, max float64) float64 {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
+
+// RenderValuationRange 将蒙特卡洛估值结果渲染为 markdown 估值区间章节
+func RenderValuationRange(result *MonteCarloValuationOutput, format NumberFormat) string {
+	var sb strings.Builder
+	sb.WriteString("## 📐 估值区间（蒙特卡洛模拟）\n\n")
+	sb.WriteString("| 分位 | 每股价值 | 相对当前价格 |\n")
+	sb.WriteString("|------|----------|--------------|\n")
+	sb.WriteString(fmt.Sprintf("| P10（悲观） | %s | %s |\n", format.Money(float64(result.P10), result.Currency), (result.UpsideP10 * 100).Sprintf("%+.1f%%")))
+	sb.WriteString(fmt.Sprintf("| P50（中性） | %s | %s |\n", format.Money(float64(result.P50), result.Currency), (result.UpsideP50 * 100).Sprintf("%+.1f%%")))
+	sb.WriteString(fmt.Sprintf("| P90（乐观） | %s | %s |\n", format.Money(float64(result.P90), result.Currency), (result.UpsideP90 * 100).Sprintf("%+.1f%%")))
+	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf("- 当前价格（由EPS×P/E推算）: %s\n", format.Money(float64(result.CurrentPrice), result.Currency)))
+	sb.WriteString(fmt.Sprintf("- 营收增长率: %s\n", describeDistribution(result.Growth, true)))
+	sb.WriteString(fmt.Sprintf("- 净利率: %s\n", describeDistribution(result.NetMargin, true)))
+	sb.WriteString(fmt.Sprintf("- 退出市盈率: %s\n", describeDistribution(result.ExitPE, false)))
+	sb.WriteString(fmt.Sprintf("- 预测年数: %d 年，折现率: %.1f%%，模拟次数: %d\n", result.Years, result.DiscountRate*100, result.Simulations))
+	return sb.String()
+}
+
+// describeDistribution 生成分布的简短描述
+func describeDistribution(d Distribution, percent bool) string {
+	scale, unit := 1.0, ""
+	if percent {
+		scale, unit = 100, "%"
+	}
+	switch d.Type {
+	case "uniform":
+		return fmt.Sprintf("均匀分布 [%.1f%s, %.1f%s]", d.Min*scale, unit, d.Max*scale, unit)
+	case "triangular":
+		return fmt.Sprintf("三角分布 [%.1f%s, %.1f%s]，众数 %.1f%s", d.Min*scale, unit, d.Max*scale, unit, d.Mean*scale, unit)
+	default:
+		return fmt.Sprintf("正态分布 均值 %.1f%s，标准差 %.1f%s", d.Mean*scale, unit, d.StdDev*scale, unit)
+	}
+}
+
+// saveValuationToFile 将估值结果写入输出目标
+func saveValuationToFile(valuation *MonteCarloValuationOutput) error {
+	// 生成文件名：valuation/valuation_AAPL_2025-09-25_15-04-05.json
+	timeSuffix := time.Now().Format("2006-01-02_15-04-05")
+	name := fmt.Sprintf("valuation/valuation_%s_%s.json", valuation.Symbol, timeSuffix)
+	location, err := writeJSONArtifact(name, valuation)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[MonteCarloValuationTool] 估值结果已保存到: %s", location)
+	return nil
+}
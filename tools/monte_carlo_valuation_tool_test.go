@@ -0,0 +1,48 @@
+package tools
+
+import "testing"
+
+func TestResolveDistribution(t *testing.T) {
+	fallback := Distribution{Type: "normal", Mean: 0.08, StdDev: 0.05, Min: -0.3, Max: 0.6}
+	f := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name    string
+		given   *DistributionInput
+		want    Distribution
+		wantErr bool
+	}{
+		{name: "未提供时使用默认分布", want: fallback},
+		{name: "显式的 0 均值保留", given: &DistributionInput{Mean: f(0)},
+			want: Distribution{Type: "normal", Mean: 0, StdDev: 0.05, Min: -0.3, Max: 0.6}},
+		{name: "正态分布只覆盖提供的字段", given: &DistributionInput{Type: "Normal", StdDev: f(0.1), Max: f(0.4)},
+			want: Distribution{Type: "normal", Mean: 0.08, StdDev: 0.1, Min: -0.3, Max: 0.4}},
+		{name: "三角分布众数默认为中点", given: &DistributionInput{Type: "triangular", Min: f(0), Max: f(0.2)},
+			want: Distribution{Type: "triangular", Mean: 0.1, Min: 0, Max: 0.2}},
+		{name: "均匀分布允许 0 下限", given: &DistributionInput{Type: "uniform", Min: f(0), Max: f(0.1)},
+			want: Distribution{Type: "uniform", Mean: 0.05, Min: 0, Max: 0.1}},
+		{name: "均匀分布上下限相等", given: &DistributionInput{Type: "uniform", Min: f(0.1), Max: f(0.1)}, wantErr: true},
+		{name: "三角分布上下限相等", given: &DistributionInput{Type: "triangular", Min: f(0.1), Max: f(0.1)}, wantErr: true},
+		{name: "均匀分布缺少上限", given: &DistributionInput{Type: "uniform", Min: f(0.1)}, wantErr: true},
+		{name: "三角分布众数超出区间", given: &DistributionInput{Type: "triangular", Mean: f(0.5), Min: f(0), Max: f(0.2)}, wantErr: true},
+		{name: "正态分布截断区间颠倒", given: &DistributionInput{Min: f(0.5), Max: f(0.1)}, wantErr: true},
+		{name: "标准差为 0", given: &DistributionInput{StdDev: f(0)}, wantErr: true},
+		{name: "未知分布类型", given: &DistributionInput{Type: "lognormal"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveDistribution("growth", tt.given, fallback)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("期望返回错误，得到 %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("意外的错误: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("得到 %+v，期望 %+v", got, tt.want)
+			}
+		})
+	}
+}
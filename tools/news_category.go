@@ -0,0 +1,107 @@
+package tools
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// 新闻主题分类
+const (
+	NewsTopicEarnings   = "earnings"
+	NewsTopicMergers    = "mna"
+	NewsTopicLitigation = "litigation"
+	NewsTopicRegulatory = "regulatory"
+	NewsTopicProduct    = "product"
+	NewsTopicManagement = "management"
+	NewsTopicOther      = "other"
+)
+
+// NewsTopics 所有可用的新闻主题，顺序即报告中的展示顺序
+var NewsTopics = []string{
+	NewsTopicEarnings,
+	NewsTopicMergers,
+	NewsTopicLitigation,
+	NewsTopicRegulatory,
+	NewsTopicProduct,
+	NewsTopicManagement,
+	NewsTopicOther,
+}
+
+// NewsTopicLabels 新闻主题的中文名称
+var NewsTopicLabels = map[string]string{
+	NewsTopicEarnings:   "业绩财报",
+	NewsTopicMergers:    "并购重组",
+	NewsTopicLitigation: "诉讼",
+	NewsTopicRegulatory: "监管",
+	NewsTopicProduct:    "产品业务",
+	NewsTopicManagement: "管理层",
+	NewsTopicOther:      "其他",
+}
+
+// NewsClassifier 可选的新闻分类器（如 LLM），返回与输入一一对应的主题列表
+type NewsClassifier func(ctx context.Context, news []CompanyNews) ([][]string, error)
+
+// newsTopicPatterns 基于关键词的主题规则（英文关键词按单词边界匹配，中文关键词直接匹配）
+var newsTopicPatterns = map[string]*regexp.Regexp{
+	NewsTopicEarnings:   regexp.MustCompile(`\b(earnings|quarterly results|revenue|profit|eps|guidance|beats?|miss(es|ed)?|outlook|forecast)\b|财报|业绩|营收|利润`),
+	NewsTopicMergers:    regexp.MustCompile(`\b(acquir(e|es|ed|ing)|acquisitions?|merger|merge|buyout|takeover|divest(s|ed|iture)?|spin-?off|stake in)\b|收购|并购|合并|分拆`),
+	NewsTopicLitigation: regexp.MustCompile(`\b(lawsuit|sues?|sued|suing|court|litigation|settle(s|d|ment)?|class action|jury|verdict|patent infringement)\b|诉讼|起诉|判决|和解`),
+	NewsTopicRegulatory: regexp.MustCompile(`\b(regulators?|regulatory|sec|ftc|doj|antitrust|investigation|probe|fined?|penalty|sanctions?|ban(s|ned)?|compliance|european commission)\b|监管|调查|罚款|反垄断`),
+	NewsTopicProduct:    regexp.MustCompile(`\b(launch(es|ed)?|unveil(s|ed)?|releases?|rollout|new product|product line|partnership|contract)\b|发布|推出|新品|合作`),
+	NewsTopicManagement: regexp.MustCompile(`\b(ceo|cfo|coo|chief executive|executive|resigns?|resignation|appoint(s|ed|ment)?|steps down|board of directors)\b|首席执行官|高管|辞职|任命`),
+}
+
+// CategorizeNews 基于关键词规则为新闻打上主题标签，未匹配任何规则的新闻标记为 other
+func CategorizeNews(news []CompanyNews) {
+	for i := range news {
+		news[i].Topics = matchNewsTopics(news[i])
+	}
+}
+
+// matchNewsTopics 返回新闻标题和摘要命中的主题
+func matchNewsTopics(item CompanyNews) []string {
+	text := strings.ToLower(item.Title + " " + item.Summary)
+	var topics []string
+	for _, topic := range NewsTopics {
+		pattern, ok := newsTopicPatterns[topic]
+		if ok && pattern.MatchString(text) {
+			topics = append(topics, topic)
+		}
+	}
+	if len(topics) == 0 {
+		topics = []string{NewsTopicOther}
+	}
+	return topics
+}
+
+// isValidNewsTopic 判断主题是否为已知主题
+func isValidNewsTopic(topic string) bool {
+	_, ok := NewsTopicLabels[topic]
+	return ok
+}
+
+// countNewsTopics 统计各主题的新闻数量
+func countNewsTopics(news []CompanyNews) map[string]int {
+	counts := make(map[string]int)
+	for _, item := range news {
+		for _, topic := range item.Topics {
+			counts[topic]++
+		}
+	}
+	return counts
+}
+
+// riskNews 筛选诉讼和监管相关的新闻，供风险分析使用
+func riskNews(news []CompanyNews) []CompanyNews {
+	var items []CompanyNews
+	for _, item := range news {
+		for _, topic := range item.Topics {
+			if topic == NewsTopicLitigation || topic == NewsTopicRegulatory {
+				items = append(items, item)
+				break
+			}
+		}
+	}
+	return items
+}
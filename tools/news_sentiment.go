@@ -0,0 +1,230 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 新闻情绪标签
+const (
+	SentimentPositive = "positive"
+	SentimentNegative = "negative"
+	SentimentNeutral  = "neutral"
+)
+
+// NewsSentiment 单条新闻的情绪评分
+type NewsSentiment struct {
+	Label string  `json:"label"` // positive、negative 或 neutral
+	Score float64 `json:"score"` // -1（非常负面）到 1（非常正面）
+}
+
+// NewsSentimentScorer 对一批新闻打分（通常由大模型实现），返回与输入一一对应的评分
+type NewsSentimentScorer func(ctx context.Context, news []CompanyNews) ([]NewsSentiment, error)
+
+// SentimentSummary 一组新闻的情绪汇总
+type SentimentSummary struct {
+	Scored       int       `json:"scored"`
+	Positive     int       `json:"positive"`
+	Negative     int       `json:"negative"`
+	Neutral      int       `json:"neutral"`
+	AverageScore SafeFloat `json:"average_score"`
+}
+
+// SummarizeSentiment 汇总已打分新闻的情绪分布，没有已打分新闻时返回 nil
+func SummarizeSentiment(news []CompanyNews) *SentimentSummary {
+	summary := &SentimentSummary{}
+	total := 0.0
+	for _, item := range news {
+		if item.Sentiment == nil {
+			continue
+		}
+		summary.add(*item.Sentiment)
+		total += item.Sentiment.Score
+	}
+	if summary.Scored == 0 {
+		return nil
+	}
+	summary.AverageScore = SafeFloat(total / float64(summary.Scored))
+	return summary
+}
+
+// add 计入一条评分（不更新平均分）
+func (s *SentimentSummary) add(sentiment NewsSentiment) {
+	s.Scored++
+	switch sentiment.Label {
+	case SentimentPositive:
+		s.Positive++
+	case SentimentNegative:
+		s.Negative++
+	default:
+		s.Neutral++
+	}
+}
+
+// SentimentBatcher 将新闻分批交给评分器，限制并发和请求间隔，并按文章 URL 缓存评分，
+// 大量新闻的调用次数为 ceil(未缓存条数 / 批大小)，成本可预期
+type SentimentBatcher struct {
+	scorer      NewsSentimentScorer
+	batchSize   int
+	concurrency int
+	interval    time.Duration // 相邻两批请求的最小间隔
+	cachePath   string
+
+	mu     sync.Mutex
+	cache  map[string]NewsSentiment
+	loaded bool
+
+	callMu   sync.Mutex
+	lastCall time.Time
+}
+
+// NewSentimentBatcher 创建新闻情绪批量评分器，评分缓存保存在 output/cache/news_sentiment.json
+func NewSentimentBatcher(scorer NewsSentimentScorer, batchSize, concurrency int, interval time.Duration) *SentimentBatcher {
+	if batchSize <= 0 {
+		batchSize = 20
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &SentimentBatcher{
+		scorer:      scorer,
+		batchSize:   batchSize,
+		concurrency: concurrency,
+		interval:    interval,
+		cachePath:   filepath.Join(OutputDir(), "cache", "news_sentiment.json"),
+		cache:       make(map[string]NewsSentiment),
+	}
+}
+
+// sentimentKey 缓存键，优先使用文章 URL
+func sentimentKey(item CompanyNews) string {
+	if item.URL != "" {
+		return item.URL
+	}
+	return item.Title
+}
+
+// Score 为新闻填充 Sentiment 字段，b 为 nil 时不做任何处理；
+// 某一批评分失败时只记录日志，该批新闻保持未评分
+func (b *SentimentBatcher) Score(ctx context.Context, news []CompanyNews) {
+	if b == nil || len(news) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	b.loadCache()
+	var pending []int
+	for i := range news {
+		if sentiment, ok := b.cache[sentimentKey(news[i])]; ok {
+			s := sentiment
+			news[i].Sentiment = &s
+			continue
+		}
+		pending = append(pending, i)
+	}
+	b.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	batches := (len(pending) + b.batchSize - 1) / b.batchSize
+	Logger(ctx).Printf("[NewsSentiment] 评分 %d 条新闻（缓存命中 %d 条），分 %d 批，并发 %d", len(pending), len(news)-len(pending), batches, b.concurrency)
+
+	sem := make(chan struct{}, b.concurrency)
+	var wg sync.WaitGroup
+	for start := 0; start < len(pending); start += b.batchSize {
+		end := min(start+b.batchSize, len(pending))
+		indexes := pending[start:end]
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			b.saveCache()
+			return
+		}
+		b.waitInterval()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			b.scoreBatch(ctx, news, indexes)
+		}()
+	}
+	wg.Wait()
+	b.saveCache()
+}
+
+// scoreBatch 对一批新闻评分并写入缓存，各批写入 news 的下标互不重叠
+func (b *SentimentBatcher) scoreBatch(ctx context.Context, news []CompanyNews, indexes []int) {
+	batch := make([]CompanyNews, len(indexes))
+	for i, index := range indexes {
+		batch[i] = news[index]
+	}
+	sentiments, err := b.scorer(ctx, batch)
+	if err != nil {
+		Logger(ctx).Printf("[NewsSentiment] 批量评分失败（%d 条新闻保持未评分）: %v", len(batch), err)
+		return
+	}
+	if len(sentiments) != len(batch) {
+		Logger(ctx).Printf("[NewsSentiment] 评分数量不匹配: 期望 %d，实际 %d", len(batch), len(sentiments))
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, index := range indexes {
+		sentiment := sentiments[i]
+		news[index].Sentiment = &sentiment
+		b.cache[sentimentKey(batch[i])] = sentiment
+	}
+}
+
+// waitInterval 保证相邻两批请求至少间隔 interval
+func (b *SentimentBatcher) waitInterval() {
+	b.callMu.Lock()
+	defer b.callMu.Unlock()
+	if wait := b.interval - time.Since(b.lastCall); wait > 0 {
+		time.Sleep(wait)
+	}
+	b.lastCall = time.Now()
+}
+
+// loadCache 首次使用时读取本地缓存，调用方需持有锁
+func (b *SentimentBatcher) loadCache() {
+	if b.loaded {
+		return
+	}
+	b.loaded = true
+	data, err := os.ReadFile(b.cachePath)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &b.cache); err != nil {
+		log.Printf("[NewsSentiment] 读取缓存失败，忽略缓存: %v", err)
+		b.cache = make(map[string]NewsSentiment)
+	}
+}
+
+// saveCache 将评分缓存写回本地文件
+func (b *SentimentBatcher) saveCache() {
+	b.mu.Lock()
+	data, err := json.Marshal(b.cache)
+	b.mu.Unlock()
+	if err != nil {
+		log.Printf("[NewsSentiment] 序列化缓存失败: %v", err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(b.cachePath), 0755); err != nil {
+		log.Printf("[NewsSentiment] 创建缓存目录失败: %v", err)
+		return
+	}
+	if err := os.WriteFile(b.cachePath, data, 0644); err != nil {
+		log.Printf("[NewsSentiment] 写入缓存失败: %v", err)
+	}
+}
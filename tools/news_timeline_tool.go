@@ -0,0 +1,353 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// 新闻时间线的回看窗口（月）
+const (
+	NewsTimelineMinMonths     = 6
+	NewsTimelineMaxMonths     = 12
+	NewsTimelineDefaultMonths = 12
+)
+
+// newsTimelineChunkSize 每次分块摘要的新闻条数，控制单次模型调用的提示词长度
+const newsTimelineChunkSize = 40
+
+// newsTimelineConcurrency 同时进行的分块摘要调用数
+const newsTimelineConcurrency = 3
+
+// newsTimelineSummaryRunes 分块摘要时每条新闻保留的正文摘要长度
+const newsTimelineSummaryRunes = 200
+
+// NewsTimelineEvent 时间线中的一个关键事件
+type NewsTimelineEvent struct {
+	Date  string `json:"date"` // YYYY-MM-DD，只知道月份时为 YYYY-MM
+	Event string `json:"event"`
+}
+
+// NewsDigest 一组新闻的摘要：叙述和关键事件
+type NewsDigest struct {
+	Narrative string              `json:"narrative"`
+	Events    []NewsTimelineEvent `json:"events"`
+}
+
+// Validate 检查模型返回的摘要是否完整
+func (d *NewsDigest) Validate() error {
+	if strings.TrimSpace(d.Narrative) == "" {
+		return fmt.Errorf("narrative 不能为空")
+	}
+	for i, e := range d.Events {
+		if strings.TrimSpace(e.Event) == "" {
+			return fmt.Errorf("第 %d 个事件的 event 为空", i+1)
+		}
+	}
+	return nil
+}
+
+// NewsTimelineSummarizer 分层摘要新闻（通常由大模型实现）：先逐块摘要，再把同一季度的多个分块摘要合并为季度叙述
+type NewsTimelineSummarizer interface {
+	// SummarizeChunk 摘要一块新闻，quarter 为所属季度（如 2025Q1）
+	SummarizeChunk(ctx context.Context, symbol, quarter string, news []CompanyNews) (*NewsDigest, error)
+	// MergeDigests 将同一季度的分块摘要合并为一段季度叙述，去掉重复事件
+	MergeDigests(ctx context.Context, symbol, quarter string, digests []NewsDigest) (*NewsDigest, error)
+}
+
+// QuarterNarrative 时间线中的一个季度
+type QuarterNarrative struct {
+	Quarter     string              `json:"quarter"` // 如 2025Q1
+	StartDate   string              `json:"start_date"`
+	EndDate     string              `json:"end_date"`
+	NewsCount   int                 `json:"news_count"`
+	Chunks      int                 `json:"chunks"`
+	TopicCounts map[string]int      `json:"topic_counts,omitempty"`
+	Sentiment   *SentimentSummary   `json:"sentiment,omitempty"`
+	Narrative   string              `json:"narrative,omitempty"`
+	Events      []NewsTimelineEvent `json:"events,omitempty"`
+	Headlines   []string            `json:"headlines,omitempty"` // 摘要失败时代替叙述的部分标题
+	Error       string              `json:"error,omitempty"`
+}
+
+// NewsTimelineInput 长周期新闻时间线的输入参数
+type NewsTimelineInput struct {
+	Symbol  string `json:"symbol" description:"股票代码，如 AAPL, TSLA, GOOG"`
+	Months  int    `json:"months,omitempty" description:"回看月数，6 到 12，默认 12"`
+	EndDate string `json:"end_date,omitempty" description:"结束日期，格式为 YYYY-MM-DD，如果不提供则使用当前日期"`
+}
+
+// NewsTimelineOutput 长周期新闻时间线的输出结果，季度按时间从早到晚排列
+type NewsTimelineOutput struct {
+	Symbol     string             `json:"symbol"`
+	StartDate  string             `json:"start_date"`
+	EndDate    string             `json:"end_date"`
+	Months     int                `json:"months"`
+	NewsCount  int                `json:"news_count"`
+	ModelCalls int                `json:"model_calls"`
+	Quarters   []QuarterNarrative `json:"quarters"`
+	Warnings   []string           `json:"warnings"`
+	Error      string             `json:"error,omitempty"`
+}
+
+// NewNewsTimelineTool 创建长周期新闻时间线工具
+// getNewsFunc 返回窗口内的全部新闻（分页拉取），summarizer 负责分块摘要和季度合并
+func NewNewsTimelineTool(getNewsFunc func(ctx context.Context, symbol, startDate, endDate string) ([]CompanyNews, error), summarizer NewsTimelineSummarizer) (tool.BaseTool, error) {
+	tool, err := inferTool("summarize_news_timeline",
+		fmt.Sprintf("对 %d 到 %d 个月的全部公司新闻做分层摘要：按季度分组、每 %d 条分块摘要、再合并为每个季度的叙述和关键事件，返回按季度排列的叙事时间线，用于长周期的定性分析（战略变化、反复出现的问题、管理层表态的前后对比）。",
+			NewsTimelineMinMonths, NewsTimelineMaxMonths, newsTimelineChunkSize),
+		func(ctx context.Context, req *NewsTimelineInput) (*NewsTimelineOutput, error) {
+			Logger(ctx).Printf("[NewsTimelineTool] 接收到请求: Symbol=%s, Months=%d, EndDate=%s", req.Symbol, req.Months, req.EndDate)
+
+			// 验证必需参数
+			if req.Symbol == "" {
+				Logger(ctx).Printf("[NewsTimelineTool] 错误: 股票代码为空")
+				return &NewsTimelineOutput{
+					Error: "股票代码不能为空",
+				}, nil
+			}
+			symbol := strings.ToUpper(req.Symbol)
+			months := req.Months
+			if months == 0 {
+				months = NewsTimelineDefaultMonths
+			}
+			if months < NewsTimelineMinMonths || months > NewsTimelineMaxMonths {
+				return &NewsTimelineOutput{
+					Symbol: symbol,
+					Error:  fmt.Sprintf("回看月数必须在 %d 到 %d 之间", NewsTimelineMinMonths, NewsTimelineMaxMonths),
+				}, nil
+			}
+			_, endDate, err := resolveDateWindow("", req.EndDate, 0, time.Now())
+			if err != nil {
+				return &NewsTimelineOutput{
+					Symbol: symbol,
+					Error:  err.Error(),
+				}, nil
+			}
+			end, _ := time.Parse(dateLayout, endDate)
+			startDate := end.AddDate(0, -months, 0).Format(dateLayout)
+
+			news, err := getNewsFunc(ctx, symbol, startDate, endDate)
+			if err != nil {
+				Logger(ctx).Printf("[NewsTimelineTool] API调用失败: %v", err)
+				if isFatalAPIError(err) {
+					return nil, err
+				}
+				return &NewsTimelineOutput{
+					Symbol:    symbol,
+					StartDate: startDate,
+					EndDate:   endDate,
+					Error:     fmt.Sprintf("获取新闻失败: %v", err),
+				}, nil
+			}
+			if len(news) == 0 {
+				return &NewsTimelineOutput{
+					Symbol:    symbol,
+					StartDate: startDate,
+					EndDate:   endDate,
+					Error:     "没有可用的新闻数据",
+				}, nil
+			}
+
+			result := BuildNewsTimeline(ctx, symbol, startDate, endDate, news, summarizer)
+			result.Months = months
+			if err := saveNewsTimelineToFile(result); err != nil {
+				Logger(ctx).Printf("[NewsTimelineTool] 保存文件失败: %v", err)
+				// 不返回错误，继续返回分析结果
+			}
+
+			Logger(ctx).Printf("[NewsTimelineTool] 返回响应: Symbol=%s, 新闻=%d, 季度=%d, 模型调用=%d", symbol, result.NewsCount, len(result.Quarters), result.ModelCalls)
+			return result, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return tool, nil
+}
+
+// BuildNewsTimeline 按季度分组新闻，逐块摘要后合并为季度叙述；
+// 分块摘要部分失败时用成功的部分合并并给出警告，全部失败时该季度只返回部分标题
+func BuildNewsTimeline(ctx context.Context, symbol, startDate, endDate string, news []CompanyNews, summarizer NewsTimelineSummarizer) *NewsTimelineOutput {
+	result := &NewsTimelineOutput{
+		Symbol:    symbol,
+		StartDate: startDate,
+		EndDate:   endDate,
+		Warnings:  []string{},
+	}
+	CategorizeNews(news)
+
+	byQuarter := make(map[string][]CompanyNews)
+	for _, item := range news {
+		date := newsDate(item)
+		if date < startDate || date > endDate {
+			continue
+		}
+		quarter := newsQuarter(date)
+		if quarter == "" {
+			continue
+		}
+		byQuarter[quarter] = append(byQuarter[quarter], item)
+		result.NewsCount++
+	}
+	quarters := make([]string, 0, len(byQuarter))
+	for quarter := range byQuarter {
+		quarters = append(quarters, quarter)
+	}
+	sort.Strings(quarters)
+
+	// 所有季度的分块一起排队，限制同时进行的模型调用数
+	type chunkJob struct {
+		quarter int
+		index   int
+		news    []CompanyNews
+	}
+	var jobs []chunkJob
+	digests := make([][]*NewsDigest, len(quarters))
+	for i, quarter := range quarters {
+		items := byQuarter[quarter]
+		sort.SliceStable(items, func(a, b int) bool { return items[a].DateTime < items[b].DateTime })
+		q := QuarterNarrative{
+			Quarter:     quarter,
+			StartDate:   newsDate(items[0]),
+			EndDate:     newsDate(items[len(items)-1]),
+			NewsCount:   len(items),
+			TopicCounts: make(map[string]int),
+			Sentiment:   SummarizeSentiment(items),
+		}
+		for _, item := range items {
+			for _, topic := range item.Topics {
+				q.TopicCounts[topic]++
+			}
+		}
+		for start := 0; start < len(items); start += newsTimelineChunkSize {
+			end := min(start+newsTimelineChunkSize, len(items))
+			jobs = append(jobs, chunkJob{quarter: i, index: q.Chunks, news: items[start:end]})
+			q.Chunks++
+		}
+		digests[i] = make([]*NewsDigest, q.Chunks)
+		result.Quarters = append(result.Quarters, q)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, newsTimelineConcurrency)
+	for _, job := range jobs {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			digest, err := summarizer.SummarizeChunk(ctx, symbol, quarters[job.quarter], job.news)
+			mu.Lock()
+			defer mu.Unlock()
+			result.ModelCalls++
+			if err != nil {
+				Logger(ctx).Printf("[NewsTimelineTool] %s 第 %d 块摘要失败: %v", quarters[job.quarter], job.index+1, err)
+				return
+			}
+			digests[job.quarter][job.index] = digest
+		}()
+	}
+	wg.Wait()
+
+	for i := range result.Quarters {
+		q := &result.Quarters[i]
+		var succeeded []NewsDigest
+		for _, d := range digests[i] {
+			if d != nil {
+				succeeded = append(succeeded, *d)
+			}
+		}
+		switch {
+		case len(succeeded) == 0:
+			q.Error = "该季度的新闻摘要全部失败"
+			q.Headlines = quarterHeadlines(byQuarter[q.Quarter])
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s 没有生成叙述，只返回部分标题", q.Quarter))
+			continue
+		case len(succeeded) < q.Chunks:
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s 有 %d/%d 块新闻摘要失败，叙述可能不完整", q.Quarter, q.Chunks-len(succeeded), q.Chunks))
+		}
+		if len(succeeded) == 1 {
+			q.Narrative, q.Events = succeeded[0].Narrative, succeeded[0].Events
+			continue
+		}
+		merged, err := summarizer.MergeDigests(ctx, symbol, q.Quarter, succeeded)
+		result.ModelCalls++
+		if err != nil {
+			// 合并失败时拼接各分块的叙述，事件按日期排列
+			Logger(ctx).Printf("[NewsTimelineTool] %s 季度合并失败: %v", q.Quarter, err)
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s 季度合并失败，叙述为各分块摘要的拼接", q.Quarter))
+			merged = concatDigests(succeeded)
+		}
+		q.Narrative, q.Events = merged.Narrative, merged.Events
+	}
+	return result
+}
+
+// newsQuarter 返回日期所属的季度，如 2025-05-03 为 2025Q2，日期无法解析时返回空字符串
+func newsQuarter(date string) string {
+	t, err := time.Parse(dateLayout, date)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%dQ%d", t.Year(), (int(t.Month())-1)/3+1)
+}
+
+// quarterHeadlines 摘要失败时返回的部分标题（按时间均匀抽取）
+func quarterHeadlines(news []CompanyNews) []string {
+	const limit = 8
+	step := max(1, len(news)/limit)
+	var headlines []string
+	for i := 0; i < len(news) && len(headlines) < limit; i += step {
+		headlines = append(headlines, fmt.Sprintf("%s %s", newsDate(news[i]), news[i].Title))
+	}
+	return headlines
+}
+
+// concatDigests 拼接多个分块摘要，事件按日期排列
+func concatDigests(digests []NewsDigest) *NewsDigest {
+	merged := &NewsDigest{}
+	var narratives []string
+	for _, d := range digests {
+		narratives = append(narratives, d.Narrative)
+		merged.Events = append(merged.Events, d.Events...)
+	}
+	merged.Narrative = strings.Join(narratives, "\n")
+	sort.SliceStable(merged.Events, func(a, b int) bool { return merged.Events[a].Date < merged.Events[b].Date })
+	return merged
+}
+
+// NewsTimelineText 分块摘要时单条新闻的文本：日期、来源、标题和截断后的正文摘要
+func NewsTimelineText(item CompanyNews) string {
+	text := fmt.Sprintf("%s (%s) %s", newsDate(item), item.Source, item.Title)
+	if summary := truncateText(item.Summary, newsTimelineSummaryRunes); summary != "" {
+		text += " - " + summary
+	}
+	return text
+}
+
+// saveNewsTimelineToFile 将新闻时间线写入输出目标
+func saveNewsTimelineToFile(output *NewsTimelineOutput) error {
+	// 生成文件名：news_timeline/news_timeline_AAPL_2025-09-25_15-04-05.json
+	timeSuffix := time.Now().Format("2006-01-02_15-04-05")
+	name := fmt.Sprintf("news_timeline/news_timeline_%s_%s.json", output.Symbol, timeSuffix)
+	location, err := writeJSONArtifact(name, output)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[NewsTimelineTool] 分析结果已保存到: %s", location)
+	return nil
+}
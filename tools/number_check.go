@@ -0,0 +1,402 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// 数值核对结果
+const (
+	ClaimVerified   = "一致"
+	ClaimCorrected  = "已更正"
+	ClaimMismatch   = "存疑"
+	ClaimUnverified = "无工具数据"
+)
+
+// 数值的类型，决定报告中的写法和核对容差
+const (
+	numericPercent  = iota // 比率，工具数据为小数，报告中写作百分比
+	numericMultiple        // 倍数，如 P/E、D/E、流动比率
+	numericMoney           // 大额金额，如市值
+	numericPrice           // 每股价格
+)
+
+// NumericFact 工具结果中可供核对的一个数值，比率类为小数（0.283 表示 28.3%）
+type NumericFact struct {
+	Metric string  `json:"metric"`
+	Symbol string  `json:"symbol"`
+	Period string  `json:"period,omitempty"`
+	Value  float64 `json:"value"`
+}
+
+// NumericClaim 报告中的一处数值引用及核对结果
+type NumericClaim struct {
+	Metric   string   `json:"metric"`
+	Label    string   `json:"label"`
+	Text     string   `json:"text"`               // 报告原文，如 "ROE 为 28.3%"
+	Value    float64  `json:"value"`              // 按工具数据的口径换算后的数值
+	Expected *float64 `json:"expected,omitempty"` // 工具数据中最接近的值
+	Status   string   `json:"status"`
+}
+
+// numericMetric 一个可核对指标在报告中的写法
+type numericMetric struct {
+	key     string
+	label   string
+	kind    int
+	pattern *regexp.Regexp
+}
+
+// numericValuePattern 指标名称之后的数值：名称与数值之间最多隔 12 个非数字字符（如 "（TTM）约"）
+const numericValuePattern = `[^0-9\n]{0,12}?(-?\d+(?:,\d{3})*(?:\.\d+)?)\s*(%|倍|x|X|万亿|亿|万|T\b|B\b|M\b)?`
+
+// newNumericMetric 根据指标名称的别名创建可核对指标
+func newNumericMetric(key, label string, kind int, aliases string) numericMetric {
+	return numericMetric{
+		key:     key,
+		label:   label,
+		kind:    kind,
+		pattern: regexp.MustCompile(`(?i)(` + aliases + `)` + numericValuePattern),
+	}
+}
+
+// numericMetrics 报告中核对的指标，键与 BenchmarkMetricValues 一致
+var numericMetrics = []numericMetric{
+	newNumericMetric("return_on_equity", "ROE", numericPercent, `ROE|净资产收益率`),
+	newNumericMetric("operating_margin", "营运利润率", numericPercent, `营运利润率|营业利润率|经营利润率|operating margin`),
+	newNumericMetric("net_margin", "净利率", numericPercent, `净利润率|净利率|net margin`),
+	newNumericMetric("gross_margin", "毛利率", numericPercent, `毛利率|gross margin`),
+	newNumericMetric("revenue_growth", "营收增长率", numericPercent, `营收增长率|收入增长率|营收增速|revenue growth`),
+	newNumericMetric("price_to_earnings_ratio", "P/E", numericMultiple, `P/E|\bPE\b|市盈率`),
+	newNumericMetric("price_to_book_ratio", "P/B", numericMultiple, `P/B|\bPB\b|市净率`),
+	newNumericMetric("debt_to_equity", "D/E", numericMultiple, `D/E|负债权益比|债务权益比|产权比率`),
+	newNumericMetric("current_ratio", "流动比率", numericMultiple, `流动比率|current ratio`),
+	newNumericMetric("market_cap", "市值", numericMoney, `总市值|市值|market cap(?:italization)?`),
+	newNumericMetric("p10", "P10", numericPrice, `\bP10\b`),
+	newNumericMetric("p50", "P50", numericPrice, `\bP50\b`),
+	newNumericMetric("p90", "P90", numericPrice, `\bP90\b`),
+	newNumericMetric("current_price", "当前股价", numericPrice, `当前股价|最新收盘价|现价|current price`),
+}
+
+// NumericFactsFromToolResult 从工具返回结果中提取可核对的数值，返回错误或无法解析的结果返回 nil
+func NumericFactsFromToolResult(toolName, content string) []NumericFact {
+	var facts []NumericFact
+	add := func(metric, symbol, period string, value float64) {
+		if IsFinite(value) {
+			facts = append(facts, NumericFact{Metric: metric, Symbol: symbol, Period: period, Value: value})
+		}
+	}
+	addSafe := func(metric, symbol, period string, value SafeFloat) {
+		if value.Valid() {
+			add(metric, symbol, period, float64(value))
+		}
+	}
+
+	switch toolName {
+	case "get_market_cap":
+		var output MarketCapOutput
+		if json.Unmarshal([]byte(content), &output) != nil || output.Error != "" {
+			return nil
+		}
+		add("market_cap", output.Symbol, output.Date, output.MarketCap)
+
+	case "get_financial_metrics":
+		var output FinancialMetricsOutput
+		if json.Unmarshal([]byte(content), &output) != nil || output.Error != "" {
+			return nil
+		}
+		for _, m := range output.Metrics {
+			for key, value := range BenchmarkMetricValues(m) {
+				add(key, output.Symbol, m.ReportPeriod, value)
+			}
+			if m.MarketCap > 0 {
+				add("market_cap", output.Symbol, m.ReportPeriod, m.MarketCap)
+			}
+		}
+
+	case "compare_peers":
+		var output PeerComparisonOutput
+		if json.Unmarshal([]byte(content), &output) != nil || output.Error != "" {
+			return nil
+		}
+		for _, company := range output.Companies {
+			for key, value := range company.Metrics {
+				addSafe(key, company.Symbol, company.ReportPeriod, value)
+			}
+		}
+		for _, c := range output.Comparisons {
+			addSafe(c.Metric, "可比公司中位数", "", c.PeerMedian)
+		}
+
+	case "analyze_fundamentals":
+		var output FundamentalAnalysisResponse
+		if json.Unmarshal([]byte(content), &output) != nil || output.Error != "" || output.Benchmark == nil {
+			return nil
+		}
+		for key, value := range output.Benchmark.Medians {
+			add(key, "行业中位数", output.Benchmark.UpdatedAt, value)
+		}
+
+	case "monte_carlo_valuation":
+		var output MonteCarloValuationOutput
+		if json.Unmarshal([]byte(content), &output) != nil || output.Error != "" {
+			return nil
+		}
+		addSafe("p10", output.Symbol, "", output.P10)
+		addSafe("p50", output.Symbol, "", output.P50)
+		addSafe("p90", output.Symbol, "", output.P90)
+		addSafe("current_price", output.Symbol, "", output.CurrentPrice)
+
+	case "get_price_history_stats":
+		var output PriceHistoryStats
+		if json.Unmarshal([]byte(content), &output) != nil || output.Error != "" {
+			return nil
+		}
+		add("current_price", output.Symbol, output.EndDate, output.EndPrice)
+	}
+	return facts
+}
+
+// numericMatch 报告中一处数值引用的位置
+type numericMatch struct {
+	metric     numericMetric
+	start, end int // 整个引用（指标名称到单位）的位置
+	numStart   int // 数值和单位的起始位置，更正时只替换这一段
+	claim      NumericClaim
+}
+
+// VerifyReportNumbers 将报告中的数值引用与工具数据逐一核对：与任一工具数据在容差内一致的视为一致；
+// 不一致时，若该指标在工具数据中只有一个取值则直接更正并注明原文，否则在原文后标注存疑；
+// 没有对应工具数据的引用只记录在结果中，不修改报告。返回核对后的报告和核对结果
+func VerifyReportNumbers(report string, facts []NumericFact, format NumberFormat) (string, []NumericClaim) {
+	byMetric := make(map[string][]float64)
+	for _, f := range facts {
+		byMetric[f.Metric] = append(byMetric[f.Metric], f.Value)
+	}
+
+	var matches []numericMatch
+	for _, metric := range numericMetrics {
+		for _, loc := range metric.pattern.FindAllStringSubmatchIndex(report, -1) {
+			unit := ""
+			if loc[6] >= 0 {
+				unit = report[loc[6]:loc[7]]
+			}
+			if !claimContextOK(report, loc) {
+				continue
+			}
+			value, ok := parseClaimValue(report[loc[4]:loc[5]], unit, metric.kind)
+			if !ok {
+				continue
+			}
+			matches = append(matches, numericMatch{
+				metric:   metric,
+				start:    loc[0],
+				end:      loc[1],
+				numStart: loc[4],
+				claim: NumericClaim{
+					Metric: metric.key,
+					Label:  metric.label,
+					Text:   strings.TrimSpace(report[loc[0]:loc[1]]),
+					Value:  value,
+				},
+			})
+		}
+	}
+	// 按出现位置排序，多个指标的写法重叠时只保留先出现的
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].start < matches[j].start })
+
+	var sb strings.Builder
+	var claims []NumericClaim
+	last := 0
+	for _, m := range matches {
+		if m.start < last {
+			continue
+		}
+		claim := m.claim
+		values := byMetric[m.metric.key]
+		sb.WriteString(report[last:m.start])
+		last = m.end
+
+		if len(values) == 0 {
+			claim.Status = ClaimUnverified
+			sb.WriteString(report[m.start:m.end])
+			claims = append(claims, claim)
+			continue
+		}
+		nearest := nearestValue(values, claim.Value)
+		claim.Expected = &nearest
+		switch {
+		case withinTolerance(claim.Value, nearest, m.metric.kind):
+			claim.Status = ClaimVerified
+			sb.WriteString(report[m.start:m.end])
+		case distinctCount(values, m.metric.kind) == 1:
+			claim.Status = ClaimCorrected
+			sb.WriteString(report[m.start:m.numStart])
+			sb.WriteString(formatClaimValue(nearest, m.metric.kind, format))
+			sb.WriteString(fmt.Sprintf("（已按工具数据更正，原文 %s）", strings.TrimSpace(report[m.numStart:m.end])))
+		default:
+			claim.Status = ClaimMismatch
+			sb.WriteString(report[m.start:m.end])
+			sb.WriteString("（⚠️ 与工具数据不一致）")
+		}
+		claims = append(claims, claim)
+	}
+	sb.WriteString(report[last:])
+	return sb.String(), claims
+}
+
+// claimContextOK 排除无法确定数值归属的写法：多个指标并列（如 "P10/P50/P90 100/150/200"、"P/E、P/B 分别为"），
+// 数值紧跟在字母之后（如 "FY2024"），以及数值后面是年份、数量等单位（如 "ROE 连续 5 年"）
+// loc 依次为整个引用、指标名称、数值、单位的位置
+func claimContextOK(report string, loc []int) bool {
+	if before, _ := utf8.DecodeLastRuneInString(report[:loc[2]]); before == '/' || before == '、' {
+		return false
+	}
+	after := strings.TrimLeft(report[loc[3]:], " ")
+	if strings.HasPrefix(after, "/") || strings.HasPrefix(after, "、") {
+		return false
+	}
+	if prev := report[loc[4]-1]; ('a' <= prev && prev <= 'z') || ('A' <= prev && prev <= 'Z') {
+		return false
+	}
+	if loc[6] < 0 {
+		rest := strings.TrimLeft(report[loc[5]:], " ")
+		for _, word := range []string{"年", "月", "日", "季", "家", "个", "只", "期", "次", "步"} {
+			if strings.HasPrefix(rest, word) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// parseClaimValue 将报告中的数值换算为工具数据的口径；比率类必须带 %，金额类必须带单位，否则无法判断口径，不核对
+func parseClaimValue(number, unit string, kind int) (float64, bool) {
+	value, err := strconv.ParseFloat(strings.ReplaceAll(number, ",", ""), 64)
+	if err != nil {
+		return 0, false
+	}
+	switch kind {
+	case numericPercent:
+		if unit != "%" {
+			return 0, false
+		}
+		return value / 100, true
+	case numericMultiple:
+		if unit != "" && unit != "倍" && !strings.EqualFold(unit, "x") {
+			return 0, false
+		}
+		return value, true
+	case numericMoney:
+		multipliers := map[string]float64{"万亿": 1e12, "亿": 1e8, "万": 1e4, "T": 1e12, "B": 1e9, "M": 1e6}
+		multiplier, ok := multipliers[unit]
+		if !ok {
+			return 0, false
+		}
+		return value * multiplier, true
+	case numericPrice:
+		if unit != "" {
+			return 0, false
+		}
+		return value, true
+	}
+	return 0, false
+}
+
+// withinTolerance 判断报告中的数值与工具数据是否一致，容差允许报告中的四舍五入
+func withinTolerance(claim, fact float64, kind int) bool {
+	diff := math.Abs(claim - fact)
+	switch kind {
+	case numericPercent:
+		return diff <= 0.005 || diff <= 0.03*math.Abs(fact)
+	case numericMultiple:
+		return diff <= 0.1 || diff <= 0.05*math.Abs(fact)
+	case numericMoney:
+		return diff <= 0.05*math.Abs(fact)
+	default:
+		return diff <= 0.01 || diff <= 0.02*math.Abs(fact)
+	}
+}
+
+// nearestValue 返回与 target 最接近的值
+func nearestValue(values []float64, target float64) float64 {
+	nearest := values[0]
+	for _, v := range values[1:] {
+		if math.Abs(v-target) < math.Abs(nearest-target) {
+			nearest = v
+		}
+	}
+	return nearest
+}
+
+// distinctCount 返回在容差范围内互不相同的取值个数
+func distinctCount(values []float64, kind int) int {
+	var distinct []float64
+	for _, v := range values {
+		seen := false
+		for _, d := range distinct {
+			if withinTolerance(v, d, kind) {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			distinct = append(distinct, v)
+		}
+	}
+	return len(distinct)
+}
+
+// formatClaimValue 按报告的写法格式化工具数据
+func formatClaimValue(value float64, kind int, format NumberFormat) string {
+	switch kind {
+	case numericPercent:
+		return fmt.Sprintf("%.1f%%", value*100)
+	case numericMultiple:
+		return fmt.Sprintf("%.2f", value)
+	case numericMoney:
+		return format.Compact(value)
+	default:
+		return format.Number(value, 2)
+	}
+}
+
+// RenderVerificationAppendix 将数值核对结果渲染为 markdown 附录
+func RenderVerificationAppendix(claims []NumericClaim, format NumberFormat) string {
+	var sb strings.Builder
+	sb.WriteString("## 附录：数值核对\n\n")
+	if len(claims) == 0 {
+		sb.WriteString("报告中没有识别到可核对的数值。\n")
+		return sb.String()
+	}
+	counts := make(map[string]int)
+	for _, c := range claims {
+		counts[c.Status]++
+	}
+	sb.WriteString(fmt.Sprintf("报告中的数值已与本次工具返回的数据逐一核对：%s %d 处，%s %d 处，%s %d 处，%s %d 处。\n\n",
+		ClaimVerified, counts[ClaimVerified], ClaimCorrected, counts[ClaimCorrected],
+		ClaimMismatch, counts[ClaimMismatch], ClaimUnverified, counts[ClaimUnverified]))
+	sb.WriteString("| 指标 | 报告原文 | 工具数据 | 结果 |\n")
+	sb.WriteString("|---|---|---|---|\n")
+	for _, c := range claims {
+		expected := "-"
+		if c.Expected != nil {
+			kind := numericPrice
+			for _, m := range numericMetrics {
+				if m.key == c.Metric {
+					kind = m.kind
+					break
+				}
+			}
+			expected = formatClaimValue(*c.Expected, kind, format)
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n", c.Label, strings.ReplaceAll(c.Text, "|", "\\|"), expected, c.Status))
+	}
+	return sb.String()
+}
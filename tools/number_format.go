@@ -0,0 +1,168 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 报告数字格式的区域设置
+const (
+	LocaleZhCN = "zh-CN" // 万/亿/万亿 单位
+	LocaleEnUS = "en-US" // K/M/B/T 单位
+)
+
+// DefaultLocale 报告默认使用中文数字单位
+const DefaultLocale = LocaleZhCN
+
+// currencySymbols 常见币种的货币符号，其他币种以代码作为前缀
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"CNY": "¥",
+	"RMB": "¥",
+	"HKD": "HK$",
+	"EUR": "€",
+	"GBP": "£",
+	"JPY": "JP¥",
+	"TWD": "NT$",
+}
+
+// compactUnit 大数单位
+type compactUnit struct {
+	value float64
+	name  string
+}
+
+// compactUnits 各区域设置的大数单位，从大到小排列
+var compactUnits = map[string][]compactUnit{
+	LocaleZhCN: {{1e12, "万亿"}, {1e8, "亿"}, {1e4, "万"}},
+	LocaleEnUS: {{1e12, "T"}, {1e9, "B"}, {1e6, "M"}, {1e3, "K"}},
+}
+
+// NumberFormat 按区域设置格式化报告中的数字和金额，保证各章节使用一致的单位和货币符号
+type NumberFormat struct {
+	Locale string
+}
+
+// NewNumberFormat 按区域设置创建数字格式，locale 为空时使用 DefaultLocale
+func NewNumberFormat(locale string) (NumberFormat, error) {
+	switch strings.TrimSpace(locale) {
+	case "":
+		return NumberFormat{Locale: DefaultLocale}, nil
+	case LocaleZhCN, "zh":
+		return NumberFormat{Locale: LocaleZhCN}, nil
+	case LocaleEnUS, "en":
+		return NumberFormat{Locale: LocaleEnUS}, nil
+	}
+	return NumberFormat{}, fmt.Errorf("不支持的区域设置: %s（可选 %s、%s）", locale, LocaleZhCN, LocaleEnUS)
+}
+
+// locale 零值格式使用默认区域设置
+func (f NumberFormat) locale() string {
+	if _, ok := compactUnits[f.Locale]; ok {
+		return f.Locale
+	}
+	return DefaultLocale
+}
+
+// Number 带千位分隔符的数字，如 1,234,567.89；非有限数返回 n/a
+func (f NumberFormat) Number(v float64, decimals int) string {
+	if !IsFinite(v) {
+		return NotAvailable
+	}
+	s := strconv.FormatFloat(math.Abs(v), 'f', decimals, 64)
+	intPart, fracPart, hasFrac := strings.Cut(s, ".")
+	var sb strings.Builder
+	if v < 0 && strings.Trim(s, "0.") != "" {
+		sb.WriteByte('-')
+	}
+	for i, digit := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteRune(digit)
+	}
+	if hasFrac {
+		sb.WriteString("." + fracPart)
+	}
+	return sb.String()
+}
+
+// Compact 以大数单位表示的数字，如 zh-CN 下 3.2万亿、1.5亿，en-US 下 3.2T、150M；小于最小单位时按 Number 输出
+func (f NumberFormat) Compact(v float64) string {
+	if !IsFinite(v) {
+		return NotAvailable
+	}
+	for _, unit := range compactUnits[f.locale()] {
+		if math.Abs(v) >= unit.value {
+			return f.Number(v/unit.value, 2) + unit.name
+		}
+	}
+	return f.Number(v, 2)
+}
+
+var (
+	defaultCurrencyMu sync.RWMutex
+	defaultCurrency   = "USD"
+)
+
+// SetDefaultCurrency 设置数据未标明币种时使用的默认币种，由市场配置在启动时设置；默认为 USD
+func SetDefaultCurrency(currency string) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		currency = "USD"
+	}
+	defaultCurrencyMu.Lock()
+	defer defaultCurrencyMu.Unlock()
+	defaultCurrency = currency
+}
+
+// DefaultCurrency 返回数据未标明币种时使用的默认币种
+func DefaultCurrency() string {
+	defaultCurrencyMu.RLock()
+	defer defaultCurrencyMu.RUnlock()
+	return defaultCurrency
+}
+
+// CurrencySymbol 返回币种的货币符号，币种为空时使用默认币种（见 SetDefaultCurrency）
+func CurrencySymbol(currency string) string {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		currency = DefaultCurrency()
+	}
+	if symbol, ok := currencySymbols[currency]; ok {
+		return symbol
+	}
+	return currency + " "
+}
+
+// Money 带货币符号和千位分隔符的金额，如 $1,234.56、-¥12.30
+func (f NumberFormat) Money(v float64, currency string) string {
+	return withCurrency(f.Number(v, 2), currency)
+}
+
+// MoneyCompact 带货币符号的大额金额，如 $3.20T、¥1.50亿
+func (f NumberFormat) MoneyCompact(v float64, currency string) string {
+	return withCurrency(f.Compact(v), currency)
+}
+
+// withCurrency 在数字前加货币符号，负号放在货币符号之前
+func withCurrency(formatted, currency string) string {
+	if formatted == NotAvailable {
+		return formatted
+	}
+	if strings.HasPrefix(formatted, "-") {
+		return "-" + CurrencySymbol(currency) + formatted[1:]
+	}
+	return CurrencySymbol(currency) + formatted
+}
+
+// UnitInstruction 写入提示词的数字格式约定，让模型撰写的各章节与程序渲染的表格使用相同单位
+func (f NumberFormat) UnitInstruction() string {
+	if f.locale() == LocaleEnUS {
+		return "报告中的数字格式请统一：金额带货币符号（如 $、¥、HK$），千位使用逗号分隔，大额金额使用 K/M/B/T 单位（如 $3.2B），不要混用万、亿等中文单位；比率使用百分数并保留一位小数。"
+	}
+	return "报告中的数字格式请统一：金额带货币符号（如 $、¥、HK$），千位使用逗号分隔，大额金额使用万/亿/万亿单位（如 $320亿），不要混用 K/M/B 等英文单位；比率使用百分数并保留一位小数。"
+}
@@ -0,0 +1,194 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// DefaultOutputDir 本地输出根目录的默认值，OUTPUT_DIR 可以修改
+const DefaultOutputDir = "output"
+
+// OutputSink 报告、工具中间结果和运行记录的统一写入目标
+// name 为相对输出根目录、以 / 分隔的路径（如 "report/AAPL_report.md"、"metrics/metrics_AAPL_ttm_<时间>.json"），
+// 返回实际写入位置（本地路径、s3:// 地址或 memory:// 地址），用于日志和运行记录
+type OutputSink interface {
+	// WriteReport 写入 markdown 报告
+	WriteReport(name string, content []byte) (string, error)
+	// WriteArtifact 写入工具中间结果、结构化结论等 JSON 文件
+	WriteArtifact(name string, data []byte) (string, error)
+	// WriteRunManifest 写入一次分析的运行记录，保存为 runs/<runID>.json
+	WriteRunManifest(runID string, data []byte) (string, error)
+}
+
+// RunManifestName 运行记录在输出根目录下的路径
+func RunManifestName(runID string) string {
+	return path.Join("runs", runID+".json")
+}
+
+var (
+	outputSinkMu sync.RWMutex
+	outputSink   OutputSink = NewFileSink(DefaultOutputDir)
+	outputDir               = DefaultOutputDir
+)
+
+// SetOutputDir 设置本地输出根目录，启动时在创建输出目标之前调用一次
+func SetOutputDir(dir string) {
+	outputSinkMu.Lock()
+	defer outputSinkMu.Unlock()
+	outputDir = dir
+}
+
+// OutputDir 本地输出根目录：OUTPUT_SINK=file 时的报告，以及缓存、风险登记簿等本地状态的位置
+func OutputDir() string {
+	outputSinkMu.RLock()
+	defer outputSinkMu.RUnlock()
+	return outputDir
+}
+
+// SetOutputSink 设置全局输出目标，启动时调用一次；默认写入本地 output/ 目录
+func SetOutputSink(sink OutputSink) {
+	outputSinkMu.Lock()
+	defer outputSinkMu.Unlock()
+	outputSink = sink
+}
+
+// Output 返回当前的全局输出目标
+func Output() OutputSink {
+	outputSinkMu.RLock()
+	defer outputSinkMu.RUnlock()
+	return outputSink
+}
+
+// writeJSONArtifact 将 v 序列化为缩进 JSON 并写入当前输出目标，返回写入位置
+func writeJSONArtifact(name string, v any) (string, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("JSON序列化失败: %v", err)
+	}
+	location, err := Output().WriteArtifact(name, data)
+	if err != nil {
+		return "", fmt.Errorf("写入文件失败: %v", err)
+	}
+	return location, nil
+}
+
+// FileSink 写入本地目录的输出目标
+type FileSink struct {
+	Root string
+}
+
+// NewFileSink 创建写入 root 目录的输出目标
+func NewFileSink(root string) *FileSink {
+	return &FileSink{Root: root}
+}
+
+// WriteReport 实现 OutputSink
+func (s *FileSink) WriteReport(name string, content []byte) (string, error) {
+	return s.write(name, content)
+}
+
+// WriteArtifact 实现 OutputSink
+func (s *FileSink) WriteArtifact(name string, data []byte) (string, error) {
+	return s.write(name, data)
+}
+
+// WriteRunManifest 实现 OutputSink
+func (s *FileSink) WriteRunManifest(runID string, data []byte) (string, error) {
+	return s.write(RunManifestName(runID), data)
+}
+
+func (s *FileSink) write(name string, data []byte) (string, error) {
+	filePath := filepath.Join(s.Root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return "", fmt.Errorf("创建目录失败: %v", err)
+	}
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		return "", err
+	}
+	return filePath, nil
+}
+
+// MemorySink 保存在内存中的输出目标，供嵌入方和测试直接读取输出，不落盘
+type MemorySink struct {
+	mu        sync.Mutex
+	reports   map[string][]byte
+	artifacts map[string][]byte
+	manifests map[string][]byte
+}
+
+// NewMemorySink 创建内存输出目标
+func NewMemorySink() *MemorySink {
+	return &MemorySink{
+		reports:   make(map[string][]byte),
+		artifacts: make(map[string][]byte),
+		manifests: make(map[string][]byte),
+	}
+}
+
+// WriteReport 实现 OutputSink
+func (s *MemorySink) WriteReport(name string, content []byte) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reports[name] = append([]byte(nil), content...)
+	return "memory://" + name, nil
+}
+
+// WriteArtifact 实现 OutputSink
+func (s *MemorySink) WriteArtifact(name string, data []byte) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.artifacts[name] = append([]byte(nil), data...)
+	return "memory://" + name, nil
+}
+
+// WriteRunManifest 实现 OutputSink
+func (s *MemorySink) WriteRunManifest(runID string, data []byte) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.manifests[runID] = append([]byte(nil), data...)
+	return "memory://" + RunManifestName(runID), nil
+}
+
+// Report 返回已写入的报告
+func (s *MemorySink) Report(name string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.reports[name]
+	return data, ok
+}
+
+// Artifact 返回已写入的中间结果
+func (s *MemorySink) Artifact(name string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.artifacts[name]
+	return data, ok
+}
+
+// RunManifest 返回已写入的运行记录
+func (s *MemorySink) RunManifest(runID string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.manifests[runID]
+	return data, ok
+}
+
+// Names 返回已写入的报告和中间结果路径，按字母顺序排列
+func (s *MemorySink) Names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.reports)+len(s.artifacts))
+	for name := range s.reports {
+		names = append(names, name)
+	}
+	for name := range s.artifacts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,222 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// 可比公司组的来源
+const (
+	PeerSourceConfig   = "config"   // 内置或用户配置的可比公司组
+	PeerSourceIndustry = "industry" // 按同行业自动发现
+	PeerSourceSector   = "sector"   // 同行业不足时按同板块自动发现
+	PeerSourceRequest  = "request"  // 调用时直接指定
+)
+
+// lowerIsBetterMetrics 数值越低越好的指标，其余指标数值越高越好
+var lowerIsBetterMetrics = map[string]bool{
+	"debt_to_equity":          true,
+	"price_to_earnings_ratio": true,
+	"price_to_book_ratio":     true,
+}
+
+// PeerGroup 可比公司组
+type PeerGroup struct {
+	Symbol string   `json:"symbol"`
+	Peers  []string `json:"peers"`
+	Source string   `json:"source"`
+}
+
+// PeerComparisonInput 同行对比的输入参数
+type PeerComparisonInput struct {
+	Symbol string   `json:"symbol" description:"股票代码，如 AAPL, TSLA, GOOG"`
+	Peers  []string `json:"peers,omitempty" description:"可选，直接指定可比公司代码列表；不提供时使用配置的可比公司组，未配置则按行业自动发现"`
+}
+
+// PeerCompany 单个公司的最新指标
+type PeerCompany struct {
+	Symbol       string               `json:"symbol"`
+	ReportPeriod string               `json:"report_period,omitempty"`
+	Metrics      map[string]SafeFloat `json:"metrics,omitempty"`
+	Error        string               `json:"error,omitempty"`
+}
+
+// PeerMetricComparison 目标公司单项指标与可比公司的对比
+type PeerMetricComparison struct {
+	Metric         string    `json:"metric"`
+	Value          SafeFloat `json:"value"`
+	PeerMedian     SafeFloat `json:"peer_median"`
+	Rank           int       `json:"rank"`  // 目标公司在组内的排名，1 为最好
+	Total          int       `json:"total"` // 参与排名的公司数
+	HigherIsBetter bool      `json:"higher_is_better"`
+}
+
+// PeerComparisonOutput 同行对比的输出结果
+type PeerComparisonOutput struct {
+	Symbol      string                 `json:"symbol"`
+	PeerSource  string                 `json:"peer_source"`
+	Peers       []string               `json:"peers"`
+	Companies   []PeerCompany          `json:"companies"`
+	Comparisons []PeerMetricComparison `json:"comparisons"`
+	Error       string                 `json:"error,omitempty"`
+}
+
+// NewPeerComparisonTool 创建同行对比工具
+func NewPeerComparisonTool(getPeersFunc func(symbol string) (*PeerGroup, error), getMetricsFunc func(ctx context.Context, symbol, date, period string, limit int) ([]FinancialMetrics, error)) (tool.BaseTool, error) {
+	tool, err := inferTool("compare_peers",
+		"将股票的关键财务指标（ROE、利润率、负债率、估值倍数、增长）与可比公司对比，给出组内排名和可比公司中位数。可比公司优先使用配置的组合，未配置时按行业自动发现。",
+		func(ctx context.Context, req *PeerComparisonInput) (*PeerComparisonOutput, error) {
+			Logger(ctx).Printf("[PeerComparisonTool] 接收到请求: Symbol=%s, Peers=%v", req.Symbol, req.Peers)
+
+			// 验证必需参数
+			if req.Symbol == "" {
+				Logger(ctx).Printf("[PeerComparisonTool] 错误: 股票代码为空")
+				return &PeerComparisonOutput{
+					Error: "股票代码不能为空",
+				}, nil
+			}
+			symbol := strings.ToUpper(req.Symbol)
+
+			group := &PeerGroup{Symbol: symbol, Source: PeerSourceRequest}
+			for _, peer := range req.Peers {
+				peer = strings.ToUpper(strings.TrimSpace(peer))
+				if peer != "" && peer != symbol {
+					group.Peers = append(group.Peers, peer)
+				}
+			}
+			if len(group.Peers) == 0 {
+				var err error
+				group, err = getPeersFunc(symbol)
+				if err != nil {
+					Logger(ctx).Printf("[PeerComparisonTool] 获取可比公司失败: %v", err)
+					return &PeerComparisonOutput{
+						Symbol: symbol,
+						Error:  fmt.Sprintf("获取可比公司失败: %v", err),
+					}, nil
+				}
+			}
+			Logger(ctx).Printf("[PeerComparisonTool] 可比公司: %v (来源: %s)", group.Peers, group.Source)
+
+			// 获取每家公司最新的 TTM 指标
+			date := time.Now().Format("2006-01-02")
+			companies := make([]PeerCompany, 0, len(group.Peers)+1)
+			for _, s := range append([]string{symbol}, group.Peers...) {
+				company := latestPeerCompany(ctx, getMetricsFunc, s, date)
+				if company.Error != "" {
+					Logger(ctx).Printf("[PeerComparisonTool] %s: %s", s, company.Error)
+				}
+				companies = append(companies, company)
+			}
+
+			if companies[0].Error != "" {
+				return &PeerComparisonOutput{
+					Symbol:     symbol,
+					PeerSource: group.Source,
+					Peers:      group.Peers,
+					Companies:  companies,
+					Error:      companies[0].Error,
+				}, nil
+			}
+
+			result := &PeerComparisonOutput{
+				Symbol:      symbol,
+				PeerSource:  group.Source,
+				Peers:       group.Peers,
+				Companies:   companies,
+				Comparisons: comparePeerMetrics(companies),
+			}
+
+			if err := savePeerComparisonToFile(result); err != nil {
+				Logger(ctx).Printf("[PeerComparisonTool] 保存对比结果失败: %v", err)
+			}
+
+			Logger(ctx).Printf("[PeerComparisonTool] 返回响应: Symbol=%s, 可比公司=%d, 对比指标=%d", result.Symbol, len(result.Peers), len(result.Comparisons))
+			return result, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return tool, nil
+}
+
+// latestPeerCompany 获取公司截至 date 最新一期 TTM 指标，失败时记录在 Error 中
+func latestPeerCompany(ctx context.Context, getMetricsFunc func(ctx context.Context, symbol, date, period string, limit int) ([]FinancialMetrics, error), symbol, date string) PeerCompany {
+	company := PeerCompany{Symbol: symbol}
+	metrics, err := getMetricsFunc(ctx, symbol, date, "ttm", 1)
+	switch {
+	case err != nil:
+		company.Error = fmt.Sprintf("获取财务指标失败: %v", err)
+	case len(metrics) == 0:
+		company.Error = "无财务指标数据"
+	default:
+		company.ReportPeriod = metrics[0].ReportPeriod
+		company.Metrics = make(map[string]SafeFloat)
+		for key, value := range BenchmarkMetricValues(metrics[0]) {
+			company.Metrics[key] = Sanitize(value)
+		}
+	}
+	return company
+}
+
+// comparePeerMetrics 计算目标公司（companies[0]）每项指标的组内排名和可比公司中位数
+func comparePeerMetrics(companies []PeerCompany) []PeerMetricComparison {
+	target := companies[0]
+	var comparisons []PeerMetricComparison
+	for _, key := range BenchmarkMetricKeys {
+		value, ok := target.Metrics[key]
+		if !ok || !value.Valid() {
+			continue
+		}
+
+		higherIsBetter := !lowerIsBetterMetrics[key]
+		var peerValues, all []float64
+		for _, company := range companies[1:] {
+			if v, ok := company.Metrics[key]; ok && v.Valid() {
+				peerValues = append(peerValues, float64(v))
+			}
+		}
+		all = append(append(all, float64(value)), peerValues...)
+		sort.Slice(all, func(i, j int) bool {
+			if higherIsBetter {
+				return all[i] > all[j]
+			}
+			return all[i] < all[j]
+		})
+		rank := sort.Search(len(all), func(i int) bool {
+			if higherIsBetter {
+				return all[i] <= float64(value)
+			}
+			return all[i] >= float64(value)
+		}) + 1
+
+		comparisons = append(comparisons, PeerMetricComparison{
+			Metric:         key,
+			Value:          value,
+			PeerMedian:     Sanitize(MedianOf(peerValues)),
+			Rank:           rank,
+			Total:          len(all),
+			HigherIsBetter: higherIsBetter,
+		})
+	}
+	return comparisons
+}
+
+// savePeerComparisonToFile 将同行对比结果写入输出目标
+func savePeerComparisonToFile(comparison *PeerComparisonOutput) error {
+	// 生成文件名：peers/peers_AAPL_2025-09-25_15-04-05.json
+	timeSuffix := time.Now().Format("2006-01-02_15-04-05")
+	name := fmt.Sprintf("peers/peers_%s_%s.json", comparison.Symbol, timeSuffix)
+	location, err := writeJSONArtifact(name, comparison)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[PeerComparisonTool] 对比结果已保存到: %s", location)
+	return nil
+}
@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// MaxPriceHistoryYears 长周期价格历史最多回溯的年数
+const MaxPriceHistoryYears = 20
+
+// PriceHistoryInput 长周期价格统计的输入参数
+type PriceHistoryInput struct {
+	Symbol string `json:"symbol" description:"股票代码，如 AAPL, TSLA, GOOG"`
+	Years  int    `json:"years,omitempty" description:"回溯年数，默认10年，最多20年"`
+}
+
+// PriceHistoryStats 长周期价格统计
+type PriceHistoryStats struct {
+	Symbol           string    `json:"symbol"`
+	StartDate        string    `json:"start_date"`
+	EndDate          string    `json:"end_date"`
+	TradingDays      int       `json:"trading_days"`
+	StartPrice       float64   `json:"start_price"`
+	EndPrice         float64   `json:"end_price"`
+	CAGR             SafeFloat `json:"cagr"`
+	MaxDrawdown      SafeFloat `json:"max_drawdown"`
+	AnnualVolatility SafeFloat `json:"annual_volatility"`
+	WorstMonth       SafeFloat `json:"worst_month"` // 最差的自然月收益率
+	MA50             SafeFloat `json:"ma50"`        // 最近 50 个交易日的收盘均价，数据不足时为 NaN
+	MA200            SafeFloat `json:"ma200"`       // 最近 200 个交易日的收盘均价
+	Gaps             int       `json:"gaps"`        // 超过一周没有价格数据的缺口数量
+	Error            string    `json:"error,omitempty"`
+}
+
+// NewPriceHistoryTool 创建长周期价格统计工具
+func NewPriceHistoryTool(getStatsFunc func(ctx context.Context, symbol string, years int) (*PriceHistoryStats, error)) (tool.BaseTool, error) {
+	tool, err := inferTool("get_price_history_stats",
+		"获取最长20年的日线价格历史，计算年化复合收益率（CAGR）、最大回撤、年化波动率、最差月度收益以及50日和200日均线，用于评估长期股东回报、持有风险和当前价格趋势。",
+		func(ctx context.Context, req *PriceHistoryInput) (*PriceHistoryStats, error) {
+			Logger(ctx).Printf("[PriceHistoryTool] 接收到请求: Symbol=%s, Years=%d", req.Symbol, req.Years)
+
+			// 验证必需参数
+			if req.Symbol == "" {
+				Logger(ctx).Printf("[PriceHistoryTool] 错误: 股票代码为空")
+				return &PriceHistoryStats{
+					Error: "股票代码不能为空",
+				}, nil
+			}
+
+			years := req.Years
+			if years <= 0 {
+				years = 10
+			}
+			if years > MaxPriceHistoryYears {
+				years = MaxPriceHistoryYears
+			}
+
+			symbol := strings.ToUpper(req.Symbol)
+			stats, err := getStatsFunc(ctx, symbol, years)
+			if err != nil {
+				Logger(ctx).Printf("[PriceHistoryTool] 获取价格历史失败: %v", err)
+				if isFatalAPIError(err) {
+					return nil, err
+				}
+				return &PriceHistoryStats{
+					Symbol: symbol,
+					Error:  fmt.Sprintf("获取价格历史失败: %v", err),
+				}, nil
+			}
+
+			Logger(ctx).Printf("[PriceHistoryTool] 返回响应: Symbol=%s, %s ~ %s, CAGR=%s, MaxDrawdown=%s",
+				stats.Symbol, stats.StartDate, stats.EndDate, stats.CAGR.Sprintf("%.4f"), stats.MaxDrawdown.Sprintf("%.4f"))
+			return stats, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return tool, nil
+}
@@ -0,0 +1,333 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// 目标价合理性结论
+const (
+	PriceTargetPlausible   = "plausible"   // 隐含假设在历史和可比公司范围内
+	PriceTargetStretched   = "stretched"   // 有一项隐含假设明显偏离
+	PriceTargetImplausible = "implausible" // 多项隐含假设明显偏离
+)
+
+// 目标价隐含假设的判断阈值：隐含市盈率超过可比公司中位数的 PriceTargetPeerPremium 倍，
+// 或隐含 EPS 年化增长率比历史高出 PriceTargetGrowthExcess 以上、超过 PriceTargetGrowthCeiling 时视为偏离
+const (
+	PriceTargetPeerPremium   = 1.5
+	PriceTargetGrowthExcess  = 0.10
+	PriceTargetGrowthCeiling = 0.30
+)
+
+// priceTargetHistoryYears 历史市盈率和 EPS 增长使用的年度数
+const priceTargetHistoryYears = 10
+
+// PriceTargetCheckInput 目标价合理性检查的输入参数
+type PriceTargetCheckInput struct {
+	Symbol       string   `json:"symbol" description:"股票代码，如 AAPL, TSLA, GOOG"`
+	TargetPrice  float64  `json:"target_price" description:"报告中给出的目标价（每股，与股价同币种）"`
+	HorizonYears int      `json:"horizon_years,omitempty" description:"达到目标价的年数，1 到 5，默认 1"`
+	Peers        []string `json:"peers,omitempty" description:"可比公司代码，不提供时使用配置的可比公司组或按行业自动发现"`
+}
+
+// PriceTargetCheckOutput 目标价分解和合理性检查结果
+type PriceTargetCheckOutput struct {
+	Symbol       string    `json:"symbol"`
+	Currency     string    `json:"currency"`
+	TargetPrice  float64   `json:"target_price"`
+	HorizonYears int       `json:"horizon_years"`
+	CurrentPrice SafeFloat `json:"current_price"`
+	Upside       SafeFloat `json:"upside"` // 目标价相对当前价格的涨幅
+	EPS          SafeFloat `json:"eps_ttm"`
+
+	CurrentPE SafeFloat `json:"current_pe"`
+	ImpliedPE SafeFloat `json:"implied_pe"` // 目标价 / 当前 EPS，即不考虑盈利增长时需要的市盈率
+
+	HistoricalPEMin    SafeFloat `json:"historical_pe_min"`
+	HistoricalPEMedian SafeFloat `json:"historical_pe_median"`
+	HistoricalPEMax    SafeFloat `json:"historical_pe_max"`
+	HistoryYears       int       `json:"history_years"`
+	PeerPEMedian       SafeFloat `json:"peer_pe_median"`
+	Peers              []string  `json:"peers,omitempty"`
+
+	ExitPE              SafeFloat `json:"exit_pe"`               // 推算隐含增长时假设的退出市盈率（历史中位数，缺失时用可比公司中位数）
+	ImpliedEPSGrowth    SafeFloat `json:"implied_eps_growth"`    // 按退出市盈率达到目标价所需的 EPS 年化增长率
+	HistoricalEPSGrowth SafeFloat `json:"historical_eps_growth"` // 历史 EPS 年化增长率
+
+	Verdict string   `json:"verdict"`
+	Flags   []string `json:"flags"`
+	Notes   []string `json:"notes"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// NewPriceTargetCheckTool 创建目标价反推与合理性检查工具
+// getPriceFunc 返回最新收盘价，getMetricsFunc 获取财务指标，getPeersFunc 返回可比公司组
+func NewPriceTargetCheckTool(getPriceFunc func(ctx context.Context, symbol string) (float64, error), getMetricsFunc func(ctx context.Context, symbol, date, period string, limit int) ([]FinancialMetrics, error), getPeersFunc func(symbol string) (*PeerGroup, error)) (tool.BaseTool, error) {
+	tool, err := inferTool("check_price_target",
+		"将报告给出的目标价分解为隐含市盈率和隐含 EPS 增长率，并与公司历史市盈率区间、历史 EPS 增长和可比公司市盈率中位数比较，给出合理性结论（plausible/stretched/implausible）。在确定目标价后调用，检查结果会附加到报告末尾。",
+		func(ctx context.Context, req *PriceTargetCheckInput) (*PriceTargetCheckOutput, error) {
+			Logger(ctx).Printf("[PriceTargetTool] 接收到请求: Symbol=%s, TargetPrice=%.2f, HorizonYears=%d", req.Symbol, req.TargetPrice, req.HorizonYears)
+
+			// 验证必需参数
+			if req.Symbol == "" {
+				Logger(ctx).Printf("[PriceTargetTool] 错误: 股票代码为空")
+				return &PriceTargetCheckOutput{
+					Error: "股票代码不能为空",
+				}, nil
+			}
+			symbol := strings.ToUpper(req.Symbol)
+			if req.TargetPrice <= 0 || !IsFinite(req.TargetPrice) {
+				return &PriceTargetCheckOutput{
+					Symbol: symbol,
+					Error:  "目标价必须是正数",
+				}, nil
+			}
+			horizon := req.HorizonYears
+			if horizon == 0 {
+				horizon = 1
+			}
+			if horizon < 1 || horizon > 5 {
+				return &PriceTargetCheckOutput{
+					Symbol: symbol,
+					Error:  "达到目标价的年数必须在 1 到 5 之间",
+				}, nil
+			}
+
+			price, err := getPriceFunc(ctx, symbol)
+			if err != nil {
+				Logger(ctx).Printf("[PriceTargetTool] 获取价格失败: %v", err)
+				if isFatalAPIError(err) {
+					return nil, err
+				}
+				return &PriceTargetCheckOutput{
+					Symbol: symbol,
+					Error:  fmt.Sprintf("获取最新价格失败: %v", err),
+				}, nil
+			}
+			date := time.Now().Format(dateLayout)
+			ttm, err := getMetricsFunc(ctx, symbol, date, "ttm", 1)
+			if err != nil {
+				Logger(ctx).Printf("[PriceTargetTool] 获取财务指标失败: %v", err)
+				if isFatalAPIError(err) {
+					return nil, err
+				}
+				return &PriceTargetCheckOutput{
+					Symbol: symbol,
+					Error:  fmt.Sprintf("获取财务指标失败: %v", err),
+				}, nil
+			}
+			if len(ttm) == 0 {
+				return &PriceTargetCheckOutput{
+					Symbol: symbol,
+					Error:  "没有可用的财务指标数据",
+				}, nil
+			}
+			// 历史和可比公司数据缺失时仍可给出部分结论
+			annual, err := getMetricsFunc(ctx, symbol, date, "annual", priceTargetHistoryYears)
+			if err != nil {
+				if isFatalAPIError(err) {
+					return nil, err
+				}
+				Logger(ctx).Printf("[PriceTargetTool] 获取年度指标失败，不做历史比较: %v", err)
+			}
+
+			group := &PeerGroup{Symbol: symbol, Source: PeerSourceRequest}
+			for _, peer := range req.Peers {
+				peer = strings.ToUpper(strings.TrimSpace(peer))
+				if peer != "" && peer != symbol {
+					group.Peers = append(group.Peers, peer)
+				}
+			}
+			if len(group.Peers) == 0 {
+				if peers, err := getPeersFunc(symbol); err != nil {
+					Logger(ctx).Printf("[PriceTargetTool] 获取可比公司失败，不做可比公司比较: %v", err)
+				} else if peers != nil {
+					group = peers
+				}
+			}
+			var peerPE []float64
+			for _, peer := range group.Peers {
+				company := latestPeerCompany(ctx, getMetricsFunc, peer, date)
+				if pe, ok := company.Metrics["price_to_earnings_ratio"]; ok && pe.Valid() {
+					peerPE = append(peerPE, float64(pe))
+				}
+			}
+
+			result := EvaluatePriceTarget(symbol, req.TargetPrice, horizon, price, ttm[0], annual, peerPE)
+			result.Peers = group.Peers
+			if err := savePriceTargetToFile(result); err != nil {
+				Logger(ctx).Printf("[PriceTargetTool] 保存文件失败: %v", err)
+				// 不返回错误，继续返回分析结果
+			}
+
+			Logger(ctx).Printf("[PriceTargetTool] 返回响应: Symbol=%s, 隐含P/E=%s, 隐含EPS增长=%s, 结论=%s",
+				symbol, result.ImpliedPE.Sprintf("%.1f"), (result.ImpliedEPSGrowth * 100).Sprintf("%.1f%%"), result.Verdict)
+			return result, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return tool, nil
+}
+
+// EvaluatePriceTarget 反推目标价的隐含市盈率和隐含 EPS 增长率，并与历史和可比公司比较
+// annual 为年度指标（最新的在前），peerPE 为可比公司的正市盈率
+func EvaluatePriceTarget(symbol string, target float64, horizon int, price float64, ttm FinancialMetrics, annual []FinancialMetrics, peerPE []float64) *PriceTargetCheckOutput {
+	result := &PriceTargetCheckOutput{
+		Symbol:       symbol,
+		Currency:     ttm.Currency,
+		TargetPrice:  target,
+		HorizonYears: horizon,
+		CurrentPrice: Sanitize(price),
+		Upside:       SafeDiv(target, price) - 1,
+		EPS:          Sanitize(ttm.EarningsPerShare),
+		CurrentPE:    NaN(),
+		ImpliedPE:    NaN(),
+
+		HistoricalPEMin:     NaN(),
+		HistoricalPEMedian:  NaN(),
+		HistoricalPEMax:     NaN(),
+		PeerPEMedian:        Sanitize(MedianOf(peerPE)),
+		ExitPE:              NaN(),
+		ImpliedEPSGrowth:    NaN(),
+		HistoricalEPSGrowth: NaN(),
+		Flags:               []string{},
+		Notes:               []string{},
+	}
+
+	var historyPE []float64
+	for _, m := range annual {
+		if m.PriceToEarningsRatio > 0 && IsFinite(m.PriceToEarningsRatio) {
+			historyPE = append(historyPE, m.PriceToEarningsRatio)
+		}
+	}
+	result.HistoryYears = len(historyPE)
+	if len(historyPE) > 0 {
+		lo, hi := historyPE[0], historyPE[0]
+		for _, pe := range historyPE {
+			lo, hi = math.Min(lo, pe), math.Max(hi, pe)
+		}
+		result.HistoricalPEMin, result.HistoricalPEMax = SafeFloat(lo), SafeFloat(hi)
+		result.HistoricalPEMedian = Sanitize(MedianOf(historyPE))
+	} else {
+		result.Notes = append(result.Notes, "缺少历史市盈率数据，未与历史区间比较")
+	}
+	if !result.PeerPEMedian.Valid() {
+		result.Notes = append(result.Notes, "缺少可比公司市盈率，未与可比公司比较")
+	}
+
+	// 历史 EPS 年化增长率：最早和最新一期 EPS 都为正时按复合增长计算
+	var epsSeries []float64
+	for _, m := range annual {
+		if m.EarningsPerShare != 0 && IsFinite(m.EarningsPerShare) {
+			epsSeries = append(epsSeries, m.EarningsPerShare)
+		}
+	}
+	if n := len(epsSeries); n >= 2 && epsSeries[0] > 0 && epsSeries[n-1] > 0 {
+		result.HistoricalEPSGrowth = Sanitize(math.Pow(epsSeries[0]/epsSeries[n-1], 1/float64(n-1)) - 1)
+	}
+
+	if ttm.EarningsPerShare <= 0 {
+		result.Notes = append(result.Notes, "最近十二个月 EPS 不为正，无法用市盈率分解目标价，请改用市销率或现金流等方法说明目标价依据")
+		result.Verdict = PriceTargetStretched
+		result.Flags = append(result.Flags, "目标价无法由当前盈利支撑（EPS 不为正）")
+		return result
+	}
+	result.CurrentPE = SafeDiv(price, ttm.EarningsPerShare)
+	result.ImpliedPE = SafeDiv(target, ttm.EarningsPerShare)
+
+	// 隐含市盈率与历史和可比公司比较
+	if result.HistoricalPEMax.Valid() && result.ImpliedPE > result.HistoricalPEMax {
+		result.Flags = append(result.Flags, fmt.Sprintf("按当前 EPS 计算的隐含市盈率 %s 倍高于过去 %d 年的最高值 %s 倍，需要盈利增长或估值扩张才能实现",
+			result.ImpliedPE.Sprintf("%.1f"), result.HistoryYears, result.HistoricalPEMax.Sprintf("%.1f")))
+	}
+	if result.PeerPEMedian.Valid() && float64(result.ImpliedPE) > float64(result.PeerPEMedian)*PriceTargetPeerPremium {
+		result.Flags = append(result.Flags, fmt.Sprintf("隐含市盈率 %s 倍超过可比公司中位数 %s 倍的 %.1f 倍",
+			result.ImpliedPE.Sprintf("%.1f"), result.PeerPEMedian.Sprintf("%.1f"), PriceTargetPeerPremium))
+	}
+
+	// 按退出市盈率反推所需的 EPS 增长
+	result.ExitPE = result.HistoricalPEMedian
+	if !result.ExitPE.Valid() {
+		result.ExitPE = result.PeerPEMedian
+	}
+	if result.ExitPE.Valid() {
+		requiredEPS := target / float64(result.ExitPE)
+		result.ImpliedEPSGrowth = Sanitize(math.Pow(requiredEPS/ttm.EarningsPerShare, 1/float64(horizon)) - 1)
+		growth := float64(result.ImpliedEPSGrowth)
+		if result.ImpliedEPSGrowth.Valid() && growth > PriceTargetGrowthCeiling {
+			result.Flags = append(result.Flags, fmt.Sprintf("以 %s 倍退出市盈率计算，%d 年内需要 EPS 年化增长 %.1f%%，超过 %.0f%%",
+				result.ExitPE.Sprintf("%.1f"), horizon, growth*100, PriceTargetGrowthCeiling*100))
+		} else if result.ImpliedEPSGrowth.Valid() && result.HistoricalEPSGrowth.Valid() &&
+			growth > float64(result.HistoricalEPSGrowth)+PriceTargetGrowthExcess {
+			result.Flags = append(result.Flags, fmt.Sprintf("隐含 EPS 年化增长 %.1f%% 比历史 %s 高出 %.0f 个百分点以上",
+				growth*100, (result.HistoricalEPSGrowth*100).Sprintf("%.1f%%"), PriceTargetGrowthExcess*100))
+		}
+	}
+
+	switch len(result.Flags) {
+	case 0:
+		result.Verdict = PriceTargetPlausible
+	case 1:
+		result.Verdict = PriceTargetStretched
+	default:
+		result.Verdict = PriceTargetImplausible
+	}
+	return result
+}
+
+// priceTargetVerdictText 合理性结论的中文描述
+var priceTargetVerdictText = map[string]string{
+	PriceTargetPlausible:   "✅ 合理：隐含假设在历史和可比公司范围内",
+	PriceTargetStretched:   "⚠️ 偏乐观：有一项隐含假设明显偏离历史或可比公司",
+	PriceTargetImplausible: "❌ 难以实现：多项隐含假设明显偏离历史和可比公司",
+}
+
+// RenderPriceTargetCheck 生成附加到报告末尾的目标价合理性检查章节
+func RenderPriceTargetCheck(result *PriceTargetCheckOutput, format NumberFormat) string {
+	var sb strings.Builder
+	sb.WriteString("## 🎯 目标价合理性检查\n\n")
+	sb.WriteString(fmt.Sprintf("- 目标价: %s（%d 年），当前价格 %s，隐含涨幅 %s\n",
+		format.Money(result.TargetPrice, result.Currency), result.HorizonYears,
+		format.Money(float64(result.CurrentPrice), result.Currency), (result.Upside * 100).Sprintf("%+.1f%%")))
+	sb.WriteString(fmt.Sprintf("- 结论: %s\n\n", priceTargetVerdictText[result.Verdict]))
+	sb.WriteString("| 假设 | 目标价隐含 | 当前 | 历史（最低 / 中位数 / 最高） | 可比公司中位数 |\n")
+	sb.WriteString("|------|------------|------|------------------------------|----------------|\n")
+	sb.WriteString(fmt.Sprintf("| 市盈率 | %s | %s | %s / %s / %s | %s |\n",
+		result.ImpliedPE.Sprintf("%.1f"), result.CurrentPE.Sprintf("%.1f"),
+		result.HistoricalPEMin.Sprintf("%.1f"), result.HistoricalPEMedian.Sprintf("%.1f"), result.HistoricalPEMax.Sprintf("%.1f"),
+		result.PeerPEMedian.Sprintf("%.1f")))
+	sb.WriteString(fmt.Sprintf("| EPS 年化增长（退出市盈率 %s） | %s | - | %s | - |\n",
+		result.ExitPE.Sprintf("%.1f"), (result.ImpliedEPSGrowth * 100).Sprintf("%.1f%%"), (result.HistoricalEPSGrowth * 100).Sprintf("%.1f%%")))
+	if len(result.Flags) > 0 {
+		sb.WriteString("\n")
+		for _, flag := range result.Flags {
+			sb.WriteString(fmt.Sprintf("- %s\n", flag))
+		}
+	}
+	for _, note := range result.Notes {
+		sb.WriteString(fmt.Sprintf("\n> 说明: %s\n", note))
+	}
+	return sb.String()
+}
+
+// savePriceTargetToFile 将目标价检查结果写入输出目标
+func savePriceTargetToFile(output *PriceTargetCheckOutput) error {
+	// 生成文件名：price_target/price_target_AAPL_2025-09-25_15-04-05.json
+	timeSuffix := time.Now().Format("2006-01-02_15-04-05")
+	name := fmt.Sprintf("price_target/price_target_%s_%s.json", output.Symbol, timeSuffix)
+	location, err := writeJSONArtifact(name, output)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[PriceTargetTool] 分析结果已保存到: %s", location)
+	return nil
+}
@@ -0,0 +1,321 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ProviderFinancialDatasets 当前工具使用的数据提供方
+const ProviderFinancialDatasets = "FinancialDatasets.ai"
+
+// ProvenanceRecord 一次工具调用所使用数据的来源记录
+type ProvenanceRecord struct {
+	Tool      string    `json:"tool"`
+	Dataset   string    `json:"dataset"`
+	Symbol    string    `json:"symbol"`
+	Provider  string    `json:"provider"`
+	FetchedAt time.Time `json:"fetched_at"`
+	Period    string    `json:"period"`        // 报告期或数据区间
+	Records   int       `json:"records"`       // 数据条数
+	URL       string    `json:"url,omitempty"` // 原始文件地址（如年报）
+}
+
+// ProvenanceFromToolResult 根据工具返回结果生成数据来源记录，而不是依赖模型在报告中自行引用
+// 纯计算类工具、返回错误或无法解析的结果返回 nil
+func ProvenanceFromToolResult(toolName, content string, fetchedAt time.Time) []ProvenanceRecord {
+	record := func(dataset, symbol, period string, records int) ProvenanceRecord {
+		return ProvenanceRecord{
+			Tool:      toolName,
+			Dataset:   dataset,
+			Symbol:    symbol,
+			Provider:  ProviderFinancialDatasets,
+			FetchedAt: fetchedAt,
+			Period:    period,
+			Records:   records,
+		}
+	}
+
+	switch toolName {
+	case "get_market_cap":
+		var output MarketCapOutput
+		if json.Unmarshal([]byte(content), &output) != nil || output.Error != "" {
+			return nil
+		}
+		return []ProvenanceRecord{record("市值", output.Symbol, output.Date, 1)}
+
+	case "get_quote":
+		var output QuoteOutput
+		if json.Unmarshal([]byte(content), &output) != nil || output.Error != "" {
+			return nil
+		}
+		var records []ProvenanceRecord
+		if output.Realtime {
+			records = append(records, record("实时行情", output.Symbol, output.QuoteTime, 1))
+		}
+		if output.Days > 0 {
+			records = append(records, record("日线价格（52 周区间）", output.Symbol, dateWindow(output.StartDate, output.EndDate), output.Days))
+		}
+		if len(output.Headlines) > 0 {
+			records = append(records, record("公司新闻（盘前/盘后异动）", output.Symbol, dateWindow(output.ReferenceDate, output.EndDate), len(output.Headlines)))
+		}
+		return records
+
+	case "get_company_profile":
+		var output CompanyProfileOutput
+		if json.Unmarshal([]byte(content), &output) != nil || output.Error != "" {
+			return nil
+		}
+		records := []ProvenanceRecord{record("公司信息", output.Symbol, fetchedAt.Format("2006-01-02"), 1)}
+		switch output.DescriptionSource {
+		case DescriptionSourceFiling:
+			r := record("10-K 年报业务章节", output.Symbol, fmt.Sprintf("%d", output.DescriptionYear), 1)
+			r.URL = output.SourceURL
+			records = append(records, r)
+		case DescriptionSourceWebsite:
+			r := record("公司官网描述", output.Symbol, fetchedAt.Format("2006-01-02"), 1)
+			r.Provider = output.SourceURL
+			r.URL = output.SourceURL
+			records = append(records, r)
+		}
+		return records
+
+	case "get_financial_metrics":
+		var output FinancialMetricsOutput
+		if json.Unmarshal([]byte(content), &output) != nil || output.Error != "" {
+			return nil
+		}
+		periods := make([]string, 0, len(output.Metrics))
+		for _, m := range output.Metrics {
+			periods = append(periods, m.ReportPeriod)
+		}
+		return []ProvenanceRecord{record("财务指标（"+output.Period+"）", output.Symbol, periodRange(periods), output.Count)}
+
+	case "get_company_news":
+		var output CompanyNewsOutput
+		if json.Unmarshal([]byte(content), &output) != nil || output.Error != "" {
+			return nil
+		}
+		return []ProvenanceRecord{record("公司新闻", output.Symbol, dateWindow(output.StartDate, output.EndDate), output.Count)}
+
+	case "assess_credit_risk":
+		var output CreditRiskOutput
+		if json.Unmarshal([]byte(content), &output) != nil || output.Error != "" {
+			return nil
+		}
+		periods := make([]string, 0, len(output.Periods))
+		for _, p := range output.Periods {
+			periods = append(periods, p.ReportPeriod)
+		}
+		return []ProvenanceRecord{record("财务报表项目（annual，资产负债表与利润表）", output.Symbol, periodRange(periods), len(output.Periods))}
+
+	case "analyze_working_capital":
+		var output WorkingCapitalOutput
+		if json.Unmarshal([]byte(content), &output) != nil || output.Error != "" {
+			return nil
+		}
+		periods := make([]string, 0, len(output.Periods))
+		for _, p := range output.Periods {
+			periods = append(periods, p.ReportPeriod)
+		}
+		return []ProvenanceRecord{record("财务报表项目（quarterly，营运资本）", output.Symbol, periodRange(periods), len(output.Periods))}
+
+	case "get_insider_trades":
+		var output InsiderTradesOutput
+		if json.Unmarshal([]byte(content), &output) != nil || output.Error != "" {
+			return nil
+		}
+		return []ProvenanceRecord{record("内部人交易", output.Symbol, dateWindow(output.StartDate, output.EndDate), output.Count)}
+
+	case "assess_management":
+		var output ManagementQualityOutput
+		if json.Unmarshal([]byte(content), &output) != nil || output.Error != "" {
+			return nil
+		}
+		periods := make([]string, 0, len(output.CapitalAllocation))
+		for _, p := range output.CapitalAllocation {
+			periods = append(periods, p.ReportPeriod)
+		}
+		return []ProvenanceRecord{
+			record("财务报表项目（annual，资本配置）", output.Symbol, periodRange(periods), len(output.CapitalAllocation)),
+			record("内部人交易与管理层新闻", output.Symbol, fmt.Sprintf("近 %d 年", managementLookbackYears), len(output.ManagementEvents)),
+		}
+
+	case "get_price_history_stats":
+		var output PriceHistoryStats
+		if json.Unmarshal([]byte(content), &output) != nil || output.Error != "" {
+			return nil
+		}
+		return []ProvenanceRecord{record("日线价格", output.Symbol, dateWindow(output.StartDate, output.EndDate), output.TradingDays)}
+
+	case "summarize_dataset":
+		var output DatasetSummary
+		if json.Unmarshal([]byte(content), &output) != nil || output.Error != "" {
+			return nil
+		}
+		dataset := "公司新闻（全量）"
+		if output.Dataset == DatasetInsiderTrades {
+			dataset = "内部人交易（全量）"
+		}
+		return []ProvenanceRecord{record(dataset, output.Symbol, dateWindow(output.StartDate, output.EndDate), output.Count)}
+
+	case "summarize_news_timeline":
+		var output NewsTimelineOutput
+		if json.Unmarshal([]byte(content), &output) != nil || output.Error != "" {
+			return nil
+		}
+		return []ProvenanceRecord{record("公司新闻（全量，按季度摘要）", output.Symbol, dateWindow(output.StartDate, output.EndDate), output.NewsCount)}
+
+	case "track_legal_risks":
+		var output LegalRiskOutput
+		if json.Unmarshal([]byte(content), &output) != nil || output.Error != "" {
+			return nil
+		}
+		return []ProvenanceRecord{record("新闻与年报法律诉讼章节", output.Symbol, dateWindow(output.StartDate, output.EndDate), len(output.Entries))}
+
+	case "track_corporate_actions":
+		var output CorporateActionsOutput
+		if json.Unmarshal([]byte(content), &output) != nil || output.Error != "" {
+			return nil
+		}
+		periods := make([]string, 0, len(output.AcquisitionSpend))
+		for _, p := range output.AcquisitionSpend {
+			periods = append(periods, p.ReportPeriod)
+		}
+		records := []ProvenanceRecord{record("并购新闻与年报业务/管理层讨论章节", output.Symbol, dateWindow(output.StartDate, output.EndDate), len(output.Sources))}
+		if len(periods) > 0 {
+			records = append(records, record("财务报表项目（annual，并购支出）", output.Symbol, periodRange(periods), len(periods)))
+		}
+		return records
+
+	case "extract_dependencies":
+		var output ConcentrationOutput
+		if json.Unmarshal([]byte(content), &output) != nil || output.Error != "" {
+			return nil
+		}
+		r := record(output.FilingType+" 年报章节", output.Symbol, fmt.Sprintf("%d", output.Year), 1)
+		r.URL = output.URL
+		return []ProvenanceRecord{r}
+
+	case "check_price_target":
+		var output PriceTargetCheckOutput
+		if json.Unmarshal([]byte(content), &output) != nil || output.Error != "" {
+			return nil
+		}
+		records := []ProvenanceRecord{record("最新价格与财务指标（ttm/annual，目标价检查）", output.Symbol, fmt.Sprintf("近 %d 年", output.HistoryYears), output.HistoryYears+1)}
+		if len(output.Peers) > 0 {
+			records = append(records, record("财务指标（ttm，可比公司市盈率）", strings.Join(output.Peers, ","), "最新", len(output.Peers)))
+		}
+		return records
+
+	case "assess_liquidity":
+		var output LiquidityOutput
+		if json.Unmarshal([]byte(content), &output) != nil || output.Error != "" {
+			return nil
+		}
+		records := []ProvenanceRecord{record("日线价格与成交量", output.Symbol, dateWindow(output.StartDate, output.EndDate), output.Days)}
+		if output.SharesOutstanding.Valid() {
+			records = append(records, record("公司概况（总股本、市值）", output.Symbol, "最新", 1))
+		}
+		if output.InsiderOwnership.Valid() {
+			records = append(records, record("内部人交易（申报持股）", output.Symbol, fmt.Sprintf("近 %d 年", managementLookbackYears), output.InsiderHolders))
+		}
+		return records
+
+	case "compare_peers":
+		var output PeerComparisonOutput
+		if json.Unmarshal([]byte(content), &output) != nil || output.Error != "" {
+			return nil
+		}
+		var records []ProvenanceRecord
+		for _, company := range output.Companies {
+			if company.Error == "" {
+				records = append(records, record("财务指标（ttm，同行对比）", company.Symbol, company.ReportPeriod, 1))
+			}
+		}
+		return records
+
+	case "analyze_competition":
+		var output CompetitiveAnalysisOutput
+		if json.Unmarshal([]byte(content), &output) != nil || output.Error != "" {
+			return nil
+		}
+		var records []ProvenanceRecord
+		for _, company := range output.Companies {
+			if company.Error == "" {
+				records = append(records, record("公司信息与财务指标（ttm，竞争格局）", company.Symbol, company.ReportPeriod, 1))
+			}
+		}
+		return records
+
+	case "find_similar_companies":
+		var output SimilarCompaniesOutput
+		if json.Unmarshal([]byte(content), &output) != nil || output.Error != "" {
+			return nil
+		}
+		return []ProvenanceRecord{record("公司画像与年报业务描述（"+output.Embedding+"）", output.Symbol, "股票池 "+output.Universe, len(output.Companies))}
+
+	case "analyze_fundamentals":
+		var output FundamentalAnalysisResponse
+		if json.Unmarshal([]byte(content), &output) != nil || output.Error != "" || output.Benchmark == nil {
+			return nil
+		}
+		b := output.Benchmark
+		r := record("行业基准中位数（"+b.Level+"）", strings.TrimSpace(b.Sector+" "+b.Industry), "更新于 "+b.UpdatedAt, b.SampleSize)
+		return []ProvenanceRecord{r}
+	}
+	return nil
+}
+
+// RenderProvenanceAppendix 将数据来源记录渲染为 markdown 附录
+func RenderProvenanceAppendix(records []ProvenanceRecord) string {
+	var sb strings.Builder
+	sb.WriteString("## 附录：数据来源\n\n")
+	if len(records) == 0 {
+		sb.WriteString("本次分析没有成功获取外部数据。\n")
+		return sb.String()
+	}
+	sb.WriteString("以下记录根据工具调用结果自动生成。\n\n")
+	sb.WriteString("| 数据集 | 股票/分组 | 提供方 | 获取时间 | 报告期/区间 | 条数 |\n")
+	sb.WriteString("|---|---|---|---|---|---|\n")
+	seen := make(map[string]bool)
+	for _, r := range records {
+		// 重复调用（如被循环检测返回缓存结果）只保留首次记录
+		key := r.Dataset + "|" + r.Symbol + "|" + r.Period
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		dataset := r.Dataset
+		if r.URL != "" {
+			dataset = fmt.Sprintf("[%s](%s)", r.Dataset, r.URL)
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s | %d |\n",
+			dataset, r.Symbol, r.Provider, r.FetchedAt.Format("2006-01-02 15:04:05"), r.Period, r.Records))
+	}
+	return sb.String()
+}
+
+// periodRange 返回报告期的最早和最晚日期，如 "2021-12-31 ~ 2024-12-31"
+func periodRange(periods []string) string {
+	if len(periods) == 0 {
+		return ""
+	}
+	sorted := append([]string(nil), periods...)
+	sort.Strings(sorted)
+	if sorted[0] == sorted[len(sorted)-1] {
+		return sorted[0]
+	}
+	return sorted[0] + " ~ " + sorted[len(sorted)-1]
+}
+
+// dateWindow 格式化日期区间，开始日期为空时只显示结束日期
+func dateWindow(startDate, endDate string) string {
+	if startDate == "" {
+		return "截至 " + endDate
+	}
+	return startDate + " ~ " + endDate
+}
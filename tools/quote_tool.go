@@ -0,0 +1,355 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// quoteRangeDays 52 周区间使用的日历天数
+const quoteRangeDays = 365
+
+// 报价所处的交易时段
+const (
+	SessionPreMarket  = "pre_market"
+	SessionRegular    = "regular"
+	SessionAfterHours = "after_hours"
+	SessionClosed     = "closed"
+)
+
+// ExtendedMoveThreshold 盘前/盘后价格相对上一个常规交易收盘价涨跌超过该比例时视为显著异动
+const ExtendedMoveThreshold = 0.03
+
+// 显著异动时获取的相关新闻：最多查询条数和返回条数
+const (
+	extendedNewsLimit     = 50
+	extendedHeadlineLimit = 5
+)
+
+// TradingSession 市场的常规交易时间，用于判断报价是否来自盘前/盘后
+type TradingSession struct {
+	Location      *time.Location
+	Open          string // 常规交易开始时间（当地时间），如 "09:30"
+	Close         string // 常规交易结束时间（当地时间），如 "16:00"
+	ExtendedHours bool   // 是否有盘前盘后交易（美股有，A股和港股没有）
+}
+
+// SessionAt 返回时间 t 所处的交易时段；没有配置交易时间时返回空字符串，节假日按交易日处理
+func (s TradingSession) SessionAt(t time.Time) string {
+	if s.Location == nil || s.Open == "" || s.Close == "" {
+		return ""
+	}
+	local := t.In(s.Location)
+	if local.Weekday() == time.Saturday || local.Weekday() == time.Sunday {
+		return SessionClosed
+	}
+	clock := local.Format("15:04")
+	switch {
+	case clock >= s.Open && clock < s.Close:
+		return SessionRegular
+	case !s.ExtendedHours:
+		return SessionClosed
+	case clock < s.Open:
+		return SessionPreMarket
+	default:
+		return SessionAfterHours
+	}
+}
+
+// QuoteHeadline 盘前/盘后异动当天的相关新闻
+type QuoteHeadline struct {
+	Title    string `json:"title"`
+	Source   string `json:"source"`
+	DateTime string `json:"datetime"`
+	URL      string `json:"url,omitempty"`
+}
+
+// QuoteSnapshot 行情源返回的实时报价
+type QuoteSnapshot struct {
+	Price            float64
+	DayChange        float64
+	DayChangePercent float64 // 百分数，如 1.25 表示 +1.25%
+	Volume           float64
+	Time             string // 报价时间
+}
+
+// QuoteInput 实时行情查询的输入参数
+type QuoteInput struct {
+	Symbol string `json:"symbol" description:"股票代码，如 AAPL, TSLA, GOOG"`
+}
+
+// QuoteOutput 实时行情查询的输出结果
+type QuoteOutput struct {
+	Symbol           string    `json:"symbol"`
+	Price            SafeFloat `json:"price"`
+	Currency         string    `json:"currency"`
+	QuoteTime        string    `json:"quote_time"`
+	Realtime         bool      `json:"realtime"` // false 表示实时行情不可用，价格为最近收盘价
+	DayChange        SafeFloat `json:"day_change"`
+	DayChangePercent SafeFloat `json:"day_change_percent"` // 比率，如 0.0125
+	Volume           SafeFloat `json:"volume"`
+	AvgVolume20      SafeFloat `json:"avg_volume_20"`
+	Week52High       SafeFloat `json:"week52_high"`
+	Week52Low        SafeFloat `json:"week52_low"`
+	RangePosition    SafeFloat `json:"range_position"` // 当前价在 52 周区间中的位置，0 为最低、1 为最高
+
+	Session          string          `json:"session,omitempty"`        // 报价所处的交易时段
+	ReferenceClose   SafeFloat       `json:"reference_close"`          // 盘前/盘后涨跌的参照：上一个常规交易收盘价
+	ReferenceDate    string          `json:"reference_date,omitempty"` // 参照收盘价的日期
+	ExtendedMove     SafeFloat       `json:"extended_move"`            // 盘前/盘后价格相对参照收盘价的涨跌，比率
+	ExtendedMoveFlag bool            `json:"extended_move_flag"`       // 涨跌超过 ExtendedMoveThreshold
+	Headlines        []QuoteHeadline `json:"headlines,omitempty"`      // 显著异动时参照收盘后的相关新闻
+
+	StartDate string `json:"start_date,omitempty"`
+	EndDate   string `json:"end_date,omitempty"`
+	Days      int    `json:"days"`
+	Note      string `json:"note,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// NewQuoteTool 创建实时行情工具：最新价、当日涨跌、成交量、52 周区间，以及盘前/盘后的显著异动和相关新闻
+// getQuoteFunc 返回实时报价（不经过数据缓存），getPricesFunc 返回日线价格用于 52 周区间，实时报价不可用时也用其最近收盘价代替；
+// session 为市场的常规交易时间，getNewsFunc 在盘前/盘后显著异动时获取参照收盘后的新闻
+func NewQuoteTool(getQuoteFunc func(ctx context.Context, symbol string) (*QuoteSnapshot, error), getPricesFunc func(ctx context.Context, symbol, startDate, endDate string) ([]LiquidityBar, error), getNewsFunc func(ctx context.Context, symbol, date string, since *string, limit int) ([]CompanyNews, error), session TradingSession) (tool.BaseTool, error) {
+	tool, err := inferTool("get_quote",
+		fmt.Sprintf("获取股票的实时行情：最新价、当日涨跌、成交量、近 20 日平均成交量和 52 周最高/最低价。估值、目标价上涨空间和市值讨论以该价格为准，不要用财务指标中的市值或每股数据推算当前股价。报价来自盘前或盘后且相对上一个收盘价涨跌超过 %.0f%% 时，extended_move_flag 为 true，并返回相关新闻标题。", ExtendedMoveThreshold*100),
+		func(ctx context.Context, req *QuoteInput) (*QuoteOutput, error) {
+			Logger(ctx).Printf("[QuoteTool] 接收到请求: Symbol=%s", req.Symbol)
+
+			// 验证必需参数
+			if req.Symbol == "" {
+				Logger(ctx).Printf("[QuoteTool] 错误: 股票代码为空")
+				return &QuoteOutput{
+					Error: "股票代码不能为空",
+				}, nil
+			}
+			symbol := strings.ToUpper(req.Symbol)
+
+			// 实时报价失败时仍可用日线数据给出最近收盘价
+			quote, err := getQuoteFunc(ctx, symbol)
+			if err != nil {
+				if isFatalAPIError(err) {
+					return nil, err
+				}
+				Logger(ctx).Printf("[QuoteTool] 获取实时报价失败，使用最近收盘价: %v", err)
+				quote = nil
+			}
+
+			now := time.Now()
+			endDate := now.Format(dateLayout)
+			startDate := now.AddDate(0, 0, -quoteRangeDays).Format(dateLayout)
+			bars, err := getPricesFunc(ctx, symbol, startDate, endDate)
+			if err != nil {
+				if isFatalAPIError(err) {
+					return nil, err
+				}
+				Logger(ctx).Printf("[QuoteTool] 获取日线价格失败，不计算 52 周区间: %v", err)
+				bars = nil
+			}
+			if quote == nil && len(bars) == 0 {
+				return &QuoteOutput{
+					Symbol: symbol,
+					Error:  "实时报价和最近的日线价格都不可用",
+				}, nil
+			}
+
+			result := EvaluateQuote(symbol, quote, bars, session, now)
+			result.StartDate, result.EndDate = startDate, endDate
+			if result.ExtendedMoveFlag {
+				// 新闻获取失败不影响行情，只是无法说明异动原因
+				since := result.ReferenceDate
+				news, err := getNewsFunc(ctx, symbol, endDate, &since, extendedNewsLimit)
+				if err != nil {
+					if isFatalAPIError(err) {
+						return nil, err
+					}
+					Logger(ctx).Printf("[QuoteTool] 获取异动相关新闻失败: %v", err)
+				}
+				result.Headlines = extendedMoveHeadlines(news)
+				if len(result.Headlines) == 0 {
+					result.Note = fmt.Sprintf("%s 以来没有找到相关新闻，异动原因不明", since)
+				}
+			}
+			if err := saveQuoteToFile(result); err != nil {
+				Logger(ctx).Printf("[QuoteTool] 保存文件失败: %v", err)
+				// 不返回错误，继续返回行情
+			}
+
+			Logger(ctx).Printf("[QuoteTool] 返回响应: Symbol=%s, 价格=%s, 涨跌=%s, 实时=%v, 时段=%s, 盘前盘后涨跌=%s, 52周区间=%s ~ %s",
+				symbol, result.Price.Sprintf("%.2f"), (result.DayChangePercent * 100).Sprintf("%+.2f%%"), result.Realtime, result.Session,
+				(result.ExtendedMove * 100).Sprintf("%+.2f%%"), result.Week52Low.Sprintf("%.2f"), result.Week52High.Sprintf("%.2f"))
+			return result, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return tool, nil
+}
+
+// EvaluateQuote 合并实时报价和近一年日线价格（按日期升序）
+// quote 为 nil 时使用最近收盘价，当日涨跌按最近两个收盘价计算；实时报价来自盘前/盘后时计算相对上一个常规交易收盘价的涨跌
+func EvaluateQuote(symbol string, quote *QuoteSnapshot, bars []LiquidityBar, session TradingSession, now time.Time) *QuoteOutput {
+	result := &QuoteOutput{
+		Symbol:           symbol,
+		Currency:         DefaultCurrency(),
+		Price:            NaN(),
+		DayChange:        NaN(),
+		DayChangePercent: NaN(),
+		Volume:           NaN(),
+		AvgVolume20:      NaN(),
+		Week52High:       NaN(),
+		Week52Low:        NaN(),
+		RangePosition:    NaN(),
+		ReferenceClose:   NaN(),
+		ExtendedMove:     NaN(),
+		Days:             len(bars),
+	}
+
+	if quote != nil {
+		result.Realtime = true
+		result.Price = Sanitize(quote.Price)
+		result.DayChange = Sanitize(quote.DayChange)
+		result.DayChangePercent = Sanitize(quote.DayChangePercent / 100)
+		result.Volume = Sanitize(quote.Volume)
+		result.QuoteTime = quote.Time
+	} else if n := len(bars); n > 0 {
+		last := bars[n-1]
+		result.Price = Sanitize(last.Close)
+		result.Volume = Sanitize(last.Volume)
+		result.QuoteTime = last.Date
+		if n > 1 {
+			result.DayChange = Sanitize(last.Close - bars[n-2].Close)
+			result.DayChangePercent = SafeDiv(last.Close, bars[n-2].Close) - 1
+		}
+		result.Note = fmt.Sprintf("实时行情不可用，价格为 %s 的收盘价", last.Date)
+	}
+
+	if len(bars) == 0 {
+		return result
+	}
+	high, low := bars[0].High, bars[0].Low
+	for _, bar := range bars {
+		high = max(high, bar.High, bar.Close)
+		low = min(low, bar.Low, bar.Close)
+	}
+	// 实时价可能突破日线数据中的区间
+	if result.Price.Valid() {
+		high = max(high, float64(result.Price))
+		low = min(low, float64(result.Price))
+	}
+	result.Week52High = Sanitize(high)
+	result.Week52Low = Sanitize(low)
+	if high > low && result.Price.Valid() {
+		result.RangePosition = Sanitize((float64(result.Price) - low) / (high - low))
+	}
+
+	recent := bars[max(0, len(bars)-20):]
+	var volume float64
+	for _, bar := range recent {
+		volume += bar.Volume
+	}
+	result.AvgVolume20 = SafeDiv(volume, float64(len(recent)))
+
+	if quote != nil {
+		evaluateExtendedMove(result, bars, session, quoteTime(quote.Time, now))
+	}
+	return result
+}
+
+// quoteTime 解析报价时间，无法解析时使用 now
+func quoteTime(value string, now time.Time) time.Time {
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return now
+}
+
+// evaluateExtendedMove 判断报价时段，盘前/盘后报价与上一个常规交易收盘价比较：
+// 盘前参照前一交易日收盘价，盘后参照当天收盘价（日线中还没有当天数据时参照最近的收盘价）
+func evaluateExtendedMove(result *QuoteOutput, bars []LiquidityBar, session TradingSession, at time.Time) {
+	result.Session = session.SessionAt(at)
+	if result.Session != SessionPreMarket && result.Session != SessionAfterHours {
+		return
+	}
+	today := at.In(session.Location).Format(dateLayout)
+	for i := len(bars) - 1; i >= 0; i-- {
+		date := bars[i].Date
+		if len(date) > len(dateLayout) {
+			date = date[:len(dateLayout)]
+		}
+		if result.Session == SessionPreMarket && date >= today {
+			continue
+		}
+		result.ReferenceClose = Sanitize(bars[i].Close)
+		result.ReferenceDate = date
+		break
+	}
+	result.ExtendedMove = SafeDiv(float64(result.Price), float64(result.ReferenceClose)) - 1
+	result.ExtendedMoveFlag = result.ExtendedMove.Valid() && math.Abs(float64(result.ExtendedMove)) >= ExtendedMoveThreshold
+}
+
+// extendedMoveHeadlines 取最新的几条新闻作为异动的相关新闻
+func extendedMoveHeadlines(news []CompanyNews) []QuoteHeadline {
+	sorted := slices.Clone(news)
+	slices.SortStableFunc(sorted, func(a, b CompanyNews) int { return strings.Compare(b.DateTime, a.DateTime) })
+	var headlines []QuoteHeadline
+	for _, item := range sorted {
+		if strings.TrimSpace(item.Title) == "" {
+			continue
+		}
+		headlines = append(headlines, QuoteHeadline{Title: item.Title, Source: item.Source, DateTime: item.DateTime, URL: item.URL})
+		if len(headlines) == extendedHeadlineLimit {
+			break
+		}
+	}
+	return headlines
+}
+
+// extendedSessionText 盘前/盘后时段的中文名称
+var extendedSessionText = map[string]string{
+	SessionPreMarket:  "盘前",
+	SessionAfterHours: "盘后",
+}
+
+// RenderExtendedMoveAlert 生成报告开头的盘前/盘后异动提示，没有显著异动时返回空字符串
+func RenderExtendedMoveAlert(result *QuoteOutput, format NumberFormat) string {
+	if result == nil || !result.ExtendedMoveFlag {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("> ⏰ **%s异动**: %s %s报价 %s，相对 %s 收盘价 %s %s（报价时间 %s）。本报告撰写时该异动可能尚未反映在财务数据和估值中。\n",
+		extendedSessionText[result.Session], result.Symbol, extendedSessionText[result.Session],
+		format.Money(float64(result.Price), result.Currency), result.ReferenceDate,
+		format.Money(float64(result.ReferenceClose), result.Currency), (result.ExtendedMove * 100).Sprintf("%+.2f%%"), result.QuoteTime))
+	if len(result.Headlines) == 0 {
+		sb.WriteString(">\n> 没有找到相关新闻，异动原因不明。\n")
+		return sb.String()
+	}
+	sb.WriteString(">\n> 相关新闻:\n")
+	for _, headline := range result.Headlines {
+		sb.WriteString(fmt.Sprintf("> - %s（%s，%s）\n", headline.Title, headline.Source, headline.DateTime))
+	}
+	return sb.String()
+}
+
+// saveQuoteToFile 将行情写入输出目标
+func saveQuoteToFile(output *QuoteOutput) error {
+	// 生成文件名：quote/quote_AAPL_2025-09-25_15-04-05.json
+	timeSuffix := time.Now().Format("2006-01-02_15-04-05")
+	name := fmt.Sprintf("quote/quote_%s_%s.json", output.Symbol, timeSuffix)
+	location, err := writeJSONArtifact(name, output)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[QuoteTool] 行情已保存到: %s", location)
+	return nil
+}
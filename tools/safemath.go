@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// NotAvailable 无法计算的数值在 JSON 和报告中的标记
+const NotAvailable = "n/a"
+
+// SafeFloat 计算得到的浮点数，NaN/Inf 序列化为 "n/a" 而不是导致 JSON 序列化失败
+type SafeFloat float64
+
+// NaN 返回表示不可用的 SafeFloat
+func NaN() SafeFloat {
+	return SafeFloat(math.NaN())
+}
+
+// Valid 判断数值是否为有限数
+func (f SafeFloat) Valid() bool {
+	return IsFinite(float64(f))
+}
+
+// Sprintf 按格式输出数值，不可用时输出 "n/a"
+func (f SafeFloat) Sprintf(format string) string {
+	if !f.Valid() {
+		return NotAvailable
+	}
+	return fmt.Sprintf(format, float64(f))
+}
+
+// MarshalJSON 实现 json.Marshaler，非有限数输出为 "n/a"
+func (f SafeFloat) MarshalJSON() ([]byte, error) {
+	if !f.Valid() {
+		return []byte(`"` + NotAvailable + `"`), nil
+	}
+	return json.Marshal(float64(f))
+}
+
+// UnmarshalJSON 实现 json.Unmarshaler，"n/a" 和 null 解析为 NaN
+func (f *SafeFloat) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" || string(data) == `"`+NotAvailable+`"` {
+		*f = NaN()
+		return nil
+	}
+	var v float64
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*f = SafeFloat(v)
+	return nil
+}
+
+// IsFinite 判断数值是否既不是 NaN 也不是 Inf
+func IsFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// SafeDiv 安全除法，分母为 0 或结果不是有限数时返回 NaN
+func SafeDiv(numerator, denominator float64) SafeFloat {
+	if denominator == 0 {
+		return NaN()
+	}
+	result := numerator / denominator
+	if !IsFinite(result) {
+		return NaN()
+	}
+	return SafeFloat(result)
+}
+
+// SafeGrowth 计算增长率 current/previous - 1，基数为 0 或负数时返回 NaN
+func SafeGrowth(current, previous float64) SafeFloat {
+	if previous <= 0 {
+		return NaN()
+	}
+	return SafeDiv(current-previous, previous)
+}
+
+// Sanitize 将非有限数转换为 SafeFloat 的不可用值
+func Sanitize(v float64) SafeFloat {
+	if !IsFinite(v) {
+		return NaN()
+	}
+	return SafeFloat(v)
+}
+
+// MedianOf 计算中位数，空切片返回 NaN
+func MedianOf(values []float64) float64 {
+	if len(values) == 0 {
+		return math.NaN()
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
@@ -0,0 +1,164 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// 工具配额的资源名称，用于 ToolQuota.Exceeded 和服务模式的任务状态
+const (
+	QuotaToolCalls   = "tool_calls"
+	QuotaToolBytes   = "tool_bytes"
+	QuotaResultBytes = "result_bytes"
+)
+
+// ToolQuota 一次分析中工具执行的配额，服务模式下每个任务一份，为 0 的项不限制；多个工具并行执行时共用
+type ToolQuota struct {
+	MaxCalls       int   // 最多执行的工具调用次数（检查点回放和重复调用的缓存结果不计入）
+	MaxBytes       int64 // 工具调用期间从数据源获取的响应累计字节数上限（由数据层经 ctx 计入，见 AddFetched）
+	MaxResultBytes int   // 单次工具结果的字节数上限，超过时丢弃结果并请 Agent 缩小查询范围
+
+	mu       sync.Mutex
+	calls    int
+	bytes    int64
+	exceeded []string // 达到上限的配额，按首次达到的顺序
+}
+
+// ToolQuotaUsage 工具配额的当前用量
+type ToolQuotaUsage struct {
+	Calls    int
+	Bytes    int64
+	Exceeded []string
+}
+
+// Usage 返回当前用量，quota 为 nil 时返回零值
+func (q *ToolQuota) Usage() ToolQuotaUsage {
+	if q == nil {
+		return ToolQuotaUsage{}
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return ToolQuotaUsage{Calls: q.calls, Bytes: q.bytes, Exceeded: append([]string(nil), q.exceeded...)}
+}
+
+// acquire 开始一次工具调用前检查次数和累计字节数，配额已用完时返回说明
+func (q *ToolQuota) acquire() (string, bool) {
+	if q == nil {
+		return "", true
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.MaxCalls > 0 && q.calls >= q.MaxCalls {
+		q.markLocked(QuotaToolCalls)
+		return fmt.Sprintf("本任务的工具调用次数已达上限 %d 次", q.MaxCalls), false
+	}
+	if q.MaxBytes > 0 && q.bytes >= q.MaxBytes {
+		q.markLocked(QuotaToolBytes)
+		return fmt.Sprintf("本任务获取的数据量已达上限 %d 字节", q.MaxBytes), false
+	}
+	q.calls++
+	return "", true
+}
+
+// AddFetched 记录从数据源读取的响应字节数（包括响应缓存命中），quota 为 nil 时不做任何事
+func (q *ToolQuota) AddFetched(n int) {
+	if q == nil || n <= 0 {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.bytes += int64(n)
+}
+
+// toolQuotaKey context 中保存工具配额的键
+type toolQuotaKey struct{}
+
+// WithToolQuota 返回带有工具配额的 context，工具内的数据请求据此计入获取的字节数
+func WithToolQuota(ctx context.Context, q *ToolQuota) context.Context {
+	return context.WithValue(ctx, toolQuotaKey{}, q)
+}
+
+// ToolQuotaFrom 返回 context 中的工具配额，没有时返回 nil
+func ToolQuotaFrom(ctx context.Context) *ToolQuota {
+	q, _ := ctx.Value(toolQuotaKey{}).(*ToolQuota)
+	return q
+}
+
+// mark 记录达到上限的配额
+func (q *ToolQuota) mark(name string) {
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.markLocked(name)
+}
+
+func (q *ToolQuota) markLocked(name string) {
+	for _, e := range q.exceeded {
+		if e == name {
+			return
+		}
+	}
+	q.exceeded = append(q.exceeded, name)
+}
+
+// sandboxedTool 隔离工具执行的包装：工具 panic 时转换为错误结果而不是让进程退出，并按配额限制调用次数和结果大小
+type sandboxedTool struct {
+	tool.InvokableTool
+	quota *ToolQuota
+}
+
+// InvokableRun 检查配额后执行工具，工具的 ctx 带上配额，数据请求读取的字节数计入配额；配额用完或结果过大时返回带 error 字段的结果，让 Agent 基于已有数据完成报告
+func (t *sandboxedTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (output string, err error) {
+	name := "unknown"
+	if info, err := t.Info(ctx); err == nil {
+		name = info.Name
+	}
+	if reason, ok := t.quota.acquire(); !ok {
+		Logger(ctx).Printf("[ToolQuota] %s，拒绝调用 %s", reason, name)
+		return errorResult(reason + "，不能再调用工具，请基于已获取的数据完成报告"), nil
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			Logger(ctx).Printf("[ToolSandbox] 工具 %s 执行时 panic: %v\n%s", name, r, debug.Stack())
+			output, err = errorResult(fmt.Sprintf("工具 %s 内部错误，本次调用没有结果，请基于已有数据继续分析", name)), nil
+		}
+	}()
+	output, err = t.InvokableTool.InvokableRun(WithToolQuota(ctx, t.quota), argumentsInJSON, opts...)
+	if err != nil {
+		return output, err
+	}
+	if t.quota != nil && t.quota.MaxResultBytes > 0 && len(output) > t.quota.MaxResultBytes {
+		t.quota.mark(QuotaResultBytes)
+		Logger(ctx).Printf("[ToolQuota] 工具 %s 的结果 %d 字节，超过单次上限 %d 字节，已丢弃", name, len(output), t.quota.MaxResultBytes)
+		output = errorResult(fmt.Sprintf("结果过大（%d 字节，单次上限 %d 字节），请缩小查询范围，如减少 limit 或缩短时间窗口", len(output), t.quota.MaxResultBytes))
+	}
+	return output, nil
+}
+
+// errorResult 只含 error 字段的工具结果
+func errorResult(message string) string {
+	output, _ := json.Marshal(map[string]string{"error": message})
+	return string(output)
+}
+
+// WithSandbox 隔离一组工具的执行：panic 转换为错误结果，quota 不为 nil 时按配额限制调用次数和结果大小
+// 应包在超时包装之内，使超时包装在独立 goroutine 中执行工具时同样能捕获 panic
+func WithSandbox(tools []tool.BaseTool, quota *ToolQuota) []tool.BaseTool {
+	wrapped := make([]tool.BaseTool, len(tools))
+	for i, t := range tools {
+		if invokable, ok := t.(tool.InvokableTool); ok {
+			wrapped[i] = &sandboxedTool{InvokableTool: invokable, quota: quota}
+			continue
+		}
+		wrapped[i] = t
+	}
+	return wrapped
+}
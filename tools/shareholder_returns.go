@@ -0,0 +1,193 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ShareholderReturnHorizons 股东回报章节统计的年数
+var ShareholderReturnHorizons = []int{1, 3, 5, 10}
+
+// shareholderReturnMaxGapDays 区间起点与第一个交易日最多相隔的天数，超过时视为价格历史不足
+const shareholderReturnMaxGapDays = 7
+
+// DividendPerShare 一个财年的每股分红，Date 为财年结束日
+type DividendPerShare struct {
+	Date   string  `json:"date"`
+	Amount float64 `json:"amount"`
+}
+
+// ShareholderReturnPeriod 一个回溯区间的股东回报
+type ShareholderReturnPeriod struct {
+	Years          int       `json:"years"`
+	StartDate      string    `json:"start_date"`
+	EndDate        string    `json:"end_date"`
+	StartPrice     SafeFloat `json:"start_price"`
+	EndPrice       SafeFloat `json:"end_price"`
+	Dividends      SafeFloat `json:"dividends_per_share"` // 区间内的每股分红合计
+	PriceReturn    SafeFloat `json:"price_return"`
+	DividendReturn SafeFloat `json:"dividend_return"` // 每股分红合计 / 起点股价
+	TotalReturn    SafeFloat `json:"total_return"`    // 股价收益 + 股息收益
+	AnnualizedTSR  SafeFloat `json:"annualized_tsr"`
+	Benchmark      SafeFloat `json:"benchmark_return"`
+	BenchmarkCAGR  SafeFloat `json:"benchmark_annualized"`
+	Excess         SafeFloat `json:"excess_annualized"` // 年化总股东回报 - 基准年化收益
+	Available      bool      `json:"available"`         // 价格历史是否覆盖整个区间
+}
+
+// ShareholderReturns 按固定区间计算的总股东回报，以及与基准的比较
+type ShareholderReturns struct {
+	Symbol    string                    `json:"symbol"`
+	Benchmark string                    `json:"benchmark"`
+	AsOf      string                    `json:"as_of"`
+	Periods   []ShareholderReturnPeriod `json:"periods"`
+	Notes     []string                  `json:"notes"`
+}
+
+// ComputeShareholderReturns 根据日收盘价（按日期升序）和财年每股分红计算 1/3/5/10 年总股东回报，
+// 并与基准的价格收益比较；benchmark 为 nil 时不做比较。分红不做再投资，财年跨越区间起点时按天数比例计入
+func ComputeShareholderReturns(prices *PriceSeries, dividends []DividendPerShare, benchmark *PriceSeries) *ShareholderReturns {
+	result := &ShareholderReturns{Symbol: prices.Symbol, Periods: []ShareholderReturnPeriod{}, Notes: []string{}}
+	if benchmark != nil {
+		result.Benchmark = benchmark.Symbol
+	}
+	n := prices.Len()
+	if n < 2 || len(prices.Close) != n {
+		result.Notes = append(result.Notes, "价格数据不足，无法计算股东回报")
+		return result
+	}
+	end := prices.Dates[n-1]
+	result.AsOf = end.Format(dateLayout)
+	endPrice := prices.Close[n-1]
+
+	// 基准只在与股票共同的交易日上比较，起止日期与股票一致
+	var stock, bench *PriceDataFrame
+	if benchmark != nil {
+		stock, bench = prices.AlignWith(&benchmark.PriceDataFrame)
+	}
+
+	sorted := append([]DividendPerShare(nil), dividends...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Date < sorted[j].Date })
+
+	for _, years := range ShareholderReturnHorizons {
+		target := end.AddDate(-years, 0, 0)
+		period := ShareholderReturnPeriod{
+			Years:          years,
+			EndDate:        result.AsOf,
+			StartPrice:     NaN(),
+			EndPrice:       Sanitize(endPrice),
+			Dividends:      NaN(),
+			PriceReturn:    NaN(),
+			DividendReturn: NaN(),
+			TotalReturn:    NaN(),
+			AnnualizedTSR:  NaN(),
+			Benchmark:      NaN(),
+			BenchmarkCAGR:  NaN(),
+			Excess:         NaN(),
+		}
+		i, ok := firstCloseOnOrAfter(&prices.PriceDataFrame, target)
+		if !ok {
+			result.Periods = append(result.Periods, period)
+			continue
+		}
+		period.Available = true
+		period.StartDate = prices.Dates[i].Format(dateLayout)
+		start := prices.Close[i]
+		period.StartPrice = Sanitize(start)
+		period.PriceReturn = SafeDiv(endPrice, start) - 1
+
+		dps := dividendsWithin(sorted, target, end)
+		period.Dividends = Sanitize(dps)
+		period.DividendReturn = SafeDiv(dps, start)
+		period.TotalReturn = period.PriceReturn + period.DividendReturn
+		period.AnnualizedTSR = annualize(period.TotalReturn, years)
+
+		if bench != nil {
+			if j, ok := firstCloseOnOrAfter(stock, target); ok && bench.Close[j] > 0 {
+				period.Benchmark = SafeDiv(bench.Close[bench.Len()-1], bench.Close[j]) - 1
+				period.BenchmarkCAGR = annualize(period.Benchmark, years)
+				period.Excess = period.AnnualizedTSR - period.BenchmarkCAGR
+			}
+		}
+		result.Periods = append(result.Periods, period)
+	}
+
+	if len(sorted) == 0 {
+		result.Notes = append(result.Notes, "没有分红数据，总股东回报只包含股价收益")
+	} else {
+		result.Notes = append(result.Notes, fmt.Sprintf("每股分红按年度现金流量表的分红总额 ÷ 期末股本估算，不考虑分红再投资；最近一个财年（%s）之后宣派的分红尚未计入", sorted[len(sorted)-1].Date))
+	}
+	if benchmark != nil {
+		result.Notes = append(result.Notes, fmt.Sprintf("基准 %s 只计算价格收益，未包含分红", benchmark.Symbol))
+	}
+	return result
+}
+
+// firstCloseOnOrAfter 返回 target 当天或之后的第一个交易日下标；第一个交易日晚于 target 太多时说明历史不足
+func firstCloseOnOrAfter(df *PriceDataFrame, target time.Time) (int, bool) {
+	i := sort.Search(df.Len(), func(i int) bool { return !df.Dates[i].Before(target) })
+	if i >= df.Len()-1 || i >= len(df.Close) {
+		return 0, false
+	}
+	if df.Dates[i].Sub(target) > shareholderReturnMaxGapDays*24*time.Hour {
+		return 0, false
+	}
+	return i, df.Close[i] > 0
+}
+
+// dividendsWithin 统计 (start, end] 内结束的财年每股分红，财年跨越 start 时按重叠天数比例计入
+func dividendsWithin(dividends []DividendPerShare, start, end time.Time) float64 {
+	var total float64
+	for _, d := range dividends {
+		fiscalEnd, err := time.Parse(dateLayout, d.Date)
+		if err != nil || !IsFinite(d.Amount) || d.Amount <= 0 || !fiscalEnd.After(start) || fiscalEnd.After(end) {
+			continue
+		}
+		fiscalStart := fiscalEnd.AddDate(-1, 0, 0)
+		if fiscalStart.Before(start) {
+			total += d.Amount * fiscalEnd.Sub(start).Hours() / fiscalEnd.Sub(fiscalStart).Hours()
+		} else {
+			total += d.Amount
+		}
+	}
+	return total
+}
+
+// annualize 将区间总收益换算为年化收益率
+func annualize(total SafeFloat, years int) SafeFloat {
+	if !total.Valid() || total <= -1 {
+		return NaN()
+	}
+	return Sanitize(math.Pow(1+float64(total), 1/float64(years)) - 1)
+}
+
+// RenderShareholderReturns 生成附加到报告末尾的股东回报章节，所有数值由程序计算
+func RenderShareholderReturns(returns *ShareholderReturns, format NumberFormat) string {
+	var sb strings.Builder
+	sb.WriteString("## 📈 股东回报历史\n\n")
+	benchmark := "基准年化"
+	if returns.Benchmark != "" {
+		benchmark = returns.Benchmark + " 年化"
+	}
+	sb.WriteString(fmt.Sprintf("截至 %s，按收盘价计算（程序计算，非模型生成）。\n\n", returns.AsOf))
+	sb.WriteString(fmt.Sprintf("| 区间 | 股价收益 | 每股分红 | 股息收益 | 总股东回报 | 年化 | %s | 年化超额 |\n", benchmark))
+	sb.WriteString("|------|----------|----------|----------|------------|------|----------|----------|\n")
+	for _, p := range returns.Periods {
+		if !p.Available {
+			sb.WriteString(fmt.Sprintf("| %d 年 | 价格历史不足 | - | - | - | - | - | - |\n", p.Years))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("| %d 年（%s 起） | %s | %s | %s | %s | %s | %s | %s |\n",
+			p.Years, p.StartDate,
+			(p.PriceReturn * 100).Sprintf("%+.1f%%"), format.Money(float64(p.Dividends), ""), (p.DividendReturn * 100).Sprintf("%+.1f%%"),
+			(p.TotalReturn * 100).Sprintf("%+.1f%%"), (p.AnnualizedTSR * 100).Sprintf("%+.1f%%"),
+			(p.BenchmarkCAGR * 100).Sprintf("%+.1f%%"), (p.Excess * 100).Sprintf("%+.1f%%")))
+	}
+	for _, note := range returns.Notes {
+		sb.WriteString(fmt.Sprintf("\n> 说明: %s\n", note))
+	}
+	return sb.String()
+}
@@ -0,0 +1,184 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// PeerSourceSimilarity 按公司画像相似度自动发现的可比公司
+const PeerSourceSimilarity = "similarity"
+
+// similarityTextWeight 综合相似度中文本嵌入（板块、行业、业务描述）的权重，其余为财务指标的权重
+const similarityTextWeight = 0.7
+
+// similarityMinMetrics 计算财务指标相似度所需的最少共同指标数
+const similarityMinMetrics = 3
+
+// similarityMaxZ 财务指标标准化后的截断值，避免个别极端估值倍数主导相似度
+const similarityMaxZ = 3.0
+
+// similarityMetricKeys 参与相似度计算的财务指标，与 BenchmarkMetricValues 的键一致
+var similarityMetricKeys = []string{
+	"return_on_equity", "debt_to_equity", "operating_margin", "net_margin", "gross_margin",
+	"current_ratio", "price_to_earnings_ratio", "price_to_book_ratio", "revenue_growth",
+}
+
+// CompanyProfile 公司画像：板块、行业和业务描述的文本嵌入，加上最新 TTM 财务指标
+type CompanyProfile struct {
+	Symbol    string             `json:"symbol"`
+	Name      string             `json:"name"`
+	Sector    string             `json:"sector"`
+	Industry  string             `json:"industry"`
+	Embedding []float64          `json:"embedding"`
+	Metrics   map[string]float64 `json:"metrics"`
+}
+
+// SimilarCompaniesInput 相似公司查询的输入参数
+type SimilarCompaniesInput struct {
+	Symbol string `json:"symbol" description:"股票代码，如 AAPL, TSLA, GOOG"`
+	Limit  int    `json:"limit,omitempty" description:"返回的相似公司数量，默认5，最多20"`
+}
+
+// SimilarCompany 一家相似公司及其相似度，相似度为 -1 到 1 的余弦相似度
+type SimilarCompany struct {
+	Symbol           string    `json:"symbol"`
+	Name             string    `json:"name"`
+	Sector           string    `json:"sector"`
+	Industry         string    `json:"industry"`
+	Similarity       SafeFloat `json:"similarity"`
+	TextSimilarity   SafeFloat `json:"text_similarity"`
+	MetricSimilarity SafeFloat `json:"metric_similarity"` // 共同指标不足时为 n/a，综合相似度只使用文本相似度
+}
+
+// SimilarCompaniesOutput 相似公司查询结果
+type SimilarCompaniesOutput struct {
+	Symbol    string           `json:"symbol"`
+	Universe  string           `json:"universe"`  // 候选股票池
+	Embedding string           `json:"embedding"` // 文本嵌入使用的模型
+	Companies []SimilarCompany `json:"companies"`
+	Error     string           `json:"error,omitempty"`
+}
+
+// RankSimilarCompanies 按综合相似度从高到低返回与 target 最相似的 limit 家公司
+// 文本相似度为嵌入向量的余弦相似度；财务指标先在全部公司中标准化再计算余弦相似度，
+// 综合相似度按 similarityTextWeight 加权
+func RankSimilarCompanies(target CompanyProfile, candidates []CompanyProfile, limit int) []SimilarCompany {
+	var pool []CompanyProfile
+	for _, c := range candidates {
+		if c.Symbol != target.Symbol {
+			pool = append(pool, c)
+		}
+	}
+	vectors := metricVectors(append([]CompanyProfile{target}, pool...))
+
+	companies := make([]SimilarCompany, 0, len(pool))
+	for i, c := range pool {
+		text := cosineSimilarity(target.Embedding, c.Embedding)
+		metric := cosineSimilarity(vectors[0], vectors[i+1])
+		combined := text
+		if IsFinite(metric) {
+			combined = similarityTextWeight*text + (1-similarityTextWeight)*metric
+		}
+		companies = append(companies, SimilarCompany{
+			Symbol:           c.Symbol,
+			Name:             c.Name,
+			Sector:           c.Sector,
+			Industry:         c.Industry,
+			Similarity:       Sanitize(combined),
+			TextSimilarity:   Sanitize(text),
+			MetricSimilarity: Sanitize(metric),
+		})
+	}
+	sort.SliceStable(companies, func(i, j int) bool {
+		return float64(companies[i].Similarity) > float64(companies[j].Similarity)
+	})
+	if limit > 0 && len(companies) > limit {
+		companies = companies[:limit]
+	}
+	return companies
+}
+
+// metricVectors 将各公司的财务指标按指标在全部公司中标准化为 z-score，缺失的指标为 NaN
+func metricVectors(profiles []CompanyProfile) [][]float64 {
+	vectors := make([][]float64, len(profiles))
+	for i := range vectors {
+		vectors[i] = make([]float64, len(similarityMetricKeys))
+	}
+	for k, key := range similarityMetricKeys {
+		values := make([]float64, len(profiles))
+		for i, p := range profiles {
+			v, ok := p.Metrics[key]
+			if !ok {
+				v = math.NaN()
+			}
+			values[i] = v
+		}
+		for i, z := range zScores(values) {
+			vectors[i][k] = math.Max(-similarityMaxZ, math.Min(similarityMaxZ, z))
+		}
+	}
+	return vectors
+}
+
+// cosineSimilarity 只使用两个向量都有效的维度计算余弦相似度，有效维度不足或长度不一致时返回 NaN
+// 嵌入向量没有缺失值，因此最少维度的要求只对财务指标向量起作用
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return math.NaN()
+	}
+	dot, normA, normB, n := 0.0, 0.0, 0.0, 0
+	for i := range a {
+		if !IsFinite(a[i]) || !IsFinite(b[i]) {
+			continue
+		}
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+		n++
+	}
+	if n < min(similarityMinMetrics, len(a)) || normA == 0 || normB == 0 {
+		return math.NaN()
+	}
+	return dot / math.Sqrt(normA*normB)
+}
+
+// NewSimilarCompaniesTool 创建相似公司查询工具
+func NewSimilarCompaniesTool(findSimilarFunc func(ctx context.Context, symbol string, limit int) (*SimilarCompaniesOutput, error)) (tool.BaseTool, error) {
+	tool, err := inferTool("find_similar_companies",
+		"基于公司画像（板块、行业、业务描述的文本嵌入和财务指标）在缓存的股票池中查找最相似的公司，可用于寻找可比公司或同类投资标的。",
+		func(ctx context.Context, req *SimilarCompaniesInput) (*SimilarCompaniesOutput, error) {
+			Logger(ctx).Printf("[SimilarCompaniesTool] 接收到请求: Symbol=%s, Limit=%d", req.Symbol, req.Limit)
+
+			if req.Symbol == "" {
+				Logger(ctx).Printf("[SimilarCompaniesTool] 错误: 股票代码为空")
+				return &SimilarCompaniesOutput{Error: "股票代码不能为空"}, nil
+			}
+			symbol := strings.ToUpper(strings.TrimSpace(req.Symbol))
+			limit := req.Limit
+			if limit <= 0 {
+				limit = 5
+			}
+			limit = min(limit, 20)
+
+			result, err := findSimilarFunc(ctx, symbol, limit)
+			if err != nil {
+				Logger(ctx).Printf("[SimilarCompaniesTool] 查找相似公司失败: %v", err)
+				if isFatalAPIError(err) {
+					return nil, err
+				}
+				return &SimilarCompaniesOutput{Symbol: symbol, Error: fmt.Sprintf("查找相似公司失败: %v", err)}, nil
+			}
+
+			Logger(ctx).Printf("[SimilarCompaniesTool] 返回响应: Symbol=%s, Universe=%s, Count=%d", result.Symbol, result.Universe, len(result.Companies))
+			return result, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return tool, nil
+}
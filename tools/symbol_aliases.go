@@ -0,0 +1,31 @@
+package tools
+
+import (
+	"strings"
+	"sync"
+)
+
+var (
+	symbolAliasesMu sync.RWMutex
+	symbolAliases   func(symbol string) []string
+)
+
+// SetSymbolAliases 设置查询股票历史代码的函数（如 META 返回 META、FB），由主程序的代码变更表在启动时设置；
+// 读取按股票代码保存的历史数据时，当前代码没有记录则依次尝试旧代码
+func SetSymbolAliases(aliases func(symbol string) []string) {
+	symbolAliasesMu.Lock()
+	defer symbolAliasesMu.Unlock()
+	symbolAliases = aliases
+}
+
+// SymbolAliases 返回与 symbol 指同一家公司的全部代码，当前代码在前；未设置时只返回 symbol 本身
+func SymbolAliases(symbol string) []string {
+	symbol = strings.ToUpper(symbol)
+	symbolAliasesMu.RLock()
+	aliases := symbolAliases
+	symbolAliasesMu.RUnlock()
+	if aliases == nil {
+		return []string{symbol}
+	}
+	return aliases(symbol)
+}
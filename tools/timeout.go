@@ -0,0 +1,67 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// timeoutTool 为单次工具调用设置执行期限的工具包装
+type timeoutTool struct {
+	tool.InvokableTool
+	timeout time.Duration
+}
+
+// InvokableRun 在期限内执行工具；数据源卡住导致超时时返回带 error 字段的结果，让 Agent 继续后续分析
+func (t *timeoutTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	runCtx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	type result struct {
+		output string
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		output, err := t.InvokableTool.InvokableRun(runCtx, argumentsInJSON, opts...)
+		done <- result{output: output, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.output, r.err
+	case <-runCtx.Done():
+		// 整体分析已取消时直接向上返回
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		name := "unknown"
+		if info, err := t.Info(ctx); err == nil {
+			name = info.Name
+		}
+		Logger(ctx).Printf("[ToolTimeout] 工具 %s 执行超过 %s，已放弃本次调用", name, t.timeout)
+		output, _ := json.Marshal(map[string]string{
+			"error": fmt.Sprintf("工具执行超过 %s 未返回，数据源可能无响应，请基于已有数据继续分析", t.timeout),
+		})
+		return string(output), nil
+	}
+}
+
+// WithTimeout 为一组工具设置单次调用的执行期限，timeout <= 0 时不做限制，原样返回
+func WithTimeout(tools []tool.BaseTool, timeout time.Duration) []tool.BaseTool {
+	if timeout <= 0 {
+		return tools
+	}
+	wrapped := make([]tool.BaseTool, len(tools))
+	for i, t := range tools {
+		if invokable, ok := t.(tool.InvokableTool); ok {
+			wrapped[i] = &timeoutTool{InvokableTool: invokable, timeout: timeout}
+			continue
+		}
+		wrapped[i] = t
+	}
+	return wrapped
+}
@@ -0,0 +1,363 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// previewMaxRunes 工具结果预览的最大长度，超出部分截断
+const previewMaxRunes = 160
+
+// toolPreviewers 各工具结果的预览格式化函数，返回不含工具名的一行摘要
+var toolPreviewers = map[string]func(content string) (string, error){
+	"get_market_cap":                previewMarketCap,
+	"get_quote":                     previewQuote,
+	"get_company_profile":           previewCompanyProfile,
+	"get_financial_metrics":         previewFinancialMetrics,
+	"get_company_news":              previewCompanyNews,
+	"assess_credit_risk":            previewCreditRisk,
+	"analyze_working_capital":       previewWorkingCapital,
+	"get_insider_trades":            previewInsiderTrades,
+	"assess_management":             previewManagement,
+	"get_price_history_stats":       previewPriceHistory,
+	"summarize_dataset":             previewDatasetSummary,
+	"summarize_news_timeline":       previewNewsTimeline,
+	"track_legal_risks":             previewLegalRisks,
+	"track_corporate_actions":       previewCorporateActions,
+	"extract_dependencies":          previewDependencies,
+	"analyze_fundamentals":          previewFundamentals,
+	"compare_peers":                 previewPeers,
+	"analyze_competition":           previewCompetition,
+	"find_similar_companies":        previewSimilarCompanies,
+	"analyze_portfolio_correlation": previewCorrelation,
+	"get_index_constituents":        previewIndexConstituents,
+	"monte_carlo_valuation":         previewMonteCarlo,
+	"check_price_target":            previewPriceTarget,
+	"assess_liquidity":              previewLiquidity,
+}
+
+// PreviewToolResult 生成工具返回结果的一行预览，如 "get_financial_metrics: 5 期, 最新 ROE 28.3%, D/E 1.70"，
+// 供终端展示 Agent 看到的数据；工具返回错误时显示错误信息，未知工具或无法解析的结果只显示返回长度
+func PreviewToolResult(toolName, content string) string {
+	var status struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal([]byte(content), &status) == nil && status.Error != "" {
+		return truncatePreview(fmt.Sprintf("%s: 错误 - %s", toolName, status.Error))
+	}
+	if previewer, ok := toolPreviewers[toolName]; ok {
+		if preview, err := previewer(content); err == nil && preview != "" {
+			return truncatePreview(toolName + ": " + preview)
+		}
+	}
+	return fmt.Sprintf("%s: 返回 %d 字符", toolName, len([]rune(content)))
+}
+
+// truncatePreview 将预览截断到 previewMaxRunes
+func truncatePreview(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	runes := []rune(s)
+	if len(runes) <= previewMaxRunes {
+		return s
+	}
+	return string(runes[:previewMaxRunes-1]) + "…"
+}
+
+// previewPercent 将比率格式化为百分比，nil 时为 n/a
+func previewPercent(v *float64) string {
+	if v == nil {
+		return NotAvailable
+	}
+	return Sanitize(*v * 100).Sprintf("%.1f%%")
+}
+
+func previewMarketCap(content string) (string, error) {
+	var output MarketCapOutput
+	if err := json.Unmarshal([]byte(content), &output); err != nil {
+		return "", err
+	}
+	format := NumberFormat{Locale: DefaultLocale}
+	return fmt.Sprintf("市值 %s（%s）", format.MoneyCompact(output.MarketCap, output.Currency), output.Date), nil
+}
+
+func previewQuote(content string) (string, error) {
+	var output QuoteOutput
+	if err := json.Unmarshal([]byte(content), &output); err != nil {
+		return "", err
+	}
+	source := "实时"
+	if !output.Realtime {
+		source = "收盘价"
+	}
+	preview := fmt.Sprintf("%s %s（%s，%s）, 52周 %s ~ %s", source, output.Price.Sprintf("%.2f"),
+		(output.DayChangePercent * 100).Sprintf("%+.2f%%"), output.QuoteTime,
+		output.Week52Low.Sprintf("%.2f"), output.Week52High.Sprintf("%.2f"))
+	if output.ExtendedMoveFlag {
+		preview += fmt.Sprintf(", ⚠️ %s异动 %s（%d 条新闻）", extendedSessionText[output.Session], (output.ExtendedMove * 100).Sprintf("%+.2f%%"), len(output.Headlines))
+	}
+	return preview, nil
+}
+
+func previewCompanyProfile(content string) (string, error) {
+	var output CompanyProfileOutput
+	if err := json.Unmarshal([]byte(content), &output); err != nil {
+		return "", err
+	}
+	source := "无业务描述"
+	switch output.DescriptionSource {
+	case DescriptionSourceFiling:
+		source = fmt.Sprintf("业务描述来自 %d 年 10-K", output.DescriptionYear)
+	case DescriptionSourceWebsite:
+		source = "业务描述来自官网"
+	}
+	return fmt.Sprintf("%s（%s / %s）, %s", output.Name, output.Sector, output.Industry, source), nil
+}
+
+func previewFinancialMetrics(content string) (string, error) {
+	var output FinancialMetricsOutput
+	if err := json.Unmarshal([]byte(content), &output); err != nil {
+		return "", err
+	}
+	if len(output.Metrics) == 0 {
+		return "没有可用数据", nil
+	}
+	latest := output.Metrics[0]
+	debtToEquity := NotAvailable
+	if latest.DebtToEquity != nil {
+		debtToEquity = Sanitize(*latest.DebtToEquity).Sprintf("%.2f")
+	}
+	preview := fmt.Sprintf("%d 期（%s）, 最新 %s ROE %s, D/E %s, 营运利润率 %s",
+		len(output.Metrics), output.Period, latest.ReportPeriod,
+		previewPercent(latest.ReturnOnEquity), debtToEquity, previewPercent(latest.OperatingMargin))
+	if len(output.Anomalies) > 0 {
+		preview += fmt.Sprintf(", ⚠️ %d 处异常变动", len(output.Anomalies))
+	}
+	return preview, nil
+}
+
+func previewCompanyNews(content string) (string, error) {
+	var output CompanyNewsOutput
+	if err := json.Unmarshal([]byte(content), &output); err != nil {
+		return "", err
+	}
+	preview := fmt.Sprintf("%d 条新闻（%s）", output.Count, dateWindow(output.StartDate, output.EndDate))
+	if len(output.RiskNews) > 0 {
+		preview += fmt.Sprintf(", 风险新闻 %d 条", len(output.RiskNews))
+	}
+	if output.Sentiment != nil {
+		preview += fmt.Sprintf(", 情绪 +%d/-%d", output.Sentiment.Positive, output.Sentiment.Negative)
+	}
+	if len(output.News) > 0 {
+		preview += fmt.Sprintf(", 最新: %s", output.News[0].Title)
+	}
+	return preview, nil
+}
+
+func previewCreditRisk(content string) (string, error) {
+	var output CreditRiskOutput
+	if err := json.Unmarshal([]byte(content), &output); err != nil {
+		return "", err
+	}
+	preview := fmt.Sprintf("结论 %s, Z'' %s, 利息保障倍数 %s", output.Verdict, output.ZScore.Sprintf("%.2f"), output.InterestCoverage.Sprintf("%.1f"))
+	if output.CoverageTrend != "" {
+		preview += "（" + coverageTrendLabel(output.CoverageTrend) + "）"
+	}
+	preview += fmt.Sprintf(", 调整债务/EBITDA %s, 调整 D/E %s", output.AdjustedDebtToEBITDA.Sprintf("%.1f"), output.AdjustedDebtToEquity.Sprintf("%.2f"))
+	return preview, nil
+}
+
+func previewWorkingCapital(content string) (string, error) {
+	var output WorkingCapitalOutput
+	if err := json.Unmarshal([]byte(content), &output); err != nil {
+		return "", err
+	}
+	if len(output.Periods) == 0 {
+		return "没有季度数据", nil
+	}
+	latest := output.Periods[0]
+	preview := fmt.Sprintf("%d 个季度, 最新 CCC %s 天（DSO %s, DIO %s, DPO %s）, 同比 %s 天",
+		len(output.Periods), latest.CCC.Sprintf("%.0f"), latest.DSO.Sprintf("%.0f"), latest.DIO.Sprintf("%.0f"),
+		latest.DPO.Sprintf("%.0f"), output.CCCChange.Sprintf("%+.0f"))
+	if output.Deteriorating {
+		preview += ", 营运资本恶化"
+	}
+	return preview, nil
+}
+
+func previewInsiderTrades(content string) (string, error) {
+	var output InsiderTradesOutput
+	if err := json.Unmarshal([]byte(content), &output); err != nil {
+		return "", err
+	}
+	format := NumberFormat{Locale: DefaultLocale}
+	preview := fmt.Sprintf("%d 笔交易（买入 %d, 卖出 %d）, 净买卖 %s 股",
+		output.Count, output.BuyCount, output.SellCount, format.Compact(output.NetShares))
+	if output.Truncated {
+		preview += "（已截断）"
+	}
+	return preview, nil
+}
+
+func previewManagement(content string) (string, error) {
+	var output ManagementQualityOutput
+	if err := json.Unmarshal([]byte(content), &output); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("管理层评分 %d/%d, 内部人持股 %s, 近一年内部人净买卖 %s 股, 高管变动新闻 %d 条",
+		output.Score, output.MaxScore, (output.InsiderOwnership * 100).Sprintf("%.2f%%"),
+		NumberFormat{Locale: DefaultLocale}.Compact(output.NetInsiderShares), len(output.ManagementEvents)), nil
+}
+
+func previewPriceHistory(content string) (string, error) {
+	var output PriceHistoryStats
+	if err := json.Unmarshal([]byte(content), &output); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s, %d 个交易日, CAGR %s, 最大回撤 %s, 年化波动率 %s",
+		dateWindow(output.StartDate, output.EndDate), output.TradingDays,
+		(output.CAGR * 100).Sprintf("%.1f%%"), (output.MaxDrawdown * 100).Sprintf("%.1f%%"),
+		(output.AnnualVolatility * 100).Sprintf("%.1f%%")), nil
+}
+
+func previewDatasetSummary(content string) (string, error) {
+	var output DatasetSummary
+	if err := json.Unmarshal([]byte(content), &output); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %d 条（%s, %d 页）", output.Dataset, output.Count,
+		dateWindow(output.StartDate, output.EndDate), output.Pages), nil
+}
+
+func previewNewsTimeline(content string) (string, error) {
+	var output NewsTimelineOutput
+	if err := json.Unmarshal([]byte(content), &output); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d 条新闻, %d 个季度叙述（%s ~ %s）, 模型调用 %d 次",
+		output.NewsCount, len(output.Quarters), output.StartDate, output.EndDate, output.ModelCalls), nil
+}
+
+func previewLegalRisks(content string) (string, error) {
+	var output LegalRiskOutput
+	if err := json.Unmarshal([]byte(content), &output); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d 条法律风险事件（新增 %d）", len(output.Entries), output.NewCount), nil
+}
+
+func previewCorporateActions(content string) (string, error) {
+	var output CorporateActionsOutput
+	if err := json.Unmarshal([]byte(content), &output); err != nil {
+		return "", err
+	}
+	format := NumberFormat{Locale: DefaultLocale}
+	return fmt.Sprintf("%d 笔交易（收购 %d, 出售 %d, 分拆 %d）, 已披露金额 %s, 并购支出/自由现金流 %s",
+		len(output.Actions), output.TypeCounts[CorporateActionAcquisition], output.TypeCounts[CorporateActionDivestiture],
+		output.TypeCounts[CorporateActionSpinOff], format.Compact(output.DisclosedDealValue),
+		(output.AcquisitionsToFCF * 100).Sprintf("%.0f%%")), nil
+}
+
+func previewDependencies(content string) (string, error) {
+	var output ConcentrationOutput
+	if err := json.Unmarshal([]byte(content), &output); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %d: 主要客户 %d 个, 主要供应商 %d 个",
+		output.FilingType, output.Year, len(output.Customers), len(output.Suppliers)), nil
+}
+
+func previewFundamentals(content string) (string, error) {
+	var output FundamentalAnalysisResponse
+	if err := json.Unmarshal([]byte(content), &output); err != nil {
+		return "", err
+	}
+	preview := fmt.Sprintf("评分 %d/%d", output.Score, FundamentalMaxScore)
+	if output.Benchmark != nil {
+		preview += fmt.Sprintf(", 行业基准 %s（%d 家）", output.Benchmark.Level, output.Benchmark.SampleSize)
+	}
+	if output.History != nil && !output.History.Sufficient {
+		preview += ", 历史数据不足"
+	}
+	return preview, nil
+}
+
+func previewPeers(content string) (string, error) {
+	var output PeerComparisonOutput
+	if err := json.Unmarshal([]byte(content), &output); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d 家可比公司（%s）: %s", len(output.Peers), output.PeerSource, strings.Join(output.Peers, ", ")), nil
+}
+
+func previewCompetition(content string) (string, error) {
+	var output CompetitiveAnalysisOutput
+	if err := json.Unmarshal([]byte(content), &output); err != nil {
+		return "", err
+	}
+	if output.Assessment == nil {
+		return "", fmt.Errorf("没有评估结论")
+	}
+	a := output.Assessment
+	return fmt.Sprintf("%s, 定价权%s, 竞争地位评分 %d/%d, 竞争对手: %s",
+		a.MarketPosition, a.PricingPower, a.Score, output.MaxScore, strings.Join(output.Competitors, ", ")), nil
+}
+
+func previewSimilarCompanies(content string) (string, error) {
+	var output SimilarCompaniesOutput
+	if err := json.Unmarshal([]byte(content), &output); err != nil {
+		return "", err
+	}
+	companies := make([]string, 0, len(output.Companies))
+	for _, c := range output.Companies {
+		companies = append(companies, fmt.Sprintf("%s %s", c.Symbol, c.Similarity.Sprintf("%.2f")))
+	}
+	return fmt.Sprintf("股票池 %s, 最相似: %s", output.Universe, strings.Join(companies, ", ")), nil
+}
+
+func previewCorrelation(content string) (string, error) {
+	var output PortfolioCorrelationOutput
+	if err := json.Unmarshal([]byte(content), &output); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d 只股票, 平均相关系数 %s, 组合波动率 %s, 分散化%s",
+		len(output.Symbols), output.AverageCorrelation.Sprintf("%.2f"),
+		(output.PortfolioVolatility * 100).Sprintf("%.1f%%"), output.Diversification), nil
+}
+
+func previewIndexConstituents(content string) (string, error) {
+	var output IndexConstituents
+	if err := json.Unmarshal([]byte(content), &output); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %d 只成分股（更新于 %s）", output.Name, output.Count, output.UpdatedAt), nil
+}
+
+func previewMonteCarlo(content string) (string, error) {
+	var output MonteCarloValuationOutput
+	if err := json.Unmarshal([]byte(content), &output); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("P10/P50/P90 %s/%s/%s, 现价 %s, P50 空间 %s",
+		output.P10.Sprintf("%.2f"), output.P50.Sprintf("%.2f"), output.P90.Sprintf("%.2f"),
+		output.CurrentPrice.Sprintf("%.2f"), (output.UpsideP50 * 100).Sprintf("%+.1f%%")), nil
+}
+
+func previewPriceTarget(content string) (string, error) {
+	var output PriceTargetCheckOutput
+	if err := json.Unmarshal([]byte(content), &output); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("目标价 %.2f, 隐含 P/E %s, 隐含 EPS 增长 %s, 结论 %s",
+		output.TargetPrice, output.ImpliedPE.Sprintf("%.1f"), (output.ImpliedEPSGrowth * 100).Sprintf("%.1f%%"), output.Verdict), nil
+}
+
+func previewLiquidity(content string) (string, error) {
+	var output LiquidityOutput
+	if err := json.Unmarshal([]byte(content), &output); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("近20日日均成交额 %s, 估算价差 %s, 自由流通 %s, 结论 %s",
+		output.AvgDollarVol20.Sprintf("%.0f"), (output.EstimatedSpread * 100).Sprintf("%.2f%%"),
+		(output.FreeFloatRatio * 100).Sprintf("%.1f%%"), output.Verdict), nil
+}
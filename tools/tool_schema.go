@@ -0,0 +1,195 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	"github.com/cloudwego/eino/schema"
+	"github.com/eino-contrib/jsonschema"
+)
+
+// 工具说明的语言，TOOL_SCHEMA_LOCALE 的取值
+const (
+	SchemaLocaleZh = "zh" // 中文（默认，与内置提示词一致）
+	SchemaLocaleEn = "en" // 英文，适合以英文提示词或对中文工具说明理解较差的模型
+)
+
+// SchemaLocales 支持的工具说明语言
+var SchemaLocales = []string{SchemaLocaleZh, SchemaLocaleEn}
+
+// ValidateSchemaLocale 检查工具说明语言
+func ValidateSchemaLocale(locale string) error {
+	if !slices.Contains(SchemaLocales, locale) {
+		return fmt.Errorf("无效的工具说明语言: %s（可选 %s）", locale, strings.Join(SchemaLocales, "、"))
+	}
+	return nil
+}
+
+// describedTool 参数说明已补全的工具，Info 返回构造时生成的说明
+type describedTool struct {
+	tool.InvokableTool
+	info *schema.ToolInfo
+}
+
+// Info 实现 tool.BaseTool
+func (t *describedTool) Info(context.Context) (*schema.ToolInfo, error) {
+	return t.info, nil
+}
+
+// inferTool 与 utils.InferTool 相同，另外把输入结构体字段的 description 标签写入参数说明；
+// utils.InferTool 只识别 jsonschema 标签，不处理 description 标签
+func inferTool[T, D any](toolName, toolDesc string, fn func(ctx context.Context, input T) (D, error)) (tool.InvokableTool, error) {
+	inner, err := utils.InferTool(toolName, toolDesc, fn)
+	if err != nil {
+		return nil, err
+	}
+	info, err := inner.Info(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	descriptions := make(map[string]string)
+	tagDescriptions(reflect.TypeFor[T](), "", descriptions, map[reflect.Type]bool{})
+	if info, err = describeParams(info, descriptions); err != nil {
+		return nil, err
+	}
+	return &describedTool{InvokableTool: inner, info: info}, nil
+}
+
+// describeParams 返回设置了参数说明的工具说明副本
+// 参数定义中同一类型的字段共用一个节点（如蒙特卡洛估值的三个分布参数），先整体复制再按路径修改，各字段的说明互不影响
+func describeParams(info *schema.ToolInfo, descriptions map[string]string) (*schema.ToolInfo, error) {
+	described := *info
+	params, err := info.ParamsOneOf.ToJSONSchema()
+	if err != nil {
+		return nil, fmt.Errorf("读取工具 %s 的参数定义失败: %w", info.Name, err)
+	}
+	if params == nil {
+		return &described, nil
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("复制工具 %s 的参数定义失败: %w", info.Name, err)
+	}
+	var copied jsonschema.Schema
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return nil, fmt.Errorf("复制工具 %s 的参数定义失败: %w", info.Name, err)
+	}
+	setParamDescriptions(&copied, "", descriptions)
+	described.ParamsOneOf = schema.NewParamsOneOfByJSONSchema(&copied)
+	return &described, nil
+}
+
+// tagDescriptions 收集结构体字段（含嵌套结构体）的 description 标签，键为以点分隔的 json 字段路径，如 growth.mean
+func tagDescriptions(t reflect.Type, prefix string, out map[string]string, seen map[reflect.Type]bool) {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return
+	}
+	seen[t] = true
+	defer delete(seen, t)
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			tagDescriptions(f.Type, prefix, out, seen)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if desc := f.Tag.Get("description"); desc != "" {
+			out[prefix+name] = desc
+		}
+		tagDescriptions(f.Type, prefix+name+".", out, seen)
+	}
+}
+
+// setParamDescriptions 按字段路径设置参数说明，数组按元素的字段继续匹配
+func setParamDescriptions(s *jsonschema.Schema, prefix string, descriptions map[string]string) {
+	if s == nil {
+		return
+	}
+	setParamDescriptions(s.Items, prefix, descriptions)
+	if s.Properties == nil {
+		return
+	}
+	for pair := s.Properties.Oldest(); pair != nil; pair = pair.Next() {
+		path := prefix + pair.Key
+		if desc, ok := descriptions[path]; ok && pair.Value != nil {
+			pair.Value.Description = desc
+		}
+		setParamDescriptions(pair.Value, path+".", descriptions)
+	}
+}
+
+// toolSchemaText 一种语言的工具说明和参数说明，参数键为以点分隔的 json 字段路径
+type toolSchemaText struct {
+	Desc   string
+	Params map[string]string
+}
+
+// localizedTool 以另一种语言提供说明的工具，调用行为不变
+type localizedTool struct {
+	tool.InvokableTool
+	text toolSchemaText
+}
+
+// Info 替换为译文，没有译文的参数保留原说明
+func (t *localizedTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	info, err := t.InvokableTool.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	localized, err := describeParams(info, t.text.Params)
+	if err != nil {
+		return nil, err
+	}
+	localized.Desc = t.text.Desc
+	return localized, nil
+}
+
+// WithSchemaLocale 按语言提供工具说明：zh 原样返回；en 使用 englishToolSchemas 中的译文，没有译文的工具保留中文说明并记录日志
+// 只改变模型看到的说明，工具名称、参数和返回结果不变
+func WithSchemaLocale(ctx context.Context, tools []tool.BaseTool, locale string) ([]tool.BaseTool, error) {
+	if locale == "" || locale == SchemaLocaleZh {
+		return tools, nil
+	}
+	if err := ValidateSchemaLocale(locale); err != nil {
+		return nil, err
+	}
+	texts := englishToolSchemas()
+	wrapped := make([]tool.BaseTool, len(tools))
+	var missing []string
+	for i, t := range tools {
+		wrapped[i] = t
+		info, err := t.Info(ctx)
+		if err != nil {
+			return nil, err
+		}
+		text, ok := texts[info.Name]
+		invokable, isInvokable := t.(tool.InvokableTool)
+		if !ok || !isInvokable {
+			missing = append(missing, info.Name)
+			continue
+		}
+		wrapped[i] = &localizedTool{InvokableTool: invokable, text: text}
+	}
+	if len(missing) > 0 {
+		Logger(ctx).Printf("[ToolSchema] 以下工具没有 %s 说明，使用中文说明: %s", locale, strings.Join(missing, ", "))
+	}
+	return wrapped, nil
+}
@@ -0,0 +1,209 @@
+package tools
+
+import "fmt"
+
+// symbolParamEn 各工具共用的股票代码参数说明
+const symbolParamEn = "Stock ticker, e.g. AAPL, TSLA, GOOG"
+
+// distributionParamsEn 蒙特卡洛估值中一个分布参数的各字段说明
+func distributionParamsEn(prefix string, params map[string]string) {
+	params[prefix+".type"] = "Distribution type: normal, uniform or triangular; defaults to normal"
+	params[prefix+".mean"] = "Mean of a normal distribution, mode of a triangular distribution (defaults to the midpoint of min and max)"
+	params[prefix+".std_dev"] = "Standard deviation of a normal distribution, must be greater than 0"
+	params[prefix+".min"] = "Lower bound of a uniform/triangular distribution (required), truncation floor of a normal distribution"
+	params[prefix+".max"] = "Upper bound of a uniform/triangular distribution (required, must exceed min), truncation cap of a normal distribution"
+}
+
+// englishToolSchemas 各工具的英文说明，键为工具名称；新增工具或修改中文说明时同步更新
+func englishToolSchemas() map[string]toolSchemaText {
+	monteCarlo := map[string]string{
+		"symbol":        symbolParamEn,
+		"metrics":       "Financial metrics; the latest period is used for revenue per share, margins and the current P/E",
+		"growth":        "Distribution of annual revenue growth (fraction, 0.1 = 10%); defaults to one centered on the latest revenue growth",
+		"net_margin":    "Distribution of the net margin at the end of the forecast (fraction); defaults to one centered on the latest net margin",
+		"exit_pe":       "Distribution of the P/E at the end of the forecast; defaults to one centered on the current P/E",
+		"years":         "Forecast years, default 5, max 10",
+		"discount_rate": "Discount rate (fraction), default 0.09",
+		"simulations":   "Number of simulations, default 10000, max 100000",
+		"seed":          "Random seed for reproducible results; defaults to the current time",
+	}
+	distributionParamsEn("growth", monteCarlo)
+	distributionParamsEn("net_margin", monteCarlo)
+	distributionParamsEn("exit_pe", monteCarlo)
+
+	return map[string]toolSchemaText{
+		"get_market_cap": {
+			Desc: "Get the market capitalization of a stock on a given date. Basic data for judging company size.",
+			Params: map[string]string{
+				"symbol": symbolParamEn,
+				"date":   "Date in YYYY-MM-DD; defaults to today",
+			},
+		},
+		"get_quote": {
+			Desc: fmt.Sprintf("Get a realtime quote: last price, day change, volume, 20-day average volume and 52-week high/low. Use this price for valuation, upside to target and market-cap discussion; do not derive the current price from market cap or per-share data in the financial metrics. When the quote is from pre-market or after hours and moved more than %.0f%% from the previous close, extended_move_flag is true and related headlines are returned.", ExtendedMoveThreshold*100),
+			Params: map[string]string{
+				"symbol": symbolParamEn,
+			},
+		},
+		"get_company_profile": {
+			Desc: "Get the business description (from the business section of the latest annual report, or the company website when there is none) plus sector, industry, exchange and employee count. Use it to introduce what the company actually does at the start of the report instead of relying on possibly outdated memory.",
+			Params: map[string]string{
+				"symbol": symbolParamEn,
+			},
+		},
+		"get_financial_metrics": {
+			Desc: "Get financial metrics of a stock: valuation ratios, profitability, efficiency and financial health. The core data for fundamental analysis. Abrupt adverse changes between periods are listed in anomalies and must be explained.",
+			Params: map[string]string{
+				"symbol": symbolParamEn,
+				"date":   "Date in YYYY-MM-DD; defaults to today",
+				"period": "Reporting period: ttm (trailing twelve months), annual or quarterly; defaults to ttm",
+				"limit":  "Number of periods, default 5, max 10",
+			},
+		},
+		"assess_credit_risk": {
+			Desc: fmt.Sprintf("Bankruptcy and credit risk assessment: yearly Altman Z''-score (non-manufacturer version), interest coverage (EBIT / interest expense) trend, and debt-to-equity and debt/EBITDA adjusted for operating lease liabilities and pension deficits, with a verdict (safe/grey/distress). Must be called when debt-to-equity exceeds the threshold (credit_risk_required is true in the financial metrics result). Thresholds (Z'', interest coverage, adjusted debt/EBITDA): safe above %.2f / at least %.0f / at most %.0f, distress below %.2f / below %.1f / above %.0f; the verdict is the worst of the three.",
+				AltmanZSafe, CoverageSafe, LeverageSafe, AltmanZDistress, CoverageDistress, LeverageDistress),
+			Params: map[string]string{
+				"symbol": symbolParamEn,
+				"years":  "Number of fiscal years, default 5, max 10",
+			},
+		},
+		"analyze_working_capital": {
+			Desc: fmt.Sprintf("Compute days sales outstanding (DSO), days inventory outstanding (DIO), days payables outstanding (DPO) and the cash conversion cycle (CCC) for the last %d quarters, compare them with the same quarter a year earlier, and flag early warnings of deteriorating working capital discipline (slower collections, inventory build-up, stretched payables) for the risk section.", workingCapitalQuarters),
+			Params: map[string]string{
+				"symbol": symbolParamEn,
+			},
+		},
+		"get_company_news": {
+			Desc: "Get the latest news of a company, categorized by topic (earnings, M&A, litigation, regulation, products and business, management). Use it for recent developments, market sentiment and potential catalysts; litigation and regulatory news is listed separately for the risk analysis.",
+			Params: map[string]string{
+				"symbol":     symbolParamEn,
+				"start_date": "Start date in YYYY-MM-DD; when omitted there is no start limit and the latest news up to the end date is returned",
+				"end_date":   "End date in YYYY-MM-DD; defaults to today",
+				"limit":      "Number of articles, default 10, max 20",
+			},
+		},
+		"get_insider_trades": {
+			Desc: "Get insider (officer and director) transactions of a stock within a date window, with buy/sell counts and net shares traded, to judge management's confidence in the company.",
+			Params: map[string]string{
+				"symbol":     symbolParamEn,
+				"start_date": "Filing start date in YYYY-MM-DD; defaults to 90 days before the end date",
+				"end_date":   "Filing end date in YYYY-MM-DD; defaults to today",
+				"limit":      "Number of transactions, default 50, max 200",
+			},
+		},
+		"assess_management": {
+			Desc: fmt.Sprintf("Assess management quality from insider ownership and trading, stock-based compensation as a share of revenue (a pay proxy), share count changes, capital allocation history (buybacks, dividends, acquisitions) and executive changes over the past %d years, with a management quality score (out of %d).", managementLookbackYears, ManagementMaxScore),
+			Params: map[string]string{
+				"symbol": symbolParamEn,
+				"years":  "Years of capital allocation history, default 5",
+			},
+		},
+		"track_legal_risks": {
+			Desc: "Search lawsuits, regulatory penalties and investigations involving the company over the past years (default 2) from news and the legal proceedings section of annual reports, and maintain the stock's risk register. Returns every risk event in the window and how many are new.",
+			Params: map[string]string{
+				"symbol":         symbolParamEn,
+				"lookback_years": "Years to look back, default 2, max 5",
+			},
+		},
+		"summarize_news_timeline": {
+			Desc: fmt.Sprintf("Hierarchically summarize all company news over %d to %d months: group by quarter, summarize chunks of %d articles, then merge into a narrative and key events per quarter. Returns a quarterly narrative timeline for long-horizon qualitative analysis (strategy shifts, recurring issues, management statements over time).",
+				NewsTimelineMinMonths, NewsTimelineMaxMonths, newsTimelineChunkSize),
+			Params: map[string]string{
+				"symbol":   symbolParamEn,
+				"months":   "Months to look back, 6 to 12, default 12",
+				"end_date": "End date in YYYY-MM-DD; defaults to today",
+			},
+		},
+		"summarize_dataset": {
+			Desc: "Fetch all company news or insider trades over a long window (possibly thousands of records), saving pages to the database instead of returning them, and return only a summary: totals, monthly distribution, news topic and sentiment distribution or insider net buying, plus a few samples. Suited to long-horizon trend analysis.",
+			Params: map[string]string{
+				"symbol":     symbolParamEn,
+				"dataset":    "Dataset: news (company news) or insider_trades (insider transactions)",
+				"start_date": "Start date in YYYY-MM-DD; defaults to 365 days before the end date",
+				"end_date":   "End date in YYYY-MM-DD; defaults to today",
+			},
+		},
+		"track_corporate_actions": {
+			Desc: "Compile the company's acquisitions, divestitures, spin-offs and mergers over the past years (default 5) from news and annual reports, with deal values, status and integration outcomes (revenue contribution, goodwill impairments, later sales), plus yearly acquisition spending from the cash flow statement and its share of free cash flow, for capital allocation and risk assessment.",
+			Params: map[string]string{
+				"symbol":         symbolParamEn,
+				"lookback_years": "Years to look back, default 5, max 5",
+			},
+		},
+		"extract_dependencies": {
+			Desc: "Extract major customers, major suppliers and customer/supplier concentration disclosures from the annual report (business, risk factors and MD&A sections) to assess the moat and dependency risk.",
+			Params: map[string]string{
+				"symbol": symbolParamEn,
+				"year":   "Fiscal year of the annual report, e.g. 2024; defaults to the latest year",
+			},
+		},
+		"compare_peers": {
+			Desc: "Compare the stock's key financial metrics (ROE, margins, leverage, valuation multiples, growth) with comparable companies, returning its rank in the group and the peer medians. Uses the configured peer set, or peers discovered by industry when none is configured.",
+			Params: map[string]string{
+				"symbol": symbolParamEn,
+				"peers":  "Optional list of peer tickers; defaults to the configured peer set, or peers discovered by industry",
+			},
+		},
+		"analyze_competition": {
+			Desc: fmt.Sprintf("Competitive landscape analysis: fetch business descriptions and key metrics of %d to %d main competitors, compare market position and pricing power (rank of gross margin, operating margin etc. within the group), and give a competitive position score (out of %d) that the final rating must take into account.",
+				minCompetitors, maxCompetitors, CompetitiveMaxScore),
+			Params: map[string]string{
+				"symbol":      symbolParamEn,
+				"competitors": "Optional tickers of 3 to 5 main competitors; defaults to the peer set",
+			},
+		},
+		"find_similar_companies": {
+			Desc: "Find the most similar companies in the cached universe by company profile (sector, industry, embedding of the business description and financial metrics). Useful for finding comparables or alternative investments.",
+			Params: map[string]string{
+				"symbol": symbolParamEn,
+				"limit":  "Number of similar companies, default 5, max 20",
+			},
+		},
+		"get_index_constituents": {
+			Desc: "Get the constituent tickers of an index (S&P 500, NASDAQ-100, CSI 300), usable as a universe for screening comparables or checking whether a stock belongs to a major index.",
+			Params: map[string]string{
+				"index": "Index name: sp500 (S&P 500), nasdaq100 (NASDAQ-100) or csi300 (CSI 300)",
+			},
+		},
+		"get_price_history_stats": {
+			Desc: "Get up to 20 years of daily price history and compute the compound annual growth rate (CAGR), maximum drawdown, annualized volatility, worst monthly return and the 50- and 200-day moving averages, to assess long-term shareholder returns, holding risk and the current price trend.",
+			Params: map[string]string{
+				"symbol": symbolParamEn,
+				"years":  "Years to look back, default 10, max 20",
+			},
+		},
+		"analyze_fundamentals": {
+			Desc:   "Analyze company fundamentals against Buffett's investment criteria, scoring key metrics such as ROE, debt ratio, operating margin and current ratio and comparing them with industry medians.",
+			Params: map[string]string{},
+		},
+		"monte_carlo_valuation": {
+			Desc:   "Monte Carlo valuation: randomly sample revenue growth, net margin and exit P/E from distributions to get a distribution of fair value per share (P10/P50/P90), giving a valuation range rather than a single target price.",
+			Params: monteCarlo,
+		},
+		"check_price_target": {
+			Desc: "Decompose the report's target price into the implied P/E and implied EPS growth, and compare them with the company's historical P/E range, historical EPS growth and the peer median P/E, giving a plausibility verdict (plausible/stretched/implausible). Call it after settling on a target price; the result is appended to the end of the report.",
+			Params: map[string]string{
+				"symbol":        symbolParamEn,
+				"target_price":  "Target price given in the report (per share, same currency as the stock price)",
+				"horizon_years": "Years to reach the target price, 1 to 5, default 1",
+				"peers":         "Peer tickers; defaults to the configured peer set, or peers discovered by industry",
+			},
+		},
+		"assess_liquidity": {
+			Desc: fmt.Sprintf("Assess trading volume and liquidity: average daily traded value over 20 days and 3 months, estimated bid-ask spread, free float and turnover, with a verdict (liquid/thin/illiquid) and the largest position that can be exited within %d trading days trading at most %.0f%% of average daily value. Call it before recommending a position size; the result is appended to the end of the report.", LiquidityExitDays, LiquidityParticipation*100),
+			Params: map[string]string{
+				"symbol":         symbolParamEn,
+				"position_value": "Intended position value (same currency as the stock price); when given, the trading days needed to build or exit it under the volume limit are computed",
+			},
+		},
+		"analyze_portfolio_correlation": {
+			Desc: "Compute pairwise daily-return correlations between the stocks in a portfolio, annualized volatility of each stock and the portfolio, and the diversification ratio, to assess diversification quality and find highly correlated holdings.",
+			Params: map[string]string{
+				"symbols": `Tickers in the portfolio, at least 2, e.g. ["AAPL", "MSFT", "KO"]`,
+				"weights": "Position weights matching symbols one to one; equal-weighted when omitted",
+				"years":   "Years to look back, default 1, max 5",
+			},
+		},
+	}
+}
@@ -0,0 +1,253 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 内置现金流折现模型的假设
+const (
+	dcfDiscountRate   = 0.10  // 折现率
+	dcfTerminalGrowth = 0.025 // 永续增长率
+	dcfYears          = 5     // 显式预测年数
+	dcfMaxGrowth      = 0.15  // 预测期增长率上限
+	dcfMinGrowth      = -0.05 // 预测期增长率下限
+	grahamMaxGrowth   = 0.15  // Graham 公式中增长率的上限
+)
+
+// ValuationData 估值模型可用的数据，来自本次分析已收集的工具结果
+type ValuationData struct {
+	Symbol     string
+	Currency   string
+	Price      float64                    // 当前股价，没有行情时为 0
+	Metrics    []FinancialMetrics         // 分析股票的财务指标，最新的在前，可能为空
+	MonteCarlo *MonteCarloValuationOutput // 蒙特卡洛估值结果，没有调用时为 nil
+}
+
+// FairValueEstimate 一个估值模型给出的每股公允价值和依据
+type FairValueEstimate struct {
+	FairValue float64
+	Rationale string // 主要假设和计算过程，显示在估值对比表中
+}
+
+// ValuationModel 估值模型，与内置模型一起在分析结束后运行，结果列入报告的估值对比表；
+// 数据不足时返回错误，表中显示原因而不是估值
+type ValuationModel interface {
+	Name() string
+	Estimate(data *ValuationData) (*FairValueEstimate, error)
+}
+
+// valuationFunc 用函数实现的估值模型
+type valuationFunc struct {
+	name     string
+	estimate func(data *ValuationData) (*FairValueEstimate, error)
+}
+
+func (f valuationFunc) Name() string { return f.name }
+
+func (f valuationFunc) Estimate(data *ValuationData) (*FairValueEstimate, error) {
+	return f.estimate(data)
+}
+
+// ValuationFunc 把估值函数包装为 ValuationModel
+func ValuationFunc(name string, estimate func(data *ValuationData) (*FairValueEstimate, error)) ValuationModel {
+	return valuationFunc{name: name, estimate: estimate}
+}
+
+var (
+	valuationModelsMu sync.Mutex
+	customValuations  []ValuationModel
+)
+
+// builtinValuationModels 内置的估值模型
+var builtinValuationModels = []ValuationModel{
+	ValuationFunc("现金流折现（DCF）", discountedCashFlow),
+	ValuationFunc("Graham 公式", grahamFormula),
+	ValuationFunc("蒙特卡洛模拟（P50）", monteCarloMedian),
+}
+
+// RegisterValuationModel 注册自定义估值模型，通常在 init 中调用；名称不能为空，也不能与已有模型重复
+func RegisterValuationModel(model ValuationModel) error {
+	name := strings.TrimSpace(model.Name())
+	if name == "" {
+		return fmt.Errorf("估值模型名称不能为空")
+	}
+	valuationModelsMu.Lock()
+	defer valuationModelsMu.Unlock()
+	for _, models := range [][]ValuationModel{builtinValuationModels, customValuations} {
+		for _, existing := range models {
+			if existing.Name() == name {
+				return fmt.Errorf("估值模型已存在: %s", name)
+			}
+		}
+	}
+	customValuations = append(customValuations, model)
+	return nil
+}
+
+// ValuationModels 内置模型和已注册的自定义模型，内置模型在前
+func ValuationModels() []ValuationModel {
+	valuationModelsMu.Lock()
+	defer valuationModelsMu.Unlock()
+	models := append([]ValuationModel(nil), builtinValuationModels...)
+	return append(models, customValuations...)
+}
+
+// ValuationResult 估值对比表中的一行
+type ValuationResult struct {
+	Model     string    `json:"model"`
+	Custom    bool      `json:"custom,omitempty"` // 用户注册的模型
+	FairValue SafeFloat `json:"fair_value"`
+	Upside    SafeFloat `json:"upside"` // 相对当前价格，没有价格时为 n/a
+	Rationale string    `json:"rationale,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// TriangulateValuation 依次运行全部估值模型；模型出错或 panic 只影响自己那一行
+func TriangulateValuation(data *ValuationData) []ValuationResult {
+	models := ValuationModels()
+	results := make([]ValuationResult, 0, len(models))
+	for i, model := range models {
+		result := ValuationResult{Model: model.Name(), Custom: i >= len(builtinValuationModels), FairValue: NaN(), Upside: NaN()}
+		estimate, err := runValuationModel(model, data)
+		switch {
+		case err != nil:
+			result.Error = err.Error()
+		case estimate == nil || !IsFinite(estimate.FairValue) || estimate.FairValue <= 0:
+			result.Error = "没有给出有效的公允价值"
+		default:
+			result.FairValue = SafeFloat(estimate.FairValue)
+			result.Rationale = estimate.Rationale
+			if data.Price > 0 {
+				result.Upside = SafeFloat(estimate.FairValue/data.Price - 1)
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// runValuationModel 运行一个模型，把 panic 转为错误
+func runValuationModel(model ValuationModel, data *ValuationData) (estimate *FairValueEstimate, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("模型运行出错: %v", r)
+		}
+	}()
+	return model.Estimate(data)
+}
+
+// RenderValuationTriangulation 将各估值模型的结果渲染为 markdown 估值对比章节，所有模型都无法估值时返回空字符串
+func RenderValuationTriangulation(results []ValuationResult, data *ValuationData, format NumberFormat) string {
+	var values []float64
+	for _, r := range results {
+		if r.FairValue.Valid() {
+			values = append(values, float64(r.FairValue))
+		}
+	}
+	if len(values) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## 🔺 估值方法对比\n\n")
+	sb.WriteString("| 模型 | 每股价值 | 相对当前价格 | 依据 |\n")
+	sb.WriteString("|------|----------|--------------|------|\n")
+	for _, r := range results {
+		name := r.Model
+		if r.Custom {
+			name += "（自定义）"
+		}
+		if r.Error != "" {
+			sb.WriteString(fmt.Sprintf("| %s | %s | %s | 无法估值：%s |\n", name, NotAvailable, NotAvailable, r.Error))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n", name, format.Money(float64(r.FairValue), data.Currency),
+			(r.Upside * 100).Sprintf("%+.1f%%"), strings.ReplaceAll(r.Rationale, "|", "/")))
+	}
+	sb.WriteString("\n")
+
+	sort.Float64s(values)
+	median := percentile(values, 0.5)
+	sb.WriteString(fmt.Sprintf("- %d 个模型的估值区间: %s ~ %s，中位数 %s", len(values),
+		format.Money(values[0], data.Currency), format.Money(values[len(values)-1], data.Currency), format.Money(median, data.Currency)))
+	if data.Price > 0 {
+		sb.WriteString(fmt.Sprintf("（当前价格 %s，%+.1f%%）", format.Money(data.Price, data.Currency), (median/data.Price-1)*100))
+	}
+	sb.WriteString("\n")
+	if len(values) > 1 && values[0] > 0 && values[len(values)-1]/values[0] > 2 {
+		sb.WriteString("- ⚠️ 各模型的估值相差一倍以上，结论对模型假设敏感，请结合各模型的依据判断\n")
+	}
+	return sb.String()
+}
+
+// discountedCashFlow 两阶段自由现金流折现：最近一期每股自由现金流按近几年营收平均增长率（限制在 -5%~15%）增长 5 年，
+// 之后按 2.5% 永续增长，折现率 10%
+func discountedCashFlow(data *ValuationData) (*FairValueEstimate, error) {
+	if len(data.Metrics) == 0 {
+		return nil, fmt.Errorf("缺少财务指标")
+	}
+	fcf := data.Metrics[0].FreeCashFlowPerShare
+	if fcf <= 0 {
+		return nil, fmt.Errorf("每股自由现金流不为正")
+	}
+	growth := averageRevenueGrowth(data.Metrics)
+	growth = clamp(growth, dcfMinGrowth, dcfMaxGrowth)
+
+	value, cash := 0.0, fcf
+	for year := 1; year <= dcfYears; year++ {
+		cash *= 1 + growth
+		value += cash / math.Pow(1+dcfDiscountRate, float64(year))
+	}
+	terminal := cash * (1 + dcfTerminalGrowth) / (dcfDiscountRate - dcfTerminalGrowth)
+	value += terminal / math.Pow(1+dcfDiscountRate, dcfYears)
+	return &FairValueEstimate{
+		FairValue: value,
+		Rationale: fmt.Sprintf("每股自由现金流 %.2f，前 %d 年增长 %.1f%%，永续增长 %.1f%%，折现率 %.0f%%",
+			fcf, dcfYears, growth*100, dcfTerminalGrowth*100, dcfDiscountRate*100),
+	}, nil
+}
+
+// grahamFormula Graham 成长股公式：每股价值 = EPS × (8.5 + 2g)，g 为近几年营收平均增长率（百分数，限制在 0~15）
+func grahamFormula(data *ValuationData) (*FairValueEstimate, error) {
+	if len(data.Metrics) == 0 {
+		return nil, fmt.Errorf("缺少财务指标")
+	}
+	eps := data.Metrics[0].EarningsPerShare
+	if eps <= 0 {
+		return nil, fmt.Errorf("每股收益不为正")
+	}
+	growth := clamp(averageRevenueGrowth(data.Metrics), 0, grahamMaxGrowth) * 100
+	return &FairValueEstimate{
+		FairValue: eps * (8.5 + 2*growth),
+		Rationale: fmt.Sprintf("EPS %.2f × (8.5 + 2 × %.1f)", eps, growth),
+	}, nil
+}
+
+// monteCarloMedian 蒙特卡洛模拟的中位数估值
+func monteCarloMedian(data *ValuationData) (*FairValueEstimate, error) {
+	mc := data.MonteCarlo
+	if mc == nil {
+		return nil, fmt.Errorf("本次分析没有运行蒙特卡洛估值")
+	}
+	if !mc.P50.Valid() {
+		return nil, fmt.Errorf("模拟结果无效")
+	}
+	return &FairValueEstimate{
+		FairValue: float64(mc.P50),
+		Rationale: fmt.Sprintf("%d 次模拟的中位数，P10~P90 为 %s ~ %s，折现率 %.1f%%", mc.Simulations, mc.P10.Sprintf("%.2f"), mc.P90.Sprintf("%.2f"), mc.DiscountRate*100),
+	}, nil
+}
+
+// averageRevenueGrowth 最近最多 3 期营收增长率的平均值
+func averageRevenueGrowth(metrics []FinancialMetrics) float64 {
+	n := min(len(metrics), 3)
+	sum := 0.0
+	for _, m := range metrics[:n] {
+		sum += m.RevenueGrowth
+	}
+	return sum / float64(n)
+}
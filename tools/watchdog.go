@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// LoopWatchdog 检测 Agent 使用相同参数重复调用同一工具的死循环
+// 重复调用直接返回首次调用的缓存结果，并在后续模型调用前注入纠正提示，避免耗尽 MaxStep
+type LoopWatchdog struct {
+	mu      sync.Mutex
+	results map[string]string // 工具名+规范化参数 -> 首次成功调用的结果
+	looping map[string]int    // 出现重复调用的工具及其重复次数
+}
+
+// NewLoopWatchdog 创建工具循环检测器，每次分析使用独立的实例
+func NewLoopWatchdog() *LoopWatchdog {
+	return &LoopWatchdog{
+		results: make(map[string]string),
+		looping: make(map[string]int),
+	}
+}
+
+// Wrap 为一组工具加上重复调用检测
+func (w *LoopWatchdog) Wrap(tools []tool.BaseTool) []tool.BaseTool {
+	wrapped := make([]tool.BaseTool, len(tools))
+	for i, t := range tools {
+		if invokable, ok := t.(tool.InvokableTool); ok {
+			wrapped[i] = &watchedTool{InvokableTool: invokable, watchdog: w}
+			continue
+		}
+		wrapped[i] = t
+	}
+	return wrapped
+}
+
+// MessageModifier 作为 react.AgentConfig.MessageModifier 使用，出现重复调用时在消息末尾追加纠正提示
+func (w *LoopWatchdog) MessageModifier(ctx context.Context, input []*schema.Message) []*schema.Message {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.looping) == 0 {
+		return input
+	}
+
+	names := make([]string, 0, len(w.looping))
+	for name := range w.looping {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var details []string
+	for _, name := range names {
+		details = append(details, fmt.Sprintf("%s（重复 %d 次）", name, w.looping[name]))
+	}
+
+	output := make([]*schema.Message, 0, len(input)+1)
+	output = append(output, input...)
+	output = append(output, schema.SystemMessage(fmt.Sprintf(
+		"注意：你已使用完全相同的参数重复调用了以下工具：%s。相同参数的结果不会变化，重复调用已直接返回之前的结果。"+
+			"请不要再次以相同参数调用，直接基于已获得的数据继续下一步分析；如需不同数据，请修改参数。",
+		strings.Join(details, "、"))))
+	return output
+}
+
+// lookup 查找相同调用的缓存结果，命中时记录重复次数
+func (w *LoopWatchdog) lookup(name, key string) (string, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	result, ok := w.results[key]
+	if !ok {
+		return "", false
+	}
+	w.looping[name]++
+	return result, true
+}
+
+// remember 缓存成功调用的结果，返回 error 字段的结果不缓存，允许重试
+func (w *LoopWatchdog) remember(key, result string) {
+	if hasErrorField(result) {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.results[key]; !ok {
+		w.results[key] = result
+	}
+}
+
+// watchedTool 带重复调用检测的工具包装
+type watchedTool struct {
+	tool.InvokableTool
+	watchdog *LoopWatchdog
+}
+
+// InvokableRun 相同参数的重复调用直接返回缓存结果
+func (t *watchedTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	info, err := t.Info(ctx)
+	if err != nil {
+		return t.InvokableTool.InvokableRun(ctx, argumentsInJSON, opts...)
+	}
+	key := info.Name + ":" + canonicalArguments(argumentsInJSON)
+
+	if result, ok := t.watchdog.lookup(info.Name, key); ok {
+		Logger(ctx).Printf("[LoopWatchdog] 检测到重复调用: %s %s，直接返回缓存结果", info.Name, argumentsInJSON)
+		return result, nil
+	}
+
+	result, err := t.InvokableTool.InvokableRun(ctx, argumentsInJSON, opts...)
+	if err == nil {
+		t.watchdog.remember(key, result)
+	}
+	return result, err
+}
+
+// canonicalArguments 规范化 JSON 参数（字段排序、去除空白），使等价参数得到相同的键
+func canonicalArguments(argumentsInJSON string) string {
+	var v any
+	if err := json.Unmarshal([]byte(argumentsInJSON), &v); err != nil {
+		return strings.TrimSpace(argumentsInJSON)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return strings.TrimSpace(argumentsInJSON)
+	}
+	return string(data)
+}
@@ -0,0 +1,200 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// workingCapitalQuarters 营运资本趋势分析使用的季度数
+const workingCapitalQuarters = 8
+
+// quarterDays 单季度天数，周转天数 = 期末余额 / 当季收入或成本 × quarterDays
+const quarterDays = 365.0 / 4
+
+// 营运资本恶化的判断阈值：现金转换周期同比增加超过 CCCDeteriorationDays 天，
+// 或应收/存货周转天数同比增加、应付周转天数同比减少超过 TurnoverDeteriorationRatio
+const (
+	CCCDeteriorationDays       = 10.0
+	TurnoverDeteriorationRatio = 0.15
+)
+
+// WorkingCapitalPeriod 一个季度的营运资本项目和周转天数，周转天数在数据缺失或分母为 0 时为 n/a
+type WorkingCapitalPeriod struct {
+	ReportPeriod       string   `json:"report_period"`
+	Revenue            *float64 `json:"revenue,omitempty"`
+	CostOfRevenue      *float64 `json:"cost_of_revenue,omitempty"`
+	AccountsReceivable *float64 `json:"accounts_receivable,omitempty"`
+	Inventory          *float64 `json:"inventory,omitempty"`
+	AccountsPayable    *float64 `json:"accounts_payable,omitempty"`
+
+	DSO SafeFloat `json:"dso"` // 应收账款周转天数
+	DIO SafeFloat `json:"dio"` // 存货周转天数
+	DPO SafeFloat `json:"dpo"` // 应付账款周转天数
+	CCC SafeFloat `json:"ccc"` // 现金转换周期 = DSO + DIO - DPO
+}
+
+// WorkingCapitalInput 营运资本趋势分析的输入参数
+type WorkingCapitalInput struct {
+	Symbol string `json:"symbol" description:"股票代码，如 AAPL, TSLA, GOOG"`
+}
+
+// WorkingCapitalOutput 营运资本趋势分析的输出结果
+type WorkingCapitalOutput struct {
+	Symbol        string                 `json:"symbol"`
+	Periods       []WorkingCapitalPeriod `json:"periods"`
+	CCCChange     SafeFloat              `json:"ccc_change"` // 最新季度现金转换周期相对去年同季度的变化（天）
+	Deteriorating bool                   `json:"deteriorating"`
+	Warnings      []string               `json:"warnings"`
+	Note          string                 `json:"note,omitempty"`
+	Error         string                 `json:"error,omitempty"`
+}
+
+// NewWorkingCapitalTool 创建营运资本与现金转换周期趋势工具
+// getWorkingCapitalFunc 返回最近 quarters 个季度的营运资本项目，最新的在前
+func NewWorkingCapitalTool(getWorkingCapitalFunc func(ctx context.Context, symbol string, quarters int) ([]WorkingCapitalPeriod, error)) (tool.BaseTool, error) {
+	tool, err := inferTool("analyze_working_capital",
+		fmt.Sprintf("计算最近 %d 个季度的应收账款周转天数（DSO）、存货周转天数（DIO）、应付账款周转天数（DPO）和现金转换周期（CCC），与去年同季度比较，标记营运资本纪律恶化（如回款变慢、存货积压、压缩付款周期）等早期预警信号，供风险部分引用。",
+			workingCapitalQuarters),
+		func(ctx context.Context, req *WorkingCapitalInput) (*WorkingCapitalOutput, error) {
+			Logger(ctx).Printf("[WorkingCapitalTool] 接收到请求: Symbol=%s", req.Symbol)
+
+			// 验证必需参数
+			if req.Symbol == "" {
+				Logger(ctx).Printf("[WorkingCapitalTool] 错误: 股票代码为空")
+				return &WorkingCapitalOutput{
+					Error: "股票代码不能为空",
+				}, nil
+			}
+			symbol := strings.ToUpper(req.Symbol)
+
+			periods, err := getWorkingCapitalFunc(ctx, symbol, workingCapitalQuarters)
+			if err != nil {
+				Logger(ctx).Printf("[WorkingCapitalTool] API调用失败: %v", err)
+				if isFatalAPIError(err) {
+					return nil, err
+				}
+				return &WorkingCapitalOutput{
+					Symbol: symbol,
+					Error:  fmt.Sprintf("获取季度营运资本数据失败: %v", err),
+				}, nil
+			}
+			if len(periods) == 0 {
+				return &WorkingCapitalOutput{
+					Symbol: symbol,
+					Error:  "没有可用的季度报表数据",
+				}, nil
+			}
+
+			result := EvaluateWorkingCapital(symbol, periods)
+			if err := saveWorkingCapitalToFile(result); err != nil {
+				Logger(ctx).Printf("[WorkingCapitalTool] 保存文件失败: %v", err)
+				// 不返回错误，继续返回分析结果
+			}
+
+			Logger(ctx).Printf("[WorkingCapitalTool] 返回响应: Symbol=%s, 季度数=%d, CCC=%s, 同比变化=%s, 恶化=%v",
+				symbol, len(result.Periods), result.Periods[0].CCC.Sprintf("%.1f"), result.CCCChange.Sprintf("%+.1f"), result.Deteriorating)
+			return result, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return tool, nil
+}
+
+// EvaluateWorkingCapital 计算每个季度的周转天数，并将最新季度与去年同季度比较（避免季节性影响）
+// periods 按报告期从新到旧排列
+func EvaluateWorkingCapital(symbol string, periods []WorkingCapitalPeriod) *WorkingCapitalOutput {
+	result := &WorkingCapitalOutput{
+		Symbol:    symbol,
+		CCCChange: NaN(),
+		Warnings:  []string{},
+	}
+	for _, p := range periods {
+		p.DSO, p.DIO, p.DPO = NaN(), NaN(), NaN()
+		if p.AccountsReceivable != nil && p.Revenue != nil {
+			p.DSO = SafeDiv(*p.AccountsReceivable*quarterDays, *p.Revenue)
+		}
+		if p.Inventory != nil && p.CostOfRevenue != nil {
+			p.DIO = SafeDiv(*p.Inventory*quarterDays, *p.CostOfRevenue)
+		}
+		if p.AccountsPayable != nil && p.CostOfRevenue != nil {
+			p.DPO = SafeDiv(*p.AccountsPayable*quarterDays, *p.CostOfRevenue)
+		}
+		// 没有存货的公司（如软件、服务业）DIO 按 0 计算
+		dio := p.DIO
+		if p.Inventory == nil || *p.Inventory == 0 {
+			dio = 0
+		}
+		p.CCC = Sanitize(float64(p.DSO) + float64(dio) - float64(p.DPO))
+		result.Periods = append(result.Periods, p)
+	}
+
+	if len(result.Periods) <= 4 {
+		result.Note = fmt.Sprintf("只有 %d 个季度的数据，无法与去年同季度比较", len(result.Periods))
+		return result
+	}
+	latest, yearAgo := result.Periods[0], result.Periods[4]
+	compare := fmt.Sprintf("%s 相比 %s", latest.ReportPeriod, yearAgo.ReportPeriod)
+
+	if latest.CCC.Valid() && yearAgo.CCC.Valid() {
+		result.CCCChange = latest.CCC - yearAgo.CCC
+		if float64(result.CCCChange) > CCCDeteriorationDays {
+			result.Deteriorating = true
+			result.Warnings = append(result.Warnings, fmt.Sprintf("现金转换周期从 %s 天延长到 %s 天（%s），营运资本占用增加",
+				yearAgo.CCC.Sprintf("%.0f"), latest.CCC.Sprintf("%.0f"), compare))
+		}
+	}
+	checks := []struct {
+		name     string
+		from, to SafeFloat
+		rising   bool // 上升为恶化
+		meaning  string
+	}{
+		{"应收账款周转天数（DSO）", yearAgo.DSO, latest.DSO, true, "回款变慢，可能放宽信用政策或收入质量下降"},
+		{"存货周转天数（DIO）", yearAgo.DIO, latest.DIO, true, "存货积压，可能面临需求放缓或减值风险"},
+		{"应付账款周转天数（DPO）", yearAgo.DPO, latest.DPO, false, "付款周期缩短，可能对供应商的议价能力下降"},
+	}
+	for _, c := range checks {
+		if !c.from.Valid() || !c.to.Valid() || float64(c.from) <= 0 {
+			continue
+		}
+		change := float64(c.to)/float64(c.from) - 1
+		if (c.rising && change > TurnoverDeteriorationRatio) || (!c.rising && change < -TurnoverDeteriorationRatio) {
+			result.Deteriorating = true
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s从 %s 天变为 %s 天（%s，%+.0f%%），%s",
+				c.name, c.from.Sprintf("%.0f"), c.to.Sprintf("%.0f"), compare, change*100, c.meaning))
+		}
+	}
+
+	// 连续多个季度同比延长时额外提示趋势性恶化
+	rising := 0
+	for i := 0; i+4 < len(result.Periods) && i < 4; i++ {
+		current, prior := result.Periods[i].CCC, result.Periods[i+4].CCC
+		if current.Valid() && prior.Valid() && current > prior {
+			rising++
+		}
+	}
+	if rising >= 3 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("最近 4 个季度中有 %d 个季度的现金转换周期同比延长", rising))
+	}
+	return result
+}
+
+// saveWorkingCapitalToFile 将营运资本分析结果写入输出目标
+func saveWorkingCapitalToFile(output *WorkingCapitalOutput) error {
+	// 生成文件名：working_capital/working_capital_AAPL_2025-09-25_15-04-05.json
+	timeSuffix := time.Now().Format("2006-01-02_15-04-05")
+	name := fmt.Sprintf("working_capital/working_capital_%s_%s.json", output.Symbol, timeSuffix)
+	location, err := writeJSONArtifact(name, output)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[WorkingCapitalTool] 分析结果已保存到: %s", location)
+	return nil
+}
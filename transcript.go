@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// 报告附录中保存的分析过程详细程度
+const (
+	TranscriptNone      = "none"      // 只保留结论
+	TranscriptReasoning = "reasoning" // 附加模型的中间推理
+	TranscriptFull      = "full"      // 附加中间推理、工具调用参数和工具返回结果
+)
+
+// validateTranscriptLevel 校验 --transcript 参数
+func validateTranscriptLevel(level string) error {
+	switch level {
+	case TranscriptNone, TranscriptReasoning, TranscriptFull:
+		return nil
+	}
+	return fmt.Errorf("无效的 --transcript: %s，可选值为 %s、%s、%s", level, TranscriptNone, TranscriptReasoning, TranscriptFull)
+}
+
+// renderTranscript 将 Agent 的中间消息渲染为 markdown 附录，final 为最终回复（已在正文中，不再重复）
+func renderTranscript(messages []*schema.Message, level, final string) string {
+	if level == TranscriptNone || len(messages) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## 附录：分析过程记录\n\n")
+	step := 0
+	for _, msg := range messages {
+		switch msg.Role {
+		case schema.Assistant:
+			if msg.Content == final && len(msg.ToolCalls) == 0 {
+				continue
+			}
+			step++
+			sb.WriteString(fmt.Sprintf("### 步骤 %d\n\n", step))
+			if msg.Content != "" {
+				sb.WriteString(msg.Content)
+				sb.WriteString("\n\n")
+			}
+			for _, call := range msg.ToolCalls {
+				if level == TranscriptFull {
+					sb.WriteString(fmt.Sprintf("- 调用工具 `%s`，参数: `%s`\n", call.Function.Name, call.Function.Arguments))
+				} else {
+					sb.WriteString(fmt.Sprintf("- 调用工具 `%s`\n", call.Function.Name))
+				}
+			}
+			if len(msg.ToolCalls) > 0 {
+				sb.WriteString("\n")
+			}
+		case schema.Tool:
+			if level != TranscriptFull {
+				continue
+			}
+			if provider := dataLicenses().withholdToolResult(msg.ToolName, msg.Content); provider != "" {
+				sb.WriteString(fmt.Sprintf("- 工具 %s 的返回结果按 %s 的数据授权条款不附带原文\n\n", msg.ToolName, provider))
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("<details><summary>工具 %s 返回结果</summary>\n\n```json\n%s\n```\n\n</details>\n\n", msg.ToolName, msg.Content))
+		}
+	}
+	return sb.String()
+}
@@ -1,17 +1,24 @@
 package main
 
-// Price 结构体
-type Price struct {
-	Open   float64 `json:"open"`
-	Close  float64 `json:"close"`
-	High   float64 `json:"high"`
-	Low    float64 `json:"low"`
-	Volume int64   `json:"volume"`
-	Time   string  `json:"time"`
+import "investment/provider"
+
+// PriceSnapshot 实时行情快照
+type PriceSnapshot struct {
+	Ticker           string  `json:"ticker"`
+	Price            float64 `json:"price"`
+	DayChange        float64 `json:"day_change"`
+	DayChangePercent float64 `json:"day_change_percent"`
+	Volume           float64 `json:"volume"`
+	Time             string  `json:"time"`
+}
+
+// PriceSnapshotResponse 结构体
+type PriceSnapshotResponse struct {
+	Snapshot PriceSnapshot `json:"snapshot"`
 }
 
 // PriceResponse 结构体
 type PriceResponse struct {
-	Ticker string  `json:"ticker"`
-	Prices []Price `json:"prices"`
+	Ticker string           `json:"ticker"`
+	Prices []provider.Price `json:"prices"`
 }
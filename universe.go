@@ -0,0 +1,477 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"investment/tools"
+
+	"golang.org/x/net/html"
+)
+
+// universeDir 指数成分股缓存目录
+var universeDir = filepath.Join("output", "universe")
+
+// universeStaleAfter 成分股缓存超过该时间后提示刷新（指数通常按季度调整）
+const universeStaleAfter = 30 * 24 * time.Hour
+
+// stockIndex 内置指数的成分股来源：Wikipedia 成分股表格中代码所在的列
+type stockIndex struct {
+	Name      string
+	URL       string
+	Columns   []string // 代码列的表头候选（小写，包含匹配）
+	MinCount  int      // 解析结果少于该数量时视为页面结构变化
+	Normalize func(string) (string, bool)
+}
+
+// stockIndexes 支持的指数
+var stockIndexes = map[string]stockIndex{
+	"sp500": {
+		Name:      "S&P 500",
+		URL:       "https://en.wikipedia.org/wiki/List_of_S%26P_500_companies",
+		Columns:   []string{"symbol"},
+		MinCount:  450,
+		Normalize: normalizeUSSymbol,
+	},
+	"nasdaq100": {
+		Name:      "NASDAQ-100",
+		URL:       "https://en.wikipedia.org/wiki/Nasdaq-100",
+		Columns:   []string{"ticker", "symbol"},
+		MinCount:  90,
+		Normalize: normalizeUSSymbol,
+	},
+	"csi300": {
+		Name:      "CSI 300",
+		URL:       "https://en.wikipedia.org/wiki/CSI_300_Index",
+		Columns:   []string{"ticker", "code", "symbol"},
+		MinCount:  250,
+		Normalize: normalizeAShareSymbol,
+	},
+}
+
+// stockIndexNames 按名称排序的指数列表
+func stockIndexNames() []string {
+	names := make([]string, 0, len(stockIndexes))
+	for name := range stockIndexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// universeCache 指数成分股的本地缓存
+type universeCache struct {
+	Index     string    `json:"index"`
+	Name      string    `json:"name"`
+	Source    string    `json:"source"` // 来源 URL 或导入的文件
+	Symbols   []string  `json:"symbols"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UniverseProvider 提供指数成分股作为筛选和回测的股票池，首次使用时下载并缓存到 output/universe/<指数>.json
+type UniverseProvider struct {
+	dir    string
+	client *http.Client
+
+	mu sync.Mutex
+}
+
+// NewUniverseProvider 创建指数成分股提供者
+func NewUniverseProvider() *UniverseProvider {
+	return &UniverseProvider{dir: universeDir, client: newHTTPClient(30 * time.Second)}
+}
+
+// Get 返回指数成分股，没有缓存时下载；缓存过期只提示，不自动刷新，刷新使用 universe refresh
+func (p *UniverseProvider) Get(index string) (*tools.IndexConstituents, error) {
+	index = strings.ToLower(index)
+	definition, ok := stockIndexes[index]
+	if !ok {
+		return nil, fmt.Errorf("不支持的指数: %s（可选 %s）", index, strings.Join(stockIndexNames(), ", "))
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cache, err := p.load(index)
+	if err != nil {
+		return nil, err
+	}
+	if cache == nil {
+		if cache, err = p.refresh(index, definition); err != nil {
+			return nil, err
+		}
+	}
+	result := &tools.IndexConstituents{
+		Index:     cache.Index,
+		Name:      cache.Name,
+		Symbols:   cache.Symbols,
+		Count:     len(cache.Symbols),
+		UpdatedAt: cache.UpdatedAt.Format("2006-01-02"),
+	}
+	if time.Since(cache.UpdatedAt) > universeStaleAfter {
+		result.Stale = true
+		log.Printf("[Universe] %s 成分股缓存已超过 %d 天，可运行 universe refresh %s 更新", index, int(universeStaleAfter.Hours()/24), index)
+	}
+	return result, nil
+}
+
+// Refresh 重新下载指数成分股并更新缓存
+func (p *UniverseProvider) Refresh(index string) (*universeCache, error) {
+	definition, ok := stockIndexes[index]
+	if !ok {
+		return nil, fmt.Errorf("不支持的指数: %s（可选 %s）", index, strings.Join(stockIndexNames(), ", "))
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.refresh(index, definition)
+}
+
+// Import 从本地 CSV 文件导入成分股（表头包含 symbol、ticker 或 code 列），用于网页结构变化或离线环境
+func (p *UniverseProvider) Import(index, path string) (*universeCache, error) {
+	definition, ok := stockIndexes[index]
+	if !ok {
+		return nil, fmt.Errorf("不支持的指数: %s（可选 %s）", index, strings.Join(stockIndexNames(), ", "))
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %v", err)
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("解析 CSV 失败: %v", err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("CSV 文件没有数据行")
+	}
+	column := matchColumn(records[0], []string{"symbol", "ticker", "code"})
+	if column < 0 {
+		return nil, fmt.Errorf("CSV 表头中没有 symbol、ticker 或 code 列")
+	}
+	var raw []string
+	for _, record := range records[1:] {
+		if column < len(record) {
+			raw = append(raw, record[column])
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.save(index, definition, path, raw)
+}
+
+// List 返回已缓存的指数
+func (p *UniverseProvider) List() ([]*universeCache, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var caches []*universeCache
+	for _, index := range stockIndexNames() {
+		cache, err := p.load(index)
+		if err != nil {
+			return nil, err
+		}
+		if cache != nil {
+			caches = append(caches, cache)
+		}
+	}
+	return caches, nil
+}
+
+// refresh 下载并解析成分股页面，调用方需持有锁
+func (p *UniverseProvider) refresh(index string, definition stockIndex) (*universeCache, error) {
+	log.Printf("[Universe] 下载 %s 成分股: %s", definition.Name, definition.URL)
+	req, err := http.NewRequest(http.MethodGet, definition.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "investment-buddy/1.0")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("下载 %s 成分股失败: %w", definition.Name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("下载 %s 成分股失败: HTTP %d", definition.Name, resp.StatusCode)
+	}
+	raw, err := parseConstituentsTable(resp.Body, definition.Columns)
+	if err != nil {
+		return nil, fmt.Errorf("解析 %s 成分股失败: %w", definition.Name, err)
+	}
+	return p.save(index, definition, definition.URL, raw)
+}
+
+// save 规范化代码、检查数量并写入缓存，调用方需持有锁
+func (p *UniverseProvider) save(index string, definition stockIndex, source string, raw []string) (*universeCache, error) {
+	var symbols []string
+	seen := make(map[string]bool)
+	for _, value := range raw {
+		symbol, ok := definition.Normalize(value)
+		if ok && !seen[symbol] {
+			seen[symbol] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+	if len(symbols) < definition.MinCount {
+		return nil, fmt.Errorf("%s 只解析到 %d 只成分股（预期至少 %d 只），来源格式可能已变化，可使用 --file 从 CSV 导入", definition.Name, len(symbols), definition.MinCount)
+	}
+	sort.Strings(symbols)
+
+	cache := &universeCache{
+		Index:     index,
+		Name:      definition.Name,
+		Source:    source,
+		Symbols:   symbols,
+		UpdatedAt: time.Now(),
+	}
+	if err := os.MkdirAll(p.dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建目录失败: %v", err)
+	}
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("JSON序列化失败: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(p.dir, index+".json"), data, 0644); err != nil {
+		return nil, fmt.Errorf("写入文件失败: %v", err)
+	}
+	return cache, nil
+}
+
+// load 读取缓存，不存在时返回 nil，调用方需持有锁
+func (p *UniverseProvider) load(index string) (*universeCache, error) {
+	data, err := os.ReadFile(filepath.Join(p.dir, index+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取成分股缓存失败: %v", err)
+	}
+	var cache universeCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("解析成分股缓存 %s 失败: %v", index, err)
+	}
+	return &cache, nil
+}
+
+// parseConstituentsTable 从 HTML 页面中找出成分股表格（优先 id="constituents"，其次 wikitable），返回代码列的文本
+func parseConstituentsTable(r io.Reader, columns []string) ([]string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	var tables []*html.Node
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "table" {
+			if attr(n, "id") == "constituents" {
+				tables = append([]*html.Node{n}, tables...)
+			} else if strings.Contains(attr(n, "class"), "wikitable") {
+				tables = append(tables, n)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	var best []string
+	for _, table := range tables {
+		rows := tableRows(table)
+		if len(rows) < 2 {
+			continue
+		}
+		column := matchColumn(rows[0], columns)
+		if column < 0 {
+			continue
+		}
+		var values []string
+		for _, row := range rows[1:] {
+			if column < len(row) {
+				values = append(values, row[column])
+			}
+		}
+		if len(values) > len(best) {
+			best = values
+		}
+	}
+	if len(best) == 0 {
+		return nil, fmt.Errorf("页面中没有包含 %s 列的成分股表格", strings.Join(columns, "/"))
+	}
+	return best, nil
+}
+
+// tableRows 返回表格每一行单元格（th 或 td）的文本
+func tableRows(table *html.Node) [][]string {
+	var rows [][]string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "table" && n != table {
+			return // 跳过嵌套表格
+		}
+		if n.Type == html.ElementNode && n.Data == "tr" {
+			var cells []string
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.ElementNode && (c.Data == "th" || c.Data == "td") {
+					cells = append(cells, strings.TrimSpace(nodeText(c)))
+				}
+			}
+			rows = append(rows, cells)
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(table)
+	return rows
+}
+
+// nodeText 返回节点下的全部文本
+func nodeText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(nodeText(c))
+	}
+	return sb.String()
+}
+
+// attr 返回节点属性值
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// matchColumn 返回第一个表头包含任一候选名称（不区分大小写）的列，按候选顺序优先
+func matchColumn(header []string, candidates []string) int {
+	for _, candidate := range candidates {
+		for i, name := range header {
+			if strings.Contains(strings.ToLower(name), candidate) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// usSymbolPattern 美股代码，允许 BRK.B 这类带类别后缀的代码
+var usSymbolPattern = regexp.MustCompile(`^[A-Z]{1,5}([.\-][A-Z])?$`)
+
+// normalizeUSSymbol 规范化美股代码
+func normalizeUSSymbol(value string) (string, bool) {
+	symbol := strings.ToUpper(strings.TrimSpace(value))
+	return symbol, usSymbolPattern.MatchString(symbol)
+}
+
+// aShareCodePattern A 股6位数字代码
+var aShareCodePattern = regexp.MustCompile(`\d{6}`)
+
+// normalizeAShareSymbol 将 A 股代码规范为 600519.SS / 000001.SZ 形式：6 开头为上交所，0、3 开头为深交所
+func normalizeAShareSymbol(value string) (string, bool) {
+	code := aShareCodePattern.FindString(value)
+	switch {
+	case code == "":
+		return "", false
+	case strings.HasPrefix(code, "6"):
+		return code + ".SS", true
+	case strings.HasPrefix(code, "0"), strings.HasPrefix(code, "3"):
+		return code + ".SZ", true
+	}
+	return "", false
+}
+
+// runUniverse 处理 universe 子命令
+// universe list：列出已缓存的指数成分股
+// universe show <index>：显示指数成分股（没有缓存时下载）
+// universe refresh <index|all> [--file constituents.csv]：重新下载成分股，或从 CSV 导入
+func runUniverse(args []string) error {
+	usage := fmt.Sprintf("用法: universe list | universe show <%s> | universe refresh <%s|all> [--file constituents.csv]", strings.Join(stockIndexNames(), "|"), strings.Join(stockIndexNames(), "|"))
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	provider := NewUniverseProvider()
+
+	switch args[0] {
+	case "list":
+		caches, err := provider.List()
+		if err != nil {
+			return err
+		}
+		if len(caches) == 0 {
+			fmt.Println("还没有缓存的指数成分股，可运行 universe refresh all")
+			return nil
+		}
+		for _, cache := range caches {
+			fmt.Printf("%-10s  %-12s  %4d 只  更新于 %s  来源 %s\n", cache.Index, cache.Name, len(cache.Symbols), cache.UpdatedAt.Format("2006-01-02"), cache.Source)
+		}
+		return nil
+
+	case "show":
+		if len(args) < 2 {
+			return errors.New(usage)
+		}
+		constituents, err := provider.Get(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s（%d 只，更新于 %s）\n%s\n", constituents.Name, constituents.Count, constituents.UpdatedAt, strings.Join(constituents.Symbols, " "))
+		return nil
+
+	case "refresh":
+		if len(args) < 2 {
+			return errors.New(usage)
+		}
+		index := strings.ToLower(args[1])
+		fs := flag.NewFlagSet("universe refresh", flag.ExitOnError)
+		file := fs.String("file", "", "从 CSV 文件导入成分股（表头包含 symbol、ticker 或 code 列），而不是下载")
+		if err := fs.Parse(args[2:]); err != nil {
+			return err
+		}
+		indexes := []string{index}
+		if index == "all" {
+			if *file != "" {
+				return fmt.Errorf("--file 只能用于单个指数")
+			}
+			indexes = stockIndexNames()
+		}
+		var failed []string
+		for _, name := range indexes {
+			var cache *universeCache
+			var err error
+			if *file != "" {
+				cache, err = provider.Import(name, *file)
+			} else {
+				cache, err = provider.Refresh(name)
+			}
+			if err != nil {
+				log.Printf("更新 %s 失败: %v", name, err)
+				failed = append(failed, name)
+				continue
+			}
+			fmt.Printf("🗂 %s 成分股已更新: %d 只，保存到 %s\n", cache.Name, len(cache.Symbols), filepath.Join(universeDir, name+".json"))
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("以下指数更新失败: %s", strings.Join(failed, ", "))
+		}
+		return nil
+	}
+	return fmt.Errorf("未知的 universe 子命令: %s", args[0])
+}
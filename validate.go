@@ -0,0 +1,416 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"investment/tools"
+)
+
+// artifactKind 输出目录中的一类 JSON 结果文件
+type artifactKind struct {
+	Name         string       // 类别名称，--kind 使用
+	Dir          string       // 输出根目录下的一级目录
+	File         string       // 文件名模式（path.Match），子目录不限
+	Type         reflect.Type // 写入文件的 Go 类型，校验时据此生成 JSON Schema
+	SymbolInName bool         // 文件名在 File 的固定前缀之后以股票代码开头（如 metrics_AAPL_ttm_<时间>.json）
+	// Check 可选，结构校验通过后的内容检查
+	Check func(root, name string, data []byte) []string
+}
+
+// artifactKinds 校验的全部结果文件，新增写入输出目标的 JSON 文件时在这里登记
+var artifactKinds = []artifactKind{
+	{Name: "metrics", Dir: "metrics", File: "metrics_*.json", Type: reflect.TypeOf(tools.FinancialMetricsOutput{}), SymbolInName: true},
+	{Name: "news", Dir: "news", File: "news_*.json", Type: reflect.TypeOf(tools.CompanyNewsOutput{}), SymbolInName: true},
+	{Name: "news_timeline", Dir: "news_timeline", File: "news_timeline_*.json", Type: reflect.TypeOf(tools.NewsTimelineOutput{}), SymbolInName: true},
+	{Name: "analysis", Dir: "analysis", File: "analysis_*.json", Type: reflect.TypeOf(tools.FundamentalAnalysisResponse{}), SymbolInName: true},
+	{Name: "insider", Dir: "insider", File: "insider_*.json", Type: reflect.TypeOf(tools.InsiderTradesOutput{}), SymbolInName: true},
+	{Name: "liquidity", Dir: "liquidity", File: "liquidity_*.json", Type: reflect.TypeOf(tools.LiquidityOutput{}), SymbolInName: true},
+	{Name: "competition", Dir: "competition", File: "competition_*.json", Type: reflect.TypeOf(tools.CompetitiveAnalysisOutput{}), SymbolInName: true},
+	{Name: "correlation", Dir: "correlation", File: "correlation_*.json", Type: reflect.TypeOf(tools.PortfolioCorrelationOutput{})},
+	{Name: "peers", Dir: "peers", File: "peers_*.json", Type: reflect.TypeOf(tools.PeerComparisonOutput{}), SymbolInName: true},
+	{Name: "management", Dir: "management", File: "management_*.json", Type: reflect.TypeOf(tools.ManagementQualityOutput{}), SymbolInName: true},
+	{Name: "dependencies", Dir: "dependencies", File: "dependencies_*.json", Type: reflect.TypeOf(tools.ConcentrationOutput{}), SymbolInName: true},
+	{Name: "price_target", Dir: "price_target", File: "price_target_*.json", Type: reflect.TypeOf(tools.PriceTargetCheckOutput{}), SymbolInName: true},
+	{Name: "corporate_actions", Dir: "corporate_actions", File: "corporate_actions_*.json", Type: reflect.TypeOf(tools.CorporateActionsOutput{}), SymbolInName: true},
+	{Name: "working_capital", Dir: "working_capital", File: "working_capital_*.json", Type: reflect.TypeOf(tools.WorkingCapitalOutput{}), SymbolInName: true},
+	{Name: "credit", Dir: "credit", File: "credit_*.json", Type: reflect.TypeOf(tools.CreditRiskOutput{}), SymbolInName: true},
+	{Name: "valuation", Dir: "valuation", File: "valuation_*.json", Type: reflect.TypeOf(tools.MonteCarloValuationOutput{}), SymbolInName: true},
+	{Name: "quote", Dir: "quote", File: "quote_*.json", Type: reflect.TypeOf(tools.QuoteOutput{}), SymbolInName: true},
+	{Name: "structured_report", Dir: "report", File: "*_report.json", Type: reflect.TypeOf(StructuredReport{}), SymbolInName: true, Check: checkStructuredReport},
+	{Name: "summary_card", Dir: "report", File: "*_report_card.json", Type: reflect.TypeOf(SummaryCard{}), SymbolInName: true},
+	{Name: "industry", Dir: "industry", File: "*.json", Type: reflect.TypeOf(IndustryReport{})},
+	{Name: "bench", Dir: "bench", File: "bench.json", Type: reflect.TypeOf(BenchReport{})},
+	{Name: "performance", Dir: "performance", File: "performance_*.json", Type: reflect.TypeOf(PerformanceReport{})},
+	{Name: "runs", Dir: "runs", File: "*.json", Type: reflect.TypeOf(RunRecord{}), SymbolInName: true, Check: checkRunRecord},
+}
+
+// maxArtifactProblems 一个文件最多列出的问题数，数组中每个元素都有同样问题时避免刷屏
+const maxArtifactProblems = 10
+
+// artifactResult 一个文件的校验结果，Problems 为空表示通过
+type artifactResult struct {
+	Name     string // 相对输出根目录的路径
+	Kind     string
+	Stage    string // 未通过的阶段：解析、结构、内容
+	Problems []string
+}
+
+// runValidate 处理 validate 子命令：扫描输出目录中的 JSON 结果文件，依次检查能否解析、是否符合写入时的结构、内容是否一致
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	dir := fs.String("dir", tools.OutputDir(), "要扫描的本地输出根目录（OUTPUT_SINK=s3 时先把结果同步到本地）")
+	kindList := fs.String("kind", "", "只校验这些类别，逗号分隔，如 metrics,news,structured_report；为空时校验全部")
+	verbose := fs.Bool("verbose", false, "同时列出通过校验的文件")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	kinds, err := selectArtifactKinds(*kindList)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(*dir); err != nil {
+		return fmt.Errorf("无法读取输出目录: %w", err)
+	}
+
+	results, err := validateArtifacts(*dir, kinds)
+	if err != nil {
+		return err
+	}
+	counts := make(map[string][2]int) // 类别 -> [文件数, 未通过数]
+	failed := 0
+	for _, r := range results {
+		c := counts[r.Kind]
+		c[0]++
+		if len(r.Problems) == 0 {
+			counts[r.Kind] = c
+			if *verbose {
+				fmt.Printf("✅ %s\n", r.Name)
+			}
+			continue
+		}
+		c[1]++
+		counts[r.Kind] = c
+		failed++
+		fmt.Printf("❌ %s（%s，%s校验未通过）\n", r.Name, r.Kind, r.Stage)
+		for _, problem := range r.Problems {
+			fmt.Printf("   - %s\n", problem)
+		}
+	}
+
+	fmt.Printf("\n%-18s %6s %6s\n", "类别", "文件", "未通过")
+	for _, kind := range kinds {
+		if c, ok := counts[kind.Name]; ok {
+			fmt.Printf("%-18s %6d %6d\n", kind.Name, c[0], c[1])
+		}
+	}
+	if len(results) == 0 {
+		fmt.Printf("%s 中没有需要校验的结果文件\n", *dir)
+		return nil
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d/%d 个文件未通过校验", failed, len(results))
+	}
+	fmt.Printf("全部 %d 个文件通过校验\n", len(results))
+	return nil
+}
+
+// selectArtifactKinds 按 --kind 选择要校验的类别
+func selectArtifactKinds(list string) ([]artifactKind, error) {
+	if strings.TrimSpace(list) == "" {
+		return artifactKinds, nil
+	}
+	var selected []artifactKind
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		i := slices.IndexFunc(artifactKinds, func(k artifactKind) bool { return k.Name == name })
+		if i < 0 {
+			names := make([]string, len(artifactKinds))
+			for j, k := range artifactKinds {
+				names[j] = k.Name
+			}
+			return nil, fmt.Errorf("未知的结果类别: %s（可选 %s）", name, strings.Join(names, ", "))
+		}
+		selected = append(selected, artifactKinds[i])
+	}
+	return selected, nil
+}
+
+// validateArtifacts 遍历 root 下属于 kinds 的 JSON 文件并逐个校验，结果按路径排序
+func validateArtifacts(root string, kinds []artifactKind) ([]artifactResult, error) {
+	var results []artifactResult
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".json") {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		kind, ok := matchArtifactKind(name, kinds)
+		if !ok {
+			return nil
+		}
+		results = append(results, validateArtifact(root, name, kind))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("扫描输出目录失败: %w", err)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	return results, nil
+}
+
+// matchArtifactKind 按一级目录和文件名找到文件的类别，同一目录下多个类别时取第一个匹配的
+func matchArtifactKind(name string, kinds []artifactKind) (artifactKind, bool) {
+	dir, _, _ := strings.Cut(name, "/")
+	for _, kind := range kinds {
+		if kind.Dir != dir {
+			continue
+		}
+		if ok, _ := path.Match(kind.File, path.Base(name)); ok {
+			return kind, true
+		}
+	}
+	return artifactKind{}, false
+}
+
+// validateArtifact 分三个阶段校验一个文件，前一阶段未通过时不再进行后面的阶段：
+// 解析（非空、是完整的单个 JSON 值）、结构（符合写入时 Go 类型生成的 JSON Schema）、内容（文件名与股票代码一致及类别自己的检查）
+func validateArtifact(root, name string, kind artifactKind) artifactResult {
+	result := artifactResult{Name: name, Kind: kind.Name}
+	fail := func(stage string, problems ...string) artifactResult {
+		result.Stage = stage
+		if len(problems) > maxArtifactProblems {
+			problems = append(problems[:maxArtifactProblems], fmt.Sprintf("……另有 %d 处问题", len(problems)-maxArtifactProblems))
+		}
+		result.Problems = problems
+		return result
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+	if err != nil {
+		return fail("解析", fmt.Sprintf("读取失败: %v", err))
+	}
+	doc, err := decodeArtifact(data)
+	if err != nil {
+		return fail("解析", err.Error())
+	}
+
+	b := &openAPIBuilder{schemas: make(map[string]any)}
+	v := &schemaValidator{components: b.schemas}
+	v.validate(doc, b.schema(kind.Type), "$")
+	if len(v.problems) > 0 {
+		return fail("结构", v.problems...)
+	}
+
+	var problems []string
+	if kind.SymbolInName {
+		problems = append(problems, checkArtifactSymbol(name, kind, doc)...)
+	}
+	if kind.Check != nil {
+		problems = append(problems, kind.Check(root, name, data)...)
+	}
+	if len(problems) > 0 {
+		return fail("内容", problems...)
+	}
+	return result
+}
+
+// decodeArtifact 解析文件内容，数字保留为 json.Number 以区分整数；空文件、截断或结尾有多余内容时返回错误
+func decodeArtifact(data []byte) (any, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, errors.New("文件为空")
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("JSON 解析失败（文件可能被截断）: %v", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("JSON 之后还有多余内容")
+	}
+	return doc, nil
+}
+
+// checkArtifactSymbol 文件名中的股票代码与内容中的 symbol 一致
+func checkArtifactSymbol(name string, kind artifactKind, doc any) []string {
+	object, ok := doc.(map[string]any)
+	if !ok {
+		return nil
+	}
+	symbol, ok := object["symbol"].(string)
+	if !ok || symbol == "" {
+		return nil
+	}
+	prefix, _, _ := strings.Cut(kind.File, "*")
+	fileSymbol, _, _ := strings.Cut(strings.TrimPrefix(path.Base(name), prefix), "_")
+	if !strings.EqualFold(fileSymbol, symbol) {
+		return []string{fmt.Sprintf("文件名中的股票代码为 %s，内容中的 symbol 为 %s", fileSymbol, symbol)}
+	}
+	return nil
+}
+
+// checkStructuredReport 结构化结论的评级、摘要和估值区间，与抽取时的检查相同
+func checkStructuredReport(root, name string, data []byte) []string {
+	var report StructuredReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return []string{err.Error()}
+	}
+	if err := report.validate(report.Symbol); err != nil {
+		return []string{err.Error()}
+	}
+	return nil
+}
+
+// checkRunRecord 运行记录的 ID 与文件名一致，引用的本地报告、摘要卡片和快照包存在
+func checkRunRecord(root, name string, data []byte) []string {
+	var run RunRecord
+	if err := json.Unmarshal(data, &run); err != nil {
+		return []string{err.Error()}
+	}
+	var problems []string
+	if id := strings.TrimSuffix(path.Base(name), ".json"); run.ID != id {
+		problems = append(problems, fmt.Sprintf("id 应为 %s，实际为 %s", id, run.ID))
+	}
+	for _, ref := range []struct{ field, location string }{
+		{"report_path", run.ReportPath},
+		{"card_path", run.CardPath},
+		{"snapshot_path", run.SnapshotPath},
+	} {
+		// s3:// 和 memory:// 等非本地位置不检查
+		if ref.location == "" || strings.Contains(ref.location, "://") {
+			continue
+		}
+		if _, err := os.Stat(ref.location); errors.Is(err, fs.ErrNotExist) {
+			problems = append(problems, fmt.Sprintf("%s 引用的文件不存在: %s", ref.field, ref.location))
+		}
+	}
+	return problems
+}
+
+// schemaValidator 按 openAPIBuilder 生成的 schema 校验解析后的 JSON 值
+// Go 会把空的切片、映射和指针写成 null，因此任何位置的 null 都视为有效；文件中多出的字段不视为问题，以兼容旧版本写入的文件
+type schemaValidator struct {
+	components map[string]any
+	problems   []string
+}
+
+// validate 校验 value 是否符合 schema，at 为 JSON 路径，用于问题说明
+func (v *schemaValidator) validate(value any, schema map[string]any, at string) {
+	if value == nil || len(schema) == 0 {
+		return
+	}
+	if ref, ok := schema["$ref"].(string); ok {
+		resolved, _ := v.components[strings.TrimPrefix(ref, "#/components/schemas/")].(map[string]any)
+		v.validate(value, resolved, at)
+		return
+	}
+	typ, _ := schema["type"].(string)
+	switch typ {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			v.mismatch(at, "对象", value)
+			return
+		}
+		required, _ := schema["required"].([]string)
+		for _, key := range required {
+			if _, ok := object[key]; !ok {
+				v.problems = append(v.problems, fmt.Sprintf("%s 缺少字段 %s", at, key))
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		additional, _ := schema["additionalProperties"].(map[string]any)
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if property, ok := properties[key].(map[string]any); ok {
+				v.validate(object[key], property, at+"."+key)
+			} else if additional != nil {
+				v.validate(object[key], additional, at+"."+key)
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			v.mismatch(at, "数组", value)
+			return
+		}
+		itemSchema, _ := schema["items"].(map[string]any)
+		for i, item := range items {
+			v.validate(item, itemSchema, fmt.Sprintf("%s[%d]", at, i))
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			v.mismatch(at, "字符串", value)
+			return
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+				v.problems = append(v.problems, fmt.Sprintf("%s 不是有效的时间: %q", at, s))
+			}
+		}
+		if enum, ok := schema["enum"].([]string); ok && !slices.Contains(enum, s) {
+			v.problems = append(v.problems, fmt.Sprintf("%s 必须是 %s 之一，实际为 %q", at, strings.Join(enum, "/"), s))
+		}
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			v.mismatch(at, "整数", value)
+			return
+		}
+		if _, err := strconv.ParseInt(n.String(), 10, 64); err != nil {
+			v.problems = append(v.problems, fmt.Sprintf("%s 应为整数，实际为 %s", at, n))
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			v.mismatch(at, "数字", value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			v.mismatch(at, "布尔值", value)
+		}
+	}
+}
+
+// mismatch 记录类型不符的问题
+func (v *schemaValidator) mismatch(at, want string, value any) {
+	got := "未知类型"
+	switch value.(type) {
+	case map[string]any:
+		got = "对象"
+	case []any:
+		got = "数组"
+	case string:
+		got = "字符串"
+	case json.Number:
+		got = "数字"
+	case bool:
+		got = "布尔值"
+	}
+	v.problems = append(v.problems, fmt.Sprintf("%s 应为%s，实际为%s", at, want, got))
+}
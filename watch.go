@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"investment/tools"
+)
+
+// watchedStock 行情监控中一只股票的状态
+type watchedStock struct {
+	Symbol    string
+	Rating    string  // 最近一次分析的评级
+	Reference float64 // 上一次提示时的价格，首次取到行情时为开始价格
+}
+
+// runWatch 处理 watch 子命令：定时获取几只股票的实时行情，价格相对上一次提示变动超过阈值时提示，直到达到刷新次数或 Ctrl-C
+func runWatch(args []string) error {
+	var symbols []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		symbols, args = append(symbols, args[0]), args[1:]
+	}
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	interval := fs.Duration("interval", 5*time.Minute, "刷新间隔（不小于 10s）")
+	move := fs.Float64("move", 0.03, "价格变动超过该比例时提示，小数形式")
+	count := fs.Int("count", 0, "刷新次数，0 表示一直运行到 Ctrl-C")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	symbols = append(symbols, fs.Args()...)
+	if len(symbols) == 0 {
+		return fmt.Errorf("用法: watch <stock_symbol>... [--interval 5m] [--move 0.03] [--count 0]")
+	}
+	if *interval < 10*time.Second {
+		return fmt.Errorf("--interval 不能小于 10s: %s", *interval)
+	}
+	if *move <= 0 {
+		return fmt.Errorf("无效的 --move: %g", *move)
+	}
+	if err := configError(sharedConfigProblems()); err != nil {
+		return err
+	}
+
+	var stocks []*watchedStock
+	for _, symbol := range parseSymbolList(symbols...) {
+		stock := &watchedStock{Symbol: canonicalSymbol(symbol)}
+		records, err := listRunRecords(runFilter{Symbol: stock.Symbol})
+		if err != nil {
+			return err
+		}
+		if len(records) > 0 {
+			stock.Rating = records[0].Rating
+		}
+		stocks = append(stocks, stock)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	fmt.Printf("👀 监控 %d 只股票，每 %s 刷新，变动超过 %.1f%% 时提示（Ctrl-C 退出）\n", len(stocks), *interval, *move*100)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for round := 1; ; round++ {
+		if err := refreshWatch(ctx, stocks, *move); err != nil {
+			if ctx.Err() != nil {
+				fmt.Println("\n已停止监控")
+				return nil
+			}
+			return err
+		}
+		if *count > 0 && round >= *count {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			fmt.Println("\n已停止监控")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshWatch 获取一轮行情并打印；数据源密钥无效或 ctx 取消时返回错误，其他失败只打印在对应股票的行中
+func refreshWatch(ctx context.Context, stocks []*watchedStock, move float64) error {
+	fmt.Printf("\n[%s]\n", time.Now().Format("15:04:05"))
+	for _, stock := range stocks {
+		snapshot, err := GetPriceSnapshot(ctx, stock.Symbol)
+		if err != nil {
+			if errors.Is(err, tools.ErrUnauthorized) || ctx.Err() != nil {
+				return err
+			}
+			fmt.Printf("%-10s ⚠️ %v\n", stock.Symbol, err)
+			continue
+		}
+		line := fmt.Sprintf("%-10s %10.2f %+7.2f%%  评级=%s", stock.Symbol, snapshot.Price, snapshot.DayChangePercent, orDash(stock.Rating))
+		if stock.Reference == 0 {
+			stock.Reference = snapshot.Price
+		} else if change := snapshot.Price/stock.Reference - 1; math.Abs(change) >= move {
+			line += fmt.Sprintf("  🔔 较 %.2f 变动 %+.1f%%", stock.Reference, change*100)
+			stock.Reference = snapshot.Price
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
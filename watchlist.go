@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// watchlistsDir 服务接口管理的关注列表的保存目录，每个列表一个 <name>.txt，格式与 --watchlist 文件相同
+var watchlistsDir = filepath.Join("output", "watchlists")
+
+// watchlistEntry 关注列表中的一只股票及用户为它写的备注
+type watchlistEntry struct {
+	Symbol string `json:"symbol" description:"股票代码"`
+	Note   string `json:"note,omitempty" description:"备注，分析时附加到用户提示词，请 Agent 在报告中回应"` // 可选
+}
+
+// watchlist 保存在 watchlistsDir 中的命名关注列表
+type watchlist struct {
+	Name    string           `json:"name" description:"关注列表名称"`
+	Path    string           `json:"path" description:"关注列表文件，可直接用于 --watchlist"`
+	Entries []watchlistEntry `json:"entries" description:"关注的股票，按文件中的顺序排列"`
+}
+
+// watchlistPath 命名关注列表的文件路径，名称规则与组合名称相同
+func watchlistPath(name string) string {
+	return filepath.Join(watchlistsDir, name+".txt")
+}
+
+// loadWatchlist 读取命名关注列表，不存在时返回的错误满足 errors.Is(err, os.ErrNotExist)
+func loadWatchlist(name string) (*watchlist, error) {
+	path := watchlistPath(name)
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	entries, err := readWatchlist(path)
+	if err != nil {
+		return nil, err
+	}
+	return &watchlist{Name: name, Path: path, Entries: entries}, nil
+}
+
+// listWatchlists 读取 watchlistsDir 中的全部关注列表，按名称排序
+func listWatchlists() ([]watchlist, error) {
+	files, err := filepath.Glob(filepath.Join(watchlistsDir, "*.txt"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	lists := make([]watchlist, 0, len(files))
+	for _, file := range files {
+		list, err := loadWatchlist(strings.TrimSuffix(filepath.Base(file), ".txt"))
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, *list)
+	}
+	return lists, nil
+}
+
+// normalizeWatchlistEntries 检查并整理接口提交的关注列表：代码转为大写，去掉重复的股票，
+// 代码和备注必须能按 readWatchlist 的格式写回一行
+func normalizeWatchlistEntries(entries []watchlistEntry) ([]watchlistEntry, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("entries 不能为空")
+	}
+	normalized := make([]watchlistEntry, 0, len(entries))
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		symbol := strings.ToUpper(strings.TrimSpace(entry.Symbol))
+		if symbol == "" || strings.HasPrefix(symbol, "#") || strings.ContainsAny(symbol, "/\\, \t\r\n") {
+			return nil, fmt.Errorf("无效的股票代码: %q", entry.Symbol)
+		}
+		note := strings.TrimSpace(entry.Note)
+		if strings.ContainsAny(note, "\r\n") {
+			return nil, fmt.Errorf("股票 %s 的备注不能换行", symbol)
+		}
+		if seen[symbol] {
+			continue
+		}
+		seen[symbol] = true
+		normalized = append(normalized, watchlistEntry{Symbol: symbol, Note: note})
+	}
+	return normalized, nil
+}
+
+// saveWatchlist 按 readWatchlist 的格式保存命名关注列表，先写临时文件再替换，读取方不会看到写了一半的文件
+func saveWatchlist(name string, entries []watchlistEntry) (*watchlist, error) {
+	if err := os.MkdirAll(watchlistsDir, 0755); err != nil {
+		return nil, fmt.Errorf("创建目录失败: %v", err)
+	}
+	var sb strings.Builder
+	for _, entry := range entries {
+		sb.WriteString(entry.Symbol)
+		if entry.Note != "" {
+			sb.WriteString(" " + entry.Note)
+		}
+		sb.WriteString("\n")
+	}
+	path := watchlistPath(name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(sb.String()), 0644); err != nil {
+		return nil, fmt.Errorf("写入文件失败: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, fmt.Errorf("写入文件失败: %v", err)
+	}
+	return &watchlist{Name: name, Path: path, Entries: entries}, nil
+}
+
+// readWatchlist 读取关注列表文件：每行一只股票，代码之后（空白或逗号分隔）的内容为备注；空行和 # 开头的行忽略
+//
+//	# 核心持仓
+//	AAPL   关注服务收入占比和回购节奏
+//	NVDA,  数据中心收入的持续性
+//	0700.HK
+func readWatchlist(path string) ([]watchlistEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取关注列表失败: %w", err)
+	}
+	defer f.Close()
+
+	var entries []watchlistEntry
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		symbol, note := text, ""
+		if i := strings.IndexAny(text, " \t,"); i >= 0 {
+			symbol, note = text[:i], strings.TrimLeft(text[i:], " \t,")
+		}
+		symbol = strings.ToUpper(symbol)
+		if strings.ContainsAny(symbol, "/\\") {
+			return nil, fmt.Errorf("关注列表 %s 第 %d 行的股票代码无效: %s", path, line, symbol)
+		}
+		entries = append(entries, watchlistEntry{Symbol: symbol, Note: strings.TrimSpace(note)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取关注列表失败: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("关注列表 %s 中没有股票代码", path)
+	}
+	return entries, nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"investment/tools"
+)
+
+// workingCapitalLineItems 营运资本分析使用的财务报表项目
+var workingCapitalLineItems = []string{
+	"revenue",
+	"cost_of_revenue",
+	"trade_and_non_trade_receivables",
+	"inventory",
+	"trade_and_non_trade_payables",
+}
+
+// getWorkingCapital 获取最近 quarters 个季度的收入、成本和营运资本项目，最新的在前
+func getWorkingCapital(ctx context.Context, symbol string, quarters int) ([]tools.WorkingCapitalPeriod, error) {
+	items, err := SearchLineItems(ctx, symbol, workingCapitalLineItems, time.Now().Format("2006-01-02"), "quarterly", quarters)
+	if err != nil {
+		return nil, err
+	}
+	periods := make([]tools.WorkingCapitalPeriod, 0, len(items))
+	for _, item := range items {
+		periods = append(periods, tools.WorkingCapitalPeriod{
+			ReportPeriod:       item.ReportPeriod,
+			Revenue:            lineItemValue(item, "revenue"),
+			CostOfRevenue:      lineItemValue(item, "cost_of_revenue"),
+			AccountsReceivable: lineItemValue(item, "trade_and_non_trade_receivables"),
+			Inventory:          lineItemValue(item, "inventory"),
+			AccountsPayable:    lineItemValue(item, "trade_and_non_trade_payables"),
+		})
+	}
+	return periods, nil
+}
@@ -0,0 +1,438 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"investment/provider"
+	"investment/tools"
+)
+
+// yahooUserAgent 请求 Yahoo Finance 使用的 User-Agent
+const yahooUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0 Safari/537.36"
+
+// yahooFinance Yahoo Finance 的 provider.DataProvider 实现，免费、不需要密钥，未配置 FINANCIAL_DATASETS_API_KEY 时作为默认数据源
+// 提供日线价格、市值、公司信息和最新一期的 TTM 基础指标；不提供历史财务指标、新闻和内部人交易，这些数据返回 tools.ErrNoData
+type yahooFinance struct{}
+
+// Name 实现 provider.DataProvider
+func (yahooFinance) Name() string {
+	return providerYahooFinance
+}
+
+// yahooSession quoteSummary 接口需要的 crumb，和数据源客户端中的会话 cookie 配对，客户端重建（重新加载配置）后重新获取
+var yahooSession struct {
+	mu     sync.Mutex
+	client *dataClient
+	crumb  string
+}
+
+// yahooCrumb 返回当前会话的 crumb，第一次调用时先访问 fc.yahoo.com 取得会话 cookie
+func yahooCrumb(ctx context.Context) (string, error) {
+	client := dataAPIClient()
+	yahooSession.mu.Lock()
+	defer yahooSession.mu.Unlock()
+	if yahooSession.client == client && yahooSession.crumb != "" {
+		return yahooSession.crumb, nil
+	}
+
+	get := func(rawURL string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", yahooUserAgent)
+		return client.http.Do(req)
+	}
+	// fc.yahoo.com 返回 404，但会设置会话 cookie
+	if resp, err := get("https://fc.yahoo.com"); err == nil {
+		resp.Body.Close()
+	}
+	resp, err := get(client.providers[providerYahooFinance].BaseURL + "/v1/test/getcrumb")
+	if err != nil {
+		return "", fmt.Errorf("获取 Yahoo Finance crumb 失败: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	crumb := strings.TrimSpace(string(body))
+	if resp.StatusCode != 200 || crumb == "" {
+		return "", fmt.Errorf("获取 Yahoo Finance crumb 失败: %w", tools.StatusError(resp.StatusCode, body))
+	}
+	yahooSession.client, yahooSession.crumb = client, crumb
+	return crumb, nil
+}
+
+// resetYahooCrumb crumb 失效（401）时丢弃，下次请求重新获取
+func resetYahooCrumb() {
+	yahooSession.mu.Lock()
+	defer yahooSession.mu.Unlock()
+	yahooSession.crumb = ""
+}
+
+// fetchYahooFinance 请求 Yahoo Finance 接口（经过响应缓存），返回 200 的响应体
+func fetchYahooFinance(ctx context.Context, ticker, endpoint string) ([]byte, error) {
+	resp, err := dataAPIClient().do(ctx, providerYahooFinance, "GET", endpoint, nil, true)
+	if err != nil {
+		return nil, fmt.Errorf("API 请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应体失败: %w", err)
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("获取数据错误: %s: %w", ticker, tools.StatusError(resp.StatusCode, body))
+	}
+	return body, nil
+}
+
+// yahooChartResponse /v8/finance/chart 的响应，价格数组中停牌或缺失的交易日为 null
+type yahooChartResponse struct {
+	Chart struct {
+		Result []struct {
+			Meta struct {
+				ExchangeTimezoneName string `json:"exchangeTimezoneName"`
+			} `json:"meta"`
+			Timestamp  []int64 `json:"timestamp"`
+			Indicators struct {
+				Quote []struct {
+					Open   []*float64 `json:"open"`
+					High   []*float64 `json:"high"`
+					Low    []*float64 `json:"low"`
+					Close  []*float64 `json:"close"`
+					Volume []*int64   `json:"volume"`
+				} `json:"quote"`
+			} `json:"indicators"`
+		} `json:"result"`
+	} `json:"chart"`
+}
+
+// Prices 获取日线价格，日期按交易所时区计算，缺少收盘价的交易日跳过
+func (yahooFinance) Prices(ctx context.Context, ticker, startDate, endDate string) ([]provider.Price, error) {
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		return nil, fmt.Errorf("无效的开始日期: %s", startDate)
+	}
+	end, err := time.Parse("2006-01-02", endDate)
+	if err != nil {
+		return nil, fmt.Errorf("无效的结束日期: %s", endDate)
+	}
+	// period2 不含当天，向后多取一天，再按交易所日期过滤
+	endpoint := fmt.Sprintf("/v8/finance/chart/%s?period1=%d&period2=%d&interval=1d",
+		url.PathEscape(ticker), start.Add(-24*time.Hour).Unix(), end.Add(48*time.Hour).Unix())
+	body, err := fetchYahooFinance(ctx, ticker, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	var chart yahooChartResponse
+	if err := json.Unmarshal(body, &chart); err != nil {
+		return nil, fmt.Errorf("解析价格响应失败: %w", err)
+	}
+	if len(chart.Chart.Result) == 0 || len(chart.Chart.Result[0].Indicators.Quote) == 0 {
+		return nil, nil
+	}
+	result := chart.Chart.Result[0]
+	loc, err := time.LoadLocation(result.Meta.ExchangeTimezoneName)
+	if err != nil {
+		loc = time.UTC
+	}
+	quote := result.Indicators.Quote[0]
+	at := func(values []*float64, i int) float64 {
+		if i < len(values) && values[i] != nil {
+			return *values[i]
+		}
+		return 0
+	}
+
+	var prices []provider.Price
+	for i, ts := range result.Timestamp {
+		if i >= len(quote.Close) || quote.Close[i] == nil {
+			continue
+		}
+		date := time.Unix(ts, 0).In(loc).Format("2006-01-02")
+		if date < startDate || date > endDate {
+			continue
+		}
+		price := provider.Price{
+			Open:  at(quote.Open, i),
+			Close: *quote.Close[i],
+			High:  at(quote.High, i),
+			Low:   at(quote.Low, i),
+			Time:  date,
+		}
+		if i < len(quote.Volume) && quote.Volume[i] != nil {
+			price.Volume = *quote.Volume[i]
+		}
+		prices = append(prices, price)
+	}
+	return prices, nil
+}
+
+// yahooValue quoteSummary 中的数值字段，没有数据时为 {}
+type yahooValue struct {
+	Raw *float64 `json:"raw"`
+}
+
+// value 数值，没有数据时为 0
+func (v yahooValue) value() float64 {
+	if v.Raw == nil {
+		return 0
+	}
+	return *v.Raw
+}
+
+// yahooQuoteSummary /v10/finance/quoteSummary 的响应，只解析用到的模块和字段
+type yahooQuoteSummary struct {
+	Price *struct {
+		LongName     string     `json:"longName"`
+		ShortName    string     `json:"shortName"`
+		ExchangeName string     `json:"exchangeName"`
+		Currency     string     `json:"currency"`
+		MarketCap    yahooValue `json:"marketCap"`
+	} `json:"price"`
+	SummaryDetail *struct {
+		MarketCap                    yahooValue `json:"marketCap"`
+		TrailingPE                   yahooValue `json:"trailingPE"`
+		PriceToSalesTrailing12Months yahooValue `json:"priceToSalesTrailing12Months"`
+		PayoutRatio                  yahooValue `json:"payoutRatio"`
+	} `json:"summaryDetail"`
+	DefaultKeyStatistics *struct {
+		EnterpriseValue     yahooValue `json:"enterpriseValue"`
+		PriceToBook         yahooValue `json:"priceToBook"`
+		PegRatio            yahooValue `json:"pegRatio"`
+		EnterpriseToRevenue yahooValue `json:"enterpriseToRevenue"`
+		EnterpriseToEbitda  yahooValue `json:"enterpriseToEbitda"`
+		TrailingEps         yahooValue `json:"trailingEps"`
+		BookValue           yahooValue `json:"bookValue"`
+		SharesOutstanding   yahooValue `json:"sharesOutstanding"`
+		MostRecentQuarter   yahooValue `json:"mostRecentQuarter"`
+	} `json:"defaultKeyStatistics"`
+	FinancialData *struct {
+		GrossMargins      yahooValue `json:"grossMargins"`
+		OperatingMargins  yahooValue `json:"operatingMargins"`
+		ProfitMargins     yahooValue `json:"profitMargins"`
+		ReturnOnEquity    yahooValue `json:"returnOnEquity"`
+		ReturnOnAssets    yahooValue `json:"returnOnAssets"`
+		CurrentRatio      yahooValue `json:"currentRatio"`
+		QuickRatio        yahooValue `json:"quickRatio"`
+		DebtToEquity      yahooValue `json:"debtToEquity"` // 百分数，如 150 表示 1.5 倍
+		RevenueGrowth     yahooValue `json:"revenueGrowth"`
+		EarningsGrowth    yahooValue `json:"earningsGrowth"`
+		FreeCashflow      yahooValue `json:"freeCashflow"`
+		FinancialCurrency string     `json:"financialCurrency"`
+	} `json:"financialData"`
+	AssetProfile *struct {
+		Industry          string `json:"industry"`
+		Sector            string `json:"sector"`
+		City              string `json:"city"`
+		State             string `json:"state"`
+		Country           string `json:"country"`
+		Website           string `json:"website"`
+		FullTimeEmployees int    `json:"fullTimeEmployees"`
+	} `json:"assetProfile"`
+	QuoteType *struct {
+		FirstTradeDateEpochUtc int64 `json:"firstTradeDateEpochUtc"`
+	} `json:"quoteType"`
+}
+
+// quoteSummary 获取股票的 quoteSummary 模块；crumb 过期（401）时换用新的 crumb 重试一次
+func (yahooFinance) quoteSummary(ctx context.Context, ticker string, modules ...string) (*yahooQuoteSummary, error) {
+	var body []byte
+	for retried := false; ; retried = true {
+		crumb, err := yahooCrumb(ctx)
+		if err != nil {
+			return nil, err
+		}
+		endpoint := fmt.Sprintf("/v10/finance/quoteSummary/%s?modules=%s&crumb=%s",
+			url.PathEscape(ticker), strings.Join(modules, ","), url.QueryEscape(crumb))
+		body, err = fetchYahooFinance(ctx, ticker, endpoint)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, tools.ErrUnauthorized) || retried {
+			return nil, err
+		}
+		log.Printf("[Yahoo] %s 的 crumb 已失效，重新获取后重试", ticker)
+		resetYahooCrumb()
+	}
+
+	var response struct {
+		QuoteSummary struct {
+			Result []yahooQuoteSummary `json:"result"`
+		} `json:"quoteSummary"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("解析 quoteSummary 响应失败: %w", err)
+	}
+	if len(response.QuoteSummary.Result) == 0 {
+		return nil, fmt.Errorf("%s quoteSummary: %w", ticker, tools.ErrNoData)
+	}
+	return &response.QuoteSummary.Result[0], nil
+}
+
+// Metrics 只提供最新一期的 TTM 指标（报告期为最近一个季度末），period 不是 ttm 或 endDate 早于最近一个季度末时返回 tools.ErrNoData
+func (p yahooFinance) Metrics(ctx context.Context, ticker, endDate, period string, limit int) ([]tools.FinancialMetrics, error) {
+	if period != "ttm" {
+		return nil, fmt.Errorf("%s %s 财务指标（Yahoo Finance 只提供最新一期 TTM 指标）: %w", ticker, period, tools.ErrNoData)
+	}
+	summary, err := p.quoteSummary(ctx, ticker, "price", "summaryDetail", "defaultKeyStatistics", "financialData")
+	if err != nil {
+		return nil, err
+	}
+	if summary.FinancialData == nil || summary.DefaultKeyStatistics == nil || summary.SummaryDetail == nil {
+		return nil, fmt.Errorf("%s 财务指标: %w", ticker, tools.ErrNoData)
+	}
+	stats, detail, financial := summary.DefaultKeyStatistics, summary.SummaryDetail, summary.FinancialData
+
+	reportPeriod := time.Now().Format("2006-01-02")
+	if stats.MostRecentQuarter.Raw != nil {
+		reportPeriod = time.Unix(int64(stats.MostRecentQuarter.value()), 0).UTC().Format("2006-01-02")
+	}
+	if reportPeriod > endDate {
+		return nil, fmt.Errorf("%s 截至 %s 的财务指标（Yahoo Finance 没有历史指标）: %w", ticker, endDate, tools.ErrNoData)
+	}
+
+	ptr := func(v yahooValue) *float64 {
+		if v.Raw == nil {
+			return nil
+		}
+		value := *v.Raw
+		return &value
+	}
+	metrics := tools.FinancialMetrics{
+		Ticker:                        ticker,
+		ReportPeriod:                  reportPeriod,
+		Period:                        "ttm",
+		Currency:                      financial.FinancialCurrency,
+		MarketCap:                     detail.MarketCap.value(),
+		EnterpriseValue:               stats.EnterpriseValue.value(),
+		PriceToEarningsRatio:          detail.TrailingPE.value(),
+		PriceToBookRatio:              stats.PriceToBook.value(),
+		PriceToSalesRatio:             detail.PriceToSalesTrailing12Months.value(),
+		EnterpriseValueToEbitdaRatio:  stats.EnterpriseToEbitda.value(),
+		EnterpriseValueToRevenueRatio: stats.EnterpriseToRevenue.value(),
+		PegRatio:                      stats.PegRatio.value(),
+		GrossMargin:                   financial.GrossMargins.value(),
+		OperatingMargin:               ptr(financial.OperatingMargins),
+		NetMargin:                     ptr(financial.ProfitMargins),
+		ReturnOnEquity:                ptr(financial.ReturnOnEquity),
+		ReturnOnAssets:                ptr(financial.ReturnOnAssets),
+		CurrentRatio:                  ptr(financial.CurrentRatio),
+		QuickRatio:                    ptr(financial.QuickRatio),
+		RevenueGrowth:                 financial.RevenueGrowth.value(),
+		EarningsGrowth:                financial.EarningsGrowth.value(),
+		PayoutRatio:                   detail.PayoutRatio.value(),
+		EarningsPerShare:              stats.TrailingEps.value(),
+		BookValuePerShare:             stats.BookValue.value(),
+	}
+	if financial.DebtToEquity.Raw != nil {
+		debtToEquity := financial.DebtToEquity.value() / 100
+		metrics.DebtToEquity = &debtToEquity
+	}
+	if metrics.MarketCap > 0 {
+		metrics.FreeCashFlowYield = financial.FreeCashflow.value() / metrics.MarketCap
+	}
+	if shares := stats.SharesOutstanding.value(); shares > 0 {
+		metrics.FreeCashFlowPerShare = financial.FreeCashflow.value() / shares
+	}
+
+	results := []tools.FinancialMetrics{metrics}
+	units := dataAPIClient().providers[providerYahooFinance].MetricUnits
+	for _, note := range tools.NormalizeFinancialMetrics(results, units) {
+		log.Printf("[Metrics] %s %s", ticker, note)
+	}
+	return results, nil
+}
+
+// News Yahoo Finance 数据源不提供公司新闻
+func (yahooFinance) News(ctx context.Context, ticker, endDate string, startDate *string, limit int, handle func(page []tools.CompanyNews) error) error {
+	return fmt.Errorf("%s 新闻（Yahoo Finance 数据源不提供新闻）: %w", ticker, tools.ErrNoData)
+}
+
+// InsiderTrades Yahoo Finance 数据源不提供内部人交易
+func (yahooFinance) InsiderTrades(ctx context.Context, ticker, endDate string, startDate *string, limit int, handle func(page []tools.InsiderTrade) error) (string, error) {
+	return "", fmt.Errorf("%s 内部人交易（Yahoo Finance 数据源不提供内部人交易）: %w", ticker, tools.ErrNoData)
+}
+
+// MarketCap 当天的市值取自报价；历史日期按该日之前最近的收盘价乘以当前总股本估算，期间有增发或回购时存在偏差
+func (p yahooFinance) MarketCap(ctx context.Context, ticker, endDate string) (float64, error) {
+	summary, err := p.quoteSummary(ctx, ticker, "price", "defaultKeyStatistics")
+	if err != nil {
+		return 0, err
+	}
+	if endDate == time.Now().Format("2006-01-02") {
+		if summary.Price == nil || summary.Price.MarketCap.value() <= 0 {
+			return 0, fmt.Errorf("%s 报价中的市值: %w", ticker, tools.ErrNoData)
+		}
+		return summary.Price.MarketCap.value(), nil
+	}
+
+	if summary.DefaultKeyStatistics == nil || summary.DefaultKeyStatistics.SharesOutstanding.value() <= 0 {
+		return 0, fmt.Errorf("%s 总股本: %w", ticker, tools.ErrNoData)
+	}
+	end, err := time.Parse("2006-01-02", endDate)
+	if err != nil {
+		return 0, fmt.Errorf("无效的结束日期: %s", endDate)
+	}
+	prices, err := p.Prices(ctx, ticker, end.AddDate(0, 0, -10).Format("2006-01-02"), endDate)
+	if err != nil {
+		return 0, err
+	}
+	if len(prices) == 0 {
+		return 0, fmt.Errorf("%s 截至 %s 的市值: %w", ticker, endDate, tools.ErrNoData)
+	}
+	return prices[len(prices)-1].Close * summary.DefaultKeyStatistics.SharesOutstanding.value(), nil
+}
+
+// Facts 获取公司基本信息，上市日期取自首个交易日，股本为当前总股本
+func (p yahooFinance) Facts(ctx context.Context, ticker string) (*provider.CompanyFacts, error) {
+	summary, err := p.quoteSummary(ctx, ticker, "price", "assetProfile", "defaultKeyStatistics", "quoteType")
+	if err != nil {
+		return nil, err
+	}
+	if summary.Price == nil {
+		return nil, fmt.Errorf("%s 公司信息: %w", ticker, tools.ErrNoData)
+	}
+
+	facts := &provider.CompanyFacts{
+		Ticker:    ticker,
+		Name:      summary.Price.LongName,
+		Exchange:  summary.Price.ExchangeName,
+		IsActive:  true,
+		MarketCap: summary.Price.MarketCap.value(),
+	}
+	if facts.Name == "" {
+		facts.Name = summary.Price.ShortName
+	}
+	if profile := summary.AssetProfile; profile != nil {
+		facts.Industry = profile.Industry
+		facts.Sector = profile.Sector
+		facts.WebsiteURL = profile.Website
+		facts.NumberOfEmployees = profile.FullTimeEmployees
+		var location []string
+		for _, part := range []string{profile.City, profile.State, profile.Country} {
+			if part != "" {
+				location = append(location, part)
+			}
+		}
+		facts.Location = strings.Join(location, ", ")
+	}
+	if stats := summary.DefaultKeyStatistics; stats != nil {
+		facts.WeightedAverageShares = int(stats.SharesOutstanding.value())
+	}
+	if quoteType := summary.QuoteType; quoteType != nil && quoteType.FirstTradeDateEpochUtc > 0 {
+		facts.ListingDate = time.Unix(quoteType.FirstTradeDateEpochUtc, 0).UTC().Format("2006-01-02")
+	}
+	return facts, nil
+}
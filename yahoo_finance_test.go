@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"investment/tools"
+)
+
+func TestYahooQuoteSummaryRetriesWithFreshCrumb(t *testing.T) {
+	tests := []struct {
+		name        string
+		statuses    []int // quoteSummary 依次返回的状态码
+		wantCrumbs  int
+		wantErr     error
+		wantSummary bool
+	}{
+		{name: "crumb 过期后重试成功", statuses: []int{http.StatusUnauthorized, http.StatusOK}, wantCrumbs: 2, wantSummary: true},
+		{name: "只重试一次", statuses: []int{http.StatusUnauthorized, http.StatusUnauthorized}, wantCrumbs: 2, wantErr: tools.ErrUnauthorized},
+		{name: "其他错误不重试", statuses: []int{http.StatusInternalServerError}, wantCrumbs: 1, wantErr: tools.ErrUpstream},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crumbs, calls := 0, 0
+			stubDataAPI(t, providerYahooFinance, &dataProvider{BaseURL: "https://yahoo.test"}, func(r *http.Request) (int, string) {
+				switch r.URL.Path {
+				case "/v1/test/getcrumb":
+					crumbs++
+					return http.StatusOK, "crumb"
+				case "/v10/finance/quoteSummary/AAPL":
+					if r.URL.Query().Get("crumb") != "crumb" {
+						t.Errorf("请求没有携带 crumb: %s", r.URL)
+					}
+					status := tt.statuses[calls]
+					calls++
+					if status != http.StatusOK {
+						return status, `{"finance":{"error":{"code":"Unauthorized"}}}`
+					}
+					return status, `{"quoteSummary":{"result":[{}]}}`
+				default:
+					return http.StatusNotFound, ""
+				}
+			})
+
+			summary, err := yahooFinance{}.quoteSummary(context.Background(), "AAPL", "price")
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("得到错误 %v，期望 %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("意外的错误: %v", err)
+			}
+			if (summary != nil) != tt.wantSummary {
+				t.Errorf("返回的 quoteSummary 为 %v", summary)
+			}
+			if crumbs != tt.wantCrumbs || calls != len(tt.statuses) {
+				t.Errorf("获取 crumb %d 次、请求 quoteSummary %d 次，期望 %d 次、%d 次", crumbs, calls, tt.wantCrumbs, len(tt.statuses))
+			}
+		})
+	}
+}
+
+func TestRedactURLDropsCrumb(t *testing.T) {
+	req, _ := http.NewRequest("GET", "https://query2.finance.yahoo.com/v10/finance/quoteSummary/AAPL?modules=price&crumb=abc", nil)
+	if got, want := redactURL(req.URL), "https://query2.finance.yahoo.com/v10/finance/quoteSummary/AAPL?modules=price"; got != want {
+		t.Errorf("得到 %s，期望 %s", got, want)
+	}
+}